@@ -39,16 +39,15 @@ package canvasadapter
 import (
 	"image/color"
 
-	"github.com/npillmayer/schuko/gtrace"
 
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/backend/gfx"
 	//"github.com/tdewolff/canvas"
 )
 
-// G traces to the graphics tracer.
+// G traces with key 'tyse.backend'.
 func G() tracing.Trace {
-	return gtrace.GraphicsTracer
+	return tracing.Select("tyse.backend")
 }
 
 // Surface creates a bridge to a canvas.Canvas.
@@ -42,14 +42,13 @@ import (
 
 	"github.com/npillmayer/arithm"
 	"github.com/npillmayer/arithm/jhobby"
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/backend/gfx"
 )
 
-// G traces to the graphics tracer.
+// G traces with key 'tyse.backend'.
 func G() tracing.Trace {
-	return gtrace.GraphicsTracer
+	return tracing.Select("tyse.backend")
 }
 
 // Contour creates an immutable adapter to contours from J.Hobby-splines.
@@ -60,15 +60,14 @@ package gfx
 import (
 	"image/color"
 
-	"github.com/npillmayer/schuko/gtrace"
 
 	"github.com/npillmayer/arithm"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// G traces to the graphics tracer.
+// G traces with key 'tyse.backend'.
 func G() tracing.Trace {
-	return gtrace.GraphicsTracer
+	return tracing.Select("tyse.backend")
 }
 
 // Surface is an interface type for a drawing surface.
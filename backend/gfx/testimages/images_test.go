@@ -3,7 +3,6 @@ package testimages
 import (
 	"testing"
 
-	"github.com/npillmayer/schuko/gtrace"
 
 	"github.com/npillmayer/arithm"
 	"github.com/npillmayer/arithm/jhobby"
@@ -13,7 +12,7 @@ import (
 )
 
 func T() tracing.Trace {
-	return gtrace.GraphicsTracer
+	return tracing.Select("tyse.backend")
 }
 
 func TestEmptyPath1(t *testing.T) {
@@ -41,11 +41,10 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE. */
 package pdf
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// T is tracing to the EngineTracer.
+// T traces with key 'tyse.backend'.
 func T() tracing.Trace {
-	return gtrace.EngineTracer
+	return tracing.Select("tyse.backend")
 }
@@ -0,0 +1,86 @@
+package pdfapi
+
+// Link annotations (PDF 1.7 §12.5.6.5). A link is an area of a page which,
+// when clicked, jumps to a position on a page of the document or opens a URI.
+//
+// Pages are added to the document as they are assembled, which may be after a
+// link to them has been created. Destinations are therefore given as page
+// indices and are resolved to page references when the document is encoded.
+// Links are collected per canvas, as canvases may be drawn on concurrently, and
+// handed over to the document when the page is assembled.
+
+type link struct {
+	canvas *Canvas
+	rect   Rectangle
+	page   int    // index of the destination page, counting from 0
+	dest   Point  // position on the destination page
+	uri    string // destination of a link to a URI, if not empty
+}
+
+type linkAnnotation struct {
+	Type    name
+	Subtype name
+	Rect    Rectangle
+	Border  []int
+	Dest    []interface{} `pdf:",omitempty"`
+	A       *uriAction    `pdf:",omitempty"`
+}
+
+type uriAction struct {
+	S   name
+	URI string
+}
+
+// PDF annotation types
+const (
+	annotType      name = "Annot"
+	linkSubtype    name = "Link"
+	uriActionType  name = "URI"
+	xyzDestination name = "XYZ"
+)
+
+// LinkTo adds a link to the page of the canvas. Clicking on rect will jump to
+// position dest on the page with index page (counting from 0) in the order of
+// assembly. The link has no border.
+func (canvas *Canvas) LinkTo(rect Rectangle, page int, dest Point) {
+	canvas.links = append(canvas.links, link{canvas: canvas, rect: rect, page: page, dest: dest})
+}
+
+// LinkToURI adds a link to the page of the canvas. Clicking on rect will open uri.
+func (canvas *Canvas) LinkToURI(rect Rectangle, uri string) {
+	canvas.links = append(canvas.links, link{canvas: canvas, rect: rect, uri: uri})
+}
+
+// addLinkAnnotations adds the links of the document as annotations to their
+// pages. Links to pages which have not been assembled are dropped.
+func (doc *Document) addLinkAnnotations() {
+	for _, l := range doc.links {
+		annot := &linkAnnotation{
+			Type:    annotType,
+			Subtype: linkSubtype,
+			Rect:    l.rect.canonical(),
+			Border:  []int{0, 0, 0},
+		}
+		if l.uri != "" {
+			annot.A = &uriAction{S: uriActionType, URI: l.uri}
+		} else if l.page >= 0 && l.page < len(doc.pages) {
+			annot.Dest = []interface{}{doc.pages[l.page].Reference, xyzDestination, l.dest.X, l.dest.Y, nil}
+		} else {
+			continue
+		}
+		l.canvas.page.Annots = append(l.canvas.page.Annots, doc.add(annot))
+	}
+	doc.links = nil
+}
+
+// canonical returns r with Min being the lower left corner and Max being the
+// upper right corner.
+func (r Rectangle) canonical() Rectangle {
+	if r.Min.X > r.Max.X {
+		r.Min.X, r.Max.X = r.Max.X, r.Min.X
+	}
+	if r.Min.Y > r.Max.Y {
+		r.Min.Y, r.Max.Y = r.Max.Y, r.Min.Y
+	}
+	return r
+}
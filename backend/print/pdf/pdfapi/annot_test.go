@@ -0,0 +1,40 @@
+package pdfapi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLinkAnnotations(t *testing.T) {
+	doc := NewDocument()
+	p1 := doc.NewPage(A4Width, A4Height)
+	p2 := doc.NewPage(A4Width, A4Height)
+	p1.LinkTo(Rectangle{Point{10, 20}, Point{50, 10}}, 1, Point{0, 700})
+	p1.LinkToURI(Rectangle{Point{10, 40}, Point{50, 30}}, "https://example.com")
+	p2.LinkTo(Rectangle{Point{10, 20}, Point{50, 10}}, 5, Point{0, 700})
+	for _, p := range []*Canvas{p1, p2} {
+		p.Close()
+		doc.Assemble(p)
+	}
+	var b bytes.Buffer
+	if err := doc.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+	if len(p1.page.Annots) != 2 || len(p2.page.Annots) != 0 {
+		t.Fatalf("expected 2 annotations on page 1 and none on page 2, have %d and %d",
+			len(p1.page.Annots), len(p2.page.Annots))
+	}
+	out := b.String()
+	dest := fmt.Sprintf("/Dest [ %d %d R /XYZ 0.00000 700.00000 null ]", p2.ref.Number, p2.ref.Generation)
+	if !strings.Contains(out, dest) {
+		t.Errorf("expected link to page 2, missing %q", dest)
+	}
+	if !strings.Contains(out, "/Rect [ 10.00000 10.00000 50.00000 20.00000 ]") {
+		t.Errorf("expected link rectangle to be normalized")
+	}
+	if !strings.Contains(out, "/A << /S /URI /URI (https://example.com) >>") {
+		t.Errorf("expected URI action for external link")
+	}
+}
@@ -40,6 +40,7 @@ type Canvas struct {
 	ref          Reference
 	contents     *stream
 	imageCounter uint
+	links        []link // links of the page, see LinkTo
 }
 
 // Document returns the document the canvas is attached to.
@@ -38,6 +38,7 @@ type Document struct {
 	catalog *catalog
 	pages   []indirectObject
 	fonts   map[name]Reference
+	links   []link // link annotations, added to pages when encoding
 }
 
 // New creates a new document with no pages.
@@ -81,6 +82,7 @@ func (doc *Document) Assemble(c *Canvas) {
 	page := c.page
 	c.ref = c.doc.add(page)
 	doc.pages = append(doc.pages, indirectObject{c.ref, page})
+	doc.links = append(doc.links, c.links...)
 }
 
 // standardFont returns a reference to a standard font dictionary.  If there is
@@ -134,6 +136,7 @@ func (doc *Document) Encode(w io.Writer) error {
 		page.Parent = doc.catalog.Pages
 		pageRoot.Kids = append(pageRoot.Kids, p.Reference)
 	}
+	doc.addLinkAnnotations()
 
 	return doc.encoder.encode(w)
 }
@@ -179,6 +182,7 @@ type pageDict struct {
 	MediaBox  Rectangle
 	CropBox   Rectangle
 	Contents  Reference
+	Annots    []Reference `pdf:",omitempty"`
 }
 
 // Point is a 2D point.
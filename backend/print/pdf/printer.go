@@ -36,11 +36,13 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 */
 
 import (
+	"context"
 	"fmt"
+	"image"
+	"image/color"
 	"io"
 	"sync"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/tyse/backend/print/pdf/pdfapi"
 	"github.com/npillmayer/tyse/core/dimen"
 )
@@ -88,6 +90,7 @@ type Printer struct {
 	running   bool             // is this printer still printing?
 	maxPageNo contPageNo       // page number of last page to print
 	pagecount contPageNo       // number of pages already completed
+	ctx       context.Context  // context of the print job, for trace spans
 }
 
 // NewPrinter creates a new Printer, given a paper format and scale factor.
@@ -141,11 +144,11 @@ func (pr *Printer) pageFailed(page *Page, err error) {
 	page.pdfcanvas = pr.doc.NewPage(pr.papersize.X, pr.papersize.Y)
 	errtext := &pdfapi.Text{}
 	cv := makeConv(pr.papersize, page.pageGeom, pr.scale)
-	errtext.MoveCursor(cv.toPdfPoint(pdfapi.Point{20, 20}))
+	errtext.MoveCursor(cv.toPdfPoint(pdfapi.Point{X: 20, Y: 20}))
 	errtext.SetFont(pdfapi.NewInternalFont(pdfapi.Helvetica), 12)
 	errtext.AddGlyphs(fmt.Sprintf("Error rendering page [%d]", page.pageNo))
 	if err != nil {
-		errtext.MoveCursor(cv.toPdfPoint(pdfapi.Point{20, 40}))
+		errtext.MoveCursor(cv.toPdfPoint(pdfapi.Point{X: 20, Y: 40}))
 		errtext.AddGlyphs(err.Error())
 	}
 	page.pdfcanvas.DrawText(errtext)
@@ -210,7 +213,7 @@ func assemblePagesToDocument(pr *Printer, w io.Writer) {
 			close(pr.errch) // signal to printing clients
 		}
 	}
-	gtrace.EngineTracer.Debugf("Assembly worker stopped")
+	T().Debugf("Assembly worker stopped")
 }
 
 func (pr *Printer) drainAssemblyQueueAndStop() {
@@ -259,8 +262,15 @@ func (pr *Printer) SetMaxPage(n int) {
 // Clients will call this promise to synchronously wait for printing
 // to finish.
 func (pr *Printer) Start(w io.Writer) func() error {
+	return pr.StartContext(context.Background(), w)
+}
+
+// StartContext is like Start, but trace spans for rendering pages will be recorded
+// as children of a span contained in ctx (see package core/spans).
+func (pr *Printer) StartContext(ctx context.Context, w io.Writer) func() error {
 	pr.mtx.Lock()
 	pr.running = true
+	pr.ctx = ctx
 	pr.mtx.Unlock()
 	go func(pp *Printer) {
 		assemblePagesToDocument(pp, w)
@@ -311,7 +321,7 @@ func (pr *Printer) PageCount() int {
 }
 
 func (pr *Printer) appendToDocument(page *Page) error {
-	gtrace.EngineTracer.Infof("OUTPUT PAGE [%d]", page.pageNo)
+	T().Infof("OUTPUT PAGE [%d]", page.pageNo)
 	page.pdfcanvas.Close()
 	pr.doc.Assemble(page.pdfcanvas)
 	return nil
@@ -323,8 +333,51 @@ func (pr *Printer) serialize(w io.Writer) {
 	pr.doc.Encode(w)
 }
 
+// RenderTree is the content of a page to print.
 // TODO
-type RenderTree struct{}
+type RenderTree struct {
+	Decorations []Decoration // backgrounds and borders of boxes, in painting order
+	Links       []Link       // hyperlinks on the page
+	Images      []Image      // raster images on the page
+}
+
+// Decoration is the background and border of a box. The background fills the
+// border box, with corners rounded by Radius, and is painted below the border.
+type Decoration struct {
+	Border      dimen.Rect  // border box on the page
+	Widths      [4]dimen.DU // border widths: top, right, bottom, left
+	Background  color.Color // background color, nil for transparent
+	Image       *Image      // background image, painted over the background color
+	BorderColor color.Color // color of the border, nil for no border
+	LineStyle   LineStyle   // line style of the border
+	Radius      dimen.DU    // radius of rounded corners
+}
+
+// LineStyle is a style for drawing borders.
+type LineStyle int8
+
+// Borders may be drawn as solid, dashed or dotted lines. Non-uniform borders are
+// always drawn solid.
+const (
+	Solid LineStyle = iota
+	Dashed
+	Dotted
+)
+
+// Image is a raster image, scaled to fill an area of a page.
+type Image struct {
+	Rect dimen.Rect  // area of the image on the page
+	Img  image.Image // image to draw
+}
+
+// Link is an area of a page which links to a position on a page of the document,
+// or to an external URI.
+type Link struct {
+	Rect   dimen.Rect  // area of the link on the page
+	PageNo int         // page number of the destination of internal links
+	Dest   dimen.Point // position on the destination page
+	URI    string      // destination of external links, empty for internal links
+}
 
 // Page represents a page in the printer queue, as part of a print job.
 // Pages will be created by Printer.PrintPage(...).
@@ -5,6 +5,7 @@ import (
 
 	"github.com/npillmayer/tyse/backend/print/pdf/pdfapi"
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
 )
 
 // render will be executed by concurrent render workers.
@@ -12,11 +13,122 @@ import (
 // TODO and create pdfapi calls on it.
 func (pr *Printer) render(page *Page) error {
 	T().Debugf("Rendering page [%d]", page.pageNo)
+	pr.mtx.RLock()
+	ctx := pr.ctx
+	pr.mtx.RUnlock()
+	_, span := spans.Start(ctx, spans.Page, "pdf.RenderPage")
+	defer span.End()
+	span.SetAttribute("page", int(page.pageNo))
 	cv := makeConv(pr.papersize, page.pageGeom, pr.scale) // set up conversion
 	renderPrinterMarks(cv, page.pdfcanvas, pr.Proofing)
+	renderDecorations(cv, page.pdfcanvas, page.content)
+	renderImages(cv, page.pdfcanvas, page.content)
+	renderLinks(cv, page.pdfcanvas, page.content)
 	return nil
 }
 
+// renderDecorations paints the backgrounds and borders of boxes on a page.
+func renderDecorations(cv *conv, canvas *pdfapi.Canvas, content *RenderTree) {
+	if content == nil {
+		return
+	}
+	for _, d := range content.Decorations {
+		canvas.PushState()
+		if d.Background != nil {
+			canvas.SetFillColor(d.Background)
+			P := &pdfapi.Path{}
+			P.Rectangle(upright(cv.Rect(d.Border)), cv.ScaledUnit(d.Radius))
+			canvas.Fill(P)
+		}
+		if d.Image != nil && d.Image.Img != nil {
+			canvas.DrawImage(d.Image.Img, upright(cv.Rect(d.Image.Rect)))
+		}
+		if d.BorderColor != nil && d.Widths != [4]dimen.DU{} {
+			renderBorder(cv, canvas, d)
+		}
+		canvas.PopState()
+	}
+}
+
+// renderBorder draws the border of a decoration. A border of uniform width is
+// stroked along the middle of the border area, honouring line style and
+// rounded corners. Otherwise every side is filled separately.
+func renderBorder(cv *conv, canvas *pdfapi.Canvas, d Decoration) {
+	w := d.Widths
+	if w[0] == w[1] && w[0] == w[2] && w[0] == w[3] {
+		inset := dimen.Rect{
+			TopL: dimen.Point{X: d.Border.TopL.X + w[0]/2, Y: d.Border.TopL.Y + w[0]/2},
+			BotR: dimen.Point{X: d.Border.BotR.X - w[0]/2, Y: d.Border.BotR.Y - w[0]/2},
+		}
+		lw := cv.ScaledUnit(w[0])
+		canvas.SetStrokeColor(d.BorderColor)
+		canvas.SetLineWidth(lw)
+		switch d.LineStyle {
+		case Dashed:
+			canvas.SetLineDash(0, []pdfapi.Unit{3 * lw})
+		case Dotted:
+			canvas.SetLineDash(0, []pdfapi.Unit{lw})
+		}
+		P := &pdfapi.Path{}
+		P.Rectangle(upright(cv.Rect(inset)), cv.ScaledUnit(d.Radius-w[0]/2))
+		canvas.Stroke(P)
+		return
+	}
+	canvas.SetFillColor(d.BorderColor)
+	b := d.Border
+	sides := []dimen.Rect{
+		{TopL: b.TopL, BotR: dimen.Point{X: b.BotR.X, Y: b.TopL.Y + w[0]}},
+		{TopL: dimen.Point{X: b.BotR.X - w[1], Y: b.TopL.Y}, BotR: b.BotR},
+		{TopL: dimen.Point{X: b.TopL.X, Y: b.BotR.Y - w[2]}, BotR: b.BotR},
+		{TopL: b.TopL, BotR: dimen.Point{X: b.TopL.X + w[3], Y: b.BotR.Y}},
+	}
+	P := &pdfapi.Path{}
+	for i, side := range sides {
+		if w[i] > 0 {
+			P.Rectangle(upright(cv.Rect(side)), 0)
+		}
+	}
+	canvas.Fill(P)
+}
+
+// upright turns a rectangle converted from page co-ordinates, which has its
+// minimum point at the top, into a rectangle going upwards from its lower left
+// corner.
+func upright(rect pdfapi.Rectangle) pdfapi.Rectangle {
+	rect.Min.Y, rect.Max.Y = rect.Max.Y, rect.Min.Y
+	return rect
+}
+
+// renderImages draws the images of a page.
+func renderImages(cv *conv, canvas *pdfapi.Canvas, content *RenderTree) {
+	if content == nil {
+		return
+	}
+	for _, img := range content.Images {
+		if img.Img == nil {
+			continue
+		}
+		// images are drawn upwards from the lower left corner
+		canvas.DrawImage(img.Img, upright(cv.Rect(img.Rect)))
+	}
+}
+
+// renderLinks adds the hyperlinks of a page as link annotations. Pages are
+// expected to be numbered 1…n (see PrintPage), so page number n is page index
+// n-1 of the document.
+func renderLinks(cv *conv, canvas *pdfapi.Canvas, content *RenderTree) {
+	if content == nil {
+		return
+	}
+	for _, l := range content.Links {
+		if l.URI != "" {
+			canvas.LinkToURI(cv.Rect(l.Rect), l.URI)
+			continue
+		}
+		canvas.LinkTo(cv.Rect(l.Rect), l.PageNo-1, cv.Point(l.Dest))
+	}
+}
+
 // Render printer marks:
 // - crop marks
 // - color scales    TODO
@@ -30,8 +142,8 @@ func renderPrinterMarks(cv *conv, canvas *pdfapi.Canvas, proofing bool) {
 	if proofing {
 		P := &pdfapi.Path{}
 		pagebox := pdfapi.Rectangle{
-			cv.toPdfPoint(pdfapi.Point{0, 0}),
-			cv.toPdfPoint(cv.pageDim),
+			Min: cv.toPdfPoint(pdfapi.Point{X: 0, Y: 0}),
+			Max: cv.toPdfPoint(cv.pageDim),
 		}
 		P.Rectangle(pagebox, 0)
 		canvas.Stroke(P)
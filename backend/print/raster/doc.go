@@ -0,0 +1,27 @@
+/*
+Package raster is a backend drawing pages and paragraphs into RGBA images. It
+is intended for quick previews without a PDF viewer and for tests comparing
+output to golden images.
+
+Glyphs are rasterized from the outlines of a font, using an anti-aliasing
+vector rasterizer. Text boxes which have been shaped are drawn from their glyph
+sequence; for boxes without shaping results, glyphs are mapped directly from the
+characters of the box text.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package raster
+
+import (
+	"github.com/npillmayer/schuko/tracing"
+)
+
+// tracer traces with key 'tyse.backend'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.backend")
+}
@@ -0,0 +1,109 @@
+package raster
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	xfont "golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// positionedGlyph is a glyph together with its origin on the baseline, in pixels.
+type positionedGlyph struct {
+	gid  sfnt.GlyphIndex
+	x, y float64
+}
+
+// drawTextBox draws the glyphs of a text box in black, with the origin of the
+// first glyph at (x, y) on the baseline.
+func (r *Renderer) drawTextBox(img draw.Image, box *khipu.TextBox, x, y float64) {
+	var glyphs []positionedGlyph
+	if seq := box.Glyphs(); len(seq.Glyphs) > 0 {
+		glyphs = r.shapedGlyphs(seq, x, y)
+	} else {
+		glyphs = r.mappedGlyphs(box.Text(), x, y)
+	}
+	r.drawGlyphs(img, glyphs)
+}
+
+// shapedGlyphs positions the glyphs of a glyph sequence resulting from shaping.
+func (r *Renderer) shapedGlyphs(seq glyphing.GlyphSequence, x, y float64) []positionedGlyph {
+	glyphs := make([]positionedGlyph, 0, len(seq.Glyphs))
+	for _, g := range seq.Glyphs {
+		glyphs = append(glyphs, positionedGlyph{
+			gid: sfnt.GlyphIndex(g.GID),
+			x:   x + r.px(g.XOffset),
+			y:   y - r.px(g.YOffset),
+		})
+		x += r.px(g.XAdvance)
+	}
+	return glyphs
+}
+
+// mappedGlyphs maps the characters of s to glyphs of the font, without shaping,
+// and positions them by their advance.
+func (r *Renderer) mappedGlyphs(s string, x, y float64) []positionedGlyph {
+	f := r.font().SFNT
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(r.px(r.fontSize()) * 64)
+	glyphs := make([]positionedGlyph, 0, len(s))
+	for _, c := range s {
+		gid, err := f.GlyphIndex(&buf, c)
+		if err != nil || gid == 0 {
+			tracer().Debugf("raster: no glyph for %#U", c)
+			continue
+		}
+		glyphs = append(glyphs, positionedGlyph{gid: gid, x: x, y: y})
+		if adv, err := f.GlyphAdvance(&buf, gid, ppem, xfont.HintingNone); err == nil {
+			x += float64(adv) / 64
+		}
+	}
+	return glyphs
+}
+
+// drawGlyphs rasterizes the outlines of glyphs into img.
+func (r *Renderer) drawGlyphs(img draw.Image, glyphs []positionedGlyph) {
+	if len(glyphs) == 0 {
+		return
+	}
+	f := r.font().SFNT
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(r.px(r.fontSize()) * 64)
+	b := img.Bounds()
+	z := vector.NewRasterizer(b.Dx(), b.Dy())
+	for _, g := range glyphs {
+		segments, err := f.LoadGlyph(&buf, g.gid, ppem, nil)
+		if err != nil {
+			tracer().Errorf("raster: cannot load outline of glyph %d: %v", g.gid, err)
+			continue
+		}
+		pt := func(p fixed.Point26_6) (float32, float32) {
+			return float32(g.x + float64(p.X)/64 - float64(b.Min.X)), float32(g.y + float64(p.Y)/64 - float64(b.Min.Y))
+		}
+		for _, seg := range segments {
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				z.ClosePath()
+				z.MoveTo(pt(seg.Args[0]))
+			case sfnt.SegmentOpLineTo:
+				z.LineTo(pt(seg.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				bx, by := pt(seg.Args[0])
+				cx, cy := pt(seg.Args[1])
+				z.QuadTo(bx, by, cx, cy)
+			case sfnt.SegmentOpCubeTo:
+				bx, by := pt(seg.Args[0])
+				cx, cy := pt(seg.Args[1])
+				dx, dy := pt(seg.Args[2])
+				z.CubeTo(bx, by, cx, cy, dx, dy)
+			}
+		}
+		z.ClosePath()
+	}
+	z.DrawOp = draw.Over
+	z.Draw(img, b, image.Black, image.Point{})
+}
@@ -0,0 +1,157 @@
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/engine/api"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+var (
+	colorContent = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	colorBox     = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	colorBoxFill = color.RGBA{0xf4, 0xf4, 0xf4, 0xff}
+)
+
+// Renderer draws pages and paragraphs into RGBA images.
+type Renderer struct {
+	Font     *font.ScalableFont // font for glyphs; if nil, the fallback font is used
+	FontSize dimen.DU           // size of Font; if 0, 10pt are used
+	DPI      float64            // resolution; if 0, 72 dpi are used, i.e. one pixel per big point
+}
+
+// NewRenderer creates a renderer for a font at a given size and resolution.
+func NewRenderer(f *font.ScalableFont, size dimen.DU, dpi float64) *Renderer {
+	return &Renderer{Font: f, FontSize: size, DPI: dpi}
+}
+
+func (r *Renderer) font() *font.ScalableFont {
+	if r.Font == nil {
+		return font.FallbackFont()
+	}
+	return r.Font
+}
+
+func (r *Renderer) fontSize() dimen.DU {
+	if r.FontSize <= 0 {
+		return 10 * dimen.PT
+	}
+	return r.FontSize
+}
+
+// px converts a dimension to pixels.
+func (r *Renderer) px(d dimen.DU) float64 {
+	dpi := r.DPI
+	if dpi <= 0 {
+		dpi = 72
+	}
+	return d.Points() * dpi / 72
+}
+
+// pixel converts a dimension to the nearest whole pixel.
+func (r *Renderer) pixel(d dimen.DU) int {
+	return int(math.Round(r.px(d)))
+}
+
+// Page draws a typeset page: the area within the page margins and the boxes
+// placed into the regions of the page.
+func (r *Renderer) Page(page *api.Page) (*image.RGBA, error) {
+	if page == nil {
+		return nil, fmt.Errorf("raster: no page to draw")
+	}
+	tracer().Debugf("raster: drawing page %d", page.Number)
+	img := newImage(r.pixel(page.Size.X), r.pixel(page.Size.Y))
+	strokeRect(img, r.rect(page.Content), colorContent)
+	for _, region := range page.Regions {
+		for _, box := range region.Boxes {
+			frame := r.rect(box.Frame)
+			draw.Draw(img, frame, image.NewUniform(colorBoxFill), image.Point{}, draw.Src)
+			strokeRect(img, frame, colorBox)
+		}
+	}
+	return img, nil
+}
+
+// Paragraph draws the text of the set lines of a paragraph. Lines are positioned
+// by their indent and baseline.
+func (r *Renderer) Paragraph(lines []*inline.SetLine) (*image.RGBA, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("raster: no lines to draw")
+	}
+	var width, height dimen.DU
+	for _, line := range lines {
+		width = dimen.Max(width, line.Indent+line.Length)
+		height = dimen.Max(height, line.Baseline+line.Descent)
+	}
+	tracer().Debugf("raster: drawing paragraph of %d lines", len(lines))
+	img := newImage(r.pixel(width), r.pixel(height))
+	for _, line := range lines {
+		for _, item := range line.Items {
+			box, ok := item.Knot.(*khipu.TextBox)
+			if !ok {
+				continue
+			}
+			x := r.px(line.Indent + item.X)
+			y := r.px(line.Baseline - box.Shift) // raised or lowered by baseline shift
+			r.drawTextBox(img, box, x, y)
+		}
+	}
+	return img, nil
+}
+
+// WritePNG encodes an image as PNG.
+func WritePNG(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
+
+// Diff compares two images pixel by pixel and returns the number of pixels which
+// differ. Pixels outside the bounds of one of the images count as different.
+func Diff(a, b image.Image) int {
+	union := a.Bounds().Union(b.Bounds())
+	inter := a.Bounds().Intersect(b.Bounds())
+	n := union.Dx()*union.Dy() - inter.Dx()*inter.Dy()
+	for y := inter.Min.Y; y < inter.Max.Y; y++ {
+		for x := inter.Min.X; x < inter.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+// newImage creates a white image of w × h pixels.
+func newImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func (r *Renderer) rect(rect dimen.Rect) image.Rectangle {
+	return image.Rect(r.pixel(rect.TopL.X), r.pixel(rect.TopL.Y), r.pixel(rect.BotR.X), r.pixel(rect.BotR.Y))
+}
+
+// strokeRect draws the frame of a rectangle with a line of one pixel.
+func strokeRect(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		img.Set(x, rect.Min.Y, c)
+		img.Set(x, rect.Max.Y-1, c)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		img.Set(rect.Min.X, y, c)
+		img.Set(rect.Max.X-1, y, c)
+	}
+}
@@ -0,0 +1,99 @@
+package raster
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/api"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+var update = flag.Bool("update", false, "update golden images in testdata")
+
+func TestParagraphGolden(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.backend")
+	defer teardown()
+	//
+	hello, world := khipu.NewTextBox("Hello", 0), khipu.NewTextBox("World", 6)
+	hello.Width, world.Width = 28*dimen.BP, 32*dimen.BP
+	line := &inline.SetLine{
+		Length:   80 * dimen.BP,
+		Ascent:   10 * dimen.BP,
+		Descent:  3 * dimen.BP,
+		Baseline: 12 * dimen.BP,
+		Items: []inline.PositionedKnot{
+			{Knot: hello, X: 2 * dimen.BP, W: 28 * dimen.BP},
+			{Knot: khipu.NewGlue(4*dimen.BP, 1*dimen.BP, 2*dimen.BP), X: 30 * dimen.BP, W: 6 * dimen.BP},
+			{Knot: world, X: 36 * dimen.BP, W: 32 * dimen.BP},
+		},
+	}
+	r := NewRenderer(nil, 12*dimen.BP, 144)
+	img, err := r.Paragraph([]*inline.SetLine{line})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 160 || img.Bounds().Dy() != 30 {
+		t.Errorf("expected image of 160×30 pixels at 144 dpi, is %v", img.Bounds())
+	}
+	compareToGolden(t, img, "hello.png")
+}
+
+func TestPageRaster(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.backend")
+	defer teardown()
+	//
+	page := &api.Page{
+		Number:  1,
+		Size:    dimen.Point{X: 100 * dimen.BP, Y: 100 * dimen.BP},
+		Content: dimen.Rect{TopL: dimen.Point{X: 10 * dimen.BP, Y: 10 * dimen.BP}, BotR: dimen.Point{X: 90 * dimen.BP, Y: 90 * dimen.BP}},
+	}
+	page.Regions = []api.Region{{Name: "body", Flow: "main", Frame: page.Content, Boxes: []api.Box{
+		{Element: "p", Frame: dimen.Rect{TopL: page.Content.TopL, BotR: dimen.Point{X: 90 * dimen.BP, Y: 30 * dimen.BP}}},
+	}}}
+	img, err := NewRenderer(nil, 0, 0).Page(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 100, 100) {
+		t.Errorf("expected page of 100×100 pixels at 72 dpi, is %v", img.Bounds())
+	}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if img.At(50, 20) != colorBoxFill || img.At(10, 20) != colorBox || img.At(5, 5) != white {
+		t.Errorf("expected box to be filled and framed on a white page")
+	}
+}
+
+func compareToGolden(t *testing.T, img image.Image, name string) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err = WritePNG(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := Diff(img, golden); n > 0 {
+		t.Errorf("image differs from golden image %s in %d pixels", name, n)
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Package svg is a simple backend rendering pages and paragraphs to SVG. It is
+intended for debugging layout and line breaking decisions visually, not for
+producing final output.
+
+Pages are drawn with their content area, the regions filled from flows and the
+boxes placed into them. Paragraphs are drawn line by line, with the boxes of a
+line, its baseline and the glue as it has been set. If a font is provided, text
+boxes which have been shaped are drawn with their glyph outlines; otherwise the
+text of a box is drawn as SVG text.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package svg
+
+import (
+	"github.com/npillmayer/schuko/tracing"
+)
+
+// tracer traces with key 'tyse.backend'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.backend")
+}
@@ -0,0 +1,152 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/linedebug"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Paragraph draws the set lines of a paragraph as SVG. Lines are positioned by
+// their indent and baseline; text boxes are drawn with their glyph outlines, if
+// r has a font and the boxes have been shaped.
+func (r *Renderer) Paragraph(lines []*inline.SetLine, w io.Writer) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("svg: no lines to draw")
+	}
+	var width, height dimen.DU
+	for _, line := range lines {
+		width = dimen.Max(width, line.Indent+line.Length)
+		height = dimen.Max(height, line.Baseline+line.Descent)
+	}
+	tracer().Debugf("svg: drawing paragraph of %d lines", len(lines))
+	ew := &errWriter{w: w}
+	svgStart(ew, width.Points(), height.Points())
+	ew.printf(`<g font-family="sans-serif" font-size="%.2f">`+"\n", r.fontSize().Points())
+	var buf sfnt.Buffer
+	for _, line := range lines {
+		r.drawLine(line, &buf, ew)
+	}
+	ew.printf("</g>\n")
+	svgEnd(ew)
+	return ew.err
+}
+
+// drawLine draws the items of a set line, its initial letter and ruby annotations,
+// if any, together with the line's extent and baseline.
+func (r *Renderer) drawLine(line *inline.SetLine, buf *sfnt.Buffer, ew *errWriter) {
+	x0, y := line.Indent.Points(), line.Baseline.Points()
+	if r.Overlays&BaselineOverlay != 0 {
+		ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="%s" stroke-width="0.3"/>`+"\n",
+			x0, y-line.Ascent.Points(), line.Length.Points(), (line.Ascent + line.Descent).Points(), colorLine)
+		ew.printf(`<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="%s" stroke-width="0.2"><title>%s</title></line>`+"\n",
+			x0, y, x0+line.Length.Points(), y, colorBaseline, escape(line.String()))
+	}
+	if il := line.Initial; il != nil {
+		ew.printf(`<text x="%.2f" y="%.2f" font-size="%.2f">%s</text>`+"\n",
+			il.X.Points(), il.Baseline.Points(), il.Em.Points(), escape(il.Box.Text()))
+	}
+	for _, ruby := range line.Ruby {
+		ew.printf(`<text x="%.2f" y="%.2f" font-size="%.2f">%s</text>`+"\n",
+			x0+ruby.X.Points(), y-ruby.Raise.Points(), ruby.Em.Points(), escape(ruby.Box.Text()))
+	}
+	for _, item := range line.Items {
+		x := x0 + item.X.Points()
+		switch item.Knot.Type() {
+		case khipu.KTTextBox:
+			box := textBoxOf(item.Knot)
+			by := y - box.Shift.Points() // raised or lowered by baseline shift
+			if r.Overlays&BoxOverlay != 0 {
+				ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="%s" stroke-width="0.2"/>`+"\n",
+					x, by-box.Height.Points(), item.W.Points(), (box.Height + box.Depth).Points(), colorBox)
+			}
+			if !r.drawGlyphs(box.Glyphs(), x, by, 1+line.Expansion, buf, ew) {
+				// text of lines is in visual order already, see inline.ReorderLine
+				ew.printf(`<text x="%.2f" y="%.2f" unicode-bidi="bidi-override">%s</text>`+"\n",
+					x, by, escape(box.Text()))
+			}
+		case khipu.KTGlue:
+			if r.Overlays&GlueOverlay != 0 {
+				ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"><title>%s</title></rect>`+"\n",
+					x, y-2, item.W.Points(), 2.0, linedebug.GlueColor(line.Ratio, line.Infinite),
+					fmt.Sprintf("%.2fpt set to %.2fpt (ratio %.2f)", item.Knot.W().Points(), item.W.Points(), line.Ratio))
+			}
+		}
+	}
+}
+
+// drawGlyphs draws the outlines of a glyph sequence, starting at (x, y) on the
+// baseline. Glyphs are scaled horizontally by sx, for lines set with font
+// expansion. It returns false if no glyphs have been drawn, i.e., if r has no font
+// or the sequence is empty.
+func (r *Renderer) drawGlyphs(seq glyphing.GlyphSequence, x, y, sx float64, buf *sfnt.Buffer,
+	ew *errWriter) bool {
+	//
+	if r.Font == nil || r.Font.SFNT == nil || len(seq.Glyphs) == 0 {
+		return false
+	}
+	for _, g := range seq.Glyphs {
+		d, err := glyphPath(r.Font.SFNT, buf, sfnt.GlyphIndex(g.GID), r.fontSize(),
+			x+g.XOffset.Points(), y-g.YOffset.Points())
+		if err != nil {
+			tracer().Errorf("svg: cannot load outline of glyph %d: %v", g.GID, err)
+		} else if d != "" && sx != 1 { // scale the outline around the glyph's origin
+			ew.printf(`<path d="%s" transform="matrix(%.4f 0 0 1 %.2f 0)"/>`+"\n", d, sx, x*(1-sx))
+		} else if d != "" {
+			ew.printf(`<path d="%s"/>`+"\n", d)
+		}
+		x += g.XAdvance.Points() * sx
+	}
+	return true
+}
+
+// glyphPath returns SVG path data for the outline of glyph gid of font f, scaled
+// to size and with its origin at (x, y).
+func glyphPath(f *sfnt.Font, buf *sfnt.Buffer, gid sfnt.GlyphIndex, size dimen.DU, x, y float64) (string, error) {
+	ppem := fixed.Int26_6(size.Points() * 64)
+	segments, err := f.LoadGlyph(buf, gid, ppem, nil)
+	if err != nil {
+		return "", err
+	}
+	pt := func(p fixed.Point26_6) string { // sfnt co-ordinates grow downwards, as do SVG's
+		return fmt.Sprintf("%.2f %.2f", x+float64(p.X)/64, y+float64(p.Y)/64)
+	}
+	var d strings.Builder
+	for _, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if d.Len() > 0 {
+				d.WriteString("Z") // close the previous contour
+			}
+			fmt.Fprintf(&d, "M%s", pt(seg.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			fmt.Fprintf(&d, "L%s", pt(seg.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			fmt.Fprintf(&d, "Q%s %s", pt(seg.Args[0]), pt(seg.Args[1]))
+		case sfnt.SegmentOpCubeTo:
+			fmt.Fprintf(&d, "C%s %s %s", pt(seg.Args[0]), pt(seg.Args[1]), pt(seg.Args[2]))
+		}
+	}
+	if d.Len() > 0 {
+		d.WriteString("Z")
+	}
+	return d.String(), nil
+}
+
+// textBoxOf returns the text box of a knot of type KTTextBox.
+func textBoxOf(knot khipu.Knot) *khipu.TextBox {
+	switch box := knot.(type) {
+	case *khipu.TextBox:
+		return box
+	case khipu.TextBox:
+		return &box
+	}
+	return &khipu.TextBox{}
+}
@@ -0,0 +1,132 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/engine/api"
+)
+
+// Overlay is a set of flags to select what to draw in addition to content.
+type Overlay uint8
+
+// Overlays to draw on top of or below the content.
+const (
+	BoxOverlay      Overlay = 1 << iota // frames of boxes and regions
+	BaselineOverlay                     // baselines and line extents
+	GlueOverlay                         // glue as set, color-coded by the line's ratio
+	NoOverlays      Overlay = 0
+	AllOverlays             = BoxOverlay | BaselineOverlay | GlueOverlay
+)
+
+const (
+	colorPage     = "#999999"
+	colorContent  = "#cccccc"
+	colorRegion   = "#4a90d9"
+	colorBox      = "#666666"
+	colorBaseline = "#e74c3c"
+	colorLine     = "#eeeeee"
+	margin        = 10.0 // margin around the drawing in pt
+)
+
+// Renderer renders pages and paragraphs to SVG.
+type Renderer struct {
+	Font     *font.ScalableFont // font for glyph outlines; if nil, text is drawn as SVG text
+	FontSize dimen.DU           // size of Font; if 0, 10pt are used
+	Overlays Overlay            // overlays to draw
+}
+
+// NewRenderer creates a renderer which draws all overlays. Glyphs are drawn from
+// outlines of f, if f is non-nil.
+func NewRenderer(f *font.ScalableFont, size dimen.DU) *Renderer {
+	return &Renderer{Font: f, FontSize: size, Overlays: AllOverlays}
+}
+
+func (r *Renderer) fontSize() dimen.DU {
+	if r.FontSize <= 0 {
+		return 10 * dimen.PT
+	}
+	return r.FontSize
+}
+
+// Page draws a typeset page as SVG: the paper, the area within the page margins,
+// the regions of the page and the boxes placed into the regions.
+func (r *Renderer) Page(page *api.Page, w io.Writer) error {
+	if page == nil {
+		return fmt.Errorf("svg: no page to draw")
+	}
+	tracer().Debugf("svg: drawing page %d", page.Number)
+	ew := &errWriter{w: w}
+	svgStart(ew, page.Size.X.Points(), page.Size.Y.Points())
+	ew.printf(`<g font-family="sans-serif" font-size="6">` + "\n")
+	rect(ew, dimen.Rect{BotR: page.Size}, "none", colorPage, 0)
+	rect(ew, page.Content, "none", colorContent, 2)
+	for _, region := range page.Regions {
+		if r.Overlays&BoxOverlay != 0 {
+			rect(ew, region.Frame, "none", colorRegion, 2)
+			label(ew, region.Frame.TopL, colorRegion, fmt.Sprintf("%s ← %s", region.Name, region.Flow))
+		}
+		for _, box := range region.Boxes {
+			rect(ew, box.Frame, "#f8f8f8", colorBox, 0)
+			if r.Overlays&BoxOverlay != 0 && box.Element != "" {
+				label(ew, box.Frame.TopL, colorBox, box.Element)
+			}
+		}
+	}
+	ew.printf("</g>\n")
+	svgEnd(ew)
+	return ew.err
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+// svgStart opens an SVG document of width w and height h (in points), with a
+// margin around it. Drawing co-ordinates start at the top left of the margin.
+func svgStart(ew *errWriter, w, h float64) {
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%.2fpt" height="%.2fpt" viewBox="0 0 %.2f %.2f">`+"\n",
+		w+2*margin, h+2*margin, w+2*margin, h+2*margin)
+	ew.printf(`<g transform="translate(%.2f %.2f)">`+"\n", margin, margin)
+}
+
+func svgEnd(ew *errWriter) {
+	ew.printf("</g>\n</svg>\n")
+}
+
+// rect draws a rectangle, optionally dashed.
+func rect(ew *errWriter, r dimen.Rect, fill, stroke string, dash float64) {
+	dasharray := ""
+	if dash > 0 {
+		dasharray = fmt.Sprintf(` stroke-dasharray="%.1f"`, dash)
+	}
+	ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s" stroke="%s" stroke-width="0.3"%s/>`+"\n",
+		r.TopL.X.Points(), r.TopL.Y.Points(), r.Width().Points(), r.Height().Points(), fill, stroke, dasharray)
+}
+
+// label draws a small text label just inside the top left corner at p.
+func label(ew *errWriter, p dimen.Point, color, text string) {
+	ew.printf(`<text x="%.2f" y="%.2f" fill="%s">%s</text>`+"\n",
+		p.X.Points()+1, p.Y.Points()+6, color, escape(text))
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// errWriter remembers the first error occuring during a sequence of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,88 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/engine/api"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestPageToSVG(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.backend")
+	defer teardown()
+	//
+	page := &api.Page{
+		Number:  1,
+		Size:    dimen.Point{X: 100 * dimen.PT, Y: 150 * dimen.PT},
+		Content: dimen.Rect{TopL: dimen.Point{X: 10 * dimen.PT, Y: 10 * dimen.PT}, BotR: dimen.Point{X: 90 * dimen.PT, Y: 140 * dimen.PT}},
+	}
+	page.Regions = []api.Region{{Name: "body", Flow: "main", Frame: page.Content, Boxes: []api.Box{
+		{Element: "p", Frame: dimen.Rect{TopL: page.Content.TopL, BotR: dimen.Point{X: 90 * dimen.PT, Y: 40 * dimen.PT}}},
+	}}}
+	var out bytes.Buffer
+	if err := NewRenderer(nil, 0).Page(page, &out); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	if !strings.HasPrefix(svg, "<svg") || strings.Count(svg, "<rect") != 4 {
+		t.Errorf("expected page, content area, region and box to be drawn, have\n%s", svg)
+	}
+	if !strings.Contains(svg, ">p</text>") || !strings.Contains(svg, "body ← main") {
+		t.Errorf("expected labels for box and region")
+	}
+}
+
+func TestParagraphToSVG(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.backend")
+	defer teardown()
+	//
+	hello, world := khipu.NewTextBox("Hello", 0), khipu.NewTextBox("World", 6)
+	hello.Width, hello.Height = 20*dimen.PT, 8*dimen.PT
+	world.Width, world.Height = 22*dimen.PT, 8*dimen.PT
+	glue := khipu.NewGlue(3*dimen.PT, 1*dimen.PT, 2*dimen.PT)
+	line := &inline.SetLine{
+		Length:   50 * dimen.PT,
+		Ratio:    1.0,
+		Ascent:   8 * dimen.PT,
+		Descent:  2 * dimen.PT,
+		Baseline: 8 * dimen.PT,
+		Items: []inline.PositionedKnot{
+			{Knot: hello, X: 0, W: 20 * dimen.PT},
+			{Knot: glue, X: 20 * dimen.PT, W: 5 * dimen.PT},
+			{Knot: world, X: 25 * dimen.PT, W: 22 * dimen.PT},
+		},
+	}
+	var out bytes.Buffer
+	if err := NewRenderer(nil, 0).Paragraph([]*inline.SetLine{line}, &out); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	if !strings.Contains(svg, ">Hello</text>") || !strings.Contains(svg, ">World</text>") {
+		t.Errorf("expected text of boxes to be drawn without a font")
+	}
+	if !strings.Contains(svg, "<line") || !strings.Contains(svg, "set to 5.00pt") {
+		t.Errorf("expected baseline and glue as set to be drawn, have\n%s", svg)
+	}
+}
+
+func TestGlyphOutline(t *testing.T) {
+	f := font.FallbackFont()
+	gid, err := f.SFNT.GlyphIndex(nil, 'O')
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := glyphPath(f.SFNT, &sfnt.Buffer{}, gid, 10*dimen.PT, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(d, "M") || strings.Count(d, "M") != 2 || !strings.HasSuffix(d, "Z") {
+		t.Errorf("expected outline of 'O' to consist of two closed contours, is %q", d)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/npillmayer/schuko/schukonf/testconfig"
+	"github.com/npillmayer/tyse"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/fontregistry"
+	"github.com/npillmayer/tyse/core/locate/resources"
+	xfont "golang.org/x/image/font"
+)
+
+// --- Fonts -----------------------------------------------------------------
+
+// Fonts found in directories given by flag -fontdir are loaded into the global
+// font registry, where they take precedence over system fonts. They are
+// registered by their normalized file names, e.g. "Junicode-Bold.ttf" as
+// "junicode-bold".
+
+// loadedFonts are the font files loaded from font directories, in order of
+// loading.
+var loadedFonts []string
+
+// loadFontDirs loads the OpenType fonts contained in directories dirs.
+func loadFontDirs(dirs []string) error {
+	loaded, err := tyse.New(tyse.WithFontDirs(dirs...)).LoadFonts()
+	loadedFonts = append(loadedFonts, loaded...)
+	return err
+}
+
+// selectFont returns the font named pattern in its regular variant. Fonts
+// loaded from font directories are searched first, then fonts packaged with
+// tyse and system fonts. If pattern is empty, selectFont returns nil.
+func selectFont(pattern string) (*font.ScalableFont, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	for _, path := range loadedFonts {
+		if fontregistry.Matches(path, pattern, xfont.StyleNormal, xfont.WeightNormal) {
+			name := fontregistry.NormalizeFontname(filepath.Base(path), xfont.StyleNormal, xfont.WeightNormal)
+			if f, ok := fontregistry.GlobalRegistry().Acquire(name); ok {
+				return f, nil
+			}
+		}
+	}
+	conf := testconfig.Conf{}
+	tc, err := resources.ResolveTypeCase(conf, pattern, xfont.StyleNormal, xfont.WeightNormal, 10).TypeCase()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find font %q: %w", pattern, err)
+	}
+	tracer().Infof("using font %s", tc.ScalableFontParent().Fontname)
+	return tc.ScalableFontParent(), nil
+}
@@ -0,0 +1,214 @@
+/*
+Command tyse typesets HTML or Markdown documents into PDF or SVG.
+
+Usage:
+
+	tyse [flags] document.html|document.md
+
+Markdown documents (extension .md or .markdown) are converted to HTML first.
+The output format is selected by the extension of the output file: .pdf writes
+a single PDF document, .svg writes an SVG file per page, numbered from 1. If no
+output file is given, the document's name with extension .pdf is used.
+
+Flags are:
+
+	-o file       output file
+	-css file     stylesheet to apply; may be repeated
+	-paper size   paper size, either A4, A5, letter, legal, or width x height,
+	              e.g. "6in x 9in" (default A4)
+	-fontdir dir  directory to load fonts from; may be repeated
+	-font name    font for drawing glyphs in SVG output
+	-fontsize d   size of the font for SVG output (default 10pt)
+	-trace level  trace level [Debug|Info|Error] (default Error)
+	-timings file write timings of typesetting stages, paragraphs, pages and
+	              runs of text as JSON to file
+
+Please note that the PDF backend does not yet output text. PDF documents show
+backgrounds, borders, images and links of the typeset pages; SVG documents
+show the boxes of the pages.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
+	"github.com/npillmayer/tyse/core/spans/timings"
+	"github.com/npillmayer/tyse/engine/api"
+	"github.com/npillmayer/tyse/input/markdown"
+)
+
+// tracer traces with key 'tyse.engine'
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.engine")
+}
+
+// stringList is a flag which may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func main() {
+	var cssFiles, fontDirs stringList
+	output := flag.String("o", "", "Output file, extension .pdf or .svg")
+	flag.Var(&cssFiles, "css", "Stylesheet to apply (may be repeated)")
+	paper := flag.String("paper", "A4", "Paper size: A4, A5, letter, legal or 'width x height'")
+	flag.Var(&fontDirs, "fontdir", "Directory to load fonts from (may be repeated)")
+	fontname := flag.String("font", "", "Font for drawing glyphs in SVG output")
+	fontsize := flag.String("fontsize", "10pt", "Size of the font for SVG output")
+	tlevel := flag.String("trace", "Error", "Trace level [Debug|Info|Error]")
+	timingsFile := flag.String("timings", "", "Write timings as JSON to file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: tyse [flags] document.html|document.md\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := setupTracing(*tlevel); err != nil {
+		fmt.Fprintf(os.Stderr, "tyse: error configuring tracing: %v\n", err)
+		os.Exit(1)
+	}
+	job := &job{
+		input:    flag.Arg(0),
+		output:   *output,
+		css:      cssFiles,
+		fontDirs: fontDirs,
+		font:     *fontname,
+	}
+	var err error
+	if job.paper, err = tyse.ParsePaperSize(*paper); err != nil {
+		fail(err)
+	}
+	if job.fontsize, _, err = dimen.Parse(*fontsize); err != nil || job.fontsize <= 0 {
+		fail(fmt.Errorf("illegal font size %q", *fontsize))
+	}
+	var rec *timings.Recorder
+	if *timingsFile != "" {
+		rec = timings.NewRecorder()
+		spans.SetRecorder(rec)
+	}
+	if err = job.run(); err != nil {
+		fail(err)
+	}
+	if rec != nil {
+		spans.SetRecorder(nil)
+		if err = writeTimings(rec, *timingsFile); err != nil {
+			fail(err)
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "tyse: %v\n", err)
+	os.Exit(1)
+}
+
+// setupTracing sets all tracers to level.
+func setupTracing(level string) error {
+	return tyse.New(tyse.WithTraceLevel(level)).SetupTracing()
+}
+
+// writeTimings writes the timings recorded by rec to file filename.
+func writeTimings(rec *timings.Recorder, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("cannot create timings file: %w", err)
+	}
+	if err = rec.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// job is a document to typeset.
+type job struct {
+	input    string      // name of the input file
+	output   string      // name of the output file
+	css      []string    // stylesheet files
+	paper    dimen.Point // paper size
+	fontDirs []string    // directories to load fonts from
+	font     string      // font for SVG output
+	fontsize dimen.DU    // font size for SVG output
+}
+
+// run typesets the document and writes the pages to the output file.
+func (job *job) run() error {
+	if job.output == "" {
+		job.output = strings.TrimSuffix(job.input, filepath.Ext(job.input)) + ".pdf"
+	}
+	format := strings.ToLower(filepath.Ext(job.output))
+	if format != ".pdf" && format != ".svg" {
+		return fmt.Errorf("unsupported output format %q, expected .pdf or .svg", format)
+	}
+	if err := loadFontDirs(job.fontDirs); err != nil {
+		return err
+	}
+	opts := []api.Option{api.WithPaperSize(job.paper)}
+	for _, name := range job.css {
+		css, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("cannot read stylesheet: %w", err)
+		}
+		opts = append(opts, api.WithCSS(string(css)))
+	}
+	doc, err := job.document()
+	if err != nil {
+		return err
+	}
+	tracer().Infof("typesetting %s", job.input)
+	pages, err := api.New(opts...).TypesetHTML(doc)
+	if err != nil {
+		return err
+	}
+	tracer().Infof("typeset %d pages", len(pages))
+	if format == ".svg" {
+		f, err := selectFont(job.font)
+		if err != nil {
+			return err
+		}
+		return writeSVG(pages, job.output, f, job.fontsize)
+	}
+	return writePDF(pages, job.output, job.paper)
+}
+
+// document returns the input document as HTML.
+func (job *job) document() (io.Reader, error) {
+	src, err := os.ReadFile(job.input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read document: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(job.input)) {
+	case ".md", ".markdown":
+		h, err := markdown.ToHTML(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(h), nil
+	}
+	return bytes.NewReader(src), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/npillmayer/tyse/backend/print/pdf"
+	"github.com/npillmayer/tyse/backend/print/svg"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/engine/api"
+)
+
+// --- Output ----------------------------------------------------------------
+
+// writePDF prints pages into PDF file filename.
+func writePDF(pages []*api.Page, filename string, papersize dimen.Point) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+	defer f.Close()
+	printer := pdf.NewPrinter(papersize, 1.0)
+	done := printer.Start(f)
+	printer.SetMaxPage(len(pages))
+	for _, page := range pages {
+		printer.PrintPage(page.Number, dimen.Rect{BotR: page.Size}, renderTree(page))
+	}
+	if err = done(); err != nil {
+		return err
+	}
+	tracer().Infof("wrote %d pages to %s", len(pages), filename)
+	return f.Close()
+}
+
+// renderTree converts the content of a page to the content of a PDF page.
+func renderTree(page *api.Page) *pdf.RenderTree {
+	tree := &pdf.RenderTree{}
+	for _, d := range page.Decorations {
+		deco := pdf.Decoration{
+			Border:      d.Frame,
+			Widths:      d.Widths,
+			Background:  d.Background,
+			BorderColor: d.BorderColor,
+			Radius:      d.Radius,
+		}
+		if d.Image != nil { // drawn in its natural size, 1px = 1bp
+			size := d.Image.Bounds().Size()
+			botR := d.Padding.TopL
+			botR.Shift(dimen.Point{X: dimen.DU(size.X) * dimen.PX, Y: dimen.DU(size.Y) * dimen.PX})
+			deco.Image = &pdf.Image{
+				Rect: dimen.Rect{TopL: d.Padding.TopL, BotR: botR},
+				Img:  d.Image,
+			}
+		}
+		switch {
+		case d.Dashed:
+			deco.LineStyle = pdf.Dashed
+		case d.Dotted:
+			deco.LineStyle = pdf.Dotted
+		}
+		tree.Decorations = append(tree.Decorations, deco)
+	}
+	for _, img := range page.Images {
+		if img.Image == nil { // SVG images are not supported by the PDF backend
+			tracer().Infof("page %d: skipping SVG image", page.Number)
+			continue
+		}
+		tree.Images = append(tree.Images, pdf.Image{Rect: img.Frame, Img: img.Image})
+	}
+	for _, link := range page.Links {
+		l := pdf.Link{Rect: link.Frame, PageNo: link.Target, Dest: link.Pos}
+		if link.Target == 0 {
+			l.URI = link.Href
+		}
+		tree.Links = append(tree.Links, l)
+	}
+	return tree
+}
+
+// writeSVG draws pages as SVG. A single page is written to filename, more than
+// one page to files with the page number appended to the name, e.g. "doc-1.svg".
+func writeSVG(pages []*api.Page, filename string, f *font.ScalableFont, size dimen.DU) error {
+	r := svg.NewRenderer(f, size)
+	for _, page := range pages {
+		name := filename
+		if len(pages) > 1 {
+			ext := filepath.Ext(filename)
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), page.Number, ext)
+		}
+		out, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		err = r.Page(page, out)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		tracer().Infof("wrote page %d to %s", page.Number, name)
+	}
+	return nil
+}
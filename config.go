@@ -0,0 +1,405 @@
+package tyse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/schuko"
+	"github.com/npillmayer/schuko/schukonf/testconfig"
+	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/schuko/tracing/gologadapter"
+	"github.com/npillmayer/schuko/tracing/trace2go"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/fontregistry"
+	"github.com/npillmayer/tyse/core/hyphenation"
+	"github.com/npillmayer/tyse/core/locate"
+	params "github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/api"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	"github.com/npillmayer/tyse/engine/glyphing/harfbuzz"
+	"github.com/npillmayer/tyse/engine/glyphing/monospace"
+	xfont "golang.org/x/image/font"
+)
+
+// Shaping engines to select with WithShaper.
+const (
+	ShaperHarfbuzz  = "harfbuzz"  // OpenType shaping with HarfBuzz (default)
+	ShaperMonospace = "monospace" // fixed-width cells, e.g. for terminal output
+)
+
+// shapingCacheSize is the number of runs of text a shaper remembers.
+const shapingCacheSize = 1024
+
+// TraceKeys are the keys of the tracers of the engine's packages.
+var TraceKeys = []string{
+	"tyse.backend", "tyse.core", "tyse.dom", "tyse.engine", "tyse.font", "tyse.fonts",
+	"tyse.frame", "tyse.frame.box", "tyse.frame.tree", "tyse.glyphs", "tyse.input",
+	"tyse.khipu", "tyse.resources",
+}
+
+// Config holds the configuration of the engine. Create a configuration with New.
+type Config struct {
+	FontDirs    []string            // directories to load fonts from
+	Language    string              // default language of text, e.g. "en_US"
+	Patterns    string              // directory of hyphenation patterns; empty for $TYSEROOT/pattern
+	Shaper      string              // shaping engine, see ShaperHarfbuzz etc.
+	TraceLevel  string              // trace level for all tracers [Debug|Info|Error]
+	TraceLevels map[string]string   // trace levels for individual tracers, overriding TraceLevel
+	PaperSize   dimen.Point         // default paper size
+	FontSize    dimen.DU            // default font size
+	Exceptions  map[string][]string // user hyphenation exceptions by language, e.g. "manu-script"
+	err         error               // error from applying options
+}
+
+// Option configures an engine configuration.
+type Option func(*Config)
+
+// New creates a configuration. Without options, text is English, shaped with
+// HarfBuzz at 10pt, pages are DIN A4 and tracers report errors only.
+func New(opts ...Option) *Config {
+	conf := &Config{
+		Language:   "en_EN",
+		Shaper:     ShaperHarfbuzz,
+		TraceLevel: "Error",
+		PaperSize:  dimen.DINA4,
+		FontSize:   10 * dimen.PT,
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+// Err returns the first error from applying options, if any.
+func (conf *Config) Err() error {
+	return conf.err
+}
+
+func (conf *Config) fail(err error) {
+	if conf.err == nil {
+		conf.err = err
+	}
+}
+
+// WithFontDirs adds directories to load fonts from.
+func WithFontDirs(dirs ...string) Option {
+	return func(conf *Config) {
+		conf.FontDirs = append(conf.FontDirs, dirs...)
+	}
+}
+
+// WithLanguage sets the default language of text, which selects hyphenation
+// patterns and line breaking rules.
+func WithLanguage(lang string) Option {
+	return func(conf *Config) {
+		conf.Language = lang
+	}
+}
+
+// WithPatterns sets the directory to load hyphenation patterns from.
+func WithPatterns(dir string) Option {
+	return func(conf *Config) {
+		conf.Patterns = dir
+	}
+}
+
+// WithHyphenationExceptions adds user hyphenation exceptions for a language, e.g.
+// "manu-script". They override the results of hyphenation patterns.
+func WithHyphenationExceptions(lang string, words ...string) Option {
+	return func(conf *Config) {
+		if conf.Exceptions == nil {
+			conf.Exceptions = make(map[string][]string)
+		}
+		conf.Exceptions[lang] = append(conf.Exceptions[lang], words...)
+	}
+}
+
+// WithShaper selects the shaping engine, either ShaperHarfbuzz or ShaperMonospace.
+func WithShaper(name string) Option {
+	return func(conf *Config) {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case ShaperHarfbuzz, ShaperMonospace:
+			conf.Shaper = name
+		default:
+			conf.fail(fmt.Errorf("unknown shaping engine %q", name))
+		}
+	}
+}
+
+// WithTraceLevel sets the trace level [Debug|Info|Error] of tracers keys. Without
+// keys, the level applies to all tracers not configured otherwise.
+func WithTraceLevel(level string, keys ...string) Option {
+	return func(conf *Config) {
+		if len(keys) == 0 {
+			conf.TraceLevel = level
+			return
+		}
+		if conf.TraceLevels == nil {
+			conf.TraceLevels = make(map[string]string)
+		}
+		for _, key := range keys {
+			conf.TraceLevels[key] = level
+		}
+	}
+}
+
+// WithPaperSize sets the default paper size of pages.
+func WithPaperSize(size dimen.Point) Option {
+	return func(conf *Config) {
+		conf.PaperSize = size
+	}
+}
+
+// WithFontSize sets the default font size.
+func WithFontSize(size dimen.DU) Option {
+	return func(conf *Config) {
+		conf.FontSize = size
+	}
+}
+
+// --- Adapters --------------------------------------------------------------
+
+// FromConfiguration reads configuration values from an application configuration.
+// Keys are
+//
+//	fontdirs     list of directories to load fonts from, separated by the OS's path list separator
+//	language     default language of text
+//	patterns     directory of hyphenation patterns
+//	exceptions   user hyphenation exceptions for the default language, separated by spaces
+//	shaper       shaping engine, "harfbuzz" or "monospace"
+//	tracelevel   trace level of all tracers
+//	trace.<key>  trace level of tracer <key>, e.g. "trace.tyse.fonts"
+//	paper        paper size, either A4, A5, letter, legal, or width x height
+//	fontsize     default font size, e.g. "11pt"
+//
+// Keys not set leave the configuration untouched.
+func FromConfiguration(c schuko.Configuration) Option {
+	return func(conf *Config) {
+		if c == nil {
+			return
+		}
+		if c.IsSet("fontdirs") {
+			conf.FontDirs = append(conf.FontDirs, filepath.SplitList(c.GetString("fontdirs"))...)
+		}
+		if c.IsSet("language") {
+			conf.Language = c.GetString("language")
+		}
+		if c.IsSet("patterns") {
+			conf.Patterns = c.GetString("patterns")
+		}
+		if c.IsSet("exceptions") {
+			WithHyphenationExceptions(conf.Language, strings.Fields(c.GetString("exceptions"))...)(conf)
+		}
+		if c.IsSet("shaper") {
+			WithShaper(c.GetString("shaper"))(conf)
+		}
+		if c.IsSet("tracelevel") {
+			conf.TraceLevel = c.GetString("tracelevel")
+		}
+		for _, key := range TraceKeys {
+			if c.IsSet("trace." + key) {
+				WithTraceLevel(c.GetString("trace."+key), key)(conf)
+			}
+		}
+		if c.IsSet("paper") {
+			size, err := ParsePaperSize(c.GetString("paper"))
+			if err != nil {
+				conf.fail(err)
+			} else {
+				conf.PaperSize = size
+			}
+		}
+		if c.IsSet("fontsize") {
+			size, percent, err := dimen.Parse(c.GetString("fontsize"))
+			if err != nil || percent || size <= 0 {
+				conf.fail(fmt.Errorf("illegal font size %q", c.GetString("fontsize")))
+			} else {
+				conf.FontSize = size
+			}
+		}
+	}
+}
+
+// FromEnvironment reads configuration values from environment variables. Variables
+// are named after the keys of FromConfiguration, in upper case, with dots replaced
+// by underscores and prefixed by TYSE_, e.g. TYSE_FONTDIRS or TYSE_TRACE_TYSE_FONTS.
+func FromEnvironment() Option {
+	return FromConfiguration(envConfig{})
+}
+
+// envConfig is a schuko.Configuration reading environment variables.
+type envConfig struct{}
+
+func envKey(key string) string {
+	return "TYSE_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func (envConfig) InitDefaults() {}
+
+func (envConfig) IsSet(key string) bool {
+	_, ok := os.LookupEnv(envKey(key))
+	return ok
+}
+
+func (envConfig) GetString(key string) string {
+	return os.Getenv(envKey(key))
+}
+
+func (envConfig) GetInt(key string) int {
+	n, _ := strconv.Atoi(os.Getenv(envKey(key)))
+	return n
+}
+
+func (envConfig) GetBool(key string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(envKey(key)))
+	return b
+}
+
+func (envConfig) IsInteractive() bool { return false }
+
+// ParsePaperSize parses a paper size, either A4, A5, letter, legal, or width x
+// height, e.g. "6in x 9in".
+func ParsePaperSize(s string) (dimen.Point, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "a4":
+		return dimen.DINA4, nil
+	case "a5":
+		return dimen.DINA5, nil
+	case "letter":
+		return dimen.USLetter, nil
+	case "legal":
+		return dimen.USLegal, nil
+	}
+	wh := strings.Fields(s)
+	if len(wh) != 3 || strings.ToLower(wh[1]) != "x" {
+		return dimen.Point{}, fmt.Errorf("illegal paper size %q", s)
+	}
+	var size [2]dimen.DU
+	for i, d := range []string{wh[0], wh[2]} {
+		var percent bool
+		var err error
+		if size[i], percent, err = dimen.Parse(d); err != nil || percent || size[i] <= 0 {
+			return dimen.Point{}, fmt.Errorf("illegal paper size %q", s)
+		}
+	}
+	return dimen.Point{X: size[0], Y: size[1]}, nil
+}
+
+// --- Applying the configuration --------------------------------------------
+
+// Setup configures the engine's packages: it sets up tracing, sets up hyphenation
+// and loads fonts from the font directories.
+func (conf *Config) Setup() error {
+	if conf.err != nil {
+		return conf.err
+	}
+	if err := conf.SetupTracing(); err != nil {
+		return err
+	}
+	conf.SetupHyphenation()
+	_, err := conf.LoadFonts()
+	return err
+}
+
+// SetupHyphenation sets the location of hyphenation patterns and adds the user
+// hyphenation exceptions.
+func (conf *Config) SetupHyphenation() {
+	locate.SetPatternDir(conf.Patterns)
+	for lang, words := range conf.Exceptions {
+		hyphenation.AddExceptions(lang, words...)
+	}
+}
+
+// SetupTracing sets the levels of all tracers, logging with the Go standard log
+// package.
+func (conf *Config) SetupTracing() error {
+	tracing.RegisterTraceAdapter("go", gologadapter.GetAdapter(), false)
+	tc := testconfig.Conf{"tracing.adapter": "go"}
+	for _, key := range TraceKeys {
+		tc["trace."+key] = conf.TraceLevel
+	}
+	for key, level := range conf.TraceLevels {
+		tc["trace."+key] = level
+	}
+	if err := trace2go.ConfigureRoot(tc, "trace", trace2go.ReplaceTracers(true)); err != nil {
+		return err
+	}
+	tracing.SetTraceSelector(trace2go.Selector())
+	return nil
+}
+
+// LoadFonts loads the OpenType fonts contained in the font directories into the
+// global font registry, where they take precedence over system fonts. Fonts are
+// registered by their normalized file names, e.g. "Junicode-Bold.ttf" as
+// "junicode-bold". LoadFonts returns the paths of the fonts loaded, in order.
+func (conf *Config) LoadFonts() ([]string, error) {
+	return conf.LoadFontsContext(context.Background())
+}
+
+// LoadFontsContext is like LoadFonts, but stops loading fonts if ctx is cancelled.
+// The fonts loaded so far are returned, together with the error of ctx.
+func (conf *Config) LoadFontsContext(ctx context.Context) ([]string, error) {
+	var loaded []string
+	for _, dir := range conf.FontDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return loaded, fmt.Errorf("cannot read font directory: %w", err)
+		}
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return loaded, err
+			}
+			if entry.IsDir() || !isFontFile(entry.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			f, err := font.LoadOpenTypeFont(path)
+			if err != nil {
+				tracer().Errorf("cannot load font %s: %v", path, err)
+				continue
+			}
+			name := fontregistry.NormalizeFontname(entry.Name(), xfont.StyleNormal, xfont.WeightNormal)
+			f.Fontname = name
+			fontregistry.GlobalRegistry().StoreFont(name, f)
+			loaded = append(loaded, path)
+			tracer().Infof("loaded font %s", path)
+		}
+	}
+	return loaded, nil
+}
+
+func isFontFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ttf", ".otf":
+		return true
+	}
+	return false
+}
+
+// Registers returns typesetting registers with the configured defaults.
+func (conf *Config) Registers() *params.TypesettingRegisters {
+	regs := params.NewTypesettingRegisters()
+	regs.Push(params.P_LANGUAGE, conf.Language)
+	return regs
+}
+
+// NewShaper returns a shaper of the configured shaping engine. Shapers for
+// HarfBuzz cache shaped runs of text and fall back to fonts of the global font
+// registry for glyphs missing in a font.
+func (conf *Config) NewShaper() glyphing.Shaper {
+	if conf.Shaper == ShaperMonospace {
+		return monospace.Shaper(conf.FontSize, nil)
+	}
+	return glyphing.FallbackShaper(harfbuzz.CachingShaper(shapingCacheSize), fontregistry.GlobalRegistry())
+}
+
+// EngineOptions returns options for an engine of package api, following the
+// configuration.
+func (conf *Config) EngineOptions() []api.Option {
+	return []api.Option{api.WithPaperSize(conf.PaperSize)}
+}
@@ -0,0 +1,75 @@
+package tyse
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/schukonf/testconfig"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/hyphenation"
+	params "github.com/npillmayer/tyse/core/parameters"
+)
+
+func TestConfigOptions(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	conf := New(
+		WithFontDirs("fonts"),
+		WithLanguage("de_DE"),
+		WithShaper("Monospace"),
+		WithTraceLevel("Debug", "tyse.fonts"),
+		WithPaperSize(dimen.DINA5),
+	)
+	if conf.Err() != nil {
+		t.Fatal(conf.Err())
+	}
+	if len(conf.FontDirs) != 1 || conf.Shaper != ShaperMonospace || conf.PaperSize != dimen.DINA5 {
+		t.Errorf("unexpected configuration %+v", conf)
+	}
+	if conf.TraceLevel != "Error" || conf.TraceLevels["tyse.fonts"] != "Debug" {
+		t.Errorf("expected tracer tyse.fonts to be set to Debug, others to Error")
+	}
+	if lang := conf.Registers().S(params.P_LANGUAGE); lang != "de_DE" {
+		t.Errorf("expected registers to have language de_DE, have %q", lang)
+	}
+	conf = New(WithHyphenationExceptions("en", "manu-script"))
+	conf.SetupHyphenation()
+	x := hyphenation.LanguageOptions("en_US").Exceptions
+	if s, ok := x.Hyphenate("manuscript"); !ok || len(s) != 2 {
+		t.Errorf("expected user exception manu-script to be set up, have %v", s)
+	}
+	if conf = New(WithShaper("typewriter")); conf.Err() == nil {
+		t.Errorf("expected unknown shaping engine to be rejected")
+	}
+}
+
+func TestConfigAdapters(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	conf := New(FromConfiguration(testconfig.Conf{
+		"language":         "fr_FR",
+		"paper":            "6in x 9in",
+		"fontsize":         "11pt",
+		"trace.tyse.fonts": "Info",
+	}))
+	if conf.Err() != nil {
+		t.Fatal(conf.Err())
+	}
+	if conf.Language != "fr_FR" || conf.PaperSize.X != 6*dimen.IN || conf.FontSize != 11*dimen.PT {
+		t.Errorf("unexpected configuration %+v", conf)
+	}
+	if conf.TraceLevels["tyse.fonts"] != "Info" {
+		t.Errorf("expected tracer tyse.fonts to be set to Info")
+	}
+	if conf = New(FromConfiguration(testconfig.Conf{"paper": "B7"})); conf.Err() == nil {
+		t.Errorf("expected illegal paper size to be rejected")
+	}
+	t.Setenv("TYSE_LANGUAGE", "nl_NL")
+	t.Setenv("TYSE_TRACE_TYSE_KHIPU", "Debug")
+	conf = New(WithLanguage("en_US"), FromEnvironment())
+	if conf.Language != "nl_NL" || conf.TraceLevels["tyse.khipu"] != "Debug" {
+		t.Errorf("expected environment to override language and trace level, is %+v", conf)
+	}
+}
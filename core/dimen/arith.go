@@ -0,0 +1,135 @@
+package dimen
+
+import "math"
+
+// Arithmetic on dimensions saturates: results exceeding the range of DU are
+// clamped to ±Infinity instead of wrapping around.
+
+// Rounding is a rounding mode for operations on dimensions which cannot be
+// carried out exactly, e.g. scaling or aligning to a grid.
+type Rounding uint8
+
+// Rounding modes. RoundNearest rounds halfway cases away from zero.
+const (
+	RoundNearest    Rounding = iota // round to the nearest value
+	RoundDown                       // round towards negative infinity
+	RoundUp                         // round towards positive infinity
+	RoundTowardZero                 // truncate
+)
+
+// Add returns d+e, saturating at ±Infinity.
+func (d DU) Add(e DU) DU {
+	return saturate(int64(d) + int64(e))
+}
+
+// Sub returns d−e, saturating at ±Infinity.
+func (d DU) Sub(e DU) DU {
+	return saturate(int64(d) - int64(e))
+}
+
+// Mul returns d·n, saturating at ±Infinity.
+func (d DU) Mul(n int64) DU {
+	if abs64(n) > Infinity {
+		n = signum64(n) * (Infinity + 1) // saturates anyway; prevents overflow of int64
+	}
+	return saturate(int64(d) * n)
+}
+
+// Scale returns d·num/den, rounded to the nearest value and saturating at
+// ±Infinity. This is the way to multiply by a fraction, e.g. 2/3, without
+// intermediate overflow.
+func (d DU) Scale(num, den int64) DU {
+	return d.ScaleRounded(num, den, RoundNearest)
+}
+
+// ScaleRounded returns d·num/den, rounded with a given rounding mode and
+// saturating at ±Infinity. A denominator of zero results in ±Infinity, or 0
+// if the numerator is zero as well.
+func (d DU) ScaleRounded(num, den int64, mode Rounding) DU {
+	if num == 0 || d == 0 {
+		return 0
+	}
+	if den == 0 {
+		return saturate(signum64(int64(d)*signum64(num)) * math.MaxInt64)
+	}
+	if abs64(num) > math.MaxInt32 { // make d·num fit into int64
+		return FromFloat(float64(d) * float64(num) / float64(den))
+	}
+	return saturate(divRounded(int64(d)*num, den, mode))
+}
+
+// Round aligns d to a grid of size grid, e.g. to a pixel grid of a device.
+// For a grid size ≤ 0, d is returned unchanged.
+func (d DU) Round(grid DU, mode Rounding) DU {
+	if grid <= 0 {
+		return d
+	}
+	return saturate(divRounded(int64(d), int64(grid), mode) * int64(grid))
+}
+
+// FromFloat converts a float, in scaled points, to a dimension. It rounds to the
+// nearest value and saturates at ±Infinity. NaN results in 0.
+func FromFloat(x float64) DU {
+	switch {
+	case math.IsNaN(x):
+		return 0
+	case x >= Infinity:
+		return Infinity
+	case x <= -Infinity:
+		return -Infinity
+	}
+	return DU(math.Round(x))
+}
+
+// divRounded returns x/y, rounded with rounding mode mode.
+func divRounded(x, y int64, mode Rounding) int64 {
+	if y < 0 {
+		x, y = -x, -y
+	}
+	q, r := x/y, x%y // truncated towards zero
+	if r == 0 {
+		return q
+	}
+	switch mode {
+	case RoundDown:
+		if x < 0 {
+			q--
+		}
+	case RoundUp:
+		if x > 0 {
+			q++
+		}
+	case RoundNearest:
+		if 2*abs64(r) >= y {
+			q += signum64(x)
+		}
+	}
+	return q
+}
+
+func saturate(x int64) DU {
+	switch {
+	case x > Infinity:
+		return Infinity
+	case x < -Infinity:
+		return -Infinity
+	}
+	return DU(x)
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func signum64(x int64) int64 {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
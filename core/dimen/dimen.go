@@ -17,6 +17,7 @@ import (
 	"math"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Online dimension conversion for print:
@@ -98,43 +99,68 @@ func (r Rect) Height() DU {
 
 // ---------------------------------------------------------------------------
 
-var dimenPattern = regexp.MustCompile(`^([+\-]?[0-9]+)(%|[cminpxtc]{2})?$`)
+var dimenPattern = regexp.MustCompile(`^([+\-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+))(%|[a-zA-Z]{2})?$`)
+
+// units maps unit strings to dimensions.
+var units = map[string]DU{
+	"sp": SP,
+	"bp": BP,
+	"px": PX,
+	"pt": PT,
+	"pc": 12 * PT,
+	"mm": MM,
+	"cm": CM,
+	"in": IN,
+}
+
+// UnitOf returns the dimension of a unit given as a string, e.g. "pt".
+// Units are case-insensitive.
+func UnitOf(unit string) (DU, bool) {
+	u, ok := units[strings.ToLower(unit)]
+	return u, ok
+}
 
-// Parse parses a string to return a dimension. Syntax is CSS Unit.
-// If a percentage value is given (`80%`), the second return value will be true.
+// Parse parses a string to return a dimension. Syntax is CSS Unit, with values
+// optionally having a fractional part, e.g. `3.5mm`. A number without a unit
+// is in scaled points.
+// If a percentage value is given (`80%`), the second return value will be true
+// and the dimension will be the percentage value, rounded to an integer.
 //
 func Parse(s string) (DU, bool, error) {
 	d := dimenPattern.FindStringSubmatch(s)
 	if len(d) < 2 {
 		return 0, false, errors.New("format error parsing dimension")
 	}
-	scale := SP
-	ispcnt := false
-	if len(d) > 2 {
-		switch d[2] {
-		case "pt", "PT":
-			scale = PT
-		case "mm", "MM":
-			scale = MM
-		case "bp", "px", "BP", "PX":
-			scale = BP
-		case "cm", "CM":
-			scale = CM
-		case "in", "IN":
-			scale = IN
-		case "sp", "SP", "":
-			scale = SP
-		case "%":
-			scale, ispcnt = 1, true
-		default:
-			return 0, false, errors.New("format error parsing dimension")
-		}
-	}
-	n, err := strconv.Atoi(d[1])
+	x, err := strconv.ParseFloat(d[1], 64)
 	if err != nil {
 		return 0, false, errors.New("format error parsing dimension")
 	}
-	return DU(n) * scale, ispcnt, nil
+	switch d[2] {
+	case "":
+		return FromFloat(x), false, nil
+	case "%":
+		return FromFloat(x), true, nil
+	}
+	scale, ok := UnitOf(d[2])
+	if !ok {
+		return 0, false, errors.New("format error parsing dimension")
+	}
+	return FromFloat(x * float64(scale)), false, nil
+}
+
+// Format formats a dimension in a given unit, e.g. "3.5mm", with at most
+// 3 decimal places. For unknown units, the dimension is formatted in scaled points.
+func (d DU) Format(unit string) string {
+	scale, ok := UnitOf(unit)
+	if !ok || scale == SP {
+		return fmt.Sprintf("%dsp", int32(d))
+	}
+	x := strconv.FormatFloat(float64(d)/float64(scale), 'f', 3, 64)
+	x = strings.TrimRight(strings.TrimRight(x, "0"), ".")
+	if x == "-0" {
+		x = "0"
+	}
+	return x + strings.ToLower(unit)
 }
 
 // ---------------------------------------------------------------------------
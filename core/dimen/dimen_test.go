@@ -1,6 +1,7 @@
 package dimen
 
 import (
+	"math"
 	"testing"
 
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
@@ -31,3 +32,97 @@ func TestParseDimen(t *testing.T) {
 		t.Errorf("(3) expected percentage-marker to be true, is %v", ispcnt)
 	}
 }
+
+func TestSaturatingArithmetic(t *testing.T) {
+	if d := DU(Infinity - 10).Add(20); d != Infinity {
+		t.Errorf("expected addition to saturate at infinity, is %d", d)
+	}
+	if d := DU(-Infinity + 10).Sub(20); d != -Infinity {
+		t.Errorf("expected subtraction to saturate at -infinity, is %d", d)
+	}
+	if d := (200 * MM).Mul(100); d != Infinity {
+		t.Errorf("expected multiplication to saturate at infinity, is %d", d)
+	}
+	if d := (-MM).Mul(math.MaxInt64); d != -Infinity {
+		t.Errorf("expected multiplication to saturate at -infinity, is %d", d)
+	}
+	if d := (300 * MM).Scale(50, 100); d != 150*MM {
+		t.Errorf("expected 50%% of 300mm not to overflow, is %d", d)
+	}
+	if d := DU(10).Scale(2, 3); d != 7 {
+		t.Errorf("expected 10·2/3 to round to 7, is %d", d)
+	}
+	if d := DU(10).ScaleRounded(2, 3, RoundDown); d != 6 {
+		t.Errorf("expected 10·2/3 to round down to 6, is %d", d)
+	}
+	if d := PT.Scale(1, 0); d != Infinity {
+		t.Errorf("expected division by zero to saturate, is %d", d)
+	}
+}
+
+func TestRounding(t *testing.T) {
+	for i, test := range []struct {
+		d, grid DU
+		mode    Rounding
+		result  DU
+	}{
+		{15, 10, RoundNearest, 20},
+		{14, 10, RoundNearest, 10},
+		{-15, 10, RoundNearest, -20},
+		{-14, 10, RoundDown, -20},
+		{14, 10, RoundDown, 10},
+		{-14, 10, RoundUp, -10},
+		{11, 10, RoundUp, 20},
+		{-19, 10, RoundTowardZero, -10},
+		{20, 10, RoundUp, 20},
+		{7, 0, RoundUp, 7},
+	} {
+		if r := test.d.Round(test.grid, test.mode); r != test.result {
+			t.Errorf("test %d: expected %d rounded to %d to be %d, is %d", i, test.d, test.grid, test.result, r)
+		}
+	}
+	if d := (10*PX + PX/3).Round(PX, RoundNearest); d != 10*PX {
+		t.Errorf("expected dimension to snap to pixel grid, is %d", d)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, test := range []struct {
+		s string
+		d DU
+	}{
+		{"2.5cm", 25 * MM},
+		{"-.5in", -IN / 2},
+		{"12PT", 12 * PT},
+		{"1pc", 12 * PT},
+		{"100", 100},
+	} {
+		d, ispcnt, err := Parse(test.s)
+		if err != nil || ispcnt || d != test.d {
+			t.Errorf("expected %q to parse to %d, is %d (%v)", test.s, test.d, d, err)
+		}
+	}
+	if d, ispcnt, err := Parse("12.5%"); err != nil || !ispcnt || d != 13 {
+		t.Errorf("expected 12.5%% to parse to a percentage of 13, is %d", d)
+	}
+	for _, s := range []string{"", "mm", "1.2.3mm", "3furlongs"} {
+		if _, _, err := Parse(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+	for _, test := range []struct {
+		d      DU
+		unit   string
+		result string
+	}{
+		{12 * PT, "pt", "12pt"},
+		{MM * 7 / 2, "mm", "3.5mm"},
+		{-IN / 4, "in", "-0.25in"},
+		{BP, "furlong", "65536sp"},
+		{0, "cm", "0cm"},
+	} {
+		if s := test.d.Format(test.unit); s != test.result {
+			t.Errorf("expected %d formatted in %s to be %q, is %q", test.d, test.unit, test.result, s)
+		}
+	}
+}
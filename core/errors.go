@@ -15,6 +15,20 @@ const (
 	EINTERNAL   int = 125 // internal error
 )
 
+// Error domains. Errors created with ErrorIn or WrapErrorIn belong to a domain,
+// which clients may test for with errors.Is:
+//
+//	if errors.Is(err, core.ErrTableMissing) {
+//	    … // fall back to a simpler layout
+//	}
+var (
+	ErrFontFormat   = errors.New("font format error")      // font binary is corrupt
+	ErrTableMissing = errors.New("font table missing")     // font lacks a (required) table
+	ErrUnsupported  = errors.New("unsupported feature")    // valid, but not supported (yet)
+	ErrResource     = errors.New("resource not available") // resource cannot be located or loaded
+	ErrShaping      = errors.New("text shaping error")     // text cannot be shaped
+)
+
 func errorText(ecode int) string {
 	switch ecode {
 	case NOERROR:
@@ -40,14 +54,20 @@ type AppError interface {
 
 type coreError struct {
 	error
-	code int
-	msg  string
+	code   int
+	msg    string
+	domain error // error domain, e.g. ErrFontFormat; may be nil
 }
 
 func (e coreError) Unwrap() error {
 	return e.error
 }
 
+// Is reports whether e belongs to error domain target.
+func (e coreError) Is(target error) bool {
+	return e.domain != nil && e.domain == target
+}
+
 func (e coreError) Error() string {
 	return fmt.Sprintf("[%d] %v", e.code, e.error)
 }
@@ -68,7 +88,7 @@ func ErrorWithCode(err error, code int) error {
 	if err == nil {
 		err = errors.New(errorText(code))
 	}
-	return coreError{err, code, errorText(code)}
+	return coreError{err, code, errorText(code), nil}
 }
 
 // WrapError wraps an error in a core error, featuring an error code and
@@ -79,7 +99,38 @@ func WrapError(err error, code int, format string, v ...interface{}) error {
 		err = errors.New(errorText(code))
 	}
 	msg := fmt.Sprintf(format, v...)
-	return coreError{err, code, msg}
+	return coreError{err, code, msg, nil}
+}
+
+// WrapErrorIn wraps an error in a core error belonging to an error domain,
+// featuring an error code and a user message. Both err and domain will match
+// with errors.Is.
+// If err is nil, WrapErrorIn is equivalent to ErrorIn.
+func WrapErrorIn(err error, domain error, code int, format string, v ...interface{}) error {
+	if err == nil {
+		return ErrorIn(domain, code, format, v...)
+	}
+	msg := fmt.Sprintf(format, v...)
+	return coreError{err, code, msg, domain}
+}
+
+// ErrorIn creates an error belonging to an error domain, featuring an error code
+// and a user-message.
+func ErrorIn(domain error, code int, format string, v ...interface{}) error {
+	return coreError{domain, code, fmt.Sprintf(format, v...), domain}
+}
+
+// Domain returns the error domain of err, i.e. one of ErrFontFormat,
+// ErrTableMissing, ErrUnsupported, ErrResource or ErrShaping. If err does
+// not belong to any of these domains, nil is returned.
+func Domain(err error) error {
+	for _, domain := range []error{ErrFontFormat, ErrTableMissing, ErrUnsupported,
+		ErrResource, ErrShaping} {
+		if errors.Is(err, domain) {
+			return domain
+		}
+	}
+	return nil
 }
 
 // Code returns the status code associated with an error.
@@ -114,6 +165,7 @@ func Error(code int, format string, v ...interface{}) error {
 		errors.New(errorText(code)),
 		code,
 		fmt.Sprintf(format, v...),
+		nil,
 	}
 }
 
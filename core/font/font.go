@@ -40,11 +40,13 @@ Copyright © 2017–2021 Norbert Pillmayer <norbert@pillmayer.com>
 package font
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sync"
 
 	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse/core/spans"
 	xfont "golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/opentype"
@@ -105,9 +107,13 @@ func LoadOpenTypeFont(fontfile string) (*ScalableFont, error) {
 
 // ParseOpenTypeFont loads an OpenType font (TTF or OTF) from memory.
 func ParseOpenTypeFont(fbytes []byte) (f *ScalableFont, err error) {
+	_, span := spans.Start(context.Background(), spans.Stage, "font.ParseOpenTypeFont")
+	defer span.End()
+	span.SetAttribute("bytes", len(fbytes))
 	f = &ScalableFont{Binary: fbytes}
 	f.SFNT, err = sfnt.Parse(f.Binary)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
 	if f.Fontname, err = f.SFNT.Name(nil, sfnt.NameIDFull); err == nil {
@@ -139,6 +145,16 @@ func (sf *ScalableFont) PrepareCase(fontsize float32) (*TypeCase, error) {
 	return typecase, err
 }
 
+// HasGlyph returns true if the font maps code-point r to a glyph, i.e., if a
+// lookup of r in table cmap does not yield .notdef.
+func (sf *ScalableFont) HasGlyph(r rune) bool {
+	if sf == nil || sf.SFNT == nil {
+		return false
+	}
+	gid, err := sf.SFNT.GlyphIndex(nil, r)
+	return err == nil && gid != 0
+}
+
 // ScalableFontParent returns the unscaled font a typecase has been derived from.
 func (tc *TypeCase) ScalableFontParent() *ScalableFont {
 	return tc.scalableFontParent
@@ -2,11 +2,13 @@ package fontregistry
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
 	"github.com/npillmayer/tyse/core/font"
 	xfont "golang.org/x/image/font"
+	"golang.org/x/text/language"
 )
 
 type sw struct {
@@ -61,7 +63,7 @@ func TestNormalizeFont(t *testing.T) {
 
 func TestOpenOpenTypeCaseCreation(t *testing.T) {
 	//fontpath := locate.FileResource("GentiumPlus-R.ttf", "font")
-	fontpath := "../locate/resources/packaged/fonts/GentiumPlus-R.ttf"
+	fontpath := "../../locate/resources/packaged/fonts/GentiumPlus-R.ttf"
 	f, err := font.LoadOpenTypeFont(fontpath)
 	if err != nil {
 		t.Fatal(err)
@@ -74,3 +76,97 @@ func TestOpenOpenTypeCaseCreation(t *testing.T) {
 	metrics := tc.Metrics()
 	fmt.Printf("interline spacing for [%s]@%.1fpt is %s\n", f.Fontname, tc.PtSize(), metrics.Height)
 }
+
+func TestRegistryEviction(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.font")
+	defer teardown()
+	//
+	var evicted []string
+	fr := NewRegistry(WithMemoryBudget(250), WithHooks(Hooks{
+		Evict: func(name string) { evicted = append(evicted, name) },
+	}))
+	for _, name := range []string{"a", "b"} {
+		fr.StoreFont(name, &font.ScalableFont{Fontname: name, Binary: make([]byte, 100)})
+	}
+	if _, ok := fr.Acquire("a"); !ok { // protects a and makes b the LRU font
+		t.Fatalf("expected font a to be present")
+	}
+	fr.StoreFont("c", &font.ScalableFont{Fontname: "c", Binary: make([]byte, 100)})
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("expected font b to be evicted, evicted = %v", evicted)
+	}
+	fr.StoreFont("d", &font.ScalableFont{Fontname: "d", Binary: make([]byte, 100)})
+	if _, ok := fr.Acquire("a"); !ok {
+		t.Errorf("expected acquired font a not to be evicted")
+	}
+	fr.Release("a")
+	fr.Release("a")
+	stats := fr.Stats()
+	if stats.Evictions != 2 || stats.Fonts != 2 || stats.Size != 200 {
+		t.Errorf("unexpected registry stats: %+v", stats)
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	// no testing tracer here: it is not safe for concurrent use
+	fr := NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tc, err := fr.TypeCase("nonexistent", 12.0); tc == nil || err == nil {
+				t.Errorf("expected fallback typecase and error for unknown font")
+			}
+		}()
+	}
+	wg.Wait()
+	stats := fr.Stats()
+	if stats.Misses != 8 || stats.Fonts != 1 {
+		t.Errorf("expected 8 misses and the fallback font only, have %+v", stats)
+	}
+}
+
+func TestRegistryTypeCaseHoldsFont(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.font")
+	defer teardown()
+	//
+	f, err := font.LoadOpenTypeFont("../../locate/resources/packaged/fonts/GentiumPlus-R.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := int64(len(f.Binary)) + 100
+	fr := NewRegistry(WithMemoryBudget(budget))
+	fr.StoreFont("gentium", f)
+	if _, err = fr.TypeCase("gentium", 12.0); err != nil {
+		t.Fatal(err)
+	}
+	fr.StoreFont("x", &font.ScalableFont{Fontname: "x", Binary: make([]byte, 200)})
+	if _, ok := fr.typecases[appendSize("gentium", 12.0)]; !ok {
+		t.Errorf("expected font of typecase in use not to be evicted")
+	}
+	fr.Release("gentium")
+	fr.StoreFont("y", &font.ScalableFont{Fontname: "y", Binary: make([]byte, 200)})
+	if stats := fr.Stats(); stats.Size > budget || len(fr.typecases) != 0 {
+		t.Errorf("expected font released to be evicted with its typecase, have %+v", stats)
+	}
+}
+
+func TestRegistryFallbacks(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.font")
+	defer teardown()
+	//
+	greek := language.MustParseScript("Grek")
+	fr := NewRegistry(WithFallbacks(language.Script{}, "gentium"), WithFallbacks(greek, "greek-a", "greek-b"))
+	if names := fr.Fallbacks(greek); len(names) != 3 || names[0] != "greek-a" || names[2] != "gentium" {
+		t.Errorf("expected fallbacks for Greek to precede general ones, have %v", names)
+	}
+	latin := language.MustParseScript("Latn")
+	if names := fr.Fallbacks(latin); len(names) != 1 || names[0] != "gentium" {
+		t.Errorf("expected general fallbacks for Latin, have %v", names)
+	}
+	fr.SetFallbacks(greek)
+	if names := fr.Fallbacks(greek); len(names) != 1 {
+		t.Errorf("expected fallbacks for Greek to be removed, have %v", names)
+	}
+}
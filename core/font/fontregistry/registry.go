@@ -12,14 +12,82 @@ import (
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/font"
 	xfont "golang.org/x/image/font"
+	"golang.org/x/text/language"
 )
 
 // Registry is a type for holding information about loaded fonts for a
-// typesetter.
+// typesetter. A registry is safe for concurrent use by typesetting workers.
+//
+// Font binaries may take up considerable memory. Clients may therefore set a
+// memory budget for a registry (see WithMemoryBudget). If the binaries of the
+// stored fonts exceed the budget, the registry evicts the least recently used
+// fonts, together with the typecases derived from them. Fonts in use may be
+// protected from eviction with Acquire and Release.
 type Registry struct {
 	sync.Mutex
-	fonts     map[string]*font.ScalableFont
+	fonts     map[string]*fontEntry
 	typecases map[string]*font.TypeCase
+	budget    int64  // memory budget for font binaries, 0 = unlimited
+	size      int64  // memory currently used by font binaries
+	clock     uint64 // logical clock for LRU bookkeeping
+	hooks     Hooks
+	stats     Stats
+	fallbacks map[language.Script][]string // fallback fonts per script
+}
+
+// fontEntry is a font stored in a registry, together with bookkeeping
+// information for reference counting and eviction.
+type fontEntry struct {
+	font    *font.ScalableFont
+	refs    int      // number of clients holding the font
+	pinned  bool     // pinned fonts are never evicted
+	lastUse uint64   // logical time of last access
+	cases   []string // keys of typecases derived from this font
+}
+
+// Hooks are callbacks for instrumenting a registry, e.g. for collecting
+// metrics. Hooks left nil are ignored. Hooks are called after the registry
+// has been unlocked, thus they are free to call methods of the registry.
+// They may be called concurrently, though.
+type Hooks struct {
+	Hit   func(name string) // a requested font is present in the registry
+	Miss  func(name string) // a requested font is not present in the registry
+	Evict func(name string) // a font has been evicted from the registry
+}
+
+// Stats holds statistics about the usage of a registry.
+type Stats struct {
+	Hits      int64 // number of requests for fonts present in the registry
+	Misses    int64 // number of requests for fonts not present in the registry
+	Evictions int64 // number of fonts evicted
+	Fonts     int   // number of fonts currently stored
+	Size      int64 // memory used by the binaries of fonts currently stored
+}
+
+// Option is a type for options when creating a registry.
+type Option func(*Registry)
+
+// WithMemoryBudget sets a budget for the memory used by font binaries, in bytes.
+// A budget ≤ 0 means unlimited memory.
+func WithMemoryBudget(bytes int64) Option {
+	return func(fr *Registry) {
+		fr.budget = bytes
+	}
+}
+
+// WithHooks sets instrumentation hooks for a registry.
+func WithHooks(hooks Hooks) Option {
+	return func(fr *Registry) {
+		fr.hooks = hooks
+	}
+}
+
+// WithFallbacks sets a list of fallback fonts for a script, in order of
+// preference (see SetFallbacks).
+func WithFallbacks(script language.Script, normalizedNames ...string) Option {
+	return func(fr *Registry) {
+		fr.SetFallbacks(script, normalizedNames...)
+	}
 }
 
 var globalFontRegistry *Registry
@@ -35,10 +103,16 @@ func GlobalRegistry() *Registry {
 	return globalFontRegistry
 }
 
-func NewRegistry() *Registry {
+// NewRegistry creates a registry for fonts. Without options, memory used by the
+// registry is unlimited.
+func NewRegistry(opts ...Option) *Registry {
 	fr := &Registry{
-		fonts:     make(map[string]*font.ScalableFont),
+		fonts:     make(map[string]*fontEntry),
 		typecases: make(map[string]*font.TypeCase),
+		fallbacks: make(map[language.Script][]string),
+	}
+	for _, opt := range opts {
+		opt(fr)
 	}
 	return fr
 }
@@ -47,19 +121,59 @@ func NewRegistry() *Registry {
 //
 // The font will be stored using the normalized font name as a key. If this
 // key is already associated with a font, that font will not be overridden.
+// Storing a font may evict other fonts, if the registry runs out of its
+// memory budget.
 func (fr *Registry) StoreFont(normalizedName string, f *font.ScalableFont) {
 	if f == nil {
 		tracer().Errorf("registry cannot store null font")
 		return
 	}
 	fr.Lock()
-	defer fr.Unlock()
 	//style, weight := GuessStyleAndWeight(f.Fontname)
 	//fname := NormalizeFontname(f.Fontname, style, weight)
 	if _, ok := fr.fonts[normalizedName]; !ok {
 		tracer().Debugf("registry stores font %s as %s", f.Fontname, normalizedName)
-		fr.fonts[normalizedName] = f
+		fr.store(normalizedName, f)
+	}
+	evicted := fr.evict()
+	fr.Unlock()
+	fr.notify(fr.hooks.Evict, evicted...)
+}
+
+// Acquire looks up a font and protects it from eviction, until it is handed
+// back with Release. Calls to Acquire and Release have to be balanced.
+// If no font is stored under key `normalizedName`, Acquire returns false.
+func (fr *Registry) Acquire(normalizedName string) (*font.ScalableFont, bool) {
+	fr.Lock()
+	e, ok := fr.fonts[normalizedName]
+	if ok {
+		e.refs++
+		fr.touch(e)
+		fr.stats.Hits++
+	} else {
+		fr.stats.Misses++
+	}
+	fr.Unlock()
+	if ok {
+		fr.notify(fr.hooks.Hit, normalizedName)
+		return e.font, true
 	}
+	fr.notify(fr.hooks.Miss, normalizedName)
+	return nil, false
+}
+
+// Release hands back a font previously acquired with Acquire. As soon as
+// no client holds the font anymore, it may be evicted.
+func (fr *Registry) Release(normalizedName string) {
+	fr.Lock()
+	if e, ok := fr.fonts[normalizedName]; ok && e.refs > 0 {
+		e.refs--
+	} else {
+		tracer().Errorf("registry: release of font %s not acquired", normalizedName)
+	}
+	evicted := fr.evict()
+	fr.Unlock()
+	fr.notify(fr.hooks.Evict, evicted...)
 }
 
 // TypeCase returns a concrete typecase with a given font, style, weight and size.
@@ -67,51 +181,146 @@ func (fr *Registry) StoreFont(normalizedName string, f *font.ScalableFont) {
 // typecase. If a suitable font has previously been stored under key
 // `normalizedName`, a typecase will be derived from this font.
 //
-// If not typecase can be produced, TypeCase will derive one from a system-wide
-// fallback font and return it, together with an error message.
+// A typecase refers to the binary of its font. TypeCase therefore acquires the
+// font stored under `normalizedName`, as Acquire does, and clients hand it back
+// with Release as soon as they no longer use the typecase.
 //
+// If not typecase can be produced, TypeCase will derive one from a system-wide
+// fallback font and return it, together with an error message. The fallback
+// font is never evicted and need not be released.
 func (fr *Registry) TypeCase(normalizedName string, size float32) (*font.TypeCase, error) {
 	//
 	tracer().Debugf("registry searches for font %s at %.2f", normalizedName, size)
-	//fname := NormalizeFontname(name, style, weight)
-	tname := appendSize(normalizedName, size)
 	fr.Lock()
-	defer fr.Unlock()
-	if t, ok := fr.typecases[tname]; ok {
-		tracer().Infof("registry found font %s", tname)
-		return t, nil
-	}
-	if f, ok := fr.fonts[normalizedName]; ok {
-		t, err := f.PrepareCase(size)
-		tracer().Infof("font registry has font %s, caches at %.2f", normalizedName, size)
-		fr.typecases[tname] = t
-		return t, err
+	fname, e, t := fr.lookupCase(normalizedName, size)
+	fr.Unlock()
+	var err error
+	if fname == normalizedName {
+		fr.notify(fr.hooks.Hit, normalizedName)
+	} else {
+		tracer().Infof("registry does not contain font %s", normalizedName)
+		err = errors.New("font " + normalizedName + " not found in registry")
+		fr.notify(fr.hooks.Miss, normalizedName)
 	}
-	tracer().Infof("registry does not contain font %s", normalizedName)
-	err := errors.New("font " + normalizedName + " not found in registry")
-	//
-	// store typecase from fallback font, if not present yet, and return it
-	fname := "fallback"
-	tname = appendSize("fallback", size)
-	if t, ok := fr.typecases[fname]; ok {
+	if t != nil {
+		tracer().Infof("registry found font %s", appendSize(fname, size))
 		return t, err
 	}
+	// Typecase not cached yet: derive it without holding the lock, as preparing
+	// a typecase may take a while, then insert it if no other client has been
+	// faster.
 	f := font.FallbackFont()
-	t, _ := f.PrepareCase(size)
-	tracer().Infof("font registry caches fallback font %s at %.2f", fname, size)
-	fr.fonts[fname] = f
-	fr.typecases[tname] = t
+	if e != nil {
+		f = e.font
+	}
+	t, caseErr := f.PrepareCase(size)
+	if caseErr != nil {
+		tracer().Errorf("registry cannot derive typecase from font %s: %v", fname, caseErr)
+		if e != nil && fname == normalizedName {
+			fr.Release(normalizedName)
+		}
+		return t, caseErr
+	}
+	fr.Lock()
+	t = fr.insertCase(fname, f, size, t)
+	evicted := fr.evict()
+	fr.Unlock()
+	fr.notify(fr.hooks.Evict, evicted...)
 	return t, err
 }
 
+// lookupCase finds the font entry for a typecase and the typecase, if it is
+// cached. If no font is stored as normalizedName, the fallback font is used,
+// and its entry may be nil if it has not been stored yet. A font found as
+// normalizedName is acquired. Callers must hold the lock.
+func (fr *Registry) lookupCase(normalizedName string, size float32) (string, *fontEntry, *font.TypeCase) {
+	fname := normalizedName
+	e, ok := fr.fonts[fname]
+	if ok {
+		fr.stats.Hits++
+		e.refs++
+	} else {
+		fr.stats.Misses++
+		fname = "fallback"
+		if e, ok = fr.fonts[fname]; !ok {
+			return fname, nil, nil
+		}
+	}
+	fr.touch(e)
+	return fname, e, fr.typecases[appendSize(fname, size)]
+}
+
+// insertCase caches typecase t of font f, unless another typecase for the same
+// font and size has been cached in the meantime, which is then returned instead.
+// Callers must hold the lock.
+func (fr *Registry) insertCase(fname string, f *font.ScalableFont, size float32, t *font.TypeCase) *font.TypeCase {
+	e, ok := fr.fonts[fname]
+	if !ok { // fallback font is stored on first use
+		e = fr.store(fname, f)
+		e.pinned = true
+	}
+	tname := appendSize(fname, size)
+	if cached, ok := fr.typecases[tname]; ok {
+		return cached
+	}
+	tracer().Infof("font registry caches font %s at %.2f", fname, size)
+	fr.typecases[tname] = t
+	e.cases = append(e.cases, tname)
+	return t
+}
+
+// --- Fallback fonts --------------------------------------------------------
+
+// SetFallbacks sets the list of fallback fonts for a script, in order of
+// preference. Shapers consult fallback fonts for characters the font of a run
+// of text has no glyph for. Fonts are given by their normalized names, and may be
+// stored in the registry later on. The zero value of language.Script sets the
+// fallback fonts for all scripts, which are consulted after the fallback fonts
+// specific to a script.
+func (fr *Registry) SetFallbacks(script language.Script, normalizedNames ...string) {
+	fr.Lock()
+	defer fr.Unlock()
+	if len(normalizedNames) == 0 {
+		delete(fr.fallbacks, script)
+		return
+	}
+	fr.fallbacks[script] = append([]string(nil), normalizedNames...)
+}
+
+// Fallbacks returns the normalized names of the fallback fonts for a script, in
+// order of preference: fonts specific to the script, followed by fonts for all
+// scripts.
+func (fr *Registry) Fallbacks(script language.Script) []string {
+	fr.Lock()
+	defer fr.Unlock()
+	var all language.Script
+	names := append([]string(nil), fr.fallbacks[script]...)
+	if script != all {
+		names = append(names, fr.fallbacks[all]...)
+	}
+	return names
+}
+
+// Stats returns statistics about the usage of the registry.
+func (fr *Registry) Stats() Stats {
+	fr.Lock()
+	defer fr.Unlock()
+	stats := fr.stats
+	stats.Fonts = len(fr.fonts)
+	stats.Size = fr.size
+	return stats
+}
+
 // LogFontList is a helper function to dump the list of known fonts and typecases
 // in a registry to the trace-file (log-level Info).
 func (fr *Registry) LogFontList() {
+	fr.Lock()
+	defer fr.Unlock()
 	level := tracer().GetTraceLevel()
 	tracer().SetTraceLevel(tracing.LevelInfo)
 	tracer().Infof("--- registered fonts ---")
 	for k, v := range fr.fonts {
-		tracer().Infof("font [%s] = %v", k, v.Fontname)
+		tracer().Infof("font [%s] = %v (refs=%d)", k, v.font.Fontname, v.refs)
 	}
 	for k, v := range fr.typecases {
 		tracer().Infof("typecase [%s] = %v", k, v.ScalableFontParent().Fontname)
@@ -120,6 +329,63 @@ func (fr *Registry) LogFontList() {
 	tracer().SetTraceLevel(level)
 }
 
+// --- Eviction --------------------------------------------------------------
+
+// store enters a font into the registry. Callers must hold the lock.
+func (fr *Registry) store(name string, f *font.ScalableFont) *fontEntry {
+	e := &fontEntry{font: f}
+	fr.fonts[name] = e
+	fr.size += int64(len(f.Binary))
+	fr.touch(e)
+	return e
+}
+
+func (fr *Registry) touch(e *fontEntry) {
+	fr.clock++
+	e.lastUse = fr.clock
+}
+
+// evict removes least recently used fonts until the memory used by font
+// binaries is within budget again. Fonts which are pinned or held by clients
+// are not evicted. evict returns the names of the fonts evicted.
+// Callers must hold the lock.
+func (fr *Registry) evict() (evicted []string) {
+	for fr.budget > 0 && fr.size > fr.budget {
+		var lru string
+		var victim *fontEntry
+		for name, e := range fr.fonts {
+			if e.pinned || e.refs > 0 {
+				continue
+			}
+			if victim == nil || e.lastUse < victim.lastUse {
+				lru, victim = name, e
+			}
+		}
+		if victim == nil {
+			tracer().Infof("registry exceeds memory budget, but all fonts are in use")
+			break
+		}
+		tracer().Debugf("registry evicts font %s", lru)
+		for _, tname := range victim.cases {
+			delete(fr.typecases, tname)
+		}
+		delete(fr.fonts, lru)
+		fr.size -= int64(len(victim.font.Binary))
+		fr.stats.Evictions++
+		evicted = append(evicted, lru)
+	}
+	return
+}
+
+func (fr *Registry) notify(hook func(string), names ...string) {
+	if hook == nil {
+		return
+	}
+	for _, name := range names {
+		hook(name)
+	}
+}
+
 func NormalizeFontname(fname string, style xfont.Style, weight xfont.Weight) string {
 	fname = strings.TrimSpace(fname)
 	fname = strings.ReplaceAll(fname, " ", "_")
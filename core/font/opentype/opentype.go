@@ -11,24 +11,73 @@ Copyright © Norbert Pillmayer <norbert@pillmayer.com>
 package opentype
 
 import (
+	"github.com/npillmayer/tyse/core/dimen"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // --- Font and glyph metrics ------------------------------------------------
 
 // FontMetricsInfo contains selected metric information for a font.
 type FontMetricsInfo struct {
-	UnitsPerEm      sfnt.Units // ad-hoc units per em
-	Ascent, Descent sfnt.Units // ascender and descender
-	MaxAdvance      sfnt.Units // maximum advance width value in 'hmtx' table
-	LineGap         sfnt.Units // typographic line gap
+	UnitsPerEm         sfnt.Units // ad-hoc units per em
+	Ascent, Descent    sfnt.Units // ascender and descender
+	MaxAdvance         sfnt.Units // maximum advance width value in 'hmtx' table
+	LineGap            sfnt.Units // typographic line gap
+	CapHeight          sfnt.Units // height of capital letters above the baseline
+	XHeight            sfnt.Units // height of lowercase letters without ascenders
+	UnderlinePosition  sfnt.Units // top of the underline relative to the baseline, negative if below
+	UnderlineThickness sfnt.Units // thickness of the underline
+	StrikeoutPosition  sfnt.Units // top of the strikeout stroke relative to the baseline
+	StrikeoutSize      sfnt.Units // thickness of the strikeout stroke
+}
+
+// LineHeight is the recommended distance between two consecutive baselines.
+func (m FontMetricsInfo) LineHeight() sfnt.Units {
+	return m.Ascent - m.Descent + m.LineGap
 }
 
 // GlyphMetricsInfo contains all the metric information for a glyph.
+// Metrics are given in font design units. They may be scaled to pixels with
+// Pixels, or to dimensions with UnitsToDimen.
 type GlyphMetricsInfo struct {
-	Advance  sfnt.Units  // advance width
-	LSB, RSB sfnt.Units  // side bearings
-	BBox     BoundingBox // bounding box
+	Advance    sfnt.Units  // advance width
+	LSB, RSB   sfnt.Units  // side bearings
+	BBox       BoundingBox // bounding box
+	VAdvance   sfnt.Units  // advance height, for vertical layout
+	TSB        sfnt.Units  // top side bearing, for vertical layout
+	UnitsPerEm sfnt.Units  // design units per em of the font
+	PPEm       uint16      // pixels per em the metrics have been requested for
+}
+
+// Pixels scales a value in font design units to pixels at m.PPEm.
+func (m GlyphMetricsInfo) Pixels(u sfnt.Units) fixed.Int26_6 {
+	return ScaleUnits(u, m.UnitsPerEm, m.PPEm)
+}
+
+// ScaleUnits scales a value in font design units to pixels, for a font with
+// unitsPerEm design units per em rendered at ppem pixels per em.
+// The result is rounded to the nearest 1/64 of a pixel. For a font without
+// units per em, ScaleUnits returns 0.
+func ScaleUnits(u, unitsPerEm sfnt.Units, ppem uint16) fixed.Int26_6 {
+	if unitsPerEm <= 0 {
+		return 0
+	}
+	x := int64(u) * int64(ppem) * 64
+	if x < 0 {
+		return fixed.Int26_6(-((-x + int64(unitsPerEm)/2) / int64(unitsPerEm)))
+	}
+	return fixed.Int26_6((x + int64(unitsPerEm)/2) / int64(unitsPerEm))
+}
+
+// UnitsToDimen scales a value in font design units to a dimension, for a font with
+// unitsPerEm design units per em set at a given font size.
+// For a font without units per em, UnitsToDimen returns 0.
+func UnitsToDimen(u, unitsPerEm sfnt.Units, fontsize dimen.DU) dimen.DU {
+	if unitsPerEm <= 0 {
+		return 0
+	}
+	return fontsize.Scale(int64(u), int64(unitsPerEm))
 }
 
 // BoundingBox describes the bounding box of a glyph.
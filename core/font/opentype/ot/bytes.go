@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Reading bytes from a font's binary representation
@@ -32,13 +33,21 @@ func u32(b []byte) uint32 {
 //
 // If somewhere along a chain of navigation calls an error occured, the finally resulting NavLocation
 // may be of size 0.
+//
+// Accessors U16, U32 and Slice never read beyond the bounds of a location: out of bounds
+// data is read as 0, and slices are clamped to the location. Clients who have to
+// distinguish between a zero value and missing data should use the checked variants
+// CheckedU16, CheckedU32 and CheckedSlice.
 type NavLocation interface {
-	Size() int                  // size in bytes
-	Bytes() []byte              // return as a byte slice
-	Slice(int, int) NavLocation // return a sub-segment of this location
-	U16(int) uint16             // convenience access to 16 bit data at byte index
-	U32(int) uint32             // convenience access to 32 bit data at byte index
-	Glyphs() []GlyphIndex       // convenience conversion to slice of glyphs
+	Size() int                                  // size in bytes
+	Bytes() []byte                              // return as a byte slice
+	Slice(int, int) NavLocation                 // return a sub-segment of this location
+	U16(int) uint16                             // convenience access to 16 bit data at byte index
+	U32(int) uint32                             // convenience access to 32 bit data at byte index
+	Glyphs() []GlyphIndex                       // convenience conversion to slice of glyphs
+	CheckedSlice(int, int) (NavLocation, error) // sub-segment, or error if out of bounds
+	CheckedU16(int) (uint16, error)             // 16 bit data, or error if out of bounds
+	CheckedU32(int) (uint32, error)             // 32 bit data, or error if out of bounds
 }
 
 // binarySegm is a segment of byte data.
@@ -54,17 +63,30 @@ func (b binarySegm) Bytes() []byte {
 	return b
 }
 
-// return a sub-segment of this location
+// return a sub-segment of this location, clamped to the bounds of b
 func (b binarySegm) Slice(from int, to int) NavLocation {
 	if from < 0 {
 		from = 0
+	} else if from > len(b) {
+		from = len(b)
 	}
 	if to > len(b) {
 		to = len(b)
+	} else if to < from {
+		to = from
 	}
 	return b[from:to]
 }
 
+// CheckedSlice returns a sub-segment of this location, or an error if [from…to)
+// is not within the bounds of b.
+func (b binarySegm) CheckedSlice(from int, to int) (NavLocation, error) {
+	if from < 0 || to < from || to > len(b) {
+		return binarySegm{}, errBufferBounds
+	}
+	return b[from:to], nil
+}
+
 func (b binarySegm) Reader() io.Reader {
 	return bytes.NewReader(b)
 }
@@ -85,32 +107,39 @@ func (b binarySegm) U32(i int) uint32 {
 	return n
 }
 
-// convenience conversion to slice of glyphs
+// CheckedU16 returns the uint16 at byte index i, or an error if i is out of bounds.
+func (b binarySegm) CheckedU16(i int) (uint16, error) {
+	return b.u16(i)
+}
+
+// CheckedU32 returns the uint32 at byte index i, or an error if i is out of bounds.
+func (b binarySegm) CheckedU32(i int) (uint32, error) {
+	return b.u32(i)
+}
+
+// convenience conversion to slice of glyphs. A trailing odd byte is ignored.
 func (b binarySegm) Glyphs() []GlyphIndex {
-	l := len(b)
-	if l|0x1 > 0 {
-		l += 1
-	}
-	glyphs := make([]GlyphIndex, l/2)
-	j := 0
-	for i := 0; i < len(b); i += 2 {
-		glyphs[j] = GlyphIndex(b[i])<<8 + GlyphIndex(b[i+1])
-		j++
+	glyphs := make([]GlyphIndex, len(b)/2)
+	for j := range glyphs {
+		glyphs[j] = GlyphIndex(b[2*j])<<8 + GlyphIndex(b[2*j+1])
 	}
 	return glyphs
-
 }
 
 func asU16Slice(b binarySegm) []uint16 {
-	r := make([]uint16, len(b)/2+1)
-	j := 0
-	for i := 0; i < len(b); i += 2 {
-		r[j] = uint16(b[i])<<8 + uint16(b[i+1])
-		j++
+	r := make([]uint16, len(b)/2)
+	for j := range r {
+		r[j] = uint16(b[2*j])<<8 + uint16(b[2*j+1])
 	}
 	return r
 }
 
+// head returns at most n bytes from the start of b. It is used to
+// trace binary data without risking to read beyond its bounds.
+func head(b []byte, n int) binarySegm {
+	return b[:min(n, len(b))]
+}
+
 // return an unsigned integer as an array of two bytes.
 func uintBytes(n uint16) binarySegm {
 	return binarySegm{byte(n >> 8 & 0xff), byte(n & 0xff)}
@@ -125,6 +154,15 @@ func (b binarySegm) view(offset, n int) (binarySegm, error) {
 	return b[offset : offset+n], nil
 }
 
+// at returns the sub-segment of b starting at offset. For offsets out of bounds,
+// an empty segment is returned. at is the bounds-checked variant of b[offset:].
+func (b binarySegm) at(offset int) binarySegm {
+	if offset < 0 || offset > len(b) {
+		return binarySegm{}
+	}
+	return b[offset:]
+}
+
 // u16 returns the uint16 in b at the relative offset i.
 func (b binarySegm) u16(i int) (uint16, error) {
 	buf, err := b.view(i, 2)
@@ -143,6 +181,15 @@ func (b binarySegm) u32(i int) (uint32, error) {
 	return u32(buf), nil
 }
 
+// tag returns the Tag in b at the relative offset i.
+func (b binarySegm) tag(i int) (Tag, error) {
+	buf, err := b.view(i, 4)
+	if err != nil {
+		return 0, err
+	}
+	return MakeTag(buf), nil
+}
+
 // --- Ranges of glyphs ------------------------------------------------------
 
 // GlyphRange is a type frequently used by sub-tables of layout tables (GPOS and GSUB).
@@ -526,17 +573,135 @@ func (a array) Get(i int) NavLocation {
 func (a array) All() []NavLocation {
 	r := make([]NavLocation, a.length)
 	for i := 0; i < a.length; i++ {
-		x := a.Get(i)
-		r = append(r, x)
+		r[i] = a.Get(i)
 	}
 	return r
 }
 
+// Iterate calls f for every record of a, in order, until f returns false.
+func (a array) Iterate(f func(i int, rec NavLocation) bool) {
+	for i := 0; i < a.length; i++ {
+		if !f(i, a.Get(i)) {
+			return
+		}
+	}
+}
+
+// DecodeAs decodes record #i of a according to a record layout, given as a struct tag
+// (see ParseRecordLayout).
+func (a array) DecodeAs(i int, structTag string) ([]uint32, error) {
+	layout, err := ParseRecordLayout(structTag)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= a.length {
+		return nil, errBufferBounds
+	}
+	return layout.Decode(a.Get(i))
+}
+
+// Array is a linear sequence of equal-sized records. It is the public face of the
+// array type used throughout this package, intended for clients adding support for
+// tables which package ot does not parse.
+type Array struct {
+	array
+}
+
+var _ NavList = Array{}
+
+// ViewArray interprets loc as an array of records of size recordSize.
+// Trailing bytes not making up a complete record are ignored.
+func ViewArray(loc NavLocation, recordSize int) Array {
+	if recordSize <= 0 {
+		return Array{}
+	}
+	return Array{viewArray(loc.Bytes(), recordSize)}
+}
+
+// ParseArray interprets loc as an array of records of size recordSize. The number of
+// records is given as a uint16 at offset, and the records follow immediately after it.
+// This is the most common layout of arrays in OpenType tables.
+func ParseArray(loc NavLocation, offset int, recordSize int, name string) (Array, error) {
+	a, err := parseArray(loc.Bytes(), offset, recordSize, name, "")
+	if err != nil {
+		return Array{}, err
+	}
+	if a.Size() > len(a.loc) {
+		return Array{}, errBufferBounds
+	}
+	return Array{a}, nil
+}
+
+// RecordLayout describes the fields of a record within an OpenType table. It is
+// used to decode records without resorting to reflection.
+type RecordLayout struct {
+	sizes []int
+	size  int
+}
+
+// ParseRecordLayout creates a record layout from a struct tag, i.e., a list of
+// OpenType data types, separated by spaces or commas, e.g.
+//
+//	"uint16 Offset16 Offset32"
+//
+// Data types are the ones used throughout the OpenType specification: uint8, int8,
+// uint16, int16, FWORD, UFWORD, F2DOT14, Offset16, uint24, Offset24, uint32, int32,
+// Fixed, Offset32, Tag and Version16Dot16.
+func ParseRecordLayout(structTag string) (RecordLayout, error) {
+	layout := RecordLayout{}
+	fields := strings.FieldsFunc(structTag, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for _, field := range fields {
+		var n int
+		switch field {
+		case "uint8", "int8":
+			n = 1
+		case "uint16", "int16", "FWORD", "UFWORD", "F2DOT14", "Offset16":
+			n = 2
+		case "uint24", "Offset24":
+			n = 3
+		case "uint32", "int32", "Fixed", "Offset32", "Tag", "Version16Dot16":
+			n = 4
+		default:
+			return RecordLayout{}, fmt.Errorf("unknown OpenType data type in record layout: %q", field)
+		}
+		layout.sizes = append(layout.sizes, n)
+		layout.size += n
+	}
+	return layout, nil
+}
+
+// Size returns the size of a record in bytes.
+func (layout RecordLayout) Size() int {
+	return layout.size
+}
+
+// Decode decodes the fields of a record at loc. Every field is returned as an
+// unsigned value; clients should convert signed fields, e.g. int16(v[0]).
+// If loc is too small to hold the record, an error is returned.
+func (layout RecordLayout) Decode(loc NavLocation) ([]uint32, error) {
+	b := loc.Bytes()
+	if len(b) < layout.size {
+		return nil, errBufferBounds
+	}
+	values := make([]uint32, len(layout.sizes))
+	for i, n := range layout.sizes {
+		for _, x := range b[:n] {
+			values[i] = values[i]<<8 | uint32(x)
+		}
+		b = b[n:]
+	}
+	return values, nil
+}
+
 // VarArray is a type for arrays of variable length records, which in turn may point to nested
 // arrays of (variable size) records.
 type VarArray interface {
-	Get(i int, deep bool) (NavLocation, error) // get record at index i; if deep: query nested arrays
-	Size() int                                 // get the number of entries
+	Get(i int, deep bool) (NavLocation, error)                    // get record at index i; if deep: query nested arrays
+	Size() int                                                    // get the number of entries
+	Len() int                                                     // get the number of entries, same as Size
+	Iterate(deep bool, f func(i int, rec NavLocation) bool) error // call f for every entry until f returns false
 }
 
 type varArray struct {
@@ -556,10 +721,14 @@ func parseVarArray16(b binarySegm, szOffset, gap, indirections int, name string)
 		tracer().Errorf("byte segment too small to parse variable array")
 		return varArray{}
 	}
-	cnt, _ := b.u16(szOffset)
+	cnt, err := b.CheckedU16(szOffset)
+	if err != nil || len(b) < szOffset+gap+int(cnt)*2 {
+		tracer().Errorf("variable array %s out of bounds", name)
+		return varArray{}
+	}
 	va := varArray{name: name, indirections: indirections, base: b}
 	va.ptrs = array{recordSize: 2, length: int(cnt), loc: b[szOffset+gap:]}
-	tracer().Debugf("parsing VarArray of size %d = %v", cnt, b[szOffset+gap:szOffset+gap+20].Glyphs())
+	tracer().Debugf("parsing VarArray of size %d = %v", cnt, head(b[szOffset+gap:], 20).Glyphs())
 	return va
 }
 
@@ -574,7 +743,7 @@ func (va varArray) Get(i int, deep bool) (b NavLocation, err error) {
 	base := va.base
 	for j := 0; j < indirect; j++ {
 		b = a.Get(i) // TODO will this create an infinite loop in case of error?
-		tracer().Debugf("varArray->Get(%d|%d), a = %v", i, a.length, binarySegm(a.loc.Bytes()[:20]).Glyphs())
+		tracer().Debugf("varArray->Get(%d|%d), a = %v", i, a.length, head(a.loc.Bytes(), 20).Glyphs())
 		tracer().Debugf("b = %d, %d to go", b.U16(0), va.indirections-1-j)
 		if b.U16(0) == 0 {
 			tracer().Debugf("link to ptrs-data is NULL, empty array")
@@ -585,11 +754,11 @@ func (va varArray) Get(i int, deep bool) (b NavLocation, err error) {
 			b = link.Jump()
 			if j+1 < va.indirections {
 				a, err = parseArray16(b.Bytes(), 0, "var-array", "var-array-entry")
-				tracer().Debugf("new a has size %d, is %v", a.length, binarySegm(a.loc.Bytes()[:20]).Glyphs())
+				tracer().Debugf("new a has size %d, is %v", a.length, head(a.loc.Bytes(), 20).Glyphs())
 			}
 		}
 	}
-	tracer().Debugf("varArray result = %v", asU16Slice(binarySegm(b.Bytes()[:min(20, 2*b.Size())])))
+	tracer().Debugf("varArray result = %v", asU16Slice(head(b.Bytes(), 20)))
 	return b, err
 }
 
@@ -597,6 +766,25 @@ func (va varArray) Size() int {
 	return va.ptrs.length
 }
 
+func (va varArray) Len() int {
+	return va.ptrs.length
+}
+
+// Iterate calls f for every entry of va, in order, until f returns false. If deep is
+// true, nested arrays will be queried (see Get). Iteration stops at the first error.
+func (va varArray) Iterate(deep bool, f func(i int, rec NavLocation) bool) error {
+	for i := 0; i < va.ptrs.length; i++ {
+		rec, err := va.Get(i, deep)
+		if err != nil {
+			return err
+		}
+		if !f(i, rec) {
+			return nil
+		}
+	}
+	return nil
+}
+
 var _ VarArray = varArray{}
 
 // --- Tag record map --------------------------------------------------------
@@ -614,7 +802,14 @@ func parseTagRecordMap16(b binarySegm, offset int, base binarySegm, name, target
 		target: target,
 		base:   base,
 	}
-	arrBase := b[offset+2 : offset+2+int(N)*(4+2)]
+	if N == 0 {
+		return m
+	}
+	arrBase, err := b.view(offset+2, int(N)*(4+2))
+	if err != nil {
+		tracer().Errorf("tag record map %s out of bounds", name)
+		return tagRecordMap16{}
+	}
 	m.records = viewArray(arrBase, 4+2)
 	return m
 }
@@ -643,7 +838,7 @@ func (m tagRecordMap16) LookupTag(tag Tag) NavLink {
 	tracer().Debugf("tag record map has %d entries", m.records.length)
 	for i := 0; i < m.records.length; i++ {
 		b := m.records.Get(i)
-		rtag := MakeTag(b.Bytes()[:4])
+		rtag, _ := binarySegm(b.Bytes()).tag(0)
 		tracer().Debugf("testing for tag = %s", rtag)
 		if tag == rtag {
 			tracer().Debugf("tag record lookup found tag (%s)", rtag)
@@ -664,7 +859,7 @@ func (m tagRecordMap16) Tags() []Tag {
 	tags := make([]Tag, 0, 3)
 	for i := 0; i < m.records.length; i++ {
 		b := m.records.Get(i)
-		tag := MakeTag(b.Bytes()[:4])
+		tag, _ := binarySegm(b.Bytes()).tag(0)
 		tracer().Debugf("  Tag = (%s)", tag)
 		tags = append(tags, tag)
 	}
@@ -681,7 +876,10 @@ func (m tagRecordMap16) Len() int {
 
 func (m tagRecordMap16) Get(i int) (Tag, NavLink) {
 	b := m.records.Get(i)
-	tag := MakeTag(b.Bytes()[:4])
+	tag, err := binarySegm(b.Bytes()).tag(0)
+	if err != nil {
+		return 0, link16{}
+	}
 	link, err := parseLink16(b.Bytes(), 4, m.base, m.target)
 	if err != nil {
 		return 0, link16{}
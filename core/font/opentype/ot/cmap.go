@@ -249,7 +249,7 @@ func makeGlyphIndexFormat4(b binarySegm) (CMapGlyphIndex, error) {
 	}
 	segCount /= 2
 	eLength := 8*int(segCount) + 2
-	if eLength > b.Size() || headerSize+eLength > int(size) {
+	if int(size) > b.Size() || headerSize+eLength > int(size) {
 		return nil, errFontFormat("cmap internal structure")
 	}
 	b = b[headerSize:size]
@@ -270,7 +270,7 @@ func makeGlyphIndexFormat4(b binarySegm) (CMapGlyphIndex, error) {
 			offset: offsets.Get(i).U16(0),
 		}
 		if entries[i].offset > 0 && entries[i].delta > 0 {
-			panic("Hurray! Font with cmap format 4, offset > 0 and delta > 0, detected!")
+			return nil, errUnsupported("cmap format 4 with both glyph offset and delta")
 		}
 	}
 	glyphTable := viewArray16(b[next:])
@@ -342,7 +342,7 @@ func makeGlyphIndexFormat12(b binarySegm) (CMapGlyphIndex, error) {
 	size, _ := b.u32(4)
 	grpCount, _ := b.u32(12)
 	eLength := 12 * int(grpCount)
-	if eLength > b.Size() || eLength+headerSize > int(size) {
+	if int64(size) > int64(b.Size()) || eLength+headerSize > int(size) {
 		return nil, errFontFormat("cmap internal structure")
 	}
 	b = b[headerSize:size]
@@ -356,8 +356,8 @@ func makeGlyphIndexFormat12(b binarySegm) (CMapGlyphIndex, error) {
 	for i := range entries {
 		entries[i] = cmapEntry32{
 			start: groups.Get(i).U32(0),
-			end:   u32(groups.Get(i).Bytes()[4:]),
-			delta: u32(groups.Get(i).Bytes()[8:]),
+			end:   groups.Get(i).U32(4),
+			delta: groups.Get(i).U32(8),
 		}
 	}
 	return format12GlyphIndex{
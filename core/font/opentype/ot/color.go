@@ -0,0 +1,548 @@
+package ot
+
+import "image/color"
+
+/*
+Color fonts come in several flavours, each of them with their own set of tables:
+
+▪︎ COLR/CPAL: glyphs are composed of layers of other glyphs, each layer filled with
+a color from a palette
+
+▪︎ sbix: Apple's format for bitmap glyphs, usually PNG images, organized in strikes
+(one strike per ppem-size)
+
+▪︎ CBDT/CBLC: Google's format for bitmap glyphs (again PNG), with CBLC locating
+glyph images within the CBDT table
+
+▪︎ SVG: glyphs as SVG documents (currently not supported)
+
+We expose the tables, but interpreting them is left to higher level packages (e.g.,
+package `otquery`) and to renderers.
+*/
+
+// --- COLR table ------------------------------------------------------------
+
+// ColrTable, the Color Table (COLR), adds support for multi-colored glyphs in a manner
+// that integrates with the rasterizers of existing text engines. A color glyph
+// is composed of layers of regular glyphs, painted in back-to-front order, each of
+// which is filled with a color taken from the CPAL table.
+//
+// Currently only version 0 of the COLR table is interpreted. For version 1 tables
+// the version 0 part (which is required to be present) will be accessible, but
+// paint graphs are not.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/colr
+type ColrTable struct {
+	tableBase
+	Version          uint16
+	baseGlyphRecords array // BaseGlyph records, sorted by glyph ID
+	layerRecords     array // Layer records
+}
+
+func newColrTable(tag Tag, b binarySegm, offset, size uint32) *ColrTable {
+	t := &ColrTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// ColorLayer is a layer of a color glyph: a glyph to be painted with a color
+// from a color palette. PaletteIndex is an index into a CPAL palette.
+// A PaletteIndex of 0xFFFF denotes the text foreground color.
+type ColorLayer struct {
+	Glyph        GlyphIndex
+	PaletteIndex uint16
+}
+
+// ForegroundPaletteIndex is a special palette index for color layers, telling the
+// client to use the text foreground color instead of a palette entry.
+const ForegroundPaletteIndex uint16 = 0xFFFF
+
+// BaseGlyphCount returns the number of color glyphs defined by a COLR table.
+func (t *ColrTable) BaseGlyphCount() int {
+	return t.baseGlyphRecords.length
+}
+
+// Layers returns the color layers of a base glyph, in back-to-front order.
+// If gid is not a color glyph, nil is returned.
+func (t *ColrTable) Layers(gid GlyphIndex) []ColorLayer {
+	// BaseGlyph record:
+	// uint16  glyphID          Glyph ID of the base glyph
+	// uint16  firstLayerIndex  Index (base 0) into the layerRecords array
+	// uint16  numLayers        Number of color layers associated with this glyph
+	N := t.baseGlyphRecords.length
+	for i, j := 0, N; i < j; { // base glyph records are sorted by glyph ID
+		h := i + (j-i)/2
+		rec := t.baseGlyphRecords.Get(h)
+		if g := GlyphIndex(rec.U16(0)); gid < g {
+			j = h
+		} else if g < gid {
+			i = h + 1
+		} else {
+			first, cnt := int(rec.U16(2)), int(rec.U16(4))
+			if first+cnt > t.layerRecords.length {
+				tracer().Errorf("COLR layer records out of bounds for glyph %d", gid)
+				return nil
+			}
+			layers := make([]ColorLayer, cnt)
+			for k := 0; k < cnt; k++ {
+				lrec := t.layerRecords.Get(first + k)
+				layers[k] = ColorLayer{
+					Glyph:        GlyphIndex(lrec.U16(0)),
+					PaletteIndex: lrec.U16(2),
+				}
+			}
+			return layers
+		}
+	}
+	return nil
+}
+
+// --- CPAL table ------------------------------------------------------------
+
+// CPalTable, the Color Palette Table (CPAL), defines a set of one or more color palettes
+// and, for version 1, information regarding palette types and names. Palette entries
+// are referenced by color layers of the COLR table.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cpal
+type CPalTable struct {
+	tableBase
+	Version           uint16
+	NumPaletteEntries int   // number of entries in each palette
+	NumPalettes       int   // number of palettes in the table
+	colorRecordsIndex array // index of each palette's first color record
+	colorRecords      array // BGRA color records
+}
+
+func newCPalTable(tag Tag, b binarySegm, offset, size uint32) *CPalTable {
+	t := &CPalTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// Color returns entry number `entry` of palette number `palette`. If either
+// index is out of range, false is returned.
+//
+// Colors are stored in sRGB with non-premultiplied alpha.
+func (t *CPalTable) Color(palette, entry int) (color.NRGBA, bool) {
+	if palette < 0 || palette >= t.NumPalettes || entry < 0 || entry >= t.NumPaletteEntries {
+		return color.NRGBA{}, false
+	}
+	first := int(t.colorRecordsIndex.Get(palette).U16(0))
+	if first+entry >= t.colorRecords.length {
+		return color.NRGBA{}, false
+	}
+	// ColorRecord: uint8 blue, green, red, alpha
+	rec := t.colorRecords.Get(first + entry).Bytes()
+	if len(rec) < 4 {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{B: rec[0], G: rec[1], R: rec[2], A: rec[3]}, true
+}
+
+// Palette returns all the colors of palette number `palette`, or nil if no
+// such palette exists.
+func (t *CPalTable) Palette(palette int) []color.NRGBA {
+	if palette < 0 || palette >= t.NumPalettes {
+		return nil
+	}
+	colors := make([]color.NRGBA, 0, t.NumPaletteEntries)
+	for i := 0; i < t.NumPaletteEntries; i++ {
+		c, ok := t.Color(palette, i)
+		if !ok {
+			break
+		}
+		colors = append(colors, c)
+	}
+	return colors
+}
+
+// --- Bitmap glyphs ---------------------------------------------------------
+
+// BitmapGlyph is a glyph image from one of the bitmap tables sbix or CBDT.
+// Data is the raw image data; its format is identified by GraphicType, which
+// usually is 'png '. Other graphic types defined by the sbix table are 'jpg ', 'tiff'
+// and 'dupe'.
+//
+// OriginX and OriginY give the offset of the image's lower left corner
+// relative to the glyph origin, in pixels. For CBDT bitmaps, Metrics are
+// filled in if the bitmap's format provides them.
+type BitmapGlyph struct {
+	PPEm             uint16 // the pixels-per-em size of the strike
+	OriginX, OriginY int16
+	GraphicType      Tag
+	Metrics          BitmapMetrics // only for CBDT
+	Data             []byte
+}
+
+// BitmapMetrics holds the glyph metrics of a CBDT bitmap glyph (small or big glyph
+// metrics, in pixels).
+type BitmapMetrics struct {
+	Height, Width    uint8
+	BearingX         int8
+	BearingY         int8
+	Advance          uint8
+	VertBearingX     int8 // big metrics only
+	VertBearingY     int8 // big metrics only
+	VertAdvance      uint8
+	HasVerticalParts bool // true for big metrics
+}
+
+// --- sbix table ------------------------------------------------------------
+
+// SbixTable, the Standard Bitmap Graphics Table (sbix), provides access to bitmap data
+// in a standard graphics format, such as PNG, JPEG or TIFF. Glyph images are
+// organized in strikes, each strike holding glyph images for a specific ppem-size.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/sbix
+type SbixTable struct {
+	tableBase
+	Version   uint16
+	Flags     uint16
+	strikes   array // Offset32 to strikes, from beginning of the sbix table
+	numGlyphs int   // taken from table maxp during consistency check
+}
+
+func newSbixTable(tag Tag, b binarySegm, offset, size uint32) *SbixTable {
+	t := &SbixTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// StrikeCount returns the number of bitmap strikes contained in the table.
+func (t *SbixTable) StrikeCount() int {
+	return t.strikes.length
+}
+
+// SbixStrike is a set of glyph bitmaps for a specific size.
+type SbixStrike struct {
+	PPEm      uint16 // the pixels-per-em size for which this strike was designed
+	PPI       uint16 // the device pixel density (in PPI) for which this strike was designed
+	loc       binarySegm
+	numGlyphs int
+}
+
+// Strike returns strike number i. If no such strike exists, a strike without any
+// glyph data will be returned.
+func (t *SbixTable) Strike(i int) SbixStrike {
+	if i < 0 || i >= t.strikes.length {
+		return SbixStrike{}
+	}
+	link := makeLink32(t.strikes.Get(i).U32(0), t.data, "Strike")
+	loc := binarySegm(link.Jump().Bytes())
+	if loc.Size() < 4 {
+		return SbixStrike{}
+	}
+	return SbixStrike{
+		PPEm:      loc.U16(0),
+		PPI:       loc.U16(2),
+		loc:       loc,
+		numGlyphs: t.numGlyphs,
+	}
+}
+
+// Glyph returns the bitmap for glyph gid, if present in the strike.
+//
+// Glyphs without bitmap data in a strike (e.g., the space character) will
+// return false.
+func (s SbixStrike) Glyph(gid GlyphIndex) (BitmapGlyph, bool) {
+	if int(gid) >= s.numGlyphs {
+		return BitmapGlyph{}, false
+	}
+	// glyphDataOffsets[numGlyphs+1] are Offset32, from the beginning of the strike data header
+	start, err1 := s.loc.u32(4 + int(gid)*4)
+	end, err2 := s.loc.u32(4 + int(gid)*4 + 4)
+	if err1 != nil || err2 != nil || end <= start || int(end) > s.loc.Size() {
+		return BitmapGlyph{}, false
+	}
+	rec := s.loc[start:end]
+	// Glyph data: int16 originOffsetX, int16 originOffsetY, Tag graphicType, uint8 data[]
+	if rec.Size() < 8 {
+		return BitmapGlyph{}, false
+	}
+	return BitmapGlyph{
+		PPEm:        s.PPEm,
+		OriginX:     int16(rec.U16(0)),
+		OriginY:     int16(rec.U16(2)),
+		GraphicType: Tag(rec.U32(4)),
+		Data:        rec[8:],
+	}, true
+}
+
+// --- CBLC/CBDT tables ------------------------------------------------------
+
+// CBLCTable, the Color Bitmap Location Table (CBLC), provides embedded bitmap locators
+// for color bitmaps, which are stored in a separate table (CBDT). The CBLC table
+// will be linked to its CBDT table during parsing; clients will therefore rarely
+// have to deal with the CBDT table directly.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cblc
+type CBLCTable struct {
+	tableBase
+	bitmapSizes array      // BitmapSize records, 48 bytes each
+	imageData   binarySegm // data of table CBDT, set during consistency check
+}
+
+func newCBLCTable(tag Tag, b binarySegm, offset, size uint32) *CBLCTable {
+	t := &CBLCTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// StrikeCount returns the number of bitmap strikes (sizes) contained in the table.
+func (t *CBLCTable) StrikeCount() int {
+	return t.bitmapSizes.length
+}
+
+// CBLCStrike is a set of bitmaps for a range of glyphs for a specific size.
+type CBLCStrike struct {
+	PPEmX, PPEmY   uint8
+	BitDepth       uint8
+	StartGlyph     GlyphIndex // lowest glyph index for this size
+	EndGlyph       GlyphIndex // highest glyph index for this size
+	indexSubtables array      // IndexSubTableArray records, 8 bytes each
+	subtablesBase  binarySegm
+	imageData      binarySegm
+}
+
+// Strike returns strike number i. If no such strike exists, a strike without any
+// glyph data will be returned.
+func (t *CBLCTable) Strike(i int) CBLCStrike {
+	if i < 0 || i >= t.bitmapSizes.length {
+		return CBLCStrike{}
+	}
+	// BitmapSize record:
+	// Offset32        indexSubTableArrayOffset  from beginning of CBLC
+	// uint32          indexTablesSize
+	// uint32          numberofIndexSubTables
+	// uint32          colorRef                  not used; set to 0
+	// SbitLineMetrics hori, vert                12 bytes each
+	// uint16          startGlyphIndex
+	// uint16          endGlyphIndex
+	// uint8           ppemX, ppemY, bitDepth
+	// int8            flags
+	rec := binarySegm(t.bitmapSizes.Get(i).Bytes())
+	if rec.Size() < 48 {
+		return CBLCStrike{}
+	}
+	link := makeLink32(rec.U32(0), t.data, "IndexSubTableArray")
+	base := binarySegm(link.Jump().Bytes())
+	n := int(rec.U32(8))
+	if n*8 > base.Size() {
+		tracer().Errorf("CBLC index sub-table array out of bounds")
+		n = base.Size() / 8
+	}
+	return CBLCStrike{
+		PPEmX:          rec[44],
+		PPEmY:          rec[45],
+		BitDepth:       rec[46],
+		StartGlyph:     GlyphIndex(rec.U16(40)),
+		EndGlyph:       GlyphIndex(rec.U16(42)),
+		indexSubtables: array{recordSize: 8, length: n, loc: base},
+		subtablesBase:  base,
+		imageData:      t.imageData,
+	}
+}
+
+// Glyph returns the bitmap for glyph gid, if present in the strike.
+// CBDT bitmaps are always PNG images, thus GraphicType will be 'png '.
+func (s CBLCStrike) Glyph(gid GlyphIndex) (BitmapGlyph, bool) {
+	if gid < s.StartGlyph || gid > s.EndGlyph {
+		return BitmapGlyph{}, false
+	}
+	// IndexSubTableArray record:
+	// uint16    firstGlyphIndex
+	// uint16    lastGlyphIndex
+	// Offset32  additionalOffsetToIndexSubtable  from beginning of the IndexSubTableArray
+	for i := 0; i < s.indexSubtables.length; i++ {
+		rec := s.indexSubtables.Get(i)
+		first, last := GlyphIndex(rec.U16(0)), GlyphIndex(rec.U16(2))
+		if gid < first || gid > last {
+			continue
+		}
+		link := makeLink32(rec.U32(4), s.subtablesBase, "IndexSubTable")
+		sub := binarySegm(link.Jump().Bytes())
+		img, imgFormat, metrics, ok := locateCBDTImage(sub, gid, first, last, s.imageData)
+		if !ok {
+			return BitmapGlyph{}, false
+		}
+		return decodeCBDTImage(img, imgFormat, metrics, uint16(s.PPEmY))
+	}
+	return BitmapGlyph{}, false
+}
+
+// locateCBDTImage finds the image data for a glyph, given an IndexSubTable.
+// It returns the image data, the image format and—for index formats 2 and 5—the
+// glyph metrics common to all glyphs of the sub-table.
+func locateCBDTImage(sub binarySegm, gid, first, last GlyphIndex, cbdt binarySegm) (
+	binarySegm, uint16, binarySegm, bool) {
+	//
+	// IndexSubHeader:
+	// uint16    indexFormat
+	// uint16    imageFormat
+	// Offset32  imageDataOffset   offset to image data in CBDT table
+	if sub.Size() < 8 {
+		return nil, 0, nil, false
+	}
+	indexFormat, imgFormat, imgOffset := sub.U16(0), sub.U16(2), int(sub.U32(4))
+	var start, end int
+	var metrics binarySegm
+	switch indexFormat {
+	case 1: // Offset32 sbitOffsets[lastGlyphIndex - firstGlyphIndex + 2]
+		k := int(gid - first)
+		s, err1 := sub.u32(8 + k*4)
+		e, err2 := sub.u32(8 + k*4 + 4)
+		if err1 != nil || err2 != nil {
+			return nil, 0, nil, false
+		}
+		start, end = imgOffset+int(s), imgOffset+int(e)
+	case 2: // uint32 imageSize, BigGlyphMetrics bigMetrics
+		size := int(sub.U32(8))
+		metrics, _ = sub.view(12, 8)
+		start = imgOffset + size*int(gid-first)
+		end = start + size
+	case 3: // Offset16 sbitOffsets[lastGlyphIndex - firstGlyphIndex + 2]
+		k := int(gid - first)
+		s, err1 := sub.u16(8 + k*2)
+		e, err2 := sub.u16(8 + k*2 + 2)
+		if err1 != nil || err2 != nil {
+			return nil, 0, nil, false
+		}
+		start, end = imgOffset+int(s), imgOffset+int(e)
+	case 4: // uint32 numGlyphs, GlyphIdOffsetPair glyphArray[numGlyphs + 1]
+		if sub.Size() < 12 {
+			return nil, 0, nil, false
+		}
+		// numGlyphs is untrusted: bound it by the pairs fitting into the sub-table,
+		// keeping in mind that glyphArray has numGlyphs+1 entries
+		n := int(sub.U32(8))
+		if pairs := (sub.Size() - 12) / 4; n > pairs-1 {
+			n = pairs - 1
+		}
+		found := false
+		for k := 0; k < n; k++ {
+			if GlyphIndex(sub.U16(12+k*4)) == gid {
+				start = imgOffset + int(sub.U16(12+k*4+2))
+				end = imgOffset + int(sub.U16(12+k*4+6))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, 0, nil, false
+		}
+	case 5: // uint32 imageSize, BigGlyphMetrics bigMetrics, uint32 numGlyphs, uint16 glyphIdArray[numGlyphs]
+		if sub.Size() < 24 {
+			return nil, 0, nil, false
+		}
+		size := int(sub.U32(8))
+		metrics, _ = sub.view(12, 8)
+		n := int(sub.U32(20))
+		if ids := (sub.Size() - 24) / 2; n > ids {
+			n = ids
+		}
+		found := false
+		for k := 0; k < n; k++ {
+			if GlyphIndex(sub.U16(24+k*2)) == gid {
+				start = imgOffset + size*k
+				end = start + size
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, 0, nil, false
+		}
+	default:
+		tracer().Infof("CBLC index sub-table format %d not supported", indexFormat)
+		return nil, 0, nil, false
+	}
+	img, err := cbdt.view(start, end-start)
+	if err != nil {
+		return nil, 0, nil, false
+	}
+	return img, imgFormat, metrics, true
+}
+
+// decodeCBDTImage interprets a CBDT image record. The CBDT specification
+// defines three image formats, all of them for PNG data:
+//
+//	17  smallGlyphMetrics, uint32 dataLen, uint8 data[dataLen]
+//	18  bigGlyphMetrics, uint32 dataLen, uint8 data[dataLen]
+//	19  uint32 dataLen, uint8 data[dataLen]   (metrics in CBLC)
+func decodeCBDTImage(img binarySegm, format uint16, metrics binarySegm, ppem uint16) (BitmapGlyph, bool) {
+	glyph := BitmapGlyph{PPEm: ppem, GraphicType: T("png ")}
+	var at int
+	switch format {
+	case 17:
+		metrics, _ = img.view(0, 5)
+		at = 5
+	case 18:
+		metrics, _ = img.view(0, 8)
+		at = 8
+	case 19:
+		at = 0
+	default:
+		tracer().Infof("CBDT image format %d not supported", format)
+		return BitmapGlyph{}, false
+	}
+	dataLen, err := img.u32(at)
+	if err != nil {
+		return BitmapGlyph{}, false
+	}
+	data, err := img.view(at+4, int(dataLen))
+	if err != nil {
+		return BitmapGlyph{}, false
+	}
+	glyph.Data = data
+	glyph.Metrics = decodeBitmapMetrics(metrics)
+	glyph.OriginX = int16(glyph.Metrics.BearingX)
+	glyph.OriginY = int16(glyph.Metrics.BearingY) - int16(glyph.Metrics.Height)
+	return glyph, true
+}
+
+// decodeBitmapMetrics decodes small (5 bytes) or big (8 bytes) glyph metrics.
+func decodeBitmapMetrics(b binarySegm) BitmapMetrics {
+	m := BitmapMetrics{}
+	switch len(b) {
+	case 5:
+		m.Height, m.Width = b[0], b[1]
+		m.BearingX, m.BearingY = int8(b[2]), int8(b[3])
+		m.Advance = b[4]
+	case 8:
+		m.Height, m.Width = b[0], b[1]
+		m.BearingX, m.BearingY = int8(b[2]), int8(b[3])
+		m.Advance = b[4]
+		m.VertBearingX, m.VertBearingY = int8(b[5]), int8(b[6])
+		m.VertAdvance = b[7]
+		m.HasVerticalParts = true
+	}
+	return m
+}
@@ -0,0 +1,168 @@
+package ot
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestColrCPal(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	colr := binarySegm{
+		0, 0, // version 0
+		0, 2, // 2 base glyph records
+		0, 0, 0, 14, // base glyph records at 14
+		0, 0, 0, 26, // layer records at 26
+		0, 3, // 3 layer records
+		// base glyph records: glyph, first layer, number of layers
+		0, 5, 0, 0, 0, 2,
+		0, 9, 0, 2, 0, 1,
+		// layer records: glyph, palette index
+		0, 20, 0, 1,
+		0, 21, 0xff, 0xff,
+		0, 22, 0, 0,
+	}
+	table, err := parseColr(T("COLR"), colr, 0, uint32(len(colr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := table.Self().AsColr()
+	if c.BaseGlyphCount() != 2 {
+		t.Errorf("expected COLR table to have 2 base glyphs, has %d", c.BaseGlyphCount())
+	}
+	layers := c.Layers(5)
+	if len(layers) != 2 || layers[0].Glyph != 20 || layers[1].PaletteIndex != ForegroundPaletteIndex {
+		t.Errorf("unexpected layers for glyph 5: %v", layers)
+	}
+	if layers := c.Layers(7); layers != nil {
+		t.Errorf("expected glyph 7 to have no color layers, has %v", layers)
+	}
+	cpal := binarySegm{
+		0, 0, // version 0
+		0, 2, // 2 entries per palette
+		0, 1, // 1 palette
+		0, 2, // 2 color records
+		0, 0, 0, 14, // color records at 14
+		0, 0, // palette 0 starts at color record 0
+		// color records: BGRA
+		0x10, 0x20, 0x30, 0xff,
+		0x40, 0x50, 0x60, 0x80,
+	}
+	table, err = parseCPal(T("CPAL"), cpal, 0, uint32(len(cpal)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := table.Self().AsCPal()
+	col, ok := p.Color(0, 1)
+	if !ok || col.R != 0x60 || col.G != 0x50 || col.B != 0x40 || col.A != 0x80 {
+		t.Errorf("unexpected color for palette entry 1: %v", col)
+	}
+	if _, ok := p.Color(1, 0); ok {
+		t.Errorf("expected palette 1 to not exist")
+	}
+	if len(p.Palette(0)) != 2 {
+		t.Errorf("expected palette 0 to have 2 colors, has %d", len(p.Palette(0)))
+	}
+}
+
+func TestSbixStrike(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	sbix := binarySegm{
+		0, 1, // version
+		0, 1, // flags
+		0, 0, 0, 1, // 1 strike
+		0, 0, 0, 12, // strike at 12
+		// strike: ppem, ppi, 3 glyph data offsets (for 2 glyphs)
+		0, 64, 0, 72,
+		0, 0, 0, 16,
+		0, 0, 0, 16, // glyph 0 has no data
+		0, 0, 0, 27, // glyph 1 has 11 bytes
+		0, 1, 0xff, 0xfe, 'p', 'n', 'g', ' ', 1, 2, 3,
+	}
+	table, err := parseSbix(T("sbix"), sbix, 0, uint32(len(sbix)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := table.Self().AsSbix()
+	s.numGlyphs = 2
+	strike := s.Strike(0)
+	if strike.PPEm != 64 {
+		t.Errorf("expected strike to have ppem 64, has %d", strike.PPEm)
+	}
+	if _, ok := strike.Glyph(0); ok {
+		t.Errorf("expected glyph 0 to have no bitmap")
+	}
+	g, ok := strike.Glyph(1)
+	if !ok {
+		t.Fatalf("expected glyph 1 to have a bitmap")
+	}
+	if g.GraphicType != T("png ") || g.OriginY != -2 || len(g.Data) != 3 {
+		t.Errorf("unexpected bitmap glyph: %v", g)
+	}
+}
+
+func TestCBLCStrike(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	cblc := binarySegm{
+		0, 3, 0, 0, // version 3.0
+		0, 0, 0, 1, // 1 bitmap size
+		// bitmap size record: index sub-table array at 56 with 2 sub-tables
+		0, 0, 0, 56, 0, 0, 0, 36, 0, 0, 0, 2, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // hori line metrics
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // vert line metrics
+		0, 3, 0, 9, // glyphs 3 to 9
+		109, 109, 32, 1, // ppem x and y, bit depth, flags
+		// index sub-table array: first glyph, last glyph, offset
+		0, 3, 0, 4, 0, 0, 0, 16,
+		0, 7, 0, 9, 0, 0, 0, 36,
+		// index format 1, image format 17, image data at 4, offsets for glyphs 3 and 4
+		0, 1, 0, 17, 0, 0, 0, 4,
+		0, 0, 0, 0, 0, 0, 0, 12, 0, 0, 0, 12,
+		// index format 4, image format 19, image data at 16, bogus number of glyphs
+		0, 4, 0, 19, 0, 0, 0, 16,
+		0, 0, 0xff, 0xff,
+		0, 8, 0, 0, // glyph 8 at 0
+		0, 0, 0, 7, // end of glyph array
+	}
+	cbdt := binarySegm{
+		0, 3, 0, 0, // version 3.0
+		// image format 17: small glyph metrics, length, PNG data
+		3, 2, 1, 3, 4, 0, 0, 0, 3, 'a', 'b', 'c',
+		// image format 19: length, PNG data
+		0, 0, 0, 3, 1, 2, 3,
+	}
+	table, err := parseCBLC(T("CBLC"), cblc, 0, uint32(len(cblc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := table.Self().AsCBLC()
+	c.imageData = cbdt
+	if c.StrikeCount() != 1 {
+		t.Fatalf("expected CBLC table to have 1 strike, has %d", c.StrikeCount())
+	}
+	strike := c.Strike(0)
+	if strike.PPEmY != 109 || strike.StartGlyph != 3 || strike.EndGlyph != 9 {
+		t.Errorf("unexpected strike: %v", strike)
+	}
+	g, ok := strike.Glyph(3)
+	if !ok {
+		t.Fatalf("expected glyph 3 to have a bitmap")
+	}
+	if g.GraphicType != T("png ") || string(g.Data) != "abc" || g.Metrics.Width != 2 || g.OriginX != 1 || g.OriginY != 0 {
+		t.Errorf("unexpected bitmap glyph: %v", g)
+	}
+	if g, ok = strike.Glyph(8); !ok || len(g.Data) != 3 || g.PPEm != 109 {
+		t.Errorf("expected glyph 8 to have a bitmap of 3 bytes, is %v", g)
+	}
+	for _, gid := range []GlyphIndex{4, 9, 10} {
+		if _, ok := strike.Glyph(gid); ok {
+			t.Errorf("expected glyph %d to have no bitmap", gid)
+		}
+	}
+}
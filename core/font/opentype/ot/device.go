@@ -0,0 +1,328 @@
+package ot
+
+import (
+	"fmt"
+	"math"
+)
+
+// --- Device tables ---------------------------------------------------------
+
+// Formats of Device tables. Local formats hold hinting deltas for a range of sizes;
+// format VariationIndexFormat marks a VariationIndex table, which links to deltas
+// for variable fonts in the font's ItemVariationStore.
+const (
+	DeltaFormatLocal2Bit uint16 = 0x0001 // signed 2-bit deltas, 8 per uint16
+	DeltaFormatLocal4Bit uint16 = 0x0002 // signed 4-bit deltas, 4 per uint16
+	DeltaFormatLocal8Bit uint16 = 0x0003 // signed 8-bit deltas, 2 per uint16
+	VariationIndexFormat uint16 = 0x8000 // VariationIndex table
+)
+
+// Device is a Device table or a VariationIndex table, which ValueRecords, Anchors
+// and other tables may link to.
+//
+// Device tables adjust positions of glyphs by whole pixels, for a range of sizes
+// in pixels per em (ppem), to improve the rendering of hinted glyphs at small sizes.
+// VariationIndex tables share the layout of Device tables and select a delta-set
+// of the ItemVariationStore (see GDefTable.ItemVariationStore).
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#device-and-variationindex-tables
+type Device struct {
+	StartSize   uint16 // smallest size to correct, in ppem; outer index for VariationIndex tables
+	EndSize     uint16 // largest size to correct, in ppem; inner index for VariationIndex tables
+	DeltaFormat uint16 // format of deltas, see DeltaFormatLocal2Bit etc.
+	deltas      binarySegm
+}
+
+// Device table:
+//
+//	uint16  startSize          Smallest size to correct, in ppem
+//	uint16  endSize            Largest size to correct, in ppem
+//	uint16  deltaFormat        Format of deltaValue array data
+//	uint16  deltaValue[ ]      Array of compressed data
+//
+// VariationIndex table:
+//
+//	uint16  deltaSetOuterIndex  A delta-set outer index, used to select an item variation data subtable
+//	uint16  deltaSetInnerIndex  A delta-set inner index, used to select a delta-set row
+//	uint16  deltaFormat         Format, = 0x8000
+
+// ParseDevice reads a Device table or a VariationIndex table from loc.
+func ParseDevice(loc NavLocation) (Device, error) {
+	b := binarySegm(loc.Bytes())
+	if len(b) < 6 {
+		return Device{}, errBufferBounds
+	}
+	d := Device{StartSize: b.U16(0), EndSize: b.U16(2), DeltaFormat: b.U16(4)}
+	switch d.DeltaFormat {
+	case VariationIndexFormat:
+		return d, nil
+	case DeltaFormatLocal2Bit, DeltaFormatLocal4Bit, DeltaFormatLocal8Bit:
+		if d.EndSize < d.StartSize {
+			return Device{}, errFontFormat("Device table with endSize < startSize")
+		}
+		perWord := 16 >> d.DeltaFormat // 8, 4 or 2 deltas per uint16
+		n := (int(d.EndSize-d.StartSize) + perWord) / perWord
+		if len(b) < 6+n*2 {
+			return Device{}, errBufferBounds
+		}
+		d.deltas = b[6 : 6+n*2]
+		return d, nil
+	}
+	return Device{}, errFontFormat(fmt.Sprintf("unknown Device table format %#x", d.DeltaFormat))
+}
+
+// DeviceAt reads a Device table or VariationIndex table at offset off from the
+// start of table parent. Offsets of 0 denote missing tables, in which case false is
+// returned.
+func DeviceAt(parent NavLocation, off uint16) (Device, bool) {
+	if off == 0 || int(off) >= parent.Size() {
+		return Device{}, false
+	}
+	d, err := ParseDevice(binarySegm(parent.Bytes())[off:])
+	if err != nil {
+		tracer().Errorf("Device table: %v", err)
+		return Device{}, false
+	}
+	return d, true
+}
+
+// IsVariationIndex returns true if d is a VariationIndex table.
+func (d Device) IsVariationIndex() bool {
+	return d.DeltaFormat == VariationIndexFormat
+}
+
+// VariationIndex returns the outer and inner index of the delta-set a VariationIndex
+// table selects.
+func (d Device) VariationIndex() (outer, inner uint16) {
+	return d.StartSize, d.EndSize
+}
+
+// Delta returns the adjustment in pixels for a size of ppem pixels per em. For
+// sizes outside of the range of the Device table, and for VariationIndex tables,
+// Delta returns 0.
+func (d Device) Delta(ppem uint16) int {
+	if d.IsVariationIndex() || ppem < d.StartSize || ppem > d.EndSize || len(d.deltas) == 0 {
+		return 0
+	}
+	bits := 1 << d.DeltaFormat // 2, 4 or 8 bits per delta
+	perWord := 16 / bits
+	i := int(ppem - d.StartSize)
+	word := d.deltas.U16(i / perWord * 2)
+	shift := 16 - bits*(i%perWord+1) // deltas are packed starting with the high bits
+	v := int(word>>shift) & (1<<bits - 1)
+	if v >= 1<<(bits-1) { // sign-extend
+		v -= 1 << bits
+	}
+	return v
+}
+
+// --- Item variation store --------------------------------------------------
+
+// ItemVariationStore holds deltas for variable fonts, as referenced by
+// VariationIndex tables. Deltas are organized in delta-sets, one for every
+// adjustable item, and contain a delta for each of a set of regions in the font's
+// design space.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#item-variation-store
+type ItemVariationStore struct {
+	axisCount int
+	regions   array // VariationRegion records
+	data      []binarySegm
+}
+
+// ItemVariationStore table:
+//
+//	uint16    format                                        Format, = 1
+//	Offset32  variationRegionListOffset                     Offset to the variation region list
+//	uint16    itemVariationDataCount                        Number of item variation data subtables
+//	Offset32  itemVariationDataOffsets[itemVariationDataCount]
+//
+// VariationRegionList:
+//
+//	uint16           axisCount     Number of variation axes
+//	uint16           regionCount   Number of variation region tables
+//	VariationRegion  variationRegions[regionCount]
+//
+// VariationRegion holds a RegionAxisCoordinates record { F2DOT14 startCoord, peakCoord,
+// endCoord } for every axis.
+
+// ParseItemVariationStore reads an ItemVariationStore from loc.
+func ParseItemVariationStore(loc NavLocation) (*ItemVariationStore, error) {
+	b := binarySegm(loc.Bytes())
+	if len(b) < 8 {
+		return nil, errBufferBounds
+	}
+	if format := b.U16(0); format != 1 {
+		return nil, errFontFormat(fmt.Sprintf("unknown ItemVariationStore format %d", format))
+	}
+	store := &ItemVariationStore{}
+	off := int(b.U32(2))
+	if off == 0 || len(b) < off+4 {
+		return nil, errBufferBounds
+	}
+	store.axisCount = int(b.U16(off))
+	regionCount := int(b.U16(off + 2))
+	size := store.axisCount * 6
+	if len(b) < off+4+regionCount*size {
+		return nil, errBufferBounds
+	}
+	store.regions = array{recordSize: size, length: regionCount, loc: b[off+4:]}
+	n := int(b.U16(6))
+	if len(b) < 8+n*4 {
+		return nil, errBufferBounds
+	}
+	store.data = make([]binarySegm, n)
+	for i := range store.data {
+		off := int(b.U32(8 + i*4))
+		if off == 0 || len(b) < off+6 {
+			return nil, errBufferBounds
+		}
+		store.data[i] = b[off:]
+	}
+	return store, nil
+}
+
+// ItemVariationData subtable:
+//
+//	uint16    itemCount                       Number of delta sets for this subtable
+//	uint16    wordDeltaCount                  Flag LONG_WORDS (0x8000) and count of ‘word’ deltas
+//	uint16    regionIndexCount                Number of variation regions referenced
+//	uint16    regionIndexes[regionIndexCount] Indexes into the variation region list
+//	DeltaSet  deltaSets[itemCount]            Delta-set rows
+//
+// A delta-set row holds wordDeltaCount deltas of int16 (int32 for LONG_WORDS), followed
+// by deltas of int8 (int16 for LONG_WORDS) for the remaining regions.
+
+// Delta returns the delta, in design units, for a delta-set of the store at a
+// position in the design space of a variable font. coords holds a normalized
+// coordinate in [-1…1] for each variation axis, where missing coordinates count
+// as 0, i.e. the default instance.
+func (store *ItemVariationStore) Delta(outer, inner uint16, coords []float64) float64 {
+	if store == nil || int(outer) >= len(store.data) {
+		return 0
+	}
+	b := store.data[outer]
+	itemCount, wordDeltaCount, regionIndexCount := int(b.U16(0)), int(b.U16(2)), int(b.U16(4))
+	if int(inner) >= itemCount {
+		return 0
+	}
+	wordSize := 2
+	if wordDeltaCount&0x8000 != 0 { // LONG_WORDS
+		wordSize = 4
+	}
+	wordCount := wordDeltaCount & 0x7fff
+	if wordCount > regionIndexCount {
+		return 0
+	}
+	rowSize := wordCount*wordSize + (regionIndexCount-wordCount)*wordSize/2
+	row := 6 + regionIndexCount*2 + int(inner)*rowSize
+	if len(b) < row+rowSize {
+		return 0
+	}
+	var delta float64
+	at := row
+	for i := 0; i < regionIndexCount; i++ {
+		var d int32
+		size := wordSize / 2
+		if i < wordCount {
+			size = wordSize
+		}
+		switch size {
+		case 1:
+			d = int32(int8(b[at]))
+		case 2:
+			d = int32(int16(b.U16(at)))
+		case 4:
+			d = int32(b.U32(at))
+		}
+		at += size
+		if d == 0 {
+			continue
+		}
+		delta += float64(d) * store.regionScalar(int(b.U16(6+i*2)), coords)
+	}
+	return delta
+}
+
+// regionScalar returns the scalar for a delta of a region, at a position in the
+// design space of a variable font.
+func (store *ItemVariationStore) regionScalar(region int, coords []float64) float64 {
+	if region >= store.regions.length {
+		return 0
+	}
+	r := store.regions.Get(region)
+	scalar := 1.0
+	for axis := 0; axis < store.axisCount; axis++ {
+		start := f2dot14(r.U16(axis * 6))
+		peak := f2dot14(r.U16(axis*6 + 2))
+		end := f2dot14(r.U16(axis*6 + 4))
+		if peak == 0 || start > peak || peak > end || (start < 0 && end > 0) {
+			continue // axis does not participate
+		}
+		var coord float64
+		if axis < len(coords) {
+			coord = coords[axis]
+		}
+		switch {
+		case coord < start || coord > end:
+			return 0
+		case coord < peak:
+			scalar *= (coord - start) / (peak - start)
+		case coord > peak:
+			scalar *= (end - coord) / (end - peak)
+		}
+	}
+	return scalar
+}
+
+func f2dot14(v uint16) float64 {
+	return float64(int16(v)) / 16384
+}
+
+// ItemVariationStore returns the item variation store of a GDEF table, or nil if
+// the GDEF table has none. Fonts which are not variable fonts won't have one.
+func (t *GDefTable) ItemVariationStore() *ItemVariationStore {
+	off := t.header.offsetFor(GDefItemVarStoreSection)
+	if off == 0 || off >= len(t.data) {
+		return nil
+	}
+	store, err := ParseItemVariationStore(t.data[off:])
+	if err != nil {
+		tracer().Errorf("GDEF item variation store: %v", err)
+		return nil
+	}
+	return store
+}
+
+// --- Instances -------------------------------------------------------------
+
+// Instance selects a size and a position in the design space of a font, for which
+// to resolve the deltas of Device tables and VariationIndex tables.
+type Instance struct {
+	PPEM       uint16              // size in pixels per em; 0 for unhinted positioning
+	UnitsPerEm uint16              // design units per em of the font
+	Coords     []float64           // normalized variation coordinates; nil for the default instance
+	Store      *ItemVariationStore // item variation store of the font, may be nil
+}
+
+// Delta returns the adjustment a Device table or a VariationIndex table defines for
+// an instance, in design units.
+func (inst Instance) Delta(d Device) float64 {
+	if d.IsVariationIndex() {
+		outer, inner := d.VariationIndex()
+		return inst.Store.Delta(outer, inner, inst.Coords)
+	}
+	if inst.PPEM == 0 || inst.UnitsPerEm == 0 {
+		return 0
+	}
+	return float64(d.Delta(inst.PPEM)) * float64(inst.UnitsPerEm) / float64(inst.PPEM)
+}
+
+// adjust adds the delta of the Device table at offset off of table parent to v.
+func (inst Instance) adjust(v int16, parent NavLocation, off uint16) int16 {
+	d, ok := DeviceAt(parent, off)
+	if !ok {
+		return v
+	}
+	return int16(math.Round(float64(v) + inst.Delta(d)))
+}
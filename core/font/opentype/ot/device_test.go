@@ -0,0 +1,71 @@
+package ot
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestDeviceDeltas(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	b := binarySegm{
+		0xff, 0xff, // some preceding field
+		0, 11, 0, 15, 0, 2, // sizes 11…15, 4-bit deltas
+		0x1f, 0x02, 0x80, 0x00, // +1 -1 0 +2 | -8
+	}
+	d, ok := DeviceAt(b, 2)
+	if !ok {
+		t.Fatalf("expected Device table at offset 2")
+	}
+	for ppem, delta := range map[uint16]int{10: 0, 11: 1, 12: -1, 13: 0, 14: 2, 15: -8, 16: 0} {
+		if d.Delta(ppem) != delta {
+			t.Errorf("expected delta at %d ppem to be %d, is %d", ppem, delta, d.Delta(ppem))
+		}
+	}
+	vr := ValueRecord{Format: ValueXAdvance | ValueXAdvDevice, XAdvance: 100, XAdvDevice: 2}
+	if r := vr.Resolve(b, Instance{PPEM: 14, UnitsPerEm: 1000}); r.XAdvance != 243 {
+		t.Errorf("expected advance at 14 ppem to be 243, is %d", r.XAdvance)
+	}
+	if r := vr.Resolve(b, Instance{}); r.XAdvance != 100 {
+		t.Errorf("expected unhinted advance to be 100, is %d", r.XAdvance)
+	}
+	if _, err := ParseDevice(binarySegm{0, 11, 0, 15, 0, 3, 0, 0}); err == nil {
+		t.Errorf("expected truncated Device table to be rejected")
+	}
+}
+
+func TestVariationIndex(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	store, err := ParseItemVariationStore(binarySegm{
+		0, 1, 0, 0, 0, 12, // format 1, regions at 12
+		0, 1, 0, 0, 0, 22, // 1 item variation data subtable at 22
+		0, 1, 0, 1, // 1 axis, 1 region
+		0, 0, 0x40, 0, 0x40, 0, // region 0…1, peak at 1
+		0, 1, 0, 0, 0, 1, 0, 0, // 1 item, no word deltas, region 0
+		50, // delta set #0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []struct {
+		coord, delta float64
+	}{{0, 0}, {0.5, 25}, {1, 50}, {-0.5, 0}} {
+		if d := store.Delta(0, 0, []float64{x.coord}); d != x.delta {
+			t.Errorf("expected delta at %.1f to be %.1f, is %.1f", x.coord, x.delta, d)
+		}
+	}
+	anchor, err := ParseAnchor(binarySegm{
+		0, 3, 0, 100, 0, 200, 0, 10, 0, 0, // format 3, x device at 10
+		0, 0, 0, 0, 0x80, 0, // VariationIndex 0/0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := anchor.Resolve(Instance{Coords: []float64{0.5}, Store: store}); a.X != 125 || a.Y != 200 {
+		t.Errorf("expected anchor at (125,200), is at (%d,%d)", a.X, a.Y)
+	}
+}
@@ -207,5 +207,15 @@ func tracer() tracing.Trace {
 
 // errFontFormat produces user level errors for font parsing.
 func errFontFormat(x string) error {
-	return core.Error(core.EINVALID, "OpenType font format: %s", x)
+	return core.ErrorIn(core.ErrFontFormat, core.EINVALID, "OpenType font format: %s", x)
+}
+
+// errTableMissing produces user level errors for fonts lacking a table.
+func errTableMissing(x string) error {
+	return core.ErrorIn(core.ErrTableMissing, core.EMISSING, "OpenType font: %s", x)
+}
+
+// errUnsupported produces user level errors for valid, but unsupported fonts.
+func errUnsupported(x string) error {
+	return core.ErrorIn(core.ErrUnsupported, core.EINVALID, "OpenType font: %s", x)
 }
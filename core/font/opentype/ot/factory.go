@@ -80,8 +80,8 @@ func NavigatorFactory(obj string, loc NavLocation, base NavLocation) Navigator {
 			tmap: parseTagRecordMap16(loc.Bytes(), 2, loc.Bytes(), "Script", "LangSys"),
 		}
 	case "LangSys":
-		tracer().Debugf("%s[0] = %x", obj, u16(loc.Bytes()))
-		tracer().Debugf("%s[2] = %x", obj, u16(loc.Bytes()[2:]))
+		tracer().Debugf("%s[0] = %x", obj, loc.U16(0))
+		tracer().Debugf("%s[2] = %x", obj, loc.U16(2))
 		lsys, err := parseLangSys(loc.Bytes(), 2, "Feature-Index")
 		if err != nil {
 			return null(err)
@@ -116,7 +116,11 @@ func NavigatorFactory(obj string, loc NavLocation, base NavLocation) Navigator {
 	if fields, ok := tableFields[obj]; ok {
 		tracer().Debugf("object %s has fields %v", obj, fields)
 		size := int(fields[0]) // total byte size of fields
-		f := otFields{pattern: fields[1:], b: base.Bytes()[:size]}
+		b, err := binarySegm(base.Bytes()).view(0, size)
+		if err != nil {
+			return null(errFontFormat(obj + " out of bounds"))
+		}
+		f := otFields{pattern: fields[1:], b: b}
 		return list{navName: navName{name: obj}, f: f}
 	}
 	tracer().Debugf("no navigator found -> null navigator")
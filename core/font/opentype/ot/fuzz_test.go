@@ -0,0 +1,326 @@
+package ot
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// FuzzParse checks that Parse does not panic on malformed fonts. Seeds are a
+// synthesized minimal font, a synthesized font with color, justification and
+// hinting tables, and the fonts available to the tests.
+//
+//	go test -run=^$ -fuzz=FuzzParse ./core/font/opentype/ot
+func FuzzParse(f *testing.F) {
+	fb := NewFontBuilder(TrueTypeFont)
+	fb.AddTable(T("cmap"), buildCMap('A', 'Z', 1))
+	fb.AddTable(T("head"), buildHead(1000))
+	fb.AddTable(T("kern"), buildKern())
+	for _, tag := range []string{"hhea", "hmtx", "maxp", "name", "OS/2", "post"} {
+		fb.AddTable(T(tag), make([]byte, 36))
+	}
+	fb.AddTable(T("GSUB"), buildLayoutTable())
+	fb.AddTable(T("GPOS"), buildLayoutTable())
+	fb.AddTable(T("GDEF"), buildGDef())
+	f.Add(fb.Bytes())
+	fb.AddTable(T("maxp"), buildMaxP(4))
+	colr, cpal := buildColrCPal()
+	fb.AddTable(T("COLR"), colr)
+	fb.AddTable(T("CPAL"), cpal)
+	fb.AddTable(T("sbix"), buildSbix(4))
+	cblc, cbdt := buildCBLC()
+	fb.AddTable(T("CBLC"), cblc)
+	fb.AddTable(T("CBDT"), cbdt)
+	fb.AddTable(T("JSTF"), buildJstf())
+	fb.AddTable(T("gasp"), buildGasp())
+	fb.AddTable(T("hdmx"), buildHdmx(4))
+	fb.AddTable(T("VDMX"), buildVDMX())
+	f.Add(fb.Bytes())
+	f.Add(goregular.TTF)
+	if gentium, err := os.ReadFile("../../../locate/resources/packaged/fonts/GentiumPlus-R.ttf"); err == nil {
+		f.Add(gentium)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		otf, err := Parse(data, EagerParsing())
+		if err != nil {
+			return
+		}
+		if cmap := otf.Table(T("cmap")).Self().AsCMap(); cmap != nil {
+			for _, r := range "Aa0ß→\U0001F600" {
+				cmap.GlyphIndexMap.Lookup(r)
+				cmap.GlyphIndexMap.ReverseLookup(GlyphIndex(r & 0xffff))
+			}
+		}
+		if otf.Layout.GSub != nil {
+			walkLayoutTable(&otf.Layout.GSub.LayoutTable)
+		}
+		if otf.Layout.GPos != nil {
+			walkLayoutTable(&otf.Layout.GPos.LayoutTable)
+		}
+		walkColorAndHintTables(otf)
+	})
+}
+
+// walkLayoutTable visits the features and lookups of a layout table, including
+// all lookup sub-tables.
+func walkLayoutTable(lytt *LayoutTable) {
+	if lytt.ScriptList != nil && lytt.ScriptList.Map().IsTagRecordMap() {
+		scripts := lytt.ScriptList.Map().AsTagRecordMap()
+		for _, tag := range scripts.Tags() {
+			if langs := scripts.LookupTag(tag).Navigate().Map(); langs.IsTagRecordMap() {
+				for _, lang := range langs.AsTagRecordMap().Tags() {
+					langs.LookupTag(lang).Navigate().List()
+				}
+			}
+		}
+	}
+	if lytt.FeatureList != nil {
+		for _, tag := range lytt.FeatureList.Tags() {
+			lytt.FeatureList.LookupTag(tag).Navigate()
+		}
+	}
+	for i := 0; i < lytt.LookupList.Len(); i++ {
+		lookup := lytt.LookupList.Navigate(i)
+		for j := 0; j < int(lookup.SubTableCount); j++ {
+			if sub := lookup.Subtable(j); sub != nil && sub.Coverage.GlyphRange != nil {
+				if inx, ok := sub.Coverage.GlyphRange.Match(GlyphIndex(j)); ok && sub.Index != nil {
+					sub.Index.Get(inx, true)
+				}
+			}
+		}
+	}
+}
+
+// walkColorAndHintTables queries the color, justification and hinting tables of
+// a font, as far as present. Loops over counts taken from the font are capped, as
+// malformed fonts may state huge counts.
+func walkColorAndHintTables(otf *Font) {
+	self := func(tag string) TableSelf {
+		if t := otf.Table(T(tag)); t != nil {
+			return t.Self()
+		}
+		return TableSelf{}
+	}
+	const maxGlyph = GlyphIndex(16)
+	if colr := self("COLR").AsColr(); colr != nil {
+		for gid := GlyphIndex(0); gid < maxGlyph; gid++ {
+			colr.Layers(gid)
+		}
+	}
+	if cpal := self("CPAL").AsCPal(); cpal != nil {
+		for i := 0; i < 2; i++ {
+			cpal.Palette(i)
+			cpal.Color(i, 1)
+		}
+	}
+	if sbix := self("sbix").AsSbix(); sbix != nil {
+		for i := 0; i < sbix.StrikeCount() && i < 4; i++ {
+			strike := sbix.Strike(i)
+			for gid := GlyphIndex(0); gid < maxGlyph; gid++ {
+				strike.Glyph(gid)
+			}
+		}
+	}
+	if cblc := self("CBLC").AsCBLC(); cblc != nil {
+		for i := 0; i < cblc.StrikeCount() && i < 4; i++ {
+			strike := cblc.Strike(i)
+			for gid := GlyphIndex(0); gid < maxGlyph; gid++ {
+				strike.Glyph(gid)
+			}
+		}
+	}
+	if jstf := self("JSTF").AsJstf(); jstf != nil {
+		for _, tag := range jstf.ScriptTags() {
+			script, _ := jstf.Script(tag)
+			script.ExtenderGlyphs()
+			script.IsExtender(1)
+			langsys := script.LangSys(T("dflt"))
+			for i := 0; i < langsys.PriorityCount() && i < 4; i++ {
+				langsys.Priority(i)
+			}
+		}
+	}
+	if gasp := self("gasp").AsGasp(); gasp != nil {
+		for _, ppem := range []uint16{8, 12, 0xffff} {
+			gasp.Behavior(ppem)
+		}
+	}
+	if hdmx := self("hdmx").AsHdmx(); hdmx != nil {
+		for i := 0; i < hdmx.RecordCount() && i < 4; i++ {
+			rec := hdmx.Record(i)
+			for gid := GlyphIndex(0); gid < maxGlyph; gid++ {
+				rec.Advance(gid)
+			}
+		}
+	}
+	if vdmx := self("VDMX").AsVDMX(); vdmx != nil {
+		vdmx.Bounds(12, 0, 0)
+		vdmx.Bounds(12, 96, 72)
+	}
+}
+
+// buildGDef builds a GDEF table (version 1.0) with empty glyph class definitions
+// and an empty attachment point list.
+func buildGDef() []byte {
+	var w TableWriter
+	w.U16Array([]uint16{1, 0}) // version 1.0
+	w.U16(12)                  // glyph class definitions
+	w.U16(16)                  // attachment point list
+	w.U16(0)                   // no ligature caret list
+	w.U16(12)                  // mark attachment class definitions
+	w.U16Array([]uint16{2, 0}) // class definitions format 2, no ranges
+	w.U16Array([]uint16{0, 0}) // attachment point list: no coverage, no glyphs
+	return w.Bytes()
+}
+
+// buildMaxP builds a maxp table (version 1.0) for a font with numGlyphs glyphs.
+func buildMaxP(numGlyphs uint16) []byte {
+	var w TableWriter
+	w.U32(0x00010000) // version 1.0
+	w.U16(numGlyphs)
+	w.Write(make([]byte, 26)) // remaining fields
+	return w.Bytes()
+}
+
+// buildColrCPal builds a COLR table (version 0) with two color layers for glyph 1,
+// and a CPAL table with a single palette of two colors.
+func buildColrCPal() ([]byte, []byte) {
+	var colr TableWriter
+	colr.U16(0)                              // version
+	colr.U16(1)                              // number of base glyph records
+	colr.U32(14)                             // base glyph records
+	colr.U32(20)                             // layer records
+	colr.U16(2)                              // number of layer records
+	colr.U16Array([]uint16{1, 0, 2})         // glyph, first layer, number of layers
+	colr.U16Array([]uint16{2, 0, 3, 0xffff}) // layers: glyph, palette index
+	var cpal TableWriter
+	cpal.U16Array([]uint16{0, 2, 1, 2}) // version, entries per palette, palettes, color records
+	cpal.U32(14)                        // color records
+	cpal.U16(0)                         // first color record of palette 0
+	cpal.Write([]byte{0x10, 0x20, 0x30, 0xff, 0x40, 0x50, 0x60, 0x80})
+	return colr.Bytes(), cpal.Bytes()
+}
+
+// buildSbix builds an sbix table with one strike, in which glyph 1 of numGlyphs
+// glyphs has a PNG bitmap.
+func buildSbix(numGlyphs int) []byte {
+	var w TableWriter
+	w.U16(1)  // version
+	w.U16(1)  // flags
+	w.U32(1)  // number of strikes
+	w.U32(12) // strike
+	w.U16(64) // ppem
+	w.U16(72) // ppi
+	glyph := []byte{0, 1, 0xff, 0xfe, 'p', 'n', 'g', ' ', 1, 2, 3}
+	start := uint32(4 + 4*(numGlyphs+1))
+	for i := 0; i <= numGlyphs; i++ { // glyph data offsets
+		if i <= 1 {
+			w.U32(start)
+		} else {
+			w.U32(start + uint32(len(glyph)))
+		}
+	}
+	w.Write(glyph)
+	return w.Bytes()
+}
+
+// buildCBLC builds a CBLC table with one strike for glyphs 1 and 2, and a CBDT
+// table with a PNG bitmap (image format 17) for glyph 1.
+func buildCBLC() ([]byte, []byte) {
+	var cblc TableWriter
+	cblc.U16Array([]uint16{3, 0}) // version 3.0
+	cblc.U32(1)                   // number of bitmap sizes
+	cblc.U32(56)                  // index sub-table array
+	cblc.U32(28)                  // size of index sub-tables
+	cblc.U32(1)                   // number of index sub-tables
+	cblc.U32(0)                   // color ref
+	cblc.Write(make([]byte, 24))  // hori and vert line metrics
+	cblc.U16Array([]uint16{1, 2}) // start and end glyph
+	cblc.Write([]byte{109, 109, 32, 1})
+	cblc.U16Array([]uint16{1, 2})  // index sub-table array: first and last glyph
+	cblc.U32(8)                    // index sub-table
+	cblc.U16Array([]uint16{1, 17}) // index format, image format
+	cblc.U32(4)                    // image data in CBDT
+	cblc.U32(0)                    // offset of glyph 1
+	cblc.U32(12)                   // offset of glyph 2 (no data)
+	cblc.U32(12)                   // end of glyph data
+	var cbdt TableWriter
+	cbdt.U16Array([]uint16{3, 0})     // version 3.0
+	cbdt.Write([]byte{3, 2, 1, 3, 4}) // small glyph metrics
+	cbdt.U32(3)                       // length of PNG data
+	cbdt.Write([]byte{'a', 'b', 'c'})
+	return cblc.Bytes(), cbdt.Bytes()
+}
+
+// buildJstf builds a JSTF table for script 'arab', with extender glyphs and a
+// default language system with one priority.
+func buildJstf() []byte {
+	var w TableWriter
+	w.U16Array([]uint16{1, 0}) // version 1.0
+	w.U16(1)                   // number of scripts
+	w.Tag(T("arab"))
+	w.U16(12)                            // script
+	w.U16Array([]uint16{6, 12, 0})       // extender glyphs, default language system, no records
+	w.U16Array([]uint16{2, 7, 9})        // extender glyphs
+	w.U16Array([]uint16{1, 4})           // default language system: 1 priority
+	w.Write(make([]byte, 10))            // no shrinkage
+	w.U16Array([]uint16{0, 0, 20, 0, 0}) // GPOS extension enable list
+	w.U16Array([]uint16{2, 3, 5})        // lookups
+	return w.Bytes()
+}
+
+// buildGasp builds a gasp table (version 1) with two ranges.
+func buildGasp() []byte {
+	var w TableWriter
+	w.U16Array([]uint16{1, 2})           // version, number of ranges
+	w.U16Array([]uint16{8, 0x0002})      // up to 8 ppem: grayscale
+	w.U16Array([]uint16{0xffff, 0x000f}) // above: grid-fitting and smoothing
+	return w.Bytes()
+}
+
+// buildHdmx builds an hdmx table with one device record for 12 ppem, for a font
+// with numGlyphs glyphs.
+func buildHdmx(numGlyphs int) []byte {
+	recsize := (2 + numGlyphs + 3) &^ 3 // 32-bit aligned
+	var w TableWriter
+	w.U16(0) // version
+	w.U16(1) // number of records
+	w.U32(uint32(recsize))
+	rec := make([]byte, recsize)
+	rec[0], rec[1] = 12, 7 // ppem, maximum width
+	for i := 0; i < numGlyphs; i++ {
+		rec[2+i] = byte(4 + i%4)
+	}
+	w.Write(rec)
+	return w.Bytes()
+}
+
+// buildVDMX builds a VDMX table with one aspect ratio matching any device, and
+// y-extents for 12 ppem.
+func buildVDMX() []byte {
+	var w TableWriter
+	w.U16Array([]uint16{1, 1, 1}) // version, number of groups, number of ratios
+	w.Write([]byte{0, 0, 0, 0})   // ratio range: any device
+	w.U16(12)                     // group
+	w.U16(1)                      // group: number of records
+	w.Write([]byte{12, 12})       // first and last ppem
+	w.U16(12)                     // ppem
+	w.I16(10)                     // yMax
+	w.I16(-3)                     // yMin
+	return w.Bytes()
+}
+
+// buildKern builds a kern table in OpenType format, with one format 0
+// sub-table and a single kern pair.
+func buildKern() []byte {
+	var w TableWriter
+	w.U16(0)                           // version
+	w.U16(1)                           // number of sub-tables
+	w.U16(0)                           // sub-table version
+	w.U16(14 + 6)                      // sub-table length
+	w.U16(0x0001)                      // coverage: horizontal, format 0
+	w.U16Array([]uint16{1, 6, 0, 0})   // nPairs, searchRange, entrySelector, rangeShift
+	w.U16Array([]uint16{1, 2, 0xffce}) // left, right, value
+	return w.Bytes()
+}
@@ -1,6 +1,9 @@
 package ot
 
-import "strconv"
+import (
+	"fmt"
+	"strconv"
+)
 
 // GPosTable is a type representing an OpenType GPOS table
 // (see https://docs.microsoft.com/en-us/typography/opentype/spec/gsub).
@@ -52,3 +55,216 @@ func (lt LayoutTableLookupType) GPosString() string {
 	}
 	return strconv.Itoa(int(lt))
 }
+
+// --- Value records ---------------------------------------------------------
+
+// ValueFormat is a bitfield which flags the fields present in a ValueRecord.
+// GPOS single and pair adjustment subtables use ValueRecords of the format(s) given
+// in the subtable, i.e., ValueRecords are of variable size.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gpos#value-record
+type ValueFormat uint16
+
+// Flags for fields present in a ValueRecord, in the order of their appearance.
+const (
+	ValueXPlacement ValueFormat = 0x0001 // includes horizontal adjustment for placement
+	ValueYPlacement ValueFormat = 0x0002 // includes vertical adjustment for placement
+	ValueXAdvance   ValueFormat = 0x0004 // includes horizontal adjustment for advance
+	ValueYAdvance   ValueFormat = 0x0008 // includes vertical adjustment for advance
+	ValueXPlaDevice ValueFormat = 0x0010 // includes Device table for horizontal placement
+	ValueYPlaDevice ValueFormat = 0x0020 // includes Device table for vertical placement
+	ValueXAdvDevice ValueFormat = 0x0040 // includes Device table for horizontal advance
+	ValueYAdvDevice ValueFormat = 0x0080 // includes Device table for vertical advance
+)
+
+// Size returns the size in bytes of a ValueRecord of format vf.
+func (vf ValueFormat) Size() int {
+	n := 0
+	for f := vf & 0x00ff; f != 0; f >>= 1 { // flags 0xff00 are reserved
+		n += int(f & 1)
+	}
+	return n * 2
+}
+
+// ValueRecord holds adjustments for the position of a glyph, in design units.
+// Fields not present in the ValueRecord's format are 0.
+//
+// Device offsets point to Device tables or VariationIndex tables, relative to the
+// start of the subtable containing the ValueRecord. An offset of 0 denotes that no
+// table is present.
+type ValueRecord struct {
+	Format     ValueFormat
+	XPlacement int16  // horizontal adjustment for placement
+	YPlacement int16  // vertical adjustment for placement
+	XAdvance   int16  // horizontal adjustment for advance
+	YAdvance   int16  // vertical adjustment for advance
+	XPlaDevice uint16 // offset to Device table for horizontal placement
+	YPlaDevice uint16 // offset to Device table for vertical placement
+	XAdvDevice uint16 // offset to Device table for horizontal advance
+	YAdvDevice uint16 // offset to Device table for vertical advance
+}
+
+// ParseValueRecord reads a ValueRecord of format vf from loc, starting at byte
+// offset at.
+func ParseValueRecord(loc NavLocation, at int, vf ValueFormat) (ValueRecord, error) {
+	vr := ValueRecord{Format: vf}
+	b := binarySegm(loc.Bytes())
+	if at < 0 || len(b) < at+vf.Size() {
+		return vr, errBufferBounds
+	}
+	next := func(flag ValueFormat) uint16 { // fields are present in the order of their flags
+		if vf&flag == 0 {
+			return 0
+		}
+		at += 2
+		return b.U16(at - 2)
+	}
+	vr.XPlacement = int16(next(ValueXPlacement))
+	vr.YPlacement = int16(next(ValueYPlacement))
+	vr.XAdvance = int16(next(ValueXAdvance))
+	vr.YAdvance = int16(next(ValueYAdvance))
+	vr.XPlaDevice = next(ValueXPlaDevice)
+	vr.YPlaDevice = next(ValueYPlaDevice)
+	vr.XAdvDevice = next(ValueXAdvDevice)
+	vr.YAdvDevice = next(ValueYAdvDevice)
+	return vr, nil
+}
+
+// Resolve returns a copy of a ValueRecord with the deltas of its Device tables or
+// VariationIndex tables applied for an instance. parent is the subtable containing
+// the ValueRecord, from which device offsets are counted.
+func (vr ValueRecord) Resolve(parent NavLocation, inst Instance) ValueRecord {
+	vr.XPlacement = inst.adjust(vr.XPlacement, parent, vr.XPlaDevice)
+	vr.YPlacement = inst.adjust(vr.YPlacement, parent, vr.YPlaDevice)
+	vr.XAdvance = inst.adjust(vr.XAdvance, parent, vr.XAdvDevice)
+	vr.YAdvance = inst.adjust(vr.YAdvance, parent, vr.YAdvDevice)
+	return vr
+}
+
+// IsZero returns true if a ValueRecord does not adjust a glyph's position.
+func (vr ValueRecord) IsZero() bool {
+	return vr.XPlacement == 0 && vr.YPlacement == 0 && vr.XAdvance == 0 && vr.YAdvance == 0
+}
+
+// --- Anchors ---------------------------------------------------------------
+
+// Anchor is a point on a glyph, in design units, to which another glyph may be
+// attached, e.g., a mark to a base glyph. Anchor tables come in three formats:
+//
+// ▪︎ Format 1: design units only
+//
+// ▪︎ Format 2: design units plus a contour point of the glyph outline, which hinting
+// may have moved
+//
+// ▪︎ Format 3: design units plus Device or VariationIndex tables
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gpos#anchor-tables
+type Anchor struct {
+	Format      uint16
+	X, Y        int16  // coordinates in design units
+	AnchorPoint uint16 // index of a glyph contour point, for format 2
+	XDevice     uint16 // offset to Device table for X, from beginning of Anchor table, for format 3
+	YDevice     uint16 // offset to Device table for Y, from beginning of Anchor table, for format 3
+	loc         binarySegm
+}
+
+// ParseAnchor reads an Anchor table from loc.
+func ParseAnchor(loc NavLocation) (Anchor, error) {
+	b := binarySegm(loc.Bytes())
+	a := Anchor{loc: b}
+	if len(b) < 6 {
+		return a, errBufferBounds
+	}
+	a.Format = b.U16(0)
+	a.X, a.Y = int16(b.U16(2)), int16(b.U16(4))
+	switch a.Format {
+	case 1:
+	case 2:
+		if len(b) < 8 {
+			return a, errBufferBounds
+		}
+		a.AnchorPoint = b.U16(6)
+	case 3:
+		if len(b) < 10 {
+			return a, errBufferBounds
+		}
+		a.XDevice, a.YDevice = b.U16(6), b.U16(8)
+	default:
+		return a, errFontFormat(fmt.Sprintf("unknown Anchor table format %d", a.Format))
+	}
+	return a, nil
+}
+
+// Resolve returns a copy of an anchor with the deltas of its Device tables or
+// VariationIndex tables applied for an instance. Contour points of format 2
+// anchors are not considered.
+func (a Anchor) Resolve(inst Instance) Anchor {
+	if a.Format != 3 {
+		return a
+	}
+	a.X = inst.adjust(a.X, a.loc, a.XDevice)
+	a.Y = inst.adjust(a.Y, a.loc, a.YDevice)
+	return a
+}
+
+// --- Mark arrays -----------------------------------------------------------
+
+// MarkArray lists the mark class and the anchor of each mark glyph covered by a
+// mark attachment subtable (GPOS lookup types 4, 5 and 6). Mark records are in
+// order of the mark coverage index.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gpos#mark-array-table
+type MarkArray struct {
+	loc     binarySegm // MarkArray table; anchor offsets are relative to its start
+	records array      // MarkRecords
+}
+
+// MarkRecord holds the class and the anchor of a mark glyph.
+type MarkRecord struct {
+	Class  uint16 // class defined for the mark
+	Anchor Anchor // attachment point of the mark
+}
+
+// MarkArray table:
+//
+//	uint16      markCount                Number of MarkRecords
+//	MarkRecord  markRecords[markCount]   Array of MarkRecords, ordered by corresponding glyphs in the MarkCoverage table
+//
+// MarkRecord:
+//
+//	uint16    markClass         Class defined for the associated mark
+//	Offset16  markAnchorOffset  Offset to Anchor table, from beginning of MarkArray table
+
+// ParseMarkArray reads a MarkArray table from loc.
+func ParseMarkArray(loc NavLocation) (MarkArray, error) {
+	b := binarySegm(loc.Bytes())
+	if len(b) < 2 {
+		return MarkArray{}, errBufferBounds
+	}
+	n := int(b.U16(0))
+	if len(b) < 2+n*4 {
+		return MarkArray{}, errBufferBounds
+	}
+	return MarkArray{loc: b, records: array{recordSize: 4, length: n, loc: b[2:]}}, nil
+}
+
+// Len returns the number of mark records.
+func (ma MarkArray) Len() int {
+	return ma.records.length
+}
+
+// Record returns the mark record for mark coverage index i.
+func (ma MarkArray) Record(i int) (MarkRecord, error) {
+	if i < 0 || i >= ma.records.length {
+		return MarkRecord{}, errBufferBounds
+	}
+	rec := ma.records.Get(i)
+	mr := MarkRecord{Class: rec.U16(0)}
+	off := int(rec.U16(2))
+	if off == 0 || off >= len(ma.loc) {
+		return mr, errFontFormat("MarkRecord without anchor")
+	}
+	var err error
+	mr.Anchor, err = ParseAnchor(ma.loc[off:])
+	return mr, err
+}
@@ -0,0 +1,67 @@
+package ot
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestValueRecord(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	vf := ValueXPlacement | ValueXAdvance | ValueYAdvDevice
+	if vf.Size() != 6 {
+		t.Errorf("expected value record of format %#x to have 6 bytes, has %d", vf, vf.Size())
+	}
+	b := binarySegm{
+		0xff, 0xff, // some preceding field
+		0xff, 0xf6, // XPlacement = -10
+		0, 120, // XAdvance = 120
+		0, 32, // YAdvDevice at 32
+	}
+	vr, err := ParseValueRecord(b, 2, vf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vr.XPlacement != -10 || vr.YPlacement != 0 || vr.XAdvance != 120 || vr.YAdvDevice != 32 {
+		t.Errorf("unexpected value record %+v", vr)
+	}
+	if _, err = ParseValueRecord(b, 4, vf); err == nil {
+		t.Errorf("expected value record exceeding buffer to be rejected")
+	}
+}
+
+func TestMarkArray(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	b := binarySegm{
+		0, 2, // 2 mark records
+		0, 0, 0, 10, // class 0, anchor at 10
+		0, 1, 0, 16, // class 1, anchor at 16
+		0, 1, 0, 200, 1, 244, // anchor format 1: (200, 500)
+		0, 2, 0xff, 0x38, 0, 0, 0, 5, // anchor format 2: (-200, 0), contour point 5
+	}
+	ma, err := ParseMarkArray(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ma.Len() != 2 {
+		t.Fatalf("expected mark array to have 2 records, has %d", ma.Len())
+	}
+	mr, err := ma.Record(0)
+	if err != nil || mr.Class != 0 || mr.Anchor.X != 200 || mr.Anchor.Y != 500 {
+		t.Errorf("unexpected mark record #0: %+v, %v", mr, err)
+	}
+	mr, err = ma.Record(1)
+	if err != nil || mr.Class != 1 || mr.Anchor.X != -200 || mr.Anchor.AnchorPoint != 5 {
+		t.Errorf("unexpected mark record #1: %+v, %v", mr, err)
+	}
+	if _, err = ma.Record(2); err == nil {
+		t.Errorf("expected mark record #2 to be out of range")
+	}
+	if _, err = ParseAnchor(binarySegm{0, 4, 0, 0, 0, 0}); err == nil {
+		t.Errorf("expected anchor format 4 to be rejected")
+	}
+}
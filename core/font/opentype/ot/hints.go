@@ -0,0 +1,233 @@
+package ot
+
+/*
+Fonts with TrueType outlines may carry tables which help rasterizers to render
+glyphs at small sizes:
+
+▪︎ gasp: the font designer's recommendations for grid-fitting and anti-aliasing,
+per range of ppem-sizes
+
+▪︎ hdmx: pre-computed advance widths of hinted glyphs, in pixels, per ppem-size
+
+▪︎ VDMX: the maximum and minimum y-extents of hinted glyphs, per ppem-size and
+device aspect ratio
+
+We expose the tables, but deciding on how to render glyphs is left to higher level
+packages (e.g., package `otquery`) and to rasterizers.
+*/
+
+// --- gasp table ------------------------------------------------------------
+
+// GaspTable, the Grid-fitting and Scan-conversion Procedure Table (gasp), contains
+// information which describes the preferred rasterization techniques for the
+// typeface when it is rendered on grayscale-capable devices.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gasp
+type GaspTable struct {
+	tableBase
+	Version uint16
+	ranges  array // GaspRange records, sorted by ppem
+}
+
+func newGaspTable(tag Tag, b binarySegm, offset, size uint32) *GaspTable {
+	t := &GaspTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// GaspBehavior is a set of flags for rasterizing glyphs.
+type GaspBehavior uint16
+
+// Flags of GaspBehavior. The symmetric variants are defined for version 1 of the
+// gasp table only.
+const (
+	GaspGridfit            GaspBehavior = 0x0001 // use grid-fitting (hinting)
+	GaspDoGray             GaspBehavior = 0x0002 // use grayscale rendering
+	GaspSymmetricGridfit   GaspBehavior = 0x0004 // use grid-fitting with ClearType symmetric smoothing
+	GaspSymmetricSmoothing GaspBehavior = 0x0008 // use smoothing along multiple axes with ClearType
+)
+
+// GaspRange is the rasterization behavior for ppem-sizes up to and including MaxPPEm.
+type GaspRange struct {
+	MaxPPEm  uint16
+	Behavior GaspBehavior
+}
+
+// RangeCount returns the number of ppem-ranges of the table.
+func (t *GaspTable) RangeCount() int {
+	return t.ranges.length
+}
+
+// Range returns range number i. Ranges are sorted by MaxPPEm.
+func (t *GaspTable) Range(i int) GaspRange {
+	if i < 0 || i >= t.ranges.length {
+		return GaspRange{}
+	}
+	rec := t.ranges.Get(i)
+	return GaspRange{MaxPPEm: rec.U16(0), Behavior: GaspBehavior(rec.U16(2))}
+}
+
+// Behavior returns the rasterization behavior for a ppem-size. If no range covers
+// ppem, false is returned.
+func (t *GaspTable) Behavior(ppem uint16) (GaspBehavior, bool) {
+	for i := 0; i < t.ranges.length; i++ {
+		if r := t.Range(i); ppem <= r.MaxPPEm {
+			return r.Behavior, true
+		}
+	}
+	return 0, false
+}
+
+// --- hdmx table ------------------------------------------------------------
+
+// HdmxTable, the Horizontal Device Metrics table (hdmx), contains the advance widths
+// of hinted glyphs, in pixels, for a set of ppem-sizes. Rasterizers may use them
+// instead of scaling the advance widths from table hmtx and running the hinting
+// instructions.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/hdmx
+type HdmxTable struct {
+	tableBase
+	Version   uint16
+	records   array // DeviceRecords, one per ppem-size
+	numGlyphs int   // taken from table maxp during consistency check
+}
+
+func newHdmxTable(tag Tag, b binarySegm, offset, size uint32) *HdmxTable {
+	t := &HdmxTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// HdmxRecord holds the device advance widths of all glyphs for a ppem-size.
+type HdmxRecord struct {
+	PPEm     uint16 // ppem-size of this record
+	MaxWidth uint16 // maximum advance width of all glyphs, in pixels
+	widths   binarySegm
+}
+
+// RecordCount returns the number of device records, i.e. of ppem-sizes covered.
+func (t *HdmxTable) RecordCount() int {
+	return t.records.length
+}
+
+// Record returns device record number i.
+func (t *HdmxTable) Record(i int) HdmxRecord {
+	if i < 0 || i >= t.records.length {
+		return HdmxRecord{}
+	}
+	rec := binarySegm(t.records.Get(i).Bytes())
+	if rec.Size() < 2 {
+		return HdmxRecord{}
+	}
+	return HdmxRecord{
+		PPEm:     uint16(rec[0]),
+		MaxWidth: uint16(rec[1]),
+		widths:   binarySegm(rec.Slice(2, 2+t.numGlyphs).Bytes()),
+	}
+}
+
+// RecordFor returns the device record for a ppem-size, if present.
+func (t *HdmxTable) RecordFor(ppem uint16) (HdmxRecord, bool) {
+	for i := 0; i < t.records.length; i++ {
+		if rec := t.Record(i); rec.PPEm == ppem {
+			return rec, true
+		}
+	}
+	return HdmxRecord{}, false
+}
+
+// Advance returns the advance width of glyph gid, in pixels.
+func (r HdmxRecord) Advance(gid GlyphIndex) (uint16, bool) {
+	if int(gid) >= r.widths.Size() {
+		return 0, false
+	}
+	return uint16(r.widths[gid]), true
+}
+
+// --- VDMX table ------------------------------------------------------------
+
+// VDMXTable, the Vertical Device Metrics table (VDMX), contains the maximum and
+// minimum y-extents of the hinted glyphs of a font, for ppem-sizes and device aspect
+// ratios. Rasterizers use them to size the line of a hinted font without clipping.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/vdmx
+type VDMXTable struct {
+	tableBase
+	Version uint16
+	ratios  array // RatioRange records
+	groups  array // Offset16 to VDMXGroups, parallel to ratios
+}
+
+func newVDMXTable(tag Tag, b binarySegm, offset, size uint32) *VDMXTable {
+	t := &VDMXTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// VDMXBounds are the y-extents of hinted glyphs, in pixels.
+type VDMXBounds struct {
+	YMax, YMin int16
+}
+
+// Bounds returns the y-extents of the hinted glyphs of a font at a ppem-size, for a
+// device with a given resolution in x- and y-direction. Resolutions are used for
+// selecting an aspect ratio only, so any unit will do. A resolution of 0 selects the
+// ratio record which matches any aspect ratio.
+// If the table does not contain bounds for ppem, false is returned.
+func (t *VDMXTable) Bounds(ppem uint16, xRes, yRes int) (VDMXBounds, bool) {
+	for i := 0; i < t.ratios.length; i++ {
+		ratio := t.ratios.Get(i).Bytes()
+		if len(ratio) < 4 || !matchesRatio(ratio[1], ratio[2], ratio[3], xRes, yRes) {
+			continue
+		}
+		link := makeLink16(t.groups.Get(i).U16(0), t.data, "VDMXGroup")
+		group := binarySegm(link.Jump().Bytes())
+		n := int(group.U16(0))
+		for j := 0; j < n; j++ {
+			entry, err := group.view(4+6*j, 6)
+			if err != nil {
+				break
+			}
+			if entry.U16(0) == ppem {
+				return VDMXBounds{YMax: int16(entry.U16(2)), YMin: int16(entry.U16(4))}, true
+			}
+		}
+		return VDMXBounds{}, false // first matching ratio is authoritative
+	}
+	return VDMXBounds{}, false
+}
+
+// matchesRatio checks if a device aspect ratio xRes:yRes lies within a ratio range
+// xRatio:yStart…yEnd. A ratio range of 0:0…0 matches any device.
+func matchesRatio(xRatio, yStart, yEnd uint8, xRes, yRes int) bool {
+	if xRatio == 0 && yStart == 0 && yEnd == 0 {
+		return true
+	}
+	if xRes <= 0 || yRes <= 0 {
+		return false
+	}
+	// yStart/xRatio ≤ yRes/xRes ≤ yEnd/xRatio
+	return int(yStart)*xRes <= yRes*int(xRatio) && yRes*int(xRatio) <= int(yEnd)*xRes
+}
@@ -0,0 +1,256 @@
+package ot
+
+// --- JSTF table ------------------------------------------------------------
+
+// JstfTable, the Justification Table (JSTF), provides font developers with additional
+// control over glyph substitution and positioning in justified text.
+//
+// For each script, a JSTF table may define extender glyphs—glyphs which can be inserted
+// into a line to extend its length, e.g. kashida (tatweel) for Arabic—and, per language
+// system, a list of priorities. Each priority lists GSUB and GPOS lookups to enable or
+// disable for shrinking or extending a line. Clients should try priorities in order,
+// until the line is justified.
+//
+// JSTF tables are rare in practice. Clients should fall back to heuristics if a font
+// does not contain one.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/jstf
+type JstfTable struct {
+	tableBase
+	scripts tagRecordMap16
+}
+
+func newJstfTable(tag Tag, b binarySegm, offset, size uint32) *JstfTable {
+	t := &JstfTable{}
+	base := tableBase{
+		data:   b,
+		name:   tag,
+		offset: offset,
+		length: size,
+	}
+	t.tableBase = base
+	t.self = t
+	return t
+}
+
+// ScriptTags returns the tags of all scripts for which justification data is present.
+func (t *JstfTable) ScriptTags() []Tag {
+	return t.scripts.Tags()
+}
+
+// Script returns the justification data for a script. If the JSTF table does not
+// contain data for the script, false is returned.
+func (t *JstfTable) Script(script Tag) (JstfScript, bool) {
+	link := t.scripts.LookupTag(script)
+	if link.IsNull() {
+		return JstfScript{}, false
+	}
+	s, err := viewJstfScript(binarySegm(link.Jump().Bytes()))
+	if err != nil {
+		tracer().Errorf("JSTF script %s: %v", script, err)
+		return JstfScript{}, false
+	}
+	return s, true
+}
+
+// JstfScript holds the justification data for a script.
+type JstfScript struct {
+	extenderGlyphs array          // glyph IDs, in increasing numerical order
+	defLangSys     binarySegm     // default JstfLangSys, may be empty
+	langSys        tagRecordMap16 // JstfLangSys records, by language tag
+}
+
+// JstfScript table:
+//
+//	Offset16  extenderGlyphOffset       Offset to ExtenderGlyph table, from beginning of JstfScript table (may be NULL)
+//	Offset16  defJstfLangSysOffset      Offset to default JstfLangSys table, from beginning of JstfScript table (may be NULL)
+//	uint16    jstfLangSysCount          Number of JstfLangSysRecords in this table- may be zero (0)
+//	JstfLangSysRecord  jstfLangSysRecords[jstfLangSysCount]  Array of JstfLangSysRecords, in alphabetical order by JstfLangSysTag
+func viewJstfScript(b binarySegm) (JstfScript, error) {
+	s := JstfScript{}
+	if len(b) < 6 {
+		return s, errBufferBounds
+	}
+	if off := int(b.U16(0)); off != 0 {
+		if len(b) < off+2 {
+			return s, errBufferBounds
+		}
+		n := int(b.U16(off))
+		if len(b) < off+2+2*n {
+			return s, errBufferBounds
+		}
+		s.extenderGlyphs = array{recordSize: 2, length: n, loc: b[off+2:]}
+	}
+	if off := int(b.U16(2)); off != 0 && off < len(b) {
+		s.defLangSys = b[off:]
+	}
+	if len(b) < 6+int(b.U16(4))*6 {
+		return s, errBufferBounds
+	}
+	s.langSys = parseTagRecordMap16(b, 4, b, "JstfScript", "JstfLangSys")
+	return s, nil
+}
+
+// ExtenderGlyphs returns the glyphs which may be inserted into a line to extend its
+// length, e.g. kashida. The list may be empty.
+func (s JstfScript) ExtenderGlyphs() []GlyphIndex {
+	glyphs := make([]GlyphIndex, s.extenderGlyphs.length)
+	for i := range glyphs {
+		glyphs[i] = GlyphIndex(s.extenderGlyphs.Get(i).U16(0))
+	}
+	return glyphs
+}
+
+// IsExtender returns true if gid is an extender glyph for the script.
+func (s JstfScript) IsExtender(gid GlyphIndex) bool {
+	for i, j := 0, s.extenderGlyphs.length; i < j; { // extender glyphs are sorted
+		h := i + (j-i)/2
+		if g := GlyphIndex(s.extenderGlyphs.Get(h).U16(0)); gid < g {
+			j = h
+		} else if g < gid {
+			i = h + 1
+		} else {
+			return true
+		}
+	}
+	return false
+}
+
+// LangSys returns the justification priorities for a language system. If lang is 0
+// or the script contains no entry for lang, the default language system is used.
+// The result may contain no priorities at all.
+func (s JstfScript) LangSys(lang Tag) JstfLangSys {
+	b := s.defLangSys
+	if lang != 0 {
+		if link := s.langSys.LookupTag(lang); !link.IsNull() {
+			b = binarySegm(link.Jump().Bytes())
+		}
+	}
+	return viewJstfLangSys(b)
+}
+
+// JstfLangSys holds a list of justification suggestions, ordered by priority.
+type JstfLangSys struct {
+	loc        binarySegm
+	priorities array // offsets to JstfPriority tables, from beginning of JstfLangSys table
+}
+
+func viewJstfLangSys(b binarySegm) JstfLangSys {
+	if len(b) < 2 {
+		return JstfLangSys{}
+	}
+	n := int(b.U16(0))
+	if len(b) < 2+2*n {
+		tracer().Errorf("JSTF language system priorities out of bounds")
+		return JstfLangSys{}
+	}
+	return JstfLangSys{
+		loc:        b,
+		priorities: array{recordSize: 2, length: n, loc: b[2:]},
+	}
+}
+
+// PriorityCount returns the number of justification priorities.
+func (ls JstfLangSys) PriorityCount() int {
+	return ls.priorities.length
+}
+
+// Priority returns the justification priority i, with 0 being the highest priority.
+func (ls JstfLangSys) Priority(i int) JstfPriority {
+	if i < 0 || i >= ls.priorities.length {
+		return JstfPriority{}
+	}
+	off := int(ls.priorities.Get(i).U16(0))
+	if off == 0 || off >= len(ls.loc) {
+		return JstfPriority{}
+	}
+	return viewJstfPriority(ls.loc[off:])
+}
+
+// JstfPriority is a set of suggestions for shrinking or extending a line. Lookup
+// indices refer to the font's GSUB and GPOS lookup lists, respectively. Lookups in
+// ShrinkageMax and ExtensionMax are GPOS-type lookups private to the JSTF table, which
+// limit the amount of shrinking or extending at this priority.
+type JstfPriority struct {
+	ShrinkageEnableGSub  []uint16 // GSUB lookups to enable for shrinking
+	ShrinkageDisableGSub []uint16 // GSUB lookups to disable for shrinking
+	ShrinkageEnableGPos  []uint16 // GPOS lookups to enable for shrinking
+	ShrinkageDisableGPos []uint16 // GPOS lookups to disable for shrinking
+	ShrinkageMax         []Lookup // maximum shrinkage
+	ExtensionEnableGSub  []uint16 // GSUB lookups to enable for extending
+	ExtensionDisableGSub []uint16 // GSUB lookups to disable for extending
+	ExtensionEnableGPos  []uint16 // GPOS lookups to enable for extending
+	ExtensionDisableGPos []uint16 // GPOS lookups to disable for extending
+	ExtensionMax         []Lookup // maximum extension
+}
+
+// JstfPriority table consists of 10 offsets (from beginning of the JstfPriority
+// table), each of which may be NULL:
+//
+//	gsubShrinkageEnable, gsubShrinkageDisable, gposShrinkageEnable, gposShrinkageDisable,
+//	shrinkageJstfMax, gsubExtensionEnable, gsubExtensionDisable, gposExtensionEnable,
+//	gposExtensionDisable, extensionJstfMax
+//
+// All of them link to JstfModList tables, except for the JstfMax links.
+func viewJstfPriority(b binarySegm) JstfPriority {
+	if len(b) < 20 {
+		tracer().Errorf("JSTF priority table too short")
+		return JstfPriority{}
+	}
+	return JstfPriority{
+		ShrinkageEnableGSub:  viewJstfModList(b, b.U16(0)),
+		ShrinkageDisableGSub: viewJstfModList(b, b.U16(2)),
+		ShrinkageEnableGPos:  viewJstfModList(b, b.U16(4)),
+		ShrinkageDisableGPos: viewJstfModList(b, b.U16(6)),
+		ShrinkageMax:         viewJstfMax(b, b.U16(8)),
+		ExtensionEnableGSub:  viewJstfModList(b, b.U16(10)),
+		ExtensionDisableGSub: viewJstfModList(b, b.U16(12)),
+		ExtensionEnableGPos:  viewJstfModList(b, b.U16(14)),
+		ExtensionDisableGPos: viewJstfModList(b, b.U16(16)),
+		ExtensionMax:         viewJstfMax(b, b.U16(18)),
+	}
+}
+
+// A JstfModList table contains a count of lookups and an array of lookup indices.
+func viewJstfModList(b binarySegm, offset uint16) []uint16 {
+	off := int(offset)
+	if off == 0 || len(b) < off+2 {
+		return nil
+	}
+	n := int(b.U16(off))
+	if len(b) < off+2+2*n {
+		tracer().Errorf("JSTF modification list out of bounds")
+		return nil
+	}
+	indices := make([]uint16, n)
+	for i := range indices {
+		indices[i] = b.U16(off + 2 + 2*i)
+	}
+	return indices
+}
+
+// A JstfMax table contains a count of lookups and an array of offsets to
+// GPOS-type Lookup tables, from the beginning of the JstfMax table.
+func viewJstfMax(b binarySegm, offset uint16) []Lookup {
+	off := int(offset)
+	if off == 0 || len(b) < off+2 {
+		return nil
+	}
+	jmax := b[off:]
+	n := int(jmax.U16(0))
+	if len(jmax) < 2+2*n {
+		tracer().Errorf("JSTF max table out of bounds")
+		return nil
+	}
+	lookups := make([]Lookup, 0, n)
+	for i := 0; i < n; i++ {
+		loff := int(jmax.U16(2 + 2*i))
+		if loff == 0 || loff >= len(jmax) {
+			continue
+		}
+		lookup := viewLookup(jmax[loff:])
+		lookup.Type = MaskGPosLookupType(lookup.Type) // subtables are GPOS subtables
+		lookups = append(lookups, lookup)
+	}
+	return lookups
+}
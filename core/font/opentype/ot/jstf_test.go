@@ -0,0 +1,62 @@
+package ot
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestJstf(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	jstf := binarySegm{
+		0, 1, 0, 0, // version 1.0
+		0, 1, // 1 script
+		'a', 'r', 'a', 'b', 0, 12, // script record, script at 12
+		// JstfScript at 12
+		0, 6, // extender glyphs at +6
+		0, 12, // default language system at +12
+		0, 0, // no language system records
+		0, 2, 0, 7, 0, 9, // 2 extender glyphs: 7, 9
+		// JstfLangSys at 24
+		0, 1, 0, 4, // 1 priority at +4
+		// JstfPriority at 28
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // no shrinkage
+		0, 0, 0, 0, 0, 20, 0, 0, 0, 0, // GPOS extension enable list at +20
+		0, 2, 0, 3, 0, 5, // lookups 3 and 5
+	}
+	table, err := parseJstf(T("JSTF"), jstf, 0, uint32(len(jstf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := table.Self().AsJstf()
+	if tags := j.ScriptTags(); len(tags) != 1 || tags[0] != T("arab") {
+		t.Errorf("expected JSTF table to have script 'arab', has %v", tags)
+	}
+	if _, ok := j.Script(T("latn")); ok {
+		t.Errorf("expected JSTF table to have no entry for script 'latn'")
+	}
+	script, ok := j.Script(T("arab"))
+	if !ok {
+		t.Fatalf("expected JSTF table to have an entry for script 'arab'")
+	}
+	if g := script.ExtenderGlyphs(); len(g) != 2 || g[1] != 9 {
+		t.Errorf("unexpected extender glyphs: %v", g)
+	}
+	if !script.IsExtender(7) || script.IsExtender(8) {
+		t.Errorf("expected glyph 7 to be an extender glyph, and glyph 8 to not be one")
+	}
+	langsys := script.LangSys(T("URD "))
+	if langsys.PriorityCount() != 1 {
+		t.Fatalf("expected default language system to have 1 priority, has %d",
+			langsys.PriorityCount())
+	}
+	prio := langsys.Priority(0)
+	if len(prio.ExtensionEnableGPos) != 2 || prio.ExtensionEnableGPos[1] != 5 {
+		t.Errorf("unexpected GPOS extension lookups: %v", prio.ExtensionEnableGPos)
+	}
+	if prio.ShrinkageEnableGSub != nil || prio.ExtensionMax != nil {
+		t.Errorf("expected priority to have no shrinkage lookups and no max lookups")
+	}
+}
@@ -297,15 +297,15 @@ func buildGlyphRangeFromCoverage(chead coverageHeader, b binarySegm) GlyphRange
 		return &glyphRangeArray{
 			is32:     false,                  // entries are uint16
 			count:    int(chead.Count),       // number of entries
-			data:     b[4:],                  // header of format 1 coverage table is 4 bytes long
-			byteSize: int(4 + chead.Count*2), // header is 4, entries are 2 bytes
+			data:     b.at(4),                // header of format 1 coverage table is 4 bytes long
+			byteSize: 4 + int(chead.Count)*2, // header is 4, entries are 2 bytes
 		}
 	}
 	return &glyphRangeRecords{
 		is32:     false,                  // entries are uint16
 		count:    int(chead.Count),       // number of records
-		data:     b[4:],                  // header of format 2 coverage table is 4 bytes long
-		byteSize: int(4 + chead.Count*6), // header is 4, entries are 6 bytes
+		data:     b.at(4),                // header of format 2 coverage table is 4 bytes long
+		byteSize: 4 + int(chead.Count)*6, // header is 4, entries are 6 bytes
 	}
 }
 
@@ -563,7 +563,7 @@ func (ll LookupList) Get(i int) NavLocation {
 // Navigate will navigate to Lookup i in the list.
 func (ll LookupList) Navigate(i int) Lookup {
 	// acts like NavLink
-	if ll.err != nil {
+	if ll.err != nil || i < 0 || i >= ll.length {
 		return Lookup{}
 	}
 	if ll.lookupsCache == nil {
@@ -572,7 +572,7 @@ func (ll LookupList) Navigate(i int) Lookup {
 		return ll.lookupsCache[i]
 	}
 	lookupPtr := ll.Get(i)
-	lookup := ll.base[lookupPtr.U16(0):]
+	lookup := ll.base.at(int(lookupPtr.U16(0)))
 	ll.lookupsCache[i] = viewLookup(lookup)
 	tracer().Debugf("cached new lookup #%d of type %d", i, ll.lookupsCache[i].Type)
 	return ll.lookupsCache[i]
@@ -27,6 +27,73 @@ func TestNavLink(t *testing.T) {
 	}
 }
 
+func TestCheckedAccessors(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	b := binarySegm{0, 1, 0, 2, 0, 0, 0, 3, 9}
+	if n, err := b.CheckedU16(2); err != nil || n != 2 {
+		t.Errorf("expected U16(2) = 2, have %d (%v)", n, err)
+	}
+	if n, err := b.CheckedU32(4); err != nil || n != 3 {
+		t.Errorf("expected U32(4) = 3, have %d (%v)", n, err)
+	}
+	if _, err := b.CheckedU16(8); err == nil {
+		t.Errorf("expected U16(8) to be out of bounds")
+	}
+	if _, err := b.CheckedSlice(4, 10); err == nil {
+		t.Errorf("expected slice [4:10] to be out of bounds")
+	}
+	if l := len(b.Slice(4, 100).Bytes()); l != 5 {
+		t.Errorf("expected slice [4:100] to be clamped to length 5, is %d", l)
+	}
+	if l := len(b.Slice(100, 2).Bytes()); l != 0 {
+		t.Errorf("expected slice [100:2] to be empty, has length %d", l)
+	}
+	if g := b.Glyphs(); len(g) != 4 || g[3] != 3 {
+		t.Errorf("expected trailing odd byte to be ignored by Glyphs(), have %v", g)
+	}
+}
+
+func TestArrayDecode(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	b := binarySegm{
+		0, 2, // 2 records
+		'a', 'b', 'c', 'd', 0xff, 0xfe, 0, 0, 0, 7, // Tag, int16, Offset32
+		'e', 'f', 'g', 'h', 0, 1, 0, 1, 0, 0,
+	}
+	a, err := ParseArray(b, 0, 10, "Records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 2 {
+		t.Fatalf("expected array to have 2 records, has %d", a.Len())
+	}
+	rec, err := a.DecodeAs(0, "Tag, int16, Offset32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Tag(rec[0]) != T("abcd") || int16(rec[1]) != -2 || rec[2] != 7 {
+		t.Errorf("unexpected record decoding: %v", rec)
+	}
+	if _, err = a.DecodeAs(0, "uint16 float"); err == nil {
+		t.Errorf("expected decoding of unknown type 'float' to fail")
+	}
+	var tags []Tag
+	a.Iterate(func(i int, rec NavLocation) bool {
+		tags = append(tags, MakeTag(rec.Bytes()))
+		return true
+	})
+	if len(tags) != 2 || tags[1] != T("efgh") {
+		t.Errorf("unexpected tags from iteration: %v", tags)
+	}
+	if _, err = ParseArray(b, 0, 12, "Records"); err == nil {
+		t.Errorf("expected array of 2*12 bytes to be out of bounds")
+	}
+}
+
 func TestTableNav(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
 	defer teardown()
@@ -2,6 +2,7 @@ package ot
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/npillmayer/tyse/core/font"
 	"golang.org/x/text/encoding/unicode"
@@ -12,12 +13,19 @@ import (
 //
 // We only support OpenType fonts with advanced layout, i.e. fonts containing tables
 // GSUB, GPOS, etc.
+//
+// Tables are parsed lazily by default (see Parse), thus the shortcuts to layout tables in
+// field Layout are not set until the respective table has been accessed with Table.
+// Field Layout is written while parsing under the font's lock; clients sharing a font
+// between goroutines should use the accessors GSub, GPos, GDef and Base instead.
 type Font struct {
 	F      *font.ScalableFont
 	Header *FontHeader
-	tables map[Tag]Table
-	CMap   *CMapTable // CMAP table is mandatory
-	Layout struct {   // OpenType core layout tables
+	tables map[Tag]Table    // parsed tables
+	raw    map[Tag]rawTable // tables not parsed yet
+	mx     sync.Mutex       // guards lazy parsing of tables
+	CMap   *CMapTable       // CMAP table is mandatory
+	Layout struct {         // OpenType core layout tables
 		GSub *GSubTable // OpenType layout GSUB
 		GPos *GPosTable // OpenType layout GPOS
 		GDef *GDefTable // OpenType layout GDEF
@@ -61,19 +69,68 @@ type FontHeader struct {
 // avar BASE CBDT CBLC CFF CFF2 cmap COLR CPAL cvar cvt DSIG EBDT EBLC EBSC fpgm fvar
 // gasp GDEF glyf GPOS GSUB gvar hdmx head hhea hmtx HVAR JSTF kern loca LTSH MATH
 // maxp MERG meta MVAR name OS/2 PCLT post prep sbix STAT SVG VDMX vhea vmtx VORG VVAR
+//
+// Tables are parsed on first access. If a table is malformed, an error is traced and
+// nil is returned.
 func (otf *Font) Table(tag Tag) Table {
-	if t, ok := otf.tables[tag]; ok {
-		return t
-	}
-	return nil
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	return otf.table(tag)
+}
+
+// GSub returns the GSUB table of the font, parsing it if necessary.
+// If the font does not contain a GSUB table, nil is returned.
+func (otf *Font) GSub() *GSubTable {
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	otf.table(T("GSUB"))
+	return otf.Layout.GSub
+}
+
+// GPos returns the GPOS table of the font, parsing it if necessary.
+// If the font does not contain a GPOS table, nil is returned.
+func (otf *Font) GPos() *GPosTable {
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	otf.table(T("GPOS"))
+	return otf.Layout.GPos
+}
+
+// GDef returns the GDEF table of the font, parsing it if necessary.
+// If the font does not contain a GDEF table, nil is returned.
+func (otf *Font) GDef() *GDefTable {
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	otf.table(T("GDEF"))
+	return otf.Layout.GDef
+}
+
+// Base returns the BASE table of the font, parsing it if necessary.
+// If the font does not contain a BASE table, nil is returned.
+func (otf *Font) Base() *BaseTable {
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	otf.table(T("BASE"))
+	return otf.Layout.Base
+}
+
+func (otf *Font) hasTable(tag Tag) bool {
+	_, parsed := otf.tables[tag]
+	_, unparsed := otf.raw[tag]
+	return parsed || unparsed
 }
 
 // TableTags returns a list of tags, one for each table contained in the font.
 func (otf *Font) TableTags() []Tag {
-	var tags = make([]Tag, 0, len(otf.tables))
+	otf.mx.Lock()
+	defer otf.mx.Unlock()
+	var tags = make([]Tag, 0, len(otf.tables)+len(otf.raw))
 	for tag := range otf.tables {
 		tags = append(tags, tag)
 	}
+	for tag := range otf.raw {
+		tags = append(tags, tag)
+	}
 	return tags
 }
 
@@ -139,8 +196,16 @@ func (t Tag) String() string {
 // For OpenType fonts based on CFF outlines: 'CFF ' (Compact Font Format 1.0),
 // 'CFF2' (Compact Font Format 2.0), 'VORG' (Vertical Origin, optional).
 //
+// Color fonts: 'COLR' (Color table), 'CPAL' (Color palette table),
+// 'sbix' (Standard bitmap graphics), 'CBDT'/'CBLC' (Color bitmap data and location).
+//
+// Justification: 'JSTF' (Justification table).
+//
+// Rasterization hints: 'gasp' (see above), 'hdmx' (Horizontal device metrics),
+// 'VDMX' (Vertical device metrics).
+//
 // Currently not used/supported:
-// SVG font table, bitmap glyph tables, color font tables, font variations.
+// SVG font table, monochrome bitmap glyph tables, font variations.
 type Table interface {
 	Extent() (uint32, uint32) // offset and byte size within the font's binary data
 	Binary() []byte           // the bytes of this table; should be treatet as read-only by clients
@@ -304,6 +369,70 @@ func (tself TableSelf) AsHMtx() *HMtxTable {
 	return nil
 }
 
+// AsColr returns this table as a COLR table, or nil.
+func (tself TableSelf) AsColr() *ColrTable {
+	if k, ok := safeSelf(tself).(*ColrTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsCPal returns this table as a CPAL table, or nil.
+func (tself TableSelf) AsCPal() *CPalTable {
+	if k, ok := safeSelf(tself).(*CPalTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsSbix returns this table as a sbix table, or nil.
+func (tself TableSelf) AsSbix() *SbixTable {
+	if k, ok := safeSelf(tself).(*SbixTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsGasp returns this table as a gasp table, or nil.
+func (tself TableSelf) AsGasp() *GaspTable {
+	if k, ok := safeSelf(tself).(*GaspTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsHdmx returns this table as a hdmx table, or nil.
+func (tself TableSelf) AsHdmx() *HdmxTable {
+	if k, ok := safeSelf(tself).(*HdmxTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsVDMX returns this table as a VDMX table, or nil.
+func (tself TableSelf) AsVDMX() *VDMXTable {
+	if k, ok := safeSelf(tself).(*VDMXTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsCBLC returns this table as a CBLC table, or nil.
+func (tself TableSelf) AsCBLC() *CBLCTable {
+	if k, ok := safeSelf(tself).(*CBLCTable); ok {
+		return k
+	}
+	return nil
+}
+
+// AsJstf returns this table as a JSTF table, or nil.
+func (tself TableSelf) AsJstf() *JstfTable {
+	if k, ok := safeSelf(tself).(*JstfTable); ok {
+		return k
+	}
+	return nil
+}
+
 // --- Concrete table implementations ----------------------------------------
 
 // HeadTable gives global information about the font.
@@ -553,7 +682,10 @@ func (n nameNames) Map() NavMap {
 		id := nameRecord.U16(6)
 		strlen := nameRecord.U16(8)
 		offset := nameRecord.U16(10)
-		str := n.strbuf[offset : offset+strlen] // UTF-16 encoded string
+		str, err := n.strbuf.view(int(offset), int(strlen)) // UTF-16 encoded string
+		if err != nil {
+			continue
+		}
 		//trace().Debugf("utf16 string = '%v'", decodeUtf16(str))
 		link := makeLink16(0, str, "NameRecord")
 		tag := MakeTag([]byte{byte(pltf), byte(enc), 0, byte(id)})
@@ -13,10 +13,34 @@ import (
 
 // ---------------------------------------------------------------------------
 
+// ParseOption is a type for options to Parse.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	eager bool
+}
+
+// EagerParsing forces Parse to parse all the tables of a font upfront. By default,
+// tables are parsed lazily on first access (see Font.Table). Parsing eagerly will
+// detect malformed tables at load time, while parsing lazily will cut down on the
+// costs of loading many fonts, e.g., for font matching.
+func EagerParsing() ParseOption {
+	return func(config *parseConfig) {
+		config.eager = true
+	}
+}
+
 // Parse parses an OpenType font from a byte slice.
 // An ot.Font needs ongoing access to the fonts byte-data after the Parse function returns.
 // Its elements are assumed immutable while the ot.Font remains in use.
-func Parse(font []byte) (*Font, error) {
+//
+// Parse will check the font's table directory and parse the 'cmap' table. Other
+// tables are parsed on first access, unless option EagerParsing is given.
+func Parse(font []byte, opts ...ParseOption) (*Font, error) {
+	config := parseConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
 	// https://www.microsoft.com/typography/otspec/otff.htm: Offset Table is 12 bytes.
 	r := bytes.NewReader(font)
 	h := FontHeader{}
@@ -27,9 +51,13 @@ func Parse(font []byte) (*Font, error) {
 	if !(h.FontType == 0x4f54544f || // OTTO
 		h.FontType == 0x00010000 || // TrueType
 		h.FontType == 0x74727565) { // true
-		return nil, errFontFormat(fmt.Sprintf("font type not supported: %x", h.FontType))
+		return nil, errUnsupported(fmt.Sprintf("font type not supported: %x", h.FontType))
+	}
+	otf := &Font{
+		Header: &h,
+		tables: make(map[Tag]Table),
+		raw:    make(map[Tag]rawTable),
 	}
-	otf := &Font{Header: &h, tables: make(map[Tag]Table)}
 	src := binarySegm(font)
 	// "The Offset Table is followed immediately by the Table Record entries …
 	// sorted in ascending order by tag", 16 bytes each.
@@ -47,39 +75,110 @@ func Parse(font []byte) (*Font, error) {
 		if off&3 != 0 { // ignore checksums, but "all tables must begin on four byte boundries".
 			return nil, errFontFormat("invalid table offset")
 		}
-		otf.tables[tag], err = parseTable(tag, src[off:off+size], off, size)
-		if err != nil {
-			return nil, err
+		if uint64(off)+uint64(size) > uint64(len(src)) {
+			return nil, errFontFormat("table " + tag.String() + " out of bounds")
+		}
+		otf.raw[tag] = rawTable{data: src[off : off+size], offset: off, size: size}
+	}
+	if config.eager {
+		for _, tag := range otf.TableTags() {
+			if _, err := otf.parse(tag); err != nil {
+				return nil, err
+			}
 		}
 	}
 	if err := extractLayoutInfo(otf); err != nil {
 		return nil, err
 	}
-	// Collect and centralize font information:
-	// The number of glyphs in the font is restricted only by the value stated in the 'head' table. The order in which glyphs are placed in a font is arbitrary.
-	// Note that a font must have at least two glyphs, and that glyph index 0 musthave an outline. See Glyph Mappings for details.
-	//
-	if hh := otf.tables[T("hhea")]; hh != nil {
-		hhead := hh.Self().AsHHea()
-		if mx := otf.tables[T("hmtx")]; mx != nil {
-			hmtx := mx.Self().AsHMtx()
-			hmtx.NumberOfHMetrics = hhead.NumberOfHMetrics
-		}
+	return otf, nil
+}
+
+// rawTable is a table of a font which has not been parsed yet.
+type rawTable struct {
+	data   binarySegm
+	offset uint32
+	size   uint32
+}
+
+// parse materializes the table for tag, if it has not been parsed yet, and connects
+// it to the information it depends upon, located in other tables.
+// parse does not lock the font, so callers are responsible for synchronization.
+//
+// Returns nil if the font does not contain a table for tag.
+func (otf *Font) parse(tag Tag) (Table, error) {
+	if t, ok := otf.tables[tag]; ok {
+		return t, nil
 	}
-	if he := otf.Table(T("head")); he != nil {
-		head := he.Self().AsHead()
-		if lo := otf.Table(T("loca")); lo != nil {
-			loca := lo.Self().AsLoca()
-			if head.IndexToLocFormat == 1 {
-				loca.inx2loc = longLocaVersion
-			}
-			if ma := otf.Table(T("maxp")); ma != nil {
-				maxp := ma.Self().AsMaxP()
-				loca.locCnt = maxp.NumGlyphs
-			}
+	raw, ok := otf.raw[tag]
+	if !ok {
+		return nil, nil
+	}
+	delete(otf.raw, tag)
+	t, err := parseTable(tag, raw.data, raw.offset, raw.size)
+	if err != nil {
+		otf.tables[tag] = nil // do not try again
+		return nil, err
+	}
+	otf.tables[tag] = t
+	otf.linkTable(tag, t)
+	return t, nil
+}
+
+// table is like parse, but will trace parsing errors instead of returning them.
+// Malformed tables are treated as missing.
+func (otf *Font) table(tag Tag) Table {
+	t, err := otf.parse(tag)
+	if err != nil {
+		tracer().Errorf("font table %s: %v", tag, err)
+	}
+	return t
+}
+
+// linkTable connects a newly parsed table to the information it depends upon, located
+// in other tables, and sets shortcuts in otf.
+func (otf *Font) linkTable(tag Tag, t Table) {
+	switch tag {
+	case T("hmtx"):
+		// Collect and centralize font information:
+		// The number of glyphs in the font is restricted only by the value stated in the 'head' table. The order in which glyphs are placed in a font is arbitrary.
+		// Note that a font must have at least two glyphs, and that glyph index 0 musthave an outline. See Glyph Mappings for details.
+		//
+		if hh := otf.table(T("hhea")); hh != nil {
+			t.Self().AsHMtx().NumberOfHMetrics = hh.Self().AsHHea().NumberOfHMetrics
+		}
+	case T("loca"):
+		loca := t.Self().AsLoca()
+		if he := otf.table(T("head")); he != nil && he.Self().AsHead().IndexToLocFormat == 1 {
+			loca.inx2loc = longLocaVersion
+		}
+		if ma := otf.table(T("maxp")); ma != nil {
+			loca.locCnt = ma.Self().AsMaxP().NumGlyphs
 		}
+	case T("sbix"):
+		if ma := otf.table(T("maxp")); ma != nil {
+			t.Self().AsSbix().numGlyphs = ma.Self().AsMaxP().NumGlyphs
+		}
+	case T("hdmx"):
+		if ma := otf.table(T("maxp")); ma != nil {
+			t.Self().AsHdmx().numGlyphs = ma.Self().AsMaxP().NumGlyphs
+		}
+	case T("CBLC"):
+		if cd := otf.table(T("CBDT")); cd != nil {
+			t.Self().AsCBLC().imageData = cd.Binary()
+		} else {
+			tracer().Infof("font has CBLC table, but no CBDT table")
+		}
+	case T("cmap"):
+		otf.CMap = t.Self().AsCMap()
+	case T("GSUB"):
+		otf.Layout.GSub = t.Self().AsGSub()
+	case T("GPOS"):
+		otf.Layout.GPos = t.Self().AsGPos()
+	case T("GDEF"):
+		otf.Layout.GDef = t.Self().AsGDef()
+	case T("BASE"):
+		otf.Layout.Base = t.Self().AsBase()
 	}
-	return otf, nil
 }
 
 // According to the OpenType spec, the following tables are
@@ -94,28 +193,23 @@ var LayoutTables = []string{
 	//"GSUB", "GPOS", "GDEF", "BASE", "JSTF",
 }
 
-// Consistency check and shortcuts to essential tables, including layout tables.
+// Consistency check and shortcuts to essential tables.
+// Shortcuts to layout tables are set as soon as the tables are parsed (see linkTable).
 func extractLayoutInfo(otf *Font) error {
 	for _, tag := range RequiredTables {
-		h := otf.tables[T(tag)]
-		if h == nil {
-			return errFontFormat("missing required table " + tag)
+		if !otf.hasTable(T(tag)) {
+			return errTableMissing("missing required table " + tag)
 		}
 	}
-	otf.CMap = otf.tables[T("cmap")].Self().AsCMap()
+	if _, err := otf.parse(T("cmap")); err != nil {
+		return err
+	}
 	// We'll operate on OpenType fonts only, i.e. fonts containing GSUB and GPOS tables.
 	for _, tag := range LayoutTables {
-		h := otf.tables[T(tag)]
-		if h == nil {
-			return errFontFormat("missing advanced layout table " + tag)
+		if !otf.hasTable(T(tag)) {
+			return errTableMissing("missing advanced layout table " + tag)
 		}
 	}
-	// store shortcuts to layout tables
-	otf.Layout.GSub = otf.tables[T("GSUB")].Self().AsGSub()
-	otf.Layout.GPos = otf.tables[T("GPOS")].Self().AsGPos()
-	otf.Layout.GDef = otf.tables[T("GDEF")].Self().AsGDef()
-	//otf.Layout.Base = otf.tables[T("BASE")].Self().AsBase()
-	//otf.Layout.Jstf = otf.tables[T("JSTF")].Self().AsJstf()
 	return nil
 }
 
@@ -123,10 +217,18 @@ func parseTable(t Tag, b binarySegm, offset, size uint32) (Table, error) {
 	switch t {
 	case T("BASE"):
 		return parseBase(t, b, offset, size)
+	case T("CBLC"):
+		return parseCBLC(t, b, offset, size)
 	case T("cmap"):
 		return parseCMap(t, b, offset, size)
+	case T("COLR"):
+		return parseColr(t, b, offset, size)
+	case T("CPAL"):
+		return parseCPal(t, b, offset, size)
 	case T("head"):
 		return parseHead(t, b, offset, size)
+	case T("gasp"):
+		return parseGasp(t, b, offset, size)
 	case T("glyf"):
 		return newTable(t, b, offset, size), nil // TODO
 	case T("GDEF"):
@@ -135,16 +237,24 @@ func parseTable(t Tag, b binarySegm, offset, size uint32) (Table, error) {
 		return parseGPos(t, b, offset, size)
 	case T("GSUB"):
 		return parseGSub(t, b, offset, size)
+	case T("hdmx"):
+		return parseHdmx(t, b, offset, size)
 	case T("hhea"):
 		return parseHHea(t, b, offset, size)
 	case T("hmtx"):
 		return parseHMtx(t, b, offset, size)
+	case T("JSTF"):
+		return parseJstf(t, b, offset, size)
 	case T("kern"):
 		return parseKern(t, b, offset, size)
 	case T("loca"):
 		return parseLoca(t, b, offset, size)
 	case T("maxp"):
 		return parseMaxP(t, b, offset, size)
+	case T("sbix"):
+		return parseSbix(t, b, offset, size)
+	case T("VDMX"):
+		return parseVDMX(t, b, offset, size)
 	}
 	tracer().Infof("font contains table (%s), will not be interpreted", t)
 	return newTable(t, b, offset, size), nil
@@ -295,7 +405,7 @@ func parseCMap(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
 		}
 	}
 	if enc.width == 0 {
-		return nil, errFontFormat("no supported cmap format found")
+		return nil, errUnsupported("no supported cmap format found")
 	}
 	var err error
 	if t.GlyphIndexMap, err = makeGlyphIndex(b, enc); err != nil {
@@ -354,18 +464,19 @@ func parseKern(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
 		h := kernSubTableHeader{
 			offset: uint16(suboffset + subheaderlen),
 			// sub-tables are of varying size; size may be off ⇒ see below
-			length:   uint32(u16(b[suboffset+2:]) - uint16(subheaderlen)),
-			coverage: u16(b[suboffset+4:]),
+			length:   uint32(b.U16(suboffset+2) - uint16(subheaderlen)),
+			coverage: b.U16(suboffset + 4),
 		}
 		if format := h.coverage >> 8; format != 0 {
 			tracer().Infof("kern sub-table format %d not supported, ignoring sub-table", format)
+			suboffset += int(subheaderlen + int(h.length))
 			continue // we only support format 0 kerning tables; skip this one
 		}
 		h.directory = [4]uint16{
-			u16(b[suboffset+subheaderlen-8:]),
-			u16(b[suboffset+subheaderlen-6:]),
-			u16(b[suboffset+subheaderlen-4:]),
-			u16(b[suboffset+subheaderlen-2:]),
+			b.U16(suboffset + subheaderlen - 8),
+			b.U16(suboffset + subheaderlen - 6),
+			b.U16(suboffset + subheaderlen - 4),
+			b.U16(suboffset + subheaderlen - 2),
 		}
 		kerncnt := uint32(h.directory[0])
 		tracer().Debugf("kern sub-table has %d entries", kerncnt)
@@ -453,6 +564,217 @@ func parseHMtx(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
 	return t, nil
 }
 
+// --- COLR table ------------------------------------------------------------
+
+// The COLR table adds support for multi-colored glyphs. Version 0 of the table
+// has a header of 14 bytes:
+//
+//	uint16    version                 Table version number
+//	uint16    numBaseGlyphRecords     Number of BaseGlyph records
+//	Offset32  baseGlyphRecordsOffset  Offset to baseGlyphRecords array
+//	Offset32  layerRecordsOffset      Offset to layerRecords array
+//	uint16    numLayerRecords         Number of Layer records
+//
+// Version 1 adds offsets to paint graphs, which we currently do not interpret.
+func parseColr(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 14 {
+		return nil, errFontFormat("size of COLR table")
+	}
+	t := newColrTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	nbase, nlayers := int(b.U16(2)), int(b.U16(12))
+	baseOffset, layerOffset := int(b.U32(4)), int(b.U32(8))
+	if baseOffset+nbase*6 > len(b) || layerOffset+nlayers*4 > len(b) {
+		return nil, errFontFormat("COLR table records out of bounds")
+	}
+	t.baseGlyphRecords = array{recordSize: 6, length: nbase, loc: b[baseOffset:]}
+	t.layerRecords = array{recordSize: 4, length: nlayers, loc: b[layerOffset:]}
+	if t.Version > 0 {
+		tracer().Infof("COLR table version %d: paint graphs will not be interpreted", t.Version)
+	}
+	tracer().Debugf("COLR table has %d base glyphs and %d layers", nbase, nlayers)
+	return t, nil
+}
+
+// --- CPAL table ------------------------------------------------------------
+
+// The palette table header has the following format:
+//
+//	uint16    version                    Table version number
+//	uint16    numPaletteEntries          Number of palette entries in each palette
+//	uint16    numPalettes                Number of palettes in the table
+//	uint16    numColorRecords            Total number of color records, combined for all palettes
+//	Offset32  colorRecordsArrayOffset    Offset from the beginning of CPAL table to the first ColorRecord
+//	uint16    colorRecordIndices[numPalettes]  Index of each palette’s first color record
+//
+// Version 1 adds offsets to palette types and labels, which we currently ignore.
+func parseCPal(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 12 {
+		return nil, errFontFormat("size of CPAL table")
+	}
+	t := newCPalTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	t.NumPaletteEntries = int(b.U16(2))
+	t.NumPalettes = int(b.U16(4))
+	ncolors, colorsOffset := int(b.U16(6)), int(b.U32(8))
+	if 12+t.NumPalettes*2 > len(b) || colorsOffset+ncolors*4 > len(b) {
+		return nil, errFontFormat("CPAL table records out of bounds")
+	}
+	t.colorRecordsIndex = array{recordSize: 2, length: t.NumPalettes, loc: b[12:]}
+	t.colorRecords = array{recordSize: 4, length: ncolors, loc: b[colorsOffset:]}
+	tracer().Debugf("CPAL table has %d palettes with %d entries", t.NumPalettes, t.NumPaletteEntries)
+	return t, nil
+}
+
+// --- sbix table ------------------------------------------------------------
+
+// The sbix table header:
+//
+//	uint16    version                  Table version number — set to 1
+//	uint16    flags                    Bit 0: Set to 1; bit 1: Draw outlines
+//	uint32    numStrikes               Number of bitmap strikes
+//	Offset32  strikeOffsets[numStrikes]  Offsets from the beginning of the 'sbix' table to data for each strike
+//
+// Strike data cannot be interpreted without knowing the number of glyphs in the font,
+// which will be set during the consistency check of the font.
+func parseSbix(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 8 {
+		return nil, errFontFormat("size of sbix table")
+	}
+	t := newSbixTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	t.Flags = b.U16(2)
+	n := int(b.U32(4))
+	if 8+n*4 > len(b) {
+		return nil, errFontFormat("sbix table strikes out of bounds")
+	}
+	t.strikes = array{recordSize: 4, length: n, loc: b[8:]}
+	tracer().Debugf("sbix table has %d strikes", n)
+	return t, nil
+}
+
+// --- CBLC table ------------------------------------------------------------
+
+// The CBLC table header:
+//
+//	uint16      majorVersion    Major version of the CBLC table, = 3
+//	uint16      minorVersion    Minor version of the CBLC table, = 0
+//	uint32      numSizes        Number of BitmapSize records
+//	BitmapSize  bitmapSizes[numSizes]  BitmapSize records array, 48 bytes each
+//
+// The CBDT table, containing the image data, will be linked to the CBLC table during
+// the consistency check of the font.
+func parseCBLC(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 8 {
+		return nil, errFontFormat("size of CBLC table")
+	}
+	t := newCBLCTable(tag, b, offset, size)
+	n := int(b.U32(4))
+	if 8+n*48 > len(b) {
+		return nil, errFontFormat("CBLC table bitmap sizes out of bounds")
+	}
+	t.bitmapSizes = array{recordSize: 48, length: n, loc: b[8:]}
+	tracer().Debugf("CBLC table has %d bitmap sizes", n)
+	return t, nil
+}
+
+// --- gasp table ------------------------------------------------------------
+
+// The gasp table header:
+//
+//	uint16     version                 Version number (0 or 1)
+//	uint16     numRanges               Number of records to follow
+//	GaspRange  gaspRanges[numRanges]   Sorted by ppem, 4 bytes each
+func parseGasp(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 4 {
+		return nil, errFontFormat("size of gasp table")
+	}
+	t := newGaspTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	n := int(b.U16(2))
+	if 4+n*4 > len(b) {
+		return nil, errFontFormat("gasp table ranges out of bounds")
+	}
+	t.ranges = array{recordSize: 4, length: n, loc: b[4:]}
+	tracer().Debugf("gasp table has %d ranges", n)
+	return t, nil
+}
+
+// --- hdmx table ------------------------------------------------------------
+
+// The hdmx table header:
+//
+//	uint16        version                   Table version number (0)
+//	int16         numRecords                Number of device records
+//	uint32        sizeDeviceRecord          Size of a device record, 32-bit aligned
+//	DeviceRecord  records[numRecords]       Array of device records
+//
+// Device records cannot be interpreted without knowing the number of glyphs in the font,
+// which will be set during the consistency check of the font.
+func parseHdmx(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 8 {
+		return nil, errFontFormat("size of hdmx table")
+	}
+	t := newHdmxTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	n, recsize := int(int16(b.U16(2))), int(b.U32(4))
+	if n < 0 || recsize < 2 || 8+n*recsize > len(b) {
+		return nil, errFontFormat("hdmx table device records out of bounds")
+	}
+	t.records = array{recordSize: recsize, length: n, loc: b[8:]}
+	tracer().Debugf("hdmx table has %d device records", n)
+	return t, nil
+}
+
+// --- VDMX table ------------------------------------------------------------
+
+// The VDMX table header:
+//
+//	uint16      version                         Version number (0 or 1)
+//	uint16      numRecs                         Number of VDMX groups present
+//	uint16      numRatios                       Number of aspect ratio groupings
+//	RatioRange  ratRange[numRatios]             Ratio record array, 4 bytes each
+//	Offset16    vdmxGroupOffsets[numRatios]     Offsets from the start of the VDMX table to VDMX groups
+func parseVDMX(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 6 {
+		return nil, errFontFormat("size of VDMX table")
+	}
+	t := newVDMXTable(tag, b, offset, size)
+	t.Version = b.U16(0)
+	n := int(b.U16(4))
+	if 6+n*6 > len(b) {
+		return nil, errFontFormat("VDMX table ratio records out of bounds")
+	}
+	t.ratios = array{recordSize: 4, length: n, loc: b[6:]}
+	t.groups = array{recordSize: 2, length: n, loc: b[6+n*4:]}
+	tracer().Debugf("VDMX table has %d aspect ratios", n)
+	return t, nil
+}
+
+// --- JSTF table ------------------------------------------------------------
+
+// The JSTF table header:
+//
+//	uint16    majorVersion     Major version of the JSTF table, = 1
+//	uint16    minorVersion     Minor version of the JSTF table, = 0
+//	uint16    jstfScriptCount  Number of JstfScriptRecords in this table
+//	JstfScriptRecord  jstfScriptRecords[jstfScriptCount]  Array of JstfScriptRecords, in alphabetical order by jstfScriptTag
+//
+// Script tables are parsed on demand.
+func parseJstf(tag Tag, b binarySegm, offset, size uint32) (Table, error) {
+	if size < 6 {
+		return nil, errFontFormat("size of JSTF table")
+	}
+	t := newJstfTable(tag, b, offset, size)
+	n := int(b.U16(4))
+	if 6+n*6 > len(b) {
+		return nil, errFontFormat("JSTF table script records out of bounds")
+	}
+	t.scripts = parseTagRecordMap16(b, 4, b, "JSTF", "JstfScript")
+	tracer().Debugf("JSTF table has %d scripts", n)
+	return t, nil
+}
+
 // --- Names -----------------------------------------------------------------
 
 func parseNames(b binarySegm) (nameNames, error) {
@@ -462,7 +784,7 @@ func parseNames(b binarySegm) (nameNames, error) {
 	N, _ := b.u16(2)
 	names := nameNames{}
 	strOffset, _ := b.u16(4)
-	names.strbuf = b[strOffset:]
+	names.strbuf = b.at(int(strOffset))
 	tracer().Debugf("name table has %d strings, starting at %d", N, strOffset)
 	if len(b) < 6+12*int(N) {
 		return nameNames{}, errFontFormat("name section corrupt")
@@ -568,8 +890,8 @@ func parseAttachmentPointList(gdef *GDefTable, b binarySegm, err error) error {
 		}
 		return nil // no entries
 	}
-	covOffset := u16(b)
-	coverage := parseCoverage(b[covOffset:])
+	covOffset := b.U16(0)
+	coverage := parseCoverage(b.at(int(covOffset)))
 	if coverage.GlyphRange == nil {
 		return errFontFormat("GDEF attachement point coverage table unreadable")
 	}
@@ -615,7 +937,7 @@ func parseMarkGlyphSets(gdef *GDefTable, b binarySegm, err error) error {
 	count, _ := b.u16(2)
 	for i := 0; i < int(count); i++ {
 		covOffset, _ := b.u32(i * 4)
-		coverage := parseCoverage(b[covOffset:])
+		coverage := parseCoverage(b.at(int(covOffset)))
 		if coverage.GlyphRange == nil {
 			return errFontFormat("GDEF mark glyph set coverage table unreadable")
 		}
@@ -683,8 +1005,8 @@ func parseLayoutHeader(lytt *LayoutTable, b binarySegm, err error) error {
 		return err
 	}
 	if h.Major != 1 || (h.Minor != 0 && h.Minor != 1) {
-		return fmt.Errorf("unsupported layout version (major: %d, minor: %d)",
-			h.Major, h.Minor)
+		return errUnsupported(fmt.Sprintf("unsupported layout version (major: %d, minor: %d)",
+			h.Major, h.Minor))
 	}
 	switch h.Minor {
 	case 0:
@@ -790,7 +1112,7 @@ func parseLookupList(lytt *LayoutTable, b binarySegm, err error) error {
 }
 
 func parseLookupSubtable(b binarySegm, lookupType LayoutTableLookupType) LookupSubtable {
-	tracer().Debugf("parse lookup subtable b = %v", asU16Slice(b[:20]))
+	tracer().Debugf("parse lookup subtable b = %v", asU16Slice(head(b, 20)))
 	if len(b) < 4 {
 		return LookupSubtable{}
 	}
@@ -804,14 +1126,22 @@ func parseLookupSubtable(b binarySegm, lookupType LayoutTableLookupType) LookupS
 // and expects to read a lookup subtable.
 func parseGSubLookupSubtable(b binarySegm, lookupType LayoutTableLookupType) LookupSubtable {
 	//trace().Debugf("parse lookup subtable b = %v", asU16Slice(b[:20]))
-	format := b.U16(0)
+	format, err := b.CheckedU16(0)
+	if err != nil {
+		tracer().Errorf("OpenType GSUB lookup subtable type %d corrupt", lookupType)
+		return LookupSubtable{}
+	}
 	tracer().Debugf("parsing GSUB sub-table type %s, format %d", lookupType.GSubString(), format)
 	sub := LookupSubtable{LookupType: lookupType, Format: format}
 	// Most of the subtable formats use a coverage table in some form to decide on which glyphs to
 	// operate on. parseGSubLookupSubtable will parse this coverage table and put it into
 	// `sub.Coverage`, then branch down to the different lookup types.
 	if !(lookupType == 7 && format == 3) { // GSUB type Extension has no coverage table
-		covlink, _ := parseLink16(b, 2, b, "Coverage")
+		covlink, err := parseLink16(b, 2, b, "Coverage")
+		if err != nil {
+			tracer().Errorf("OpenType GSUB lookup subtable type %d has corrupt coverage", lookupType)
+			return LookupSubtable{}
+		}
 		sub.Coverage = parseCoverage(covlink.Jump().Bytes())
 	}
 	switch lookupType {
@@ -836,7 +1166,12 @@ func parseGSubLookupSubtable(b binarySegm, lookupType LayoutTableLookupType) Loo
 // https://docs.microsoft.com/en-us/typography/opentype/spec/gsub#lookuptype-1-single-substitution-subtable
 func parseGSubLookupSubtableType1(b binarySegm, sub LookupSubtable) LookupSubtable {
 	if sub.Format == 1 {
-		sub.Support = int16(b.U16(4))
+		delta, err := b.CheckedU16(4)
+		if err != nil {
+			tracer().Errorf("OpenType GSUB lookup subtable type 1 corrupt")
+			return LookupSubtable{}
+		}
+		sub.Support = int16(delta)
 	} else {
 		sub.Index = parseVarArray16(b, 4, 2, 1, "LookupSubtableGSub1")
 	}
@@ -911,8 +1246,10 @@ func parseGSubLookupSubtableType6(b binarySegm, sub LookupSubtable) LookupSubtab
 		sub.Index = parseVarArray16(b, 10, 2, 2, "LookupSubtableGSub6-2")
 	case 3:
 		offset := 2 // skip over format field
-		// TODO treat error conditions
-		seqctx := sub.Support.(*SequenceContext)
+		seqctx, ok := sub.Support.(*SequenceContext)
+		if !ok {
+			return LookupSubtable{}
+		}
 		offset += 2 + len(seqctx.BacktrackCoverage)*2
 		offset += 2 + len(seqctx.InputCoverage)*2
 		offset += 2 + len(seqctx.LookaheadCoverage)*2
@@ -935,16 +1272,25 @@ func parseGSubLookupSubtableType7(b binarySegm, sub LookupSubtable) LookupSubtab
 		return LookupSubtable{}
 	}
 	tracer().Debugf("OpenType GSUB extension subtable is of type %s", sub.LookupType.GSubString())
-	link, _ := parseLink32(b, 4, b, "ext.LookupSubtable")
+	link, err := parseLink32(b, 4, b, "ext.LookupSubtable")
+	if err != nil {
+		tracer().Errorf("OpenType GSUB extension subtable link corrupt")
+		return LookupSubtable{}
+	}
 	loc := link.Jump()
 	return parseGSubLookupSubtable(loc.Bytes(), sub.LookupType)
 }
 
 func parseGPosLookupSubtable(b binarySegm, lookupType LayoutTableLookupType) LookupSubtable {
-	format := b.U16(0)
+	format, err := b.CheckedU16(0)
+	if err != nil {
+		tracer().Errorf("OpenType GPOS lookup subtable type %d corrupt", lookupType)
+		return LookupSubtable{}
+	}
 	tracer().Debugf("parsing GPOS sub-table type %s, format %d", lookupType.GPosString(), format)
-	panic("TODO GPOS Lookup Subtable")
-	//return LookupSubtable{}
+	// TODO GPOS Lookup Subtable
+	tracer().Infof("OpenType GPOS lookup subtables not yet supported")
+	return LookupSubtable{LookupType: lookupType, Format: format}
 }
 
 // --- parse class def table -------------------------------------------------
@@ -983,8 +1329,13 @@ func parseClassDefinitions(b binarySegm) (ClassDefinitions, error) {
 func parseCoverage(b binarySegm) Coverage {
 	tracer().Debugf("parsing Coverage")
 	h := coverageHeader{}
-	h.CoverageFormat = b.U16(0)
-	h.Count = b.U16(2)
+	var err1, err2 error
+	h.CoverageFormat, err1 = b.CheckedU16(0)
+	h.Count, err2 = b.CheckedU16(2)
+	if err1 != nil || err2 != nil {
+		tracer().Errorf("corrupt coverage table")
+		return Coverage{GlyphRange: &glyphRangeArray{}} // matches no glyph
+	}
 	// r := bytes.NewReader(b)
 	// if err := binary.Read(r, binary.BigEndian, &h); err != nil {
 	// 	return Coverage{}
@@ -1081,7 +1432,7 @@ func parseSequenceContextFormat3(b binarySegm, sub LookupSubtable) (LookupSubtab
 	if len(b) <= 8 {
 		return sub, errFontFormat("corrupt sequence context")
 	}
-	glyphCount := int(b.U16(2))
+	glyphCount := int(b.U16(2)) // len(b) has been checked above
 	seqctx := SequenceContext{}
 	sub.Support = seqctx
 	seqctx.InputCoverage = make([]Coverage, glyphCount)
@@ -1104,8 +1455,8 @@ func parseChainedSequenceContext(b binarySegm, sub LookupSubtable) (LookupSubtab
 	case 1:
 		//parseSequenceContextFormat1(sub.Format, b, sub)
 		// nothing to to for format 1
-		panic("TODO chained 1")
-		//return sub, nil
+		// TODO chained 1
+		return sub, errUnsupported("chained sequence context format 1")
 	case 2:
 		return parseChainedSequenceContextFormat2(b, sub)
 	case 3:
@@ -1129,7 +1480,7 @@ func parseChainedSequenceContextFormat2(b binarySegm, sub LookupSubtable) (Looku
 
 func parseChainedSequenceContextFormat3(b binarySegm, sub LookupSubtable) (LookupSubtable, error) {
 	tracer().Debugf("chained sequence context format 3 ........................")
-	tracer().Debugf("b = %v", b[:26].Glyphs())
+	tracer().Debugf("b = %v", head(b, 26).Glyphs())
 	offset := 2
 	backtrack, err1 := parseChainedSeqContextCoverages(b, offset, nil)
 	offset += 2 + len(backtrack)*2
@@ -1163,7 +1514,11 @@ func parseChainedSeqContextCoverages(b binarySegm, at int, err error) ([]Coverag
 	if err != nil {
 		return []Coverage{}, err
 	}
-	count := int(b.U16(at))
+	n, err := b.CheckedU16(at)
+	if err != nil {
+		return []Coverage{}, err
+	}
+	count := int(n)
 	coverages := make([]Coverage, count)
 	tracer().Debugf("chained seq context with %d coverages", count)
 	for i := 0; i < count; i++ {
@@ -1185,23 +1540,37 @@ func parseChainedSeqContextCoverages(b binarySegm, at int, err error) ([]Coverag
 // uint16   seqLookupCount                Number of SequenceLookupRecords
 // uint16   inputSequence[glyphCount-1]   Sequence of classes to be matched to the input glyph sequence, beginning with the second glyph position
 // SequenceLookupRecord seqLookupRecords[seqLookupCount]   Array of SequenceLookupRecords
+//
+// A corrupt sequence rule will result in an empty rule.
 func (lksub LookupSubtable) SequenceRule(b binarySegm) sequenceRule {
 	seqrule := sequenceRule{}
-	seqrule.glyphCount = b.U16(0)
-	seqrule.inputSequence = array{
-		recordSize: 2, // sizeof(uint16)
-		length:     int(seqrule.glyphCount) - 1,
-	}
-	seqrule.inputSequence.loc = b[4 : 4+seqrule.inputSequence.length*2]
+	glyphCount, err1 := b.CheckedU16(0)
+	cnt, err2 := b.CheckedU16(2)
+	if err1 != nil || err2 != nil || glyphCount == 0 {
+		tracer().Errorf("corrupt sequence rule")
+		return sequenceRule{}
+	}
+	inputLen := int(glyphCount) - 1
+	input, err1 := b.CheckedSlice(4, 4+inputLen*2)
 	// SequenceLookupRecord:
 	// Type     Name             Description
 	// uint16   sequenceIndex    Index (zero-based) into the input glyph sequence
 	// uint16   lookupListIndex  Index (zero-based) into the LookupList
-	cnt := b.U16(2)
+	records, err2 := b.CheckedSlice(4+inputLen*2, 4+inputLen*2+int(cnt)*4)
+	if err1 != nil || err2 != nil {
+		tracer().Errorf("sequence rule out of bounds")
+		return sequenceRule{}
+	}
+	seqrule.glyphCount = glyphCount
+	seqrule.inputSequence = array{
+		recordSize: 2, // sizeof(uint16)
+		length:     inputLen,
+		loc:        input.(binarySegm),
+	}
 	seqrule.lookupRecords = array{
 		recordSize: 4, // 2* sizeof(uint16)
 		length:     int(cnt),
-		loc:        b[4+seqrule.inputSequence.length*2:],
+		loc:        records.(binarySegm),
 	}
 	return seqrule
 }
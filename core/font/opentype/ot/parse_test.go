@@ -1,6 +1,8 @@
 package ot
 
 import (
+	"errors"
+	"sync"
 	"testing"
 
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
@@ -23,6 +25,43 @@ func TestParseHeader(t *testing.T) {
 	}
 }
 
+func TestParseErrorDomains(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	f := loadTestFont(t, "gentiumplus")
+	corrupt := func(patch func(b []byte)) []byte {
+		b := append([]byte(nil), f.F.Binary...)
+		patch(b)
+		return b
+	}
+	unsupported := corrupt(func(b []byte) { copy(b, "wOFF") })
+	misaligned := corrupt(func(b []byte) { b[12+11]++ }) // offset of first table record
+	nocmap := corrupt(func(b []byte) {
+		for r := b[12:]; len(r) >= 16; r = r[16:] {
+			if string(r[:4]) == "cmap" {
+				copy(r, "cmaq")
+			}
+		}
+	})
+	for i, test := range []struct {
+		font   []byte
+		domain error
+	}{
+		{unsupported, core.ErrUnsupported},
+		{misaligned, core.ErrFontFormat},
+		{nocmap, core.ErrTableMissing},
+	} {
+		_, err := Parse(test.font)
+		if !errors.Is(err, test.domain) || core.Domain(err) != test.domain {
+			t.Errorf("test %d: expected error in domain %q, have %v", i, test.domain, err)
+		}
+		if core.UserMessage(err) == "" {
+			t.Errorf("test %d: expected error to carry a user message", i)
+		}
+	}
+}
+
 // TODO TODO
 func TestCMapTableGlyphIndex(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
@@ -68,7 +107,7 @@ func TestParseGPos(t *testing.T) {
 		t.Fatalf("expected font to have GPOS table, hasn't")
 	}
 	gposTag := T("GPOS")
-	gpos := otf.tables[gposTag].Self().AsGPos()
+	gpos := otf.Table(gposTag).Self().AsGPos()
 	if gpos == nil {
 		t.Fatalf("cannot find a GPOS table")
 	}
@@ -110,7 +149,7 @@ func TestParseGSub(t *testing.T) {
 		t.Fatalf("expected font to have GSUB table, hasn't")
 	}
 	gsubTag := T("GSUB")
-	gsub := otf.tables[gsubTag].Self().AsGSub()
+	gsub := otf.Table(gsubTag).Self().AsGSub()
 	if gsub == nil {
 		t.Fatalf("cannot find a GSUB table")
 	}
@@ -128,6 +167,64 @@ func TestParseGSub(t *testing.T) {
 	// }
 }
 
+func TestParseLazily(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	f := loadTestFont(t, "gentiumplus")
+	otf, err := Parse(f.F.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otf.Layout.GSub != nil || len(otf.raw) == 0 {
+		t.Errorf("expected GSUB to not be parsed before first access")
+	}
+	if otf.CMap == nil {
+		t.Errorf("expected cmap to be parsed eagerly")
+	}
+	tagCount := len(otf.TableTags())
+	if gsub := otf.Table(T("GSUB")).Self().AsGSub(); gsub == nil || otf.Layout.GSub != gsub {
+		t.Errorf("expected GSUB to be parsed on first access, and shortcut to be set")
+	}
+	if len(otf.TableTags()) != tagCount {
+		t.Errorf("expected number of tables to be stable, was %d, is %d", tagCount, len(otf.TableTags()))
+	}
+	otf, err = Parse(f.F.Binary, EagerParsing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otf.Layout.GSub == nil || otf.Layout.GPos == nil || len(otf.raw) != 0 {
+		t.Errorf("expected all tables to be parsed eagerly")
+	}
+}
+
+func TestLayoutAccessorsConcurrently(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	f := loadTestFont(t, "gentiumplus")
+	otf, err := Parse(f.F.Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	gsubs := make([]*GSubTable, 4)
+	for i := range gsubs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gsubs[i] = otf.GSub()
+			otf.GPos()
+		}(i)
+	}
+	wg.Wait()
+	for i, gsub := range gsubs {
+		if gsub == nil || gsub != gsubs[0] {
+			t.Errorf("expected goroutine %d to see the single GSUB table", i)
+		}
+	}
+}
+
 func TestParseKern(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
 	defer teardown()
@@ -149,7 +246,7 @@ func TestParseKern(t *testing.T) {
 	if !hasKern {
 		t.Fatalf("expected font to have kern table, hasn't")
 	}
-	kern := otf.tables[T("kern")].Self().AsKern()
+	kern := otf.Table(T("kern")).Self().AsKern()
 	if kern == nil {
 		t.Fatalf("cannot find a kern table")
 	}
@@ -165,7 +262,7 @@ func TestParseOtherTables(t *testing.T) {
 		core.UserError(err)
 		t.Fatal(err)
 	}
-	maxp := otf.tables[T("maxp")].Self().AsMaxP()
+	maxp := otf.Table(T("maxp")).Self().AsMaxP()
 	if maxp == nil {
 		t.Fatalf("cannot find a maxp table")
 	}
@@ -173,11 +270,11 @@ func TestParseOtherTables(t *testing.T) {
 	if maxp.NumGlyphs != 3874 {
 		t.Errorf("expected Calibri to have 3874 glyphs, but %d indicated", maxp.NumGlyphs)
 	}
-	loca := otf.tables[T("loca")].Self().AsLoca()
+	loca := otf.Table(T("loca")).Self().AsLoca()
 	if loca == nil {
 		t.Fatalf("cannot find a maxp table")
 	}
-	hhea := otf.tables[T("hhea")].Self().AsHHea()
+	hhea := otf.Table(T("hhea")).Self().AsHHea()
 	if hhea == nil {
 		t.Fatalf("cannot find a hhea table")
 	}
@@ -254,7 +351,7 @@ func TestParseGSubLookups(t *testing.T) {
 // ---------------------------------------------------------------------------
 
 func getTable(otf *Font, name string, t *testing.T) Table {
-	table := otf.tables[T(name)]
+	table := otf.Table(T(name))
 	if table == nil {
 		t.Fatalf("table %s not found in font", name)
 	}
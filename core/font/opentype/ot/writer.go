@@ -0,0 +1,257 @@
+package ot
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Writing bytes of a font's binary representation
+//
+// Package ot is mainly concerned with reading fonts. However, some clients need to
+// synthesize font data, e.g. for subsetting fonts or for constructing test
+// fixtures programmatically instead of relying on binary font files.
+// TableWriter assembles the binary data of a single table, FontBuilder combines
+// tables into a font, constructing the table directory.
+
+// TableWriter is a helper to assemble the binary data of a font table.
+// All values are written big-endian, as required by the OpenType specification.
+// The zero value is an empty table, ready to use.
+//
+// Offsets are usually not known when writing the field holding them. Clients
+// therefore reserve space for an offset and patch it later, when the
+// offset target is written:
+//
+//	var w ot.TableWriter
+//	w.U16(1)                          // version
+//	at := w.ReserveOffset16()         // offset to sub-table, relative to start of table
+//	…
+//	err := w.PatchOffset16(at, 0)     // sub-table starts here
+//	w.U16Array(glyphs)
+type TableWriter struct {
+	buf []byte
+}
+
+// Len returns the number of bytes written so far.
+func (w *TableWriter) Len() int {
+	return len(w.buf)
+}
+
+// Bytes returns the binary data written.
+func (w *TableWriter) Bytes() []byte {
+	return w.buf
+}
+
+// U8 writes an 8-bit unsigned value.
+func (w *TableWriter) U8(x uint8) {
+	w.buf = append(w.buf, x)
+}
+
+// U16 writes a 16-bit unsigned value.
+func (w *TableWriter) U16(x uint16) {
+	w.buf = append(w.buf, byte(x>>8), byte(x))
+}
+
+// I16 writes a 16-bit signed value, e.g. a FWORD.
+func (w *TableWriter) I16(x int16) {
+	w.U16(uint16(x))
+}
+
+// U32 writes a 32-bit unsigned value.
+func (w *TableWriter) U32(x uint32) {
+	w.buf = append(w.buf, byte(x>>24), byte(x>>16), byte(x>>8), byte(x))
+}
+
+// Tag writes a tag.
+func (w *TableWriter) Tag(t Tag) {
+	w.U32(uint32(t))
+}
+
+// Write appends raw bytes.
+func (w *TableWriter) Write(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+// U16Array writes an array of 16-bit values, without a count.
+func (w *TableWriter) U16Array(a []uint16) {
+	for _, x := range a {
+		w.U16(x)
+	}
+}
+
+// U32Array writes an array of 32-bit values, without a count.
+func (w *TableWriter) U32Array(a []uint32) {
+	for _, x := range a {
+		w.U32(x)
+	}
+}
+
+// Glyphs writes an array of glyph indices, without a count.
+func (w *TableWriter) Glyphs(glyphs []GlyphIndex) {
+	for _, g := range glyphs {
+		w.U16(uint16(g))
+	}
+}
+
+// ReserveOffset16 reserves space for a 16-bit offset, to be set with
+// PatchOffset16. It returns the position of the offset field.
+func (w *TableWriter) ReserveOffset16() int {
+	at := len(w.buf)
+	w.U16(0)
+	return at
+}
+
+// ReserveOffset32 reserves space for a 32-bit offset, to be set with
+// PatchOffset32. It returns the position of the offset field.
+func (w *TableWriter) ReserveOffset32() int {
+	at := len(w.buf)
+	w.U32(0)
+	return at
+}
+
+// ReserveOffsets16 reserves space for an array of n 16-bit offsets and returns
+// the positions of the offset fields.
+func (w *TableWriter) ReserveOffsets16(n int) []int {
+	slots := make([]int, n)
+	for i := range slots {
+		slots[i] = w.ReserveOffset16()
+	}
+	return slots
+}
+
+// PatchOffset16 sets a 16-bit offset field, previously reserved at position at,
+// to point to the current end of the data. The offset is relative to position
+// base, e.g. the start of the enclosing sub-table.
+// It is an error if the offset does not fit into 16 bits.
+func (w *TableWriter) PatchOffset16(at int, base int) error {
+	offset := len(w.buf) - base
+	if at < 0 || at+2 > len(w.buf) || offset < 0 || offset > 0xffff {
+		return errFontFormat(fmt.Sprintf("offset16 out of range: %d", offset))
+	}
+	w.buf[at], w.buf[at+1] = byte(offset>>8), byte(offset)
+	return nil
+}
+
+// PatchOffset32 sets a 32-bit offset field, previously reserved at position at,
+// to point to the current end of the data. The offset is relative to position
+// base.
+func (w *TableWriter) PatchOffset32(at int, base int) error {
+	offset := len(w.buf) - base
+	if at < 0 || at+4 > len(w.buf) || offset < 0 {
+		return errFontFormat(fmt.Sprintf("offset32 out of range: %d", offset))
+	}
+	w.buf[at], w.buf[at+1] = byte(offset>>24), byte(offset>>16)
+	w.buf[at+2], w.buf[at+3] = byte(offset>>8), byte(offset)
+	return nil
+}
+
+// Align pads the data with zeros to a multiple of n bytes.
+func (w *TableWriter) Align(n int) {
+	for n > 1 && len(w.buf)%n != 0 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+// Checksum calculates the checksum of a table, i.e. the sum of its data
+// interpreted as 32-bit values, with the data padded with zeros to a multiple of
+// 4 bytes.
+func Checksum(b []byte) uint32 {
+	var sum uint32
+	for ; len(b) >= 4; b = b[4:] {
+		sum += u32(b)
+	}
+	if len(b) > 0 {
+		pad := [4]byte{}
+		copy(pad[:], b)
+		sum += u32(pad[:])
+	}
+	return sum
+}
+
+// --- Font builder ----------------------------------------------------------
+
+// Font types for FontBuilder, i.e. the sfntVersion of the font header.
+const (
+	TrueTypeFont uint32 = 0x00010000 // font with TrueType outlines
+	CFFFont      uint32 = 0x4f54544f // 'OTTO', font with CFF outlines
+)
+
+// checksumMagic is used for calculating head.checksumAdjustment.
+const checksumMagic = 0xb1b0afba
+
+// FontBuilder assembles tables into a font, constructing the table directory.
+//
+//	fb := ot.NewFontBuilder(ot.TrueTypeFont)
+//	fb.AddTable(ot.T("head"), head)
+//	…
+//	otf, err := ot.Parse(fb.Bytes())
+type FontBuilder struct {
+	fontType uint32
+	tables   map[Tag][]byte
+}
+
+// NewFontBuilder creates a builder for a font of a given font type.
+func NewFontBuilder(fontType uint32) *FontBuilder {
+	return &FontBuilder{
+		fontType: fontType,
+		tables:   make(map[Tag][]byte),
+	}
+}
+
+// AddTable adds the binary data of a table to the font, replacing a table
+// previously added for the same tag.
+func (fb *FontBuilder) AddTable(tag Tag, data []byte) {
+	fb.tables[tag] = data
+}
+
+// Bytes returns the binary font data. The table directory is sorted by tag,
+// tables are 4-byte aligned and table checksums are calculated. If the font
+// contains a 'head' table, its checksumAdjustment field is set.
+func (fb *FontBuilder) Bytes() []byte {
+	tags := make([]Tag, 0, len(fb.tables))
+	for tag := range fb.tables {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	n := len(tags)
+	searchRange, entrySelector := 0, 0 // stay 0 for an empty font, as does rangeShift
+	if n > 0 {
+		for 1<<(entrySelector+1) <= n {
+			entrySelector++
+		}
+		searchRange = 16 << entrySelector
+	}
+	//
+	w := TableWriter{}
+	w.U32(fb.fontType)
+	w.U16(uint16(n))
+	w.U16(uint16(searchRange))
+	w.U16(uint16(entrySelector))
+	w.U16(uint16(16*n - searchRange))
+	offset := w.Len() + 16*n
+	headAt := -1
+	tables := make([][]byte, n)
+	for i, tag := range tags {
+		data := fb.tables[tag]
+		if tag == T("head") && len(data) >= 12 {
+			data = append([]byte(nil), data...)
+			copy(data[8:12], []byte{0, 0, 0, 0}) // checksumAdjustment
+			headAt = offset
+		}
+		tables[i] = data
+		w.Tag(tag)
+		w.U32(Checksum(data))
+		w.U32(uint32(offset))
+		w.U32(uint32(len(data)))
+		offset += (len(data) + 3) &^ 3
+	}
+	for _, data := range tables {
+		w.Write(data)
+		w.Align(4)
+	}
+	if headAt >= 0 {
+		adjustment := checksumMagic - Checksum(w.buf)
+		w.buf[headAt+8], w.buf[headAt+9] = byte(adjustment>>24), byte(adjustment>>16)
+		w.buf[headAt+10], w.buf[headAt+11] = byte(adjustment>>8), byte(adjustment)
+	}
+	return w.Bytes()
+}
@@ -0,0 +1,135 @@
+package ot
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestTableWriterOffsets(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	var w TableWriter
+	w.U16(1)
+	slots := w.ReserveOffsets16(2)
+	if err := w.PatchOffset16(slots[1], 0); err != nil {
+		t.Fatal(err)
+	}
+	w.Glyphs([]GlyphIndex{7, 8})
+	b := binarySegm(w.Bytes())
+	if b.U16(2) != 0 || b.U16(4) != 6 || b.U16(6) != 7 {
+		t.Errorf("unexpected table data: % x", w.Bytes())
+	}
+	w.Write(make([]byte, 0x10000))
+	if err := w.PatchOffset16(slots[0], 0); err == nil {
+		t.Errorf("expected offset overflow to be flagged")
+	}
+	w.U8(1)
+	w.Align(4)
+	if w.Len()%4 != 0 {
+		t.Errorf("expected table to be 4-byte aligned, length is %d", w.Len())
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	if sum := Checksum([]byte{0, 0, 0, 1, 0, 0, 0, 2, 1}); sum != 0x01000003 {
+		t.Errorf("expected checksum to be 0x01000003, is %#x", sum)
+	}
+}
+
+func TestFontBuilder(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	fb := NewFontBuilder(TrueTypeFont)
+	fb.AddTable(T("cmap"), buildCMap('A', 'C', 1))
+	fb.AddTable(T("head"), buildHead(1000))
+	for _, tag := range []string{"hhea", "hmtx", "maxp", "name", "OS/2", "post"} {
+		fb.AddTable(T(tag), make([]byte, 36))
+	}
+	for _, tag := range LayoutTables {
+		fb.AddTable(T(tag), buildLayoutTable())
+	}
+	font := fb.Bytes()
+	if Checksum(font) != checksumMagic {
+		t.Errorf("expected checksum of font to be %#x, is %#x", checksumMagic, Checksum(font))
+	}
+	otf, err := Parse(font)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(otf.TableTags()); n != 11 {
+		t.Errorf("expected synthesized font to have 11 tables, has %d", n)
+	}
+	if head := otf.Table(T("head")).Self().AsHead(); head == nil || head.UnitsPerEm != 1000 {
+		t.Errorf("expected head table with 1000 units per em")
+	}
+	cmap := otf.Table(T("cmap")).Self().AsCMap()
+	if g := cmap.GlyphIndexMap.Lookup('B'); g != 2 {
+		t.Errorf("expected 'B' to map to glyph 2, is %d", g)
+	}
+}
+
+func TestFontBuilderEmpty(t *testing.T) {
+	font := NewFontBuilder(TrueTypeFont).Bytes()
+	if len(font) != 12 {
+		t.Fatalf("expected empty font to consist of the offset table only, has %d bytes", len(font))
+	}
+	for i, b := range font[4:] {
+		if b != 0 {
+			t.Errorf("expected header fields of empty font to be 0, byte %d is %#x", i+4, b)
+		}
+	}
+}
+
+// buildHead builds a minimal head table.
+func buildHead(unitsPerEm uint16) []byte {
+	var w TableWriter
+	w.U16(1) // major version
+	w.U16(0) // minor version
+	w.U32(0) // font revision
+	w.U32(0) // checksum adjustment
+	w.U32(0x5f0f3cf5)
+	w.U16(0) // flags
+	w.U16(unitsPerEm)
+	w.Write(make([]byte, 54-w.Len()))
+	return w.Bytes()
+}
+
+// buildCMap builds a cmap table with a single format 4 sub-table, mapping
+// code points from…to to consecutive glyphs.
+func buildCMap(from, to rune, glyph GlyphIndex) []byte {
+	var w TableWriter
+	w.U16(0) // version
+	w.U16(1) // number of encoding records
+	w.U16(3) // platform Windows
+	w.U16(1) // Unicode BMP
+	at := w.ReserveOffset32()
+	w.PatchOffset32(at, 0)
+	start := w.Len()
+	w.U16(4) // format
+	length := w.ReserveOffset16()
+	w.U16(0)                                 // language
+	w.U16Array([]uint16{4, 4, 1, 0})         // segCountX2, searchRange, entrySelector, rangeShift
+	w.U16Array([]uint16{uint16(to), 0xffff}) // end codes
+	w.U16(0)                                 // padding
+	w.U16Array([]uint16{uint16(from), 0xffff})
+	w.U16Array([]uint16{uint16(glyph) - uint16(from), 1})
+	w.U16Array([]uint16{0, 0})
+	w.PatchOffset16(length, start)
+	return w.Bytes()
+}
+
+// buildLayoutTable builds an empty GSUB/GPOS/GDEF table, version 1.0.
+func buildLayoutTable() []byte {
+	var w TableWriter
+	w.U16(1)
+	w.U16(0)
+	slots := w.ReserveOffsets16(3)
+	for _, at := range slots {
+		w.PatchOffset16(at, 0)
+		w.U16(0) // empty list
+	}
+	return w.Bytes()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/pterm/pterm"
+)
+
+// --- Non-interactive mode --------------------------------------------------
+
+// Besides the REPL, otcli executes commands given with flag -e, or read from a
+// script file given with flag -script. Commands are separated by newlines or
+// semicolons, and script lines starting with '#' are comments. Execution stops
+// at the first failing command, and otcli exits with a non-zero status, which
+// makes otcli usable for checks in CI and in shell pipelines:
+//
+//	otcli -font Calibri.ttf -e "table:GSUB scripts:latn"
+//	otcli -font Calibri.ttf -script checks.ot -json | jq .value
+//
+// With flag -json, results are printed as JSON objects, one per line.
+
+// result is the outcome of a single operation of a command.
+type result struct {
+	Op    string      `json:"op"`              // name of the operation
+	Arg   string      `json:"arg,omitempty"`   // argument of the operation
+	Value interface{} `json:"value,omitempty"` // machine-readable result
+	Error string      `json:"error,omitempty"` // error message, if the operation failed
+}
+
+var opNames = map[int]string{
+	QUIT:     "quit",
+	HELP:     "help",
+	NAVIGATE: "->",
+	TABLE:    "table",
+	LIST:     "list",
+	MAP:      "map",
+	SCRIPTS:  "scripts",
+	FEATURES: "features",
+	CMAP:     "cmap",
+	GLYPH:    "glyph",
+	COVERAGE: "coverage",
+	APPLY:    "apply",
+}
+
+// report outputs the result of operation op. In text mode, text is printed, if
+// not empty; in JSON mode, value is printed as part of a JSON object.
+func (intp *Intp) report(op Op, text string, value interface{}) {
+	if !intp.json {
+		if text != "" {
+			pterm.Println(text)
+		}
+		return
+	}
+	intp.printJSON(result{Op: opNames[op.code], Arg: op.arg, Value: value})
+}
+
+// fail outputs an error of operation op.
+func (intp *Intp) fail(op Op, err error) {
+	if !intp.json {
+		pterm.Error.Println(err.Error())
+		return
+	}
+	intp.printJSON(result{Op: opNames[op.code], Arg: op.arg, Error: err.Error()})
+}
+
+func (intp *Intp) printJSON(r result) {
+	if err := json.NewEncoder(os.Stdout).Encode(r); err != nil {
+		tracer().Errorf("cannot encode result: %v", err)
+	}
+}
+
+// runBatch executes commands from string cmds, then from script file script, if
+// not empty. It returns the first error, if any.
+func (intp *Intp) runBatch(cmds, script string) error {
+	lines := splitCommands(cmds)
+	if script != "" {
+		s, err := readScript(script)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, s...)
+	}
+	for _, line := range lines {
+		cmd, err := intp.parseCommand(line)
+		if err != nil {
+			intp.fail(Op{code: HELP, arg: line}, err)
+			return err
+		}
+		err, quit := intp.execute(cmd)
+		if err != nil {
+			return err
+		}
+		if quit {
+			break
+		}
+	}
+	return nil
+}
+
+// readScript returns the commands of a script file, skipping comments and empty
+// lines.
+func readScript(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open script: %w", err)
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(line, "#") {
+			lines = append(lines, splitCommands(line)...)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitCommands splits s at semicolons and newlines, dropping empty commands.
+func splitCommands(s string) []string {
+	var cmds []string
+	for _, cmd := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' }) {
+		if cmd = strings.TrimSpace(cmd); cmd != "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// tagNames returns tags as strings, for JSON output.
+func tagNames(tags []ot.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.String()
+	}
+	return names
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otlayout"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+)
+
+// --- Glyph inspection ------------------------------------------------------
+
+// Commands for inspecting glyphs work without navigating the font's tables:
+//
+//	cmap:A  cmap:U+00E9       glyph ID for a character
+//	glyph:36                  metrics of a glyph
+//	coverage:3  coverage:3:1  glyphs covered by subtable 0 (or 1) of lookup 3 of the current table
+//	apply:liga:fi  apply:smcp@latn:36,37
+//	                          apply a GSUB feature to the glyphs of a text or a list of glyph IDs
+//
+// Features for apply are looked up for script DFLT, unless a script is appended
+// to the feature tag.
+
+// glyphInfo is the JSON representation of a glyph's metrics, in font units.
+type glyphInfo struct {
+	Glyph      ot.GlyphIndex `json:"glyph"`
+	CodePoint  string        `json:"codepoint,omitempty"`
+	Advance    int           `json:"advance"`
+	LSB        int           `json:"lsb"`
+	RSB        int           `json:"rsb"`
+	BBox       [4]int        `json:"bbox"` // xmin, ymin, xmax, ymax
+	UnitsPerEm int           `json:"unitsPerEm"`
+}
+
+func (intp *Intp) cmap(c Op) error {
+	r, err := parseRune(c.arg)
+	if err != nil {
+		return err
+	}
+	gid := otquery.GlyphIndex(intp.font, r)
+	intp.report(c, fmt.Sprintf("%U %q maps to glyph %d", r, r, gid), gid)
+	return nil
+}
+
+func (intp *Intp) glyph(c Op) error {
+	gid, err := parseGlyph(c.arg)
+	if err != nil {
+		return err
+	}
+	if n := intp.numGlyphs(); n > 0 && int(gid) >= n {
+		return fmt.Errorf("glyph %d out of range, font has %d glyphs", gid, n)
+	}
+	m := otquery.GlyphMetrics(intp.font, gid, 0)
+	info := glyphInfo{
+		Glyph:      gid,
+		Advance:    int(m.Advance),
+		LSB:        int(m.LSB),
+		RSB:        int(m.RSB),
+		BBox:       [4]int{int(m.BBox.MinX), int(m.BBox.MinY), int(m.BBox.MaxX), int(m.BBox.MaxY)},
+		UnitsPerEm: int(m.UnitsPerEm),
+	}
+	if r := otquery.CodePointForGlyph(intp.font, gid); r != 0 {
+		info.CodePoint = fmt.Sprintf("%U", r)
+	}
+	intp.report(c, fmt.Sprintf("glyph %d %s: advance = %d, lsb = %d, rsb = %d, bbox = %v (%d units per em)",
+		gid, info.CodePoint, info.Advance, info.LSB, info.RSB, info.BBox, info.UnitsPerEm), info)
+	return nil
+}
+
+// coverage lists the glyphs covered by a lookup subtable. Coverage tables cannot
+// be enumerated, therefore every glyph of the font is matched against it.
+func (intp *Intp) coverage(c Op) error {
+	lyt, err := intp.layoutTable()
+	if err != nil {
+		return err
+	}
+	inx, err := strconv.Atoi(c.arg)
+	if err != nil {
+		return fmt.Errorf("lookup index not numeric: %v", c.arg)
+	}
+	sub := 0
+	if c.format != "" {
+		if sub, err = strconv.Atoi(c.format); err != nil {
+			return fmt.Errorf("subtable index not numeric: %v", c.format)
+		}
+	}
+	lookup := lyt.LookupList.Navigate(inx)
+	if lookup.Type == 0 {
+		return fmt.Errorf("no lookup at index %d", inx)
+	}
+	subtable := lookup.Subtable(sub)
+	if subtable == nil || subtable.Coverage.GlyphRange == nil {
+		return fmt.Errorf("lookup %d has no coverage table for subtable %d", inx, sub)
+	}
+	n := intp.numGlyphs()
+	glyphs := []ot.GlyphIndex{}
+	for gid := 0; gid < n; gid++ {
+		if _, ok := subtable.Coverage.GlyphRange.Match(ot.GlyphIndex(gid)); ok {
+			glyphs = append(glyphs, ot.GlyphIndex(gid))
+		}
+	}
+	intp.report(c, fmt.Sprintf("lookup %d/%d covers %d glyphs: %v", inx, sub, len(glyphs), glyphs), glyphs)
+	return nil
+}
+
+// apply applies a GSUB feature to a sequence of glyphs, as a shaper would do.
+func (intp *Intp) apply(c Op) error {
+	tag, script := c.arg, ""
+	if i := strings.IndexByte(tag, '@'); i >= 0 {
+		tag, script = tag[:i], tag[i+1:]
+	}
+	if len(tag) != 4 {
+		return fmt.Errorf("illegal feature tag %q", tag)
+	}
+	var scr ot.Tag
+	if script != "" {
+		scr = ot.T(script)
+	}
+	gsub, _, err := otlayout.FontFeatures(intp.font, scr, 0)
+	if err != nil {
+		return err
+	}
+	var feat otlayout.Feature
+	for _, f := range gsub {
+		if f != nil && f.Tag() == ot.T(tag) {
+			feat = f
+			break
+		}
+	}
+	if feat == nil {
+		return fmt.Errorf("font has no GSUB feature %s for script %s", tag, scr)
+	}
+	in, err := intp.glyphSequence(c.format)
+	if err != nil {
+		return err
+	}
+	buf := append([]ot.GlyphIndex{}, in...)
+	for pos := 0; pos < len(buf); {
+		next, ok, b := otlayout.ApplyFeature(intp.font, feat, buf, pos, 0)
+		buf = b
+		if !ok || next <= pos {
+			next = pos + 1
+		}
+		pos = next
+	}
+	intp.report(c, fmt.Sprintf("%s: %v => %v", tag, in, buf), map[string][]ot.GlyphIndex{"in": in, "out": buf})
+	return nil
+}
+
+// layoutTable returns the current table as a layout table, i.e. GSUB or GPOS.
+func (intp *Intp) layoutTable() (*ot.LayoutTable, error) {
+	if err := intp.checkTable(); err != nil {
+		return nil, err
+	}
+	if gsub := intp.table.Self().AsGSub(); gsub != nil {
+		return &gsub.LayoutTable, nil
+	}
+	if gpos := intp.table.Self().AsGPos(); gpos != nil {
+		return &gpos.LayoutTable, nil
+	}
+	return nil, errors.New("current table is neither GSUB nor GPOS")
+}
+
+func (intp *Intp) numGlyphs() int {
+	if maxp := intp.font.Table(ot.T("maxp")); maxp != nil {
+		return maxp.Self().AsMaxP().NumGlyphs
+	}
+	return 0
+}
+
+// glyphSequence returns the glyphs for s, which is either a comma-separated list
+// of glyph IDs or a text, whose characters are mapped to glyphs by the font's cmap.
+func (intp *Intp) glyphSequence(s string) ([]ot.GlyphIndex, error) {
+	if s == "" {
+		return nil, errors.New("no glyphs to apply feature to")
+	}
+	var glyphs []ot.GlyphIndex
+	for _, g := range strings.Split(s, ",") {
+		gid, err := parseGlyph(g)
+		if err != nil { // not a list of glyph IDs, but a text
+			glyphs = glyphs[:0]
+			for _, r := range s {
+				glyphs = append(glyphs, otquery.GlyphIndex(intp.font, r))
+			}
+			return glyphs, nil
+		}
+		glyphs = append(glyphs, gid)
+	}
+	return glyphs, nil
+}
+
+// parseRune parses a single character, or a code point in notation U+XXXX.
+func parseRune(s string) (rune, error) {
+	if strings.HasPrefix(strings.ToUpper(s), "U+") {
+		n, err := strconv.ParseUint(s[2:], 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("illegal code point %q", s)
+		}
+		return rune(n), nil
+	}
+	if r, n := utf8.DecodeRuneInString(s); n > 0 && n == len(s) && r != utf8.RuneError {
+		return r, nil
+	}
+	return 0, fmt.Errorf("expected a single character or U+XXXX, have %q", s)
+}
+
+func parseGlyph(s string) (ot.GlyphIndex, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("illegal glyph ID %q", s)
+	}
+	return ot.GlyphIndex(n), nil
+}
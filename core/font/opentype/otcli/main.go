@@ -42,10 +42,31 @@ func main() {
 	// command line flags
 	tlevel := flag.String("trace", "Info", "Trace level [Debug|Info|Error]")
 	fontname := flag.String("font", "", "Font to load")
+	cmds := flag.String("e", "", "Commands to execute non-interactively, separated by ';'")
+	script := flag.String("script", "", "File with commands to execute non-interactively")
+	asJSON := flag.Bool("json", false, "Print results as JSON objects, one per line")
 	flag.Parse()
-	tracer().SetTraceLevel(tracing.LevelError)    // will set the correct level later
-	pterm.Info.Println("Welcome to OpenType CLI") // colored welcome message
+	tracer().SetTraceLevel(tracing.LevelError) // will set the correct level later
+	batch := *cmds != "" || *script != ""
+	if !batch {
+		pterm.Info.Println("Welcome to OpenType CLI") // colored welcome message
+	}
 	tracer().Infof("Trace level is %s", *tlevel)
+	intp := &Intp{stack: make([]pathNode, 0, 100), json: *asJSON}
+	//
+	// load font to use
+	if err := intp.loadFont(*fontname); err != nil { // font name provided by flag
+		tracer().Errorf(err.Error())
+		os.Exit(4)
+	}
+	//
+	// execute commands given by flags, if any
+	if batch {
+		if err := intp.runBatch(*cmds, *script); err != nil {
+			os.Exit(5)
+		}
+		return
+	}
 	//
 	// set up REPL
 	repl, err := readline.New("ot > ")
@@ -53,13 +74,7 @@ func main() {
 		tracer().Errorf(err.Error())
 		os.Exit(3)
 	}
-	intp := &Intp{repl: repl, stack: make([]pathNode, 0, 100)}
-	//
-	// load font to use
-	if err := intp.loadFont(*fontname); err != nil { // font name provided by flag
-		tracer().Errorf(err.Error())
-		os.Exit(4)
-	}
+	intp.repl = repl
 	//
 	// start receiving commands
 	pterm.Info.Println("Quit with <ctrl>D") // inform user how to stop the CLI
@@ -89,9 +104,10 @@ type pathNode struct {
 // Intp is our interpreter object
 type Intp struct {
 	font  *ot.Font
-	repl  *readline.Instance
+	repl  *readline.Instance // nil in non-interactive mode
 	table ot.Table
 	stack []pathNode
+	json  bool // print results as JSON
 }
 
 // REPL starts interactive mode.
@@ -107,13 +123,12 @@ func (intp *Intp) REPL() {
 		println(line)
 		cmd, err := intp.parseCommand(line)
 		if err != nil {
-			tracer().Errorf(err.Error())
+			intp.fail(Op{code: HELP, arg: line}, err)
 			continue
 		}
 		err, quit := intp.execute(cmd)
 		if err != nil {
-			tracer().Errorf(err.Error())
-			continue
+			continue // already reported
 		}
 		if quit {
 			break
@@ -143,11 +158,18 @@ const (
 	MAP
 	SCRIPTS
 	FEATURES
+	CMAP
+	GLYPH
+	COVERAGE
+	APPLY
 )
 
 func (intp *Intp) parseCommand(line string) (*Command, error) {
 	command := &Command{}
-	steps := strings.Split(line, " ")
+	steps := strings.Fields(line)
+	if len(steps) > len(command.op) {
+		return nil, fmt.Errorf("too many steps in command, maximum is %d", len(command.op))
+	}
 	command.count = len(steps)
 	for i, step := range steps {
 		command.op[i].arg = ""
@@ -177,6 +199,14 @@ func (intp *Intp) parseCommand(line string) (*Command, error) {
 			case "featurelist", "features":
 				command.op[i].code = FEATURES
 				tracer().Infof("feature-list")
+			case "cmap":
+				command.op[i].code = CMAP
+			case "glyph":
+				command.op[i].code = GLYPH
+			case "coverage":
+				command.op[i].code = COVERAGE
+			case "apply":
+				command.op[i].code = APPLY
 			default:
 				command.op[i].code = HELP
 			}
@@ -186,7 +216,7 @@ func (intp *Intp) parseCommand(line string) (*Command, error) {
 }
 
 func (intp *Intp) execute(cmd *Command) (error, bool) {
-	tracer().Infof("cmd = %v", cmd.op)
+	tracer().Infof("cmd = %v", cmd.op[:cmd.count])
 	if cmd.op[0].code == HELP {
 		help(cmd.op[0].arg)
 		return nil, false
@@ -194,120 +224,147 @@ func (intp *Intp) execute(cmd *Command) (error, bool) {
 	if cmd.op[0].code == QUIT {
 		return nil, true
 	}
-	for _, c := range cmd.op {
-		switch c.code {
-		case NAVIGATE:
-			if intp.table == nil {
-				pterm.Error.Println("cannot walk without table being set")
-			} else if intp.table == intp.lastPathNode().table {
-				tracer().Infof("ignoring '->'")
-			} else if intp.lastPathNode().link == nil {
-				pterm.Error.Println("no link to walk")
-			} else {
-				l := intp.lastPathNode().link
-				n := pathNode{location: l.Navigate()}
-				intp.stack = append(intp.stack, n)
-				tracer().Infof("walked to %s", n.location.Name())
-			}
-		case TABLE:
+	for _, c := range cmd.op[:cmd.count] {
+		if err := intp.executeOp(c); err != nil {
+			intp.fail(c, err)
+			return err, false
+		}
+	}
+	return nil, false
+}
+
+func (intp *Intp) executeOp(c Op) error {
+	switch c.code {
+	case NAVIGATE:
+		if intp.table == nil {
+			return errors.New("cannot walk without table being set")
+		} else if intp.table == intp.lastPathNode().table {
+			tracer().Infof("ignoring '->'")
+		} else if intp.lastPathNode().link == nil {
+			return errors.New("no link to walk")
+		} else {
+			l := intp.lastPathNode().link
+			n := pathNode{location: l.Navigate()}
+			intp.stack = append(intp.stack, n)
+			tracer().Infof("walked to %s", n.location.Name())
+			intp.report(c, "", n.location.Name())
+		}
+	case TABLE:
+		tag := c.arg
+		intp.table = intp.font.Table(ot.T(tag))
+		if intp.table == nil {
+			return fmt.Errorf("font has no table %q", tag)
+		}
+		intp.stack = intp.stack[:0]
+		intp.stack = append(intp.stack, pathNode{table: intp.table})
+		tracer().Infof("setting table: %v", tag)
+		intp.report(c, "", tag)
+	case MAP:
+		if err := intp.checkTable(); err != nil {
+			return err
+		}
+		var target ot.NavLink
+		m := intp.lastPathNode().location.Map()
+		if c.arg != "" {
 			tag := c.arg
-			intp.table = intp.font.Table(ot.T(tag))
-			intp.stack = intp.stack[:0]
-			intp.stack = append(intp.stack, pathNode{table: intp.table})
-			tracer().Infof("setting table: %v", tag)
-		case MAP:
-			if intp.table == nil {
-				pterm.Error.Println("cannot map without table being set")
-			}
-			var target ot.NavLink
-			m := intp.lastPathNode().location.Map()
-			if c.arg != "" {
-				tag := c.arg
-				if m.IsTagRecordMap() {
-					trm := m.AsTagRecordMap()
-					target = trm.LookupTag(ot.T(tag))
-					tracer().Infof("%s map keys = %v", trm.Name(), trm.Tags())
-					pterm.Printfln("%s table maps [tag %v] = %v", trm.Name(), ot.T(tag), target.Name())
-				} else {
-					target = m.LookupTag(ot.T(tag))
-					pterm.Printfln("%s table maps [%v] = %v", m.Name(), ot.T(tag), target.Name())
-				}
-			} else if m.IsTagRecordMap() {
+			if m.IsTagRecordMap() {
 				trm := m.AsTagRecordMap()
-				pterm.Printfln("%s map keys = %v", trm.Name(), trm.Tags())
-			}
-			n := intp.lastPathNode()
-			n.link = target
-			intp.setLastPathNode(n)
-		case LIST:
-			if intp.table == nil {
-				pterm.Error.Println("cannot list without table being set")
-			}
-			l := intp.lastPathNode().location.List()
-			if c.arg == "" {
-				pterm.Printfln("List has %d entries", l.Len())
-			} else if i, err := strconv.Atoi(c.arg); err == nil {
-				loc := l.Get(i)
-				size := loc.Size()
-				value := decodeLocation(loc, l.Name())
-				switch value.(type) {
-				case int:
-					pterm.Printfln("%s list index %d holds number = %d", l.Name(), i, value)
-				default:
-					pterm.Printfln("%s list index %d holds data of %d bytes", l.Name(), i, size)
-				}
+				target = trm.LookupTag(ot.T(tag))
+				tracer().Infof("%s map keys = %v", trm.Name(), trm.Tags())
+				intp.report(c, fmt.Sprintf("%s table maps [tag %v] = %v", trm.Name(), ot.T(tag), target.Name()),
+					target.Name())
 			} else {
-				pterm.Error.Printfln("List index not numeric: %v", c.arg)
-			}
-		case SCRIPTS:
-			if err := intp.checkTable(); err != nil {
-				return err, false
+				target = m.LookupTag(ot.T(tag))
+				intp.report(c, fmt.Sprintf("%s table maps [%v] = %v", m.Name(), ot.T(tag), target.Name()),
+					target.Name())
 			}
-			s := intp.table.Self().AsGSub().ScriptList
-			if s == nil {
-				s = intp.table.Self().AsGPos().ScriptList
-			}
-			if s == nil {
-				return errors.New("table has no script list"), false
-			}
-			m := s.Map().AsTagRecordMap()
-			pterm.Printfln("ScriptList keys: %v", m.Tags())
-			n := pathNode{location: s}
-			if c.arg != "" {
-				l := m.LookupTag(ot.T(c.arg))
-				if l.IsNull() {
-					tracer().Infof("script lookup [%s] returns null", ot.T(c.arg).String())
-					break
-				}
-				n.link = l
+		} else if m.IsTagRecordMap() {
+			trm := m.AsTagRecordMap()
+			intp.report(c, fmt.Sprintf("%s map keys = %v", trm.Name(), trm.Tags()), tagNames(trm.Tags()))
+		}
+		n := intp.lastPathNode()
+		n.link = target
+		intp.setLastPathNode(n)
+	case LIST:
+		if err := intp.checkTable(); err != nil {
+			return err
+		}
+		l := intp.lastPathNode().location.List()
+		if c.arg == "" {
+			intp.report(c, fmt.Sprintf("List has %d entries", l.Len()), l.Len())
+		} else if i, err := strconv.Atoi(c.arg); err == nil {
+			loc := l.Get(i)
+			size := loc.Size()
+			value := decodeLocation(loc, l.Name())
+			switch value.(type) {
+			case int:
+				intp.report(c, fmt.Sprintf("%s list index %d holds number = %d", l.Name(), i, value), value)
+			default:
+				intp.report(c, fmt.Sprintf("%s list index %d holds data of %d bytes", l.Name(), i, size),
+					map[string]int{"size": size})
 			}
-			intp.stack = append(intp.stack, n)
-		case FEATURES:
-			f := intp.table.Self().AsGSub().FeatureList
-			if c.arg == "" {
-				tracer().Infof("%s table has %d entries", f.Name(), f.Len())
-			} else if i, err := strconv.Atoi(c.arg); err == nil {
-				tag, _ := f.Get(i)
-				//tag, lnk := f.Get(i)
-				pterm.Printfln("%s list index %d holds feature record = %v", f.Name(), i, tag)
-			} else {
-				pterm.Error.Printfln("List index not numeric: %v", c.arg)
+		} else {
+			return fmt.Errorf("list index not numeric: %v", c.arg)
+		}
+	case SCRIPTS:
+		if err := intp.checkTable(); err != nil {
+			return err
+		}
+		s := intp.table.Self().AsGSub().ScriptList
+		if s == nil {
+			s = intp.table.Self().AsGPos().ScriptList
+		}
+		if s == nil {
+			return errors.New("table has no script list")
+		}
+		m := s.Map().AsTagRecordMap()
+		intp.report(c, fmt.Sprintf("ScriptList keys: %v", m.Tags()), tagNames(m.Tags()))
+		n := pathNode{location: s}
+		if c.arg != "" {
+			l := m.LookupTag(ot.T(c.arg))
+			if l.IsNull() {
+				tracer().Infof("script lookup [%s] returns null", ot.T(c.arg).String())
+				break
 			}
+			n.link = l
+		}
+		intp.stack = append(intp.stack, n)
+	case FEATURES:
+		if err := intp.checkTable(); err != nil {
+			return err
+		}
+		f := intp.table.Self().AsGSub().FeatureList
+		if c.arg == "" {
+			intp.report(c, fmt.Sprintf("%s table has %d entries", f.Name(), f.Len()), f.Len())
+		} else if i, err := strconv.Atoi(c.arg); err == nil {
+			tag, _ := f.Get(i)
+			//tag, lnk := f.Get(i)
+			intp.report(c, fmt.Sprintf("%s list index %d holds feature record = %v", f.Name(), i, tag), tag.String())
+		} else {
+			return fmt.Errorf("list index not numeric: %v", c.arg)
 		}
+	case CMAP:
+		return intp.cmap(c)
+	case GLYPH:
+		return intp.glyph(c)
+	case COVERAGE:
+		return intp.coverage(c)
+	case APPLY:
+		return intp.apply(c)
 	}
-	return nil, false
+	return nil
 }
 
 func (intp *Intp) checkTable() error {
 	if intp.table == nil {
-		return errors.New("not table set")
+		return errors.New("no table set")
 	}
 	return nil
 }
 
 func (intp *Intp) loadFont(fontname string) (err error) {
 	intp.font, err = loadLocalFont(fontname)
-	if err == nil {
+	if err == nil && !intp.json {
 		pterm.Printfln("font tables: %v", intp.font.TableTags())
 	}
 	return
@@ -406,6 +463,16 @@ func help(topic string) {
 	+-----------------------------------+
 	LangSys behaves as a list.
 	`)
+	case "glyph", "glyphs", "cmap", "coverage", "apply":
+		pterm.Info.Println("Glyphs")
+		pterm.Println(`
+	cmap:A  cmap:U+00E9       glyph ID for a character
+	glyph:36                  metrics of glyph 36, in font units
+	coverage:3  coverage:3:1  glyphs covered by subtable 0 (or 1) of lookup 3
+	                          of the current table (GSUB or GPOS)
+	apply:liga:fi             apply GSUB feature liga to the glyphs for "fi"
+	apply:smcp@latn:36,37     apply feature smcp for script latn to glyphs 36 and 37
+	`)
 	default:
 		pterm.Info.Println("General Help, TODO")
 	}
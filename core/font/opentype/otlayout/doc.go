@@ -15,17 +15,21 @@ Copyright © Norbert Pillmayer <norbert@pillmayer.com>
 package otlayout
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core"
 )
 
-// trace traces to a global core-tracer.
+// trace traces with key 'tyse.fonts'.
 func trace() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.fonts")
 }
 
 // errFontFormat produces user level errors for font parsing.
 func errFontFormat(x string) error {
-	return core.Error(core.EINVALID, "OpenType font format: %s", x)
+	return core.ErrorIn(core.ErrFontFormat, core.EINVALID, "OpenType font format: %s", x)
+}
+
+// errTableMissing produces user level errors for fonts lacking a table.
+func errTableMissing(x string) error {
+	return core.ErrorIn(core.ErrTableMissing, core.EMISSING, "OpenType font: %s", x)
 }
@@ -159,23 +159,30 @@ func (f feature) LookupIndex(i int) int {
 // extracting the feature will have required the layout table in the first place. Presence of the
 // layout table is not checked again.
 func ApplyFeature(otf *ot.Font, feat Feature, buf []ot.GlyphIndex, pos, alt int) (int, bool, []ot.GlyphIndex) {
+	return applyFeature(otf, feat, buf, pos, alt, nil)
+}
+
+// applyFeature applies a feature as described for ApplyFeature. If observe is
+// non-nil, it is called for every lookup subtable tried (see TraceFeature).
+func applyFeature(otf *ot.Font, feat Feature, buf []ot.GlyphIndex, pos, alt int, observe func(Step)) (
+	int, bool, []ot.GlyphIndex) {
+	//
 	if feat == nil { // this is legal for unused mandatory feature slots
 		return pos, false, buf
 	} else if buf == nil || pos < 0 || pos >= len(buf) {
 		trace().Infof("application of font-feature requested for unusable buffer condition")
 		return pos, false, buf
 	}
-	var lytTable *ot.LayoutTable
-	if feat.Type() == GSubFeatureType {
-		lytTable = &otf.Table(ot.T("GSUB")).Self().AsGSub().LayoutTable
-	} else {
-		lytTable = &otf.Table(ot.T("GPOS")).Self().AsGPos().LayoutTable
+	lytTable := layoutTableFor(otf, feat)
+	if lytTable == nil {
+		trace().Infof("font has no layout table for feature %s", feat.Tag())
+		return pos, false, buf
 	}
 	var applied, ok bool
 	for i := 0; i < feat.LookupCount(); i++ { // lookups have to be applied in sequence
 		inx := feat.LookupIndex(i)
 		lookup := lytTable.LookupList.Navigate(inx)
-		pos, ok, buf = applyLookup(&lookup, feat, buf, pos, alt)
+		pos, ok, buf = applyLookup(&lookup, inx, feat, buf, pos, alt, observe)
 		applied = applied || ok
 	}
 	return pos, applied, buf
@@ -183,9 +190,10 @@ func ApplyFeature(otf *ot.Font, feat Feature, buf []ot.GlyphIndex, pos, alt int)
 
 // To apply a lookup, we have to iterate over the lookup's subtables and call them
 // appropriately, respecting different subtable semantics and formats.
-// Therefore this function more or less is a large switch to delegate to functions
-// implementing a specific subtable logic.
-func applyLookup(lookup *ot.Lookup, feat Feature, buf []ot.GlyphIndex, pos, alt int) (int, bool, []ot.GlyphIndex) {
+// inx is the index of the lookup in the LookupList, for observe.
+func applyLookup(lookup *ot.Lookup, inx int, feat Feature, buf []ot.GlyphIndex, pos, alt int,
+	observe func(Step)) (int, bool, []ot.GlyphIndex) {
+	//
 	trace().Debugf("applying lookup '%s'/%d", feat.Tag(), lookup.Type)
 	for i := 0; i < int(lookup.SubTableCount) && pos < len(buf); i++ {
 		trace().Debugf("-------------------- pos = %d", pos)
@@ -195,44 +203,74 @@ func applyLookup(lookup *ot.Lookup, feat Feature, buf []ot.GlyphIndex, pos, alt
 		if sub == nil {
 			continue
 		}
-		switch sub.LookupType {
-		case 1: // Single Substitution Subtable
-			switch sub.Format {
-			case 1:
-				return gsubLookupType1Fmt1(lookup, sub, buf, pos)
-			case 2:
-				return gsubLookupType1Fmt2(lookup, sub, buf, pos)
-			}
-		case 2: // Multiple Substitution Subtable
-			return gsubLookupType2Fmt1(lookup, sub, buf, pos)
-		case 3: // Alternate Substitution Subtable
-			return gsubLookupType3Fmt1(lookup, sub, buf, pos, alt)
-		case 4: // Ligature Substitution Subtable
-			return gsubLookupType4Fmt1(lookup, sub, buf, pos)
-		case 5:
-			switch sub.Format {
-			case 1:
-				return gsubLookupType5Fmt1(lookup, sub, buf, pos)
-			case 2:
-				return gsubLookupType5Fmt2(lookup, sub, buf, pos)
-			case 3:
-				return gsubLookupType5Fmt3(lookup, sub, buf, pos)
-			}
-		case 6:
-			switch sub.Format {
-			case 1:
-				return gsubLookupType6Fmt1(lookup, sub, buf, pos)
-			case 2:
-				return gsubLookupType6Fmt2(lookup, sub, buf, pos)
-			case 3:
-				return gsubLookupType6Fmt3(lookup, sub, buf, pos)
-			}
+		var step Step
+		if observe != nil {
+			step = startStep(feat, inx, i, sub, buf, pos)
+		}
+		next, ok, out, known := applySubtable(lookup, sub, buf, pos, alt)
+		if observe != nil {
+			observe(step.finish(next, ok, out))
+		}
+		if known {
+			return next, ok, out
 		}
 		trace().Errorf("unknown GSUB lookup type %d/%d", sub.LookupType, sub.Format)
 	}
 	return pos, false, buf
 }
 
+// applySubtable more or less is a large switch to delegate to functions
+// implementing a specific subtable logic. It returns false as its last result for
+// subtables of unknown type or format.
+func applySubtable(lookup *ot.Lookup, sub *ot.LookupSubtable, buf []ot.GlyphIndex, pos, alt int) (
+	int, bool, []ot.GlyphIndex, bool) {
+	//
+	var next int
+	var ok bool
+	switch sub.LookupType {
+	case 1: // Single Substitution Subtable
+		switch sub.Format {
+		case 1:
+			next, ok, buf = gsubLookupType1Fmt1(lookup, sub, buf, pos)
+		case 2:
+			next, ok, buf = gsubLookupType1Fmt2(lookup, sub, buf, pos)
+		default:
+			return pos, false, buf, false
+		}
+	case 2: // Multiple Substitution Subtable
+		next, ok, buf = gsubLookupType2Fmt1(lookup, sub, buf, pos)
+	case 3: // Alternate Substitution Subtable
+		next, ok, buf = gsubLookupType3Fmt1(lookup, sub, buf, pos, alt)
+	case 4: // Ligature Substitution Subtable
+		next, ok, buf = gsubLookupType4Fmt1(lookup, sub, buf, pos)
+	case 5:
+		switch sub.Format {
+		case 1:
+			next, ok, buf = gsubLookupType5Fmt1(lookup, sub, buf, pos)
+		case 2:
+			next, ok, buf = gsubLookupType5Fmt2(lookup, sub, buf, pos)
+		case 3:
+			next, ok, buf = gsubLookupType5Fmt3(lookup, sub, buf, pos)
+		default:
+			return pos, false, buf, false
+		}
+	case 6:
+		switch sub.Format {
+		case 1:
+			next, ok, buf = gsubLookupType6Fmt1(lookup, sub, buf, pos)
+		case 2:
+			next, ok, buf = gsubLookupType6Fmt2(lookup, sub, buf, pos)
+		case 3:
+			next, ok, buf = gsubLookupType6Fmt3(lookup, sub, buf, pos)
+		default:
+			return pos, false, buf, false
+		}
+	default:
+		return pos, false, buf, false
+	}
+	return next, ok, buf, true
+}
+
 // GSUB LookupType 1: Single Substitution Subtable
 //
 // Single substitution (SingleSubst) subtables tell a client to replace a single glyph
@@ -644,16 +682,29 @@ func lookupGlyphs(index ot.VarArray, ginx int, deep bool) []ot.GlyphIndex {
 	return outglyphs.Glyphs()
 }
 
+// layoutTableFor returns the GSUB or GPOS table of a font, depending on the type
+// of a feature, or nil.
+func layoutTableFor(otf *ot.Font, feat Feature) *ot.LayoutTable {
+	if feat.Type() == GSubFeatureType {
+		if gsub := otf.GSub(); gsub != nil {
+			return &gsub.LayoutTable
+		}
+	} else if gpos := otf.GPos(); gpos != nil {
+		return &gpos.LayoutTable
+	}
+	return nil
+}
+
 // get GSUB and GPOS from a font safely
 func getLayoutTables(otf *ot.Font) ([]*ot.LayoutTable, error) {
 	var table ot.Table
 	var lytt = make([]*ot.LayoutTable, 2)
 	if table = otf.Table(ot.T("GSUB")); table == nil {
-		return nil, errFontFormat(fmt.Sprintf("font %s has no GSUB table", otf.F.Fontname))
+		return nil, errTableMissing(fmt.Sprintf("font %s has no GSUB table", otf.F.Fontname))
 	}
 	lytt[0] = &table.Self().AsGSub().LayoutTable
 	if table = otf.Table(ot.T("GPOS")); table == nil {
-		return nil, errFontFormat(fmt.Sprintf("font %s has no GPOS table", otf.F.Fontname))
+		return nil, errTableMissing(fmt.Sprintf("font %s has no GPOS table", otf.F.Fontname))
 	}
 	lytt[1] = &table.Self().AsGPos().LayoutTable
 	return lytt, nil
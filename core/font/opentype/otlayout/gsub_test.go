@@ -103,6 +103,38 @@ func TestFeatureCase(t *testing.T) {
 	}
 }
 
+func TestTraceFeatureCase(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	otf := parseFont(t, "calibri")
+	t.Logf("Using font %s for test", otf.F.Fontname)
+	gsubFeats, _, err := FontFeatures(otf, ot.T("latn"), 0)
+	if err != nil || len(gsubFeats) < 2 {
+		t.Fatalf("GSUB feature 'case' not found in font Calibri")
+	}
+	featcase := gsubFeats[1]
+	plan := Plan(otf, featcase)
+	if len(plan) == 0 || plan[0].Lookup != 9 || len(plan[0].Subtables) == 0 {
+		t.Errorf("expected plan for 'case' to start with lookup 9, is %v", plan)
+	}
+	in := prepareGlyphBuffer("a@", otf, t)
+	steps, buf := TraceFeatures(otf, []Feature{featcase}, in, 0)
+	var applied []Step
+	for _, step := range steps {
+		t.Logf("step: %s", step)
+		if step.Applied {
+			applied = append(applied, step)
+		}
+	}
+	if len(applied) != 1 || applied[0].Pos != 1 || applied[0].Output()[0] != 925 {
+		t.Errorf("expected 'case' to apply once, replacing '@' with glyph 925, have %v", applied)
+	}
+	if buf[1] != 925 || in[1] == 925 {
+		t.Errorf("expected a copy of the buffer to be modified, have %v => %v", in, buf)
+	}
+}
+
 /*
 Calibri:
 	<ScriptTag value="latn"/>
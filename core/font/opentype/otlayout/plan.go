@@ -0,0 +1,158 @@
+package otlayout
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+// --- Lookup plans ----------------------------------------------------------
+
+// PlannedLookup describes a lookup which a feature links to, together with its
+// subtables. Lookups are applied in the order they are planned.
+type PlannedLookup struct {
+	Feature   ot.Tag                   // the feature linking to the lookup
+	Lookup    int                      // index of the lookup in the LookupList
+	Type      ot.LayoutTableLookupType // lookup type, may be Extension
+	Flag      ot.LayoutTableLookupFlag // lookup flags
+	Subtables []PlannedSubtable        // subtables of the lookup, in order
+}
+
+// PlannedSubtable describes a subtable of a lookup.
+type PlannedSubtable struct {
+	Type   ot.LayoutTableLookupType // resolved for Extension lookups
+	Format uint16
+}
+
+// Plan lists the lookups a sequence of features will apply to a glyph buffer, in
+// order. Features which are nil, as well as features for a layout table missing
+// in font otf, are skipped.
+func Plan(otf *ot.Font, feats ...Feature) []PlannedLookup {
+	var plan []PlannedLookup
+	for _, feat := range feats {
+		if feat == nil {
+			continue
+		}
+		lytTable := layoutTableFor(otf, feat)
+		if lytTable == nil {
+			continue
+		}
+		for i := 0; i < feat.LookupCount(); i++ {
+			inx := feat.LookupIndex(i)
+			lookup := lytTable.LookupList.Navigate(inx)
+			p := PlannedLookup{Feature: feat.Tag(), Lookup: inx, Type: lookup.Type, Flag: lookup.Flag}
+			for j := 0; j < int(lookup.SubTableCount); j++ {
+				if sub := lookup.Subtable(j); sub != nil {
+					p.Subtables = append(p.Subtables, PlannedSubtable{Type: sub.LookupType, Format: sub.Format})
+				}
+			}
+			plan = append(plan, p)
+		}
+	}
+	return plan
+}
+
+// --- Tracing feature application -------------------------------------------
+
+// A Step records an attempt to apply a lookup subtable to a glyph buffer at a
+// position. Steps are meant for debugging fonts, i.e., for finding out why a
+// feature did or did not apply.
+type Step struct {
+	Feature  ot.Tag                   // the feature applied
+	Lookup   int                      // index of the lookup in the LookupList
+	Subtable int                      // index of the subtable within the lookup
+	Type     ot.LayoutTableLookupType // subtable type, resolved for Extension lookups
+	Format   uint16                   // subtable format
+	Pos      int                      // buffer position the subtable has been tried at
+	Covered  bool                     // has the glyph at Pos been found in the subtable's coverage?
+	Coverage int                      // coverage index of the glyph at Pos, if covered
+	Applied  bool                     // has the subtable substituted glyphs?
+	Next     int                      // buffer position after application
+	Before   []ot.GlyphIndex          // copy of the glyph buffer before application
+	After    []ot.GlyphIndex          // copy of the glyph buffer after application
+}
+
+func startStep(feat Feature, inx, i int, sub *ot.LookupSubtable, buf []ot.GlyphIndex, pos int) Step {
+	step := Step{
+		Feature:  feat.Tag(),
+		Lookup:   inx,
+		Subtable: i,
+		Type:     sub.LookupType,
+		Format:   sub.Format,
+		Pos:      pos,
+		Before:   append([]ot.GlyphIndex{}, buf...),
+	}
+	if sub.Coverage.GlyphRange != nil {
+		step.Coverage, step.Covered = sub.Coverage.GlyphRange.Match(buf[pos])
+	}
+	return step
+}
+
+func (step Step) finish(next int, applied bool, buf []ot.GlyphIndex) Step {
+	step.Next, step.Applied = next, applied
+	step.After = append([]ot.GlyphIndex{}, buf...)
+	return step
+}
+
+// Input returns the glyphs an applied step has replaced, starting at Pos.
+func (step Step) Input() []ot.GlyphIndex {
+	if !step.Applied {
+		return nil
+	}
+	n := step.Next - step.Pos + len(step.Before) - len(step.After)
+	if n < 0 || step.Pos+n > len(step.Before) {
+		return nil
+	}
+	return step.Before[step.Pos : step.Pos+n]
+}
+
+// Output returns the glyphs an applied step has substituted, starting at Pos.
+func (step Step) Output() []ot.GlyphIndex {
+	if !step.Applied || step.Next > len(step.After) {
+		return nil
+	}
+	return step.After[step.Pos:step.Next]
+}
+
+func (step Step) String() string {
+	s := fmt.Sprintf("'%s' lookup %d/%d (%d|%d) at %d: glyph %d", step.Feature, step.Lookup,
+		step.Subtable, step.Type, step.Format, step.Pos, step.Before[step.Pos])
+	if !step.Covered {
+		return s + " not covered"
+	}
+	s += fmt.Sprintf(" covered as #%d", step.Coverage)
+	if !step.Applied {
+		return s + ", not applied"
+	}
+	return s + fmt.Sprintf(", subst %v for %v", step.Output(), step.Input())
+}
+
+// TraceFeature applies a feature to buffer buf at position pos, just like
+// ApplyFeature does, and calls observe for every lookup subtable tried.
+func TraceFeature(otf *ot.Font, feat Feature, buf []ot.GlyphIndex, pos, alt int, observe func(Step)) (
+	int, bool, []ot.GlyphIndex) {
+	//
+	return applyFeature(otf, feat, buf, pos, alt, observe)
+}
+
+// TraceFeatures applies a sequence of features to every position of a glyph
+// buffer, as a shaper would do. It returns the steps taken, in order, and the
+// resulting glyphs. buf is not modified.
+func TraceFeatures(otf *ot.Font, feats []Feature, buf []ot.GlyphIndex, alt int) ([]Step, []ot.GlyphIndex) {
+	var steps []Step
+	observe := func(step Step) {
+		steps = append(steps, step)
+	}
+	buf = append([]ot.GlyphIndex{}, buf...)
+	for _, feat := range feats {
+		for pos := 0; pos < len(buf); {
+			next, ok, b := applyFeature(otf, feat, buf, pos, alt, observe)
+			buf = b
+			if !ok || next <= pos {
+				next = pos + 1
+			}
+			pos = next
+		}
+	}
+	return steps, buf
+}
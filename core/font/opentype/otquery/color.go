@@ -0,0 +1,149 @@
+package otquery
+
+import (
+	"image/color"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+// ColorFormat flags the kinds of color glyph information a font contains.
+type ColorFormat uint8
+
+// Color glyph formats supported by package otquery.
+const (
+	ColorLayers  ColorFormat = 1 << iota // COLR/CPAL layered glyphs
+	ColorSbix                            // sbix bitmap strikes
+	ColorBitmaps                         // CBDT/CBLC bitmap strikes
+)
+
+// ColorFormats returns the color glyph formats contained in a font, or 0 for
+// monochrome fonts.
+func ColorFormats(otf *ot.Font) ColorFormat {
+	var f ColorFormat
+	if otf.Table(ot.T("COLR")) != nil && otf.Table(ot.T("CPAL")) != nil {
+		f |= ColorLayers
+	}
+	if otf.Table(ot.T("sbix")) != nil {
+		f |= ColorSbix
+	}
+	if otf.Table(ot.T("CBLC")) != nil && otf.Table(ot.T("CBDT")) != nil {
+		f |= ColorBitmaps
+	}
+	return f
+}
+
+// ColoredLayer is a layer of a color glyph, ready to be painted by a renderer.
+// If Foreground is true, the layer has to be painted with the current text
+// color and Color should be ignored.
+type ColoredLayer struct {
+	Glyph      ot.GlyphIndex
+	Color      color.NRGBA
+	Foreground bool
+}
+
+// ColorGlyph returns the layers of a COLR color glyph, in back-to-front order, with
+// colors taken from CPAL palette number `palette`. Palette 0 is the default palette.
+//
+// If the font has no COLR/CPAL tables, or if gid is not a color glyph, nil
+// is returned. Renderers should then paint gid as a regular glyph.
+func ColorGlyph(otf *ot.Font, gid ot.GlyphIndex, palette int) []ColoredLayer {
+	colrTable, cpalTable := otf.Table(ot.T("COLR")), otf.Table(ot.T("CPAL"))
+	if colrTable == nil || cpalTable == nil {
+		return nil
+	}
+	colr, cpal := colrTable.Self().AsColr(), cpalTable.Self().AsCPal()
+	if colr == nil || cpal == nil {
+		return nil
+	}
+	if palette < 0 || palette >= cpal.NumPalettes {
+		tracer().Infof("font has no color palette #%d, using default palette", palette)
+		palette = 0
+	}
+	layers := colr.Layers(gid)
+	if len(layers) == 0 {
+		return nil
+	}
+	colored := make([]ColoredLayer, len(layers))
+	for i, l := range layers {
+		colored[i].Glyph = l.Glyph
+		if l.PaletteIndex == ot.ForegroundPaletteIndex {
+			colored[i].Foreground = true
+			continue
+		}
+		c, ok := cpal.Color(palette, int(l.PaletteIndex))
+		if !ok {
+			tracer().Errorf("color glyph %d references invalid palette entry %d", gid, l.PaletteIndex)
+			colored[i].Foreground = true
+			continue
+		}
+		colored[i].Color = c
+	}
+	return colored
+}
+
+// BitmapGlyph returns a color bitmap for a glyph, if the font contains one in
+// an sbix or CBDT table. Strikes are selected by size: the strike with the smallest
+// ppem-size not smaller than `ppem` is preferred. If no such strike exists, the
+// largest strike available will be selected (renderers will have to scale bitmaps
+// anyway).
+//
+// If no bitmap is available for gid, false is returned.
+func BitmapGlyph(otf *ot.Font, gid ot.GlyphIndex, ppem uint16) (ot.BitmapGlyph, bool) {
+	if t := otf.Table(ot.T("sbix")); t != nil {
+		if sbix := t.Self().AsSbix(); sbix != nil {
+			strikes := make([]ot.SbixStrike, sbix.StrikeCount())
+			sizes := make([]uint16, len(strikes))
+			for i := range strikes {
+				strikes[i] = sbix.Strike(i)
+				sizes[i] = strikes[i].PPEm
+			}
+			for _, i := range strikeOrder(sizes, ppem) {
+				if g, ok := strikes[i].Glyph(gid); ok {
+					return g, true
+				}
+			}
+		}
+	}
+	if t := otf.Table(ot.T("CBLC")); t != nil {
+		if cblc := t.Self().AsCBLC(); cblc != nil {
+			strikes := make([]ot.CBLCStrike, cblc.StrikeCount())
+			sizes := make([]uint16, len(strikes))
+			for i := range strikes {
+				strikes[i] = cblc.Strike(i)
+				sizes[i] = uint16(strikes[i].PPEmY)
+			}
+			for _, i := range strikeOrder(sizes, ppem) {
+				if g, ok := strikes[i].Glyph(gid); ok {
+					return g, true
+				}
+			}
+		}
+	}
+	return ot.BitmapGlyph{}, false
+}
+
+// strikeOrder returns the indices of strikes in order of preference for a
+// requested ppem-size: first all strikes at least as large as ppem, smallest
+// first, then all smaller strikes, largest first.
+func strikeOrder(sizes []uint16, ppem uint16) []int {
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	better := func(a, b uint16) bool {
+		if a >= ppem && b >= ppem {
+			return a < b
+		} else if a >= ppem {
+			return true
+		} else if b >= ppem {
+			return false
+		}
+		return a > b
+	}
+	for i := 1; i < len(order); i++ { // insertion sort; fonts have few strikes
+		for j := i; j > 0 && better(sizes[order[j]], sizes[order[j-1]]); j-- {
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+	return order
+}
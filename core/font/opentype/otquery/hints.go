@@ -0,0 +1,51 @@
+package otquery
+
+import "github.com/npillmayer/tyse/core/font/opentype/ot"
+
+// --- Rasterization hints ---------------------------------------------------
+
+// defaultRasterization is used for fonts which do not state their preferences.
+const defaultRasterization = ot.GaspGridfit | ot.GaspDoGray
+
+// Rasterization returns the rasterization behavior the font designer recommends for
+// a ppem-size, i.e. whether to use grid-fitting and anti-aliasing. It is taken from
+// table gasp. For fonts without a gasp table, or without a recommendation for ppem,
+// grid-fitting and grayscale rendering is returned.
+func Rasterization(otf *ot.Font, ppem uint16) ot.GaspBehavior {
+	if t := otf.Table(ot.T("gasp")); t != nil {
+		if gasp := t.Self().AsGasp(); gasp != nil {
+			if behavior, ok := gasp.Behavior(ppem); ok {
+				return behavior
+			}
+		}
+	}
+	return defaultRasterization
+}
+
+// DeviceAdvance returns the advance width of a hinted glyph at a ppem-size, in pixels,
+// as pre-computed in table hdmx. If the font does not contain device metrics for
+// ppem, false is returned and clients will have to scale the glyph's advance width
+// (see GlyphMetrics).
+func DeviceAdvance(otf *ot.Font, gid ot.GlyphIndex, ppem uint16) (uint16, bool) {
+	if t := otf.Table(ot.T("hdmx")); t != nil {
+		if hdmx := t.Self().AsHdmx(); hdmx != nil {
+			if rec, ok := hdmx.RecordFor(ppem); ok {
+				return rec.Advance(gid)
+			}
+		}
+	}
+	return 0, false
+}
+
+// DeviceYExtents returns the maximum and minimum y-extents of the hinted glyphs of a
+// font at a ppem-size, in pixels, as stated in table VDMX. xRes and yRes are the
+// resolution of the output device; they select the aspect ratio of pixels.
+// If the font does not contain vertical device metrics for ppem, false is returned.
+func DeviceYExtents(otf *ot.Font, ppem uint16, xRes, yRes int) (ot.VDMXBounds, bool) {
+	if t := otf.Table(ot.T("VDMX")); t != nil {
+		if vdmx := t.Self().AsVDMX(); vdmx != nil {
+			return vdmx.Bounds(ppem, xRes, yRes)
+		}
+	}
+	return ot.VDMXBounds{}, false
+}
@@ -0,0 +1,42 @@
+package otquery
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+func TestRasterizationHints(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	gentium := loadPackagedFont(t, "GentiumPlus-R.ttf")
+	for ppem, expected := range map[uint16]ot.GaspBehavior{
+		6:  ot.GaspDoGray,
+		12: ot.GaspGridfit,
+		16: ot.GaspGridfit,
+		17: ot.GaspDoGray,
+	} {
+		if behavior := Rasterization(gentium, ppem); behavior != expected {
+			t.Errorf("expected gasp behavior at %d ppem to be %d, is %d", ppem, expected, behavior)
+		}
+	}
+	g := GlyphIndex(gentium, 'g')
+	if adv, ok := DeviceAdvance(gentium, g, 16); !ok || adv != 8 {
+		t.Errorf("expected device advance of 'g' at 16 ppem to be 8 px, is %d", adv)
+	}
+	if _, ok := DeviceAdvance(gentium, g, 14); ok {
+		t.Errorf("expected no device advance of 'g' at 14 ppem")
+	}
+	if bounds, ok := DeviceYExtents(gentium, 12, 96, 96); !ok || bounds != (ot.VDMXBounds{YMax: 16, YMin: -6}) {
+		t.Errorf("expected y-extents at 12 ppem to be +16/-6, are %+v", bounds)
+	}
+	logo := loadPackagedFont(t, "TySELogo-Regular.otf") // no hinting tables
+	if Rasterization(logo, 12) != ot.GaspGridfit|ot.GaspDoGray {
+		t.Errorf("expected default rasterization for font without gasp table")
+	}
+	if _, ok := DeviceYExtents(logo, 12, 0, 0); ok {
+		t.Errorf("expected no y-extents for font without VDMX table")
+	}
+}
@@ -85,6 +85,48 @@ func LayoutTables(otf *ot.Font) []string {
 	return lt
 }
 
+// FontStyle reports whether a font is an italic (or oblique) face, and whether it
+// is a bold face. Flags are taken from table OS/2, with a weight class of 600 and
+// above counting as bold. For fonts without table OS/2, table head is consulted.
+func FontStyle(otf *ot.Font) (italic, bold bool) {
+	if t := otf.Table(ot.T("OS/2")); t != nil {
+		os2 := t.Binary()
+		fsSelection := u16at(os2, 62)
+		const fsItalic, fsBold, fsOblique = 1 << 0, 1 << 5, 1 << 9
+		italic = fsSelection&(fsItalic|fsOblique) != 0
+		bold = fsSelection&fsBold != 0 || u16at(os2, 4) >= 600 // usWeightClass
+		return
+	}
+	if t := otf.Table(ot.T("head")); t != nil {
+		macStyle := u16at(t.Binary(), 44)
+		italic, bold = macStyle&0x02 != 0, macStyle&0x01 != 0
+	}
+	return
+}
+
+// HasFeature reports whether a font contains an OpenType layout feature, in
+// table GSUB or in table GPOS, for any script.
+func HasFeature(otf *ot.Font, feature ot.Tag) bool {
+	var lists []ot.TagRecordMap
+	if gsub := otf.GSub(); gsub != nil {
+		lists = append(lists, gsub.FeatureList)
+	}
+	if gpos := otf.GPos(); gpos != nil {
+		lists = append(lists, gpos.FeatureList)
+	}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, tag := range list.Tags() {
+			if tag == feature {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GlyphClasses collects glyph class information for a glyph.
 //
 // From the OpenType spec:
@@ -3,7 +3,9 @@ package otquery
 import (
 	"github.com/npillmayer/tyse/core/font/opentype"
 	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	xfont "golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // --- Font Information -------------------------------------------------
@@ -13,7 +15,11 @@ import (
 // font, DFLT will be returned. If the script has no support in the font,
 // DFLT will be returned for the script.
 func FontSupportsScript(otf *ot.Font, scr ot.Tag, lang ot.Tag) (ot.Tag, ot.Tag) {
-	gsub := otf.Layout.GSub
+	gsub := otf.GSub()
+	if gsub == nil {
+		tracer().Infof("font has no GSUB table")
+		return ot.DFLT, ot.DFLT
+	}
 	rec := gsub.ScriptList.Map().LookupTag(scr)
 	if rec.IsNull() {
 		tracer().Infof("cannot find script %s in font", scr.String())
@@ -29,33 +35,108 @@ func FontSupportsScript(otf *ot.Font, scr ot.Tag, lang ot.Tag) (ot.Tag, ot.Tag)
 	return scr, ot.DFLT
 }
 
-// FontMetrics retrieves selected metrics of a font.
+// MetricsPolicy selects the source of a font's ascender, descender and line gap.
+// Fonts carry these values up to three times, in table hhea, and as typographic
+// and as Windows metrics in table OS/2, and fonts in the wild often disagree with
+// themselves.
+type MetricsPolicy uint8
+
+// Policies for selecting vertical metrics of a font.
+const (
+	AutoMetrics MetricsPolicy = iota // typographic metrics if the font asks for them, hhea otherwise
+	HHeaMetrics                      // ascender, descender and line gap from table hhea
+	TypoMetrics                      // sTypoAscender, sTypoDescender and sTypoLineGap from table OS/2
+	WinMetrics                       // usWinAscent and usWinDescent from table OS/2, without line gap
+)
+
+// metricsSource reads ascender, descender and line gap from one of the tables of
+// a font.
+type metricsSource func() (ascent, descent, gap sfnt.Units)
+
+// FontMetrics retrieves selected metrics of a font, with vertical metrics
+// selected by AutoMetrics.
 func FontMetrics(otf *ot.Font) opentype.FontMetricsInfo {
+	return LineMetrics(otf, AutoMetrics)
+}
+
+// LineMetrics retrieves the metrics of a font which are relevant for placing
+// lines of text: ascender, descender and line gap (selected by a policy),
+// cap height, x-height and the positions of underline and strikeout. Data is
+// combined from tables hhea, OS/2 and post.
+//
+// Policy AutoMetrics uses the typographic metrics if flag USE_TYPO_METRICS is set
+// for the font, and the hhea metrics otherwise. If the metrics selected by a policy
+// are zero, the other sources are tried.
+// Cap height and x-height are taken from table OS/2 (version 2 and up).
+// For older fonts they are measured from the glyphs 'H' and 'x'.
+func LineMetrics(otf *ot.Font, policy MetricsPolicy) opentype.FontMetricsInfo {
 	metrics := opentype.FontMetricsInfo{}
-	hhea := otf.Table(ot.T("hhea"))
-	b := hhea.Binary()
-	metrics.Ascent = sfnt.Units(i16(b[4:]))
-	metrics.Descent = sfnt.Units(i16(b[6:]))
-	metrics.LineGap = sfnt.Units(i16(b[8:]))
-	metrics.MaxAdvance = sfnt.Units(u16(b[8:]))
-	if metrics.Ascent == 0 && metrics.Descent == 0 {
-		if os2 := otf.Table(ot.T("OS/2")); os2 != nil {
-			tracer().Debugf("OS/2")
-			b := os2.Binary()
-			a := sfnt.Units(i16(b[68:]))
-			if a > metrics.Ascent {
-				tracer().Debugf("override of ascent: %d -> %d", metrics.Ascent, a)
-				metrics.Ascent = sfnt.Units(a)
-			}
-			d := sfnt.Units(i16(b[70:]))
-			if d < metrics.Descent {
-				tracer().Debugf("override of descent: %d -> %d", metrics.Descent, d)
-				metrics.Descent = sfnt.Units(d)
-			}
+	if head := otf.Table(ot.T("head")); head != nil { // head is a required table
+		metrics.UnitsPerEm = sfnt.Units(head.Self().AsHead().UnitsPerEm)
+	}
+	var hhea, os2, post []byte
+	if t := otf.Table(ot.T("hhea")); t != nil {
+		hhea = t.Binary()
+		metrics.MaxAdvance = sfnt.Units(u16at(hhea, 10))
+	}
+	if t := otf.Table(ot.T("OS/2")); t != nil {
+		os2 = t.Binary()
+		metrics.StrikeoutSize = sfnt.Units(int16(u16at(os2, 26)))
+		metrics.StrikeoutPosition = sfnt.Units(int16(u16at(os2, 28)))
+	}
+	if t := otf.Table(ot.T("post")); t != nil {
+		post = t.Binary()
+		metrics.UnderlinePosition = sfnt.Units(int16(u16at(post, 8)))
+		metrics.UnderlineThickness = sfnt.Units(int16(u16at(post, 10)))
+	}
+	//
+	// ascender, descender and line gap
+	fromHHea := func() (ascent, descent, gap sfnt.Units) {
+		return sfnt.Units(int16(u16at(hhea, 4))), sfnt.Units(int16(u16at(hhea, 6))),
+			sfnt.Units(int16(u16at(hhea, 8)))
+	}
+	fromTypo := func() (ascent, descent, gap sfnt.Units) {
+		return sfnt.Units(int16(u16at(os2, 68))), sfnt.Units(int16(u16at(os2, 70))),
+			sfnt.Units(int16(u16at(os2, 72)))
+	}
+	fromWin := func() (ascent, descent, gap sfnt.Units) {
+		return sfnt.Units(u16at(os2, 74)), -sfnt.Units(u16at(os2, 76)), 0
+	}
+	sources := []metricsSource{fromHHea, fromTypo, fromWin}
+	switch policy {
+	case AutoMetrics:
+		const useTypoMetrics = 1 << 7 // bit 7 of OS/2.fsSelection
+		if u16at(os2, 62)&useTypoMetrics != 0 {
+			sources = []metricsSource{fromTypo, fromHHea, fromWin}
+		}
+	case TypoMetrics:
+		sources = []metricsSource{fromTypo, fromHHea, fromWin}
+	case WinMetrics:
+		sources = []metricsSource{fromWin, fromTypo, fromHHea}
+	}
+	for _, source := range sources {
+		if a, d, gap := source(); a != 0 || d != 0 {
+			tracer().Debugf("font metrics: ascent = %d, descent = %d, line gap = %d", a, d, gap)
+			metrics.Ascent, metrics.Descent, metrics.LineGap = a, d, gap
+			break
+		}
+	}
+	//
+	// cap height and x-height
+	if u16at(os2, 0) >= 2 {
+		metrics.XHeight = sfnt.Units(int16(u16at(os2, 86)))
+		metrics.CapHeight = sfnt.Units(int16(u16at(os2, 88)))
+	}
+	if metrics.XHeight == 0 {
+		if gid := GlyphIndex(otf, 'x'); gid != 0 {
+			metrics.XHeight = GlyphMetrics(otf, gid, 0).BBox.MaxY
+		}
+	}
+	if metrics.CapHeight == 0 {
+		if gid := GlyphIndex(otf, 'H'); gid != 0 {
+			metrics.CapHeight = GlyphMetrics(otf, gid, 0).BBox.MaxY
 		}
 	}
-	head := otf.Table(ot.T("head")).Self().AsHead() // Head is a required table
-	metrics.UnitsPerEm = sfnt.Units(head.UnitsPerEm)
 	return metrics
 }
 
@@ -83,45 +164,30 @@ func CodePointForGlyph(otf *ot.Font, gid ot.GlyphIndex) rune {
 	return otf.CMap.GlyphIndexMap.ReverseLookup(gid)
 }
 
-// GlyphMetrics retrieves metrics for a given glyph.
-func GlyphMetrics(otf *ot.Font, gid ot.GlyphIndex) opentype.GlyphMetricsInfo {
-	metrics := opentype.GlyphMetricsInfo{}
+// GlyphMetrics retrieves the metrics for a given glyph in one call: advance width
+// and side bearings from table hmtx, the bounding box from table glyf (or from
+// the CFF outlines for fonts without glyf table), and vertical metrics from table
+// vmtx. For fonts without vertical metrics, these are derived from the font's
+// ascender and descender, as recommended by the OpenType specification.
+//
+// Metrics are returned in font design units. ppem is the size in pixels per em the
+// metrics are requested for, and is used for scaling metrics to pixels
+// (see opentype.GlyphMetricsInfo.Pixels). It may be 0 if clients are interested in
+// design units only.
+func GlyphMetrics(otf *ot.Font, gid ot.GlyphIndex, ppem uint16) opentype.GlyphMetricsInfo {
+	metrics := opentype.GlyphMetricsInfo{PPEm: ppem}
+	if head := otf.Table(ot.T("head")); head != nil { // head is a required table
+		metrics.UnitsPerEm = sfnt.Units(head.Self().AsHead().UnitsPerEm)
+	}
 	//
 	// table HMtx: advance width and left side bearing
-	hmtx := otf.Table(ot.T("hmtx")).Self().AsHMtx() // required table in OpenType
-	// table MaxP: number of glyphs
-	maxp := otf.Table(ot.T("maxp")).Self().AsMaxP() // required table in OpenType
-	mtxcnt := hmtx.NumberOfHMetrics
-	diff := maxp.NumGlyphs - mtxcnt
-	//tracer().Debugf("#glyphs=%d, #hmtx=%d, diff=%d", maxp.NumGlyphs, mtxcnt, diff)
-	if gid < ot.GlyphIndex(mtxcnt) {
-		l := ot.ParseList(hmtx.Binary(), mtxcnt, 4)
-		entry := l.Get(int(gid))
-		metrics.Advance = sfnt.Units(u16(entry.Bytes()))
-		metrics.LSB = sfnt.Units(i16(entry.Bytes()[2:]))
-	} else { // advance repetition of last advance in hmtx
-		l := ot.ParseList(hmtx.Binary(), mtxcnt, 4)
-		lastEntry := l.Get(mtxcnt - 1)
-		metrics.Advance = sfnt.Units(u16(lastEntry.Bytes()))
-		l = ot.ParseList(hmtx.Binary()[mtxcnt*4:], diff, 2)
-		entry := l.Get(int(gid) - mtxcnt)
-		metrics.LSB = sfnt.Units(i16(entry.Bytes()))
+	if hmtx := otf.Table(ot.T("hmtx")); hmtx != nil { // required table in OpenType
+		mtxcnt := hmtx.Self().AsHMtx().NumberOfHMetrics
+		metrics.Advance, metrics.LSB = longMetric(hmtx.Binary(), mtxcnt, gid)
 	}
 	//
-	// table glyf: bounding box
-	if glyf := otf.Table(ot.T("glyf")); glyf != nil {
-		if lo := otf.Table(ot.T("loca")); lo != nil {
-			loca := lo.Self().AsLoca()
-			loc := loca.IndexToLocation(gid)
-			b := glyf.Binary()[loc:]
-			metrics.BBox = opentype.BoundingBox{
-				MinX: sfnt.Units(i16(b[2:])),
-				MinY: sfnt.Units(i16(b[4:])),
-				MaxX: sfnt.Units(i16(b[6:])),
-				MaxY: sfnt.Units(i16(b[8:])),
-			}
-		}
-	}
+	// table glyf or CFF outlines: bounding box
+	metrics.BBox = glyphBounds(otf, gid, metrics.UnitsPerEm)
 	// RSB calculation: rsb = aw - (lsb + xMax - xMin)
 	// From the spec:
 	// If a glyph has no contours, xMax/xMin are not defined. The left side bearing indicated
@@ -129,9 +195,95 @@ func GlyphMetrics(otf *ot.Font, gid ot.GlyphIndex) opentype.GlyphMetricsInfo {
 	if !metrics.BBox.Empty() { // leave RSB for empty bboxes
 		metrics.RSB = metrics.Advance - (metrics.LSB + metrics.BBox.Dx())
 	}
+	//
+	// tables vhea and vmtx: advance height and top side bearing
+	vhea, vmtx := otf.Table(ot.T("vhea")), otf.Table(ot.T("vmtx"))
+	if vhea != nil && vmtx != nil {
+		mtxcnt := int(u16at(vhea.Binary(), 34)) // numOfLongVerMetrics
+		adv, tsb := longMetric(vmtx.Binary(), mtxcnt, gid)
+		metrics.VAdvance, metrics.TSB = sfnt.Units(uint16(adv)), tsb
+	} else {
+		ascent, descent := typoAscentDescent(otf)
+		metrics.VAdvance = ascent - descent
+		if !metrics.BBox.Empty() {
+			metrics.TSB = ascent - metrics.BBox.MaxY
+		}
+	}
 	return metrics
 }
 
+// longMetric reads an entry of a metrics table (hmtx or vmtx): an array of
+// mtxcnt long metrics (advance, side bearing), followed by an array of side
+// bearings only. Glyphs beyond the long metrics repeat the last advance.
+func longMetric(b []byte, mtxcnt int, gid ot.GlyphIndex) (advance, bearing sfnt.Units) {
+	if mtxcnt <= 0 {
+		return 0, 0
+	}
+	if int(gid) < mtxcnt {
+		advance = sfnt.Units(u16at(b, int(gid)*4))
+		bearing = sfnt.Units(int16(u16at(b, int(gid)*4+2)))
+		return
+	}
+	advance = sfnt.Units(u16at(b, (mtxcnt-1)*4))
+	bearing = sfnt.Units(int16(u16at(b, mtxcnt*4+(int(gid)-mtxcnt)*2)))
+	return
+}
+
+// glyphBounds returns the bounding box of a glyph, read from table glyf. For fonts
+// with CFF outlines, the outlines have to be interpreted, which we delegate
+// to package sfnt.
+func glyphBounds(otf *ot.Font, gid ot.GlyphIndex, upem sfnt.Units) opentype.BoundingBox {
+	if glyf := otf.Table(ot.T("glyf")); glyf != nil {
+		lo := otf.Table(ot.T("loca"))
+		if lo == nil {
+			return opentype.BoundingBox{}
+		}
+		loca := lo.Self().AsLoca()
+		loc := int(loca.IndexToLocation(gid))
+		if next := int(loca.IndexToLocation(gid + 1)); next == loc {
+			return opentype.BoundingBox{} // glyph without contours
+		}
+		b := glyf.Binary()
+		return opentype.BoundingBox{
+			MinX: sfnt.Units(int16(u16at(b, loc+2))),
+			MinY: sfnt.Units(int16(u16at(b, loc+4))),
+			MaxX: sfnt.Units(int16(u16at(b, loc+6))),
+			MaxY: sfnt.Units(int16(u16at(b, loc+8))),
+		}
+	}
+	if otf.F == nil || otf.F.SFNT == nil || upem <= 0 {
+		return opentype.BoundingBox{}
+	}
+	// at a size of upem pixels per em, a pixel is a font unit
+	var buf sfnt.Buffer
+	r, _, err := otf.F.SFNT.GlyphBounds(&buf, sfnt.GlyphIndex(gid), fixed.I(int(upem)), xfont.HintingNone)
+	if err != nil {
+		tracer().Errorf("cannot get bounds for glyph %d: %v", gid, err)
+		return opentype.BoundingBox{}
+	}
+	// sfnt's y-axis points downwards
+	return opentype.BoundingBox{
+		MinX: sfnt.Units(r.Min.X.Round()),
+		MinY: sfnt.Units(-r.Max.Y.Round()),
+		MaxX: sfnt.Units(r.Max.X.Round()),
+		MaxY: sfnt.Units(-r.Min.Y.Round()),
+	}
+}
+
+// typoAscentDescent returns the typographic ascender and descender of a font, from
+// table OS/2 if present, from table hhea otherwise.
+func typoAscentDescent(otf *ot.Font) (ascent, descent sfnt.Units) {
+	if os2 := otf.Table(ot.T("OS/2")); os2 != nil && len(os2.Binary()) >= 72 {
+		b := os2.Binary()
+		return sfnt.Units(int16(u16at(b, 68))), sfnt.Units(int16(u16at(b, 70)))
+	}
+	if hhea := otf.Table(ot.T("hhea")); hhea != nil {
+		b := hhea.Binary()
+		return sfnt.Units(int16(u16at(b, 4))), sfnt.Units(int16(u16at(b, 6)))
+	}
+	return 0, 0
+}
+
 // --- Helpers ----------------------------------------------------------
 
 func u16(b []byte) uint16 {
@@ -142,6 +294,14 @@ func i16(b []byte) int16 {
 	return int16(b[0])<<8 | int16(b[1])<<0
 }
 
+// u16at returns the uint16 at offset i of b, or 0 if i is out of bounds.
+func u16at(b []byte, i int) uint16 {
+	if i < 0 || i+2 > len(b) {
+		return 0
+	}
+	return u16(b[i:])
+}
+
 // func i32(b []byte) int32 {
 // 	return int32(b[0])<<24 | int32(b[1])<<16 | int32(b[2])<<8 | int32(b[3])<<0
 // }
@@ -6,10 +6,13 @@ import (
 
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/opentype"
 	"github.com/npillmayer/tyse/core/font/opentype/ot"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 // --- Test Suite Preparation ------------------------------------------------
@@ -48,7 +51,7 @@ func (env *MetricsTestEnviron) TestGlyphIndex() {
 
 func (env *MetricsTestEnviron) TestGlyphMetrics() {
 	gid := GlyphIndex(env.calibri, 'A')
-	m := GlyphMetrics(env.calibri, gid)
+	m := GlyphMetrics(env.calibri, gid, 0)
 	env.T().Logf("metrics = %v", m)
 	env.Equal(sfnt.Units(1185), m.Advance, "expected font.Advance for 'A' to be 1185 units")
 }
@@ -59,6 +62,72 @@ func (env *MetricsTestEnviron) TestLanguageMatch() {
 	env.Equal("TRK ", lang.String(), "expected Turkish language support in test font")
 }
 
+func TestGlyphMetricsAggregate(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	gentium := loadPackagedFont(t, "GentiumPlus-R.ttf") // TrueType outlines
+	m := GlyphMetrics(gentium, GlyphIndex(gentium, 'g'), 12)
+	expected := opentype.BoundingBox{MinX: 30, MinY: -500, MaxX: 989, MaxY: 960}
+	if m.Advance != 999 || m.LSB != 30 || m.RSB != 10 || m.BBox != expected {
+		t.Errorf("unexpected metrics for 'g': %+v", m)
+	}
+	if m.UnitsPerEm != 2048 || m.VAdvance != 3000 || m.TSB != 1290 {
+		t.Errorf("unexpected vertical metrics for 'g': %+v", m)
+	}
+	if px := m.Pixels(m.Advance); px != fixed.Int26_6(375) { // 999·12/2048 px = 5.85 px
+		t.Errorf("expected advance of 'g' at 12 ppem to be 375/64 px, is %d/64", px)
+	}
+	if m = GlyphMetrics(gentium, GlyphIndex(gentium, ' '), 0); !m.BBox.Empty() || m.Advance != 451 {
+		t.Errorf("expected space to have an advance, but no contours: %+v", m)
+	}
+	logo := loadPackagedFont(t, "TySELogo-Regular.otf") // CFF outlines
+	m = GlyphMetrics(logo, 5, 0)
+	expected = opentype.BoundingBox{MinX: 81, MinY: -1, MaxX: 584, MaxY: 700}
+	if m.Advance != 600 || m.BBox != expected || m.RSB != 600-(m.LSB+503) {
+		t.Errorf("unexpected metrics for CFF glyph: %+v", m)
+	}
+}
+
+func TestLineMetrics(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	logo := loadPackagedFont(t, "TySELogo-Regular.otf") // flags USE_TYPO_METRICS
+	m := FontMetrics(logo)
+	if m.Ascent != 760 || m.Descent != -200 || m.LineGap != 240 || m.LineHeight() != 1200 {
+		t.Errorf("expected typographic metrics to be selected, have %+v", m)
+	}
+	if m.CapHeight != 700 || m.XHeight != 500 {
+		t.Errorf("expected cap height 700 and x-height 500, have %+v", m)
+	}
+	if m.UnderlinePosition != -75 || m.UnderlineThickness != 50 {
+		t.Errorf("expected underline at -75 with thickness 50, have %+v", m)
+	}
+	if m.StrikeoutPosition != 300 || m.StrikeoutSize != 50 {
+		t.Errorf("expected strikeout at 300 with thickness 50, have %+v", m)
+	}
+	if m = LineMetrics(logo, HHeaMetrics); m.Ascent != 1000 || m.LineGap != 0 {
+		t.Errorf("expected hhea metrics to be selected, have %+v", m)
+	}
+	gentium := loadPackagedFont(t, "GentiumPlus-R.ttf")
+	if m = LineMetrics(gentium, WinMetrics); m.Ascent != 2250 || m.Descent != -750 {
+		t.Errorf("expected Windows metrics to be selected, have %+v", m)
+	}
+}
+
+func TestScaleUnits(t *testing.T) {
+	if px := opentype.ScaleUnits(-1000, 2048, 16); px != -500 {
+		t.Errorf("expected -1000 units at 16 ppem to be -500/64 px, is %d", px)
+	}
+	if d := opentype.UnitsToDimen(1000, 2000, 10*dimen.PT); d != 5*dimen.PT {
+		t.Errorf("expected half an em of a 10pt font to be 5pt, is %s", d)
+	}
+	if opentype.ScaleUnits(1000, 0, 12) != 0 || opentype.UnitsToDimen(1000, 0, dimen.PT) != 0 {
+		t.Errorf("expected scaling without units per em to result in 0")
+	}
+}
+
 // --- Helpers ---------------------------------------------------------------
 
 func loadLocalFont(t *testing.T, fontFileName string) *ot.Font {
@@ -76,3 +145,34 @@ func loadLocalFont(t *testing.T, fontFileName string) *ot.Font {
 	t.Logf("parsed OpenType font = %s", otf.F.Fontname)
 	return otf
 }
+
+func loadPackagedFont(t *testing.T, fontFileName string) *ot.Font {
+	path := filepath.Join("..", "..", "..", "locate", "resources", "packaged", "fonts", fontFileName)
+	f, err := font.LoadOpenTypeFont(path)
+	if err != nil {
+		t.Fatalf("cannot load packaged font %s: %s", fontFileName, err)
+	}
+	otf, err := ot.Parse(f.Binary)
+	if err != nil {
+		t.Fatalf("cannot decode packaged font %s: %s", fontFileName, err)
+	}
+	otf.F = f
+	return otf
+}
+
+func TestFontStyleAndFeatures(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	gentium := loadPackagedFont(t, "GentiumPlus-R.ttf")
+	if italic, bold := FontStyle(gentium); italic || bold {
+		t.Errorf("expected Gentium regular to be an upright, non-bold face")
+	}
+	if !HasFeature(gentium, ot.T("smcp")) {
+		t.Errorf("expected Gentium to have small caps")
+	}
+	logo := loadPackagedFont(t, "TySELogo-Regular.otf")
+	if HasFeature(logo, ot.T("smcp")) {
+		t.Errorf("expected TySE logo font not to have small caps")
+	}
+}
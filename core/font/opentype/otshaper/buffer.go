@@ -16,6 +16,25 @@ import (
 type ShapedGlyph struct {
 	Index   ot.GlyphIndex // glyph-index pointing into an OpenType font
 	Advance sfnt.Units    // advance-width of this glyph
+	Cluster int           // byte position of the input character(s) this glyph represents
+	Pos     Position      // adjustments of the glyph's position by GPOS lookups
+}
+
+// Position holds the adjustments GPOS lookups apply to the position of a glyph, in
+// font units. Placements offset the glyph from its pen position, advances change the
+// advance of the pen after the glyph, e.g., for kerning.
+type Position struct {
+	XPlacement, YPlacement sfnt.Units
+	XAdvance, YAdvance     sfnt.Units
+}
+
+// Add adds the adjustments of a GPOS value record to a position. Deltas from Device
+// tables have to be resolved beforehand (see ot.ValueRecord.Resolve).
+func (p *Position) Add(vr ot.ValueRecord) {
+	p.XPlacement += sfnt.Units(vr.XPlacement)
+	p.YPlacement += sfnt.Units(vr.YPlacement)
+	p.XAdvance += sfnt.Units(vr.XAdvance)
+	p.YAdvance += sfnt.Units(vr.YAdvance)
 }
 
 // Buffer holds a sequence of shaped glyphs, represented as glyph-indices for a given
@@ -41,6 +60,13 @@ func (b Buffer) Glyphs() []ot.GlyphIndex {
 	return glyphs
 }
 
+// Adjust adds the adjustments of a GPOS value record to the position of glyph #i.
+func (b Buffer) Adjust(i int, vr ot.ValueRecord) {
+	if i >= 0 && i < len(b) {
+		b[i].Pos.Add(vr)
+	}
+}
+
 // For different scripts or script/language combinations shapers may prefer NFC or NFD
 const (
 	PREFER_COMPOSED   = 0
@@ -252,6 +278,7 @@ func (b Buffer) mapGlyphs(input string, otf *ot.Font, script ot.Tag, lang ot.Tag
 	iterInput.InitString(normalizerDefault, input)
 	var n int
 	for !iterInput.Done() { // now every character is Unicode-normalized NFC or NFD
+		cluster := iterInput.Pos()     // position of the code-points within the input
 		codepoints := iterInput.Next() // get a sequence of code-points
 		tracer().Debugf("read codepoints '%s' (%v)", string(codepoints), codepoints)
 		glyphs := findRepresentation(codepoints, otf, buf, normFlag)
@@ -259,8 +286,9 @@ func (b Buffer) mapGlyphs(input string, otf *ot.Font, script ot.Tag, lang ot.Tag
 		var glyph ot.GlyphIndex
 		for _, glyph = range glyphs {
 			b[n].Index = glyph
-			metrics := otquery.GlyphMetrics(otf, glyph)
+			metrics := otquery.GlyphMetrics(otf, glyph, 0)
 			b[n].Advance = metrics.Advance
+			b[n].Cluster = cluster
 			n++
 		}
 	}
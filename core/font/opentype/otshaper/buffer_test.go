@@ -101,6 +101,12 @@ func (env *BufferTestEnviron) TestBufferInitialMapping() {
 		env.T().Logf("buffer is %v", buf[:n])
 		env.Equal(d.want, buf.Glyphs()[:n], "expected different mapping")
 	}
+	n := buf.mapGlyphs("Café", env.otf, ot.T("dev2"), ot.DFLT)
+	clusters := make([]int, n)
+	for i, g := range buf[:n] {
+		clusters[i] = g.Cluster
+	}
+	env.Equal([]int{0, 1, 2, 3, 3}, clusters, "expected de-composed é to form a single cluster")
 }
 
 func (env *BufferTestEnviron) TestBufferDraw() {
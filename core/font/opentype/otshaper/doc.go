@@ -45,7 +45,7 @@ func tracer() tracing.Trace {
 
 // errShaper produces user level errors for text shaping.
 func errShaper(x string) error {
-	return core.Error(core.EINVALID, "OpenType text shaping: %s", x)
+	return core.ErrorIn(core.ErrShaping, core.EINVALID, "OpenType text shaping: %s", x)
 }
 
 // assert emulates assertions known from other programming languages.
@@ -22,6 +22,7 @@ var script2opentype = map[string]string{
 	"Gujr": "gjr2", // Not gujr
 	"Guru": "gur2", // Not guru
 	"Hang": "hang", // Hanguli
+	"Hani": "hani", // Han
 	"Hans": "hani", // Han (simplified)
 	"Hebr": "hebr", // Hebrew
 	"Hira": "hira", // Hiragana
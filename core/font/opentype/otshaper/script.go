@@ -0,0 +1,141 @@
+package otshaper
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"golang.org/x/text/language"
+)
+
+// --- Script runs -----------------------------------------------------------
+
+// ScriptRun is a segment of text written in a single script. Shaping happens
+// per script run, as OpenType lookups are selected by script and language system.
+type ScriptRun struct {
+	Start, End int             // byte positions [Start…End) of the run within the text
+	Script     language.Script // ISO 15924 script code, Zzzz if unknown
+	Lang       language.Tag    // language of the run, und if no locale matches the script
+	ScriptTag  ot.Tag          // OpenType script tag, DFLT if unsupported
+	LangTag    ot.Tag          // OpenType language system tag, DFLT if unsupported
+}
+
+// unicodeScriptCodes maps names of Unicode script properties to ISO 15924 codes,
+// for scripts which are not spelled out as script codes in script2opentype.
+var unicodeScriptCodes = map[string]string{
+	"Arabic":     "Arab",
+	"Armenian":   "Armn",
+	"Bengali":    "Beng",
+	"Bopomofo":   "Bopo",
+	"Cherokee":   "Cher",
+	"Cyrillic":   "Cyrl",
+	"Devanagari": "Deva",
+	"Ethiopic":   "Ethi",
+	"Georgian":   "Geor",
+	"Greek":      "Grek",
+	"Gujarati":   "Gujr",
+	"Gurmukhi":   "Guru",
+	"Han":        "Hani",
+	"Hangul":     "Hang",
+	"Hebrew":     "Hebr",
+	"Hiragana":   "Hira",
+	"Kannada":    "Knda",
+	"Katakana":   "Kana",
+	"Khmer":      "Khmr",
+	"Lao":        "Laoo",
+	"Latin":      "Latn",
+	"Malayalam":  "Mlym",
+	"Mongolian":  "Mong",
+	"Myanmar":    "Mymr",
+	"Oriya":      "Orya",
+	"Sinhala":    "Sinh",
+	"Syriac":     "Syrc",
+	"Tamil":      "Taml",
+	"Telugu":     "Telu",
+	"Thaana":     "Thaa",
+	"Thai":       "Thai",
+	"Tibetan":    "Tibt",
+}
+
+// ScriptRuns segments text into runs of a single script, by the Unicode script
+// property of its characters. Characters common to scripts (spaces, punctuation,
+// digits) and combining marks take the script of the preceding text, or of the
+// following text at the start of the input.
+//
+// Language systems are selected from locales, which are BCP 47 language tags in
+// order of preference: a run gets the first locale written in the run's script.
+// If no locale matches, the language of the run is und and its language system
+// is DFLT. Clients therefore do not have to supply script and language for
+// shaping themselves.
+func ScriptRuns(text string, locales ...language.Tag) []ScriptRun {
+	var runs []ScriptRun
+	var name string // Unicode name of the script of the current run
+	start := 0
+	for i, r := range text {
+		s := scriptOf(r, name)
+		if s == "Common" || s == "Inherited" || s == name {
+			continue
+		}
+		if name != "" {
+			runs = append(runs, makeScriptRun(start, i, name, locales))
+			start = i
+		}
+		name = s
+	}
+	if start < len(text) {
+		runs = append(runs, makeScriptRun(start, len(text), name, locales))
+	}
+	tracer().Debugf("text segmented into %d script runs", len(runs))
+	return runs
+}
+
+func makeScriptRun(start, end int, name string, locales []language.Tag) ScriptRun {
+	run := ScriptRun{Start: start, End: end, Lang: language.Und, LangTag: ot.DFLT}
+	run.Script, run.ScriptTag = scriptCode(name)
+	for _, l := range locales {
+		if s, conf := l.Script(); conf > language.No && s == run.Script {
+			run.Lang = l
+			run.LangTag = LanguageTagForLanguage(l, language.Low)
+			break
+		}
+	}
+	return run
+}
+
+// scriptCode returns the ISO 15924 code and the OpenType script tag for the
+// script with Unicode name name.
+func scriptCode(name string) (language.Script, ot.Tag) {
+	code := "Zzzz"
+	if c, ok := unicodeScriptCodes[name]; ok {
+		code = c
+	}
+	script := language.MustParseScript(code)
+	tag := ScriptTagForScript(script)
+	if otScr, ok := script2opentype[name]; ok && tag == ot.DFLT {
+		tag = ot.T(otScr)
+	}
+	return script, tag
+}
+
+// scriptOf returns the Unicode name of the script of rune r. hint is the script
+// of the preceding text, which is tested first, as it is the most likely one.
+func scriptOf(r rune, hint string) string {
+	if r == utf8.RuneError {
+		return "Common"
+	}
+	if t, ok := unicode.Scripts[hint]; ok && unicode.Is(t, r) {
+		return hint
+	}
+	if unicode.Is(unicode.Common, r) {
+		return "Common"
+	}
+	if unicode.Is(unicode.Inherited, r) {
+		return "Inherited"
+	}
+	for name, t := range unicode.Scripts {
+		if unicode.Is(t, r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
@@ -0,0 +1,54 @@
+package otshaper
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"golang.org/x/text/language"
+)
+
+func TestScriptRuns(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.fonts")
+	defer teardown()
+	//
+	text := "(Hello, שלום!) Привет"
+	runs := ScriptRuns(text, language.Russian, language.German)
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 script runs, have %d: %v", len(runs), runs)
+	}
+	expected := []struct {
+		text   string
+		script ot.Tag
+		lang   ot.Tag
+	}{
+		{"(Hello, ", ot.T("latn"), ot.T("DEU")},
+		{"שלום!) ", ot.T("hebr"), ot.DFLT},
+		{"Привет", ot.T("cyrl"), ot.T("RUS")},
+	}
+	for i, run := range runs {
+		if s := text[run.Start:run.End]; s != expected[i].text {
+			t.Errorf("expected run #%d to be %q, is %q", i, expected[i].text, s)
+		}
+		if run.ScriptTag != expected[i].script || run.LangTag != expected[i].lang {
+			t.Errorf("expected run #%d to be tagged %s/%s, is %s/%s", i, expected[i].script,
+				expected[i].lang, run.ScriptTag, run.LangTag)
+		}
+	}
+	if runs[1].Lang != language.Und {
+		t.Errorf("expected Hebrew run without matching locale to be und, is %s", runs[1].Lang)
+	}
+}
+
+func TestScriptRunsCommon(t *testing.T) {
+	runs := ScriptRuns("1 + 2 = 3")
+	if len(runs) != 1 || runs[0].ScriptTag != ot.DFLT || runs[0].End != 9 {
+		t.Errorf("expected a single DFLT run for script-neutral text, have %v", runs)
+	}
+	if runs := ScriptRuns("漢字"); len(runs) != 1 || runs[0].ScriptTag != ot.T("hani") {
+		t.Errorf("expected Han run to be tagged hani, have %v", runs)
+	}
+	if runs := ScriptRuns(""); len(runs) != 0 {
+		t.Errorf("expected no runs for empty text, have %d", len(runs))
+	}
+}
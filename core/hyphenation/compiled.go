@@ -0,0 +1,55 @@
+package hyphenation
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/derekparker/trie"
+)
+
+// compiledDictionary is the serialized form of a Dictionary.
+type compiledDictionary struct {
+	Identifier string
+	Patterns   map[string][]int
+	Exceptions map[string][]int
+}
+
+// MarshalBinary encodes the compiled form of a dictionary, i.e. its patterns with
+// positions extracted, and its exceptions. Decoding it with UnmarshalBinary is
+// considerably cheaper than reading patterns from a pattern file.
+func (dict *Dictionary) MarshalBinary() ([]byte, error) {
+	c := compiledDictionary{
+		Identifier: dict.Identifier,
+		Patterns:   make(map[string][]int),
+		Exceptions: dict.exceptions,
+	}
+	for _, pattern := range dict.patterns.Keys() {
+		if node, ok := dict.patterns.Find(pattern); ok {
+			c.Patterns[pattern] = node.Meta().([]int)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a dictionary encoded by MarshalBinary, replacing the
+// contents of dict.
+func (dict *Dictionary) UnmarshalBinary(data []byte) error {
+	var c compiledDictionary
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&c); err != nil {
+		return err
+	}
+	dict.Identifier = c.Identifier
+	dict.exceptions = c.Exceptions
+	if dict.exceptions == nil {
+		dict.exceptions = make(map[string][]int)
+	}
+	dict.patterns = trie.New()
+	for pattern, positions := range c.Patterns {
+		dict.patterns.Add(pattern, positions)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package hyphenation
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/npillmayer/schuko/gconf"
@@ -79,3 +80,98 @@ func TestUSPatterns(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestReadPatterns(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.core.hyphenation")
+	defer teardown()
+	//
+	dict, err := ReadPatterns(strings.NewReader("% plain text patterns\nl1l 1ta\n"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dict.ReadExceptions(strings.NewReader("ta-ble % exception\n")); err != nil {
+		t.Fatal(err)
+	}
+	if h := dict.HyphenationString("hello"); h != "hel-lo" {
+		t.Errorf("hello should be hel-lo, is %s", h)
+	}
+	if h := dict.HyphenationString("table"); h != "ta-ble" {
+		t.Errorf("table should be ta-ble, is %s", h)
+	}
+	data, err := dict.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := &Dictionary{}
+	if err = compiled.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if compiled.Identifier != "test" {
+		t.Errorf("expected identifier to be 'test', is %q", compiled.Identifier)
+	}
+	for _, word := range []string{"hello", "table", "retake"} {
+		if h, c := dict.HyphenationString(word), compiled.HyphenationString(word); h != c {
+			t.Errorf("compiled dictionary hyphenates %s as %s, expected %s", word, c, h)
+		}
+	}
+}
+
+func TestLookupPatterns(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.core.hyphenation")
+	defer teardown()
+	//
+	for tag, name := range map[string]string{
+		"en_US": "en-us", "en-GB": "en-gb", "en": "en-us", "de-AT": "de-1996", "de-CH": "de-ch-1901",
+	} {
+		res, ok := LookupPatterns(tag)
+		if !ok || res.Name != name {
+			t.Errorf("expected patterns %s for %s, have %q", name, tag, res.Name)
+		}
+	}
+	if _, ok := LookupPatterns("tlh"); ok {
+		t.Errorf("expected no patterns for Klingon")
+	}
+	RegisterPatterns("tlh", "tlh")
+	if res, ok := LookupPatterns("tlh-Latn"); !ok || res.PatternFile() != "hyph-tlh.pat.txt" {
+		t.Errorf("expected registered patterns for tlh, have %v", res)
+	}
+}
+
+func TestHyphenateWith(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.core.hyphenation")
+	defer teardown()
+	//
+	dict, err := ReadPatterns(strings.NewReader("1ta 1la 1ri\n"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{LeftMin: 2, RightMin: 2}
+	if h := strings.Join(dict.HyphenateWith("atari", opts), "-"); h != "ata-ri" {
+		t.Errorf("atari should be ata-ri with minima 2/2, is %s", h)
+	}
+	opts.RightMin = 3
+	if h := strings.Join(dict.HyphenateWith("atari", opts), "-"); h != "atari" {
+		t.Errorf("atari should not be hyphenated with minima 2/3, is %s", h)
+	}
+	if h := strings.Join(dict.HyphenateWith("tala-tari", opts), "|"); h != "tala-tari" {
+		t.Errorf("expected compound not to be hyphenated, is %s", h)
+	}
+	opts.Compounds, opts.RightMin = true, 2
+	if h := strings.Join(dict.HyphenateWith("tala-tari", opts), "|"); h != "ta|la-|ta|ri" {
+		t.Errorf("expected parts of compound to be hyphenated, is %s", h)
+	}
+	opts.Exceptions = NewExceptions("manu-script", "at-a-ri")
+	if h := strings.Join(dict.HyphenateWith("Atari", opts), "-"); h != "At-a-ri" {
+		t.Errorf("expected user exception to override patterns and minima, is %s", h)
+	}
+	if h := strings.Join((*Dictionary)(nil).HyphenateWith("manuscript", opts), "-"); h != "manu-script" {
+		t.Errorf("expected user exceptions to apply without dictionary, is %s", h)
+	}
+	AddExceptions("x-test", "hy-phen")
+	if x := LanguageOptions("x-test-US").Exceptions; x.Len() != 1 {
+		t.Errorf("expected language to have 1 user exception, has %d", x.Len())
+	}
+	if opts := LanguageOptions("de_CH"); !opts.Compounds || opts.RightMin != 2 {
+		t.Errorf("expected German to hyphenate compounds, with minima 2/2, is %+v", opts)
+	}
+}
@@ -3,6 +3,7 @@ package hyphenation
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -96,17 +97,39 @@ type Dictionary struct {
 //    "a5ban" => (a)(5b)(a)(n) => positions["aban"] = [0,5,0,0].
 //
 func LoadPatterns(patternfile string) *Dictionary {
-	file, err := os.Open(patternfile)
+	dict, err := LoadPatternFile(patternfile)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return dict
+}
+
+// LoadPatternFile is like LoadPatterns, but returns an error instead of terminating
+// the application if the pattern file cannot be read.
+func LoadPatternFile(patternfile string) (*Dictionary, error) {
+	file, err := os.Open(patternfile)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
+	return ReadPatterns(file, fmt.Sprintf("patterns: %s", patternfile))
+}
+
+// ReadPatterns reads hyphenation patterns from r and returns a dictionary for them.
+// The dictionary is identified by id, unless the patterns carry an identifier
+// of their own.
+//
+// Patterns may either be given in the format of TeX pattern files (see LoadPatterns)
+// or as plain text, as in the hyph-*.pat.txt files of the hyph-utf8 project. Plain
+// text patterns are separated by white space, usually one pattern per line.
+// Comments start with '%'.
+func ReadPatterns(r io.Reader, id string) (*Dictionary, error) {
 	dict := &Dictionary{
 		exceptions: make(map[string][]int),
 		patterns:   trie.New(),
-		Identifier: fmt.Sprintf("patterns: %s", patternfile),
+		Identifier: id,
 	}
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() { // internally, it advances token based on sperator
 		line := scanner.Text()
 		if strings.HasPrefix(line, "\\message{") { // extract patterns identifier
@@ -117,29 +140,36 @@ func LoadPatterns(patternfile string) *Dictionary {
 		} else if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "\\") ||
 			line == "" || strings.HasPrefix(line, "}") {
 			// ignore comments, TeX commands, etc.
-		} else { // read and decode a pattern: ".ab1a" "abe4l3in", ...
-			var pattern string          // will become the pattern without positions
-			var positions []int         // we'll extract positions
-			var wasdigit bool           // has the last char been a digit?
-			for _, char := range line { // iterate over runes of the pattern
-				if unicode.IsDigit(char) {
-					d, _ := strconv.Atoi(string(char))
-					positions = append(positions, d) // add to positions array
-					wasdigit = true
-				} else { // '.' or alphabetic rune
-					pattern = pattern + string(char)
-					if wasdigit {
-						wasdigit = false
-					} else {
-						positions = append(positions, 0) // append a 0
-					}
-				}
+		} else { // read and decode patterns: ".ab1a" "abe4l3in", ...
+			for _, pattern := range strings.Fields(stripComment(line)) {
+				dict.addPattern(pattern)
 			}
-			//fmt.Printf("pattern '%s'\thas positions %v\n", pattern, positions)
-			dict.patterns.Add(pattern, positions)
 		}
 	}
-	return dict
+	return dict, scanner.Err()
+}
+
+// addPattern decodes a pattern like "abe4l3in" and adds it to the trie.
+func (dict *Dictionary) addPattern(p string) {
+	var pattern string       // will become the pattern without positions
+	var positions []int      // we'll extract positions
+	var wasdigit bool        // has the last char been a digit?
+	for _, char := range p { // iterate over runes of the pattern
+		if unicode.IsDigit(char) {
+			d, _ := strconv.Atoi(string(char))
+			positions = append(positions, d) // add to positions array
+			wasdigit = true
+		} else { // '.' or alphabetic rune
+			pattern = pattern + string(char)
+			if wasdigit {
+				wasdigit = false
+			} else {
+				positions = append(positions, 0) // append a 0
+			}
+		}
+	}
+	//fmt.Printf("pattern '%s'\thas positions %v\n", pattern, positions)
+	dict.patterns.Add(pattern, positions)
 }
 
 /*
@@ -161,22 +191,54 @@ func (dict *Dictionary) readExceptions(scanner *bufio.Scanner) {
 		if strings.HasPrefix(line, "}") {
 			return
 		}
-		var positions []int // we'll extract positions
-		washyphen := false
-		for _, char := range line {
-			if char == '-' {
-				positions = append(positions, 1) // possible break point
-				washyphen = true
-			} else if washyphen { // skip letter
-				washyphen = false
-			} else { // a letter without a '-'
-				positions = append(positions, 0) // append 0
-			}
+		dict.addException(line)
+	}
+}
+
+// ReadExceptions reads a list of exceptions from r and adds them to the dictionary.
+// Exceptions are hyphenated words, separated by white space, as in the
+// hyph-*.hyp.txt files of the hyph-utf8 project:
+//
+//	as-so-ciate
+//	project
+//	ta-ble
+//
+// Comments start with '%'.
+func (dict *Dictionary) ReadExceptions(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		for _, word := range strings.Fields(stripComment(scanner.Text())) {
+			dict.addException(word)
 		}
-		word := strings.Replace(line, "-", "", -1)
-		dict.exceptions[word] = positions
-		//fmt.Printf("exception '%s'\thas positions %v\n", line, positions)
 	}
+	return scanner.Err()
+}
+
+// addException decodes a hyphenated word like "ta-ble" and adds it to the exceptions.
+func (dict *Dictionary) addException(line string) {
+	var positions []int // we'll extract positions
+	washyphen := false
+	for _, char := range line {
+		if char == '-' {
+			positions = append(positions, 1) // possible break point
+			washyphen = true
+		} else if washyphen { // skip letter
+			washyphen = false
+		} else { // a letter without a '-'
+			positions = append(positions, 0) // append 0
+		}
+	}
+	word := strings.Replace(line, "-", "", -1)
+	dict.exceptions[word] = positions
+	//fmt.Printf("exception '%s'\thas positions %v\n", line, positions)
+}
+
+// stripComment removes a trailing comment from a line.
+func stripComment(line string) string {
+	if i := strings.IndexByte(line, '%'); i >= 0 {
+		return line[:i]
+	}
+	return line
 }
 
 // HyphenationString return a word with possible hyphens inserted.
@@ -0,0 +1,265 @@
+package hyphenation
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// --- Options ---------------------------------------------------------------
+
+// Options control the hyphenation of words beyond patterns (see HyphenateWith).
+type Options struct {
+	LeftMin    int         // minimum number of runes before the first hyphen
+	RightMin   int         // minimum number of runes after the last hyphen
+	Compounds  bool        // hyphenate the parts of compound words, e.g. "Mehrzweck-Hallenbad"
+	Exceptions *Exceptions // user exceptions, overriding the results of patterns
+}
+
+// explicitHyphens are the characters separating the parts of compound words.
+const explicitHyphens = "-‐"
+
+// HasExplicitHyphen returns true if s contains a hyphen, as in compound words like
+// "lime-tree".
+func HasExplicitHyphen(s string) bool {
+	return strings.ContainsAny(s, explicitHyphens)
+}
+
+// defaultOptions are the options for languages without options of their own. They
+// are the defaults of TeX's \lefthyphenmin and \righthyphenmin.
+var defaultOptions = Options{LeftMin: 2, RightMin: 3}
+
+var languageOptions = struct {
+	sync.RWMutex
+	options    map[string]Options     // normalized tag => options
+	exceptions map[string]*Exceptions // base language => user exceptions
+}{
+	options: map[string]Options{
+		"da": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"de": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"en": {LeftMin: 2, RightMin: 3},
+		"fi": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"it": {LeftMin: 2, RightMin: 2},
+		"nb": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"nl": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"nn": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"no": {LeftMin: 2, RightMin: 2, Compounds: true},
+		"sv": {LeftMin: 2, RightMin: 2, Compounds: true},
+	},
+	exceptions: make(map[string]*Exceptions),
+}
+
+// LanguageOptions returns the hyphenation options for a BCP 47 language tag,
+// including the user exceptions added with AddExceptions. Tags are matched as
+// with LookupPatterns.
+func LanguageOptions(tag string) Options {
+	languageOptions.RLock()
+	defer languageOptions.RUnlock()
+	opts := defaultOptions
+	t := normalizeTag(tag)
+	for t != "" {
+		if o, ok := languageOptions.options[t]; ok {
+			opts = o
+			break
+		}
+		i := strings.LastIndexByte(t, '-')
+		if i < 0 {
+			break
+		}
+		t = t[:i]
+	}
+	if opts.Exceptions == nil {
+		opts.Exceptions = languageOptions.exceptions[baseLanguage(tag)]
+	}
+	return opts
+}
+
+// SetLanguageOptions sets the hyphenation options for a BCP 47 language tag,
+// e.g. to hyphenate compound words of a language.
+func SetLanguageOptions(tag string, opts Options) {
+	languageOptions.Lock()
+	defer languageOptions.Unlock()
+	languageOptions.options[normalizeTag(tag)] = opts
+}
+
+// AddExceptions adds user exceptions for the language of a BCP 47 tag. Exceptions
+// apply to all variants of a language, i.e. exceptions for "en" apply to "en-US"
+// and "en-GB" as well.
+func AddExceptions(tag string, words ...string) {
+	languageOptions.Lock()
+	defer languageOptions.Unlock()
+	base := baseLanguage(tag)
+	x := languageOptions.exceptions[base]
+	if x == nil {
+		x = NewExceptions()
+		languageOptions.exceptions[base] = x
+	}
+	x.Add(words...)
+}
+
+func baseLanguage(tag string) string {
+	t := normalizeTag(tag)
+	if i := strings.IndexByte(t, '-'); i >= 0 {
+		return t[:i]
+	}
+	return t
+}
+
+// HyphenateWith is like Hyphenate, but follows options opts:
+//
+//   - user exceptions take precedence over the dictionary and are not subject
+//     to the hyphenation minima
+//   - no hyphens are placed within opts.LeftMin runes from the start and
+//     opts.RightMin runes from the end of a word
+//   - words containing explicit hyphens are hyphenated part by part if
+//     opts.Compounds is set, and are left alone otherwise, as TeX does;
+//     explicit hyphens end the syllable before them
+//
+// dict may be nil, in which case only user exceptions are considered.
+func (dict *Dictionary) HyphenateWith(word string, opts Options) []string {
+	if syllables, ok := opts.Exceptions.Hyphenate(word); ok {
+		return syllables
+	}
+	if dict == nil {
+		return []string{word}
+	}
+	if !HasExplicitHyphen(word) {
+		return applyMinima(dict.Hyphenate(word), opts.LeftMin, opts.RightMin)
+	}
+	if !opts.Compounds {
+		return []string{word}
+	}
+	var syllables []string
+	part := opts
+	part.Compounds = false
+	pending := "" // hyphens at the start of the word
+	for word != "" {
+		i := strings.IndexAny(word, explicitHyphens)
+		if i < 0 {
+			i = len(word)
+		}
+		if i > 0 {
+			parts := dict.HyphenateWith(word[:i], part)
+			parts[0] = pending + parts[0]
+			pending = ""
+			syllables = append(syllables, parts...)
+		}
+		if i < len(word) { // the hyphen ends the syllable before it
+			_, w := utf8.DecodeRuneInString(word[i:])
+			if len(syllables) == 0 {
+				pending += word[i : i+w]
+			} else {
+				syllables[len(syllables)-1] += word[i : i+w]
+			}
+			i += w
+		}
+		word = word[i:]
+	}
+	if pending != "" {
+		syllables = append(syllables, pending)
+	}
+	return syllables
+}
+
+// applyMinima joins syllables which are shorter than left (at the start of a
+// word) or right (at the end of a word).
+func applyMinima(syllables []string, left, right int) []string {
+	if len(syllables) < 2 {
+		return syllables
+	}
+	total := 0
+	for _, s := range syllables {
+		total += utf8.RuneCountInString(s)
+	}
+	joined := make([]string, 0, len(syllables))
+	var syllable strings.Builder
+	n := 0 // number of runes before the current break
+	for i, s := range syllables {
+		syllable.WriteString(s)
+		n += utf8.RuneCountInString(s)
+		if i == len(syllables)-1 || (n >= left && total-n >= right) {
+			joined = append(joined, syllable.String())
+			syllable.Reset()
+		}
+	}
+	return joined
+}
+
+// --- User exceptions -------------------------------------------------------
+
+// Exceptions is a list of user exceptions, i.e. words with hyphenation points
+// set by the user, e.g. "manu-script". Exceptions override the results of
+// hyphenation patterns and the exceptions of a dictionary. Words are matched
+// case-insensitively.
+//
+// Exceptions are safe for concurrent use.
+type Exceptions struct {
+	mu    sync.RWMutex
+	words map[string][]int // lower case word => rune positions of hyphens
+}
+
+// NewExceptions creates a list of user exceptions from hyphenated words. Words
+// without hyphens will not be hyphenated at all.
+func NewExceptions(words ...string) *Exceptions {
+	x := &Exceptions{words: make(map[string][]int)}
+	x.Add(words...)
+	return x
+}
+
+// Add adds hyphenated words to the list, replacing existing entries for them.
+func (x *Exceptions) Add(words ...string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, w := range words {
+		var word strings.Builder
+		var hyphens []int
+		n := 0
+		for _, r := range strings.TrimSpace(w) {
+			if r == '-' {
+				hyphens = append(hyphens, n)
+				continue
+			}
+			word.WriteRune(r)
+			n++
+		}
+		if n > 0 {
+			x.words[strings.ToLower(word.String())] = hyphens
+		}
+	}
+}
+
+// Len returns the number of words in the list.
+func (x *Exceptions) Len() int {
+	if x == nil {
+		return 0
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.words)
+}
+
+// Hyphenate splits word at the hyphenation points of its exception. ok is false
+// if there is no exception for word. x may be nil.
+func (x *Exceptions) Hyphenate(word string) (syllables []string, ok bool) {
+	if x == nil {
+		return nil, false
+	}
+	x.mu.RLock()
+	hyphens, ok := x.words[strings.ToLower(word)]
+	x.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	start, n := 0, 0 // byte position of syllable, rune count
+	for i := range word {
+		if len(hyphens) > 0 && hyphens[0] == n {
+			if i > start {
+				syllables = append(syllables, word[start:i])
+			}
+			start = i
+			hyphens = hyphens[1:]
+		}
+		n++
+	}
+	return append(syllables, word[start:]), true
+}
@@ -15,6 +15,12 @@ Resulting from the API of the trie, the implementation of the pattern
 application algorithm is bad. TODO: improve time complexity of pattern
 application.
 
+Patterns may be read from TeX pattern files or from the plain text pattern files
+of the hyph-utf8 project (hyph-*.pat.txt and hyph-*.hyp.txt). A registry maps
+BCP 47 language tags to pattern files (see LookupPatterns). Dictionaries may be
+compiled into a binary form, which is much cheaper to load than pattern files.
+Package resources loads and caches dictionaries for languages.
+
 Further Reading
 
   https://www.microsoft.com/en-us/Typography/OpenTypeSpecification.aspx
@@ -0,0 +1,92 @@
+package hyphenation
+
+import (
+	"strings"
+	"sync"
+)
+
+// PatternSource is the location to download pattern files from, if they are not
+// available locally. It points to the plain text patterns of the hyph-utf8
+// project.
+var PatternSource = "https://raw.githubusercontent.com/hyphenation/tex-hyphen/master/hyph-utf8/tex/generic/hyph-utf8/patterns/txt/"
+
+// PatternResource describes the hyphenation patterns for a language, as
+// published by the hyph-utf8 project.
+type PatternResource struct {
+	Tag  string // BCP 47 language tag, e.g. "en-US"
+	Name string // name of the patterns, e.g. "en-us" for hyph-en-us.pat.txt
+}
+
+// PatternFile returns the name of the file holding the patterns, e.g.
+// "hyph-en-us.pat.txt".
+func (res PatternResource) PatternFile() string {
+	return "hyph-" + res.Name + ".pat.txt"
+}
+
+// ExceptionsFile returns the name of the file holding the exceptions, e.g.
+// "hyph-en-us.hyp.txt". Not every language has exceptions.
+func (res PatternResource) ExceptionsFile() string {
+	return "hyph-" + res.Name + ".hyp.txt"
+}
+
+// TeXFile returns the name of a TeX pattern file, e.g. "hyph-en-us.tex".
+func (res PatternResource) TeXFile() string {
+	return "hyph-" + res.Name + ".tex"
+}
+
+// URL returns the location to download a pattern file from.
+func (res PatternResource) URL(file string) string {
+	return PatternSource + file
+}
+
+var registry = struct {
+	sync.RWMutex
+	patterns map[string]string // normalized tag => name of patterns
+}{
+	patterns: map[string]string{
+		"af": "af", "bg": "bg", "ca": "ca", "cs": "cs", "cy": "cy", "da": "da",
+		"de": "de-1996", "de-1901": "de-1901", "de-ch": "de-ch-1901",
+		"el": "el-monoton", "en": "en-us", "en-gb": "en-gb", "en-us": "en-us",
+		"eo": "eo", "es": "es", "et": "et", "eu": "eu", "fi": "fi", "fr": "fr",
+		"ga": "ga", "gl": "gl", "hr": "hr", "hu": "hu", "hy": "hy", "ia": "ia",
+		"id": "id", "is": "is", "it": "it", "ka": "ka", "kmr": "kmr", "la": "la",
+		"lt": "lt", "lv": "lv", "mn": "mn-cyrl", "nb": "nb", "nl": "nl", "nn": "nn",
+		"no": "nb", "oc": "oc", "pl": "pl", "pt": "pt", "ro": "ro", "ru": "ru",
+		"sk": "sk", "sl": "sl", "sr": "sr-cyrl", "sv": "sv", "tk": "tk", "tr": "tr",
+		"uk": "uk",
+	},
+}
+
+// RegisterPatterns maps a BCP 47 language tag to the name of pattern files,
+// replacing an existing mapping. Name "de-1996" selects files
+// hyph-de-1996.pat.txt and hyph-de-1996.hyp.txt.
+func RegisterPatterns(tag string, name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.patterns[normalizeTag(tag)] = name
+}
+
+// LookupPatterns finds the hyphenation patterns for a BCP 47 language tag. If
+// there are no patterns for a tag, subtags are stripped from the end until
+// patterns are found, i.e. "de-AT" will find the patterns for "de".
+// Underscores are accepted as separators, too, i.e. "en_US" is equal to "en-US".
+func LookupPatterns(tag string) (PatternResource, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t := normalizeTag(tag)
+	for t != "" {
+		if name, ok := registry.patterns[t]; ok {
+			return PatternResource{Tag: tag, Name: name}, true
+		}
+		i := strings.LastIndexByte(t, '-')
+		if i < 0 {
+			break
+		}
+		t = t[:i]
+	}
+	return PatternResource{}, false
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
@@ -13,13 +13,20 @@ is quick and dirty right now.
 package locate
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/hyphenation"
+	"github.com/npillmayer/tyse/core/locate/resources"
 )
 
+// tracer traces to tracing key 'tyse.resources'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.resources")
+}
+
 func gtrootdir() string {
 	gtroot := os.Getenv("TYSEROOT")
 	if gtroot == "" {
@@ -33,6 +40,26 @@ func gtrootdir() string {
 	return gtroot
 }
 
+// patternDir is a directory to load hyphenation patterns from, see SetPatternDir.
+var patternDir string
+
+// SetPatternDir sets the directory to load hyphenation patterns from. If dir is
+// empty, patterns are loaded from folder "pattern" of $TYSEROOT.
+func SetPatternDir(dir string) {
+	dictsMutex.Lock()
+	defer dictsMutex.Unlock()
+	patternDir = dir
+	dicts = nil
+}
+
+// patternDirectory returns the directory to load hyphenation patterns from.
+func patternDirectory() string {
+	if patternDir != "" {
+		return patternDir
+	}
+	return filepath.Join(gtrootdir(), "pattern")
+}
+
 // Return path for a resource file
 func FileResource(item string, typ string) string {
 	gtroot := gtrootdir()
@@ -43,25 +70,32 @@ func FileResource(item string, typ string) string {
 	case "font":
 		path = filepath.Join(os.Getenv("HOME"), "Library", "Fonts", item)
 	case "pattern":
-		path = filepath.Join(gtroot, "pattern", item)
+		path = filepath.Join(patternDirectory(), item)
 		//path = "/Users/npi/prg/go/gotype/etc/" + item
 	}
 	return path
 }
 
 var dicts map[string]*hyphenation.Dictionary
+var dictsMutex sync.Mutex
 
+// Dictionary returns the hyphenation dictionary for a language, e.g. "en_US".
+// Patterns are loaded from the pattern directory (see SetPatternDir) or, if not
+// present there, downloaded and cached (see resources.LoadDictionary).
+// If no patterns are available for loc, nil is returned.
 func Dictionary(loc string) *hyphenation.Dictionary {
+	dictsMutex.Lock()
+	defer dictsMutex.Unlock()
 	if dicts == nil {
 		dicts = make(map[string]*hyphenation.Dictionary)
 	}
-	if dicts[loc] == nil {
-		pname := "hyph-en-us.tex"
-		d := hyphenation.LoadPatterns(FileResource(pname, "pattern"))
-		dicts[loc] = d
+	if d, ok := dicts[loc]; ok {
+		return d
 	}
-	if dicts[loc] == nil {
-		panic(fmt.Sprintf("No dictionnary found for %s", loc))
+	d, err := resources.LoadDictionary(loc, patternDirectory())
+	if err != nil {
+		tracer().Errorf("no hyphenation dictionary for %s: %v", loc, err)
 	}
-	return dicts[loc]
+	dicts[loc] = d // remember missing dictionaries, too
+	return d
 }
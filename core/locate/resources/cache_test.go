@@ -4,7 +4,6 @@ import (
 	"path"
 	"testing"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/testconfig"
 	"github.com/npillmayer/schuko/tracing"
 )
@@ -14,7 +13,7 @@ func TestCacheDownload(t *testing.T) {
 		"app-key": "tyse-test",
 	})
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracer().SetTraceLevel(tracing.LevelDebug)
 	//
 	cachedir, err := CacheDirPath("fonts")
 	if err != nil {
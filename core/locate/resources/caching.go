@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -14,17 +15,41 @@ import (
 // DownloadFile will download a url to a local file (usually located in the
 // user's cache directory).
 func DownloadCachedFile(filepath string, url string) error {
-	resp, err := http.Get(url)
+	return DownloadCachedFileContext(context.Background(), filepath, url)
+}
+
+// DownloadCachedFileContext is like DownloadCachedFile, but the download may be
+// cancelled by ctx. Incomplete downloads are removed, so they will not be mistaken
+// for cached files later.
+func DownloadCachedFileContext(ctx context.Context, filepath string, url string) error {
+	out, err := os.Create(filepath)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	out, err := os.Create(filepath)
+	if err = download(ctx, url, out); err != nil {
+		out.Close()
+		os.Remove(filepath)
+		return err
+	}
+	return out.Close()
+}
+
+// download writes the resource at url to w. Responses with a status other than
+// 200 OK are reported as errors.
+func download(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-	_, err = io.Copy(out, resp.Body)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return core.Error(resp.StatusCode, "response: %v", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
 	return err
 }
 
@@ -40,7 +65,7 @@ func DownloadCachedFile(filepath string, url string) error {
 func CacheDirPath(subfolders ...string) (string, error) {
 	tracer().Debugf("config[%s] = %s", "app-key", gconf.GetString("app-key"))
 	if gconf.GetString("app-key") == "" {
-		return "", core.WrapError(errors.New("application key is not set"), core.EMISSING,
+		return "", core.WrapErrorIn(errors.New("application key is not set"), core.ErrResource, core.EMISSING,
 			"application key is not configured; need to set it for cache access")
 	}
 	cachedir, err := os.UserCacheDir()
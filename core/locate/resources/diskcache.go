@@ -0,0 +1,261 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Persistent cache ------------------------------------------------------
+
+// Cache is a persistent cache for artifacts which are expensive to produce, e.g.
+// fonts downloaded from the Google Fonts service, lists of fonts or derived data.
+// Artifacts are kept as files in a cache directory and survive the application.
+//
+// Entries are addressed by a key, usually the URL an artifact is downloaded from,
+// plus a checksum of its source, e.g. a version tag or the checksum of an input
+// file (see Checksum). An entry is valid if it has been stored with the same
+// source checksum, is not older than the cache's maximum age, and its contents
+// are intact. Invalid entries are treated as missing.
+//
+// A Cache is safe for concurrent use within a process.
+type Cache struct {
+	dir    string
+	maxAge time.Duration
+	mu     sync.Mutex
+}
+
+// cacheMeta holds the metadata of a cache entry, stored beside the artifact.
+type cacheMeta struct {
+	Key      string    `json:"key"`
+	Source   string    `json:"source,omitempty"` // checksum of the artifact's source
+	Checksum string    `json:"checksum"`         // checksum of the artifact
+	Created  time.Time `json:"created"`
+}
+
+// OpenCache opens a cache in a folder of the user's cache directory (see
+// CacheDirPath).
+func OpenCache(subfolders ...string) (*Cache, error) {
+	dir, err := CacheDirPath(subfolders...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCache(dir)
+}
+
+// NewCache opens a cache located in directory dir, creating it if necessary.
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// SetMaxAge sets the time entries stay valid. Entries older than d are treated
+// as missing. d = 0 lets entries stay valid forever, which is the default.
+func (c *Cache) SetMaxAge(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxAge = d
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Checksum returns a checksum of data, suitable for source checksums of cache
+// entries.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileChecksum returns the checksum of the contents of a file.
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// entry returns the path names of the artifact and of the metadata for key.
+func (c *Cache) entry(key string) (data, meta string) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:16])
+	if ext := filepath.Ext(key); len(ext) > 1 && len(ext) <= 5 && !strings.ContainsAny(ext, "/?&=") {
+		name += ext // keep extensions, e.g. for font files
+	}
+	return filepath.Join(c.dir, name), filepath.Join(c.dir, name+".meta")
+}
+
+// lookup returns the path of the artifact for key, if the entry is valid.
+// It has to be called with c.mu held.
+func (c *Cache) lookup(key, source string) (string, bool) {
+	datapath, metapath := c.entry(key)
+	b, err := os.ReadFile(metapath)
+	if err != nil {
+		return "", false
+	}
+	var meta cacheMeta
+	if err = json.Unmarshal(b, &meta); err != nil || meta.Key != key {
+		return "", false
+	}
+	if meta.Source != source {
+		tracer().Debugf("cache entry %s is outdated", key)
+		return "", false
+	}
+	if c.maxAge > 0 && time.Since(meta.Created) > c.maxAge {
+		tracer().Debugf("cache entry %s has expired", key)
+		return "", false
+	}
+	if sum, err := FileChecksum(datapath); err != nil || sum != meta.Checksum {
+		tracer().Infof("cache entry %s is corrupt, removing it", key)
+		c.remove(key)
+		return "", false
+	}
+	return datapath, true
+}
+
+// Get returns the artifact stored for key and a source checksum, if present and
+// valid.
+func (c *Cache) Get(key, source string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	datapath, ok := c.lookup(key, source)
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(datapath)
+	return data, err == nil
+}
+
+// Put stores an artifact for key and a source checksum, replacing an existing
+// entry.
+func (c *Cache) Put(key, source string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.store(key, source, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	return err
+}
+
+// store writes an artifact produced by write, together with its metadata, and
+// returns the path of the artifact. Artifacts are written to temporary files
+// first, so incomplete artifacts never show up as entries.
+// It has to be called with c.mu held.
+func (c *Cache) store(key, source string, write func(io.Writer) error) (string, error) {
+	datapath, metapath := c.entry(key)
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+	h := sha256.New()
+	if err = write(io.MultiWriter(tmp, h)); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	meta, err := json.Marshal(cacheMeta{
+		Key:      key,
+		Source:   source,
+		Checksum: hex.EncodeToString(h.Sum(nil)),
+		Created:  time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), datapath); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(metapath, meta, 0644); err != nil {
+		os.Remove(datapath)
+		return "", err
+	}
+	tracer().Debugf("cached %s as %s", key, datapath)
+	return datapath, nil
+}
+
+// Fetch returns the artifact stored for key and a source checksum. If the cache
+// holds no valid entry, the artifact is produced by calling produce and stored.
+func (c *Cache) Fetch(key, source string, produce func() ([]byte, error)) ([]byte, error) {
+	if data, ok := c.Get(key, source); ok {
+		return data, nil
+	}
+	data, err := produce()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Put(key, source, data); err != nil {
+		tracer().Errorf("cannot cache %s: %v", key, err)
+	}
+	return data, nil
+}
+
+// FetchFile is like Fetch, but returns the path of the artifact's file. The
+// artifact is written by write, if the cache holds no valid entry. Clients must
+// not modify the file.
+func (c *Cache) FetchFile(key, source string, write func(io.Writer) error) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if datapath, ok := c.lookup(key, source); ok {
+		return datapath, nil
+	}
+	return c.store(key, source, write)
+}
+
+// Invalidate removes the entry for key, if any.
+func (c *Cache) Invalidate(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remove(key)
+}
+
+func (c *Cache) remove(key string) error {
+	datapath, metapath := c.entry(key)
+	err := os.Remove(metapath)
+	if e := os.Remove(datapath); err == nil {
+		err = e
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Clear removes all entries of the cache. Other files in the cache directory are
+// left untouched.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metas, err := filepath.Glob(filepath.Join(c.dir, "*.meta"))
+	if err != nil {
+		return err
+	}
+	for _, metapath := range metas {
+		for _, p := range []string{strings.TrimSuffix(metapath, ".meta"), metapath} {
+			if err = os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package resources
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestDiskCachePutGet(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	cache, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "https://example.com/fonts/Font-regular.ttf"
+	if _, ok := cache.Get(key, "v1"); ok {
+		t.Errorf("expected empty cache to miss")
+	}
+	if err = cache.Put(key, "v1", []byte("font")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := cache.Get(key, "v1"); !ok || string(data) != "font" {
+		t.Errorf("expected cache to hit with 'font', have %q, %v", data, ok)
+	}
+	if _, ok := cache.Get(key, "v2"); ok {
+		t.Errorf("expected entry to be outdated for a different source")
+	}
+	datapath, _ := cache.entry(key)
+	if err = os.WriteFile(datapath, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(key, "v1"); ok {
+		t.Errorf("expected corrupt entry to miss")
+	}
+	if _, err = os.Stat(datapath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected corrupt entry to be removed")
+	}
+}
+
+func TestDiskCacheFetch(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	cache, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	produced := 0
+	produce := func() ([]byte, error) {
+		produced++
+		return []byte("list"), nil
+	}
+	for i := 0; i < 2; i++ {
+		if data, err := cache.Fetch("list", "", produce); err != nil || string(data) != "list" {
+			t.Fatalf("expected 'list', have %q, %v", data, err)
+		}
+	}
+	if produced != 1 {
+		t.Errorf("expected artifact to be produced once, was produced %d times", produced)
+	}
+	cache.SetMaxAge(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cache.Fetch("list", "", produce)
+	if produced != 2 {
+		t.Errorf("expected expired artifact to be produced again")
+	}
+	cache.SetMaxAge(0)
+	errFailed := errors.New("failed")
+	_, err = cache.FetchFile("file", "", func(w io.Writer) error {
+		w.Write([]byte("incomplete"))
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("expected FetchFile to fail, have %v", err)
+	}
+	if _, ok := cache.Get("file", ""); ok {
+		t.Errorf("expected incomplete artifact not to be cached")
+	}
+}
+
+func TestDiskCacheInvalidate(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	cache, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put("a", "", []byte("a"))
+	cache.Put("b", "", []byte("b"))
+	if err = cache.Invalidate("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get("a", ""); ok {
+		t.Errorf("expected invalidated entry to miss")
+	}
+	if _, ok := cache.Get("b", ""); !ok {
+		t.Errorf("expected entry b to hit")
+	}
+	if err = cache.Invalidate("a"); err != nil {
+		t.Errorf("expected invalidating a missing entry to succeed, have %v", err)
+	}
+	if err = cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(cache.Dir()); len(entries) != 0 {
+		t.Errorf("expected cache to be empty after Clear, has %d files", len(entries))
+	}
+}
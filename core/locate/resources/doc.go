@@ -11,6 +11,17 @@ will return a resource-specific promise type, which the client will call later
 to receive the loaded resource. The call to the promise-function will then block
 until loading has completed.
 
+All promises implement interface Promise and keep the outcome of loading,
+including errors, so they may be awaited more than once. WaitAll awaits a list
+of promises and aggregates the errors of failed loads. Resolve… functions take
+options to limit the time for loading and to retry failed loads (see
+ResolveOption).
+
+Downloaded and derived artifacts, e.g. fonts from the Google Fonts service,
+are kept in a persistent Cache in the user's cache directory. Cache entries are
+checked against a checksum of their source and of their contents, and may
+expire or be invalidated explicitly.
+
 License
 
 Governed by a 3-Clause BSD license. License file may be found in the root
@@ -44,7 +44,7 @@ func cacheFontConfigList(conf schuko.Configuration, update bool) (string, bool)
 		if _, err = os.Stat(dir); os.IsNotExist(err) {
 			err = os.MkdirAll(dir, 0755)
 			if err != nil {
-				err = core.WrapError(err, core.EINVALID,
+				err = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 					"user configuration path cannot be created: %s", dir)
 				core.UserError(err)
 				return "", false
@@ -56,12 +56,12 @@ func cacheFontConfigList(conf schuko.Configuration, update bool) (string, bool)
 		return "", false
 	}
 	if !path.IsAbs(fcpath) {
-		err = core.Error(core.EINVALID, "fontconfig binary fc-list must point to absolute path: %s", fcpath)
+		err = core.ErrorIn(core.ErrResource, core.EINVALID, "fontconfig binary fc-list must point to absolute path: %s", fcpath)
 		core.UserError(err)
 		return "", false
 	}
 	if fi, err := os.Stat(fcpath); err != nil || (fi.Mode().Perm()&0100) == 0 {
-		err = core.WrapError(err, core.EINVALID,
+		err = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 			"fontconfig configuration points to an invalid binary: %s", fcpath)
 		core.UserError(err)
 		return "", false
@@ -73,7 +73,7 @@ func cacheFontConfigList(conf schuko.Configuration, update bool) (string, bool)
 		err = fccmd.Run()
 	}
 	if err != nil {
-		err = core.WrapError(err, core.EINVALID,
+		err = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 			"fontconfig output file cannot be created: %s", fcListFilename)
 		core.UserError(err)
 		return "", false
@@ -88,7 +88,7 @@ func loadFontConfigList(conf schuko.Configuration) ([]font.Descriptor, bool) {
 	}
 	fc, err := os.Open(fclist)
 	if err != nil {
-		err = core.WrapError(err, core.EINVALID,
+		err = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 			"fontconfig font list cannot be opened: %s", fclist)
 		core.UserError(err)
 		return []font.Descriptor{}, false
@@ -133,7 +133,7 @@ func loadFontConfigList(conf schuko.Configuration) ([]font.Descriptor, bool) {
 		fontConfigDescriptors = append(fontConfigDescriptors, desc)
 	}
 	if err = scanner.Err(); err != nil {
-		err = core.WrapError(err, core.EINVALID,
+		err = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 			"encountered a problem during reading of fontconfig font list: %s", fclist)
 		core.UserError(err)
 		return fontConfigDescriptors, false
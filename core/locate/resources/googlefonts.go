@@ -1,16 +1,18 @@
 package resources
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
-	"path"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/npillmayer/schuko"
 	"github.com/npillmayer/schuko/gconf"
@@ -48,7 +50,7 @@ func setupGoogleFontsDirectory() error {
 		if apikey == "" {
 			err := errors.New("Google API key not set")
 			tracer().Errorf(err.Error())
-			googleFontsLoadError = core.WrapError(err, core.EMISSING,
+			googleFontsLoadError = core.WrapErrorIn(err, core.ErrResource, core.EMISSING,
 				`Google Fonts API-key must be set in global configuration or as GOOGLE_API_KEY in environment;
       please refer to https://developers.google.com/fonts/docs/developer_api`)
 			return
@@ -57,27 +59,19 @@ func setupGoogleFontsDirectory() error {
 			"sort": []string{"alpha"},
 			"key":  []string{apikey},
 		}
-		resp, err := http.Get(googleFontsAPI + values.Encode())
+		data, err := fetchGoogleFontsDirectory(googleFontsAPI, values)
 		if err != nil {
 			tracer().Errorf("Google Fonts API request not OK: %s", err.Error())
-			googleFontsLoadError = core.WrapError(err, core.ECONNECTION,
+			googleFontsLoadError = core.WrapErrorIn(err, core.ErrResource, core.ECONNECTION,
 				"could not get fonts-diretory from Google font service")
 			return
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			tracer().Errorf("Google Fonts API request not OK: %v", resp.Status)
-			err := core.Error(resp.StatusCode, "response: %v", resp.Status)
-			googleFontsLoadError = core.WrapError(err, core.ECONNECTION,
-				"could not get fonts-diretory from Google font service")
-			return
-		}
-		dec := json.NewDecoder(resp.Body)
-		err = dec.Decode(&googleFontsDirectory)
+		err = json.Unmarshal(data, &googleFontsDirectory)
 		if err != nil {
 			tracer().Errorf("Google Fonts API response not decoded: %v", err)
-			googleFontsLoadError = core.WrapError(err, core.EINVALID,
+			googleFontsLoadError = core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 				"could not decode fonts-list from Google font service")
+			return
 		}
 		tracer().Infof("transfered list of %d font from Google Fonts service",
 			len(googleFontsDirectory.Items))
@@ -85,6 +79,28 @@ func setupGoogleFontsDirectory() error {
 	return googleFontsLoadError
 }
 
+// googleFontsDirectoryMaxAge is the time a list of fonts from the Google Fonts
+// service is kept in the cache.
+const googleFontsDirectoryMaxAge = 24 * time.Hour
+
+// fetchGoogleFontsDirectory gets the list of fonts from the Google Fonts service,
+// or from the cache if it has been downloaded recently. The API key is not part
+// of the cache key, so it will not be written to disk.
+func fetchGoogleFontsDirectory(api string, values url.Values) ([]byte, error) {
+	produce := func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := download(context.Background(), api+values.Encode(), &buf)
+		return buf.Bytes(), err
+	}
+	cache, err := OpenCache("google")
+	if err != nil {
+		tracer().Infof("cannot cache list of Google fonts: %v", err)
+		return produce()
+	}
+	cache.SetMaxAge(googleFontsDirectoryMaxAge)
+	return cache.Fetch(api+"sort="+values.Get("sort"), "", produce)
+}
+
 // FindGoogleFont scans the Google Font Service for fonts matching `pattern` and
 // having a given style and weight.
 //
@@ -103,7 +119,7 @@ func FindGoogleFont(conf schuko.Configuration, pattern string, style xfont.Style
 	}
 	r, err := regexp.Compile(strings.ToLower(pattern))
 	if err != nil {
-		return fi, core.WrapError(err, core.EINVALID,
+		return fi, core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
 			"cannot match Google font: invalid font name pattern: %v", err)
 	}
 	//trace().Debugf("trying to match (%s)", strings.ToLower(pattern))
@@ -131,6 +147,12 @@ func FindGoogleFont(conf schuko.Configuration, pattern string, style xfont.Style
 // CacheGoogleFont loads a font described by fi with a given variant.
 // The loaded font is cached in the user's cache directory.
 func CacheGoogleFont(fi GoogleFontInfo, variant string) (filepath string, err error) {
+	return CacheGoogleFontContext(context.Background(), fi, variant)
+}
+
+// CacheGoogleFontContext is like CacheGoogleFont, but downloading the font may be
+// cancelled by ctx.
+func CacheGoogleFontContext(ctx context.Context, fi GoogleFontInfo, variant string) (filepath string, err error) {
 	var fileurl string
 	for _, v := range fi.Variants {
 		if v == variant {
@@ -141,22 +163,19 @@ func CacheGoogleFont(fi GoogleFontInfo, variant string) (filepath string, err er
 		return "", fmt.Errorf("no variant equals %s, cannot cache %s", variant, fi.Family)
 	}
 	letter := strings.ToUpper(fi.Family[:1])
-	cachedir, err := CacheDirPath("fonts", letter)
+	cache, err := OpenCache("fonts", letter)
 	if err != nil {
 		return "", err
 	}
-	ext := path.Ext(fileurl)
-	name := fi.Family + "-" + variant + ext
-	filepath = path.Join(cachedir, name)
-	tracer().Infof("caching font %s as %s", fi.Family, filepath)
-	if _, err := os.Stat(filepath); err == nil {
-		tracer().Infof("font already cached: %s", filepath)
-	} else {
-		err = DownloadCachedFile(filepath, fileurl)
-		if err != nil {
-			return "", err
-		}
+	// cached fonts are replaced when Google publishes a new version of the font
+	filepath, err = cache.FetchFile(fileurl, fi.Version, func(w io.Writer) error {
+		tracer().Infof("downloading font %s-%s", fi.Family, variant)
+		return download(ctx, fileurl, w)
+	})
+	if err != nil {
+		return "", err
 	}
+	tracer().Infof("font %s-%s cached as %s", fi.Family, variant, filepath)
 	return filepath, nil
 }
 
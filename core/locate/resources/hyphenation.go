@@ -0,0 +1,159 @@
+package resources
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/npillmayer/tyse/core"
+	"github.com/npillmayer/tyse/core/hyphenation"
+)
+
+// LoadDictionary loads the hyphenation dictionary for a BCP 47 language tag, e.g.
+// "en-US" (see hyphenation.LookupPatterns).
+//
+// Pattern files are searched for in directory dir first, either as plain text
+// (hyph-en-us.pat.txt and hyph-en-us.hyp.txt) or as a TeX pattern file
+// (hyph-en-us.tex). If dir is empty or does not hold the patterns, they are
+// downloaded from hyphenation.PatternSource into the user's cache directory.
+//
+// Compiled dictionaries are cached as well, keyed by the checksums of the pattern
+// files, so patterns have to be compiled only once, unless pattern files change.
+func LoadDictionary(tag string, dir string) (*hyphenation.Dictionary, error) {
+	return LoadDictionaryContext(context.Background(), tag, dir)
+}
+
+// LoadDictionaryContext is like LoadDictionary, but downloading patterns may be
+// cancelled by ctx.
+func LoadDictionaryContext(ctx context.Context, tag string, dir string) (*hyphenation.Dictionary, error) {
+	res, ok := hyphenation.LookupPatterns(tag)
+	if !ok {
+		return nil, core.ErrorIn(core.ErrResource, core.EMISSING,
+			"no hyphenation patterns known for language %q", tag)
+	}
+	cache, err := OpenCache("patterns")
+	if err != nil {
+		tracer().Infof("cannot cache hyphenation patterns: %v", err)
+		cache = nil
+	}
+	var files []string // pattern file and, optionally, exceptions file
+	if path, ok := localFile(dir, res.PatternFile()); ok {
+		files = append(files, path)
+		if path, ok := localFile(dir, res.ExceptionsFile()); ok {
+			files = append(files, path)
+		}
+	} else if path, ok := localFile(dir, res.TeXFile()); ok {
+		files = append(files, path) // TeX pattern files include exceptions
+	} else if cache == nil {
+		return nil, core.ErrorIn(core.ErrResource, core.EMISSING,
+			"hyphenation patterns %s not found", res.PatternFile())
+	} else {
+		if files, err = downloadPatterns(ctx, cache, res); err != nil {
+			return nil, err
+		}
+	}
+	return compileDictionary(cache, res, files)
+}
+
+// localFile returns the path of file in directory dir, if it exists.
+func localFile(dir string, file string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	path := filepath.Join(dir, file)
+	if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+		return "", false
+	}
+	return path, true
+}
+
+// downloadPatterns gets the pattern file and the exceptions file for res, either
+// from the cache or from hyphenation.PatternSource.
+func downloadPatterns(ctx context.Context, cache *Cache, res hyphenation.PatternResource) ([]string, error) {
+	url := res.URL(res.PatternFile())
+	path, err := cache.FetchFile(url, "", func(w io.Writer) error {
+		tracer().Infof("downloading hyphenation patterns %s", url)
+		return download(ctx, url, w)
+	})
+	if err != nil {
+		return nil, core.WrapErrorIn(err, core.ErrResource, core.ECONNECTION,
+			"cannot download hyphenation patterns %s", res.PatternFile())
+	}
+	files := []string{path}
+	url = res.URL(res.ExceptionsFile())
+	path, err = cache.FetchFile(url, "", func(w io.Writer) error {
+		err := download(ctx, url, w)
+		if core.Code(err) == http.StatusNotFound {
+			return nil // no exceptions for this language; cache an empty list
+		}
+		return err
+	})
+	if err != nil {
+		tracer().Infof("cannot download hyphenation exceptions %s: %v", res.ExceptionsFile(), err)
+		return files, nil
+	}
+	return append(files, path), nil
+}
+
+// compileDictionary reads patterns and, optionally, exceptions from files and
+// compiles them into a dictionary. Compiled dictionaries are kept in cache, if
+// cache is not nil.
+func compileDictionary(cache *Cache, res hyphenation.PatternResource, files []string) (*hyphenation.Dictionary, error) {
+	sums := make([]string, len(files))
+	for i, file := range files {
+		sum, err := FileChecksum(file)
+		if err != nil {
+			return nil, core.WrapErrorIn(err, core.ErrResource, core.EMISSING,
+				"cannot read hyphenation patterns %s", file)
+		}
+		sums[i] = sum
+	}
+	key, source := "hyphenation:"+res.Name, strings.Join(sums, ":")
+	if cache != nil {
+		if data, ok := cache.Get(key, source); ok {
+			dict := &hyphenation.Dictionary{}
+			if err := dict.UnmarshalBinary(data); err == nil {
+				tracer().Debugf("using compiled hyphenation patterns for %s", res.Tag)
+				return dict, nil
+			}
+		}
+	}
+	dict, err := readDictionary(res, files)
+	if err != nil {
+		return nil, core.WrapErrorIn(err, core.ErrResource, core.EINVALID,
+			"cannot read hyphenation patterns for %s", res.Tag)
+	}
+	if cache != nil {
+		data, err := dict.MarshalBinary()
+		if err == nil {
+			err = cache.Put(key, source, data)
+		}
+		if err != nil {
+			tracer().Errorf("cannot cache compiled hyphenation patterns: %v", err)
+		}
+	}
+	return dict, nil
+}
+
+// readDictionary reads patterns from files[0] and exceptions from files[1], if
+// present.
+func readDictionary(res hyphenation.PatternResource, files []string) (*hyphenation.Dictionary, error) {
+	f, err := os.Open(files[0])
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dict, err := hyphenation.ReadPatterns(f, "patterns: "+res.Name)
+	if err != nil || len(files) < 2 {
+		return dict, err
+	}
+	x, err := os.Open(files[1])
+	if err != nil {
+		return nil, err
+	}
+	defer x.Close()
+	return dict, dict.ReadExceptions(x)
+}
@@ -0,0 +1,183 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"image"
+	_ "image/jpeg" // register JPEG format for image.Decode
+	_ "image/png"  // register PNG format for image.Decode
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core"
+	"github.com/npillmayer/tyse/core/dimen"
+)
+
+// --- Pictures --------------------------------------------------------------
+
+// Picture is an image loaded for an element of a document, e.g. for `<img>`.
+// Raster images (PNG and JPEG) are decoded, whereas SVG images are kept as their
+// source, for backends to render.
+type Picture struct {
+	Format string      // "png", "jpeg" or "svg"
+	Image  image.Image // decoded raster image, nil for SVG
+	SVG    []byte      // source of an SVG image
+	Size   dimen.Point // intrinsic size
+}
+
+// AspectRatio returns the intrinsic ratio of width to height of a picture, or 0 if
+// a picture has no intrinsic height.
+func (pic *Picture) AspectRatio() float64 {
+	if pic == nil || pic.Size.Y == 0 {
+		return 0
+	}
+	return float64(pic.Size.X) / float64(pic.Size.Y)
+}
+
+// PicturePromise loads a picture in the background. A call to `Picture` will
+// block until loading is completed. `PictureContext` will block until loading
+// is completed or ctx is cancelled, whichever happens first.
+type PicturePromise interface {
+	Promise
+	Picture() (*Picture, error)
+	PictureContext(ctx context.Context) (*Picture, error)
+}
+
+type pictureLoader struct {
+	*future[*Picture]
+}
+
+func (loader pictureLoader) Await(ctx context.Context) error {
+	_, err := loader.await(ctx)
+	return err
+}
+
+func (loader pictureLoader) Picture() (*Picture, error) {
+	return loader.await(context.Background())
+}
+
+func (loader pictureLoader) PictureContext(ctx context.Context) (*Picture, error) {
+	return loader.await(ctx)
+}
+
+// ResolvePicture loads a picture from the file system. path is usually the value
+// of an attribute `src`. Pixels of raster images are taken to measure 1 CSS px.
+// SVG images are sized from attributes `width` and `height` or from their view
+// box, with a default of 300×150 px.
+//
+// Pictures are not returned synchronously, but rather as a promise
+// of kind PicturePromise (async/await-pattern).
+func ResolvePicture(path string, opts ...ResolveOption) PicturePromise {
+	return ResolvePictureContext(context.Background(), path, opts...)
+}
+
+// ResolvePictureContext is like ResolvePicture, but loading may be cancelled by ctx.
+func ResolvePictureContext(ctx context.Context, path string, opts ...ResolveOption) PicturePromise {
+	return pictureLoader{launch(ctx, opts, func(context.Context) (*Picture, error) {
+		data, err := os.ReadFile(strings.TrimPrefix(path, "file://"))
+		if err != nil {
+			tracer().Errorf("cannot read image %s: %v", path, err)
+			return nil, notFound(path, imageResourceType)
+		}
+		return DecodePicture(data)
+	})}
+}
+
+// DecodePicture decodes the contents of an image file. Supported formats are
+// PNG, JPEG and SVG.
+func DecodePicture(data []byte) (*Picture, error) {
+	if isSVG(data) {
+		size, err := svgSize(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Picture{Format: "svg", SVG: data, Size: size}, nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, core.WrapErrorIn(err, core.ErrResource, core.EINVALID, "cannot decode image: %v", err)
+	}
+	b := img.Bounds()
+	return &Picture{
+		Format: format,
+		Image:  img,
+		Size:   dimen.Point{X: dimen.DU(b.Dx()) * dimen.PX, Y: dimen.DU(b.Dy()) * dimen.PX},
+	}, nil
+}
+
+func isSVG(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("<svg"))
+}
+
+// svgSize finds the intrinsic size of an SVG image from the attributes of its
+// root element.
+func svgSize(data []byte) (dimen.Point, error) {
+	size := dimen.Point{X: 300 * dimen.PX, Y: 150 * dimen.PX}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return size, core.WrapErrorIn(err, core.ErrResource, core.EINVALID, "cannot decode SVG image: %v", err)
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root.Name.Local != "svg" {
+			return size, core.ErrorIn(core.ErrResource, core.EINVALID, "SVG image has root element <%s>", root.Name.Local)
+		}
+		var w, h dimen.DU
+		var hasW, hasH bool
+		for _, attr := range root.Attr {
+			switch attr.Name.Local {
+			case "width":
+				w, hasW = svgLength(attr.Value)
+			case "height":
+				h, hasH = svgLength(attr.Value)
+			case "viewBox":
+				if vb := strings.Fields(strings.ReplaceAll(attr.Value, ",", " ")); len(vb) == 4 {
+					vw, errw := strconv.ParseFloat(vb[2], 64)
+					vh, errh := strconv.ParseFloat(vb[3], 64)
+					if errw == nil && errh == nil && vw > 0 && vh > 0 {
+						size = dimen.Point{X: dimen.DU(vw * float64(dimen.PX)), Y: dimen.DU(vh * float64(dimen.PX))}
+					}
+				}
+			}
+		}
+		switch { // a missing dimension keeps the aspect ratio of the view box
+		case hasW && hasH:
+			size = dimen.Point{X: w, Y: h}
+		case hasW:
+			size = dimen.Point{X: w, Y: size.Y.Scale(int64(w), int64(size.X))}
+		case hasH:
+			size = dimen.Point{X: size.X.Scale(int64(h), int64(size.Y)), Y: h}
+		}
+		return size, nil
+	}
+}
+
+// svgLength parses an absolute SVG length. Percentages are not supported.
+func svgLength(s string) (dimen.DU, bool) {
+	s = strings.TrimSpace(s)
+	unit := dimen.PX
+	for _, u := range []struct {
+		suffix string
+		unit   dimen.DU
+	}{{"px", dimen.PX}, {"pt", dimen.PT}, {"mm", dimen.MM}, {"cm", dimen.CM}, {"in", dimen.IN}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSuffix(s, u.suffix), u.unit
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return dimen.DU(v * float64(unit)), true
+}
@@ -0,0 +1,214 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Promises --------------------------------------------------------------
+
+// Promise is implemented by all resource promises (see ImagePromise, TypeCasePromise
+// and PicturePromise). Await blocks until loading of a resource has completed or
+// ctx is cancelled, whichever happens first, and returns the error of loading, if
+// any. Promises may be awaited more than once.
+type Promise interface {
+	Await(ctx context.Context) error
+}
+
+// future holds the outcome of loading a resource in the background. The outcome
+// is kept, so a future may be awaited any number of times.
+type future[T any] struct {
+	done chan struct{} // closed when loading has completed
+	val  T
+	err  error
+}
+
+func newFuture[T any]() *future[T] {
+	return &future[T]{done: make(chan struct{})}
+}
+
+// resolve sets the outcome of loading. It must be called exactly once.
+func (f *future[T]) resolve(val T, err error) {
+	f.val, f.err = val, err
+	close(f.done)
+}
+
+// await blocks until f is resolved or ctx is cancelled.
+func (f *future[T]) await(ctx context.Context) (T, error) {
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case <-f.done:
+		return f.val, f.err
+	}
+}
+
+// completed returns the outcome of loading without blocking; ok is false if loading
+// has not completed yet.
+func (f *future[T]) completed() (val T, err error, ok bool) {
+	select {
+	case <-f.done:
+		return f.val, f.err, true
+	default:
+		return val, nil, false
+	}
+}
+
+// WaitAll awaits a list of promises. It returns nil if all resources have been
+// loaded successfully, and LoadErrors otherwise. If ctx is cancelled, promises
+// not completed so far fail with the error of ctx.
+func WaitAll(ctx context.Context, promises []Promise) error {
+	var errs LoadErrors
+	for i, p := range promises {
+		if p == nil {
+			continue
+		}
+		if err := p.Await(ctx); err != nil {
+			errs = append(errs, LoadError{Index: i, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// LoadError is the error of a promise awaited with WaitAll.
+type LoadError struct {
+	Index int   // index of the promise
+	Err   error // error of loading the resource
+}
+
+func (e LoadError) Error() string {
+	return fmt.Sprintf("resource #%d: %v", e.Index, e.Err)
+}
+
+func (e LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadErrors aggregates the errors of promises awaited with WaitAll, in order of
+// promises. errors.Is and errors.As will match any of the errors.
+type LoadErrors []LoadError
+
+func (errs LoadErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d resources failed to load: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+func (errs LoadErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, e := range errs {
+		unwrapped[i] = e
+	}
+	return unwrapped
+}
+
+// --- Resolve options -------------------------------------------------------
+
+// ResolveOption configures the loading of a resource by one of the Resolve…
+// functions.
+type ResolveOption func(*resolveConfig)
+
+type resolveConfig struct {
+	timeout time.Duration
+	retry   RetryPolicy
+}
+
+// WithTimeout limits the time for loading a resource, including retries. If loading
+// takes longer, the promise fails with context.DeadlineExceeded.
+func WithTimeout(d time.Duration) ResolveOption {
+	return func(rc *resolveConfig) {
+		rc.timeout = d
+	}
+}
+
+// WithRetry sets a policy for retrying failed loads, e.g. of fonts downloaded from
+// the Google Fonts service.
+func WithRetry(policy RetryPolicy) ResolveOption {
+	return func(rc *resolveConfig) {
+		rc.retry = policy
+	}
+}
+
+// RetryPolicy tells how often and when to retry loading a resource. The zero value
+// loads a resource just once.
+type RetryPolicy struct {
+	Attempts  int              // maximum number of attempts, including the first one
+	Backoff   time.Duration    // delay before the first retry, doubled for every further retry
+	Retryable func(error) bool // errors to retry on; nil to retry on every error
+}
+
+// retryable returns true if another attempt should be made after attempt
+// number attempt has failed with err.
+func (policy RetryPolicy) retryable(attempt int, err error) bool {
+	if attempt >= policy.Attempts {
+		return false
+	}
+	return policy.Retryable == nil || policy.Retryable(err)
+}
+
+// launch loads a resource in the background, following options opts, and returns
+// a future for the outcome.
+func launch[T any](ctx context.Context, opts []ResolveOption, load func(context.Context) (T, error)) *future[T] {
+	rc := resolveConfig{}
+	for _, opt := range opts {
+		opt(&rc)
+	}
+	f := newFuture[T]()
+	go func() {
+		ctx := ctx
+		if rc.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rc.timeout)
+			defer cancel()
+		}
+		backoff := rc.retry.Backoff
+		for attempt := 1; ; attempt++ {
+			val, err := within(ctx, load)
+			if err == nil || ctx.Err() != nil || !rc.retry.retryable(attempt, err) {
+				f.resolve(val, err)
+				return
+			}
+			tracer().Infof("loading resource failed, retrying: %v", err)
+			select {
+			case <-ctx.Done():
+				f.resolve(val, ctx.Err())
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}()
+	return f
+}
+
+// within calls load, but returns early with the error of ctx if ctx is cancelled
+// before load returns.
+func within[T any](ctx context.Context, load func(context.Context) (T, error)) (T, error) {
+	type outcome struct {
+		val T
+		err error
+	}
+	ch := make(chan outcome, 1) // buffered, as load may outlive ctx
+	go func() {
+		val, err := load(ctx)
+		ch <- outcome{val, err}
+	}()
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case o := <-ch:
+		return o.val, o.err
+	}
+}
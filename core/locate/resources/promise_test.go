@@ -0,0 +1,77 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+)
+
+func TestPromiseRetry(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	errFlaky := errors.New("flaky")
+	attempts := 0
+	load := func(context.Context) (int, error) {
+		if attempts++; attempts < 3 {
+			return 0, errFlaky
+		}
+		return 42, nil
+	}
+	f := launch(context.Background(), []ResolveOption{WithRetry(RetryPolicy{Attempts: 3})}, load)
+	if v, err := f.await(context.Background()); err != nil || v != 42 {
+		t.Errorf("expected 42 after 3 attempts, have %d, %v", v, err)
+	}
+	attempts = 0
+	f = launch(context.Background(), []ResolveOption{WithRetry(RetryPolicy{
+		Attempts:  3,
+		Retryable: func(err error) bool { return !errors.Is(err, errFlaky) },
+	})}, load)
+	if _, err := f.await(context.Background()); !errors.Is(err, errFlaky) || attempts != 1 {
+		t.Errorf("expected a single attempt to fail, have %d attempts, %v", attempts, err)
+	}
+}
+
+func TestPromiseTimeout(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	block := make(chan struct{})
+	defer close(block)
+	f := launch(context.Background(), []ResolveOption{WithTimeout(10 * time.Millisecond)},
+		func(context.Context) (int, error) {
+			<-block // ignores ctx
+			return 1, nil
+		})
+	if _, err := f.await(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected loading to time out, error is %v", err)
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	path := filepath.Join(t.TempDir(), "pic.svg")
+	if err := os.WriteFile(path, []byte(`<svg width="20" height="10"></svg>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := ResolvePicture(filepath.Join(t.TempDir(), "missing.png"))
+	promises := []Promise{ResolvePicture(path), missing, ResolvePicture(path)}
+	err := WaitAll(context.Background(), promises)
+	var errs LoadErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 1 {
+		t.Fatalf("expected promise #1 to fail, error is %v", err)
+	}
+	if _, err = missing.Picture(); err == nil {
+		t.Errorf("expected failed promise to keep its error")
+	}
+	if err = WaitAll(context.Background(), promises[:1]); err != nil {
+		t.Errorf("expected picture to be loaded, error is %v", err)
+	}
+}
@@ -1,11 +1,18 @@
 package resources
 
 import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/npillmayer/schuko/schukonf/testconfig"
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/core/font/fontregistry"
 	xfont "golang.org/x/image/font"
 )
@@ -26,6 +33,63 @@ func TestLoadImage(t *testing.T) {
 	t.Logf("width of image = %d", w)
 }
 
+func TestResolvePicture(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pic.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pic, err := ResolvePicture(path).Picture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic.Format != "png" || pic.Size != (dimen.Point{X: 40 * dimen.PX, Y: 30 * dimen.PX}) {
+		t.Errorf("expected PNG of 40×30 px, have %s of %v", pic.Format, pic.Size)
+	}
+	if _, err = ResolvePicture(filepath.Join(t.TempDir(), "missing.png")).Picture(); err == nil {
+		t.Errorf("expected missing picture to be flagged")
+	}
+}
+
+func TestDecodePicture(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "resources")
+	defer teardown()
+	//
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	pic, err := DecodePicture(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic.Format != "jpeg" || pic.AspectRatio() != 0.5 {
+		t.Errorf("expected JPEG with aspect ratio 0.5, have %s with %v", pic.Format, pic.AspectRatio())
+	}
+	for _, tc := range []struct {
+		svg  string
+		size dimen.Point
+	}{
+		{`<svg xmlns="http://www.w3.org/2000/svg"/>`, dimen.Point{X: 300 * dimen.PX, Y: 150 * dimen.PX}},
+		{`<svg width="2in" height="10mm"></svg>`, dimen.Point{X: 2 * dimen.IN, Y: 10 * dimen.MM}},
+		{`<?xml version="1.0"?><svg viewBox="0 0 100 50" width="50pt"></svg>`, dimen.Point{X: 50 * dimen.PT, Y: 25 * dimen.PT}},
+	} {
+		pic, err := DecodePicture([]byte(tc.svg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pic.Format != "svg" || pic.Size != tc.size {
+			t.Errorf("expected SVG of size %v, have %s of %v", tc.size, pic.Format, pic.Size)
+		}
+	}
+}
+
 func TestLoadPackagedFont(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "resources")
 	defer teardown()
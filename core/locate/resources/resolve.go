@@ -40,7 +40,7 @@ func notFound(res string, rtype resourceType) error {
 	default:
 		s = fmt.Sprintf("resource not found: %s", res)
 	}
-	err := core.WrapError(e, core.EMISSING, s)
+	err := core.WrapErrorIn(e, core.ErrResource, core.EMISSING, s)
 	return err
 }
 
@@ -49,100 +49,119 @@ var packaged embed.FS
 
 // --- Images ---------------------------------------------------------------
 
-type imgPlusErr struct {
-	img image.Image
-	err error
+// ResolveImage currently will only search for images packaged with the
+// application. If no image matches, a placeholder image is loaded, and the
+// promise carries an error as well.
+func ResolveImage(name string, resolution string, opts ...ResolveOption) ImagePromise {
+	return ResolveImageContext(context.Background(), name, resolution, opts...)
 }
 
-// ResolveImage currently will only search for images packaged with the
-// application.
-func ResolveImage(name string, resolution string) ImagePromise {
-	ch := make(chan imgPlusErr)
-	go func(ch chan<- imgPlusErr) {
-		result := imgPlusErr{}
-		images, _ := packaged.ReadDir("packaged/images")
-		var imagename string
-		for _, image := range images {
-			//T().Debugf("image file %s", image.Name())
-			if image.Name() == name {
+// ResolveImageContext is like ResolveImage, but loading may be cancelled by ctx.
+func ResolveImageContext(ctx context.Context, name string, resolution string, opts ...ResolveOption) ImagePromise {
+	return imageLoader{launch(ctx, opts, func(context.Context) (image.Image, error) {
+		return loadPackagedImage(name, resolution)
+	})}
+}
+
+func loadPackagedImage(name string, resolution string) (img image.Image, err error) {
+	images, _ := packaged.ReadDir("packaged/images")
+	var imagename string
+	for _, image := range images {
+		//T().Debugf("image file %s", image.Name())
+		if image.Name() == name {
+			imagename = image.Name()
+			break
+		}
+		if strings.HasPrefix(image.Name(), name+"-") {
+			if strings.HasSuffix(image.Name(), resolution) {
 				imagename = image.Name()
 				break
 			}
-			if strings.HasPrefix(image.Name(), name+"-") {
-				if strings.HasSuffix(image.Name(), resolution) {
-					imagename = image.Name()
-					break
-				}
-			}
 		}
-		if imagename == "" {
-			imagename = "packaged/images/placeholder.png"
-			result.err = notFound(name, imageResourceType)
-		}
-		file, err := packaged.Open("packaged/images/" + imagename)
-		if err != nil {
-			result.err = err
-		} else {
-			defer file.Close()
-			result.img, err = png.Decode(file)
-			if err != nil {
-				result.err = err
-			}
-		}
-		ch <- result
-		close(ch)
-	}(ch)
-	return imageLoader{
-		await: func(ctx context.Context) (image.Image, error) {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case r := <-ch:
-				return r.img, r.err
-			}
-		},
 	}
+	if imagename == "" {
+		imagename = "placeholder.png"
+		err = notFound(name, imageResourceType)
+	}
+	file, e := packaged.Open("packaged/images/" + imagename)
+	if e != nil {
+		return nil, e
+	}
+	defer file.Close()
+	if img, e = png.Decode(file); e != nil {
+		return nil, e
+	}
+	return img, err
 }
 
 // ImagePromise loads an image in the background. A call to `Image` will block
-// until loading is completed.
+// until loading is completed. `ImageContext` will block until loading is
+// completed or ctx is cancelled, whichever happens first.
 type ImagePromise interface {
+	Promise
 	Image() (image.Image, error)
+	ImageContext(ctx context.Context) (image.Image, error)
 }
 
 type imageLoader struct {
-	await func(ctx context.Context) (image.Image, error)
+	*future[image.Image]
+}
+
+func (loader imageLoader) Await(ctx context.Context) error {
+	_, err := loader.await(ctx)
+	return err
 }
 
 func (loader imageLoader) Image() (image.Image, error) {
 	return loader.await(context.Background())
 }
 
+func (loader imageLoader) ImageContext(ctx context.Context) (image.Image, error) {
+	return loader.await(ctx)
+}
+
 // --- Fonts -----------------------------------------------------------------
 
-type fontPlusErr struct {
+// resolvedFont is the outcome of resolving a font.
+type resolvedFont struct {
 	font *font.TypeCase
 	desc font.Descriptor
-	err  error
 }
 
 // TypeCasePromise runs font location asynchronously in the background.
 // A call to `TypeCase()` blocks until font loading is completed.
+// `TypeCaseContext(ctx)` blocks until font loading is completed or ctx is
+// cancelled, whichever happens first.
 type TypeCasePromise interface {
+	Promise
 	TypeCase() (*font.TypeCase, error)
+	TypeCaseContext(ctx context.Context) (*font.TypeCase, error)
 	Descriptor() font.Descriptor // descriptor of typecase to load, before and after
 }
 
 type fontLoader struct {
-	await func(ctx context.Context) (*font.TypeCase, error)
-	desc  font.Descriptor
+	*future[resolvedFont]
+	desc font.Descriptor // descriptor before loading
+}
+
+func (loader fontLoader) Await(ctx context.Context) error {
+	_, err := loader.await(ctx)
+	return err
 }
 
 func (loader fontLoader) TypeCase() (*font.TypeCase, error) {
-	return loader.await(context.Background())
+	return loader.TypeCaseContext(context.Background())
+}
+
+func (loader fontLoader) TypeCaseContext(ctx context.Context) (*font.TypeCase, error) {
+	r, err := loader.await(ctx)
+	return r.font, err
 }
 
 func (loader fontLoader) Descriptor() font.Descriptor {
+	if r, _, ok := loader.completed(); ok && r.desc.Family != "" {
+		return r.desc
+	}
 	return loader.desc
 }
 
@@ -178,106 +197,113 @@ func (loader fontLoader) Descriptor() font.Descriptor {
 //
 // Typecases are not returned synchronously, but rather as a promise
 // of kind TypeCasePromise (async/await-pattern).
-func ResolveTypeCase(conf schuko.Configuration, pattern string, style xfont.Style, weight xfont.Weight, size float32) TypeCasePromise {
-	// TODO include a context parameter
+func ResolveTypeCase(conf schuko.Configuration, pattern string, style xfont.Style, weight xfont.Weight, size float32,
+	opts ...ResolveOption) TypeCasePromise {
+	//
+	return ResolveTypeCaseContext(context.Background(), conf, pattern, style, weight, size, opts...)
+}
+
+// ResolveTypeCaseContext is like ResolveTypeCase, but resolution may be cancelled
+// by ctx. If ctx is cancelled before a font has been found, searching for system
+// fonts and Google fonts is skipped, and the promise delivers the error of ctx.
+// Downloads of Google fonts are cancelled as well.
+func ResolveTypeCaseContext(ctx context.Context, conf schuko.Configuration, pattern string,
+	style xfont.Style, weight xfont.Weight, size float32, opts ...ResolveOption) TypeCasePromise {
+	//
 	desc := font.Descriptor{
 		Family: pattern,
 	}
-	ch := make(chan fontPlusErr)
-	go func(ch chan<- fontPlusErr) {
-		result := fontPlusErr{
-			desc: desc,
-		}
-		name := fontregistry.NormalizeFontname(pattern, style, weight)
-		if t, err := fontregistry.GlobalRegistry().TypeCase(name, size); err == nil {
-			result.font = t
-			result.desc.Family = t.ScalableFontParent().Fontname
-			ch <- result
-			close(ch)
-			return
+	return fontLoader{
+		future: launch(ctx, opts, func(ctx context.Context) (resolvedFont, error) {
+			return resolveTypeCase(ctx, conf, pattern, style, weight, size)
+		}),
+		desc: desc,
+	}
+}
+
+// resolveTypeCase is the work horse of ResolveTypeCase.
+func resolveTypeCase(ctx context.Context, conf schuko.Configuration, pattern string,
+	style xfont.Style, weight xfont.Weight, size float32) (result resolvedFont, err error) {
+	//
+	result.desc.Family = pattern
+	name := fontregistry.NormalizeFontname(pattern, style, weight)
+	if t, err := fontregistry.GlobalRegistry().TypeCase(name, size); err == nil {
+		result.font = t
+		result.desc.Family = t.ScalableFontParent().Fontname
+		return result, nil
+	}
+	var f *font.ScalableFont
+	fonts, _ := packaged.ReadDir("packaged/fonts")
+	var fname string // path to embedded font, if any
+	for _, f := range fonts {
+		if fontregistry.Matches(f.Name(), pattern, style, weight) {
+			tracer().Debugf("found embedded font file %s", f.Name())
+			fname = f.Name()
+			break
 		}
-		var f *font.ScalableFont
-		fonts, _ := packaged.ReadDir("packaged/fonts")
-		var fname string // path to embedded font, if any
-		for _, f := range fonts {
-			if fontregistry.Matches(f.Name(), pattern, style, weight) {
-				tracer().Debugf("found embedded font file %s", f.Name())
-				fname = f.Name()
-				break
+	}
+	if fname != "" { // font is packaged embedded font
+		var file fs.File
+		file, err = packaged.Open("packaged/fonts/" + fname)
+		if err == nil {
+			defer file.Close()
+			bytez, _ := io.ReadAll(file)
+			if f, err = font.ParseOpenTypeFont(bytez); err == nil {
+				result.desc.Family = fname
+				name = fname
 			}
 		}
-		if fname != "" { // font is packaged embedded font
-			var file fs.File
-			file, result.err = packaged.Open("packaged/fonts/" + fname)
-			if result.err == nil {
-				defer file.Close()
-				bytez, _ := io.ReadAll(file)
-				if f, result.err = font.ParseOpenTypeFont(bytez); result.err == nil {
-					result.desc.Family = fname
-					name = fname
-				}
-			}
-			if f == nil { // cannot process embedded font => seriously compromised installation
-				result.err = core.WrapError(result.err, core.EINTERNAL,
-					"internal application error - packaged font not readable: %s", fname)
-				ch <- result
-				close(ch)
-				return
-			}
+		if f == nil { // cannot process embedded font => seriously compromised installation
+			return result, core.WrapErrorIn(err, core.ErrResource, core.EINTERNAL,
+				"internal application error - packaged font not readable: %s", fname)
 		}
-		if f == nil { // next try system fonts
-			if desc, _ := FindLocalFont(conf, pattern, style, weight); desc.Family != "" {
-				f, result.err = font.LoadOpenTypeFont(desc.Path)
-			}
+	}
+	if f == nil && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if f == nil { // next try system fonts
+		if desc, _ := FindLocalFont(conf, pattern, style, weight); desc.Family != "" {
+			f, err = font.LoadOpenTypeFont(desc.Path)
 		}
-		if f == nil { // next try Google font service
-			var fiList []GoogleFontInfo
-			if fiList, result.err = FindGoogleFont(conf, pattern, style, weight); result.err == nil {
-				var l []font.Descriptor
-				for _, finfo := range fiList { // morph Google font info font font.Descriptor list
-					l = append(l, finfo.Descriptor)
-				}
-				desc, variant, confidence := fontregistry.ClosestMatch(l, pattern, style, weight)
-				if confidence > fontregistry.LowConfidence {
-					var fpath string
-					var i int
-					for j, d := range fiList { // find matching variant again
-						if d.Descriptor.Family == desc.Family {
-							i = j // this must succeed
-						}
-					}
-					if fpath, result.err = CacheGoogleFont(fiList[i], variant); result.err == nil {
-						f, result.err = font.LoadOpenTypeFont(fpath)
-						name = path.Base(fpath)
-						result.desc.Family = name
+	}
+	if f == nil && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if f == nil { // next try Google font service
+		var fiList []GoogleFontInfo
+		if fiList, err = FindGoogleFont(conf, pattern, style, weight); err == nil {
+			var l []font.Descriptor
+			for _, finfo := range fiList { // morph Google font info font font.Descriptor list
+				l = append(l, finfo.Descriptor)
+			}
+			desc, variant, confidence := fontregistry.ClosestMatch(l, pattern, style, weight)
+			if confidence > fontregistry.LowConfidence {
+				var fpath string
+				var i int
+				for j, d := range fiList { // find matching variant again
+					if d.Descriptor.Family == desc.Family {
+						i = j // this must succeed
 					}
 				}
+				if fpath, err = CacheGoogleFontContext(ctx, fiList[i], variant); err == nil {
+					f, err = font.LoadOpenTypeFont(fpath)
+					name = fiList[i].Family + "-" + variant + path.Ext(fpath)
+					result.desc.Family = name
+				}
 			}
 		}
-		if f != nil { // if found, enter into font registry
-			f.Fontname = name
-			fontregistry.GlobalRegistry().StoreFont(name, f)
-			result.font, result.err = fontregistry.GlobalRegistry().TypeCase(name, size)
-			result.desc.Family = name
-			//font.GlobalRegistry().DebugList()
-		} else { // use fallback font
-			result.font, _ = fontregistry.GlobalRegistry().TypeCase("fallback", size)
-			result.desc.Family = "fallback"
-		}
-		ch <- result
-		close(ch)
-	}(ch)
-	loader := fontLoader{desc: desc}
-	loader.await = func(ctx context.Context) (*font.TypeCase, error) {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case r := <-ch:
-			loader.desc = r.desc
-			return r.font, r.err
-		}
 	}
-	return loader
+	if f != nil { // if found, enter into font registry
+		f.Fontname = name
+		fontregistry.GlobalRegistry().StoreFont(name, f)
+		result.font, err = fontregistry.GlobalRegistry().TypeCase(name, size)
+		result.desc.Family = name
+		//font.GlobalRegistry().DebugList()
+	} else { // use fallback font
+		result.font, _ = fontregistry.GlobalRegistry().TypeCase("fallback", size)
+		result.desc.Family = "fallback"
+	}
+	return result, err
 }
 
 // FindLocalFont searches for a locally installed font variant.
@@ -1,50 +1,52 @@
 /*
-Package option implements an experimental option type.
+Package option implements an option type.
 
 Handling the infinite number of complicated rules in HTML and CSS I tend
-to miss option types and matching. Without generics the implementation
-in this package unfortunately is quite hacky and all-in-all defeats the
-purpose of Go to be a simple, typesafe language. However, for now I'll prefer
-a concise notation to type safety in some cases related to CSS styling and
-layout. Let's see where it leads us.
+to miss option types and matching. With generics, an option type is
+straightforward: Option[T] either holds a value of type T or is unset.
 
-As soon as generics will be part of Go, I'll rewrite this package (However,
-I suspect someone else will quickly come up with an implementation of an option type,
-or it even will find its way into the standard lib).
-Clients probably should not use this package for now, except for experimental purposes.
+	x := option.SomeOf(42)
+	y := option.NoneOf[int]()
 
-The Int64T type is kind of a blueprint for other implementations of optional types,
-i.e. types implementing the option.Type interface.
+	y.IsNone()     // => true
+	x.UnwrapOr(7)  // => 42
 
-	x := option.SomeInt64(42)
+Options are transformed with Map and AndThen, and may be matched with MatchOption:
 
-	msg, _ = x.Match(option.Maybe{
-		option.None: "attention: x is unset",
-		option.Some: strcnv.Itoa(x.Unwrap(), 10),
+	msg := option.MatchOption(x, option.Patterns[int, string]{
+		None: func() string { return "attention: x is unset" },
+		Some: strconv.Itoa,
 	})
 
 will yield a `msg` of
 
-    "42"
+	"42"
 
-whereas an unset option will be matching `option.None`:
+whereas an unset option will be matching `None`:
 
-    x := option.Int64()
-    x.IsNone()  // => true
+	"attention: x is unset"
 
-    "attention: x is unset"
+To match concrete values, use MatchValue:
 
-To match concrete values, use `option.Of{…}`
+	msg = option.MatchValue(x, map[int]string{
+		42: "best answer",
+	}, option.Patterns[int, string]{
+		None: func() string { return "no answer" },
+		Some: func(int) string { return "sub-par answer" },
+	})
+
+# Legacy option types
+
+Before generics were available, this package implemented option types with
+empty interfaces, e.g. Int64T, together with matching on maps (option.Of and
+option.Maybe), with keys option.None, option.Some and option.Error. These are
+deprecated and will be removed in a future release; until then they keep
+their names, which is why the generic constructors are called SomeOf and
+NoneOf. Legacy options may be converted with their Option() method.
 
-	msg, _ = x.Match(option.Of{
-		option.None: "no answer",
-		42:          "best answer",
-		…
-		option.Some: "sub-par answer",
-	}
 __________________________________________________________________________
 
-BSD 3-Clause License
+# BSD 3-Clause License
 
 Copyright (c) 2020–21, Norbert Pillmayer
 All rights reserved.
@@ -1,254 +1,147 @@
 package option
 
-import (
-	"errors"
-	"math"
-	"strconv"
-)
-
-var ErrNoSuchMatchPattern = errors.New("no such match pattern")
-var ErrCannotMatchUnsetValue = errors.New("cannot match unset value")
-var ErrCannotMatchValue = errors.New("cannot match value")
-
-type MaybeOption int
-
-const (
-	None MaybeOption = iota
-	Some
-	Error
-)
-
-// Maybe is a type used for matching of optional types.
-// It will match `Some` if a value is set, `None` if it is unset, or `Error`
-// if an error occurs.
-type Maybe map[MaybeOption]interface{}
-
-// Of is a type used for matching of option-types.
-// It will first try to match concrete values, and in case of no match will
-// then try a Maybe match.
-type Of map[interface{}]interface{}
-
-//type expr func(interface{}) func(interface{}, MaybeOption) interface{}
-
-// Type is a type for option values.
-type Type interface {
-	Match(choices interface{}) (interface{}, error)
-	Equals(other interface{}) bool
-	IsNone() bool
-	//Expr(interface{}) expr
-}
-
-// Match will do a standard matching of o against choices.
-// It may be used to create a new type of interface OptionT.
-//
-// choices are expected to be a map type, where keys of the map are either
-// concrete values for o, or of type MaybeOption. Values of the map may be
-// of any type.
-//
-// If choices is of unknown kind, nil and ErrNoSuchMatchPattern are returned.
-//
-func Match(o Type, choices interface{}) (value interface{}, err error) {
-	switch c := choices.(type) {
-	case Of:
-		return c.Match(o)
-	case Maybe:
-		return c.Match(o)
-	}
-	return nil, ErrNoSuchMatchPattern
-}
-
-func (of Of) Match(o Type) (value interface{}, err error) {
-	tracer().Debugf("Match(Type=%T) for %T", of, o)
-	if o.IsNone() {
-		tracer().Debugf("o is None")
-		if expr, ok := of[None]; ok {
-			tracer().Debugf("matched nil expr=%T %v", expr, expr)
-			value, err = valueOrExpr(expr, o, None)
-		} else {
-			err = ErrCannotMatchUnsetValue
-		}
-	} else {
-		err = ErrCannotMatchValue
-		matched := false
-		for k, expr := range of {
-			if o.Equals(k) {
-				matched = true
-				tracer().Debugf("matched expr=%T %v", expr, expr)
-				value, err = valueOrExpr(expr, o, Some)
-			}
-		}
-		if !matched {
-			if expr, ok := of[Some]; ok {
-				tracer().Debugf("matched some expr=%T %v", expr, expr)
-				value, err = valueOrExpr(expr, o, Some)
-			}
-		}
-		if err != nil {
-			tracer().Errorf(err.Error())
-			if expr, ok := of[Error]; ok {
-				value, err = valueOrExpr(expr, o, Error)
-			}
-		}
-	}
-	tracer().Debugf("===> return %v (%T) with error=%v", value, value, err)
-	return value, err
-}
-
-func (maybe Maybe) Match(o Type) (value interface{}, err error) {
-	tracer().Debugf("Match(Type=%T) for %T", maybe, o)
-	if o.IsNone() {
-		tracer().Debugf("o is None")
-		if expr, ok := maybe[None]; ok {
-			tracer().Debugf("matched nil expr=%T %v", expr, expr)
-			value, err = valueOrExpr(expr, o, None)
-		} else {
-			err = ErrCannotMatchUnsetValue
-		}
-	} else {
-		if expr, ok := maybe[Some]; ok {
-			tracer().Debugf("matched some expr=%T %v", expr, expr)
-			value, err = valueOrExpr(expr, o, Some)
-		}
-		if err != nil {
-			tracer().Errorf(err.Error())
-			if expr, ok := maybe[Error]; ok {
-				value, err = valueOrExpr(expr, o, Error)
-			}
-		}
-	}
-	tracer().Debugf("===> return %v (%T) with error=%v", value, value, err)
-	return value, err
-}
-
-func valueOrExpr(op interface{}, value Type, t MaybeOption) (interface{}, error) {
-	tracer().Debugf("value or expr %v(%v), t=%v", op, value, t)
-	switch x := op.(type) {
-	case func(interface{}, MaybeOption) (interface{}, error):
-		tracer().Debugf("calling func(value, type)")
-		return x(value, t)
-	case func(interface{}) (interface{}, error):
-		tracer().Debugf("calling func(value)")
-		return x(value)
-	}
-	return op, nil
+import "fmt"
+
+// Option is an optional value of type T. The zero value is an unset option.
+type Option[T any] struct {
+	value T
+	ok    bool
 }
 
-// Fail may be used as an option case, causing a Match to fail with an error.
-// The error will be returned by Match(…), unless caught with an option.Error
-// label.
-//
-//     _, err := o.Match(option.Of{
-//          option.None: …,
-//          99:          option.Fail(errors.New("99 is illegal")),
-//          option.Some: …,
-//     })
-//
-func Fail(err error) func(interface{}) (interface{}, error) {
-	localErr := err
-	return func(interface{}) (interface{}, error) {
-		return nil, localErr
-	}
+// SomeOf creates an option with a value of x.
+func SomeOf[T any](x T) Option[T] {
+	return Option[T]{value: x, ok: true}
 }
 
-// Safe wraps a Match's return values and drops the error value.
-func Safe(x interface{}, err error) interface{} {
-	return x
+// NoneOf creates an option without a value.
+func NoneOf[T any]() Option[T] {
+	return Option[T]{}
 }
 
-// WrapResult wraps the result of a function call, which must return a (value, error)
-// tuple.
-//
-// Attention: the wrapped call will be executed independently of the matching option.
-// Therefore it must not have side effects and should execute quickly.
-// Because of this “trap” this function will likely be dropped.
-//
-func WrapResult(x interface{}, err error) func(interface{}) (interface{}, error) {
-	localX := x
-	localErr := err
-	return func(interface{}) (interface{}, error) {
-		return localX, localErr
+// FromPointer creates an option from a pointer, which is unset for nil.
+func FromPointer[T any](p *T) Option[T] {
+	if p == nil {
+		return NoneOf[T]()
 	}
+	return SomeOf(*p)
 }
 
-// --- Int64T-----------------------------------------------------------------
-
-// Int64T is an option type for int64.
-type Int64T int64
-
-// Int64None is used as an in-band null value for type int64 for optional integers.
-const Int64None int64 = math.MaxInt64
-
-// SomeInt64 creates an optional int64 with an initial value of x.
-func SomeInt64(x int) Int64T {
-	return Int64T(x)
+// IsSome returns true if o has a value.
+func (o Option[T]) IsSome() bool {
+	return o.ok
 }
 
-// Int64 creates an optional int64 without an initial value.
-func Int64() Int64T {
-	return Int64T(Int64None)
+// IsNone returns true if o is unset.
+func (o Option[T]) IsNone() bool {
+	return !o.ok
 }
 
-func (o Int64T) Match(choices interface{}) (value interface{}, err error) {
-	return Match(o, choices)
+// Get returns the value of o and a flag indicating if o is set.
+//
+//	if x, ok := o.Get(); ok {
+//	    …
+//	}
+func (o Option[T]) Get() (T, bool) {
+	return o.value, o.ok
 }
 
-func (o Int64T) Equals(other interface{}) bool {
-	tracer().Debugf("EQUALS %v ? %v", o, other)
-	switch i := other.(type) {
-	case int64:
-		return int64(o) == i
-	case int32:
-		return int64(o) == int64(i)
-	case int:
-		return int64(o) == int64(i)
+// Unwrap returns the value of o. It panics if o is unset.
+func (o Option[T]) Unwrap() T {
+	if !o.ok {
+		panic("option: unwrap of unset option")
 	}
-	return false
+	return o.value
 }
 
-func (o Int64T) Unwrap() int64 {
-	return int64(o)
+// UnwrapOr returns the value of o, or x if o is unset.
+func (o Option[T]) UnwrapOr(x T) T {
+	if !o.ok {
+		return x
+	}
+	return o.value
 }
 
-// IsNone returns true if o is unset.
-func (o Int64T) IsNone() bool {
-	return o == Int64T(Int64None)
+// OrElse returns o if it is set, and the result of f otherwise.
+func (o Option[T]) OrElse(f func() Option[T]) Option[T] {
+	if !o.ok {
+		return f()
+	}
+	return o
 }
 
-func (o Int64T) String() string {
-	if o.IsNone() {
-		return "Int64.None"
+// Filter returns o if it is set and its value satisfies pred, and an unset
+// option otherwise.
+func (o Option[T]) Filter(pred func(T) bool) Option[T] {
+	if o.ok && pred(o.value) {
+		return o
 	}
-	return strconv.FormatInt(int64(o), 10)
+	return NoneOf[T]()
 }
 
-// --- reference types -------------------------------------------------------
-
-type RefT struct {
-	ref interface{}
+func (o Option[T]) String() string {
+	if !o.ok {
+		return "None"
+	}
+	return fmt.Sprintf("Some(%v)", o.value)
 }
 
-func (o RefT) Equals(other interface{}) bool {
-	return o.ref == other
+// Map applies f to the value of o, if set.
+func Map[T, U any](o Option[T], f func(T) U) Option[U] {
+	if !o.ok {
+		return NoneOf[U]()
+	}
+	return SomeOf(f(o.value))
 }
 
-func (o RefT) IsNone() bool {
-	return o.ref == nil
+// AndThen applies f to the value of o, if set. Other than with Map, f returns an
+// option itself, which allows chaining of operations which may fail.
+func AndThen[T, U any](o Option[T], f func(T) Option[U]) Option[U] {
+	if !o.ok {
+		return NoneOf[U]()
+	}
+	return f(o.value)
 }
 
-func (o RefT) Unwrap() interface{} {
-	return o.ref
-}
+// --- Matching --------------------------------------------------------------
 
-func Something(x interface{}) RefT {
-	return RefT{ref: x}
+// Patterns is a set of cases for matching an option, see MatchOption.
+// Cases left nil yield the zero value of R.
+type Patterns[T, R any] struct {
+	None func() R
+	Some func(T) R
 }
 
-func Nothing() RefT {
-	return RefT{ref: nil}
+// MatchOption matches o against patterns and returns the result of the case
+// selected.
+//
+//	msg := option.MatchOption(x, option.Patterns[int, string]{
+//	    None: func() string { return "attention: x is unset" },
+//	    Some: strconv.Itoa,
+//	})
+func MatchOption[T, R any](o Option[T], patterns Patterns[T, R]) (r R) {
+	switch {
+	case o.ok && patterns.Some != nil:
+		r = patterns.Some(o.value)
+	case !o.ok && patterns.None != nil:
+		r = patterns.None()
+	}
+	return
 }
 
-func (o RefT) Match(choices interface{}) (value interface{}, err error) {
-	return Match(o, choices)
+// MatchValue matches o against concrete values first. If the value of o is not a
+// key of values, MatchValue falls back to patterns.
+//
+//	answer := option.MatchValue(x, map[int]string{
+//	    42: "best answer",
+//	}, option.Patterns[int, string]{
+//	    None: func() string { return "no answer" },
+//	    Some: func(int) string { return "sub-par answer" },
+//	})
+func MatchValue[T comparable, R any](o Option[T], values map[T]R, patterns Patterns[T, R]) R {
+	if o.ok {
+		if r, ok := values[o.value]; ok {
+			return r
+		}
+	}
+	return MatchOption(o, patterns)
 }
-
-var _ Type = RefT{}
@@ -3,9 +3,9 @@ package option_test
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/testconfig"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/option"
@@ -14,7 +14,7 @@ import (
 func TestOptionMaybe(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	var y1, y2, y3 interface{}
 	x := option.SomeInt64(42)
@@ -52,7 +52,7 @@ func TestOptionMaybe(t *testing.T) {
 func TestOptionOf(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	var y1 interface{}
 	x := option.SomeInt64(1)
@@ -72,7 +72,7 @@ func TestOptionOf(t *testing.T) {
 func TestOptionRef(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	var y1 interface{}
 	x := option.Something("hey")
@@ -92,7 +92,7 @@ func TestOptionRef(t *testing.T) {
 func TestOptionFail(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	x := option.SomeInt64(1)
 	t.Logf("x = %v, x.T = %T, x.unwrap = %v", x, x, x.Unwrap())
@@ -115,7 +115,7 @@ func TestOptionFail(t *testing.T) {
 func TestOptionWrap(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	x := option.SomeInt64(1)
 	t.Logf("x = %v, x.T = %T, x.unwrap = %v", x, x, x.Unwrap())
@@ -143,7 +143,7 @@ func TestOptionWrap(t *testing.T) {
 func TestOptionWrapError(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	tracing.Select("tyse.core").SetTraceLevel(tracing.LevelDebug)
 	//
 	x := option.SomeInt64(1)
 	t.Logf("x = %v, x.T = %T, x.unwrap = %v", x, x, x.Unwrap())
@@ -178,3 +178,68 @@ func nonsense(x interface{}) (interface{}, error) {
 func stringify(x interface{}) (interface{}, error) {
 	return fmt.Sprintf("Value = %v", x), nil
 }
+
+func TestGenericOption(t *testing.T) {
+	x := option.SomeOf(42)
+	y := option.NoneOf[int]()
+	if !x.IsSome() || !y.IsNone() {
+		t.Errorf("expected x to be set and y to be unset")
+	}
+	if x.UnwrapOr(7) != 42 || y.UnwrapOr(7) != 7 {
+		t.Errorf("expected UnwrapOr to respect set and unset options")
+	}
+	if v, ok := y.OrElse(func() option.Option[int] { return option.SomeOf(1) }).Get(); !ok || v != 1 {
+		t.Errorf("expected OrElse to replace unset option, is %d", v)
+	}
+	s := option.Map(x, strconv.Itoa)
+	if s.Unwrap() != "42" {
+		t.Errorf("expected mapped option to be \"42\", is %v", s)
+	}
+	half := func(n int) option.Option[int] {
+		if n%2 != 0 {
+			return option.NoneOf[int]()
+		}
+		return option.SomeOf(n / 2)
+	}
+	if h := option.AndThen(option.AndThen(x, half), half); h.IsSome() {
+		t.Errorf("expected 42/2/2 to fail, is %v", h)
+	}
+	if x.Filter(func(n int) bool { return n > 50 }).IsSome() {
+		t.Errorf("expected filter to drop 42")
+	}
+	if option.FromPointer[int](nil).IsSome() || x.String() != "Some(42)" || y.String() != "None" {
+		t.Errorf("unexpected conversion or string representation")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected unwrap of unset option to panic")
+		}
+	}()
+	y.Unwrap()
+}
+
+func TestGenericMatch(t *testing.T) {
+	patterns := option.Patterns[int, string]{
+		None: func() string { return "no answer" },
+		Some: func(int) string { return "sub-par answer" },
+	}
+	best := map[int]string{42: "best answer"}
+	for _, test := range []struct {
+		x      option.Option[int]
+		answer string
+	}{
+		{option.SomeOf(42), "best answer"},
+		{option.SomeOf(7), "sub-par answer"},
+		{option.NoneOf[int](), "no answer"},
+	} {
+		if a := option.MatchValue(test.x, best, patterns); a != test.answer {
+			t.Errorf("expected %v to match to %q, is %q", test.x, test.answer, a)
+		}
+	}
+	if msg := option.MatchOption(option.SomeOf(42), option.Patterns[int, string]{Some: strconv.Itoa}); msg != "42" {
+		t.Errorf("expected SomeOf(42) to match to \"42\", is %q", msg)
+	}
+	if legacy := option.SomeInt64(3).Option(); legacy.Unwrap() != 3 || option.Int64().Option().IsSome() {
+		t.Errorf("expected legacy options to convert to generic ones")
+	}
+}
@@ -29,6 +29,13 @@ const (
 	P_HYPHENCHAR
 	P_HYPHENPENALTY
 	P_MINHYPHENLENGTH
+	P_LEFTHYPHENMIN
+	P_RIGHTHYPHENMIN
+	P_HYPHENEXCEPTIONS
+	P_CJKPENALTY
+	P_LINEBREAKSTRICTNESS
+	P_WHITESPACE
+	P_NORMALIZATION
 	P_STOPPER
 )
 
@@ -62,6 +69,13 @@ func initParameters(p *[P_STOPPER]interface{}) {
 	p[P_HYPHENCHAR] = int('-')            // a rune
 	p[P_HYPHENPENALTY] = 0                // a numeric penalty (int)
 	p[P_MINHYPHENLENGTH] = dimen.Infinity // a numeric quantitiv (int) = # of runes
+	p[P_LEFTHYPHENMIN] = 0                // min # of runes before a hyphen (int); 0 for the language's default
+	p[P_RIGHTHYPHENMIN] = 0               // min # of runes after a hyphen (int); 0 for the language's default
+	p[P_HYPHENEXCEPTIONS] = nil           // user hyphenation exceptions (*hyphenation.Exceptions)
+	p[P_CJKPENALTY] = 0                   // penalty for breaks between CJK characters (int)
+	p[P_LINEBREAKSTRICTNESS] = "normal"   // kinsoku rules: "strict", "normal" or "loose"
+	p[P_WHITESPACE] = "normal"            // CSS white-space: "normal", "nowrap", "pre", "pre-wrap" or "pre-line"
+	p[P_NORMALIZATION] = "NFC"            // Unicode normalization form of text: "NFC" or "NFD"
 }
 
 func (regs *TypesettingRegisters) Begingroup() {
@@ -4,9 +4,9 @@ package parameters
 
 import "strconv"
 
-const _TypesettingParameter_name = "noneP_LANGUAGEP_SCRIPTP_TEXTDIRECTIONP_BASELINESKIPP_LINESKIPP_LINESKIPLIMITP_HYPHENPENALTYP_STOPPER"
+const _TypesettingParameter_name = "noneP_LANGUAGEP_SCRIPTP_TEXTDIRECTIONP_BASELINESKIPP_LINESKIPP_LINESKIPLIMITP_HYPHENCHARP_HYPHENPENALTYP_MINHYPHENLENGTHP_LEFTHYPHENMINP_RIGHTHYPHENMINP_HYPHENEXCEPTIONSP_CJKPENALTYP_LINEBREAKSTRICTNESSP_WHITESPACEP_NORMALIZATIONP_STOPPER"
 
-var _TypesettingParameter_index = [...]uint8{0, 4, 14, 22, 37, 51, 61, 76, 91, 100}
+var _TypesettingParameter_index = [...]uint8{0, 4, 14, 22, 37, 51, 61, 76, 88, 103, 120, 135, 151, 169, 181, 202, 214, 229, 238}
 
 func (i TypesettingParameter) String() string {
 	if i < 0 || i >= TypesettingParameter(len(_TypesettingParameter_index)-1) {
@@ -0,0 +1,33 @@
+/*
+Package spans records structured trace spans for the typesetting stages.
+
+Tracing with package schuko/tracing produces log lines, which is fine for
+debugging, but not very helpful for observing the latency of typesetting
+requests in a server deployment. Package spans lets the engine delimit units
+of work—a stage of the pipeline, a paragraph, a page, a run of text—as spans, which may be
+exported to an observability backend by plugging in a Recorder.
+
+By default spans are discarded. Clients wanting to observe spans set a recorder
+once during initialization:
+
+	spans.SetRecorder(myRecorder)
+
+Package otelspans contains a recorder exporting spans to OpenTelemetry,
+package timings a recorder measuring the durations of spans, which may be
+exported as JSON.
+
+Instrumenting code looks like this:
+
+	ctx, span := spans.Start(ctx, spans.Paragraph, "knuthplass.BreakParagraph")
+	defer span.End()
+	…
+	span.SetAttribute("lines", len(breakpoints))
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package spans
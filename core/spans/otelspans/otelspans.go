@@ -0,0 +1,105 @@
+/*
+Package otelspans exports the engine's trace spans to OpenTelemetry.
+
+Server deployments usually observe requests with an OpenTelemetry SDK and an
+APM backend. Setting a recorder from this package will make typesetting stages,
+paragraphs and pages show up as child spans of the request's span:
+
+	tp := sdktrace.NewTracerProvider(…)  // configured by the application
+	spans.SetRecorder(otelspans.NewRecorder(tp.Tracer("tyse")))
+
+Every span carries an attribute "tyse.span.kind" with a value of
+"stage", "paragraph", "page" or "run", to enable filtering.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package otelspans
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/spans"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// KindKey is the attribute key for the kind of a span.
+const KindKey = attribute.Key("tyse.span.kind")
+
+// Recorder is a spans.Recorder creating OpenTelemetry spans.
+type Recorder struct {
+	tracer trace.Tracer
+}
+
+var _ spans.Recorder = &Recorder{}
+
+// NewRecorder creates a span recorder, which starts spans with an OpenTelemetry
+// tracer.
+func NewRecorder(tracer trace.Tracer) *Recorder {
+	return &Recorder{tracer: tracer}
+}
+
+// Start starts an OpenTelemetry span. If ctx contains a span, the new span
+// will be a child of it.
+func (r *Recorder) Start(ctx context.Context, kind spans.Kind, name string) (context.Context, spans.Span) {
+	ctx, s := r.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(KindKey.String(kind.String())),
+	)
+	return ctx, span{s}
+}
+
+// span wraps an OpenTelemetry span.
+type span struct {
+	otel trace.Span
+}
+
+func (s span) SetAttribute(key string, value interface{}) {
+	s.otel.SetAttributes(keyValue(key, value))
+}
+
+func (s span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.otel.RecordError(err)
+	s.otel.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.otel.End()
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	k := attribute.Key(key)
+	switch v := value.(type) {
+	case string:
+		return k.String(v)
+	case bool:
+		return k.Bool(v)
+	case int:
+		return k.Int(v)
+	case int32:
+		return k.Int64(int64(v))
+	case int64:
+		return k.Int64(v)
+	case uint16:
+		return k.Int64(int64(v))
+	case uint32:
+		return k.Int64(int64(v))
+	case float32:
+		return k.Float64(float64(v))
+	case float64:
+		return k.Float64(v)
+	case fmt.Stringer:
+		return k.String(v.String())
+	}
+	return k.String(fmt.Sprintf("%v", value))
+}
@@ -0,0 +1,58 @@
+package otelspans
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/spans"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestChildOfRequestSpan(t *testing.T) {
+	spans.SetRecorder(NewRecorder(noop.NewTracerProvider().Tracer("tyse")))
+	defer spans.SetRecorder(nil)
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+	ctx, s := spans.Start(ctx, spans.Paragraph, "para")
+	defer s.End()
+	s.SetAttribute("lines", 3)
+	s.RecordError(errors.New("test error"))
+	if trace.SpanContextFromContext(ctx).TraceID() != parent.TraceID() {
+		t.Errorf("expected span to belong to the trace of the request span")
+	}
+	if sp, ok := s.(span); !ok || sp.otel.SpanContext().TraceID() != parent.TraceID() {
+		t.Errorf("expected OpenTelemetry span to be a child of the request span")
+	}
+}
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestKeyValue(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		typ   attribute.Type
+		str   string
+	}{
+		{"x", attribute.STRING, "x"},
+		{true, attribute.BOOL, "true"},
+		{7, attribute.INT64, "7"},
+		{uint16(7), attribute.INT64, "7"},
+		{1.5, attribute.FLOAT64, "1.5"},
+		{stringer{}, attribute.STRING, "stringer"},
+		{[]int{1}, attribute.STRING, "[1]"},
+	} {
+		kv := keyValue("k", test.value)
+		if kv.Value.Type() != test.typ || kv.Value.Emit() != test.str {
+			t.Errorf("%v: expected %v %q, have %v %q", test.value, test.typ, test.str,
+				kv.Value.Type(), kv.Value.Emit())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package spans
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Kind classifies the unit of work a span covers.
+type Kind uint8
+
+// Kinds of spans the engine records.
+const (
+	Stage     Kind = iota // a stage of the typesetting pipeline, e.g. font loading
+	Paragraph             // breaking a single paragraph into lines
+	Page                  // laying out or rendering a single page
+	Run                   // shaping a single run of text
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Stage:
+		return "stage"
+	case Paragraph:
+		return "paragraph"
+	case Page:
+		return "page"
+	case Run:
+		return "run"
+	}
+	return "unknown"
+}
+
+// Span is a unit of work in progress. Spans have to be ended by calling End,
+// usually in a defer statement.
+type Span interface {
+	SetAttribute(key string, value interface{}) // value should be a string, bool or number
+	RecordError(err error)                      // err may be nil
+	End()
+}
+
+// Recorder starts spans. A recorder is responsible for relating a new span to
+// a parent span contained in ctx, and for returning a context containing the
+// new span.
+type Recorder interface {
+	Start(ctx context.Context, kind Kind, name string) (context.Context, Span)
+}
+
+type recorderHolder struct {
+	recorder Recorder
+}
+
+var recorder atomic.Value // holds recorderHolder
+
+// SetRecorder sets the global span recorder. Setting it to nil disables
+// recording of spans, which is the default.
+func SetRecorder(r Recorder) {
+	recorder.Store(recorderHolder{recorder: r})
+}
+
+// Enabled returns true if a span recorder is set. Instrumented code may check this
+// to avoid computing expensive span attributes.
+func Enabled() bool {
+	h, _ := recorder.Load().(recorderHolder)
+	return h.recorder != nil
+}
+
+// Start starts a new span of a given kind. If ctx is nil, context.Background() is
+// assumed.
+// If no recorder is set, ctx is returned unchanged together with a span which
+// does nothing.
+func Start(ctx context.Context, kind Kind, name string) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h, _ := recorder.Load().(recorderHolder)
+	if h.recorder == nil {
+		return ctx, noSpan{}
+	}
+	return h.recorder.Start(ctx, kind, name)
+}
+
+// noSpan is a span which discards everything.
+type noSpan struct{}
+
+func (noSpan) SetAttribute(string, interface{}) {}
+func (noSpan) RecordError(error)                {}
+func (noSpan) End()                             {}
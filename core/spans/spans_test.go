@@ -0,0 +1,68 @@
+package spans
+
+import (
+	"context"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// testRecorder records the names and parents of started spans.
+type testRecorder struct {
+	started []string
+	parents []interface{}
+	ended   int
+}
+
+func (r *testRecorder) Start(ctx context.Context, kind Kind, name string) (context.Context, Span) {
+	r.started = append(r.started, kind.String()+":"+name)
+	r.parents = append(r.parents, ctx.Value(ctxKey{}))
+	return context.WithValue(ctx, ctxKey{}, name), testSpan{r}
+}
+
+type testSpan struct{ r *testRecorder }
+
+func (testSpan) SetAttribute(string, interface{}) {}
+func (testSpan) RecordError(error)                {}
+func (s testSpan) End()                           { s.r.ended++ }
+
+func TestNoRecorder(t *testing.T) {
+	SetRecorder(nil)
+	if Enabled() {
+		t.Errorf("expected span recording to be disabled by default")
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	c, span := Start(ctx, Stage, "test")
+	if c != ctx {
+		t.Errorf("expected context to be returned unchanged without a recorder")
+	}
+	span.SetAttribute("x", 1)
+	span.RecordError(nil)
+	span.End()
+	if c, _ := Start(nil, Page, "test"); c == nil {
+		t.Errorf("expected nil context to be replaced by background context")
+	}
+}
+
+func TestRecorderParents(t *testing.T) {
+	r := &testRecorder{}
+	SetRecorder(r)
+	defer SetRecorder(nil)
+	if !Enabled() {
+		t.Fatalf("expected span recording to be enabled")
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	ctx, stage := Start(ctx, Stage, "stage")
+	_, para := Start(ctx, Paragraph, "para")
+	para.End()
+	stage.End()
+	if len(r.started) != 2 || r.started[0] != "stage:stage" || r.started[1] != "paragraph:para" {
+		t.Errorf("unexpected spans started: %v", r.started)
+	}
+	if r.parents[0] != "request" || r.parents[1] != "stage" {
+		t.Errorf("expected spans to be children of enclosing spans, parents are %v", r.parents)
+	}
+	if r.ended != 2 {
+		t.Errorf("expected 2 spans to be ended, have %d", r.ended)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Package timings measures the durations of the engine's trace spans.
+
+For performance analysis of a typesetting run it is often sufficient to know
+where the time went, without setting up an observability backend. A recorder
+from this package keeps every span in memory, together with its start time,
+duration and parent span. After typesetting, timings may be exported as JSON:
+
+	rec := timings.NewRecorder()
+	spans.SetRecorder(rec)
+	…                   // typeset a document
+	spans.SetRecorder(nil)
+	err := rec.WriteJSON(w)
+
+The JSON document contains a list of spans in order of their start, and a
+summary of spans with equal kind and name, e.g. the total time spent shaping
+runs of text.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package timings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/npillmayer/tyse/core/spans"
+)
+
+// Timing is the measurement of a finished span. Times are in nanoseconds,
+// start times relative to the creation of the recorder.
+type Timing struct {
+	ID         int                    `json:"id"`
+	Parent     int                    `json:"parent,omitempty"` // ID of the parent span, 0 for none
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Start      time.Duration          `json:"start_ns"`
+	Duration   time.Duration          `json:"duration_ns"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Total summarizes the timings of spans with equal kind and name.
+type Total struct {
+	Kind  string        `json:"kind"`
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// Recorder is a spans.Recorder measuring the durations of spans. It is safe for
+// concurrent use.
+type Recorder struct {
+	mtx     sync.Mutex
+	created time.Time
+	lastID  int
+	timings []Timing // finished spans
+}
+
+var _ spans.Recorder = &Recorder{}
+
+// NewRecorder creates a recorder. Start times of spans are measured from now.
+func NewRecorder() *Recorder {
+	return &Recorder{created: time.Now()}
+}
+
+type parentKey struct{}
+
+// Start starts measuring a span. If ctx contains a span started by r, the new span
+// will be a child of it.
+func (r *Recorder) Start(ctx context.Context, kind spans.Kind, name string) (context.Context, spans.Span) {
+	r.mtx.Lock()
+	r.lastID++
+	id := r.lastID
+	r.mtx.Unlock()
+	parent, _ := ctx.Value(parentKey{}).(int)
+	s := &span{
+		rec:    r,
+		timing: Timing{ID: id, Parent: parent, Kind: kind.String(), Name: name},
+		start:  time.Now(),
+	}
+	return context.WithValue(ctx, parentKey{}, id), s
+}
+
+// Timings returns the timings of all finished spans, in order of their start.
+func (r *Recorder) Timings() []Timing {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	timings := append([]Timing(nil), r.timings...)
+	sort.SliceStable(timings, func(i, j int) bool {
+		if timings[i].Start == timings[j].Start {
+			return timings[i].ID < timings[j].ID
+		}
+		return timings[i].Start < timings[j].Start
+	})
+	return timings
+}
+
+// Totals summarizes the timings of finished spans by kind and name. Totals are
+// sorted by decreasing total duration.
+func (r *Recorder) Totals() []Total {
+	index := make(map[[2]string]int)
+	var totals []Total
+	for _, t := range r.Timings() {
+		key := [2]string{t.Kind, t.Name}
+		i, ok := index[key]
+		if !ok {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, Total{Kind: t.Kind, Name: t.Name})
+		}
+		totals[i].Count++
+		totals[i].Total += t.Duration
+		if t.Duration > totals[i].Max {
+			totals[i].Max = t.Duration
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		return totals[i].Total > totals[j].Total
+	})
+	return totals
+}
+
+// WriteJSON writes the timings of finished spans and their totals to w.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Spans  []Timing `json:"spans"`
+		Totals []Total  `json:"totals"`
+	}{
+		Spans:  r.Timings(),
+		Totals: r.Totals(),
+	})
+}
+
+func (r *Recorder) finish(t Timing) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.timings = append(r.timings, t)
+}
+
+// span is a span in progress. Spans are not safe for concurrent use, as is the
+// case for spans of other recorders.
+type span struct {
+	rec    *Recorder
+	timing Timing
+	start  time.Time
+	ended  bool
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	if s.timing.Attributes == nil {
+		s.timing.Attributes = make(map[string]interface{})
+	}
+	switch value.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		s.timing.Attributes[key] = value
+	default:
+		s.timing.Attributes[key] = fmt.Sprintf("%v", value)
+	}
+}
+
+func (s *span) RecordError(err error) {
+	if err != nil {
+		s.timing.Error = err.Error()
+	}
+}
+
+// End finishes measuring a span. Calling End more than once has no effect.
+func (s *span) End() {
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.timing.Start = s.start.Sub(s.rec.created)
+	s.timing.Duration = time.Since(s.start)
+	s.rec.finish(s.timing)
+}
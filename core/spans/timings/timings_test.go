@@ -0,0 +1,74 @@
+package timings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/spans"
+)
+
+func TestParents(t *testing.T) {
+	rec := NewRecorder()
+	spans.SetRecorder(rec)
+	defer spans.SetRecorder(nil)
+	ctx, stage := spans.Start(context.Background(), spans.Stage, "stage")
+	for i := 0; i < 2; i++ {
+		_, run := spans.Start(ctx, spans.Run, "shape")
+		run.SetAttribute("i", i)
+		run.End()
+	}
+	stage.RecordError(errors.New("test error"))
+	stage.End()
+	stage.End() // must not record the span twice
+	timings := rec.Timings()
+	if len(timings) != 3 {
+		t.Fatalf("expected 3 timings, have %d", len(timings))
+	}
+	if timings[0].Name != "stage" || timings[0].Parent != 0 || timings[0].Error != "test error" {
+		t.Errorf("unexpected timing for top-level span: %+v", timings[0])
+	}
+	for _, run := range timings[1:] {
+		if run.Kind != "run" || run.Parent != timings[0].ID {
+			t.Errorf("expected run to be a child of stage, have %+v", run)
+		}
+		if run.Duration > timings[0].Duration {
+			t.Errorf("expected run to take less time than its parent")
+		}
+	}
+	totals := rec.Totals()
+	if len(totals) != 2 || totals[0].Name != "stage" || totals[1].Count != 2 {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rec := NewRecorder()
+	_, span := rec.Start(context.Background(), spans.Page, "page")
+	span.SetAttribute("page", 1)
+	span.SetAttribute("list", []int{1, 2})
+	span.End()
+	var buf bytes.Buffer
+	if err := rec.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Spans  []map[string]interface{} `json:"spans"`
+		Totals []map[string]interface{} `json:"totals"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("cannot decode JSON: %v\n%s", err, buf.String())
+	}
+	if len(doc.Spans) != 1 || doc.Spans[0]["kind"] != "page" || doc.Spans[0]["duration_ns"] == nil {
+		t.Errorf("unexpected spans: %v", doc.Spans)
+	}
+	attrs, _ := doc.Spans[0]["attributes"].(map[string]interface{})
+	if attrs["page"] != 1.0 || attrs["list"] != "[1 2]" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+	if len(doc.Totals) != 1 || doc.Totals[0]["count"] != 1.0 {
+		t.Errorf("unexpected totals: %v", doc.Totals)
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Package tyse configures the typesetting engine as a whole.
+
+The engine's packages keep some of their configuration in package-level state:
+tracers, the global font registry, the location of hyphenation patterns, and
+default typesetting registers. Applications embedding the engine configure it
+through a single Config instead of touching these one by one.
+
+Usage
+
+	conf := tyse.New(
+		tyse.FromEnvironment(),
+		tyse.WithFontDirs("./fonts"),
+		tyse.WithPaperSize(dimen.DINA5),
+	)
+	if err := conf.Setup(); err != nil {
+		…
+	}
+	engine := api.New(conf.EngineOptions()...)
+
+Configuration may as well be read from a schuko.Configuration, e.g. from a
+configuration file, with option FromConfiguration. Options are applied in
+order, i.e. later options override earlier ones.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package tyse
+
+import (
+	"github.com/npillmayer/schuko/tracing"
+)
+
+// tracer traces with key 'tyse.engine'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.engine")
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"golang.org/x/net/html"
+)
+
+var document = `
+<html><head>
+<style>
+  p { margin: 0; }
+</style>
+</head><body>
+  <p>The quick brown fox jumps over the lazy dog.</p>
+  <p>The quick brown fox jumps over the lazy dog.</p>
+  <aside style="flow-into: sidebar">The lazy dog</aside>
+</body>
+`
+
+func TestTypesetHTML(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	engine := New(
+		WithPaperSize(dimen.DINA5),
+		WithCSS(`@page { margin: 1cm; } aside { display: none; }`),
+	)
+	pages, err := engine.TypesetHTML(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatalf("expected document to be typeset onto pages, have none")
+	}
+	page := pages[0]
+	if page.Number != 1 || page.Size != dimen.DINA5 {
+		t.Errorf("expected page 1 of size DIN A5, have page %d of size %v", page.Number, page.Size)
+	}
+	if page.Content.TopL.X != dimen.CM || page.Content.BotR.X != dimen.DINA5.X-dimen.CM {
+		t.Errorf("expected margins of 1cm, content area is %v", page.Content)
+	}
+	if len(page.Regions) != 1 || page.Regions[0].Flow != MainFlow {
+		t.Fatalf("expected a single region for the main flow")
+	}
+	if len(page.Regions[0].Boxes) == 0 {
+		t.Fatalf("expected content in main region of page 1")
+	}
+	for _, box := range page.Regions[0].Boxes {
+		if box.Frame.TopL.Y < page.Content.TopL.Y || box.Frame.TopL.X < page.Content.TopL.X {
+			t.Errorf("expected <%s> within the content area, is at %v", box.Element, box.Frame.TopL)
+		}
+	}
+}
+
+func TestTypesetNamedFlow(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	half := dimen.DINA4.X / 2
+	template := &Template{Name: "sidebar", Regions: []TemplateRegion{
+		{Name: "body", Flow: MainFlow, Area: dimen.Rect{BotR: dimen.Point{X: half, Y: dimen.DINA4.Y}}},
+		{Name: "aside", Flow: "sidebar", Area: dimen.Rect{
+			TopL: dimen.Point{X: half},
+			BotR: dimen.DINA4,
+		}},
+	}}
+	engine := New(WithTemplates(template, nil))
+	pages, err := engine.TypesetHTML(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || len(pages[0].Regions) != 2 {
+		t.Fatalf("expected a single page with 2 regions, have %d pages", len(pages))
+	}
+	aside := pages[0].Regions[1]
+	if aside.Flow != "sidebar" || len(aside.Boxes) != 1 || aside.Boxes[0].Element != "aside" {
+		t.Fatalf("expected <aside> in region for flow 'sidebar', have %v", aside.Boxes)
+	}
+	if aside.Boxes[0].Frame.TopL.X != half {
+		t.Errorf("expected <aside> at x=%v, is at x=%v", half, aside.Boxes[0].Frame.TopL.X)
+	}
+}
+
+func TestTypesetNoDocument(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	if _, err := New().Typeset(nil); !errors.Is(err, ErrNoDocument) {
+		t.Errorf("expected missing document to be flagged, have %v", err)
+	}
+}
+
+func TestTypesetPageReference(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	h, err := html.Parse(strings.NewReader(`<html><body>
+	<p>See page <a data-type="xref" data-xrefstyle="select: page" href="#fox">?</a>.</p>
+	<p id="fox" style="break-before: page">The quick brown fox jumps over the lazy dog.</p>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := New().Typeset(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, have %d", len(pages))
+	}
+	var ref *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			ref = n
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			find(ch)
+		}
+	}
+	find(h)
+	if ref == nil || ref.FirstChild == nil || ref.FirstChild.Data != "2" {
+		t.Errorf("expected page reference to be filled in with page number 2")
+	}
+	if len(pages[0].Links) != 1 || pages[0].Links[0].Target != 2 {
+		t.Fatalf("expected a link to page 2 on page 1, have %v", pages[0].Links)
+	}
+	if pages[0].Links[0].Href != "#fox" || pages[0].Links[0].Frame.Width() <= 0 {
+		t.Errorf("expected link to #fox with non-empty frame, have %v", pages[0].Links[0])
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	h, err := html.Parse(strings.NewReader(`<html><body>
+	<nav data-type="toc"><h1>Contents</h1></nav>
+	<h1 id="fox" style="break-before: page">Foxes</h1>
+	<p>The quick brown fox jumps over the lazy dog.</p>
+	<h2>Dogs</h2>
+	<p>The lazy dog.</p>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := New()
+	if _, err = engine.Typeset(h); err != nil {
+		t.Fatal(err)
+	}
+	toc := engine.TableOfContents()
+	if len(toc) != 2 {
+		t.Fatalf("expected 2 entries in table of contents, have %d", len(toc))
+	}
+	if toc[0].Title != "Foxes" || toc[0].ID != "fox" || toc[0].Page != 2 {
+		t.Errorf("expected 'Foxes' on page 2, have %+v", toc[0])
+	}
+	if toc[1].Level != 2 || toc[1].ID == "" || toc[1].Page != 2 {
+		t.Errorf("expected 'Dogs' at level 2 with generated id on page 2, have %+v", toc[1])
+	}
+	var items int
+	var count func(*html.Node)
+	count = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "li" {
+			items++
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			count(ch)
+		}
+	}
+	count(h)
+	if items != 2 {
+		t.Errorf("expected table of contents section with 2 entries, have %d", items)
+	}
+}
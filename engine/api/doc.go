@@ -0,0 +1,45 @@
+/*
+Package api is the stable entry point to the typesetting engine: it takes an
+HTML document (DOM in) and returns laid out pages (pages out).
+
+# Status
+
+The engine itself is an early draft and its internal packages (DOM, box tree,
+layout, khipus, fonts, …) change frequently. Package api insulates clients from
+this churn and is the only package of the engine with a stability guarantee.
+
+# Stability
+
+Package api follows semantic versioning. Within a major version, exported
+identifiers of this package will neither be removed nor changed in an
+incompatible way. Types of this package do not expose types of internal
+packages, with the exception of package core/dimen.
+
+If functionality behind the facade moves between internal packages, the
+facade keeps its API and adapts internally. Identifiers to be retired will be
+marked with a "Deprecated:" paragraph in their doc comment, pointing to their
+replacement, and will be kept for at least one minor release. Internal packages
+follow the same practice whenever an exported identifier changes incompatibly.
+
+Usage
+
+	engine := api.New(api.WithPaperSize(dimen.DINA5), api.WithCSS(stylesheet))
+	pages, err := engine.TypesetHTML(strings.NewReader(document))
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package api
+
+import (
+	"github.com/npillmayer/schuko/tracing"
+)
+
+// tracer traces with key 'tyse.engine'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.engine")
+}
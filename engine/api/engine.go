@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	douceur "github.com/aymerick/douceur/css"
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+	"golang.org/x/net/html"
+)
+
+// ErrNoDocument is returned if the input to the engine does not contain a
+// document to typeset.
+var ErrNoDocument = errors.New("no document to typeset")
+
+// MainFlow is the name of the flow which receives all content not routed into
+// a named flow with CSS property "flow-into".
+const MainFlow = frame.MainFlow
+
+// Engine typesets HTML documents into pages. An engine may be used for more
+// than one document, but not concurrently.
+type Engine struct {
+	papersize dimen.Point
+	gutter    dimen.DU
+	rtl       bool
+	sheets    []cssom.StyleSheet
+	left      *Template
+	right     *Template
+	toc       []TOCEntry // table of contents of the document typeset last
+	err       error      // error from applying options
+}
+
+// Option configures an engine.
+type Option func(*Engine)
+
+// New creates an engine. Without options, pages are DIN A4 without margins,
+// using a single region for the main flow.
+func New(opts ...Option) *Engine {
+	e := &Engine{papersize: dimen.DINA4}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithPaperSize sets the paper size of pages.
+func WithPaperSize(size dimen.Point) Option {
+	return func(e *Engine) {
+		e.papersize = size
+	}
+}
+
+// WithCSS adds a stylesheet, which applies in addition to the stylesheets of a
+// document. Page margins may be set with @page rules, including selectors
+// `:left`, `:right` and `:first`.
+func WithCSS(css string) Option {
+	return func(e *Engine) {
+		sheet, err := parser.Parse(css)
+		if err != nil {
+			e.err = fmt.Errorf("cannot parse stylesheet: %w", err)
+			return
+		}
+		e.sheets = append(e.sheets, douceuradapter.Wrap(sheet))
+	}
+}
+
+// WithBinding configures two-sided output: gutter is extra space at the inner
+// margins, and rtl selects right-to-left page progression, i.e. binding at the
+// right edge.
+func WithBinding(gutter dimen.DU, rtl bool) Option {
+	return func(e *Engine) {
+		e.gutter, e.rtl = gutter, rtl
+	}
+}
+
+// WithTemplates sets page templates for left and right pages. Either may be
+// nil, in which case the other one is used for both sides.
+func WithTemplates(left, right *Template) Option {
+	return func(e *Engine) {
+		if left == nil {
+			left = right
+		} else if right == nil {
+			right = left
+		}
+		e.left, e.right = left, right
+	}
+}
+
+// TypesetHTML parses an HTML document from r and typesets it.
+func (e *Engine) TypesetHTML(r io.Reader) ([]*Page, error) {
+	return e.TypesetHTMLContext(context.Background(), r)
+}
+
+// TypesetHTMLContext is like TypesetHTML, but may be cancelled by ctx (see
+// TypesetContext).
+func (e *Engine) TypesetHTMLContext(ctx context.Context, r io.Reader) ([]*Page, error) {
+	h, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return e.TypesetContext(ctx, h)
+}
+
+// Typeset typesets an HTML parse tree and returns the resulting pages.
+//
+// Links to elements of the document are resolved to the pages their targets are
+// placed on (see Page.Links). Links of the form
+//
+//	<a data-type="xref" data-xrefstyle="page" href="#id">?</a>
+//
+// have their text replaced by the number of the page of element id, for
+// references like "see page N". An element <nav data-type="toc"> is filled with
+// a table of contents (see also TableOfContents). As this may shift content, the
+// document is typeset again if it has been changed. Note that h will be
+// modified in this case.
+func (e *Engine) Typeset(h *html.Node) ([]*Page, error) {
+	return e.TypesetContext(context.Background(), h)
+}
+
+// TypesetContext is like Typeset, but stops typesetting if ctx is cancelled or
+// its deadline is exceeded, which is useful for large documents. In this case
+// no pages are returned, together with the error of ctx. Trace spans are
+// recorded as children of a span contained in ctx (see package core/spans).
+func (e *Engine) TypesetContext(ctx context.Context, h *html.Node) ([]*Page, error) {
+	e.toc = nil
+	if e.err != nil {
+		return nil, e.err
+	}
+	if h == nil {
+		return nil, ErrNoDocument
+	}
+	pages, err := e.typeset(ctx, h)
+	if err == nil && fillInTableOfContents(h, layout.TableOfContents(pages)) {
+		tracer().Debugf("table of contents generated, typesetting a second time")
+		pages, err = e.typeset(ctx, h)
+	}
+	xrefs := layout.ResolveCrossReferences(pages)
+	if err == nil && fillInPageReferences(h, xrefs) {
+		tracer().Debugf("page references changed, typesetting again")
+		pages, err = e.typeset(ctx, h)
+		layout.ResolveCrossReferences(pages)
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	e.toc = toTOC(layout.TableOfContents(pages))
+	layout.PlaceImages(pages)
+	layout.PaintDecorations(pages)
+	tracer().Infof("typeset document into %d pages", len(pages))
+	return toPages(pages), err
+}
+
+// typeset performs a single pass of typesetting h: building the box tree,
+// layout and pagination.
+func (e *Engine) typeset(ctx context.Context, h *html.Node) ([]*layout.Page, error) {
+	ctx, span := spans.Start(ctx, spans.Stage, "api.Typeset")
+	defer span.End()
+	var css cssom.StyleSheet = douceuradapter.Wrap(&douceur.Stylesheet{})
+	for _, sheet := range e.sheets {
+		css.AppendRules(sheet)
+	}
+	for _, sheet := range douceuradapter.ExtractStyleElements(h) {
+		css.AppendRules(sheet)
+	}
+	doc := dom.FromHTMLParseTree(h, css)
+	if doc == nil {
+		return nil, ErrNoDocument
+	}
+	_, bspan := spans.Start(ctx, spans.Stage, "boxtree.BuildBoxTree")
+	boxes, err := boxtree.BuildBoxTree(doc)
+	bspan.RecordError(err)
+	bspan.End()
+	if err != nil {
+		return nil, err
+	}
+	router := boxtree.RouteFlows(boxes)
+	pm, err := e.pageModel(css)
+	if err != nil {
+		return nil, err
+	}
+	content := pm.ContentArea(1)
+	view := &layout.View{Width: content.Width(), Height: content.Height()}
+	_, lspan := spans.Start(ctx, spans.Stage, "layout.Layout")
+	err = layout.LayoutContext(ctx, boxes.RenderNode().(*boxtree.PrincipalBox), view)
+	lspan.RecordError(err)
+	lspan.End()
+	if err != nil {
+		return nil, err
+	}
+	paginator := layout.NewPaginator(pm, layout.SpreadTemplates(pm, e.template(e.left), e.template(e.right)))
+	pages, err := paginator.PaginateContext(ctx, router)
+	span.RecordError(err)
+	return pages, err
+}
+
+func (e *Engine) pageModel(css cssom.StyleSheet) (*layout.PageModel, error) {
+	pm := layout.NewPageModel(e.papersize)
+	pm.Gutter = e.gutter
+	if e.rtl {
+		pm.Binding = layout.BindRight
+	}
+	for _, rule := range cssom.EffectiveRules(css, cssom.PrintMedia(e.papersize)) {
+		if err := pm.AddPageRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	return pm, nil
+}
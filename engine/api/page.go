@@ -0,0 +1,165 @@
+package api
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+)
+
+// Page is a typeset page.
+type Page struct {
+	Number      int          // page number, counting from 1
+	Left        bool         // is this a left page of a spread?
+	Size        dimen.Point  // paper size
+	Content     dimen.Rect   // area within the page margins
+	Regions     []Region     // regions of the page, filled from flows
+	Links       []Link       // links placed on the page
+	Images      []Image      // images placed on the page
+	Decorations []Decoration // backgrounds and borders of boxes, in painting order
+}
+
+// Link is an area of a page which links to a position in the document or, for
+// links to other documents, to a URI.
+type Link struct {
+	Frame  dimen.Rect  // position on the page
+	Href   string      // link target as given in the document
+	Target int         // number of the page the link refers to, 0 for external or unresolved links
+	Pos    dimen.Point // position of the link target on the target page
+}
+
+// Region is an area on a page, filled with content from a flow.
+type Region struct {
+	Name  string
+	Flow  string
+	Frame dimen.Rect // position on the page
+	Boxes []Box      // content of the region
+}
+
+// Box is a laid out box of a region.
+type Box struct {
+	Element string     // name of the HTML element the box has been created for
+	Frame   dimen.Rect // position on the page
+}
+
+// Image is an image placed on a page. Raster images are decoded, whereas SVG
+// images are given as their source.
+type Image struct {
+	Frame dimen.Rect  // position on the page
+	Image image.Image // decoded raster image, nil for SVG images
+	SVG   []byte      // source of an SVG image
+}
+
+// Decoration is the background and border of a box. Backgrounds fill the border
+// box, with corners rounded by Radius. Borders are drawn between the border box
+// and the padding box.
+type Decoration struct {
+	Frame       dimen.Rect  // border box, position on the page
+	Padding     dimen.Rect  // padding box, position on the page
+	Widths      [4]dimen.DU // border widths: top, right, bottom, left
+	Background  color.Color // background color, nil for transparent
+	Image       image.Image // background raster image, drawn at the top left of the padding box
+	BorderColor color.Color // color of the border, nil for no border
+	Dashed      bool        // border is dashed
+	Dotted      bool        // border is dotted
+	Radius      dimen.DU    // radius of rounded corners
+}
+
+// Template is a page template, defining regions which consume flows.
+type Template struct {
+	Name    string
+	Regions []TemplateRegion
+}
+
+// TemplateRegion is a region of a page template. Area is relative to the area
+// within the page margins; an empty area denotes the complete area.
+type TemplateRegion struct {
+	Name string
+	Flow string
+	Area dimen.Rect
+}
+
+// template converts a facade page template to a layout page template. If t is
+// nil, a template with a single region for the main flow is returned.
+func (e *Engine) template(t *Template) *layout.PageTemplate {
+	if t == nil {
+		return &layout.PageTemplate{
+			Name:    "default",
+			Regions: []layout.Region{{Name: "main", Flow: MainFlow}},
+		}
+	}
+	pt := &layout.PageTemplate{Name: t.Name}
+	for _, r := range t.Regions {
+		pt.Regions = append(pt.Regions, layout.Region{Name: r.Name, Flow: r.Flow, Area: r.Area})
+	}
+	return pt
+}
+
+func toPages(pages []*layout.Page) []*Page {
+	result := make([]*Page, len(pages))
+	for i, p := range pages {
+		page := &Page{
+			Number:  p.Number,
+			Left:    p.Side == layout.LeftPage,
+			Size:    p.Rect.BotR,
+			Content: p.Content,
+		}
+		for _, r := range p.Regions {
+			region := Region{Name: r.Name, Flow: r.Flow, Frame: r.Frame}
+			for _, c := range r.Content {
+				region.Boxes = append(region.Boxes, toBox(c))
+			}
+			page.Regions = append(page.Regions, region)
+		}
+		for _, l := range p.Links {
+			link := Link{Frame: l.Rect, Href: l.Href}
+			if l.Anchor != nil {
+				link.Target, link.Pos = l.Anchor.Page, l.Anchor.Pos
+			}
+			page.Links = append(page.Links, link)
+		}
+		for _, img := range p.Images {
+			page.Images = append(page.Images, Image{Frame: img.Rect, Image: img.Picture.Image, SVG: img.Picture.SVG})
+		}
+		for _, d := range p.Decorations {
+			page.Decorations = append(page.Decorations, toDecoration(d))
+		}
+		result[i] = page
+	}
+	return result
+}
+
+func toDecoration(d *layout.Decoration) Decoration {
+	deco := Decoration{
+		Frame:       d.Border,
+		Padding:     d.Padding,
+		Widths:      d.Widths,
+		Background:  d.Background,
+		BorderColor: d.BorderColor,
+		Dashed:      d.LineStyle == frame.LSDashed,
+		Dotted:      d.LineStyle == frame.LSDotted,
+		Radius:      d.Radius,
+	}
+	if d.Image != nil {
+		deco.Image = d.Image.Image
+	}
+	return deco
+}
+
+func toBox(c *frame.Container) Box {
+	box := Box{}
+	if c.DOMNode() != nil {
+		box.Element = c.DOMNode().NodeName()
+	}
+	cssbox := c.CSSBox()
+	var w, h dimen.DU
+	cssbox.W.Match().Just(&w)
+	cssbox.H.Match().Just(&h)
+	box.Frame = dimen.Rect{
+		TopL: cssbox.TopL,
+		BotR: dimen.Point{X: cssbox.TopL.X + w, Y: cssbox.TopL.Y + h},
+	}
+	return box
+}
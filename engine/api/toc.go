@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TOCEntry is an entry of the table of contents of a typeset document.
+type TOCEntry struct {
+	Level int         // 1 for <h1> through 6 for <h6>
+	Title string      // text of the heading
+	ID    string      // id of the heading
+	Page  int         // number of the page the heading is placed on
+	Pos   dimen.Point // position of the heading on its page
+}
+
+// TableOfContents returns the headings of the document typeset last, together
+// with the numbers of the pages they have been placed on.
+func (e *Engine) TableOfContents() []TOCEntry {
+	return e.toc
+}
+
+func toTOC(entries []*layout.TOCEntry) []TOCEntry {
+	toc := make([]TOCEntry, len(entries))
+	for i, entry := range entries {
+		toc[i] = TOCEntry{
+			Level: entry.Level,
+			Title: entry.Title,
+			ID:    entry.ID,
+			Page:  entry.Page,
+			Pos:   entry.Pos,
+		}
+	}
+	return toc
+}
+
+// fillInTableOfContents generates the table of contents section of a document,
+// if it contains a placeholder element <nav data-type="toc"> without a list. The section
+// is a nested list of links to the headings, each followed by a page reference,
+// using the markup of HTMLBook:
+//
+//	<ol><li><a href="#id">Title</a> <a data-type="xref" data-xrefstyle="page" href="#id">?</a>
+//	    <ol>…subsections…</ol></li></ol>
+//
+// Headings without an id get a generated one. Page numbers are filled in by
+// fillInPageReferences. Returns true if a table of contents has been generated.
+func fillInTableOfContents(h *html.Node, entries []*layout.TOCEntry) bool {
+	nav := findTOCPlaceholder(h)
+	if nav == nil || len(entries) == 0 {
+		return false
+	}
+	lists := []*html.Node{element("ol")}
+	nav.AppendChild(lists[0])
+	var item *html.Node
+	for i, entry := range entries {
+		heading := entry.Node.HTMLNode()
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("toc-%d", i+1)
+			heading.Attr = append(heading.Attr, html.Attribute{Key: "id", Val: entry.ID})
+		}
+		depth := entry.Level
+		if depth > len(lists) { // skipped heading levels nest a single level deeper
+			if item != nil {
+				sub := element("ol")
+				item.AppendChild(sub)
+				lists = append(lists, sub)
+			}
+			depth = len(lists)
+		}
+		lists = lists[:depth]
+		item = element("li")
+		title := element("a", html.Attribute{Key: "href", Val: "#" + entry.ID})
+		title.AppendChild(&html.Node{Type: html.TextNode, Data: entry.Title})
+		pageref := element("a",
+			html.Attribute{Key: "data-type", Val: "xref"},
+			html.Attribute{Key: "data-xrefstyle", Val: "page"},
+			html.Attribute{Key: "href", Val: "#" + entry.ID})
+		pageref.AppendChild(&html.Node{Type: html.TextNode, Data: "?"})
+		item.AppendChild(title)
+		item.AppendChild(&html.Node{Type: html.TextNode, Data: " "})
+		item.AppendChild(pageref)
+		lists[depth-1].AppendChild(item)
+	}
+	tracer().Debugf("generated table of contents with %d entries", len(entries))
+	return true
+}
+
+// findTOCPlaceholder returns the first element <nav data-type="toc"> without a
+// list of entries. The placeholder may contain a heading.
+func findTOCPlaceholder(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "nav" && attr(n, "data-type") == "toc" {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type == html.ElementNode && ch.Data == "ol" {
+				return nil
+			}
+		}
+		return n
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if nav := findTOCPlaceholder(ch); nav != nil {
+			return nav
+		}
+	}
+	return nil
+}
+
+func element(name string, attrs ...html.Attribute) *html.Node {
+	return &html.Node{Type: html.ElementNode, DataAtom: atom.Lookup([]byte(name)), Data: name, Attr: attrs}
+}
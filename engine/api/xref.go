@@ -0,0 +1,80 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/frame/layout"
+	"golang.org/x/net/html"
+)
+
+// fillInPageReferences replaces the text of page references, i.e. links with
+// attribute data-xrefstyle "page" or "select: page", by the number of the page
+// of their target. The first text node of the link receives the page number,
+// other text nodes are cleared. Returns true if any text has changed.
+func fillInPageReferences(h *html.Node, xrefs *layout.CrossRefs) bool {
+	if h == nil || xrefs == nil {
+		return false
+	}
+	changed := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" && isPageReference(n) {
+			href := attr(n, "href")
+			if no, ok := xrefs.PageOf(strings.TrimPrefix(href, "#")); ok && strings.HasPrefix(href, "#") {
+				changed = setText(n, strconv.Itoa(no)) || changed
+			} else {
+				tracer().Infof("cannot resolve page reference to '%s'", href)
+			}
+			return
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(h)
+	return changed
+}
+
+func isPageReference(n *html.Node) bool {
+	style := strings.TrimSpace(attr(n, "data-xrefstyle"))
+	style = strings.TrimSpace(strings.TrimPrefix(style, "select:"))
+	return style == "page"
+}
+
+// setText sets the text of the first text node below n to text, and clears all
+// other text nodes below n. If n has no text node, one is appended.
+func setText(n *html.Node, text string) bool {
+	changed, first := false, true
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type != html.TextNode {
+				walk(ch)
+				continue
+			}
+			data := ""
+			if first {
+				data, first = text, false
+			}
+			if ch.Data != data {
+				ch.Data, changed = data, true
+			}
+		}
+	}
+	walk(n)
+	if first {
+		n.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+		changed = true
+	}
+	return changed
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -3,18 +3,16 @@ package css
 import (
 	"testing"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/testconfig"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/dimen"
-	"github.com/npillmayer/tyse/core/option"
 	"github.com/npillmayer/tyse/engine/dom/style"
 )
 
 func TestDimen(t *testing.T) {
 	teardown := testconfig.QuickConfig(t)
 	defer teardown()
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	//
 	p := style.Property("100pt")
 	d := DimenOption(p)
@@ -24,11 +22,13 @@ func TestDimen(t *testing.T) {
 	//
 	p = style.Property("auto")
 	d = DimenOption(p)
-	x, err := d.Match(option.Of{
-		option.None: "NONE",
-		Auto:        "AUTO",
-	})
-	if err != nil || x != "AaUTO" {
-		t.Errorf("expected AUTO, have %v with error %v", x, err)
+	if d.IsNone() || !d.Equals(Auto) {
+		t.Errorf("expected AUTO, have %v", d)
+	}
+	if m := MaxDimen(Dimen(), SomeDimen(dimen.PT)); m.Unwrap() != dimen.PT {
+		t.Errorf("expected max of unset and 1pt to be 1pt, have %v", m)
+	}
+	if m := MinDimen(SomeDimen(dimen.PT), SomeDimen(dimen.BP)); m.Unwrap() != dimen.PT {
+		t.Errorf("expected min of 1pt and 1bp to be 1pt, have %v", m)
 	}
 }
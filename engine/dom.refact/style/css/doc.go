@@ -52,11 +52,10 @@ package css
 // https://developer.mozilla.org/en-US/docs/Web/CSS/Reference#dom-css_cssom
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// T traces to the engine tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.EngineTracer
+	return tracing.Select("tyse.dom")
 }
@@ -348,28 +348,26 @@ func ParseDimen(s string) (DimenT, error) {
 	return dim, nil
 }
 
-// MaxDimen returns the greater of two dimensions.
+// MaxDimen returns the greater of two dimensions. If one of them is unset, the
+// other one is returned.
 func MaxDimen(d1, d2 DimenT) DimenT {
-	max, _ := d1.Match(option.Maybe{
-		option.None: d2,
-		option.Some: option.Safe(d2.Match(option.Maybe{
-			option.None: d1,
-			option.Some: dimen.Max(d1.Unwrap(), d2.Unwrap()),
-		})),
-	})
-	return max.(DimenT)
+	if d1.IsNone() {
+		return d2
+	} else if d2.IsNone() {
+		return d1
+	}
+	return SomeDimen(dimen.Max(d1.Unwrap(), d2.Unwrap()))
 }
 
-// MinDimen returns the lesser of two dimensions.
+// MinDimen returns the lesser of two dimensions. If one of them is unset, the
+// other one is returned.
 func MinDimen(d1, d2 DimenT) DimenT {
-	max, _ := d1.Match(option.Maybe{
-		option.None: d2,
-		option.Some: option.Safe(d2.Match(option.Maybe{
-			option.None: d1,
-			option.Some: dimen.Min(d1.Unwrap(), d2.Unwrap()),
-		})),
-	})
-	return max.(DimenT)
+	if d1.IsNone() {
+		return d2
+	} else if d2.IsNone() {
+		return d1
+	}
+	return SomeDimen(dimen.Min(d1.Unwrap(), d2.Unwrap()))
 }
 
 // --- PositionT -------------------------------------------------------------
@@ -8,7 +8,6 @@ import (
 	"sync"
 
 	"github.com/andybalholm/cascadia"
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/engine/dom/style"
 	"github.com/npillmayer/tyse/engine/tree"
@@ -51,9 +50,9 @@ THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
 OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 ----------------------------------------------------------------- */
 
-// T returns a global tracer. We trace to the engine tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.EngineTracer
+	return tracing.Select("tyse.dom")
 }
 
 // CSSOM is the "CSS Object Model", similar to the DOM for HTML.
@@ -1,7 +1,6 @@
 package style
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"golang.org/x/net/html"
 )
 
@@ -100,7 +99,7 @@ func DisplayPropertyForHTMLNode(node *html.Node) Property {
 	case "i", "b", "span", "strong":
 		return "inline"
 	}
-	gtrace.EngineTracer.Infof("unknown HTML element %s/%d will be set to display: block",
+	T().Infof("unknown HTML element %s/%d will be set to display: block",
 		node.Data, node.Type)
 	return "block"
 }
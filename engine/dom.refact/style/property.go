@@ -39,14 +39,13 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/engine/tree"
 )
 
-// T traces to a global engine tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.EngineTracer
+	return tracing.Select("tyse.dom")
 }
 
 // Property is a raw value for a CSS property. For example, with
@@ -50,11 +50,10 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package styledtree
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// T traces to the engine tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.EngineTracer
+	return tracing.Select("tyse.dom")
 }
@@ -54,16 +54,15 @@ import (
 	"fmt"
 
 	"github.com/antchfx/xpath"
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/engine/dom/styledtree"
 	"github.com/npillmayer/tyse/engine/tree"
 	"golang.org/x/net/html"
 )
 
-// T traces to a global core tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.dom")
 }
 
 type NodeNavigator struct {
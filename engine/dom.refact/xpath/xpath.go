@@ -39,14 +39,13 @@ import (
 	"errors"
 
 	"github.com/antchfx/xpath"
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/engine/tree"
 )
 
-// T traces to a global core tracer.
+// T traces with key 'tyse.dom'.
 func T() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.dom")
 }
 
 var errInvalidXPathExpr = errors.New("Invalid XPath expression")
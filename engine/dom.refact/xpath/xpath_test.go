@@ -5,7 +5,6 @@ import (
 	"testing"
 
 	"github.com/aymerick/douceur/parser"
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/schuko/tracing/logrusadapter"
 	"github.com/npillmayer/tyse/engine/dom/style"
@@ -30,9 +29,8 @@ const (
 )
 
 func Test0(t *testing.T) {
-	gtrace.EngineTracer = logrusadapter.New()
-	gtrace.EngineTracer.SetTraceLevel(tracing.LevelDebug)
-	T = gtrace.EngineTracer
+	T = logrusadapter.New()
+	T.SetTraceLevel(tracing.LevelDebug)
 }
 
 type UNUSED interface{}
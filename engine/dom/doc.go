@@ -17,6 +17,17 @@ Excerpt: "In this paper, I argue that HTML5 offers unique advantages to authors
 For an in-depth description of HTMLbook please refer to
 https://oreillymedia.github.io/HTMLBook/.
 
+Creating a DOM
+
+FromHTML reads an HTML document and returns its styled W3C DOM. HTMLBook
+default styles (see HTMLBookCSS) are applied, together with the document's
+<style> elements and an optional author style sheet:
+
+    doc, err := dom.FromHTML(reader, authorCSS)
+
+Clients holding a parse tree from golang.org/x/net/html already may call
+FromHTMLParseTree instead.
+
 Tree Implementation
 
 Styling and layout of HTML/CSS involves a lot of operations on different trees.
@@ -5,9 +5,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aymerick/douceur/parser"
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
 	"github.com/npillmayer/tyse/engine/dom"
 	"github.com/npillmayer/tyse/engine/dom/domdbg"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
 	"github.com/npillmayer/tyse/engine/dom/styledtree"
 	"github.com/npillmayer/tyse/engine/tree"
 	"golang.org/x/net/html"
@@ -19,6 +21,7 @@ var myhtml = `
 <html><head>
 <style>
   body { border-color: red; }
+  p { border: 2pt solid blue; font: bold 10pt serif; }
 </style>
 </head><body>
   <p>The quick brown fox jumps over the lazy dog.</p>
@@ -101,6 +104,28 @@ func TestW3CStyles1(t *testing.T) {
 	}
 }
 
+func TestW3CShorthandStyles(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	root := buildDOM(t)
+	body := root.FirstChild().FirstChild().NextSibling().(*dom.W3CNode)
+	p := body.FirstChild().NextSibling().(*dom.W3CNode)
+	props := p.ComputedStyles()
+	for key, value := range map[string]string{
+		"border-left-width":   "2pt",
+		"border-bottom-style": "solid",
+		"border-top-color":    "blue",
+		"font-weight":         "bold",
+		"font-size":           "10pt",
+		"font-style":          "normal",
+	} {
+		if v := props.GetPropertyValue(key); v.String() != value {
+			t.Errorf("expected %s of <p> to be '%s', is '%s'", key, value, v)
+		}
+	}
+}
+
 /*
 func prepareStyledTree(t *testing.T) *tree.Node {
 	h, errhtml := html.Parse(strings.NewReader(myhtml))
@@ -178,3 +203,43 @@ func indent(w int) string {
 	return s
 }
 */
+
+var htmlbook = `
+<html><body data-type="book">
+  <section data-type="chapter"><h1>Chapter 1</h1>
+    <p>The quick brown fox jumps over the lazy dog.</p>
+  </section>
+</body></html>
+`
+
+func TestFromHTMLBook(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	author, err := parser.Parse(`h1 { font-size: 20pt; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := dom.FromHTML(strings.NewReader(htmlbook), douceuradapter.Wrap(author))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := root.FirstChild().FirstChild().NextSibling().(*dom.W3CNode)
+	section := body.FirstChild().NextSibling().(*dom.W3CNode)
+	if section.NodeName() != "section" {
+		t.Fatalf("expected <section>, have <%s>", section.NodeName())
+	}
+	if v := section.ComputedStyles().GetPropertyValue("break-before"); v.String() != "page" {
+		t.Errorf("expected chapter to break before page, is '%s'", v)
+	}
+	h1 := section.FirstChild().(*dom.W3CNode)
+	for key, value := range map[string]string{
+		"font-weight": "bold",
+		"break-after": "avoid",
+		"font-size":   "20pt",
+	} {
+		if v := h1.ComputedStyles().GetPropertyValue(key); v.String() != value {
+			t.Errorf("expected %s of <h1> to be '%s', is '%s'", key, value, v)
+		}
+	}
+}
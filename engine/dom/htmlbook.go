@@ -0,0 +1,94 @@
+package dom
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
+	"golang.org/x/net/html"
+)
+
+// --- HTMLBook documents ----------------------------------------------------
+
+// HTMLBookCSS is the default style sheet for documents following HTMLBook
+// semantics (https://oreillymedia.github.io/HTMLBook/). Book divisions start on
+// a new page, headings are kept with the content following them, and block
+// elements like figures, examples and sidebars are not broken across pages.
+const HTMLBookCSS = `
+section[data-type="chapter"], section[data-type="appendix"],
+section[data-type="preface"], section[data-type="foreword"],
+section[data-type="introduction"], section[data-type="afterword"],
+section[data-type="glossary"], section[data-type="bibliography"],
+section[data-type="index"], section[data-type="colophon"],
+section[data-type="dedication"], section[data-type="titlepage"],
+section[data-type="copyright-page"], section[data-type="halftitlepage"],
+section[data-type="acknowledgments"], nav[data-type="toc"] {
+  break-before: page;
+}
+div[data-type="part"] { break-before: right; }
+h1, h2, h3, h4, h5, h6 { font-weight: bold; break-after: avoid; break-inside: avoid; }
+h1 { font-size: 2em; margin-top: 0; margin-bottom: 1em; }
+h2 { font-size: 1.5em; margin-top: 1em; margin-bottom: 0.75em; }
+h3 { font-size: 1.25em; margin-top: 1em; margin-bottom: 0.5em; }
+h4, h5, h6 { font-size: 1em; margin-top: 1em; margin-bottom: 0.5em; }
+section[data-type="sect1"] > h1 { font-size: 1.5em; margin-top: 1em; margin-bottom: 0.75em; }
+h6 { font-style: italic; font-weight: normal; }
+p { margin-top: 0; margin-bottom: 0.5em; widows: 2; orphans: 2; }
+figure, table, pre, div[data-type="example"], aside[data-type="sidebar"],
+div[data-type="note"], div[data-type="tip"], div[data-type="warning"],
+div[data-type="caution"], div[data-type="important"] {
+  margin-top: 1em; margin-bottom: 1em; break-inside: avoid;
+}
+blockquote { margin: 1em 2em; }
+pre { white-space: pre; hyphens: none; }
+figcaption, caption { font-style: italic; break-before: avoid; }
+em, i, cite, dfn { font-style: italic; }
+strong, b { font-weight: bold; }
+ol { list-style-type: decimal; }
+ul { list-style-type: disc; }
+`
+
+// HTMLBookStyles returns the default style sheet for HTMLBook documents, with
+// the rules of an optional author style sheet css appended. It is intended for
+// front-ends which create an HTML parse tree by other means than parsing HTML,
+// and pass it to FromHTMLParseTree.
+func HTMLBookStyles(css cssom.StyleSheet) (cssom.StyleSheet, error) {
+	sheet, err := parser.Parse(HTMLBookCSS)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse HTMLBook default styles: %w", err)
+	}
+	styles := douceuradapter.Wrap(sheet)
+	if css != nil {
+		styles.AppendRules(css)
+	}
+	return styles, nil
+}
+
+// FromHTML reads an HTML document from r and returns its W3C DOM, styled with
+// the HTMLBook default styles, the document's <style> elements and an optional
+// author style sheet css. Styles from css take precedence over the HTMLBook
+// defaults.
+//
+// FromHTML wires up the HTML parser, the CSSOM and the styled tree, and is the
+// recommended entry point for clients which do not need to control these steps
+// themselves. For documents parsed already, use FromHTMLParseTree.
+func FromHTML(r io.Reader, css cssom.StyleSheet) (*W3CNode, error) {
+	if r == nil {
+		return nil, fmt.Errorf("cannot create DOM: no input")
+	}
+	h, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse HTML document: %w", err)
+	}
+	styles, err := HTMLBookStyles(css)
+	if err != nil {
+		return nil, err
+	}
+	doc := FromHTMLParseTree(h, styles)
+	if doc == nil {
+		return nil, fmt.Errorf("cannot style HTML document")
+	}
+	return doc, nil
+}
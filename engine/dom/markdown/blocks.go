@@ -0,0 +1,368 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// --- Block structure -------------------------------------------------------
+
+// parseBlocks parses lines (with tabs expanded) into a sequence of block-level
+// HTML elements. Containers (block quotes and list items) are parsed
+// recursively.
+func parseBlocks(lines []string) []*html.Node {
+	var blocks []*html.Node
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			content := strings.TrimRight(strings.Join(para, "\n"), " ")
+			blocks = append(blocks, appendAll(element("p"), parseInlines(content)))
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		indent := leadingSpaces(line)
+		switch {
+		case isBlank(line):
+			flush()
+			i++
+		case indent >= 4 && len(para) == 0:
+			var n *html.Node
+			n, i = indentedCode(lines, i)
+			blocks = append(blocks, n)
+		case indent >= 4: // lazy paragraph continuation
+			para = append(para, strings.TrimLeft(line, " "))
+			i++
+		case isFence(line[indent:]):
+			flush()
+			var n *html.Node
+			n, i = fencedCode(lines, i)
+			blocks = append(blocks, n)
+		case atxLevel(line[indent:]) > 0:
+			flush()
+			blocks = append(blocks, atxHeading(line[indent:]))
+			i++
+		case len(para) > 0 && setextLevel(line[indent:]) > 0:
+			content := strings.TrimRight(strings.Join(para, "\n"), " ")
+			h := element("h" + strconv.Itoa(setextLevel(line[indent:])))
+			blocks = append(blocks, appendAll(h, parseInlines(content)))
+			para = nil
+			i++
+		case isThematicBreak(line[indent:]):
+			flush()
+			blocks = append(blocks, element("hr"))
+			i++
+		case strings.HasPrefix(line[indent:], ">"):
+			flush()
+			var n *html.Node
+			n, i = blockQuote(lines, i)
+			blocks = append(blocks, n)
+		case startsList(line, len(para) > 0):
+			flush()
+			var n *html.Node
+			n, i = list(lines, i)
+			blocks = append(blocks, n)
+		default:
+			para = append(para, line[indent:])
+			i++
+		}
+	}
+	flush()
+	return blocks
+}
+
+// startsBlock is true if line starts a block which interrupts a paragraph.
+func startsBlock(line string) bool {
+	if isBlank(line) {
+		return true
+	}
+	indent := leadingSpaces(line)
+	if indent >= 4 {
+		return false
+	}
+	rest := line[indent:]
+	return isFence(rest) || atxLevel(rest) > 0 || isThematicBreak(rest) ||
+		strings.HasPrefix(rest, ">") || startsList(line, true)
+}
+
+// --- Leaf blocks -----------------------------------------------------------
+
+// atxLevel returns the level of an ATX heading ("## Heading"), or 0.
+func atxLevel(s string) int {
+	l := 0
+	for l < len(s) && s[l] == '#' {
+		l++
+	}
+	if l == 0 || l > 6 || (l < len(s) && s[l] != ' ') {
+		return 0
+	}
+	return l
+}
+
+func atxHeading(s string) *html.Node {
+	level := atxLevel(s)
+	content := strings.TrimSpace(s[level:])
+	if t := strings.TrimRight(content, "#"); t == "" || strings.HasSuffix(t, " ") {
+		content = strings.TrimSpace(t) // closing sequence of #s
+	}
+	h := element("h" + strconv.Itoa(level))
+	return appendAll(h, parseInlines(content))
+}
+
+// setextLevel returns 1 for "===" and 2 for "---" underlines, or 0.
+func setextLevel(s string) int {
+	s = strings.TrimRight(s, " ")
+	if s == "" {
+		return 0
+	}
+	if strings.Trim(s, "=") == "" {
+		return 1
+	}
+	if strings.Trim(s, "-") == "" {
+		return 2
+	}
+	return 0
+}
+
+// isThematicBreak is true for lines of three or more '*', '-' or '_',
+// optionally separated by spaces.
+func isThematicBreak(s string) bool {
+	s = strings.ReplaceAll(strings.TrimRight(s, " "), " ", "")
+	if len(s) < 3 {
+		return false
+	}
+	return strings.Trim(s, s[:1]) == "" && strings.ContainsAny(s[:1], "*-_")
+}
+
+func isFence(s string) bool {
+	return strings.HasPrefix(s, "```") || strings.HasPrefix(s, "~~~")
+}
+
+// fencedCode parses a fenced code block starting at line i, returning the
+// code block and the index of the line following it.
+func fencedCode(lines []string, i int) (*html.Node, int) {
+	indent := leadingSpaces(lines[i])
+	opening := lines[i][indent:]
+	fence := opening[:len(opening)-len(strings.TrimLeft(opening, opening[:1]))]
+	info := strings.TrimSpace(opening[len(fence):])
+	var code []string
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if l := strings.TrimSpace(line); leadingSpaces(line) < 4 && strings.HasPrefix(l, fence) &&
+			strings.Trim(l, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, removeIndent(line, indent))
+	}
+	pre := element("pre", attr("data-type", "programlisting"))
+	if info != "" {
+		lang := strings.Fields(info)[0]
+		pre.Attr = append(pre.Attr, attr("data-code-language", lang))
+	}
+	if len(code) > 0 {
+		pre.AppendChild(text(strings.Join(code, "\n") + "\n"))
+	}
+	return pre, i
+}
+
+// indentedCode parses an indented code block starting at line i.
+func indentedCode(lines []string, i int) (*html.Node, int) {
+	var code []string
+	for ; i < len(lines); i++ {
+		if !isBlank(lines[i]) && leadingSpaces(lines[i]) < 4 {
+			break
+		}
+		code = append(code, removeIndent(lines[i], 4))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	pre := element("pre", attr("data-type", "programlisting"))
+	pre.AppendChild(text(strings.Join(code, "\n") + "\n"))
+	return pre, i
+}
+
+// --- Container blocks ------------------------------------------------------
+
+// blockQuote parses a block quote starting at line i, including lazy
+// continuation lines of a paragraph.
+func blockQuote(lines []string, i int) (*html.Node, int) {
+	var content []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		indent := leadingSpaces(line)
+		if indent < 4 && strings.HasPrefix(line[indent:], ">") {
+			rest := line[indent+1:]
+			if strings.HasPrefix(rest, " ") {
+				rest = rest[1:]
+			}
+			content = append(content, rest)
+			continue
+		}
+		if isBlank(line) || len(content) == 0 || isBlank(content[len(content)-1]) ||
+			startsBlock(line) {
+			break
+		}
+		content = append(content, line) // lazy continuation
+	}
+	return appendAll(element("blockquote"), parseBlocks(content)), i
+}
+
+// listMarker describes the marker of a list item.
+type listMarker struct {
+	ordered bool
+	char    byte // bullet character or delimiter of an ordered marker
+	start   int  // start number of an ordered list
+	offset  int  // column where the content of the item starts
+	empty   bool // the item starts with a blank line
+}
+
+// parseMarker checks if line starts with a list item marker.
+func parseMarker(line string) (listMarker, bool) {
+	indent := leadingSpaces(line)
+	if indent >= 4 || indent == len(line) {
+		return listMarker{}, false
+	}
+	m := listMarker{}
+	pos := indent
+	if c := line[pos]; c == '-' || c == '*' || c == '+' {
+		m.char = c
+		pos++
+	} else {
+		digits := 0
+		for pos < len(line) && line[pos] >= '0' && line[pos] <= '9' && digits < 9 {
+			pos++
+			digits++
+		}
+		if digits == 0 || pos == len(line) || (line[pos] != '.' && line[pos] != ')') {
+			return listMarker{}, false
+		}
+		m.ordered, m.char = true, line[pos]
+		m.start, _ = strconv.Atoi(line[indent:pos])
+		pos++
+	}
+	if pos < len(line) && line[pos] != ' ' {
+		return listMarker{}, false
+	}
+	spaces := leadingSpaces(line[pos:])
+	switch {
+	case pos+spaces == len(line):
+		m.empty, spaces = true, 1
+	case spaces > 4: // indented code as content
+		spaces = 1
+	}
+	m.offset = pos + spaces
+	return m, true
+}
+
+// startsList is true if line starts a list item. Within a paragraph, only
+// non-empty items of bullet lists or of ordered lists starting with 1 may
+// start a list.
+func startsList(line string, inParagraph bool) bool {
+	m, ok := parseMarker(line)
+	if !ok || isThematicBreak(strings.TrimLeft(line, " ")) {
+		return false
+	}
+	return !inParagraph || (!m.empty && (!m.ordered || m.start == 1))
+}
+
+// list parses a list starting at line i. Items of a tight list (without blank
+// lines between the blocks of items) have their paragraphs unwrapped.
+func list(lines []string, i int) (*html.Node, int) {
+	first, _ := parseMarker(lines[i])
+	var n *html.Node
+	if first.ordered {
+		n = element("ol")
+		if first.start != 1 {
+			n.Attr = append(n.Attr, attr("start", strconv.Itoa(first.start)))
+		}
+	} else {
+		n = element("ul")
+	}
+	loose := false
+	var items [][]string
+	for i < len(lines) {
+		m, ok := parseMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.char != first.char ||
+			isThematicBreak(strings.TrimLeft(lines[i], " ")) {
+			break
+		}
+		var content []string
+		if !m.empty {
+			content = append(content, lines[i][m.offset:])
+		}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				content = append(content, "")
+				continue
+			}
+			if leadingSpaces(line) >= m.offset {
+				content = append(content, line[m.offset:])
+				continue
+			}
+			if len(content) > 0 && !isBlank(content[len(content)-1]) && !startsBlock(line) {
+				content = append(content, strings.TrimLeft(line, " ")) // lazy continuation
+				continue
+			}
+			break
+		}
+		trailing := false
+		for len(content) > 0 && isBlank(content[len(content)-1]) {
+			content = content[:len(content)-1]
+			trailing = true
+		}
+		for _, l := range content {
+			loose = loose || isBlank(l)
+		}
+		if next, ok := parseMarker(lineAt(lines, i)); trailing && ok && next.char == first.char {
+			loose = true // blank line between items
+		}
+		items = append(items, content)
+	}
+	for _, content := range items {
+		li := element("li")
+		for _, b := range parseBlocks(content) {
+			if !loose && b.Type == html.ElementNode && b.Data == "p" {
+				for ch := b.FirstChild; ch != nil; ch = b.FirstChild {
+					b.RemoveChild(ch)
+					li.AppendChild(ch)
+				}
+				continue
+			}
+			li.AppendChild(b)
+		}
+		n.AppendChild(li)
+	}
+	return n, i
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+// lineAt returns line i of lines, or an empty line if i is out of range.
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// removeIndent removes up to n leading spaces from line.
+func removeIndent(line string, n int) string {
+	if l := leadingSpaces(line); l < n {
+		n = l
+	}
+	return line[n:]
+}
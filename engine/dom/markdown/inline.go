@@ -0,0 +1,316 @@
+package markdown
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// --- Inline content --------------------------------------------------------
+
+// parseInlines parses the inline content of a paragraph or heading.
+func parseInlines(s string) []*html.Node {
+	var nodes []*html.Node
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, text(buf.String()))
+			buf.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n': // hard line break
+			flush()
+			nodes = append(nodes, element("br"))
+			i += 2
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			buf.WriteByte(s[i+1])
+			i += 2
+		case c == '\n':
+			t := buf.String()
+			if trimmed := strings.TrimRight(t, " "); len(t)-len(trimmed) >= 2 {
+				buf.Reset()
+				buf.WriteString(trimmed)
+				flush()
+				nodes = append(nodes, element("br"))
+			} else {
+				buf.Reset()
+				buf.WriteString(trimmed)
+				buf.WriteByte('\n')
+			}
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+		case c == '`':
+			if n, end, ok := codeSpan(s, i); ok {
+				flush()
+				nodes = append(nodes, n)
+				i = end
+			} else {
+				run := runLength(s, i)
+				buf.WriteString(s[i : i+run])
+				i += run
+			}
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if content, dest, title, end, ok := link(s, i+1); ok {
+				flush()
+				img := element("img", attr("src", dest), attr("alt", plainText(parseInlines(content))))
+				if title != "" {
+					img.Attr = append(img.Attr, attr("title", title))
+				}
+				nodes = append(nodes, img)
+				i = end
+			} else {
+				buf.WriteByte(c)
+				i++
+			}
+		case c == '[':
+			if content, dest, title, end, ok := link(s, i); ok {
+				flush()
+				a := element("a", attr("href", dest))
+				if title != "" {
+					a.Attr = append(a.Attr, attr("title", title))
+				}
+				nodes = append(nodes, appendAll(a, parseInlines(content)))
+				i = end
+			} else {
+				buf.WriteByte(c)
+				i++
+			}
+		case c == '<':
+			if dest, end, ok := autolink(s, i); ok {
+				flush()
+				href := dest
+				if !strings.Contains(dest, ":") {
+					href = "mailto:" + dest
+				}
+				nodes = append(nodes, appendAll(element("a", attr("href", href)), []*html.Node{text(dest)}))
+				i = end
+			} else {
+				buf.WriteByte(c)
+				i++
+			}
+		case c == '*' || c == '_':
+			if n, end, ok := emphasis(s, i); ok {
+				flush()
+				nodes = append(nodes, n)
+				i = end
+			} else {
+				run := runLength(s, i)
+				buf.WriteString(s[i : i+run])
+				i += run
+			}
+		case c == '&':
+			if j := strings.IndexByte(s[i:], ';'); j > 1 && j < 32 {
+				if entity := s[i : i+j+1]; html.UnescapeString(entity) != entity {
+					buf.WriteString(html.UnescapeString(entity))
+					i += j + 1
+					continue
+				}
+			}
+			buf.WriteByte(c)
+			i++
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return nodes
+}
+
+// codeSpan parses a code span starting with a run of backticks at s[i], and
+// returns the <code> element and the position following the span.
+func codeSpan(s string, i int) (*html.Node, int, bool) {
+	run := runLength(s, i)
+	for j := i + run; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		if r := runLength(s, j); r != run {
+			j += r
+			continue
+		}
+		code := strings.ReplaceAll(s[i+run:j], "\n", " ")
+		if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		return appendAll(element("code"), []*html.Node{text(code)}), j + run, true
+	}
+	return nil, i, false
+}
+
+// emphasis parses emphasis (a run of one delimiter), strong emphasis (two) or
+// both (three) starting at s[i], with the closing run being of the same length.
+func emphasis(s string, i int) (*html.Node, int, bool) {
+	d := s[i]
+	run := runLength(s, i)
+	start := i + run
+	if run > 3 || start >= len(s) || isSpace(s[start]) {
+		return nil, i, false
+	}
+	if d == '_' && i > 0 && isAlnum(s[i-1]) { // intraword underscores
+		return nil, i, false
+	}
+	for j := start; j < len(s); {
+		switch {
+		case s[j] == '`': // code spans take precedence
+			if _, end, ok := codeSpan(s, j); ok {
+				j = end
+				continue
+			}
+			j += runLength(s, j)
+			continue
+		case s[j] == '\\':
+			j += 2
+			continue
+		case s[j] != d:
+			j++
+			continue
+		}
+		r := runLength(s, j)
+		if r == run && !isSpace(s[j-1]) && (d != '_' || j+r == len(s) || !isAlnum(s[j+r])) {
+			inner := parseInlines(s[start:j])
+			var n *html.Node
+			switch run {
+			case 1:
+				n = appendAll(element("em"), inner)
+			case 2:
+				n = appendAll(element("strong"), inner)
+			default:
+				n = element("strong")
+				n.AppendChild(appendAll(element("em"), inner))
+			}
+			return n, j + r, true
+		}
+		j += r
+	}
+	return nil, i, false
+}
+
+// link parses a link of the form [content](destination "title") starting with
+// the opening bracket at s[i]. It returns the content, destination and title
+// of the link and the position following it.
+func link(s string, i int) (content, dest, title string, end int, ok bool) {
+	depth := 0
+	j := i
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if j+1 >= len(s) || s[j] != ']' || s[j+1] != '(' {
+		return
+	}
+	content = s[i+1 : j]
+	k := skipSpaces(s, j+2)
+	if k < len(s) && s[k] == '<' { // <destination>
+		e := strings.IndexAny(s[k:], ">\n")
+		if e < 0 || s[k+e] != '>' {
+			return
+		}
+		dest, k = s[k+1:k+e], k+e+1
+	} else {
+		parens, d := 0, k
+		for ; k < len(s) && !isSpace(s[k]); k++ {
+			if s[k] == '(' {
+				parens++
+			} else if s[k] == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+		}
+		dest = s[d:k]
+	}
+	k = skipSpaces(s, k)
+	if k < len(s) && (s[k] == '"' || s[k] == '\'') {
+		e := strings.IndexByte(s[k+1:], s[k])
+		if e < 0 {
+			return
+		}
+		title, k = s[k+1:k+1+e], skipSpaces(s, k+e+2)
+	}
+	if k >= len(s) || s[k] != ')' {
+		return
+	}
+	return content, html.UnescapeString(dest), html.UnescapeString(title), k + 1, true
+}
+
+// autolink parses an autolink <scheme:…> or <address@host> starting at s[i].
+func autolink(s string, i int) (string, int, bool) {
+	e := strings.IndexByte(s[i:], '>')
+	if e < 0 {
+		return "", i, false
+	}
+	dest := s[i+1 : i+e]
+	if dest == "" || strings.ContainsAny(dest, " \n<") {
+		return "", i, false
+	}
+	if colon := strings.IndexByte(dest, ':'); colon >= 2 || strings.Contains(dest, "@") {
+		return dest, i + e + 1, true
+	}
+	return "", i, false
+}
+
+// plainText returns the text content of a sequence of inline nodes.
+func plainText(nodes []*html.Node) string {
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			collect(ch)
+		}
+	}
+	for _, n := range nodes {
+		collect(n)
+	}
+	return b.String()
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+// runLength returns the length of the run of characters equal to s[i].
+func runLength(s string, i int) int {
+	j := i
+	for j < len(s) && s[j] == s[i] {
+		j++
+	}
+	return j - i
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
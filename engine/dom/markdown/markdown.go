@@ -0,0 +1,183 @@
+/*
+Package markdown is an input adapter for documents written in Markdown.
+
+Markdown is converted to an HTML parse tree following HTMLBook conventions,
+which then runs through the usual styling pipeline (see package dom). This
+lets small documents be typeset without authoring HTML.
+
+Level-1 headings start a chapter, deeper headings start nested sections:
+
+    # Title        ⟹  <section data-type="chapter"><h1>Title</h1> …
+    ## Section     ⟹  <section data-type="sect1"><h1>Section</h1> …
+    ### Sub       ⟹  <section data-type="sect2"><h2>Sub</h2> …
+
+Fenced and indented code blocks become
+<pre data-type="programlisting">, with the info string of a fence as
+attribute data-code-language.
+
+Status
+
+The parser implements a subset of CommonMark (https://commonmark.org):
+ATX and setext headings, paragraphs, hard line breaks, thematic breaks,
+block quotes, bullet and ordered lists, fenced and indented code blocks,
+emphasis, strong emphasis, code spans, links, images, autolinks, backslash
+escapes and entity references. Raw HTML, link reference definitions and
+tables are not supported and are treated as text.
+
+___________________________________________________________________________
+
+License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+
+*/
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// tracer will return a tracer. We are tracing to 'tyse.dom'
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.dom")
+}
+
+// Parse reads a Markdown document from r and returns it as an HTML parse tree
+// following HTMLBook conventions. The tree is a document node containing an
+// <html> element with <head> and <body>, as returned by html.Parse.
+func Parse(r io.Reader) (*html.Node, error) {
+	if r == nil {
+		return nil, fmt.Errorf("cannot parse Markdown: no input")
+	}
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read Markdown document: %w", err)
+	}
+	text := strings.ReplaceAll(string(src), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	blocks := parseBlocks(strings.Split(text, "\n"))
+	tracer().Debugf("Markdown document has %d top-level blocks", len(blocks))
+	doc := &html.Node{Type: html.DocumentNode}
+	root := element("html")
+	body := element("body", attr("data-type", "book"))
+	doc.AppendChild(root)
+	root.AppendChild(element("head"))
+	root.AppendChild(body)
+	sectionize(body, blocks)
+	return doc, nil
+}
+
+// ToDOM reads a Markdown document from r and returns its W3C DOM, styled with
+// the HTMLBook default styles and an optional author style sheet css, just as
+// dom.FromHTML does for HTML documents.
+func ToDOM(r io.Reader, css cssom.StyleSheet) (*dom.W3CNode, error) {
+	h, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	styles, err := dom.HTMLBookStyles(css)
+	if err != nil {
+		return nil, err
+	}
+	doc := dom.FromHTMLParseTree(h, styles)
+	if doc == nil {
+		return nil, fmt.Errorf("cannot style Markdown document")
+	}
+	return doc, nil
+}
+
+// --- Sections --------------------------------------------------------------
+
+// sectionTypes are the HTMLBook section types for heading levels 1…6.
+var sectionTypes = [...]string{"chapter", "sect1", "sect2", "sect3", "sect4", "sect5"}
+
+// sectionize appends blocks to body, wrapping every heading and the blocks
+// following it into a section. Sections of deeper headings are nested into
+// the enclosing ones. Following HTMLBook, chapters and sect1 sections are
+// headed by <h1>, sect<n> sections by <h<n>>.
+func sectionize(body *html.Node, blocks []*html.Node) {
+	type section struct {
+		level int
+		node  *html.Node
+	}
+	var open []section
+	for _, b := range blocks {
+		level := headingLevel(b)
+		if level == 0 {
+			if len(open) == 0 {
+				body.AppendChild(b)
+			} else {
+				open[len(open)-1].node.AppendChild(b)
+			}
+			continue
+		}
+		for len(open) > 0 && open[len(open)-1].level >= level {
+			open = open[:len(open)-1]
+		}
+		sec := element("section", attr("data-type", sectionTypes[level-1]))
+		if len(open) == 0 {
+			body.AppendChild(sec)
+		} else {
+			open[len(open)-1].node.AppendChild(sec)
+		}
+		if level > 1 {
+			rename(b, fmt.Sprintf("h%d", level-1))
+		}
+		sec.AppendChild(b)
+		open = append(open, section{level: level, node: sec})
+	}
+}
+
+// headingLevel returns 1…6 for heading elements and 0 for anything else.
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode || len(n.Data) != 2 || n.Data[0] != 'h' {
+		return 0
+	}
+	if l := int(n.Data[1] - '0'); l >= 1 && l <= 6 {
+		return l
+	}
+	return 0
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+func element(tag string, attrs ...html.Attribute) *html.Node {
+	return &html.Node{
+		Type:     html.ElementNode,
+		Data:     tag,
+		DataAtom: atom.Lookup([]byte(tag)),
+		Attr:     attrs,
+	}
+}
+
+func rename(n *html.Node, tag string) {
+	n.Data = tag
+	n.DataAtom = atom.Lookup([]byte(tag))
+}
+
+func attr(key, val string) html.Attribute {
+	return html.Attribute{Key: key, Val: val}
+}
+
+func text(s string) *html.Node {
+	return &html.Node{Type: html.TextNode, Data: s}
+}
+
+// appendAll appends children to n and returns n.
+func appendAll(n *html.Node, children []*html.Node) *html.Node {
+	for _, ch := range children {
+		n.AppendChild(ch)
+	}
+	return n
+}
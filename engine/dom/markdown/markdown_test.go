@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/dom"
+	"golang.org/x/net/html"
+)
+
+var mddoc = `# Chapter
+
+Some *emphasized* and **strong** text with ` + "`code`" + `.
+
+## Section
+
+- one
+- two
+
+` + "```go\nfunc main() {}\n```\n"
+
+func TestMarkdownStructure(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.dom")
+	defer teardown()
+	//
+	doc, err := Parse(strings.NewReader(mddoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc.FirstChild.LastChild
+	chapter := body.FirstChild
+	if chapter == nil || chapter.Data != "section" || chapter.Attr[0].Val != "chapter" {
+		t.Fatalf("expected chapter as first child of body, have %v", chapter)
+	}
+	var tags []string
+	for n := chapter.FirstChild; n != nil; n = n.NextSibling {
+		tags = append(tags, n.Data)
+	}
+	if strings.Join(tags, " ") != "h1 p section" {
+		t.Errorf("expected chapter to contain 'h1 p section', has '%s'", strings.Join(tags, " "))
+	}
+	p := chapter.FirstChild.NextSibling
+	if em := p.FirstChild.NextSibling; em.Data != "em" || em.FirstChild.Data != "emphasized" {
+		t.Errorf("expected <em>emphasized</em>, have <%s>", em.Data)
+	}
+	sect1 := chapter.LastChild
+	tags = tags[:0]
+	for n := sect1.FirstChild; n != nil; n = n.NextSibling {
+		tags = append(tags, n.Data)
+	}
+	if strings.Join(tags, " ") != "h1 ul pre" {
+		t.Errorf("expected sect1 to contain 'h1 ul pre', has '%s'", strings.Join(tags, " "))
+	}
+	if pre := sect1.LastChild; attrValue(pre, "data-code-language") != "go" {
+		t.Errorf("expected code block to be of language 'go'")
+	}
+}
+
+func TestMarkdownToDOM(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.dom")
+	defer teardown()
+	//
+	root, err := ToDOM(strings.NewReader(mddoc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := root.FirstChild().FirstChild().NextSibling().(*dom.W3CNode)
+	chapter := body.FirstChild().(*dom.W3CNode)
+	if v := chapter.ComputedStyles().GetPropertyValue("break-before"); v.String() != "page" {
+		t.Errorf("expected chapter to break before page, is '%s'", v)
+	}
+	if chapter.FirstChild().NodeType() != html.ElementNode {
+		t.Errorf("expected heading as first child of chapter")
+	}
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -1,6 +1,10 @@
 package style
 
-import "image/color"
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
 
 // TODO use standard palette
 //
@@ -11,6 +15,9 @@ func (p Property) Color() color.Color {
 		//if p == "default" || p == NullStyle {
 		return nil
 	}
+	if p == "transparent" || p == "currentcolor" {
+		return nil // no color of its own
+	}
 	switch p {
 	case "red":
 		return color.RGBA{0xff, 0, 0, 0xff}
@@ -20,10 +27,51 @@ func (p Property) Color() color.Color {
 		return color.RGBA{0, 0, 0xff, 0xff}
 	case "gray", "grey":
 		return color.RGBA{0x80, 0x80, 0x80, 0xff}
+	case "white":
+		return color.White
+	}
+	if c, ok := parseColor(string(p)); ok {
+		return c
 	}
 	return color.Black
 }
 
+// parseColor parses hex colors (#rgb and #rrggbb) and functional colors
+// rgb(r, g, b) with components in 0…255.
+func parseColor(s string) (color.Color, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return nil, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return nil, false
+		}
+		return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+	}
+	if strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")") {
+		comps := strings.Split(s[4:len(s)-1], ",")
+		if len(comps) != 3 {
+			return nil, false
+		}
+		var rgb [3]uint8
+		for i, comp := range comps {
+			v, err := strconv.Atoi(strings.TrimSpace(comp))
+			if err != nil || v < 0 || v > 255 {
+				return nil, false
+			}
+			rgb[i] = uint8(v)
+		}
+		return color.RGBA{rgb[0], rgb[1], rgb[2], 0xff}, true
+	}
+	return nil, false
+}
+
 func ColorString(c color.Color) string {
 	if c == nil {
 		return "powderblue" // X11 color and CSS color
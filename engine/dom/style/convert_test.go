@@ -0,0 +1,25 @@
+package style_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+func TestPropertyColor(t *testing.T) {
+	for p, c := range map[style.Property]color.Color{
+		"red":              color.RGBA{0xff, 0, 0, 0xff},
+		"white":            color.White,
+		"#fc0":             color.RGBA{0xff, 0xcc, 0, 0xff},
+		"#102030":          color.RGBA{0x10, 0x20, 0x30, 0xff},
+		"rgb(1, 2, 3)":     color.RGBA{1, 2, 3, 0xff},
+		"transparent":      nil,
+		"rgb(1, 2, 300)":   color.Black,
+		"some-other-color": color.Black,
+	} {
+		if x := p.Color(); x != c {
+			t.Errorf("expected %s to be %v, is %v", p, c, x)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// BreakT is an enum type for the CSS fragmentation properties break-before,
+// break-after and break-inside.
+type BreakT uint8
+
+// Enum values for type BreakT
+const (
+	BreakAuto      BreakT = iota // CSS auto (default)
+	BreakAvoid                   // CSS avoid
+	BreakAvoidPage               // CSS avoid-page
+	BreakPage                    // CSS page
+	BreakLeft                    // CSS left
+	BreakRight                   // CSS right
+)
+
+// Break returns the break type from a property string. The legacy values of
+// page-break-before and page-break-after ("always") are accepted as well.
+// Illegal input and unset properties result in BreakAuto.
+func Break(p style.Property) BreakT {
+	switch strings.ToLower(string(p)) {
+	case "avoid", "avoid-column", "avoid-region":
+		return BreakAvoid
+	case "avoid-page":
+		return BreakAvoidPage
+	case "page", "always", "column", "region":
+		return BreakPage
+	case "left", "verso":
+		return BreakLeft
+	case "right", "recto":
+		return BreakRight
+	}
+	return BreakAuto
+}
+
+// IsForced returns true if b forces a page break.
+func (b BreakT) IsForced() bool {
+	return b >= BreakPage
+}
+
+// Avoids returns true if b asks to avoid a page break.
+func (b BreakT) Avoids() bool {
+	return b == BreakAvoid || b == BreakAvoidPage
+}
+
+// LineCount returns the number of lines from a property string, as used for the
+// CSS properties widows and orphans. If p is not a positive integer, dflt is
+// returned.
+func LineCount(p style.Property, dflt int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(string(p)))
+	if err != nil || n < 1 {
+		return dflt
+	}
+	return n
+}
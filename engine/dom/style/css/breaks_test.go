@@ -0,0 +1,25 @@
+package css_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestBreakProperties(t *testing.T) {
+	if b := css.Break("always"); !b.IsForced() || b != css.BreakPage {
+		t.Errorf("expected legacy 'always' to force a page break")
+	}
+	if b := css.Break("recto"); b != css.BreakRight || !b.IsForced() {
+		t.Errorf("expected 'recto' to force a break to a right page")
+	}
+	if b := css.Break("avoid-page"); !b.Avoids() || b.IsForced() {
+		t.Errorf("expected 'avoid-page' to avoid a break")
+	}
+	if css.Break("") != css.BreakAuto {
+		t.Errorf("expected unset break property to be auto")
+	}
+	if css.LineCount("3", 2) != 3 || css.LineCount("0", 2) != 2 || css.LineCount("x", 2) != 2 {
+		t.Errorf("expected widows/orphans to be parsed as positive line counts")
+	}
+}
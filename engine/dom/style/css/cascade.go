@@ -28,7 +28,11 @@ func GetCascadedProperty(node *styledtree.StyNode, key string) (style.Property,
 	var group *style.PropertyGroup
 	for node != nil && group == nil {
 		group = node.Styles().Group(groupname)
-		node = node.Parent().Payload
+		if parent := node.Parent(); parent != nil {
+			node = parent.Payload
+		} else {
+			node = nil
+		}
 	}
 	if group == nil {
 		errmsg := fmt.Sprintf("Cannot find ancestor with prop-group %s -- did you create global properties?", groupname)
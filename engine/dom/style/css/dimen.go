@@ -147,7 +147,7 @@ func (m *DMatcher) Just(du *dimen.DU) *DMatcher {
 }
 
 func (m *DMatcher) Percentage(p *Percent) *DMatcher {
-	if m.dimen.flags&dimenPercent > 0 {
+	if m.dimen.IsPercent() {
 		if p != nil {
 			*p = m.dimen.percent
 		}
@@ -215,7 +215,7 @@ func (d DimenT) IsRelative() bool {
 
 // IsPercent returns true if d represents a percentage dimension (`%`).
 func (d DimenT) IsPercent() bool {
-	return d.flags&dimenPercent > 0
+	return d.flags&relativeMask == dimenPercent
 }
 
 // IsAbsolute returns true if d represents a valid absolute dimension.
@@ -223,17 +223,38 @@ func (d DimenT) IsAbsolute() bool {
 	return d.flags == dimenAbsolute
 }
 
+// ScaleFromFont resolves font-relative dimensions (`em`, `rem`, `ex`, `ch`)
+// against a font size. Other dimensions are returned unchanged.
+//
+// See type Resolution for resolving dimensions within a complete context.
+func (d DimenT) ScaleFromFont(fontsize dimen.DU) DimenT {
+	return Resolution{FontSize: fontsize}.fontRelative(d)
+}
+
+// ScaleFromViewport resolves viewport-relative dimensions (`vw`, `vh`, `vmin`, `vmax`)
+// against a viewport of size w × h. Other dimensions are returned unchanged.
+func (d DimenT) ScaleFromViewport(w, h dimen.DU) DimenT {
+	return Resolution{Viewport: dimen.Point{X: w, Y: h}}.viewportRelative(d)
+}
+
 // ---------------------------------------------------------------------------
 
-var dimenPattern = regexp.MustCompile(`^([+\-]?[0-9]+)(%|[a-zA-Z]{2,4})?$`)
+var dimenPattern = regexp.MustCompile(`^([+\-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+))(%|[a-zA-Z]{1,4})?$`)
+
+// relativeScale is the fixed-point scale for values of relative dimensions,
+// which may be fractional, e.g. `1.5em`.
+const relativeScale = 1 << 16
 
-// ParseDimen parses a string to return an optional dimension. Syntax is CSS Unit.
-// Valid dimensions are
+// ParseDimen parses a string to return an optional dimension. Syntax is CSS Unit,
+// with values optionally having a fractional part. Valid dimensions are
 //
-//     15px
-//     80%
-//     -33rem
+//	15px
+//	80%
+//	-33rem
+//	1.5em
 //
+// Pixels are converted at 72 DPI. To convert lengths for other devices, use
+// Resolution.Length.
 func ParseDimen(s string) (DimenT, error) {
 	// tracer().Debugf("parse dimen string = '%s'", s)
 	if s == "" || s == "none" {
@@ -247,42 +268,41 @@ func ParseDimen(s string) (DimenT, error) {
 	case "thick":
 		return JustDimen(dimen.PX * 2), nil
 	}
-	d := dimenPattern.FindStringSubmatch(s)
-	if len(d) < 2 {
+	x, unit, ok := splitDimen(s)
+	if !ok {
 		return DimenT{}, errors.New("format error parsing dimension")
 	}
-	scale := dimen.SP
-	dim := JustDimen(0)
-	if len(d) > 2 {
-		switch d[2] {
-		case "pt", "PT":
-			scale = dimen.PT
-		case "mm", "MM":
-			scale = dimen.MM
-		case "bp", "px", "BP", "PX":
-			scale = dimen.BP
-		case "cm", "CM":
-			scale = dimen.CM
-		case "in", "IN":
-			scale = dimen.IN
-		case "", "sp", "SP":
-			scale = dimen.SP
-		default:
-			u := strings.ToLower(d[2])
-			if unit, ok := relUnitStringMap[u]; ok {
-				dim = DimenT{}
-				dim.flags = unit
-			} else {
-				return DimenT{}, errors.New("format error parsing dimension")
-			}
-		}
+	switch unit {
+	case "", "sp":
+		return JustDimen(dimen.FromFloat(x)), nil
+	case "q":
+		return JustDimen(dimen.FromFloat(x * float64(dimen.MM) / 4)), nil
+	case "%":
+		dim := Percentage(FromFloat(x))
+		dim.d = dimen.FromFloat(x)
+		return dim, nil
 	}
-	n, err := strconv.Atoi(d[1])
-	if err != nil { // this cannot happen
-		return DimenT{}, errors.New("format error parsing dimension")
+	if scale, ok := dimen.UnitOf(unit); ok {
+		return JustDimen(dimen.FromFloat(x * float64(scale))), nil
+	}
+	if flags, ok := relUnitStringMap[unit]; ok {
+		return DimenT{d: dimen.FromFloat(x * relativeScale), flags: flags}, nil
+	}
+	return DimenT{}, errors.New("format error parsing dimension")
+}
+
+// splitDimen splits a CSS dimension into its numeric value and its unit, which
+// is returned in lower case.
+func splitDimen(s string) (float64, string, bool) {
+	d := dimenPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if d == nil {
+		return 0, "", false
+	}
+	x, err := strconv.ParseFloat(d[1], 64)
+	if err != nil {
+		return 0, "", false
 	}
-	dim.d = dimen.DU(n) * scale
-	return dim, nil
+	return x, strings.ToLower(d[2]), true
 }
 
 // UnitString returns 'sp' (scaled points) for non-relative dimensions and a string
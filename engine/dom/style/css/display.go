@@ -16,6 +16,7 @@ const (
 	DisplayNone     DisplayMode = 0x0001 // CSS outer display = none
 	BlockMode       DisplayMode = 0x0002 // CSS block context (inner or outer)
 	InlineMode      DisplayMode = 0x0004 // CSS inline context
+	RunInMode       DisplayMode = 0x0008 // CSS run-in, resolved to block or inline during box generation
 	FlowRootMode    DisplayMode = 0x0010 // CSS flow-root display property
 	ListItemMode    DisplayMode = 0x0020 // CSS list-item display
 	FlexMode        DisplayMode = 0x0040 // CSS inner display = flex
@@ -26,7 +27,7 @@ const (
 )
 
 var allDisplayModes = []DisplayMode{
-	DisplayNone, BlockMode, InlineMode, ListItemMode, FlowRootMode, FlexMode,
+	DisplayNone, BlockMode, InlineMode, RunInMode, ListItemMode, FlowRootMode, FlexMode,
 	GridMode, TableMode, InnerBlockMode, InnerInlineMode,
 }
 
@@ -140,10 +141,18 @@ func ParseDisplay(display string) (DisplayMode, error) {
 		return BlockMode | InnerInlineMode, nil
 	case "inline-block":
 		return InlineMode | InnerBlockMode, nil
+	case "run-in":
+		return RunInMode | InnerInlineMode, nil
 	case "table":
 		return BlockMode | TableMode, nil
 	case "inline-table":
 		return InlineMode | TableMode, nil
+	case "flex":
+		return BlockMode | FlexMode, nil
+	case "inline-flex":
+		return InlineMode | FlexMode, nil
+	case "ruby", "ruby-base", "ruby-text": // ruby annotations are set by the paragraph
+		return InlineMode | InnerInlineMode, nil
 	}
 	return BlockMode, fmt.Errorf("Unknown display mode: %s", display)
 }
@@ -0,0 +1,137 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// FlexDirectionT is an enum type for the CSS flex-direction property.
+type FlexDirectionT uint8
+
+// Enum values for type FlexDirectionT
+const (
+	FlexRow           FlexDirectionT = iota // CSS row (default)
+	FlexRowReverse                          // CSS row-reverse
+	FlexColumn                              // CSS column
+	FlexColumnReverse                       // CSS column-reverse
+)
+
+// FlexDirection returns the flex direction from a property string. Illegal input
+// and unset properties result in FlexRow.
+func FlexDirection(p style.Property) FlexDirectionT {
+	switch strings.ToLower(string(p)) {
+	case "row-reverse":
+		return FlexRowReverse
+	case "column":
+		return FlexColumn
+	case "column-reverse":
+		return FlexColumnReverse
+	}
+	return FlexRow
+}
+
+// IsColumn returns true if the main axis of d is vertical.
+func (d FlexDirectionT) IsColumn() bool {
+	return d == FlexColumn || d == FlexColumnReverse
+}
+
+// IsReverse returns true if items are placed from the end of the main axis.
+func (d FlexDirectionT) IsReverse() bool {
+	return d == FlexRowReverse || d == FlexColumnReverse
+}
+
+// FlexWrapT is an enum type for the CSS flex-wrap property.
+type FlexWrapT uint8
+
+// Enum values for type FlexWrapT
+const (
+	FlexNoWrap      FlexWrapT = iota // CSS nowrap (default)
+	FlexWrap                         // CSS wrap
+	FlexWrapReverse                  // CSS wrap-reverse
+)
+
+// FlexWrapping returns the flex wrap mode from a property string. Illegal input
+// and unset properties result in FlexNoWrap.
+func FlexWrapping(p style.Property) FlexWrapT {
+	switch strings.ToLower(string(p)) {
+	case "wrap":
+		return FlexWrap
+	case "wrap-reverse":
+		return FlexWrapReverse
+	}
+	return FlexNoWrap
+}
+
+// JustifyContentT is an enum type for the CSS justify-content property.
+type JustifyContentT uint8
+
+// Enum values for type JustifyContentT
+const (
+	JustifyStart        JustifyContentT = iota // CSS flex-start (default)
+	JustifyEnd                                 // CSS flex-end
+	JustifyCenter                              // CSS center
+	JustifySpaceBetween                        // CSS space-between
+	JustifySpaceAround                         // CSS space-around
+	JustifySpaceEvenly                         // CSS space-evenly
+)
+
+// JustifyContent returns the justification of flex items from a property
+// string. Illegal input and unset properties result in JustifyStart.
+func JustifyContent(p style.Property) JustifyContentT {
+	switch strings.ToLower(string(p)) {
+	case "flex-end", "end", "right":
+		return JustifyEnd
+	case "center":
+		return JustifyCenter
+	case "space-between":
+		return JustifySpaceBetween
+	case "space-around":
+		return JustifySpaceAround
+	case "space-evenly":
+		return JustifySpaceEvenly
+	}
+	return JustifyStart
+}
+
+// AlignItemsT is an enum type for the CSS align-items and align-self properties.
+type AlignItemsT uint8
+
+// Enum values for type AlignItemsT
+const (
+	AlignAuto     AlignItemsT = iota // CSS auto, for align-self only
+	AlignStretch                     // CSS stretch (default)
+	AlignStart                       // CSS flex-start
+	AlignEnd                         // CSS flex-end
+	AlignCenter                      // CSS center
+	AlignBaseline                    // CSS baseline
+)
+
+// AlignItems returns the cross axis alignment of flex items from a property
+// string. Illegal input and unset properties result in AlignAuto.
+func AlignItems(p style.Property) AlignItemsT {
+	switch strings.ToLower(string(p)) {
+	case "stretch", "normal":
+		return AlignStretch
+	case "flex-start", "start", "self-start":
+		return AlignStart
+	case "flex-end", "end", "self-end":
+		return AlignEnd
+	case "center":
+		return AlignCenter
+	case "baseline":
+		return AlignBaseline
+	}
+	return AlignAuto
+}
+
+// FlexFactor returns a flex grow or shrink factor from a property string. If p
+// is not a valid non-negative number, dflt is returned.
+func FlexFactor(p style.Property, dflt float64) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(string(p)), 64)
+	if err != nil || f < 0 {
+		return dflt
+	}
+	return f
+}
@@ -0,0 +1,31 @@
+package css_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestFlexProperties(t *testing.T) {
+	if d := css.FlexDirection("column-reverse"); !d.IsColumn() || !d.IsReverse() {
+		t.Errorf("expected column-reverse to be a reversed column direction")
+	}
+	if css.FlexWrapping("wrap") != css.FlexWrap || css.JustifyContent("space-between") != css.JustifySpaceBetween {
+		t.Errorf("expected flex-wrap and justify-content to be recognized")
+	}
+	if css.AlignItems("center") != css.AlignCenter || css.AlignItems("auto") != css.AlignAuto {
+		t.Errorf("expected align-items to be recognized")
+	}
+	if css.FlexFactor("2.5", 0) != 2.5 || css.FlexFactor("x", 1) != 1 {
+		t.Errorf("expected flex factors to be parsed")
+	}
+	kv, err := style.SplitCompoundProperty("flex", "2")
+	if err != nil || kv[0].Value != "2" || kv[1].Value != "1" || kv[2].Value != "0" {
+		t.Errorf("expected 'flex: 2' to be split into (2, 1, 0), is %v", kv)
+	}
+	kv, _ = style.SplitCompoundProperty("flex", "none")
+	if kv[0].Value != "0" || kv[1].Value != "0" || kv[2].Value != "auto" {
+		t.Errorf("expected 'flex: none' to be split into (0, 0, auto), is %v", kv)
+	}
+}
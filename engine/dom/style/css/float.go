@@ -0,0 +1,70 @@
+package css
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// FloatT is an enum type for the CSS float property.
+type FloatT uint8
+
+// Enum values for type FloatT
+const (
+	FloatNone  FloatT = iota // CSS none (default)
+	FloatLeft                // CSS left
+	FloatRight               // CSS right
+)
+
+// Float returns the float type from a property string. Illegal input and unset
+// properties result in FloatNone.
+func Float(p style.Property) FloatT {
+	switch strings.ToLower(string(p)) {
+	case "left", "inline-start":
+		return FloatLeft
+	case "right", "inline-end":
+		return FloatRight
+	}
+	return FloatNone
+}
+
+// IsFloating returns true if f is not FloatNone.
+func (f FloatT) IsFloating() bool {
+	return f != FloatNone
+}
+
+// ClearT is an enum type for the CSS clear property.
+type ClearT uint8
+
+// Enum values for type ClearT
+const (
+	ClearNone  ClearT = 0x00 // CSS none (default)
+	ClearLeft  ClearT = 0x01 // CSS left
+	ClearRight ClearT = 0x02 // CSS right
+	ClearBoth  ClearT = 0x03 // CSS both
+)
+
+// Clear returns the clear type from a property string. Illegal input and unset
+// properties result in ClearNone.
+func Clear(p style.Property) ClearT {
+	switch strings.ToLower(string(p)) {
+	case "left", "inline-start":
+		return ClearLeft
+	case "right", "inline-end":
+		return ClearRight
+	case "both":
+		return ClearBoth
+	}
+	return ClearNone
+}
+
+// Clears returns true if c requires a box to be placed below floats on side f.
+func (c ClearT) Clears(f FloatT) bool {
+	switch f {
+	case FloatLeft:
+		return c&ClearLeft != 0
+	case FloatRight:
+		return c&ClearRight != 0
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// ColumnCount returns the number of columns from a value of the CSS column-count
+// property. "auto", illegal input and unset properties result in 0.
+func ColumnCount(p style.Property) int {
+	n, err := strconv.Atoi(strings.TrimSpace(string(p)))
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// ColumnWidth returns the optimal width of columns from a value of the CSS
+// column-width property, for text set at font size fontsize. "auto", illegal
+// input and unset properties result in 0.
+func ColumnWidth(p style.Property, fontsize dimen.DU) dimen.DU {
+	w, err := Resolution{FontSize: fontsize}.Length(p, 0)
+	if err != nil || w < 0 {
+		return 0
+	}
+	return w
+}
+
+// ColumnGap returns the gap between columns from a value of the CSS column-gap
+// property, for text set at font size fontsize. Percentages refer to the width
+// of the multi-column container, w. "normal", illegal input and unset properties
+// result in 1em.
+func ColumnGap(p style.Property, fontsize, w dimen.DU) dimen.DU {
+	gap, err := Resolution{FontSize: fontsize}.Length(p, w)
+	if err != nil || gap < 0 {
+		return Resolution{FontSize: fontsize}.fontSize()
+	}
+	return gap
+}
+
+// IsMulticol returns true if the values of the CSS properties column-count and
+// column-width make an element a multi-column container.
+func IsMulticol(count, width style.Property) bool {
+	return ColumnCount(count) > 0 || ColumnWidth(width, DefaultFontSize) > 0
+}
+
+// Columns determines the used number and width of columns for a multi-column
+// container with a content width of avail, following
+// https://www.w3.org/TR/css-multicol-1/#pseudo-algorithm . count and width are
+// the values of column-count and column-width, with 0 for `auto`, and gap is the
+// gap between columns. At least one column is returned.
+func Columns(count int, width, gap, avail dimen.DU) (int, dimen.DU) {
+	n := count
+	if width > 0 {
+		fitting := int((avail + gap) / (width + gap))
+		if n == 0 || fitting < n {
+			n = fitting
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	w := (avail+gap)/dimen.DU(n) - gap
+	if w < 0 {
+		w = 0
+	}
+	return n, w
+}
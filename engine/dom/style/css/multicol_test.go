@@ -0,0 +1,45 @@
+package css_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestColumnProperties(t *testing.T) {
+	if css.ColumnCount("3") != 3 || css.ColumnCount("auto") != 0 || css.ColumnCount("0") != 0 {
+		t.Errorf("expected column-count to be parsed as positive count or auto")
+	}
+	if w := css.ColumnWidth("10em", 10*dimen.PT); w != 100*dimen.PT {
+		t.Errorf("expected column width of 100pt, have %v", w)
+	}
+	if gap := css.ColumnGap("normal", 12*dimen.PT, 0); gap != 12*dimen.PT {
+		t.Errorf("expected normal column gap to be 1em, have %v", gap)
+	}
+	if !css.IsMulticol("auto", "20em") || css.IsMulticol("auto", "auto") {
+		t.Errorf("expected column-width to make a multi-column container")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	for _, x := range []struct {
+		count      int
+		width, gap dimen.DU
+		avail      dimen.DU
+		n          int
+		expectedW  dimen.DU
+	}{
+		{3, 0, 10, 320, 3, 100},
+		{0, 100, 10, 320, 3, 100},
+		{4, 100, 10, 320, 3, 100},
+		{2, 50, 10, 320, 2, 155},
+		{0, 400, 10, 320, 1, 320},
+	} {
+		n, w := css.Columns(x.count, x.width*dimen.PT, x.gap*dimen.PT, x.avail*dimen.PT)
+		if n != x.n || w != x.expectedW*dimen.PT {
+			t.Errorf("columns(%d, %v): expected %d columns of %dpt, have %d of %v",
+				x.count, x.width, x.n, x.expectedW, n, w)
+		}
+	}
+}
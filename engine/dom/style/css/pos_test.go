@@ -68,3 +68,17 @@ func TestPositionAuto(t *testing.T) {
 		t.Errorf("expected ABSOLUTE, have %v", x)
 	}
 }
+
+func TestPositionOffsets(t *testing.T) {
+	a := css.Absolute(css.PositionOffsets("10pt", "auto", "", "50%"))
+	if !a.IsAbsolute() || a.IsFixed() || css.Position("").IsAbsolute() {
+		t.Errorf("expected position to be absolute, isn't: %#v", a)
+	}
+	o := a.Offsets()
+	if len(o) != 4 || !o[css.Top].Dim.IsAbsolute() || !o[css.Left].Dim.IsPercent() {
+		t.Fatalf("expected top and left offsets to be set, have %v", o)
+	}
+	if !o[css.Right].Dim.IsNone() || !o[css.Bottom].Dim.IsNone() {
+		t.Errorf("expected right and bottom offsets to be unset, have %v", o)
+	}
+}
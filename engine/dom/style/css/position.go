@@ -137,6 +137,26 @@ func Position(p style.Property) PositionT {
 	return PositionT{}
 }
 
+// PositionOffsets creates position offsets from the CSS properties "top",
+// "right", "bottom" and "left". Offsets which are unset, `auto` or illegal are
+// omitted.
+func PositionOffsets(top, right, bottom, left style.Property) []PositionOffset {
+	offsets := make([]PositionOffset, 0, 4)
+	for dir, p := range [4]style.Property{top, right, bottom, left} {
+		d := DimenOption(p)
+		if d.IsAbsolute() || d.IsPercent() {
+			offsets = append(offsets, PositionOffset{Dim: d, Dir: PosDir(dir)})
+		}
+	}
+	return offsets
+}
+
+// Offsets returns the offsets of p, ordered by PosDir. For positions without
+// offsets, nil is returned.
+func (p PositionT) Offsets() []PositionOffset {
+	return p.offsets
+}
+
 // ---------------------------------------------------------------------------
 
 func (p PositionT) Match() *PMatcher {
@@ -251,9 +271,14 @@ func (p PositionT) IsRelative() bool {
 	return p.kind == positionRelative
 }
 
+// IsStatic returns true if p represents a static position.
+func (p PositionT) IsStatic() bool {
+	return p.kind == positionStatic
+}
+
 // IsAbsolute returns true if d represents a valid absolute position.
 func (p PositionT) IsAbsolute() bool {
-	return p.kind == positionUnset
+	return p.kind == positionAbsolute
 }
 
 // IsFixed returns true if d represents a fixed position.
@@ -0,0 +1,290 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// TextAlignT is an enum type for the CSS properties text-align and text-align-last.
+type TextAlignT uint8
+
+// Enum values for type TextAlignT
+const (
+	TextAlignStart   TextAlignT = iota // CSS start (default)
+	TextAlignEnd                       // CSS end
+	TextAlignLeft                      // CSS left
+	TextAlignRight                     // CSS right
+	TextAlignCenter                    // CSS center
+	TextAlignJustify                   // CSS justify and justify-all
+)
+
+// TextAlign returns the alignment type from a property string. Illegal input and
+// unset properties result in TextAlignStart.
+func TextAlign(p style.Property) TextAlignT {
+	switch strings.ToLower(string(p)) {
+	case "end":
+		return TextAlignEnd
+	case "left":
+		return TextAlignLeft
+	case "right":
+		return TextAlignRight
+	case "center":
+		return TextAlignCenter
+	case "justify", "justify-all":
+		return TextAlignJustify
+	}
+	return TextAlignStart
+}
+
+// Resolve maps the logical alignments start and end to left or right, depending on
+// the text direction.
+func (a TextAlignT) Resolve(rtl bool) TextAlignT {
+	switch {
+	case a == TextAlignStart && rtl, a == TextAlignEnd && !rtl:
+		return TextAlignRight
+	case a == TextAlignStart, a == TextAlignEnd:
+		return TextAlignLeft
+	}
+	return a
+}
+
+// WhiteSpaceT is an enum type for the CSS white-space property.
+type WhiteSpaceT uint8
+
+// Enum values for type WhiteSpaceT
+const (
+	WhiteSpaceNormal  WhiteSpaceT = iota // CSS normal (default)
+	WhiteSpaceNoWrap                     // CSS nowrap
+	WhiteSpacePre                        // CSS pre
+	WhiteSpacePreWrap                    // CSS pre-wrap and break-spaces
+	WhiteSpacePreLine                    // CSS pre-line
+)
+
+// WhiteSpace returns the white-space type from a property string. Illegal input
+// and unset properties result in WhiteSpaceNormal.
+func WhiteSpace(p style.Property) WhiteSpaceT {
+	switch strings.ToLower(string(p)) {
+	case "nowrap":
+		return WhiteSpaceNoWrap
+	case "pre":
+		return WhiteSpacePre
+	case "pre-wrap", "break-spaces":
+		return WhiteSpacePreWrap
+	case "pre-line":
+		return WhiteSpacePreLine
+	}
+	return WhiteSpaceNormal
+}
+
+// String returns the CSS keyword for a white-space type.
+func (ws WhiteSpaceT) String() string {
+	switch ws {
+	case WhiteSpaceNoWrap:
+		return "nowrap"
+	case WhiteSpacePre:
+		return "pre"
+	case WhiteSpacePreWrap:
+		return "pre-wrap"
+	case WhiteSpacePreLine:
+		return "pre-line"
+	}
+	return "normal"
+}
+
+// Wraps returns true if lines may be broken at soft wrap opportunities.
+func (ws WhiteSpaceT) Wraps() bool {
+	return ws != WhiteSpaceNoWrap && ws != WhiteSpacePre
+}
+
+// PreservesSpaces returns true if sequences of spaces are not collapsed.
+func (ws WhiteSpaceT) PreservesSpaces() bool {
+	return ws == WhiteSpacePre || ws == WhiteSpacePreWrap
+}
+
+// PreservesNewlines returns true if newlines force a line break.
+func (ws WhiteSpaceT) PreservesNewlines() bool {
+	return ws == WhiteSpacePre || ws == WhiteSpacePreWrap || ws == WhiteSpacePreLine
+}
+
+// HyphensT is an enum type for the CSS hyphens property.
+type HyphensT uint8
+
+// Enum values for type HyphensT
+const (
+	HyphensManual HyphensT = iota // CSS manual (default)
+	HyphensNone                   // CSS none
+	HyphensAuto                   // CSS auto
+)
+
+// Hyphens returns the hyphenation type from a property string. Illegal input and
+// unset properties result in HyphensManual.
+func Hyphens(p style.Property) HyphensT {
+	switch strings.ToLower(string(p)) {
+	case "none":
+		return HyphensNone
+	case "auto":
+		return HyphensAuto
+	}
+	return HyphensManual
+}
+
+// HyphenateLimitCharsT holds the values of the CSS hyphenate-limit-chars property.
+// Values of 0 stand for auto, i.e. for the defaults of the language of the text.
+type HyphenateLimitCharsT struct {
+	Word   int // minimum number of characters of words to hyphenate
+	Before int // minimum number of characters before a hyphen
+	After  int // minimum number of characters after a hyphen
+}
+
+// HyphenateLimitChars returns the limits of hyphenation from a property string of
+// up to three integers or auto. If the third value is omitted, it is the same as
+// the second one. Illegal input and unset properties result in all values being
+// auto.
+func HyphenateLimitChars(p style.Property) HyphenateLimitCharsT {
+	fields := strings.Fields(strings.ToLower(string(p)))
+	if len(fields) > 3 {
+		return HyphenateLimitCharsT{}
+	}
+	var limits [3]int
+	for i, f := range fields {
+		if f == "auto" {
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return HyphenateLimitCharsT{}
+		}
+		limits[i] = n
+	}
+	if len(fields) == 2 {
+		limits[2] = limits[1]
+	}
+	return HyphenateLimitCharsT{Word: limits[0], Before: limits[1], After: limits[2]}
+}
+
+// WordBreakT is an enum type for the CSS word-break property.
+type WordBreakT uint8
+
+// Enum values for type WordBreakT
+const (
+	WordBreakNormal   WordBreakT = iota // CSS normal (default)
+	WordBreakBreakAll                   // CSS break-all
+	WordBreakKeepAll                    // CSS keep-all
+)
+
+// WordBreak returns the word-break type from a property string. The deprecated
+// value break-word is treated as normal. Illegal input and unset properties
+// result in WordBreakNormal.
+func WordBreak(p style.Property) WordBreakT {
+	switch strings.ToLower(string(p)) {
+	case "break-all":
+		return WordBreakBreakAll
+	case "keep-all":
+		return WordBreakKeepAll
+	}
+	return WordBreakNormal
+}
+
+// TextTransformT is an enum type for the CSS text-transform property.
+type TextTransformT uint8
+
+// Enum values for type TextTransformT
+const (
+	TextTransformNone       TextTransformT = iota // CSS none (default)
+	TextTransformUppercase                        // CSS uppercase
+	TextTransformLowercase                        // CSS lowercase
+	TextTransformCapitalize                       // CSS capitalize
+)
+
+// TextTransform returns the text transformation type from a property string.
+// Values full-width and full-size-kana are not supported. Illegal input and unset
+// properties result in TextTransformNone.
+func TextTransform(p style.Property) TextTransformT {
+	switch strings.ToLower(string(p)) {
+	case "uppercase":
+		return TextTransformUppercase
+	case "lowercase":
+		return TextTransformLowercase
+	case "capitalize":
+		return TextTransformCapitalize
+	}
+	return TextTransformNone
+}
+
+// LineHeight returns the distance between baselines from a value of the CSS
+// line-height property, for text set at font size fontsize. Numbers and
+// percentages are multiples of the font size. "normal", illegal input and unset
+// properties result in 0, meaning that the line height should be taken from the
+// metrics of the font.
+func LineHeight(p style.Property, fontsize dimen.DU) dimen.DU {
+	s := strings.TrimSpace(string(p))
+	if s == "" || s == "normal" {
+		return 0
+	}
+	if x, err := strconv.ParseFloat(s, 64); err == nil {
+		if x < 0 {
+			return 0
+		}
+		return dimen.FromFloat(x * float64(fontsize))
+	}
+	if pct, ok := strings.CutSuffix(s, "%"); ok { // may exceed 100%
+		if x, err := strconv.ParseFloat(pct, 64); err == nil && x >= 0 {
+			return dimen.FromFloat(x * float64(fontsize) / 100)
+		}
+	}
+	h, err := Resolution{FontSize: fontsize}.Length(p, fontsize)
+	if err != nil || h < 0 {
+		tracer().Debugf("unsupported value for line-height: %q", p)
+		return 0
+	}
+	return h
+}
+
+// TabSize returns the distance between tab stops from a value of the CSS tab-size
+// property, for spaces of width space and text set at font size fontsize. Numbers
+// are multiples of the width of a space. Illegal input and unset properties
+// result in the width of 8 spaces.
+func TabSize(p style.Property, space, fontsize dimen.DU) dimen.DU {
+	s := strings.TrimSpace(string(p))
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n >= 0 {
+		return dimen.FromFloat(n * float64(space))
+	}
+	if d, err := (Resolution{FontSize: fontsize}).Length(p, 0); err == nil && d >= 0 && s != "" {
+		return d
+	}
+	return 8 * space
+}
+
+// InitialLetter returns the size and the sink of an initial letter from a value
+// of the CSS initial-letter property, see
+// https://www.w3.org/TR/css-inline-3/#sizing-initial-letter . The size is the
+// number of lines the letter spans, the sink is the number of lines it sinks
+// into the paragraph. Keyword "drop" or an omitted sink result in a sink of the
+// size rounded down, keyword "raise" results in a sink of 1. "normal", illegal
+// input and unset properties result in a size of 0, i.e. no initial letter.
+func InitialLetter(p style.Property) (float64, int) {
+	fields := strings.Fields(strings.ToLower(string(p)))
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, 0
+	}
+	size, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || size < 1 {
+		return 0, 0
+	}
+	sink := int(size)
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "drop":
+		case "raise":
+			sink = 1
+		default:
+			if sink, err = strconv.Atoi(fields[1]); err != nil || sink < 1 {
+				return 0, 0
+			}
+		}
+	}
+	return size, sink
+}
@@ -0,0 +1,120 @@
+package css_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestTextProperties(t *testing.T) {
+	if a := css.TextAlign("start").Resolve(true); a != css.TextAlignRight {
+		t.Errorf("expected 'start' to align right for rtl text, have %d", a)
+	}
+	if a := css.TextAlign("end").Resolve(false); a != css.TextAlignRight {
+		t.Errorf("expected 'end' to align right for ltr text, have %d", a)
+	}
+	if a := css.TextAlign("justify-all").Resolve(false); a != css.TextAlignJustify {
+		t.Errorf("expected 'justify-all' to justify, have %d", a)
+	}
+	if ws := css.WhiteSpace("pre"); ws.Wraps() || !ws.PreservesSpaces() || !ws.PreservesNewlines() {
+		t.Errorf("expected 'pre' to preserve spaces and newlines, without wrapping")
+	}
+	if ws := css.WhiteSpace("pre-line"); !ws.Wraps() || ws.PreservesSpaces() {
+		t.Errorf("expected 'pre-line' to wrap and collapse spaces")
+	}
+	if ws := css.WhiteSpace("break-spaces"); ws.String() != "pre-wrap" {
+		t.Errorf("expected 'break-spaces' to be treated as 'pre-wrap', is %q", ws)
+	}
+	if css.Hyphens("auto") != css.HyphensAuto || css.Hyphens("") != css.HyphensManual {
+		t.Errorf("expected hyphens to default to manual")
+	}
+	if css.WordBreak("keep-all") != css.WordBreakKeepAll || css.WordBreak("break-word") != css.WordBreakNormal {
+		t.Errorf("expected word-break to treat break-word as normal")
+	}
+}
+
+func TestTextTransform(t *testing.T) {
+	for p, expected := range map[style.Property]css.TextTransformT{
+		"":           css.TextTransformNone,
+		"none":       css.TextTransformNone,
+		"uppercase":  css.TextTransformUppercase,
+		"Lowercase":  css.TextTransformLowercase,
+		"capitalize": css.TextTransformCapitalize,
+		"full-width": css.TextTransformNone,
+	} {
+		if tt := css.TextTransform(p); tt != expected {
+			t.Errorf("expected text-transform %q to be %d, have %d", p, expected, tt)
+		}
+	}
+}
+
+func TestHyphenateLimitChars(t *testing.T) {
+	for p, expected := range map[style.Property]css.HyphenateLimitCharsT{
+		"":          {},
+		"auto":      {},
+		"6":         {Word: 6},
+		"6 2":       {Word: 6, Before: 2, After: 2},
+		"auto 2 3":  {Before: 2, After: 3},
+		"6 2 3 4":   {},
+		"six 2 3":   {},
+		"6 -2 auto": {},
+	} {
+		if limits := css.HyphenateLimitChars(p); limits != expected {
+			t.Errorf("expected hyphenate-limit-chars %q to be %v, have %v", p, expected, limits)
+		}
+	}
+}
+
+func TestLineHeight(t *testing.T) {
+	em := 10 * dimen.PT
+	for p, expected := range map[style.Property]dimen.DU{
+		"normal": 0,
+		"1.5":    15 * dimen.PT,
+		"120%":   12 * dimen.PT,
+		"2em":    20 * dimen.PT,
+		"14pt":   14 * dimen.PT,
+		"-2":     0,
+		"auto":   0,
+	} {
+		if h := css.LineHeight(p, em); h != expected {
+			t.Errorf("expected line-height %q to be %s, have %s", p, expected, h)
+		}
+	}
+}
+
+func TestTabSize(t *testing.T) {
+	space, em := 5*dimen.PT, 10*dimen.PT
+	for p, expected := range map[style.Property]dimen.DU{
+		"":     40 * dimen.PT,
+		"4":    20 * dimen.PT,
+		"2em":  20 * dimen.PT,
+		"30pt": 30 * dimen.PT,
+		"-1":   40 * dimen.PT,
+	} {
+		if d := css.TabSize(p, space, em); d != expected {
+			t.Errorf("expected tab-size %q to be %s, have %s", p, expected, d)
+		}
+	}
+}
+
+func TestInitialLetter(t *testing.T) {
+	for _, x := range []struct {
+		p    style.Property
+		size float64
+		sink int
+	}{
+		{"normal", 0, 0},
+		{"3", 3, 3},
+		{"3 2", 3, 2},
+		{"2.5", 2.5, 2},
+		{"3 raise", 3, 1},
+		{"0.5", 0, 0},
+		{"3 0", 0, 0},
+	} {
+		if size, sink := css.InitialLetter(x.p); size != x.size || sink != x.sink {
+			t.Errorf("expected initial-letter %q to be %.1f/%d, have %.1f/%d", x.p, x.size, x.sink, size, sink)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// DefaultFontSize is the font size relative units are resolved against if a
+// resolution context does not provide a font size.
+const DefaultFontSize = 10 * dimen.PT
+
+// ErrNotALength is flagged if a CSS value cannot be resolved to a length,
+// e.g. `auto` or an unknown unit.
+var ErrNotALength = errors.New("not a CSS length")
+
+// Resolution is the context for resolving CSS lengths into dimensions.
+// Font-relative units (`em`, `rem`, `ex`, `ch`) refer to the font of the element
+// a length belongs to and to the font of the root element, viewport-relative
+// units (`vw`, `vh`, `vmin`, `vmax`) refer to the viewport, which for paged
+// media is the page.
+//
+// Fields left zero fall back to defaults: font size is DefaultFontSize, root
+// font size is the font size, x-height and the advance width of '0' are half the
+// font size, and DPI is 72, i.e. a pixel is a big point.
+type Resolution struct {
+	FontSize     dimen.DU    // font size of the element, for `em`
+	RootFontSize dimen.DU    // font size of the root element, for `rem`
+	XHeight      dimen.DU    // x-height of the element's font, for `ex`
+	ZeroAdvance  dimen.DU    // advance width of '0' in the element's font, for `ch`
+	Viewport     dimen.Point // size of the viewport or page, for `vw`, `vh`, …
+	DPI          int         // device resolution, for `px`
+}
+
+// Resolve resolves font-relative and viewport-relative dimensions to absolute
+// dimensions. Percentages depend on the containing block and are returned
+// unchanged, as are all other dimensions.
+func (res Resolution) Resolve(d DimenT) DimenT {
+	return res.viewportRelative(res.fontRelative(d))
+}
+
+// Absolute resolves a dimension to a fixed length. Percentages refer to
+// reference, which usually is a length of the containing block.
+// Dimensions which do not represent a length, e.g. `auto` or unset
+// dimensions, result in ErrNotALength.
+func (res Resolution) Absolute(d DimenT, reference dimen.DU) (dimen.DU, error) {
+	d = res.Resolve(d)
+	switch {
+	case d.IsAbsolute():
+		return d.d, nil
+	case d.IsPercent():
+		return reference.Scale(int64(d.percent), 100), nil
+	}
+	return 0, ErrNotALength
+}
+
+// Length converts a CSS length, given as a property value, into a dimension.
+// Values may be fractional, e.g. `1.5em` or `.25in`. Percentages refer to
+// reference. A number without a unit is valid for zero only.
+//
+// Length parses p with ParseDimen, with pixels converted for the resolution's DPI.
+func (res Resolution) Length(p style.Property, reference dimen.DU) (dimen.DU, error) {
+	x, unit, ok := splitDimen(string(p))
+	if !ok || (unit == "" && x != 0) {
+		return 0, fmt.Errorf("%w: %q", ErrNotALength, p)
+	}
+	if unit == "px" {
+		return dimen.FromFloat(x * float64(res.pixel())), nil
+	}
+	d, err := ParseDimen(string(p))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrNotALength, p)
+	}
+	return res.Absolute(d, reference)
+}
+
+func (res Resolution) fontRelative(d DimenT) DimenT {
+	switch d.flags & relativeMask {
+	case dimenEM:
+		return JustDimen(res.fontSize().Scale(int64(d.d), relativeScale))
+	case dimenREM:
+		return JustDimen(res.rootFontSize().Scale(int64(d.d), relativeScale))
+	case dimenEX:
+		return JustDimen(res.xHeight().Scale(int64(d.d), relativeScale))
+	case dimenCH:
+		return JustDimen(res.zeroAdvance().Scale(int64(d.d), relativeScale))
+	}
+	return d
+}
+
+func (res Resolution) viewportRelative(d DimenT) DimenT {
+	w, h := res.Viewport.X, res.Viewport.Y
+	switch d.flags & relativeMask {
+	case dimenVW:
+		return JustDimen(w.Scale(int64(d.d), 100*relativeScale))
+	case dimenVH:
+		return JustDimen(h.Scale(int64(d.d), 100*relativeScale))
+	case dimenVMIN:
+		return JustDimen(dimen.Min(w, h).Scale(int64(d.d), 100*relativeScale))
+	case dimenVMAX:
+		return JustDimen(dimen.Max(w, h).Scale(int64(d.d), 100*relativeScale))
+	}
+	return d
+}
+
+func (res Resolution) fontSize() dimen.DU {
+	if res.FontSize == 0 {
+		return DefaultFontSize
+	}
+	return res.FontSize
+}
+
+func (res Resolution) rootFontSize() dimen.DU {
+	if res.RootFontSize == 0 {
+		return res.fontSize()
+	}
+	return res.RootFontSize
+}
+
+func (res Resolution) xHeight() dimen.DU {
+	if res.XHeight == 0 {
+		return res.fontSize() / 2
+	}
+	return res.XHeight
+}
+
+func (res Resolution) zeroAdvance() dimen.DU {
+	if res.ZeroAdvance == 0 {
+		return res.fontSize() / 2
+	}
+	return res.ZeroAdvance
+}
+
+func (res Resolution) pixel() dimen.DU {
+	if res.DPI <= 0 {
+		return dimen.PX
+	}
+	return dimen.IN / dimen.DU(res.DPI)
+}
@@ -0,0 +1,82 @@
+package css_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestUnitResolution(t *testing.T) {
+	res := css.Resolution{
+		FontSize:     12 * dimen.PT,
+		RootFontSize: 10 * dimen.PT,
+		Viewport:     dimen.Point{X: 200 * dimen.MM, Y: 300 * dimen.MM},
+	}
+	for i, test := range []struct {
+		value  string
+		length dimen.DU
+	}{
+		{"0", 0},
+		{"12pt", 12 * dimen.PT},
+		{"1.5em", 18 * dimen.PT},
+		{"2rem", 20 * dimen.PT},
+		{"1ex", 6 * dimen.PT},
+		{"2ch", 12 * dimen.PT},
+		{"50%", 50 * dimen.MM},
+		{"10vw", 20 * dimen.MM},
+		{"10vh", 30 * dimen.MM},
+		{"10vmax", 30 * dimen.MM},
+		{"-.5in", -dimen.IN / 2},
+		{"12px", 12 * dimen.PX},
+		{"4Q", dimen.MM},
+	} {
+		d, err := res.Length(style.Property(test.value), 100*dimen.MM)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if d != test.length {
+			t.Errorf("test %d: expected %s to be %d, is %d", i, test.value, test.length, d)
+		}
+	}
+	for _, value := range []string{"auto", "12", "3furlongs", "1.2.3em"} {
+		if _, err := res.Length(style.Property(value), 0); !errors.Is(err, css.ErrNotALength) {
+			t.Errorf("expected %q not to be a length, error is %v", value, err)
+		}
+	}
+	res.DPI = 96
+	if d, _ := res.Length("96px", 0); d != dimen.IN {
+		t.Errorf("expected 96px to be 1in at 96 dpi, is %d", d)
+	}
+}
+
+func TestResolveDimen(t *testing.T) {
+	res := css.Resolution{FontSize: 12 * dimen.PT, Viewport: dimen.DINA4}
+	em := css.DimenOption("2em")
+	if !em.IsRelative() || em.IsPercent() {
+		t.Errorf("expected 2em to be a relative dimension, but not a percentage")
+	}
+	var d dimen.DU
+	if res.Resolve(em).Match().Just(&d) == nil || d != 24*dimen.PT {
+		t.Errorf("expected 2em to resolve to 24pt, is %d", d)
+	}
+	if res.Resolve(css.DimenOption("1.5em")).Match().Just(&d) == nil || d != 18*dimen.PT {
+		t.Errorf("expected 1.5em to resolve to 18pt, is %d", d)
+	}
+	if css.DimenOption("2.5pt").Match().Just(&d) == nil || d != dimen.PT.Scale(5, 2) {
+		t.Errorf("expected 2.5pt to be parsed as fixed dimension, is %d", d)
+	}
+	if res.Resolve(css.DimenOption("50%")).IsAbsolute() {
+		t.Errorf("expected percentage to be left unresolved")
+	}
+	if d, err := res.Absolute(css.DimenOption("50%"), 10*dimen.PT); err != nil || d != 5*dimen.PT {
+		t.Errorf("expected 50%% of 10pt to be 5pt, is %d (%v)", d, err)
+	}
+	if d, err := res.Absolute(css.DimenOption("100vh"), 0); err != nil || d != dimen.DINA4.Y {
+		t.Errorf("expected 100vh to be the page height without overflow, is %d (%v)", d, err)
+	}
+	if _, err := res.Absolute(css.Auto(), 0); err != css.ErrNotALength {
+		t.Errorf("expected auto not to resolve to a length")
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/andybalholm/cascadia"
 	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/engine/dom/style"
 	"github.com/npillmayer/tyse/engine/dom/styledtree"
 	"github.com/npillmayer/tyse/engine/tree"
@@ -26,6 +27,7 @@ type CSSOM struct {
 	rulesTree         *rulesTreeType               // style sheets
 	defaultProperties *style.PropertyMap           // "user agent" style properties
 	compoundSplitters []CompoundPropertiesSplitter // split up compound properties
+	sharing           *styleSharing                // share property maps between nodes
 }
 
 // NewCSSOM creates an empty CSSOM.
@@ -38,6 +40,7 @@ func NewCSSOM(additionalProperties []style.KeyValue) CSSOM {
 	cssom.defaultProperties = style.InitializeDefaultPropertyValues(additionalProperties)
 	cssom.compoundSplitters = make([]CompoundPropertiesSplitter, 1)
 	cssom.compoundSplitters[0] = style.SplitCompoundProperty
+	cssom.sharing = newStyleSharing()
 	return cssom
 }
 
@@ -66,6 +69,13 @@ func (cssom CSSOM) AddStylesForScope(scope *html.Node, css StyleSheet, source Pr
 	return nil
 }
 
+// SetMedia sets the media context against which @media rules will be
+// evaluated. The default is a print medium with DIN A4 pages.
+func (cssom CSSOM) SetMedia(m Media) {
+	cssom.rulesTree.media = m
+	cssom.rulesTree.index.invalidate()
+}
+
 // --- A rules tree -----------------------------------------------------
 
 // RulesTree holds the styling rules of a stylesheet.
@@ -77,6 +87,8 @@ type rulesTreeType struct {
 	stylesheets *sync.Map                    // of type html.Node -> []stylesheetType
 	selectors   map[string]cascadia.Selector // cache of compiled selectors
 	source      PropertySource               // where do these rules come from?
+	media       Media                        // context for evaluating @media rules
+	index       *indexCache                  // rule indexes for style sheets of the root scope
 }
 
 // ad-hoc container type for stylesheets and their origin.
@@ -90,6 +102,8 @@ func newRulesTree() *rulesTreeType {
 	rt := &rulesTreeType{}
 	rt.stylesheets = &sync.Map{}
 	rt.selectors = make(map[string]cascadia.Selector)
+	rt.media = PrintMedia(dimen.DINA4)
+	rt.index = &indexCache{}
 	return rt
 }
 
@@ -114,6 +128,9 @@ func (rt rulesTreeType) StoreStylesheetForHTMLNode(h *html.Node, sheet StyleShee
 	if h == nil {
 		h = rootElement
 	}
+	if h == rootElement {
+		rt.index.invalidate()
+	}
 	sheets := rt.StylesheetsForHTMLNode(h)
 	if sheets == nil {
 		tracer().Debugf("Adding first style sheet for HTML node %v", h)
@@ -237,26 +254,21 @@ func (matches *matchesList) String() string {
 // FilterMatchesFor(node) iterates through all the rules relevant at this
 // point and looks for rules matching the current HTML node h.
 // The heavy lifting is done by cascadia. We have to 'compile' all rules
-// and will cache compiled rules.
+// and will cache compiled rules. Rules of the style sheets for the document
+// are looked up in rule indexes, testing h only against rules which may
+// plausibly match (see type ruleIndex).
 //
 // Will return a slice of CSS rules matched for h.
 func (rt *rulesTreeType) FilterMatchesFor(h *html.Node) *matchesList {
 	//list := &matchesList{}
 	matchingRules := make([]Rule, 0, 3)
-	sheets := rt.StylesheetsForHTMLNode(rootElement)
-	for _, s := range sheets {
-		rules := s.stylesheet.Rules()
-		tracer().Debugf("Stylesheet has %d rules", len(rules))
-		for _, rule := range rules {
-			tracer().Debugf("Now try to match for HTML = %v", h.Data)
-			if rt.matchRuleForHTMLNode(h, rule) {
-				matchingRules = append(matchingRules, rule)
-			}
-		}
+	for _, ix := range rt.rootIndexes() {
+		tracer().Debugf("Now try to match for HTML = %v", h.Data)
+		matchingRules = ix.match(h, matchingRules)
 	}
-	sheets = rt.StylesheetsForHTMLNode(h)
+	sheets := rt.StylesheetsForHTMLNode(h)
 	for _, s := range sheets {
-		for _, rule := range s.stylesheet.Rules() {
+		for _, rule := range EffectiveRules(s.stylesheet, rt.media) {
 			if rt.matchRuleForHTMLNode(h, rule) {
 				matchingRules = append(matchingRules, rule)
 			}
@@ -270,6 +282,8 @@ func (rt *rulesTreeType) matchRuleForHTMLNode(h *html.Node, rule Rule) bool {
 	if selectorString == "" { // style-attribute local for this HTML node
 		//matchingRules = append(matchingRules, rule)
 		return true
+	} else if isAtRule(selectorString) { // e.g., @page rules do not apply to HTML nodes
+		return false
 	} // else try to match selector for this rule against HTML node
 	var sel cascadia.Selector
 	found := false
@@ -343,7 +357,7 @@ type propertyPlusSpecifityType struct {
 // no is a sequence number for rules, ensuring that later rules override
 // previously defined rules / properties.
 func (sp *propertyPlusSpecifityType) calcSpecifity(no int) {
-	if sp.rule.IsImportant(sp.propertyKey) {
+	if sp.important { // longhands of a shorthand carry the shorthand's importance
 		sp.spec = 99999 // max
 		return
 	}
@@ -385,16 +399,17 @@ func (matches *matchesList) createStyleGroups(parent *tree.Node[*styledtree.StyN
 			break
 		}
 		groupname := style.GroupNameFromPropertyKey(pspec.propertyKey)
+		value := style.ComputedValue(pspec.propertyKey, pspec.propertyValue)
 		group := pmap.Group(groupname)
 		if group != nil {
-			group.Set(pspec.propertyKey, pspec.propertyValue)
+			group.Set(pspec.propertyKey, value)
 		} else {
 			tracer().Infof("parent is %s, searching for prop group %s", parent, groupname)
 			_, pg := findAncestorWithPropertyGroup(parent, groupname) // must succeed
 			if pg == nil {
 				panic(fmt.Sprintf("Cannot find ancestor with prop-group %s -- did you create global properties?", groupname))
 			}
-			group, isNew := pg.ForkOnProperty(pspec.propertyKey, pspec.propertyValue, true)
+			group, isNew := pg.ForkOnProperty(pspec.propertyKey, value, true)
 			if isNew { // a new property group has been created
 				pmap = pmap.AddAllFromGroup(group, true) // put it into the group map
 			}
@@ -500,15 +515,21 @@ func (cssom CSSOM) Style(dom *html.Node) (*tree.Node[*styledtree.StyNode], error
 	// a loss of space efficiency, but we may gain performance by
 	// overlapping the operations.
 	tracer().Debugf("--- Now styling newly created nodes --------")
+	sharing := cssom.sharing
+	if sharing == nil {
+		sharing = newStyleSharing()
+	}
+	sharing.reset()
 	walker = tree.NewWalker(styledRootNode)
 	createStyles := func(node *tree.Node[*styledtree.StyNode], parent *tree.Node[*styledtree.StyNode], pos int) (*tree.Node[*styledtree.StyNode], error) {
-		return createStylesForNode(node, cssom.rulesTree, cssom.compoundSplitters)
+		return createStylesForNode(node, cssom.rulesTree, cssom.compoundSplitters, sharing)
 	}
 	future = walker.TopDown(createStyles).Promise() // build the style tree
 	if _, err := future(); err != nil {
 		tracer().Errorf("Error while creating style properties: %v", err)
 		return nil, err
 	}
+	tracer().Infof("Style sharing: %s", sharing.stats)
 	return styledRootNode, nil
 }
 
@@ -574,7 +595,7 @@ func isStylable(a atom.Atom) bool {
 }
 
 func createStylesForNode(node *tree.Node[*styledtree.StyNode], rulesTree *rulesTreeType,
-	splitters []CompoundPropertiesSplitter) (*tree.Node[*styledtree.StyNode], error) {
+	splitters []CompoundPropertiesSplitter, sharing *styleSharing) (*tree.Node[*styledtree.StyNode], error) {
 	//
 	//styler := creator.ToStyler(node)
 	h := node.Payload.HTMLNode()
@@ -584,14 +605,17 @@ func createStylesForNode(node *tree.Node[*styledtree.StyNode], rulesTree *rulesT
 			matchlist := rulesTree.FilterMatchesFor(h)
 			if matchlist != nil && len(matchlist.matchingRules) != 0 {
 				matchlist.SortProperties(splitters)
-				pmap := matchlist.createStyleGroups(node.Parent())
+				pmap := sharing.propertyMap(node, matchlist, func() *style.PropertyMap {
+					return matchlist.createStyleGroups(node.Parent())
+				})
 				tracer().Debugf("Setting styles for node %v =\n%s", node, pmap)
 				//creator.SetStyles(node, pmap)
 				node.Payload.SetStyles(pmap)
-			} else {
-				tracer().Debugf("Node %v matched no style rules", node)
+				return node, nil
 			}
+			tracer().Debugf("Node %v matched no style rules", node)
 		}
+		sharing.inherit(node)
 		return node, nil
 	}
 	return nil, nil
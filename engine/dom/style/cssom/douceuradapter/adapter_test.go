@@ -4,6 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
 	"golang.org/x/net/html"
 )
 
@@ -29,3 +32,56 @@ func TestExtract1(t *testing.T) {
 		t.Error("Should extract 1 stylesheet")
 	}
 }
+
+func TestMediaQueries(t *testing.T) {
+	media := cssom.PrintMedia(dimen.DINA4)
+	for i, test := range []struct {
+		query string
+		match bool
+	}{
+		{"", true},
+		{"all", true},
+		{"print", true},
+		{"screen", false},
+		{"only print", true},
+		{"not print", false},
+		{"screen, print", true},
+		{"print and (min-width: 200mm)", true},
+		{"print and (max-width: 200mm)", false},
+		{"(width: 210mm) and (height: 297mm)", true},
+		{"(orientation: landscape)", false},
+		{"not screen and (orientation: portrait)", true},
+	} {
+		match, err := media.Matches(test.query)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if match != test.match {
+			t.Errorf("test %d: expected %q to evaluate to %v", i, test.query, test.match)
+		}
+	}
+	for _, query := range []string{"print and", "(color: 8)", "(min-width: 10em)", "print screen"} {
+		if _, err := media.Matches(query); err == nil {
+			t.Errorf("expected media query %q to be rejected", query)
+		}
+	}
+}
+
+func TestEffectiveRules(t *testing.T) {
+	sheet, err := parser.Parse(`
+	p { color: black; }
+	@media screen { p { color: blue; } }
+	@media print { p { color: red; } @media (max-width: 100mm) { p { color: green; } } }
+	@page :first { margin: 1cm; }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := cssom.EffectiveRules(Wrap(sheet), cssom.PrintMedia(dimen.DINA4))
+	var values []string
+	for _, r := range rules {
+		values = append(values, r.Selector()+"="+string(r.Value("color")))
+	}
+	if strings.Join(values, ",") != "p=black,p=red,@page :first=" {
+		t.Errorf("expected effective rules for print media, have %v", values)
+	}
+}
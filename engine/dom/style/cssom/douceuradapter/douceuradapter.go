@@ -11,6 +11,8 @@ Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
 package douceuradapter
 
 import (
+	"strings"
+
 	"github.com/aymerick/douceur/css"
 	"github.com/aymerick/douceur/parser"
 	"github.com/npillmayer/tyse/engine/dom/style"
@@ -68,7 +70,11 @@ var _ cssom.StyleSheet = &CSSStyles{}
 type Rule css.Rule
 
 // Selector returns the prelude / selectors of the rule.
+// For at-rules, the at-keyword is prepended, e.g. "@page :left".
 func (r Rule) Selector() string {
+	if r.Kind == css.AtRule {
+		return strings.TrimSpace(r.Name + " " + r.Prelude)
+	}
 	return r.Prelude
 }
 
@@ -105,7 +111,20 @@ func (r Rule) IsImportant(key string) bool {
 	return false
 }
 
+// EmbeddedRules returns the rules nested within an at-rule, e.g. within
+// "@media print { … }". For other rules, the result is empty.
+//
+// Interface cssom.EmbeddingRule
+func (r Rule) EmbeddedRules() []cssom.Rule {
+	rules := make([]cssom.Rule, len(r.Rules))
+	for i := range r.Rules {
+		rules[i] = Rule(*r.Rules[i])
+	}
+	return rules
+}
+
 var _ cssom.Rule = &Rule{}
+var _ cssom.EmbeddingRule = &Rule{}
 
 // ExtractStyleElements visits <head> and <body> elements in an HTML parse
 // tree and searches for embedded <style>s. It returns the content of
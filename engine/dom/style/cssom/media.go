@@ -0,0 +1,161 @@
+package cssom
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+// Media is the context @media rules are evaluated against. For a typesetter
+// this will usually be a paged medium, with width and height set to the size
+// of a page.
+type Media struct {
+	Type   string   // media type, e.g. "print" or "screen"
+	Width  dimen.DU // width of the viewport / page
+	Height dimen.DU // height of the viewport / page
+}
+
+// PrintMedia returns a print media context for pages of a given paper size.
+func PrintMedia(papersize dimen.Point) Media {
+	return Media{Type: "print", Width: papersize.X, Height: papersize.Y}
+}
+
+// Matches evaluates a comma-separated list of media queries against m, e.g.
+//
+//	print and (min-width: 150mm), screen and (orientation: landscape)
+//
+// The list matches if any of its queries matches. Queries consist of an optional
+// media type, optionally prefixed by "only" or "not", and media features joined
+// by "and". Supported media features are width, height (both with prefixes "min-"
+// and "max-") and orientation. Lengths have to be absolute.
+// An empty list of queries matches every media.
+func (m Media) Matches(queries string) (bool, error) {
+	if strings.TrimSpace(queries) == "" {
+		return true, nil
+	}
+	for _, q := range strings.Split(queries, ",") {
+		match, err := m.matchQuery(q)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var mediaAnd = regexp.MustCompile(`\s+and\s+`)
+
+func (m Media) matchQuery(q string) (bool, error) {
+	q = strings.ToLower(strings.TrimSpace(q))
+	if q == "" {
+		return false, fmt.Errorf("empty media query")
+	}
+	negate := false
+	if strings.HasPrefix(q, "not ") {
+		negate, q = true, strings.TrimSpace(q[4:])
+	} else if strings.HasPrefix(q, "only ") {
+		q = strings.TrimSpace(q[5:])
+	}
+	match := true
+	for i, part := range mediaAnd.Split(q, -1) {
+		var ok bool
+		var err error
+		switch {
+		case strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")"):
+			ok, err = m.matchFeature(part[1 : len(part)-1])
+		case i == 0 && !strings.ContainsAny(part, "() "):
+			ok = part == "all" || part == m.Type
+		default:
+			err = fmt.Errorf("malformed media query: %q", q)
+		}
+		if err != nil {
+			return false, err
+		}
+		match = match && ok
+	}
+	return match != negate, nil
+}
+
+func (m Media) matchFeature(feature string) (bool, error) {
+	name, value, found := strings.Cut(feature, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if name == "orientation" {
+		if !found {
+			return true, nil
+		}
+		switch value {
+		case "portrait":
+			return m.Height >= m.Width, nil
+		case "landscape":
+			return m.Width > m.Height, nil
+		}
+		return false, fmt.Errorf("illegal value for media feature orientation: %q", value)
+	}
+	var actual dimen.DU
+	switch strings.TrimPrefix(strings.TrimPrefix(name, "min-"), "max-") {
+	case "width":
+		actual = m.Width
+	case "height":
+		actual = m.Height
+	default:
+		return false, fmt.Errorf("unsupported media feature: %q", name)
+	}
+	if !found {
+		return actual > 0, nil
+	}
+	var d dimen.DU
+	if css.DimenOption(style.Property(value)).Match().Just(&d) == nil {
+		return false, fmt.Errorf("media feature %s requires an absolute length, is %q", name, value)
+	}
+	switch {
+	case strings.HasPrefix(name, "min-"):
+		return actual >= d, nil
+	case strings.HasPrefix(name, "max-"):
+		return actual <= d, nil
+	}
+	return actual == d, nil
+}
+
+// EffectiveRules returns the rules of a stylesheet which apply for media m.
+// Rules embedded in @media rules are included if the media query of the @media
+// rule matches m, with nested @media rules evaluated recursively. Other at-rules,
+// e.g. @page rules, are retained. Media queries which cannot be evaluated
+// do not match.
+func EffectiveRules(sheet StyleSheet, m Media) []Rule {
+	return m.collectRules(sheet.Rules(), nil)
+}
+
+func (m Media) collectRules(rules []Rule, effective []Rule) []Rule {
+	for _, rule := range rules {
+		selector := strings.TrimSpace(rule.Selector())
+		if selector != "@media" && !strings.HasPrefix(selector, "@media ") {
+			effective = append(effective, rule)
+			continue
+		}
+		embedding, ok := rule.(EmbeddingRule)
+		if !ok {
+			tracer().Errorf("@media rule does not provide embedded rules: %s", selector)
+			continue
+		}
+		match, err := m.Matches(strings.TrimPrefix(selector, "@media"))
+		if err != nil {
+			tracer().Errorf("cannot evaluate %s: %v", selector, err)
+			continue
+		}
+		if match {
+			effective = m.collectRules(embedding.EmbeddedRules(), effective)
+		}
+	}
+	return effective
+}
+
+// isAtRule returns true if a selector denotes an at-rule, e.g. "@page :first".
+func isAtRule(selector string) bool {
+	return strings.HasPrefix(strings.TrimSpace(selector), "@")
+}
@@ -0,0 +1,315 @@
+package cssom
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// --- Rule index -------------------------------------------------------
+
+// Testing every rule of a large stylesheet against every element is the
+// most expensive part of styling. Like browser engines do, we put rules into
+// buckets by the ID, class or tag name an element has to carry for the rule
+// to match. These are taken from the rightmost compound selector of a rule,
+// e.g.
+//
+//     div.note > p        ⟹  tag "p"
+//     ul li.active a#top  ⟹  ID "top"
+//     .sidebar *          ⟹  no bucket (universal)
+//
+// An element then has to be tested against the rules in the buckets for its
+// ID, its classes and its tag name, and against the universal rules only.
+
+// ruleIndex holds the rules of a stylesheet, bucketed by ID, class and tag name.
+type ruleIndex struct {
+	rules     []indexedRule
+	byID      map[string][]int // rule positions by ID
+	byClass   map[string][]int // rule positions by class
+	byTag     map[string][]int // rule positions by tag name
+	universal []int            // rule positions without a bucket
+}
+
+type indexedRule struct {
+	rule Rule
+	sel  cascadia.Selector // nil for style attributes, which always match
+}
+
+// newRuleIndex compiles the selectors of rules and puts the rules into buckets.
+// At-rules and rules with selectors which cannot be compiled are left out.
+func newRuleIndex(rules []Rule) *ruleIndex {
+	ix := &ruleIndex{
+		byID:    make(map[string][]int),
+		byClass: make(map[string][]int),
+		byTag:   make(map[string][]int),
+	}
+	for _, rule := range rules {
+		selector := rule.Selector()
+		if isAtRule(selector) {
+			continue
+		}
+		r := indexedRule{rule: rule}
+		if selector != "" {
+			sel, err := cascadia.Compile(selector)
+			if err != nil {
+				tracer().Errorf("CSS selector seems not to work: %s", selector)
+				continue
+			}
+			r.sel = sel
+		}
+		pos := len(ix.rules)
+		ix.rules = append(ix.rules, r)
+		ix.insert(pos, selector)
+	}
+	tracer().Debugf("Indexed %d rules: %d IDs, %d classes, %d tags, %d universal", len(ix.rules),
+		len(ix.byID), len(ix.byClass), len(ix.byTag), len(ix.universal))
+	return ix
+}
+
+// insert puts the rule at position pos into the buckets for the alternatives
+// of its selector list. If any alternative does not fit into a bucket, the rule
+// is universal.
+func (ix *ruleIndex) insert(pos int, selector string) {
+	type bucket struct {
+		m   map[string][]int
+		key string
+	}
+	var buckets []bucket
+	for _, alt := range splitSelectorList(selector) {
+		kind, key := selectorKey(rightmostCompound(alt))
+		switch kind {
+		case '#':
+			buckets = append(buckets, bucket{ix.byID, key})
+		case '.':
+			buckets = append(buckets, bucket{ix.byClass, key})
+		case 't':
+			buckets = append(buckets, bucket{ix.byTag, key})
+		default:
+			ix.universal = append(ix.universal, pos)
+			return
+		}
+	}
+	for _, b := range buckets {
+		if l := b.m[b.key]; len(l) == 0 || l[len(l)-1] != pos {
+			b.m[b.key] = append(l, pos)
+		}
+	}
+}
+
+// match appends the rules matching h to matching, in the order of the
+// stylesheet.
+func (ix *ruleIndex) match(h *html.Node, matching []Rule) []Rule {
+	for _, pos := range ix.candidates(h) {
+		r := ix.rules[pos]
+		if r.sel == nil || r.sel.Match(h) {
+			matching = append(matching, r.rule)
+		}
+	}
+	return matching
+}
+
+// candidates returns the positions of the rules which may match h, in
+// increasing order.
+func (ix *ruleIndex) candidates(h *html.Node) []int {
+	cand := append([]int(nil), ix.universal...)
+	cand = append(cand, ix.byTag[h.Data]...)
+	for _, a := range h.Attr {
+		switch a.Key {
+		case "id":
+			cand = append(cand, ix.byID[a.Val]...)
+		case "class":
+			for _, class := range strings.Fields(a.Val) {
+				cand = append(cand, ix.byClass[class]...)
+			}
+		}
+	}
+	sort.Ints(cand)
+	unique := cand[:0]
+	for i, pos := range cand { // a rule may be in more than one bucket
+		if i == 0 || pos != cand[i-1] {
+			unique = append(unique, pos)
+		}
+	}
+	return unique
+}
+
+// indexCache holds the rule indexes for the stylesheets of the root scope.
+type indexCache struct {
+	sync.Mutex
+	indexes []*ruleIndex // one index per stylesheet
+	valid   bool         // false if stylesheets or media have changed
+}
+
+// rootIndexes returns the rule indexes for the stylesheets of the root scope,
+// creating them if necessary.
+func (rt *rulesTreeType) rootIndexes() []*ruleIndex {
+	rt.index.Lock()
+	defer rt.index.Unlock()
+	if !rt.index.valid {
+		rt.index.indexes = nil
+		for _, s := range rt.StylesheetsForHTMLNode(rootElement) {
+			rules := EffectiveRules(s.stylesheet, rt.media)
+			rt.index.indexes = append(rt.index.indexes, newRuleIndex(rules))
+		}
+		rt.index.valid = true
+	}
+	return rt.index.indexes
+}
+
+// invalidate marks the rule indexes as outdated, e.g. after stylesheets have
+// been added.
+func (ic *indexCache) invalidate() {
+	ic.Lock()
+	defer ic.Unlock()
+	ic.valid = false
+}
+
+// --- Selector keys ----------------------------------------------------
+
+// splitSelectorList splits a selector list at top-level commas.
+func splitSelectorList(selector string) []string {
+	var list []string
+	start := 0
+	scanSelector(selector, func(i int, c byte) {
+		if c == ',' {
+			list = append(list, selector[start:i])
+			start = i + 1
+		}
+	})
+	return append(list, selector[start:])
+}
+
+// rightmostCompound returns the rightmost compound selector of a complex
+// selector, e.g. "p.intro" for "article > p.intro".
+func rightmostCompound(selector string) string {
+	selector = strings.TrimSpace(selector)
+	start := 0
+	scanSelector(selector, func(i int, c byte) {
+		if strings.IndexByte(" \t\n>+~", c) >= 0 {
+			start = i + 1
+		}
+	})
+	return selector[start:]
+}
+
+// scanSelector calls f for every character of a selector which is not within
+// brackets, parentheses or quotes.
+func scanSelector(selector string, f func(int, byte)) {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0:
+			f(i, c)
+		}
+	}
+}
+
+// selectorKey returns the most selective bucket for a compound selector:
+// kind '#' for an ID, '.' for a class, 't' for a tag name, or 0 if the
+// selector does not fit into a bucket.
+func selectorKey(compound string) (byte, string) {
+	var class, tag string
+	i := 0
+	if n := identLength(compound); n > 0 {
+		tag, i = strings.ToLower(compound[:n]), n
+	} else if strings.HasPrefix(compound, "*") {
+		i = 1
+	}
+	for i < len(compound) {
+		switch c := compound[i]; c {
+		case '#', '.':
+			n := identLength(compound[i+1:])
+			if n == 0 {
+				return 0, ""
+			}
+			if c == '#' {
+				return '#', compound[i+1 : i+1+n]
+			}
+			if class == "" {
+				class = compound[i+1 : i+1+n]
+			}
+			i += 1 + n
+		case '[':
+			i = skipGroup(compound, i)
+		case ':': // pseudo-class or pseudo-element
+			i++
+			if i < len(compound) && compound[i] == ':' {
+				i++
+			}
+			n := identLength(compound[i:])
+			if n == 0 {
+				return 0, ""
+			}
+			if i += n; i < len(compound) && compound[i] == '(' {
+				i = skipGroup(compound, i)
+			}
+		default:
+			return 0, "" // e.g., a namespace, or escaped characters
+		}
+	}
+	switch {
+	case class != "":
+		return '.', class
+	case tag != "":
+		return 't', tag
+	}
+	return 0, ""
+}
+
+// identLength returns the length of the CSS identifier s starts with. Escaped
+// characters are not supported.
+func identLength(s string) int {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '-' ||
+			c >= 0x80 || (n > 0 && c >= '0' && c <= '9') {
+			n++
+			continue
+		}
+		break
+	}
+	return n
+}
+
+// skipGroup returns the position following the bracket or parenthesis
+// opened at s[i].
+func skipGroup(s string, i int) int {
+	depth, quote := 0, byte(0)
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
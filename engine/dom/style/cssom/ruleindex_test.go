@@ -0,0 +1,187 @@
+package cssom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"golang.org/x/net/html"
+)
+
+func TestSelectorKeys(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.dom")
+	defer teardown()
+	//
+	for selector, expected := range map[string]string{
+		"p":                              "t:p",
+		"DIV":                            "t:div",
+		"div.note > p":                   "t:p",
+		"ul li.active a#top":             "#:top",
+		"p.intro.lead":                   ".:intro",
+		".sidebar *":                     "",
+		"*":                              "",
+		`section[data-type="chapter"]`:   "t:section",
+		`[data-type="a b"] > h1:not(.x)`: "t:h1",
+		"a:hover::before":                "t:a",
+		".btn:nth-child(2n+1)":           ".:btn",
+		`div[title="a > b"]`:             "t:div",
+		"svg|rect":                       "",
+	} {
+		kind, key := selectorKey(rightmostCompound(selector))
+		have := ""
+		if kind != 0 {
+			if kind == 't' {
+				have = "t:" + key
+			} else {
+				have = string(kind) + ":" + key
+			}
+		}
+		if have != expected {
+			t.Errorf("expected key '%s' for selector '%s', have '%s'", expected, selector, have)
+		}
+	}
+}
+
+func TestRuleIndexMatchesAllRules(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.dom")
+	defer teardown()
+	//
+	rt := newRulesTree()
+	rt.StoreStylesheetForHTMLNode(nil, frameworkStylesheet(200), Author)
+	doc := frameworkDocument(t, 50)
+	elements := 0
+	forEachElement(doc, func(h *html.Node) {
+		elements++
+		indexed := rt.FilterMatchesFor(h).matchingRules
+		linear := rt.matchLinear(h)
+		if fmt.Sprint(indexed) != fmt.Sprint(linear) {
+			t.Errorf("<%s %v>: index matched %d rules, expected %d", h.Data, h.Attr,
+				len(indexed), len(linear))
+		}
+	})
+	if elements == 0 {
+		t.Fatalf("no elements in test document")
+	}
+}
+
+// Matching a document of 500 sections against a stylesheet in the shape of a
+// CSS framework, with ~4000 rules, mostly utility classes.
+
+func BenchmarkMatchLinear(b *testing.B) {
+	rt, doc := benchmarkSetup(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		forEachElement(doc, func(h *html.Node) { rt.matchLinear(h) })
+	}
+}
+
+func BenchmarkMatchIndexed(b *testing.B) {
+	rt, doc := benchmarkSetup(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		forEachElement(doc, func(h *html.Node) { rt.FilterMatchesFor(h) })
+	}
+}
+
+// --- Test helpers ----------------------------------------------------------
+
+func benchmarkSetup(b *testing.B) (*rulesTreeType, *html.Node) {
+	tracer().SetTraceLevel(tracing.LevelError)
+	rt := newRulesTree()
+	rt.StoreStylesheetForHTMLNode(nil, frameworkStylesheet(500), Author)
+	doc := frameworkDocument(b, 500)
+	rt.rootIndexes()                                              // do not measure the creation of the index
+	forEachElement(doc, func(h *html.Node) { rt.matchLinear(h) }) // compile selectors
+	return rt, doc
+}
+
+// matchLinear matches h against every rule of the root scope, without using
+// the rule index.
+func (rt *rulesTreeType) matchLinear(h *html.Node) []Rule {
+	var matching []Rule
+	for _, s := range rt.StylesheetsForHTMLNode(rootElement) {
+		for _, rule := range EffectiveRules(s.stylesheet, rt.media) {
+			if rt.matchRuleForHTMLNode(h, rule) {
+				matching = append(matching, rule)
+			}
+		}
+	}
+	return matching
+}
+
+// frameworkStylesheet creates a stylesheet in the shape of a CSS framework:
+// element defaults, followed by n groups of component and utility rules.
+func frameworkStylesheet(n int) *testSheet {
+	var sheet testSheet
+	add := func(selector string) {
+		sheet.rules = append(sheet.rules, &testRule{selector: selector})
+	}
+	for _, tag := range []string{"html", "body", "h1", "h2", "h3", "p", "ul", "ol", "li",
+		"a", "img", "table", "td", "th", "pre", "code", "blockquote", "section", "div"} {
+		add(tag)
+	}
+	add("*, *::before, *::after")
+	add("h1, h2, h3, .h1, .h2, .h3")
+	add("a:not([href])")
+	for i := 0; i < n; i++ {
+		add(fmt.Sprintf(".m-%d", i))
+		add(fmt.Sprintf(".p-%d", i))
+		add(fmt.Sprintf(".col-md-%d", i))
+		add(fmt.Sprintf(".btn-%d:hover", i))
+		add(fmt.Sprintf(".nav-%d > li a", i))
+		add(fmt.Sprintf(".card-%d .card-body p", i))
+		add(fmt.Sprintf("#section-%d h2", i))
+		add(fmt.Sprintf(`[data-toggle="t%d"]`, i))
+	}
+	return &sheet
+}
+
+// frameworkDocument creates a document of n sections using classes and IDs of
+// a framework stylesheet.
+func frameworkDocument(tb testing.TB, n int) *html.Node {
+	var b strings.Builder
+	b.WriteString("<html><body>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<section id="section-%d" class="card-%d m-%d">`, i, i%50, i%7)
+		fmt.Fprintf(&b, `<h2 class="h2">Section %d</h2>`, i)
+		fmt.Fprintf(&b, `<div class="card-body col-md-%d"><p class="p-%d">Text <a>link</a></p></div>`, i%12, i%5)
+		fmt.Fprintf(&b, `<ul class="nav-%d"><li><a href="#" data-toggle="t%d">item</a></li></ul>`, i%20, i)
+		b.WriteString("</section>")
+	}
+	b.WriteString("</body></html>")
+	doc, err := html.Parse(strings.NewReader(b.String()))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return doc
+}
+
+func forEachElement(n *html.Node, f func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		f(n)
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		forEachElement(ch, f)
+	}
+}
+
+type testSheet struct {
+	rules []Rule
+}
+
+func (s *testSheet) AppendRules(other StyleSheet) { s.rules = append(s.rules, other.Rules()...) }
+func (s *testSheet) Empty() bool                  { return len(s.rules) == 0 }
+func (s *testSheet) Rules() []Rule                { return s.rules }
+
+type testRule struct {
+	selector string
+}
+
+func (r *testRule) Selector() string            { return r.selector }
+func (r *testRule) Properties() []string        { return []string{"display"} }
+func (r *testRule) Value(string) style.Property { return "block" }
+func (r *testRule) IsImportant(string) bool     { return false }
+func (r *testRule) String() string              { return r.selector }
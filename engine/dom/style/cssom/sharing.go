@@ -0,0 +1,157 @@
+package cssom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/styledtree"
+	"github.com/npillmayer/tyse/engine/tree"
+)
+
+// --- Style sharing ----------------------------------------------------
+
+// Large documents consist of many elements styled identically, e.g., the
+// paragraphs of a chapter. Similar to Stylo's style sharing cache, elements
+// are given the same property map if they inherit from identical styles and
+// match identical properties. Creating the property map is then done only
+// once.
+//
+// The property map created for a node depends on the properties matched for
+// the node and on the property groups the node inherits, which are searched
+// for upwards the tree. We therefore assign a "style identity" to every
+// styled node: nodes with the same style identity resolve every property group
+// to the same group. A node's identity is determined by the identity of its
+// parent and the properties it matches. Nodes with equal identities share
+// their property maps.
+//
+// Shared property maps must not be modified after styling.
+
+// SharingStats reports the effectiveness of style sharing for the most recent
+// call to Style.
+type SharingStats struct {
+	Styled int // nodes with matching properties
+	Shared int // nodes re-using the property map of a node styled before
+	Maps   int // distinct property maps created
+}
+
+// SharingRatio returns the fraction of styled nodes re-using a property map.
+func (s SharingStats) SharingRatio() float64 {
+	if s.Styled == 0 {
+		return 0
+	}
+	return float64(s.Shared) / float64(s.Styled)
+}
+
+func (s SharingStats) String() string {
+	return fmt.Sprintf("%d styled nodes, %d shared (%.1f%%), %d property maps",
+		s.Styled, s.Shared, 100*s.SharingRatio(), s.Maps)
+}
+
+// SharingStats returns statistics about style sharing for the most recent call
+// to Style.
+func (cssom CSSOM) SharingStats() SharingStats {
+	if cssom.sharing == nil {
+		return SharingStats{}
+	}
+	cssom.sharing.mu.Lock()
+	defer cssom.sharing.mu.Unlock()
+	return cssom.sharing.stats
+}
+
+type styleSharing struct {
+	mu     sync.Mutex
+	ids    map[*styledtree.StyNode]int // style identity of styled nodes
+	styles map[sharingKey]sharedStyle  // property maps by identity of parent and matches
+	stats  SharingStats
+}
+
+type sharingKey struct {
+	parent     int    // style identity of the parent
+	properties string // properties matched, ordered by specifity
+}
+
+type sharedStyle struct {
+	id   int // style identity of nodes with this property map
+	pmap *style.PropertyMap
+}
+
+func newStyleSharing() *styleSharing {
+	s := &styleSharing{}
+	s.reset()
+	return s
+}
+
+// reset clears the cache before styling a new tree.
+func (s *styleSharing) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = make(map[*styledtree.StyNode]int)
+	s.styles = make(map[sharingKey]sharedStyle)
+	s.stats = SharingStats{}
+}
+
+// identity returns the style identity of a node. Nodes which have not been
+// styled inherit the identity of their parent, the root has identity 0.
+// Must be called with s.mu held.
+func (s *styleSharing) identity(node *tree.Node[*styledtree.StyNode]) int {
+	for ; node != nil; node = node.Parent() {
+		if id, ok := s.ids[node.Payload]; ok {
+			return id
+		}
+	}
+	return 0
+}
+
+// inherit records that node, which does not match any properties, has the
+// style identity of its parent.
+func (s *styleSharing) inherit(node *tree.Node[*styledtree.StyNode]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids[node.Payload] = s.identity(node.Parent())
+}
+
+// propertyMap returns the property map for a node with properties matched,
+// either from the cache or by calling create, and records the node's style
+// identity.
+func (s *styleSharing) propertyMap(node *tree.Node[*styledtree.StyNode], matches *matchesList,
+	create func() *style.PropertyMap) *style.PropertyMap {
+	//
+	s.mu.Lock()
+	key := sharingKey{parent: s.identity(node.Parent()), properties: matches.signature()}
+	s.stats.Styled++
+	if shared, ok := s.styles[key]; ok {
+		s.ids[node.Payload] = shared.id
+		s.stats.Shared++
+		s.mu.Unlock()
+		return shared.pmap
+	}
+	s.mu.Unlock()
+	pmap := create()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if shared, ok := s.styles[key]; ok { // created concurrently by a cousin
+		s.ids[node.Payload] = shared.id
+		s.stats.Shared++
+		return shared.pmap
+	}
+	shared := sharedStyle{id: len(s.styles) + 1, pmap: pmap}
+	s.styles[key] = shared
+	s.ids[node.Payload] = shared.id
+	s.stats.Maps++
+	return pmap
+}
+
+// signature lists the properties of a sorted matches list, which determine
+// the property map created for it.
+func (matches *matchesList) signature() string {
+	var b strings.Builder
+	for _, p := range matches.propertiesTable {
+		b.WriteString(p.propertyKey)
+		b.WriteByte(':')
+		b.WriteString(string(p.propertyValue))
+		b.WriteByte(';')
+	}
+	return b.String()
+}
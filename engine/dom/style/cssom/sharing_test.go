@@ -0,0 +1,74 @@
+package cssom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
+	"github.com/npillmayer/tyse/engine/dom/styledtree"
+	"github.com/npillmayer/tyse/engine/tree"
+	"golang.org/x/net/html"
+)
+
+var sharinghtml = `
+<html><body>
+  <div><p>One</p><p>Two</p><p style="color: blue;">Three</p></div>
+  <div class="note"><p>Four</p><p>Five</p></div>
+</body></html>
+`
+
+var sharingcss = `
+p { margin-bottom: 10pt; font-size: 12pt; }
+div.note { font-size: 10pt; }
+`
+
+func TestStyleSharing(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.dom")
+	defer teardown()
+	//
+	h, err := html.Parse(strings.NewReader(sharinghtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := parser.Parse(sharingcss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := cssom.NewCSSOM(nil)
+	s.AddStylesForScope(nil, douceuradapter.Wrap(sheet), cssom.Author)
+	root, err := s.Style(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paras := map[string]*styledtree.StyNode{}
+	var collect func(n *tree.Node[*styledtree.StyNode])
+	collect = func(n *tree.Node[*styledtree.StyNode]) {
+		if hn := n.Payload.HTMLNode(); hn.Type == html.ElementNode && hn.Data == "p" {
+			paras[hn.FirstChild.Data] = n.Payload
+		}
+		for _, ch := range n.Children(true) {
+			collect(ch)
+		}
+	}
+	collect(root)
+	if paras["One"].Styles() == nil || paras["One"].Styles() != paras["Two"].Styles() {
+		t.Errorf("expected sibling paragraphs to share their styles")
+	}
+	if paras["Four"].Styles() != paras["Five"].Styles() {
+		t.Errorf("expected paragraphs of note to share their styles")
+	}
+	if paras["One"].Styles() == paras["Three"].Styles() {
+		t.Errorf("expected paragraph with style attribute to have styles of its own")
+	}
+	if paras["One"].Styles() == paras["Four"].Styles() {
+		t.Errorf("expected paragraphs with different parent styles not to share styles")
+	}
+	stats := s.SharingStats()
+	t.Logf("style sharing: %s", stats)
+	if stats.Shared < 2 || stats.Styled != stats.Shared+stats.Maps {
+		t.Errorf("unexpected sharing statistics: %s", stats)
+	}
+}
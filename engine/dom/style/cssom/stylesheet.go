@@ -31,3 +31,13 @@ type Rule interface {
 	Value(string) style.Property // property value for key, e.g. "15px"
 	IsImportant(string) bool     // is property key marked as important?
 }
+
+// EmbeddingRule is an optional interface for at-rules which embed other rules,
+// e.g. @media rules. Their selector is the at-keyword together with the prelude,
+// e.g. "@media print and (min-width: 100mm)".
+//
+// See function EffectiveRules.
+type EmbeddingRule interface {
+	Rule
+	EmbeddedRules() []Rule // rules nested within the at-rule
+}
@@ -61,7 +61,7 @@ type KeyValue struct {
 
 // --- CSS Property Groups ----------------------------------------------
 //
-// Caching is currently not implemented.
+// Property maps of nodes with identical styles are shared, see package cssom.
 
 // PropertyGroup is a collection of propertes sharing a common topic.
 // CSS knows a whole lot of properties. We split them up into organisatorial
@@ -152,21 +152,19 @@ func (pg *PropertyGroup) Add(key string, p Property) {
 }
 
 // ForkOnProperty creates a new PropertyGroup, pre-filled with a given property.
-// If 'cascade' is true, the new PropertyGroup will be
-// linking to the ancesting PropertyGroup containing this property.
+// If 'cascade' is true, the new PropertyGroup will be linking to pg, so that
+// the other properties of the group, e.g. line-height for a fork on font-size,
+// are still inherited from pg and its ancestors.
 func (pg *PropertyGroup) ForkOnProperty(key string, p Property, cascade bool) (*PropertyGroup, bool) {
-	var ancestor *PropertyGroup
+	var parent *PropertyGroup
 	if cascade {
-		ancestor = pg.Cascade(key)
-		if ancestor != nil {
-			p2, _ := ancestor.Get(key)
-			if p2 == p {
-				return pg, false
-			}
+		if p2, _ := pg.Cascade(key).Get(key); p2 == p {
+			return pg, false
 		}
+		parent = pg
 	}
 	npg := NewPropertyGroup(pg.name)
-	npg.Parent = ancestor
+	npg.Parent = parent
 	//npg.signature = pg.signature
 	npg.Set(key, p)
 	return npg, true
@@ -191,11 +189,10 @@ func (pg *PropertyGroup) Cascade(key string) *PropertyGroup {
 //
 // Unknown style property keys will return a group name of "X".
 func GroupNameFromPropertyKey(key string) string {
-	groupname, found := groupNameFromPropertyKey[key]
-	if !found {
-		groupname = "X"
+	if spec, ok := LookupProperty(key); ok {
+		return spec.Group
 	}
-	return groupname
+	return PGX
 }
 
 // Symbolic names for string literals, denoting PropertyGroups.
@@ -208,145 +205,16 @@ const (
 	PGRegion    = "Region"
 	PGColor     = "Color"
 	PGText      = "Text"
+	PGFont      = "Font"
 	PGX         = "X"
 )
 
-var groupNameFromPropertyKey = map[string]string{
-	"margin-top":                 PGMargins, // Margins
-	"margin-left":                PGMargins,
-	"margin-right":               PGMargins,
-	"margin-bottom":              PGMargins,
-	"padding-top":                PGPadding, // Padding
-	"padding-left":               PGPadding,
-	"padding-right":              PGPadding,
-	"padding-bottom":             PGPadding,
-	"border-top-color":           PGBorder, // Border
-	"border-left-color":          "Border",
-	"border-right-color":         "Border",
-	"border-bottom-color":        "Border",
-	"border-top-width":           "Border",
-	"border-left-width":          "Border",
-	"border-right-width":         "Border",
-	"border-bottom-width":        "Border",
-	"border-top-style":           "Border",
-	"border-left-style":          "Border",
-	"border-right-style":         "Border",
-	"border-bottom-style":        "Border",
-	"border-top-left-radius":     "Border",
-	"border-top-right-radius":    "Border",
-	"border-bottom-left-radius":  "Border",
-	"border-bottom-right-radius": "Border",
-	"width":                      "Dimension", // Dimension
-	"height":                     "Dimension",
-	"min-width":                  "Dimension",
-	"min-height":                 "Dimension",
-	"max-width":                  "Dimension",
-	"max-height":                 "Dimension",
-	"display":                    PGDisplay, // Display
-	"float":                      PGDisplay,
-	"visibility":                 PGDisplay,
-	"position":                   PGDisplay,
-	"flow-into":                  PGRegion,
-	"flow-from":                  PGRegion,
-	"color":                      PGColor,
-	"background-color":           PGColor,
-	"direction":                  PGText,
-	"white-space":                PGText,
-	"word-spacing":               PGText,
-	"letter-spacing":             PGText,
-	"word-break":                 PGText,
-	"word-wrap":                  PGText,
-}
-
 // IsCascading returns wether the standard behaviour for a propery is to be
 // inherited or not, i.e., a call to retrieve its value will cascade.
+// Unknown properties are not inherited.
 func IsCascading(key string) bool {
-	if strings.HasPrefix(key, "list-style") {
-		return true
-	}
-	switch key {
-	case "color", "cursor", "direction", "position", "flow-into", "flow-from":
-		return true
-	case "letter-spacing", "line-height", "quotes", "visibility", "white-space":
-		return true
-	case "word-spacing", "word-break", "word-wrap":
-		return true
-	}
-	return false
-}
-
-// SplitCompoundProperty splits up a shortcut property into its individual
-// components. Returns a slice of key-value pairs representing the
-// individual (fine grained) style properties.
-// Example:
-//    SplitCompountProperty("padding", "3px")
-// will return
-//    "padding-top"    => "3px"
-//    "padding-right"  => "3px"
-//    "padding-bottom" => "3px"
-//    "padding-left  " => "3px"
-// For the logic behind this, refer to e.g.
-// https://www.w3schools.com/css/css_padding.asp .
-func SplitCompoundProperty(key string, value Property) ([]KeyValue, error) {
-	fields := strings.Fields(value.String())
-	switch key {
-	case "margins":
-		return feazeCompound4("margin", "", fourDirs, fields)
-	case "padding":
-		return feazeCompound4("padding", "", fourDirs, fields)
-	case "border-color":
-		return feazeCompound4("border", "color", fourDirs, fields)
-	case "border-width":
-		return feazeCompound4("border", "width", fourDirs, fields)
-	case "border-style":
-		return feazeCompound4("border", "style", fourDirs, fields)
-	case "border-radius":
-		return feazeCompound4("border", "style", fourCorners, fields)
-	}
-	return nil, fmt.Errorf("not recognized as compound property: %s", key)
-}
-
-// CSS logic to distribute individual values from compound shortcuts is as
-// follows: https://www.w3schools.com/css/css_border.asp
-func feazeCompound4(pre string, suf string, dirs [4]string, fields []string) ([]KeyValue, error) {
-	l := len(fields)
-	if l == 0 || l > 4 {
-		return nil, fmt.Errorf("expecting 1-3 values for %s-%s", pre, suf)
-	}
-	r := make([]KeyValue, 4)
-	r[0] = KeyValue{p(pre, suf, dirs[0]), Property(fields[0])}
-	if l >= 2 {
-		r[1] = KeyValue{p(pre, suf, dirs[1]), Property(fields[1])}
-		if l >= 3 {
-			r[2] = KeyValue{p(pre, suf, dirs[2]), Property(fields[2])}
-			if l == 4 {
-				r[3] = KeyValue{p(pre, suf, dirs[3]), Property(fields[3])}
-			} else {
-				r[3] = KeyValue{p(pre, suf, dirs[3]), Property(fields[1])}
-			}
-		} else {
-			r[2] = KeyValue{p(pre, suf, dirs[2]), Property(fields[0])}
-			r[3] = KeyValue{p(pre, suf, dirs[3]), Property(fields[1])}
-		}
-	} else {
-		r[1] = KeyValue{p(pre, suf, dirs[1]), Property(fields[0])}
-		r[2] = KeyValue{p(pre, suf, dirs[2]), Property(fields[0])}
-		r[3] = KeyValue{p(pre, suf, dirs[3]), Property(fields[0])}
-	}
-	return r, nil
-}
-
-var fourDirs = [4]string{"top", "right", "bottom", "left"}
-var fourCorners = [4]string{"top-right", "bottom-right", "bottom-left", "top-left"}
-
-func p(prefix string, suffix string, tag string) string {
-	if suffix == "" {
-		return prefix + "-" + tag
-	}
-	if prefix == "" {
-		return tag + "-" + suffix
-	}
-	return prefix + "-" + tag + "-" + suffix
+	spec, ok := LookupProperty(key)
+	return ok && spec.Inherited
 }
 
 // --- Property Map -----------------------------------------------------
@@ -0,0 +1,238 @@
+package style
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// --- Property Registry ------------------------------------------------
+
+// PropertySpec describes a CSS property known to the styling engine: the
+// property group it belongs to, wether it is inherited, its initial value
+// and, optionally, a transformation of specified values to computed values.
+//
+// The styling engine handles properties generically, driven by their specs.
+// Adding a new property therefore is a matter of registering a spec for it
+// (see RegisterProperty).
+type PropertySpec struct {
+	Name      string                  // property name, e.g. "margin-top"
+	Group     string                  // property group, e.g. PGMargins
+	Inherited bool                    // is the property inherited by default?
+	Initial   Property                // initial value
+	Compute   func(Property) Property // transformation to a computed value, or nil
+}
+
+// ComputedValue returns the value to store for a value p specified for
+// the property. CSS-wide keywords "initial" and "unset" are resolved, and
+// the spec's transformation is applied. "inherit" is kept, as it will be
+// resolved when looking up the property.
+func (spec PropertySpec) ComputedValue(p Property) Property {
+	switch p {
+	case "inherit", NullStyle:
+		return p
+	case "initial":
+		return spec.Initial
+	case "unset":
+		if spec.Inherited {
+			return "inherit"
+		}
+		return spec.Initial
+	}
+	if spec.Compute != nil {
+		return spec.Compute(p)
+	}
+	return p
+}
+
+var registry = struct {
+	sync.RWMutex
+	specs map[string]PropertySpec
+}{specs: make(map[string]PropertySpec)}
+
+// RegisterProperty adds a property to the properties known to the styling
+// engine, or replaces the spec of a known one. Properties have to be
+// registered before a CSSOM is created, as user-agent defaults are set up from
+// the registered properties.
+//
+// The property group of a new property may be a group of its own. If it is
+// empty, the property is put into group PGX.
+func RegisterProperty(spec PropertySpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("cannot register property without a name")
+	}
+	if spec.Group == "" {
+		spec.Group = PGX
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if old, ok := registry.specs[spec.Name]; ok && old.Group != spec.Group {
+		return fmt.Errorf("property %s is registered for group %s", spec.Name, old.Group)
+	}
+	registry.specs[spec.Name] = spec
+	return nil
+}
+
+// LookupProperty returns the spec for a property, together with an indicator
+// wether the property is known.
+func LookupProperty(key string) (PropertySpec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	spec, ok := registry.specs[key]
+	return spec, ok
+}
+
+// RegisteredProperties returns the specs of all known properties, ordered by
+// group and name.
+func RegisteredProperties() []PropertySpec {
+	registry.RLock()
+	specs := make([]PropertySpec, 0, len(registry.specs))
+	for _, spec := range registry.specs {
+		specs = append(specs, spec)
+	}
+	registry.RUnlock()
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].Group != specs[j].Group {
+			return specs[i].Group < specs[j].Group
+		}
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+// ComputedValue returns the computed value for a value p specified for a
+// property key (see PropertySpec.ComputedValue). Values of unknown properties
+// are returned unchanged.
+func ComputedValue(key string, p Property) Property {
+	if spec, ok := LookupProperty(key); ok {
+		return spec.ComputedValue(p)
+	}
+	return p
+}
+
+func init() {
+	for _, spec := range builtinProperties {
+		if err := RegisterProperty(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// builtinProperties are the properties supported by the engine. Initial
+// values of "default" have the following semantics: treat this as an inherent
+// UA default, which should not be instantiated in memory, but rather will be
+// treated implicitely by rendering code.
+// See issue https://github.com/npillmayer/tyse/issues/8
+var builtinProperties = []PropertySpec{
+	{"margin-top", PGMargins, false, "0", nil},
+	{"margin-left", PGMargins, false, "0", nil},
+	{"margin-right", PGMargins, false, "0", nil},
+	{"margin-bottom", PGMargins, false, "0", nil},
+	{"padding-top", PGPadding, false, "0", nil},
+	{"padding-left", PGPadding, false, "0", nil},
+	{"padding-right", PGPadding, false, "0", nil},
+	{"padding-bottom", PGPadding, false, "0", nil},
+	{"border-top-color", PGBorder, false, "default", nil},
+	{"border-left-color", PGBorder, false, "default", nil},
+	{"border-right-color", PGBorder, false, "default", nil},
+	{"border-bottom-color", PGBorder, false, "default", nil},
+	{"border-top-width", PGBorder, false, "medium", nil},
+	{"border-left-width", PGBorder, false, "medium", nil},
+	{"border-right-width", PGBorder, false, "medium", nil},
+	{"border-bottom-width", PGBorder, false, "medium", nil},
+	{"border-top-style", PGBorder, false, "none", nil},
+	{"border-left-style", PGBorder, false, "none", nil},
+	{"border-right-style", PGBorder, false, "none", nil},
+	{"border-bottom-style", PGBorder, false, "none", nil},
+	{"border-top-left-radius", PGBorder, false, "0", nil},
+	{"border-top-right-radius", PGBorder, false, "0", nil},
+	{"border-bottom-left-radius", PGBorder, false, "0", nil},
+	{"border-bottom-right-radius", PGBorder, false, "0", nil},
+	{"width", PGDimension, false, "auto", nil},
+	{"height", PGDimension, false, "auto", nil},
+	{"min-width", PGDimension, false, "none", nil},
+	{"min-height", PGDimension, false, "none", nil},
+	{"max-width", PGDimension, false, "none", nil},
+	{"max-height", PGDimension, false, "none", nil},
+	{"display", PGDisplay, false, "block", nil}, // UA default depends on element, see GetUserAgentDefaultProperty
+	{"float", PGDisplay, false, "none", nil},
+	{"clear", PGDisplay, false, "none", nil},
+	{"flex-direction", PGDisplay, false, "row", nil},
+	{"flex-wrap", PGDisplay, false, "nowrap", nil},
+	{"justify-content", PGDisplay, false, "flex-start", nil},
+	{"align-items", PGDisplay, false, "stretch", nil},
+	{"align-self", PGDisplay, false, "auto", nil},
+	{"flex-grow", PGDisplay, false, "0", nil},
+	{"flex-shrink", PGDisplay, false, "1", nil},
+	{"flex-basis", PGDisplay, false, "auto", nil},
+	{"visibility", PGDisplay, true, "visible", nil},
+	{"cursor", PGDisplay, true, "auto", nil},
+	{"position", PGDisplay, true, "static", nil},
+	{"top", PGDisplay, false, "auto", nil},
+	{"right", PGDisplay, false, "auto", nil},
+	{"bottom", PGDisplay, false, "auto", nil},
+	{"left", PGDisplay, false, "auto", nil},
+	{"break-before", PGDisplay, false, "auto", legacyBreak},
+	{"break-after", PGDisplay, false, "auto", legacyBreak},
+	{"break-inside", PGDisplay, false, "auto", nil},
+	{"column-count", PGDisplay, false, "auto", nil},
+	{"column-width", PGDisplay, false, "auto", nil},
+	{"column-gap", PGDisplay, false, "normal", nil},
+	{"column-rule-width", PGDisplay, false, "medium", nil},
+	{"column-rule-style", PGDisplay, false, "none", nil},
+	{"column-rule-color", PGDisplay, false, "currentcolor", nil},
+	{"flow-into", PGRegion, true, "none", nil},
+	{"flow-from", PGRegion, true, "none", nil},
+	{"color", PGColor, true, "default", nil},
+	{"background-color", PGColor, false, "default", nil},
+	{"background-image", PGColor, false, "none", nil},
+	{"background-repeat", PGColor, false, "repeat", nil},
+	{"background-attachment", PGColor, false, "scroll", nil},
+	{"background-position", PGColor, false, "0% 0%", nil},
+	{"font-style", PGFont, true, "normal", nil},
+	{"font-variant", PGFont, true, "normal", nil},
+	{"font-variant-ligatures", PGFont, true, "normal", nil},
+	{"font-variant-numeric", PGFont, true, "normal", nil},
+	{"font-variant-caps", PGFont, true, "normal", nil},
+	{"font-feature-settings", PGFont, true, "normal", nil},
+	{"font-weight", PGFont, true, "normal", nil},
+	{"font-stretch", PGFont, true, "normal", nil},
+	{"font-size", PGFont, true, "medium", nil},
+	{"font-size-adjust", PGFont, true, "none", nil},
+	{"line-height", PGFont, true, "normal", nil},
+	{"font-family", PGFont, true, "serif", nil},
+	{"direction", PGText, true, "ltr", nil},
+	{"white-space", PGText, true, "normal", nil},
+	{"word-spacing", PGText, true, "normal", nil},
+	{"letter-spacing", PGText, true, "normal", nil},
+	{"word-break", PGText, true, "normal", nil},
+	{"word-wrap", PGText, true, "normal", nil},
+	{"overflow-wrap", PGText, true, "normal", nil},
+	{"hyphens", PGText, true, "manual", nil},
+	{"hyphenate-limit-chars", PGText, true, "auto", nil},
+	{"text-align", PGText, true, "start", nil},
+	{"text-align-last", PGText, true, "auto", nil},
+	{"text-justify", PGText, true, "auto", nil}, // "auto" selects multi-level justification, see package inline
+	{"text-indent", PGText, true, "0", nil},
+	{"tab-size", PGText, true, "8", nil},
+	{"text-transform", PGText, true, "none", nil},
+	{"initial-letter", PGText, false, "normal", nil},
+	{"vertical-align", PGText, false, "baseline", nil},
+	{"widows", PGText, true, "2", nil},
+	{"orphans", PGText, true, "2", nil},
+	{"quotes", PGText, true, "auto", nil},
+	{"list-style-type", PGText, true, "disc", nil},
+	{"list-style-position", PGText, true, "outside", nil},
+	{"list-style-image", PGText, true, "none", nil},
+	{"counter-reset", PGText, false, "none", nil},
+	{"counter-increment", PGText, false, "none", nil},
+}
+
+// legacyBreak maps values of CSS 2 properties page-break-before and
+// page-break-after to values of break-before and break-after.
+func legacyBreak(p Property) Property {
+	if p == "always" {
+		return "page"
+	}
+	return p
+}
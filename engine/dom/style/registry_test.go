@@ -0,0 +1,70 @@
+package style_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+func TestPropertyRegistry(t *testing.T) {
+	for key, inherited := range map[string]bool{
+		"font-size": true, "hyphens": true, "margin-top": false, "float": false,
+		"unknown-property": false,
+	} {
+		if style.IsCascading(key) != inherited {
+			t.Errorf("expected %s to be inherited = %v", key, inherited)
+		}
+	}
+	if g := style.GroupNameFromPropertyKey("hyphens"); g != style.PGText {
+		t.Errorf("expected hyphens to be in group %s, is in %s", style.PGText, g)
+	}
+	defaults := style.InitializeDefaultPropertyValues([]style.KeyValue{{Key: "font-family", Value: "sans-serif"}})
+	for _, spec := range style.RegisteredProperties() {
+		if _, ok := defaults.Property(spec.Name); !ok {
+			t.Errorf("expected user-agent defaults to contain %s", spec.Name)
+		}
+	}
+	if p, _ := defaults.Property("font-family"); p != "sans-serif" {
+		t.Errorf("expected additional property to override initial font-family, is %s", p)
+	}
+	if p := style.GetUserAgentDefaultProperty(nil, "break-inside"); p != "auto" {
+		t.Errorf("expected initial value 'auto' for break-inside, is '%s'", p)
+	}
+}
+
+func TestComputedValues(t *testing.T) {
+	for _, x := range []struct {
+		key, value, computed string
+	}{
+		{"margin-top", "initial", "0"},
+		{"margin-top", "unset", "0"},
+		{"color", "unset", "inherit"},
+		{"color", "inherit", "inherit"},
+		{"break-before", "always", "page"},
+		{"break-after", "avoid", "avoid"},
+		{"unknown-property", "initial", "initial"},
+	} {
+		if p := style.ComputedValue(x.key, style.Property(x.value)); p.String() != x.computed {
+			t.Errorf("expected %s: %s to compute to '%s', is '%s'", x.key, x.value, x.computed, p)
+		}
+	}
+}
+
+func TestRegisterProperty(t *testing.T) {
+	err := style.RegisterProperty(style.PropertySpec{
+		Name: "x-test-ornament", Group: style.PGText, Inherited: true, Initial: "none",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !style.IsCascading("x-test-ornament") || style.GroupNameFromPropertyKey("x-test-ornament") != style.PGText {
+		t.Errorf("expected registered property to be inherited and in group %s", style.PGText)
+	}
+	defaults := style.InitializeDefaultPropertyValues(nil)
+	if p, _ := defaults.Property("x-test-ornament"); p != "none" {
+		t.Errorf("expected initial value of registered property in defaults, is '%s'", p)
+	}
+	if style.RegisterProperty(style.PropertySpec{Name: "margin-top", Group: style.PGFont}) == nil {
+		t.Errorf("expected moving margin-top to another group to fail")
+	}
+}
@@ -0,0 +1,467 @@
+package style
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- Shorthand Properties ---------------------------------------------
+//
+// CSS shorthand properties set a group of longhand properties at once, e.g.
+//
+//     border: 1px solid red
+//
+// sets border-{top,right,bottom,left}-{width,style,color}. Shorthands are
+// expanded to their longhands before the cascade, so that all consumers of
+// the styled tree see longhand properties only. Longhands omitted from a
+// shorthand are reset to their initial values, as required by the spec.
+
+// shorthand describes a shorthand property: its longhands (in canonical order),
+// the initial values of the longhands, and a function to distribute the fields
+// of a shorthand value onto the longhands. expand returns one value per longhand;
+// empty values denote longhands omitted from the shorthand.
+type shorthand struct {
+	longhands []string
+	initial   []Property
+	expand    func(fields []string) ([]string, error)
+}
+
+var shorthands = map[string]*shorthand{
+	"margin":        sides("margin-", "", "0", fourSides),
+	"padding":       sides("padding-", "", "0", fourSides),
+	"border-width":  sides("border-", "-width", "medium", fourSides),
+	"border-style":  sides("border-", "-style", "none", fourSides),
+	"border-color":  sides("border-", "-color", "currentcolor", fourSides),
+	"border-radius": sides("border-", "-radius", "0", fourCorners),
+	"border-top":    borderSide("top"),
+	"border-right":  borderSide("right"),
+	"border-bottom": borderSide("bottom"),
+	"border-left":   borderSide("left"),
+	"border":        border(),
+	"font": {
+		longhands: []string{"font-style", "font-variant", "font-weight", "font-stretch",
+			"font-size", "line-height", "font-family"},
+		initial: []Property{"normal", "normal", "normal", "normal", "medium", "normal", "serif"},
+		expand:  expandFont,
+	},
+	"background": {
+		longhands: []string{"background-color", "background-image", "background-repeat",
+			"background-attachment", "background-position"},
+		initial: []Property{"transparent", "none", "repeat", "scroll", "0% 0%"},
+		expand:  expandBackground,
+	},
+	"list-style": {
+		longhands: []string{"list-style-type", "list-style-position", "list-style-image"},
+		initial:   []Property{"disc", "outside", "none"},
+		expand:    expandListStyle,
+	},
+	"flex": {
+		longhands: []string{"flex-grow", "flex-shrink", "flex-basis"},
+		initial:   []Property{"0", "1", "auto"},
+		expand:    expandFlex,
+	},
+	"columns": {
+		longhands: []string{"column-width", "column-count"},
+		initial:   []Property{"auto", "auto"},
+		expand:    expandColumns,
+	},
+	"column-rule": {
+		longhands: []string{"column-rule-width", "column-rule-style", "column-rule-color"},
+		initial:   []Property{"medium", "none", "currentcolor"},
+		expand:    expandBorder,
+	},
+}
+
+// IsShorthand returns true if key denotes a known shorthand property.
+func IsShorthand(key string) bool {
+	_, ok := shorthands[key]
+	return ok
+}
+
+// SplitCompoundProperty splits up a shorthand property into its individual
+// longhand components. Returns a slice of key-value pairs representing the
+// individual (fine grained) style properties.
+// Example:
+//
+//     SplitCompoundProperty("padding", "3px 5px")
+//
+// will return
+//
+//     "padding-top"    => "3px"
+//     "padding-right"  => "5px"
+//     "padding-bottom" => "3px"
+//     "padding-left"   => "5px"
+//
+// Longhands not set by the shorthand value are set to their initial values.
+// "inherit" and "initial" are passed on to all the longhands.
+func SplitCompoundProperty(key string, value Property) ([]KeyValue, error) {
+	sh, ok := shorthands[key]
+	if !ok {
+		return nil, fmt.Errorf("not recognized as compound property: %s", key)
+	}
+	fields := shorthandFields(value.String())
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty value for %s", key)
+	}
+	var values []string
+	if len(fields) == 1 && (fields[0] == "inherit" || fields[0] == "initial") {
+		values = make([]string, len(sh.longhands))
+		for i := range values {
+			values[i] = fields[0]
+		}
+	} else {
+		var err error
+		if values, err = sh.expand(fields); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	kv := make([]KeyValue, len(sh.longhands))
+	for i, longhand := range sh.longhands {
+		kv[i] = KeyValue{Key: longhand, Value: Property(values[i])}
+		if values[i] == "" {
+			kv[i].Value = sh.initial[i]
+		}
+	}
+	return kv, nil
+}
+
+// shorthandFields splits a shorthand value at white space, keeping
+// parenthesized parts (e.g., "rgb(0, 0, 0)") and quoted strings intact.
+func shorthandFields(value string) []string {
+	var fields []string
+	var b strings.Builder
+	depth, quote := 0, rune(0)
+	for _, r := range value {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0 && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields
+}
+
+// --- Boxes -------------------------------------------------------------
+
+var fourSides = [4]string{"top", "right", "bottom", "left"}
+var fourCorners = [4]string{"top-left", "top-right", "bottom-right", "bottom-left"}
+
+// sides creates a shorthand for a property applying to the four sides
+// (or corners) of a box.
+func sides(prefix, suffix string, initial Property, dirs [4]string) *shorthand {
+	sh := &shorthand{expand: expandFour}
+	for _, dir := range dirs {
+		sh.longhands = append(sh.longhands, prefix+dir+suffix)
+		sh.initial = append(sh.initial, initial)
+	}
+	return sh
+}
+
+// CSS logic to distribute individual values from 1–4 fields is as
+// follows: https://www.w3schools.com/css/css_padding.asp
+func expandFour(fields []string) ([]string, error) {
+	switch len(fields) {
+	case 1:
+		return []string{fields[0], fields[0], fields[0], fields[0]}, nil
+	case 2:
+		return []string{fields[0], fields[1], fields[0], fields[1]}, nil
+	case 3:
+		return []string{fields[0], fields[1], fields[2], fields[1]}, nil
+	case 4:
+		return fields, nil
+	}
+	return nil, fmt.Errorf("expecting 1-4 values, have %d", len(fields))
+}
+
+// borderSide creates a shorthand for border-top, border-right, etc.
+func borderSide(side string) *shorthand {
+	return &shorthand{
+		longhands: []string{"border-" + side + "-width", "border-" + side + "-style", "border-" + side + "-color"},
+		initial:   []Property{"medium", "none", "currentcolor"},
+		expand:    expandBorder,
+	}
+}
+
+// border creates the shorthand for border, which sets all four sides alike.
+func border() *shorthand {
+	sh := &shorthand{}
+	for _, side := range fourSides {
+		s := borderSide(side)
+		sh.longhands = append(sh.longhands, s.longhands...)
+		sh.initial = append(sh.initial, s.initial...)
+	}
+	sh.expand = func(fields []string) ([]string, error) {
+		values, err := expandBorder(fields)
+		if err != nil {
+			return nil, err
+		}
+		return append(append(append(values, values...), values...), values...), nil
+	}
+	return sh
+}
+
+var borderStyles = keywords("none", "hidden", "dotted", "dashed", "solid", "double",
+	"groove", "ridge", "inset", "outset")
+
+// expandBorder distributes "<width> || <style> || <color>", in any order.
+func expandBorder(fields []string) ([]string, error) {
+	values := make([]string, 3)
+	for _, f := range fields {
+		var i int
+		switch {
+		case isLength(f) || f == "thin" || f == "medium" || f == "thick":
+			i = 0
+		case borderStyles[f]:
+			i = 1
+		default:
+			i = 2 // everything else has to be a color
+		}
+		if values[i] != "" {
+			return nil, fmt.Errorf("duplicate value '%s'", f)
+		}
+		values[i] = f
+	}
+	return values, nil
+}
+
+// --- Font --------------------------------------------------------------
+
+var fontStyles = keywords("italic", "oblique")
+var fontVariants = keywords("small-caps")
+var fontWeights = keywords("bold", "bolder", "lighter", "100", "200", "300", "400", "500",
+	"600", "700", "800", "900")
+var fontStretches = keywords("ultra-condensed", "extra-condensed", "condensed", "semi-condensed",
+	"semi-expanded", "expanded", "extra-expanded", "ultra-expanded")
+var fontSizes = keywords("xx-small", "x-small", "small", "medium", "large", "x-large",
+	"xx-large", "smaller", "larger")
+
+// expandFont distributes
+//
+//     [ <style> || <variant> || <weight> || <stretch> ]? <size> [ / <line-height> ]? <family>
+//
+// "normal" may be given for any of the first four longhands and leaves them at
+// their initial value.
+func expandFont(fields []string) ([]string, error) {
+	values := make([]string, 7)
+	i := 0
+	for ; i < len(fields); i++ {
+		f := fields[i]
+		if f == "normal" {
+			continue
+		}
+		var k int
+		switch {
+		case fontStyles[f]:
+			k = 0
+		case fontVariants[f]:
+			k = 1
+		case fontWeights[f]:
+			k = 2
+		case fontStretches[f]:
+			k = 3
+		default:
+			k = -1
+		}
+		if k < 0 {
+			break
+		}
+		if values[k] != "" {
+			return nil, fmt.Errorf("duplicate value '%s'", f)
+		}
+		values[k] = f
+	}
+	if i == len(fields) {
+		return nil, fmt.Errorf("font size and family required")
+	}
+	size := fields[i]
+	if sz, lh, found := strings.Cut(size, "/"); found { // 12pt/14pt
+		size, values[5] = sz, lh
+	}
+	i++
+	if values[5] == "" && i < len(fields) && strings.HasPrefix(fields[i], "/") { // 12pt / 14pt, 12pt /14pt
+		values[5] = strings.TrimPrefix(fields[i], "/")
+		i++
+		if values[5] == "" && i < len(fields) {
+			values[5] = fields[i]
+			i++
+		}
+	}
+	if !isLength(size) && !fontSizes[size] {
+		return nil, fmt.Errorf("illegal font size '%s'", size)
+	}
+	values[4] = size
+	if i == len(fields) {
+		return nil, fmt.Errorf("font family required")
+	}
+	values[6] = strings.Join(fields[i:], " ")
+	return values, nil
+}
+
+// --- Background --------------------------------------------------------
+
+var backgroundRepeats = keywords("repeat", "repeat-x", "repeat-y", "no-repeat", "space", "round")
+var backgroundAttachments = keywords("scroll", "fixed", "local")
+var backgroundPositions = keywords("left", "right", "top", "bottom", "center")
+
+// expandBackground distributes color, image, repeat, attachment and position
+// of a (single layer) background, given in any order. Position may consist
+// of more than one field.
+func expandBackground(fields []string) ([]string, error) {
+	values := make([]string, 5)
+	var position []string
+	for _, f := range fields {
+		var i int
+		switch {
+		case f == "none" || strings.HasPrefix(f, "url(") || strings.Contains(f, "-gradient("):
+			i = 1
+		case backgroundRepeats[f]:
+			i = 2
+		case backgroundAttachments[f]:
+			i = 3
+		case backgroundPositions[f] || isLength(f):
+			position = append(position, f)
+			continue
+		default:
+			i = 0 // everything else has to be a color
+		}
+		if values[i] != "" {
+			return nil, fmt.Errorf("duplicate value '%s'", f)
+		}
+		values[i] = f
+	}
+	values[4] = strings.Join(position, " ")
+	return values, nil
+}
+
+// --- Lists -------------------------------------------------------------
+
+var listStylePositions = keywords("inside", "outside")
+
+// expandListStyle distributes "<type> || <position> || <image>", in any order.
+// A value of "none" sets the type, if not given otherwise, else the image.
+func expandListStyle(fields []string) ([]string, error) {
+	values := make([]string, 3)
+	nones := 0
+	for _, f := range fields {
+		var i int
+		switch {
+		case f == "none":
+			nones++
+			continue
+		case listStylePositions[f]:
+			i = 1
+		case strings.HasPrefix(f, "url("):
+			i = 2
+		default:
+			i = 0
+		}
+		if values[i] != "" {
+			return nil, fmt.Errorf("duplicate value '%s'", f)
+		}
+		values[i] = f
+	}
+	for ; nones > 0; nones-- {
+		if values[0] == "" {
+			values[0] = "none"
+		} else if values[2] == "" {
+			values[2] = "none"
+		} else {
+			return nil, fmt.Errorf("duplicate value 'none'")
+		}
+	}
+	return values, nil
+}
+
+// --- Flex --------------------------------------------------------------
+
+// expandFlex splits the flex shorthand into flex-grow, flex-shrink and flex-basis.
+// See https://www.w3.org/TR/css-flexbox-1/#flex-common .
+func expandFlex(fields []string) ([]string, error) {
+	grow, shrink, basis := "0", "1", "auto"
+	isNumber := func(s string) bool {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+	}
+	switch l := len(fields); {
+	case l == 1 && fields[0] == "none":
+		shrink = "0"
+	case l == 1 && fields[0] == "auto":
+		grow = "1"
+	case l == 1 && isNumber(fields[0]):
+		grow, basis = fields[0], "0"
+	case l == 1:
+		grow, basis = "1", fields[0]
+	case l == 2 && isNumber(fields[1]):
+		grow, shrink, basis = fields[0], fields[1], "0"
+	case l == 2:
+		grow, basis = fields[0], fields[1]
+	case l == 3:
+		grow, shrink, basis = fields[0], fields[1], fields[2]
+	default:
+		return nil, fmt.Errorf("expecting 1-3 values, have %d", l)
+	}
+	return []string{grow, shrink, basis}, nil
+}
+
+// --- Columns -----------------------------------------------------------
+
+// expandColumns distributes "<column-width> || <column-count>", in any order.
+// A number without a unit is the column count, "auto" leaves a longhand at
+// its initial value.
+func expandColumns(fields []string) ([]string, error) {
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("expecting 1-2 values, have %d", len(fields))
+	}
+	values := make([]string, 2)
+	for _, f := range fields {
+		if f == "auto" {
+			continue
+		}
+		i := 0
+		if _, err := strconv.Atoi(f); err == nil {
+			i = 1
+		} else if !isLength(f) {
+			return nil, fmt.Errorf("illegal value '%s'", f)
+		}
+		if values[i] != "" {
+			return nil, fmt.Errorf("duplicate value '%s'", f)
+		}
+		values[i] = f
+	}
+	return values, nil
+}
+
+// --- Helpers -----------------------------------------------------------
+
+func keywords(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+// isLength is true for fields starting like a length or percentage, e.g.
+// "0", "12pt", "-1.5em" or "50%".
+func isLength(f string) bool {
+	f = strings.TrimLeft(f, "+-")
+	return f != "" && (f[0] >= '0' && f[0] <= '9' || f[0] == '.')
+}
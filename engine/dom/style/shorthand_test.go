@@ -0,0 +1,101 @@
+package style_test
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+func TestShorthandExpansion(t *testing.T) {
+	for _, x := range []struct {
+		key, value string
+		expected   map[string]style.Property
+	}{
+		{"margin", "1pt 2pt", map[string]style.Property{
+			"margin-top": "1pt", "margin-right": "2pt", "margin-bottom": "1pt", "margin-left": "2pt"}},
+		{"padding", "1pt 2pt 3pt", map[string]style.Property{
+			"padding-top": "1pt", "padding-right": "2pt", "padding-bottom": "3pt", "padding-left": "2pt"}},
+		{"border-radius", "4px 0", map[string]style.Property{
+			"border-top-left-radius": "4px", "border-top-right-radius": "0"}},
+		{"border", "solid rgb(255, 0, 0) 1px", map[string]style.Property{
+			"border-top-width": "1px", "border-left-style": "solid", "border-bottom-color": "rgb(255, 0, 0)"}},
+		{"border-top", "dashed", map[string]style.Property{
+			"border-top-width": "medium", "border-top-style": "dashed", "border-top-color": "currentcolor"}},
+		{"font", `italic bold 12pt/14pt "Times New Roman", serif`, map[string]style.Property{
+			"font-style": "italic", "font-variant": "normal", "font-weight": "bold", "font-size": "12pt",
+			"line-height": "14pt", "font-family": `"Times New Roman", serif`}},
+		{"font", "10pt / 1.2 sans-serif", map[string]style.Property{
+			"font-style": "normal", "font-size": "10pt", "line-height": "1.2", "font-family": "sans-serif"}},
+		{"background", "url(paper.png) no-repeat left top #eee", map[string]style.Property{
+			"background-color": "#eee", "background-image": "url(paper.png)", "background-repeat": "no-repeat",
+			"background-attachment": "scroll", "background-position": "left top"}},
+		{"list-style", "none inside", map[string]style.Property{
+			"list-style-type": "none", "list-style-position": "inside", "list-style-image": "none"}},
+		{"list-style", "upper-roman", map[string]style.Property{
+			"list-style-type": "upper-roman", "list-style-position": "outside"}},
+		{"flex", "2", map[string]style.Property{
+			"flex-grow": "2", "flex-shrink": "1", "flex-basis": "0"}},
+		{"columns", "12em 3", map[string]style.Property{
+			"column-width": "12em", "column-count": "3"}},
+		{"columns", "2", map[string]style.Property{
+			"column-width": "auto", "column-count": "2"}},
+		{"column-rule", "1pt dotted", map[string]style.Property{
+			"column-rule-width": "1pt", "column-rule-style": "dotted", "column-rule-color": "currentcolor"}},
+		{"padding", "inherit", map[string]style.Property{
+			"padding-top": "inherit", "padding-left": "inherit"}},
+	} {
+		kv, err := style.SplitCompoundProperty(x.key, style.Property(x.value))
+		if err != nil {
+			t.Errorf("%s: %s: %v", x.key, x.value, err)
+			continue
+		}
+		longhands := make(map[string]style.Property, len(kv))
+		for _, p := range kv {
+			longhands[p.Key] = p.Value
+		}
+		for key, value := range x.expected {
+			if longhands[key] != value {
+				t.Errorf("%s: %s: expected %s = '%s', have '%s'", x.key, x.value, key, value, longhands[key])
+			}
+		}
+	}
+}
+
+func TestShorthandErrors(t *testing.T) {
+	for _, x := range []struct{ key, value string }{
+		{"color", "red"},                  // not a shorthand
+		{"margin", "1pt 2pt 3pt 4pt 5pt"}, // too many values
+		{"border", "1px 2px"},             // duplicate width
+		{"font", "bold italic"},           // size and family missing
+		{"font", "12pt"},                  // family missing
+	} {
+		if _, err := style.SplitCompoundProperty(x.key, style.Property(x.value)); err == nil {
+			t.Errorf("expected %s: %s to be rejected", x.key, x.value)
+		}
+	}
+}
+
+func TestLineHeightInheritance(t *testing.T) {
+	if !style.IsCascading("line-height") {
+		t.Fatalf("expected line-height to be inherited")
+	}
+	ua := style.NewPropertyGroup(style.PGFont)
+	kvs, err := style.SplitCompoundProperty("font", "medium serif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range kvs {
+		ua.Set(kv.Key, kv.Value)
+	}
+	parent, _ := ua.ForkOnProperty("line-height", "1.5", true)
+	child, isNew := parent.ForkOnProperty("font-size", "12pt", true)
+	if !isNew {
+		t.Fatalf("expected font-size to fork the font group")
+	}
+	if p, _ := child.Cascade("line-height").Get("line-height"); p != "1.5" {
+		t.Errorf("expected line-height to be inherited from parent, is %q", p)
+	}
+	if p, _ := child.Cascade("font-family").Get("font-family"); p != "serif" {
+		t.Errorf("expected font-family to be inherited from user agent, is %q", p)
+	}
+}
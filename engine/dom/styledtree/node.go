@@ -81,8 +81,13 @@ func (sn *StyNode) GetPropertyValue(key string, pmap *style.PropertyMap) style.P
 		tracer().P("key", key).Debugf("styling: cascading for key %s", key)
 		tracer().P("key", key).Debugf("styling: cascading with property group %s", groupname)
 		var group *style.PropertyGroup
-		for sn != nil && group == nil {
-			sn = sn.Parent().Payload
+		if p != "inherit" {
+			group = pmap.Group(groupname)
+		}
+		for n := sn.Parent(); n != nil && group == nil; n = n.Parent() {
+			if styles := n.Payload.Styles(); styles != nil {
+				group = styles.Group(groupname)
+			}
 		}
 		if group == nil {
 			return style.NullStyle
@@ -11,9 +11,11 @@ Copyright © 2017–2021 Norbert Pillmayer <norbert@pillmayer.com>
 */
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/percent"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 )
 
@@ -83,7 +85,36 @@ func (box *Box) SetWidth(w css.DimenT) {
 	box.W = w
 }
 
-/*
+// HasFixedBorderBoxWidth return true if box.W, horizontal margins and border width for
+// left and right border have fixed (known) values.
+// If includeMargins is true, left and right margins are checked as well.
+func (box *Box) HasFixedBorderBoxWidth(includeMargins bool) bool {
+	if includeMargins {
+		if !box.Margins[Left].IsAbsolute() || !box.Margins[Right].IsAbsolute() {
+			return false
+		}
+	}
+	if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
+		!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() ||
+		!box.W.IsAbsolute() {
+		return false
+	}
+	return true
+}
+
+// InitEmptyBox initializes padding, border and margins to 0 and box.W to auto.
+func InitEmptyBox(box *Box) *Box {
+	if box == nil {
+		box = &Box{}
+	}
+	for dir := Top; dir <= Left; dir++ {
+		box.Padding[dir] = css.JustDimen(0)
+		box.BorderWidth[dir] = css.JustDimen(0)
+		box.Margins[dir] = css.JustDimen(0)
+	}
+	box.W = css.Auto()
+	return box
+}
 
 // ContentWidth returns the width of the content box.
 // If this box has box-sizing set to `border-box` and the width dimensions do
@@ -93,15 +124,11 @@ func (box *Box) ContentWidth() css.DimenT {
 		return box.W
 	}
 	if box.HasFixedBorderBoxWidth(false) {
-		w := box.W.Unwrap()
-		w -= innerDecorationWidth(box).Unwrap()
-		// w -= box.Padding[Left].Unwrap()
-		// w -= box.Padding[Right].Unwrap()
-		// w -= box.BorderWidth[Left].Unwrap()
-		// w -= box.BorderWidth[Right].Unwrap()
-		return css.SomeDimen(w)
+		w := fixed(box.W)
+		w -= fixed(innerDecorationWidth(box))
+		return css.JustDimen(w)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
 // ContentHeight returns the height of the content box.
@@ -112,11 +139,11 @@ func (box *Box) ContentHeight() css.DimenT {
 		return box.H
 	}
 	if box.HasFixedBorderBoxHeight(false) {
-		h := box.H.Unwrap()
-		h -= innerDecorationHeight(box).Unwrap()
-		return css.SomeDimen(h)
+		h := fixed(box.H)
+		h -= fixed(innerDecorationHeight(box))
+		return css.JustDimen(h)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
 // FixContentWidth sets a known value for the width of the content box.
@@ -129,48 +156,14 @@ func (box *Box) FixContentWidth(w dimen.DU) bool {
 	if !ok {
 		return false
 	}
-		// if box.BorderBoxSizing {
-		// 	decW := innerDecorationWidth(box)
-		// 	if decW.IsNone() {
-		// 		return false
-		// 	}
-		// 	//w += decW.Unwrap()
-		// 	//
-		// 	// if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
-		// 	// 	!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() {
-		// 	// 	return false
-		// 	// }
-		// 	// w += box.Padding[Left].Unwrap()
-		// 	// w += box.Padding[Right].Unwrap()
-		// 	// w += box.BorderWidth[Left].Unwrap()
-		// 	// w += box.BorderWidth[Right].Unwrap()
-		// }
 	box.W = W
 	return true
 }
 
-// HasFixedBorderBoxWidth return true if box.W, horizontal margins and border width for
-// left and right border have fixed (known) values.
-// If includeMargins is true, left and right margins are checked as well.
-func (box *Box) HasFixedBorderBoxWidth(includeMargins bool) bool {
-	if includeMargins {
-		if !box.Margins[Left].IsAbsolute() || !box.Margins[Right].IsAbsolute() {
-			return false
-		}
-	}
-	if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
-		!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() ||
-		!box.W.IsAbsolute() {
-		return false
-	}
-	return true
-}
-
-// HasFixedBorderBoxHeight return true if box.W, horizontal margins and border width for
-// left and right border have fixed (known) values.
-// If includeMargins is true, left and right margins are checked as well.
+// HasFixedBorderBoxHeight return true if box.H, vertical margins and border width for
+// top and bottom border have fixed (known) values.
+// If includeMargins is true, top and bottom margins are checked as well.
 func (box *Box) HasFixedBorderBoxHeight(includeMargins bool) bool {
-	//T().Debugf("fixed border box height ? => %s", box.DebugString())
 	if includeMargins {
 		if !box.Margins[Top].IsAbsolute() || !box.Margins[Bottom].IsAbsolute() {
 			return false
@@ -192,18 +185,14 @@ func (box *Box) BorderBoxWidth() css.DimenT {
 		return box.W
 	}
 	if box.HasFixedBorderBoxWidth(false) {
-		w := box.W.Unwrap()
-		w += innerDecorationWidth(box).Unwrap()
-		// w += box.Padding[Left].Unwrap()
-		// w += box.Padding[Right].Unwrap()
-		// w += box.BorderWidth[Left].Unwrap()
-		// w += box.BorderWidth[Right].Unwrap()
-		return css.SomeDimen(w)
+		w := fixed(box.W)
+		w += fixed(innerDecorationWidth(box))
+		return css.JustDimen(w)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
-// BorderBoxHeight returns the width of a box, including padding and border.
+// BorderBoxHeight returns the height of a box, including padding and border.
 // If box has box-sizing set to `content-box`and at least one of the dimensions
 // is not of fixed value, an unset dimension is returned.
 func (box *Box) BorderBoxHeight() css.DimenT {
@@ -211,11 +200,11 @@ func (box *Box) BorderBoxHeight() css.DimenT {
 		return box.H
 	}
 	if box.HasFixedBorderBoxHeight(false) {
-		h := box.W.Unwrap()
-		h += innerDecorationHeight(box).Unwrap()
-		return css.SomeDimen(h)
+		h := fixed(box.H)
+		h += fixed(innerDecorationHeight(box))
+		return css.JustDimen(h)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
 // FixBorderBoxWidth sets a known border box width for a box.
@@ -224,66 +213,47 @@ func (box *Box) BorderBoxHeight() css.DimenT {
 // internal widths has a variable value, the size is not set.
 // Otherwise padding and border have to be set beforehand to have a correct result
 // for the width-calculation.
-//
-// Will return true if all inner horizontal dimensions (i.e., excluding
-// margins) are fixed.
 func (box *Box) FixBorderBoxWidth(w dimen.DU) {
 	if box.BorderBoxSizing {
-		box.W = css.SomeDimen(w)
-		_, ok := fixPaddingAndBorderWidthFromBorderBoxWidth(box, w)
-		if !ok {
+		box.W = css.JustDimen(w)
+		if _, ok := fixPaddingAndBorderWidthFromBorderBoxWidth(box, w); !ok {
 			tracer().Errorf("cannot fix padding and border")
 		}
 		return
 	}
-	//contentW := box.fixPaddingAndBorderWidthFromBorderBox(w)
-	tracer().Debugf("w = %v", w)
 	contentW, ok := fixPaddingAndBorderWidthFromBorderBoxWidth(box, w)
-	tracer().Debugf("contentW = %v", contentW)
+	tracer().Debugf("w = %v, contentW = %v", w, contentW)
 	if !ok || contentW.IsNone() {
 		tracer().Errorf("cannot fix padding and border")
 		return
 	}
-	// decW := innerDecorationWidth(box)
-	// if decW.IsNone() {
-	// 	return false
-	// }
-	// w -= decW.Unwrap()
-	// if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
-	// 	!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() {
-	// 	return false
-	// }
-	// w -= box.Padding[Left].Unwrap()
-	// w -= box.Padding[Right].Unwrap()
-	// w -= box.BorderWidth[Left].Unwrap()
-	// w -= box.BorderWidth[Right].Unwrap()
 	box.W = contentW
-	return
 }
 
 // TotalWidth returns the overall width of a box, including margins.
 // If one of the dimensions is not of fixed value, an unset dimension is returned.
 func (box *Box) TotalWidth() css.DimenT {
 	if box.HasFixedBorderBoxWidth(true) {
-		w := box.BorderBoxWidth().Unwrap()
-		w += box.Margins[Left].Unwrap()
-		w += box.Margins[Right].Unwrap()
-		return css.SomeDimen(w)
+		w := fixed(box.BorderBoxWidth())
+		w += fixed(box.Margins[Left])
+		w += fixed(box.Margins[Right])
+		return css.JustDimen(w)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
 // TotalHeight returns the overall height of a box.
 func (box *Box) TotalHeight() css.DimenT {
 	if box.HasFixedBorderBoxHeight(true) {
-		h := box.BorderBoxHeight().Unwrap()
-		h += box.Margins[Top].Unwrap()
-		h += box.Margins[Bottom].Unwrap()
-		return css.SomeDimen(h)
+		h := fixed(box.BorderBoxHeight())
+		h += fixed(box.Margins[Top])
+		h += fixed(box.Margins[Bottom])
+		return css.JustDimen(h)
 	}
-	return css.Dimen()
+	return css.DimenT{}
 }
 
+// OuterBox returns the margin box of a box.
 func (box *Box) OuterBox() Rect {
 	r := Rect{TopL: box.TopL}
 	r.W = box.TotalWidth()
@@ -297,281 +267,184 @@ func (box *Box) DecorationWidth(includeMargins bool) css.DimenT {
 	w := dimen.Zero
 	if includeMargins {
 		if !box.Margins[Left].IsAbsolute() || !box.Margins[Right].IsAbsolute() {
-			return css.Dimen()
+			return css.DimenT{}
 		}
-		w += box.Margins[Left].Unwrap()
-		w += box.Margins[Right].Unwrap()
+		w += fixed(box.Margins[Left])
+		w += fixed(box.Margins[Right])
 	}
 	decW := innerDecorationWidth(box)
 	if decW.IsNone() {
 		return decW
 	}
-	return css.SomeDimen(w + decW.Unwrap())
-	// if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
-	// 	!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() {
-	// 	return css.Dimen()
-	// }
-	// w += box.Padding[Left].Unwrap()
-	// w += box.Padding[Right].Unwrap()
-	// w += box.BorderWidth[Left].Unwrap()
-	// w += box.BorderWidth[Right].Unwrap()
-	// return css.SomeDimen(w)
+	return css.JustDimen(w + fixed(decW))
 }
 
+// FixPercentages resolves %-relative padding, border widths and margins against
+// the width of the enclosing box. It returns true if padding and border widths
+// are fixed afterwards.
 func (box *Box) FixPercentages(enclosingWidth dimen.DU) bool {
-	fixed := true
+	isFixed := true
 	for dir := Top; dir <= Left; dir++ {
 		if box.Padding[dir].IsPercent() {
-			p := box.Padding[dir].Unwrap()
-			box.Padding[dir] = css.SomeDimen(p * enclosingWidth / 100)
+			box.Padding[dir] = css.JustDimen(percentOf(box.Padding[dir], enclosingWidth))
 		}
 		if box.BorderWidth[dir].IsPercent() {
-			p := box.BorderWidth[dir].Unwrap()
-			box.BorderWidth[dir] = css.SomeDimen(p * enclosingWidth / 100)
+			box.BorderWidth[dir] = css.JustDimen(percentOf(box.BorderWidth[dir], enclosingWidth))
 		}
 		if box.Margins[dir].IsPercent() {
-			p := box.BorderWidth[dir].Unwrap()
-			box.BorderWidth[dir] = css.SomeDimen(p * enclosingWidth / 100)
+			box.Margins[dir] = css.JustDimen(percentOf(box.Margins[dir], enclosingWidth))
 		}
-		if !box.Padding[dir].IsAbsolute() || !box.BorderWidth[dir].IsAbsolute() ||
-			!box.BorderWidth[dir].IsAbsolute() {
-			fixed = false
+		if !box.Padding[dir].IsAbsolute() || !box.BorderWidth[dir].IsAbsolute() {
+			isFixed = false
 		}
 	}
-	return fixed
+	return isFixed
 }
 
 // ----------------------------------------------------------------------------------
 
-// InitEmptyBox initializes padding, border and margins to 0 and box.W to auto.
-func InitEmptyBox(box *Box) *Box {
-	if box == nil {
-		box = &Box{}
+// fixed returns the value of an absolute dimension d, and 0 otherwise.
+func fixed(d css.DimenT) dimen.DU {
+	var x dimen.DU
+	d.Match().Just(&x)
+	return x
+}
+
+// percentOf returns the value of a %-relative dimension d with respect to w,
+// and 0 if d is not %-relative.
+func percentOf(d css.DimenT, w dimen.DU) dimen.DU {
+	var p percent.Percent
+	if d.Match().Percentage(&p) == nil {
+		return 0
 	}
-	box.Padding[Top] = css.SomeDimen(0)
-	box.Padding[Right] = css.SomeDimen(0)
-	box.Padding[Bottom] = css.SomeDimen(0)
-	box.Padding[Left] = css.SomeDimen(0)
-	box.BorderWidth[Top] = css.SomeDimen(0)
-	box.BorderWidth[Right] = css.SomeDimen(0)
-	box.BorderWidth[Bottom] = css.SomeDimen(0)
-	box.BorderWidth[Left] = css.SomeDimen(0)
-	box.Margins[Top] = css.SomeDimen(0)
-	box.Margins[Right] = css.SomeDimen(0)
-	box.Margins[Bottom] = css.SomeDimen(0)
-	box.Margins[Left] = css.SomeDimen(0)
-	//
-	box.W = css.DimenOption("auto")
-	return box
+	return w.Scale(int64(p), 100)
 }
 
 func innerDecorationWidth(box *Box) css.DimenT {
 	if !box.Padding[Left].IsAbsolute() || !box.Padding[Right].IsAbsolute() ||
 		!box.BorderWidth[Left].IsAbsolute() || !box.BorderWidth[Right].IsAbsolute() {
-		return css.Dimen()
+		return css.DimenT{}
 	}
 	w := dimen.Zero
-	w += box.Padding[Left].Unwrap()
-	w += box.Padding[Right].Unwrap()
-	w += box.BorderWidth[Left].Unwrap()
-	w += box.BorderWidth[Right].Unwrap()
-	return css.SomeDimen(w)
+	w += fixed(box.Padding[Left])
+	w += fixed(box.Padding[Right])
+	w += fixed(box.BorderWidth[Left])
+	w += fixed(box.BorderWidth[Right])
+	return css.JustDimen(w)
 }
 
 func innerDecorationHeight(box *Box) css.DimenT {
 	if !box.Padding[Top].IsAbsolute() || !box.Padding[Bottom].IsAbsolute() ||
 		!box.BorderWidth[Top].IsAbsolute() || !box.BorderWidth[Bottom].IsAbsolute() {
-		return css.Dimen()
+		return css.DimenT{}
 	}
 	h := dimen.Zero
-	h += box.Padding[Top].Unwrap()
-	h += box.Padding[Bottom].Unwrap()
-	h += box.BorderWidth[Top].Unwrap()
-	h += box.BorderWidth[Bottom].Unwrap()
-	return css.SomeDimen(h)
+	h += fixed(box.Padding[Top])
+	h += fixed(box.Padding[Bottom])
+	h += fixed(box.BorderWidth[Top])
+	h += fixed(box.BorderWidth[Bottom])
+	return css.JustDimen(h)
 }
 
 // CollapseMargins returns the greater margin between bottom margin of box1 and
 // top margin of box2, and the smaller one as the second return value.
 //
 // If any of the boxes' margins are unset, return values may be unset, too.
-//
 func CollapseMargins(box1, box2 *Box) (css.DimenT, css.DimenT) {
 	if box1 == nil {
 		if box2 == nil {
-			return css.SomeDimen(0), css.SomeDimen(0)
+			return css.JustDimen(0), css.JustDimen(0)
 		}
-		return box2.Margins[Top], css.SomeDimen(0)
+		return box2.Margins[Top], css.JustDimen(0)
 	} else if box2 == nil {
-		return box1.Margins[Bottom], css.SomeDimen(0)
+		return box1.Margins[Bottom], css.JustDimen(0)
 	}
-	return css.MaxDimen(box1.Margins[Bottom], box2.Margins[Top]),
-		css.MinDimen(box1.Margins[Bottom], box2.Margins[Top])
-}
-
-// FixPaddingAndBorderWidth fixes padding and border width values of %-dimension
-// if the content-width of box is fixed.
-//
-// Will return true if all 4 paddings have fixed dimensions.
-//
-// The padding size is relative to the width of that element’s content area
-// (i.e. the width inside, and not including, the padding, border and margin of
-// the element).
-// So, if your <h1> is 500px wide, 10% padding = 0.1 × 500 pixels = 50 pixels.
-// Note that top and bottom padding will also be 10% of the _width_ of the element,
-// not 10% of the height of the element.
-func (box *Box) fixPaddingAndBorderWidth(w dimen.DU) bool {
-	fixed := true
-	for dir := Top; dir <= Left; dir++ {
-		if box.Padding[dir].UnitString() == "%" {
-			p := box.Padding[dir].Unwrap()
-			box.Padding[dir] = css.SomeDimen(p * w / 100)
-		}
-		if box.BorderWidth[dir].UnitString() == "%" {
-			p := box.BorderWidth[dir].Unwrap()
-			box.BorderWidth[dir] = css.SomeDimen(p * w / 100)
-		}
-		if !box.Padding[dir].IsAbsolute() || !box.BorderWidth[dir].IsAbsolute() {
-			fixed = false
-		}
+	var m1, m2 dimen.DU
+	switch {
+	case box1.Margins[Bottom].Match().Just(&m1) == nil:
+		return box2.Margins[Top], box2.Margins[Top]
+	case box2.Margins[Top].Match().Just(&m2) == nil:
+		return box1.Margins[Bottom], box1.Margins[Bottom]
 	}
-	return fixed
+	return css.JustDimen(dimen.Max(m1, m2)), css.JustDimen(dimen.Min(m1, m2))
 }
 
-func setWFromEnclosing(box *Box, enclw dimen.DU) {
-	if !box.W.IsPercent() {
-		return
-	}
-	box.W = css.SomeDimen(box.W.Unwrap() * enclw)
-}
+// fixPaddingAndBorderWidthFromBorderBoxWidth resolves %-relative padding and
+// border widths for a box with border box width w. It returns the width of the
+// box, i.e. the content width for box-sizing `content-box`.
 func fixPaddingAndBorderWidthFromBorderBoxWidth(box *Box, w dimen.DU) (css.DimenT, bool) {
 	tracer().Debugf("fix padding from bbox")
-	var hundredPcntW int64
-	var W css.DimenT
+	hundredPcntW, W := w, css.JustDimen(w)
 	if !box.BorderBoxSizing {
 		pcnt, total := int64(100), w
 		for dir := Right; dir <= Left; dir += 2 { // horizontal
-			if box.Padding[dir].IsAbsolute() {
-				total -= box.Padding[dir].Unwrap()
-			} else if box.Padding[dir].IsPercent() {
-				pcnt += int64(box.Padding[dir].Unwrap())
-			} else {
-				return css.Dimen(), false
-			}
-			if box.BorderWidth[dir].IsAbsolute() {
-				total -= box.BorderWidth[dir].Unwrap()
-			} else if box.BorderWidth[dir].IsPercent() {
-				pcnt += int64(box.BorderWidth[dir].Unwrap())
-			} else {
-				return css.Dimen(), false
+			for _, d := range []css.DimenT{box.Padding[dir], box.BorderWidth[dir]} {
+				var x dimen.DU
+				var p percent.Percent
+				switch {
+				case d.Match().Just(&x) != nil:
+					total -= x
+				case d.Match().Percentage(&p) != nil:
+					pcnt += int64(p)
+				default:
+					return css.DimenT{}, false
+				}
 			}
 		}
-		hundredPcntW = int64(total) * 100 / pcnt
+		hundredPcntW = total.Scale(100, pcnt)
 		tracer().Debugf("100%% = %v", hundredPcntW)
-		W = css.SomeDimen(dimen.DU(hundredPcntW))
-	} else {
-		hundredPcntW = int64(w)
-		W = css.SomeDimen(w)
+		W = css.JustDimen(hundredPcntW)
 	}
 	setPcntPaddingAndBorder(box, hundredPcntW)
 	return W, true
 }
 
+// fixPaddingAndBorderWidthFromContentWidth resolves %-relative padding and
+// border widths for a box with content width w. It returns the width of the
+// box, i.e. the border box width for box-sizing `border-box`.
 func fixPaddingAndBorderWidthFromContentWidth(box *Box, w dimen.DU) (css.DimenT, bool) {
-	var hundredPcntW int64
-	var W css.DimenT
+	hundredPcntW, W := w, css.JustDimen(w)
 	if box.BorderBoxSizing {
 		pcnt, total := int64(100), w
 		for dir := Right; dir <= Left; dir += 2 { // horizontal
-			if box.Padding[dir].IsAbsolute() {
-				total += box.Padding[dir].Unwrap()
-			} else if box.Padding[dir].IsPercent() {
-				pcnt -= int64(box.Padding[dir].Unwrap())
-			} else {
-				return css.Dimen(), false
-			}
-			if box.BorderWidth[dir].IsAbsolute() {
-				total += box.BorderWidth[dir].Unwrap()
-			} else if box.BorderWidth[dir].IsPercent() {
-				pcnt -= int64(box.BorderWidth[dir].Unwrap())
-			} else {
-				return css.Dimen(), false
+			for _, d := range []css.DimenT{box.Padding[dir], box.BorderWidth[dir]} {
+				var x dimen.DU
+				var p percent.Percent
+				switch {
+				case d.Match().Just(&x) != nil:
+					total += x
+				case d.Match().Percentage(&p) != nil:
+					pcnt -= int64(p)
+				default:
+					return css.DimenT{}, false
+				}
 			}
 		}
-		hundredPcntW = int64(total) * 100 / pcnt
-		W = css.SomeDimen(dimen.DU(hundredPcntW))
-	} else {
-		hundredPcntW = int64(w)
-		W = css.SomeDimen(w)
+		if pcnt <= 0 {
+			return css.DimenT{}, false
+		}
+		hundredPcntW = total.Scale(100, pcnt)
+		W = css.JustDimen(hundredPcntW)
 	}
 	setPcntPaddingAndBorder(box, hundredPcntW)
 	return W, true
 }
 
-func setPcntPaddingAndBorder(box *Box, hundredPcntW int64) {
+func setPcntPaddingAndBorder(box *Box, hundredPcntW dimen.DU) {
 	for dir := Top; dir <= Left; dir++ {
 		if box.Padding[dir].IsPercent() {
-			p := box.Padding[dir].Unwrap()
-			box.Padding[dir] = css.SomeDimen(dimen.DU(int64(p) * hundredPcntW / 100))
+			box.Padding[dir] = css.JustDimen(percentOf(box.Padding[dir], hundredPcntW))
 		}
 		if box.BorderWidth[dir].IsPercent() {
-			p := box.BorderWidth[dir].Unwrap()
-			box.BorderWidth[dir] = css.SomeDimen(dimen.DU(int64(p) * hundredPcntW / 100))
+			box.BorderWidth[dir] = css.JustDimen(percentOf(box.BorderWidth[dir], hundredPcntW))
 		}
 	}
 }
 
-func (box *Box) fixPaddingAndBorderWidthFromBorderBox(w dimen.DU) css.DimenT {
-	// if !box.BorderBoxSizing {
-	// 	panic("content box sizing set, cannot fix border box")
-	// }
-	pcnt, total := 100, int(w)
-	addPcnt := func(p interface{}) interface{} {
-		pcnt += p.(int)
-		return 0
-	}
-	subtrTotal := func(t interface{}) interface{} {
-		total -= t.(int)
-		return 0
-	}
-	x := box.Padding[Left]
-	if _, err := x.Match(option.Of{
-		css.FixedValue: subtrTotal, // total = total - x
-		"%":            addPcnt,    // pcnt = pcnt + x
-	}); err != nil { // for other than fixed and %
-		return css.Dimen()
-	}
-	x = box.Padding[Right]
-	if _, err := x.Match(option.Of{
-		css.FixedValue: subtrTotal, // total = total - x
-		"%":            addPcnt,    // pcnt = pcnt + x
-	}); err != nil { // for other than fixed and %
-		return css.Dimen()
-	}
-	x = box.BorderWidth[Left]
-	if _, err := x.Match(option.Of{
-		css.FixedValue: subtrTotal, // total = total - x
-		"%":            addPcnt,    // pcnt = pcnt + x
-	}); err != nil { // for other than fixed and %
-		return css.Dimen()
-	}
-	x = box.BorderWidth[Right]
-	if _, err := x.Match(option.Of{
-		css.FixedValue: subtrTotal, // total = total - x
-		"%":            addPcnt,    // pcnt = pcnt + x
-	}); err != nil { // for other than fixed and %
-		return css.Dimen()
-	}
-	unit := total / pcnt
-	return css.SomeDimen(dimen.DU(unit * 100))
-	//return box.FixPaddingAndBorderWidth(dimen.Dimen(unit * 100))
-}
-
 // --- API for constraint width solving --------------------------------------
 
 // ErrUnfixedScaledUnit is returned if a dimension calculation encounters a
 // dimension-specification which is dependent on view-size or font-size.
-//
 var ErrUnfixedScaledUnit error = errors.New("font/view dependent dimension is unfixed")
 
 // ErrContentScaling is returned if a dimension calculation encounters a
@@ -587,12 +460,11 @@ var ErrUnderspecified error = errors.New("box width dimensions are underspecifie
 //
 // This will distribute space according to the equation (ref. CSS spec):
 //
-//     margin-left + border-width-left + padding-left + width +
-//       padding-right + border-width-right + margin-right = width of containing block
+//	margin-left + border-width-left + padding-left + width +
+//	  padding-right + border-width-right + margin-right = width of containing block
 //
 // Returns a flag denoting whether there was enough information to specify each width
 // dimension.
-//
 func FixDimensionsFromEnclosingWidth(box *Box, enclosingWidth dimen.DU) (bool, error) {
 	tracer().Debugf("fix contraint dimensions, enclosing = %v", enclosingWidth)
 	fixIllegalDimensionSpecifications(box)
@@ -600,15 +472,14 @@ func FixDimensionsFromEnclosingWidth(box *Box, enclosingWidth dimen.DU) (bool, e
 	if err := checkForUnresolvedDependentDimensions(box); err != nil {
 		return false, err
 	}
-	calc, err := box.W.Match(option.Of{
-		option.None: calcWidthAsRest, // defaults to `auto`
-		css.Auto:    calcWidthAsRest,
-		option.Some: takeWidth,
+	solve := css.DimenPattern[calcFn](box.W).OneOf(css.DimenPatterns[calcFn]{
+		Unset:   calcWidthAsRest, // defaults to `auto`
+		Auto:    calcWidthAsRest,
+		Inherit: takeWidth,
+		Initial: takeWidth,
+		Just:    takeWidth,
+		Default: takeWidth,
 	})
-	if err != nil {
-		return false, err
-	}
-	solve := asCalcFn(calc)
 	w, err := solve(box, enclosingWidth)
 	if err != nil {
 		return false, err
@@ -617,23 +488,14 @@ func FixDimensionsFromEnclosingWidth(box *Box, enclosingWidth dimen.DU) (bool, e
 	}
 	box.W = w
 	tracer().Debugf("dimensions calculated from enclosing width: %s", box.DebugString())
-	// if !box.Padding[dir].IsAbsolute() || !box.BorderWidth[dir].IsAbsolute() ||
-	// 	!box.BorderWidth[dir].IsAbsolute() {
-	// 	fixed = false
-	// }
 	return true, nil
 }
 
 type calcFn func(box *Box, enclosing dimen.DU) (css.DimenT, error)
 
-func asCalcFn(f interface{}) calcFn {
-	return f.(func(box *Box, enclosing dimen.DU) (css.DimenT, error))
-}
-
 func takeWidth(box *Box, enclosing dimen.DU) (css.DimenT, error) {
 	tracer().Debugf("calculating width: simply take is as is = %v", box.W)
-	fixed := distributeHorizontalMarginSpace(box, enclosing)
-	if !fixed {
+	if !distributeHorizontalMarginSpace(box, enclosing) {
 		return box.W, ErrUnderspecified
 	}
 	return box.W, nil
@@ -642,25 +504,9 @@ func takeWidth(box *Box, enclosing dimen.DU) (css.DimenT, error) {
 // Spec: If 'width' is set to 'auto', any other 'auto' values become '0'
 // and 'width' follows from the resulting equality.
 func calcWidthAsRest(box *Box, enclosing dimen.DU) (css.DimenT, error) {
-	//T().Debugf("calculate width as rest for box %s", box.DebugString())
-	left, err := box.Margins[Left].MatchToDimen(option.Of{
-		option.None: dimen.Zero,
-		css.Auto:    dimen.Zero,
-		option.Some: box.Margins[Left].Unwrap(),
-	})
-	if err != nil {
-		return css.Dimen(), err
-	}
-	box.Margins[Left] = css.SomeDimen(left)
-	right, err := box.Margins[Right].MatchToDimen(option.Of{
-		option.None: dimen.Zero,
-		css.Auto:    dimen.Zero,
-		option.Some: box.Margins[Left].Unwrap(),
-	})
-	if err != nil {
-		return css.Dimen(), err
-	}
-	box.Margins[Right] = css.SomeDimen(right)
+	left, right := fixed(box.Margins[Left]), fixed(box.Margins[Right]) // unset and `auto` become 0
+	box.Margins[Left] = css.JustDimen(left)
+	box.Margins[Right] = css.JustDimen(right)
 	width := enclosing - left - right
 	tracer().Debugf("w = %v", width)
 	if !box.BorderBoxSizing {
@@ -668,36 +514,33 @@ func calcWidthAsRest(box *Box, enclosing dimen.DU) (css.DimenT, error) {
 		if d = innerDecorationWidth(box); d.IsNone() {
 			return d, ErrUnderspecified // this cannot happen
 		}
-		width -= d.Unwrap()
+		width -= fixed(d)
 	}
-	r := css.SomeDimen(width)
+	r := css.JustDimen(width)
 	tracer().Debugf("calculate width as rest to w = %v", r)
 	return r, nil
-	//return css.SomeDimen(width), nil
 }
 
 // distributeHorizontalMarginSpace distributes space into left and right margins
-// after the border-box has been fixed.
+// after the border-box has been fixed. If neither margin is `auto`, the right
+// margin takes up the remaining space.
 func distributeHorizontalMarginSpace(box *Box, enclosing dimen.DU) bool {
 	if !box.HasFixedBorderBoxWidth(false) {
 		return false
 	}
-	w := box.BorderBoxWidth().Unwrap()
-	remaining := enclosing - w
+	remaining := enclosing - fixed(box.BorderBoxWidth())
 	left, right := box.Margins[Left], box.Margins[Right]
-	l, err := left.Match(option.Of{
-		css.Auto: option.Safe(right.Match(option.Of{
-			css.Auto:    remaining / 2,
-			option.Some: remaining - right.Unwrap(),
-		})),
-	})
-	if err != nil {
-		tracer().Errorf("distribute h-margins: %s", err.Error())
-		return false
-	}
-	r := remaining - l.(dimen.DU)
-	box.Margins[Left] = css.SomeDimen(l.(dimen.DU))
-	box.Margins[Right] = css.SomeDimen(r)
+	var l dimen.DU
+	switch {
+	case left.Match().IsKind(css.Auto()) == nil:
+		l = fixed(left)
+	case right.Match().IsKind(css.Auto()) != nil:
+		l = remaining / 2
+	default:
+		l = remaining - fixed(right)
+	}
+	box.Margins[Left] = css.JustDimen(l)
+	box.Margins[Right] = css.JustDimen(remaining - l)
 	return true
 }
 
@@ -705,64 +548,47 @@ func distributeHorizontalMarginSpace(box *Box, enclosing dimen.DU) bool {
 // which are dependent on view-size, font-size or content.
 func checkForUnresolvedDependentDimensions(box *Box) error {
 	for dir := Top; dir <= Left; dir++ {
-		if _, err := box.Padding[dir].Match(option.Of{
-			option.None:       nil, // defaults to `auto`
-			css.FontScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ViewScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ContentScaled: option.Fail(ErrContentScaling),
-			option.Some:       nil,
-		}); err != nil {
-			return err
-		}
-		if _, err := box.BorderWidth[dir].Match(option.Of{
-			option.None:       nil, // defaults to `auto`
-			css.FontScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ViewScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ContentScaled: option.Fail(ErrContentScaling),
-			option.Some:       nil,
-		}); err != nil {
-			return err
-		}
-		if _, err := box.Margins[dir].Match(option.Of{
-			option.None:       nil, // defaults to `auto`
-			css.FontScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ViewScaled:    option.Fail(ErrUnfixedScaledUnit),
-			css.ContentScaled: option.Fail(ErrContentScaling),
-			option.Some:       nil,
-		}); err != nil {
-			return err
+		for _, d := range []css.DimenT{box.Padding[dir], box.BorderWidth[dir], box.Margins[dir]} {
+			if err := dependencyOf(d); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-// Property   Default    Valid values           Purpose
-// ---------+----------+----------------------+-----------------------------------
-// padding    Varies     length or percentage 	Controls the size of the padding.
-//                                              Negative values are not allowed.
-//                                              Percentages refer to width of the
-//                                              containing block.
+// dependencyOf returns ErrUnfixedScaledUnit for font- or view-relative
+// dimensions, ErrContentScaling for content-dependent dimensions, and nil
+// otherwise.
+func dependencyOf(d css.DimenT) error {
+	switch {
+	case d.IsPercent():
+		return nil
+	case d.IsRelative():
+		return ErrUnfixedScaledUnit
+	}
+	return css.DimenPattern[error](d).OneOf(css.DimenPatterns[error]{
+		Default: ErrContentScaling, // neither fixed nor relative
+	})
+}
+
+// fixIllegalDimensionSpecifications resets padding and border widths which
+// are unset, negative or `auto` to 0:
 //
-// Similar for border width.
+//	Property   Default    Valid values           Purpose
+//	---------+----------+----------------------+-----------------------------------
+//	padding    Varies     length or percentage   Controls the size of the padding.
+//	                                             Negative values are not allowed.
+//	                                             Percentages refer to width of the
+//	                                             containing block.
 //
+// Similar for border width.
 func fixIllegalDimensionSpecifications(box *Box) {
 	for dir := Top; dir <= Left; dir++ {
-		padd := box.Padding[dir]
-		if padd.Equals(css.Auto) || (padd.IsAbsolute() && padd.Unwrap() < 0) {
-			padd = css.SomeDimen(0)
-		}
-		if !padd.IsAbsolute() {
-			padd = css.SomeDimen(0)
-		}
-		bord := box.BorderWidth[dir]
-		if bord.Equals(css.Auto) || (bord.IsAbsolute() && bord.Unwrap() < 0) {
-			bord = css.SomeDimen(0)
-		}
-		if !bord.IsAbsolute() {
-			bord = css.SomeDimen(0)
+		for _, d := range []*css.DimenT{&box.Padding[dir], &box.BorderWidth[dir]} {
+			if d.IsNone() || fixed(*d) < 0 || d.Match().IsKind(css.Auto()) != nil {
+				*d = css.JustDimen(0)
+			}
 		}
 	}
 }
-
-
-*/
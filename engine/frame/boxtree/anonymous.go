@@ -0,0 +1,254 @@
+package boxtree
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+// Reconciling block-level and inline-level boxes, following CSS 2.1 §9.2.
+//
+// From the spec: "if a block container box has a block-level box inside it,
+// then we force it to have only block-level boxes inside it." Runs of
+// inline-level boxes are wrapped into anonymous block boxes. An inline box
+// containing a block-level box is broken around it, the pieces of the inline
+// box ending up in anonymous block boxes of the enclosing block container.
+// White space which would be collapsed away does not generate anonymous boxes.
+
+// wrapInlineRuns wraps runs of inline-level children of a block container into
+// anonymous block boxes, if the container has in-flow block-level children as
+// well. Children of flex containers are flex items; there only runs of text are
+// wrapped.
+func wrapInlineRuns(parent *frame.Container, children []*frame.Container) []*frame.Container {
+	var inRun func(c *frame.Container) bool
+	switch {
+	case isInlineBox(parent):
+		return children // will be broken around block-level children by its parent
+	case parent.Display.Contains(css.FlexMode):
+		inRun = func(c *frame.Container) bool { return IsText(c.RenderNode()) }
+	case containsBlockLevel(children):
+		inRun = func(c *frame.Container) bool { return !isBlockLevel(c) || isOutOfFlow(c) }
+	default:
+		return children // block container establishes an inline formatting context
+	}
+	var wrapped, run []*frame.Container
+	flush := func() {
+		if len(run) > 0 && !isWhitespaceOnly(run) {
+			anon := NewAnonymousBox(css.BlockMode | css.InnerInlineMode)
+			for _, c := range run {
+				anon.AddChild(c.TreeNode())
+			}
+			tracer().Debugf("[%s] wraps %d inline-level box(es) in anon box", boxname(parent), len(run))
+			wrapped = append(wrapped, &anon.Container)
+		}
+		run = nil
+	}
+	for _, c := range children {
+		switch {
+		case inRun(c) && (len(run) > 0 || !isOutOfFlow(c)):
+			run = append(run, c)
+		default:
+			flush()
+			wrapped = append(wrapped, c)
+		}
+	}
+	flush()
+	return wrapped
+}
+
+// breakAroundBlocks breaks an inline box around its in-flow block-level
+// children. It returns the sequence of boxes replacing c in its parent: boxes
+// for the pieces of c, interleaved with the block-level boxes.
+// For all other boxes it returns c.
+//
+// TODO all the pieces are attributed with the padding, borders and margins of
+// the inline box, whereas the start of the first piece and the end of the
+// last piece should carry them.
+func breakAroundBlocks(c *frame.Container) []*frame.Container {
+	pbox, ok := c.RenderNode().(*PrincipalBox)
+	if !ok || !isInlineBox(c) || !hasBlockLevelChild(c) {
+		return []*frame.Container{c}
+	}
+	var pieces []*frame.Container
+	var piece *PrincipalBox
+	for _, ch := range c.TreeNode().Children(true) {
+		ch.Isolate()
+		if sub := ch.Payload; isBlockLevel(sub) && !isOutOfFlow(sub) {
+			pieces = append(pieces, sub)
+			piece = nil
+			continue
+		}
+		if piece == nil {
+			piece = NewPrincipalBox(pbox.domNode, c.Display)
+			pieces = append(pieces, &piece.Container)
+		}
+		piece.TreeNode().AddChild(ch)
+	}
+	tracer().Debugf("inline box [%s] is broken into %d boxes", boxname(c), len(pieces))
+	return pieces
+}
+
+// resolveRunIns resolves the display mode of run-in boxes (CSS 2.1 §9.2.3):
+//
+// If a run-in box contains a block box, the run-in box becomes a block box.
+// If a sibling block box (that does not float and is not absolutely or fixed
+// positioned) follows the run-in box, the run-in box becomes the first inline
+// box of the block box. A run-in cannot run in to a block that already starts
+// with a run-in or that itself is a run-in. Otherwise, the run-in box becomes
+// a block box.
+func resolveRunIns(children []*frame.Container) []*frame.Container {
+	resolved := make([]*frame.Container, 0, len(children))
+	for i, c := range children {
+		if c.Display.Outer() != css.RunInMode {
+			resolved = append(resolved, c)
+			continue
+		}
+		if hasBlockLevelChild(c) {
+			c.Display = css.BlockMode | css.InnerBlockMode
+		} else if target := runInTarget(children[i+1:]); target != nil {
+			c.Display = css.InlineMode | css.InnerInlineMode
+			c.RenderNode().(*PrincipalBox).ranIn = true
+			runInto(c, target)
+			tracer().Debugf("run-in box [%s] runs into [%s]", boxname(c), boxname(target))
+			continue
+		} else {
+			c.Display = css.BlockMode | css.InnerInlineMode
+		}
+		resolved = append(resolved, c)
+	}
+	return resolved
+}
+
+// runInTarget finds the block box a run-in box may run into, given the
+// siblings following the run-in box.
+func runInTarget(siblings []*frame.Container) *frame.Container {
+	for _, c := range siblings {
+		if isWhitespaceOnly([]*frame.Container{c}) {
+			continue
+		}
+		if !IsPrincipal(c.RenderNode()) || !isBlockLevel(c) || isOutOfFlow(c) || startsWithRunIn(c) {
+			return nil
+		}
+		if c.Display.Inner().Overlaps(css.InnerBlockMode | css.InnerInlineMode | css.ListItemMode) {
+			return c
+		}
+		return nil
+	}
+	return nil
+}
+
+// runInto inserts a run-in box as the first inline box of a block box.
+func runInto(runin *frame.Container, block *frame.Container) {
+	children := block.TreeNode().Children(true)
+	switch {
+	case !hasBlockLevelChild(block):
+		block.TreeNode().InsertChildAt(0, runin.TreeNode())
+	case IsAnonymous(children[0].Payload.RenderNode()):
+		children[0].InsertChildAt(0, runin.TreeNode())
+	default:
+		anon := NewAnonymousBox(css.BlockMode | css.InnerInlineMode)
+		anon.AddChild(runin.TreeNode())
+		block.TreeNode().InsertChildAt(0, anon.TreeNode())
+	}
+}
+
+// startsWithRunIn is true if a run-in box has run into block box c.
+func startsWithRunIn(c *frame.Container) bool {
+	first, ok := c.TreeNode().Child(0)
+	if ok && IsAnonymous(first.Payload.RenderNode()) {
+		first, ok = first.Child(0)
+	}
+	if !ok {
+		return false
+	}
+	pbox := TreeNodeAsPrincipalBox(first)
+	return pbox != nil && pbox.ranIn
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+// isBlockLevel is true for block-level boxes, including list items and
+// anonymous block boxes.
+func isBlockLevel(c *frame.Container) bool {
+	return c.Display.Outer() == css.BlockMode
+}
+
+// isInlineBox is true for non-replaced inline boxes, i.e. elements with
+// "display: inline", which are not atomic inline-level boxes.
+func isInlineBox(c *frame.Container) bool {
+	return IsPrincipal(c.RenderNode()) && c.Display.Outer() == css.InlineMode &&
+		c.Display.Inner() == css.InnerInlineMode && !IsReplaced(c.RenderNode())
+}
+
+// isOutOfFlow is true for boxes which are floated or absolutely positioned.
+// They do not count as block-level boxes when reconciling levels of boxes.
+func isOutOfFlow(c *frame.Container) bool {
+	if !IsPrincipal(c.RenderNode()) {
+		return false
+	}
+	styles := c.DOMNode().ComputedStyles()
+	if css.Float(styles.GetPropertyValue("float")).IsFloating() {
+		return true
+	}
+	pos := css.Position(styles.GetPropertyValue("position"))
+	return pos.IsAbsolute() || pos.IsFixed()
+}
+
+func containsBlockLevel(boxes []*frame.Container) bool {
+	for _, c := range boxes {
+		if isBlockLevel(c) && !isOutOfFlow(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBlockLevelChild(c *frame.Container) bool {
+	return containsBlockLevel(childBoxes(c))
+}
+
+func childBoxes(c *frame.Container) []*frame.Container {
+	children := c.TreeNode().Children(true)
+	boxes := make([]*frame.Container, len(children))
+	for i, ch := range children {
+		boxes[i] = ch.Payload
+	}
+	return boxes
+}
+
+// isWhitespaceOnly is true if a run of boxes contains nothing but white space
+// which will be collapsed away. Inline boxes containing nothing but such white
+// space count as white space as well.
+func isWhitespaceOnly(run []*frame.Container) bool {
+	for _, c := range run {
+		switch b := c.RenderNode().(type) {
+		case *TextBox:
+			if strings.TrimSpace(b.domNode.NodeValue()) != "" || !collapsesWhitespace(b.domNode) {
+				return false
+			}
+		case *PrincipalBox:
+			if !isInlineBox(c) || !isWhitespaceOnly(childBoxes(c)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// collapsesWhitespace is true if white space of a text node will be collapsed,
+// according to property "white-space" of its parent element.
+func collapsesWhitespace(textnode *dom.W3CNode) bool {
+	parent, ok := textnode.ParentNode().(*dom.W3CNode)
+	if !ok || parent == nil {
+		return true
+	}
+	switch parent.ComputedStyles().GetPropertyValue("white-space") {
+	case "pre", "pre-wrap", "pre-line", "break-spaces":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package boxtree_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"golang.org/x/net/html"
+)
+
+func TestAnonymousBoxes(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame.box")
+	defer teardown()
+	//
+	for i, x := range []struct {
+		body, shape string
+	}{
+		{ // block container with inline and block content
+			`<div>Some text<p>para</p>more text</div>`,
+			`div[anon['Some text'] p['para'] anon['more text']]`,
+		},
+		{ // inline box containing a block box is broken around it
+			`<div><span>a<div>b</div>c</span></div>`,
+			`div[anon[span['a']] div['b'] anon[span['c']]]`,
+		},
+		{ // white space between blocks does not generate anonymous boxes
+			"<div>\n  <p>a</p>\n  <p>b</p>\n</div>",
+			`div[p['a'] p['b']]`,
+		},
+		{ // display: none generates no box
+			`<div><span style="display: none">x</span><p>y</p></div>`,
+			`div[p['y']]`,
+		},
+		{ // an inline-block is an atomic inline-level box
+			`<div>x<span style="display: inline-block"><p>y</p></span></div>`,
+			`div['x' span[p['y']]]`,
+		},
+		{ // floats do not count as block-level boxes
+			`<div>text<p style="float: left">f</p></div>`,
+			`div['text' p['f']]`,
+		},
+	} {
+		div := boxFor(t, x.body, "div")
+		if shape := shapeOf(div); shape != x.shape {
+			t.Errorf("test #%d: box tree is %s, expected %s", i, shape, x.shape)
+		}
+	}
+}
+
+func TestRunIn(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame.box")
+	defer teardown()
+	//
+	for i, x := range []struct {
+		body, shape string
+		display     css.DisplayMode
+	}{
+		{ // run-in followed by a block runs into the block
+			`<div><b style="display: run-in">Head</b> <p>text</p></div>`,
+			`div[p[b['Head'] 'text']]`,
+			css.InlineMode | css.InnerInlineMode,
+		},
+		{ // run-in followed by a block with block content
+			`<div><b style="display: run-in">Head</b><div><p>text</p></div></div>`,
+			`div[div[anon[b['Head']] p['text']]]`,
+			css.InlineMode | css.InnerInlineMode,
+		},
+		{ // run-in followed by text becomes a block box
+			`<div><b style="display: run-in">Head</b>tail</div>`,
+			`div[b['Head'] anon['tail']]`,
+			css.BlockMode | css.InnerInlineMode,
+		},
+		{ // run-in cannot run into a run-in
+			`<div><b style="display: run-in">A</b><i style="display: run-in">B</i><p>c</p></div>`,
+			`div[b['A'] p[i['B'] 'c']]`,
+			css.BlockMode | css.InnerInlineMode,
+		},
+	} {
+		div := boxFor(t, x.body, "div")
+		if shape := shapeOf(div); shape != x.shape {
+			t.Errorf("test #%d: box tree is %s, expected %s", i, shape, x.shape)
+		}
+		if b := findBox(div, "b"); b == nil || b.Display != x.display {
+			t.Errorf("test #%d: expected run-in to be resolved to %s", i, x.display.FullString())
+		}
+	}
+}
+
+func TestListItemMarkers(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame.box")
+	defer teardown()
+	//
+	ul := boxFor(t, `<ul><li>a</li><li>b</li></ul><ol style="list-style-type: upper-roman">
+	<li>x</li><li>y</li><li style="list-style-type: lower-alpha">z</li></ol>`, "body")
+	var markers []string
+	var collect func(c *frame.Container)
+	collect = func(c *frame.Container) {
+		if pbox, ok := c.RenderNode().(*boxtree.PrincipalBox); ok && pbox.DOMNode().NodeName() == "li" {
+			markers = append(markers, pbox.Marker)
+		}
+		for _, ch := range c.TreeNode().Children(true) {
+			collect(ch.Payload)
+		}
+	}
+	collect(ul)
+	expected := []string{"•", "•", "I.", "II.", "c."}
+	if strings.Join(markers, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected list markers %v, have %v", expected, markers)
+	}
+}
+
+func TestListItemNumbering(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame.box")
+	defer teardown()
+	//
+	for i, x := range []struct {
+		list    string
+		markers string
+	}{
+		{`<ol start="3"><li>a</li><li>b</li></ol>`, "3. 4."},
+		{`<ol reversed><li>a</li><li>b</li><li>c</li></ol>`, "3. 2. 1."},
+		{`<ol><li>a</li><li value="10">b</li><li>c</li></ol>`, "1. 10. 11."},
+		{`<ol style="counter-reset: list-item 4"><li style="counter-increment: list-item 2">a</li>
+		<li>b</li></ol>`, "6. 7."},
+	} {
+		ol := boxFor(t, `<div style="list-style-type: decimal">`+x.list+`</div>`, "ol")
+		var markers []string
+		for _, ch := range ol.TreeNode().Children(true) {
+			if pbox, ok := ch.Payload.RenderNode().(*boxtree.PrincipalBox); ok {
+				markers = append(markers, pbox.Marker)
+			}
+		}
+		if m := strings.Join(markers, " "); m != x.markers {
+			t.Errorf("test #%d: expected list markers %q, have %q", i, x.markers, m)
+		}
+	}
+}
+
+func TestListMarkerPosition(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame.box")
+	defer teardown()
+	//
+	body := boxFor(t, `<ul style="list-style-position: inside"><li>a</li></ul><ul><li>b</li></ul>`, "body")
+	var inside []bool
+	var collect func(c *frame.Container)
+	collect = func(c *frame.Container) {
+		if pbox, ok := c.RenderNode().(*boxtree.PrincipalBox); ok && pbox.DOMNode().NodeName() == "li" {
+			inside = append(inside, pbox.MarkerInside)
+		}
+		for _, ch := range c.TreeNode().Children(true) {
+			collect(ch.Payload)
+		}
+	}
+	collect(body)
+	if len(inside) != 2 || !inside[0] || inside[1] {
+		t.Errorf("expected first marker to be inside and second to be outside, have %v", inside)
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+// boxFor builds a box tree for an HTML body and returns the first box for an
+// element with a given name.
+func boxFor(t *testing.T, body string, name string) *frame.Container {
+	h, err := html.Parse(strings.NewReader("<html><body>" + body + "</body></html>"))
+	if err != nil {
+		t.Fatalf("cannot create test document: %v", err)
+	}
+	boxes, err := boxtree.BuildBoxTree(dom.FromHTMLParseTree(h, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := findBox(boxes, name)
+	if c == nil {
+		t.Fatalf("no box for <%s> found", name)
+	}
+	return c
+}
+
+func findBox(c *frame.Container, name string) *frame.Container {
+	if boxtree.IsPrincipal(c.RenderNode()) && c.DOMNode().NodeName() == name {
+		return c
+	}
+	for _, ch := range c.TreeNode().Children(true) {
+		if found := findBox(ch.Payload, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// shapeOf returns a compact string representation of a box tree. Text boxes are
+// represented by their (trimmed) text, anonymous boxes by 'anon'.
+func shapeOf(c *frame.Container) string {
+	var b strings.Builder
+	switch c.RenderNode().(type) {
+	case *boxtree.TextBox:
+		b.WriteString("'" + strings.TrimSpace(c.DOMNode().NodeValue()) + "'")
+		return b.String()
+	case *boxtree.AnonymousBox:
+		b.WriteString("anon")
+	default:
+		b.WriteString(c.DOMNode().NodeName())
+	}
+	children := c.TreeNode().Children(true)
+	if len(children) > 0 {
+		shapes := make([]string, len(children))
+		for i, ch := range children {
+			shapes[i] = shapeOf(ch.Payload)
+		}
+		b.WriteString("[" + strings.Join(shapes, " ") + "]")
+	}
+	return b.String()
+}
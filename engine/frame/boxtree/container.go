@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/npillmayer/tyse/core/option"
+	"github.com/npillmayer/tyse/core/locate/resources"
 	"github.com/npillmayer/tyse/engine/dom"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 	"github.com/npillmayer/tyse/engine/frame"
@@ -29,9 +29,13 @@ import (
 // boxes, which will be generated for reconciling context/level-discrepancies.
 //
 type PrincipalBox struct {
-	frame.Container                  // a principal box is also a layout container
-	Box             *frame.StyledBox // styled box for a DOM node
-	domNode         *dom.W3CNode     // the DOM node this PrincipalBox refers to
+	frame.Container                          // a principal box is also a layout container
+	Box             *frame.StyledBox         // styled box for a DOM node
+	Marker          string                   // marker text for list items, empty for other boxes
+	MarkerInside    bool                     // marker starts the first line, instead of hanging outside
+	Picture         resources.PicturePromise // content of a replaced element, nil for other boxes
+	domNode         *dom.W3CNode             // the DOM node this PrincipalBox refers to
+	ranIn           bool                     // box has been a run-in and runs into its following block
 }
 
 // NewPrincipalBox creates either a block-level container or an inline-level container
@@ -51,11 +55,11 @@ func NewPrincipalBox(domnode *dom.W3CNode, mode css.DisplayMode) *PrincipalBox {
 //
 //    box := layout.PrincipalBoxFromNode(n)
 //
-func TreeNodeAsPrincipalBox(n *tree.Node) *PrincipalBox {
-	if n == nil {
+func TreeNodeAsPrincipalBox(n *tree.Node[*frame.Container]) *PrincipalBox {
+	if n == nil || n.Payload == nil {
 		return nil
 	}
-	pbox, ok := n.Payload.(*PrincipalBox)
+	pbox, ok := n.Payload.RenderNode().(*PrincipalBox)
 	if ok {
 		return pbox
 	}
@@ -305,35 +309,39 @@ func (pbox *PrincipalBox) PresetContained() bool {
 	tracer().Debugf("[%v] pre-sets %d sub container(s)", pbox.domNode.NodeName(), len(children))
 	hasAdded := false
 	for _, ch := range children {
-		switch b := ch.Payload.(type) {
+		switch b := ch.Payload.RenderNode().(type) {
 		case *PrincipalBox:
-			pos := css.PositionOption(b.DOMNode())
-			doAdd, err := pos.Match(option.Of{
-				option.None:            true,
-				css.PositionFloatLeft:  false,
-				css.PositionFloatRight: false,
-				css.PositionAbsolute:   false,
-				css.PositionFixed:      false,
-				option.Some:            true,
-			})
-			if err != nil {
-				tracer().Errorf("preset children container: %v", err)
-				return false
-			}
-			if doAdd.(bool) {
+			if isInFlowOrFloat(b.DOMNode(), pbox.domNode) {
 				b.CSSBox().Max.W = pbox.CSSBox().W
 				pbox.Context.AddContained(&b.Container)
 				hasAdded = true
 			}
 		case *TextBox:
 			pbox.Context.AddContained(&b.Container)
-		case AnonymousBox:
-			tracer().Errorf("unexpected anonymous box child")
+		case *AnonymousBox:
+			b.CSSBox().Max.W = pbox.CSSBox().W
+			pbox.Context.AddContained(&b.Container)
+			hasAdded = true
 		}
 	}
 	return hasAdded
 }
 
+// isInFlowOrFloat returns true if a box for node n takes part in the formatting
+// context of its parent node, i.e., it is not absolutely positioned and it has not
+// been routed into a named flow different from parent's flow. Floats are
+// contained by the parent context, which will place them.
+func isInFlowOrFloat(n, parent *dom.W3CNode) bool {
+	if n == nil {
+		return true
+	}
+	if parent != nil && flowOf(n) != flowOf(parent) {
+		return false
+	}
+	pos := css.Position(n.ComputedStyles().GetPropertyValue("position"))
+	return !pos.IsAbsolute() && !pos.IsFixed()
+}
+
 // --- Anonymous Boxes -----------------------------------------------------------------
 
 // AnonymousBox is a type for CSS anonymous boxes.
@@ -367,11 +375,6 @@ func (anon *AnonymousBox) DOMNode() *dom.W3CNode {
 	return parent.DOMNode()
 }
 
-// TreeNode returns the underlying tree node for a box.
-func (anon *AnonymousBox) TreeNode() *tree.Node {
-	return &anon.Node
-}
-
 // CSSBox returns the underlying box of a render tree element.
 func (anon *AnonymousBox) CSSBox() *frame.Box {
 	return anon.Box
@@ -439,29 +442,16 @@ func (anon *AnonymousBox) PresetContained() bool {
 	tracer().Debugf("[anon] pre-sets %d sub container(s)", len(children))
 	hasAdded := false
 	for _, ch := range children {
-		switch b := ch.Payload.(type) {
+		switch b := ch.Payload.RenderNode().(type) {
 		case *PrincipalBox:
-			pos := css.PositionOption(b.DOMNode())
-			doAdd, err := pos.Match(option.Of{
-				option.None:            true,
-				css.PositionFloatLeft:  false,
-				css.PositionFloatRight: false,
-				css.PositionAbsolute:   false,
-				css.PositionFixed:      false,
-				option.Some:            true,
-			})
-			if err != nil {
-				tracer().Errorf("preset children container: %v", err)
-				return false
-			}
-			if doAdd.(bool) {
+			if isInFlowOrFloat(b.DOMNode(), nil) {
 				b.CSSBox().Max.W = anon.CSSBox().W
 				anon.Context.AddContained(&b.Container)
 				hasAdded = true
 			}
 		case *TextBox:
 			anon.Context.AddContained(&b.Container)
-		case AnonymousBox:
+		case *AnonymousBox:
 			tracer().Errorf("unexpected anonymous box child")
 		}
 	}
@@ -660,6 +650,13 @@ func IsPrincipal(c frame.RenderTreeNode) bool {
 	return ok
 }
 
+// IsReplaced returns true if this is the principal box of a replaced element,
+// e.g. of an image.
+func IsReplaced(c frame.RenderTreeNode) bool {
+	pbox, ok := c.(*PrincipalBox)
+	return ok && pbox.Picture != nil
+}
+
 // IsAnonymous returns true if this box is an anonymous box created by the layout algorithm.
 func IsAnonymous(c frame.RenderTreeNode) bool {
 	_, ok := c.(*AnonymousBox)
@@ -1,6 +1,12 @@
 /*
 Package boxtree produces a box-tree from a styled tree (DOM).
 
+Boxes are generated following the rules of CSS 2.1 §9.2: every element
+generates a principal box according to its "display" property (block, inline,
+inline-block, list-item, run-in), unless it has "display: none". Anonymous block
+boxes are created to separate block-level from inline-level content, and list
+items carry a marker text.
+
 ______________________________________________________________________
 
 License
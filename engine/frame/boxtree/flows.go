@@ -0,0 +1,53 @@
+package boxtree
+
+import (
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+// RouteFlows collects the containers of a box tree into named flows (CSS
+// Regions), according to CSS property "flow-into". The children of boxRoot belong
+// to the main flow, unless they are routed into a named flow. Descendants may be
+// routed into a named flow as well, which takes them out of the flow of their
+// parent.
+//
+// Routing has to be done before layout, as routed containers will not take part
+// in the formatting context of their parent (see PresetContained).
+func RouteFlows(boxRoot *frame.Container) *frame.FlowRouter {
+	router := frame.NewFlowRouter()
+	if boxRoot == nil {
+		return router
+	}
+	for _, ch := range boxRoot.TreeNode().Children(true) {
+		c := ch.Payload
+		name := flowOf(c.DOMNode())
+		router.Route(c, name)
+		routeDescendants(c, name, router)
+	}
+	return router
+}
+
+// routeDescendants routes descendants of c into named flows, if they do not
+// belong to flow, i.e. the flow of c.
+func routeDescendants(c *frame.Container, flow string, router *frame.FlowRouter) {
+	for _, ch := range c.TreeNode().Children(true) {
+		sub := ch.Payload
+		name := flow
+		if IsPrincipal(sub.RenderNode()) {
+			name = flowOf(sub.DOMNode())
+		}
+		if name != flow {
+			tracer().Debugf("[%s] is routed into flow '%s'", ContainerName(sub), name)
+			router.Route(sub, name)
+		}
+		routeDescendants(sub, name, router)
+	}
+}
+
+// flowOf returns the name of the flow a DOM node is routed into.
+func flowOf(n *dom.W3CNode) string {
+	if n == nil {
+		return frame.MainFlow
+	}
+	return frame.FlowName(n.ComputedStyles().GetPropertyValue("flow-into"))
+}
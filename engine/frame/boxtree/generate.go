@@ -6,6 +6,7 @@ package boxtree
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/engine/dom"
@@ -20,103 +21,72 @@ var errDOMNodeNotSuitable = errors.New("DOM node is not suited for layout")
 var ErrNoBoxTreeCreated = errors.New("no box tree created")
 
 // BuildBoxTree creates a render box tree from a styled tree.
+//
+// Boxes are generated according to the "display" property of DOM nodes (see
+// CSS 2.1 §9.2). Elements with "display: none" do not generate a box, neither
+// do their descendants. Block containers with mixed block-level and inline-level
+// content have runs of inline-level boxes wrapped into anonymous block boxes,
+// inline boxes containing block-level boxes are broken around them, and run-in
+// boxes are resolved to be either block-level or inline-level. List items are
+// numbered and get their markers.
 func BuildBoxTree(domRoot *dom.W3CNode) (*frame.Container, error) {
 	if domRoot == nil {
 		return nil, ErrDOMRootIsNull
 	}
-	domWalker := domRoot.Walk()
 	tracer().Debugf("Creating box tree")
-	dom2box := newAssoc()
-	createBoxForEach := prepareBoxCreator(dom2box)
-	future := domWalker.TopDown(createBoxForEach).Promise() // start asynchronous traversal
-	renderNodes, err := future()                            // wait for top-down traversal to finish
-	if err != nil {
-		return nil, err
-	}
-	tracer().Infof("Walker returned %d render nodes", len(renderNodes))
-	/*
-		for _, rnode := range renderNodes {
-			n := TreeNodeAsPrincipalBox(rnode)
-			T().Infof("  node for %s", n.domNode.NodeName())
-		}
-	*/
-	tracer().Infof("dom2box dict contains %d entries", dom2box.Length())
-	//T().Errorf("domRoot/2 = %s", dbgNodeString(domRoot))
-	boxRoot, ok := dom2box.Get(domRoot)
-	//T().Errorf("box for domRoot = %v", boxRoot)
-	if !ok || !IsPrincipal(boxRoot.RenderNode()) {
-		//if !ok || boxRoot.Type() != TypePrincipal {
+	boxRoot := buildBoxes(domRoot)
+	if boxRoot == nil || !IsPrincipal(boxRoot.RenderNode()) {
 		tracer().Errorf("No box created for root style node")
-		err = ErrNoBoxTreeCreated
-	} else {
-		err = attributeBoxes(boxRoot.RenderNode().(*PrincipalBox))
-		if err == nil {
-			err = reorderBoxTree(boxRoot.RenderNode().(*PrincipalBox))
-		}
+		return boxRoot, ErrNoBoxTreeCreated
 	}
-	return boxRoot, err
-}
-
-// prepareBocCreator is an action function for concurrent tree-traversal.
-func prepareBoxCreator(dict *domToBoxAssoc) tree.Action {
-	dom2box := dict
-	tracer().Infof("generate ACTION box creator ========================================")
-	action := func(node *tree.Node, parentNode *tree.Node, chpos int) (*tree.Node, error) {
-		domnode, err := dom.NodeFromTreeNode(node)
-		if err != nil {
-			tracer().Errorf("action 1: %s", err.Error())
-			return nil, err
-		}
-		var parent *dom.W3CNode
-		if parentNode != nil {
-			parent, err = dom.NodeFromTreeNode(parentNode)
-			if err != nil {
-				tracer().Errorf("action 2: %s", err.Error())
-				return nil, err
-			}
-		}
-		return createAndAttachBoxNode(domnode, parent, chpos, dom2box)
+	err := attributeBoxes(boxRoot.RenderNode().(*PrincipalBox))
+	if err == nil {
+		err = reorderBoxTree(boxRoot.RenderNode().(*PrincipalBox))
 	}
-	return action
+	return boxRoot, err
 }
 
-func createAndAttachBoxNode(domnode *dom.W3CNode, parent *dom.W3CNode, chpos int, dom2box *domToBoxAssoc) (
-	*tree.Node, error) {
-	//
-	tracer().Infof("making box for %s", domnode.NodeName())
+// buildBoxes creates the box for a DOM node and, recursively, the boxes for
+// its children. Boxes for children are created first, as reconciling
+// block-level and inline-level boxes needs the boxes of a whole level of
+// siblings.
+//
+// We do not use a tree walker here (as we do for attributing boxes): walker
+// actions map nodes of a tree to nodes of the same type, whereas we map nodes
+// of the styled tree to boxes.
+func buildBoxes(domnode *dom.W3CNode) *frame.Container {
+	tracer().Debugf("making box for %s", domnode.NodeName())
 	box := NewBoxForDOMNode(domnode)
 	if box == nil { // legit, e.g. for "display:none"
-		tracer().Debugf("box is nil")
-		return nil, nil // will not descend to children of domnode
+		return nil // will not descend to children of domnode
 	}
-	tracer().Infof("remembering %d/%s", domnode.NodeType(), domnode.NodeName())
-	dom2box.Put(domnode, box) // associate the styled tree node to this box
-	if domnode.IsDocument() { // document root cannot be attached to anything
-		return box.TreeNode(), nil
+	if !IsPrincipal(box.RenderNode()) {
+		return box
 	}
-	if parentNode := domnode.ParentNode(); parentNode != nil {
-		parent := parentNode.(*dom.W3CNode)
-		parentbox, found := dom2box.Get(parent)
-		if found {
-			tracer().Debugf("adding new box [%s] to parent [%s]\n", boxname(box), boxname(parentbox))
-			p := parentbox.RenderNode().(*PrincipalBox)
-			var err error
-			switch b := box.RenderNode().(type) {
-			case *PrincipalBox:
-				err = p.AddChild(b, chpos)
-			case *TextBox:
-				err = p.AddTextChild(b, chpos)
-			default:
-				tracer().Errorf("Unknown box type for %v", box)
-				err = errDOMNodeNotSuitable
-			}
-			if err != nil {
-				tracer().Errorf(err.Error())
-			}
+	var children []*frame.Container
+	var items []*PrincipalBox // list items, to be numbered for their markers
+	domchildren := domnode.ChildNodes()
+	for i := 0; i < domchildren.Length(); i++ {
+		domchild, ok := domchildren.Item(i).(*dom.W3CNode)
+		if !ok || domchild == nil {
+			continue
+		}
+		c := buildBoxes(domchild)
+		if c == nil {
+			continue
 		}
+		if c.Display.Contains(css.ListItemMode) {
+			items = append(items, c.RenderNode().(*PrincipalBox))
+		}
+		children = append(children, breakAroundBlocks(c)...)
+	}
+	numberListItems(box.RenderNode().(*PrincipalBox), items)
+	children = resolveRunIns(children)
+	children = wrapInlineRuns(box, children)
+	for _, c := range children {
+		box.TreeNode().AddChild(c.TreeNode())
 	}
-	possiblyCreateMiniHierarchy(box)
-	return box.TreeNode(), nil
+	return box
 }
 
 // ----------------------------------------------------------------------
@@ -137,32 +107,13 @@ func NewBoxForDOMNode(domnode *dom.W3CNode) *frame.Container {
 		return nil // do not produce box for illegal mode or for display = "none"
 	}
 	pbox := NewPrincipalBox(domnode, mode)
+	pbox.Picture = replacedContent(domnode)
 	//pbox.PrepareAnonymousBoxes()
 	// TODO find index within parent
 	// and set #ChildInx
 	return &pbox.Container
 }
 
-func possiblyCreateMiniHierarchy(c *frame.Container) {
-	//if c.Type() != TypePrincipal {
-	if !IsPrincipal(c.RenderNode()) {
-		return
-	}
-	//htmlnode := pbox.DOMNode().HTMLNode()
-	//propertyMap := styler.ComputedStyles()
-	switch c.DOMNode().NodeName() {
-	case "li":
-		//markertype, _ := style.GetCascadedProperty(c.DOMNode, "list-style-type", toStyler)
-		markertype := c.DOMNode().ComputedStyles().GetPropertyValue("list-style-type")
-		if markertype != "none" {
-			//markerbox := newContainer(BlockMode, FlowMode)
-			// TODO: fill box with correct marker symbol
-			//pbox.Add(markerbox)
-			tracer().Debugf("need marker for principal box")
-		}
-	}
-}
-
 // ---------------------------------------------------------------------------
 
 // attributeBoxes attributes principal boxes with sizing information and styles
@@ -178,16 +129,18 @@ func attributeBoxes(boxRoot *PrincipalBox) error {
 }
 
 // Tree action: attribute each box from CSS styles.
-func makeAttributesAction(root *frame.Container) tree.Action {
+func makeAttributesAction(root *frame.Container) tree.Action[*frame.Container] {
 	tracer().Infof("generate ACTION attributer ==============================================")
 	view := viewFromBoxRoot(root)
 	//return func attributeFromCSS(node *tree.Node, unused *tree.Node, chpos int) (match *tree.Node, err error) {
-	return func(node *tree.Node, parentNode *tree.Node, chpos int) (*tree.Node, error) {
+	return func(node *tree.Node[*frame.Container], parentNode *tree.Node[*frame.Container], chpos int) (
+		*tree.Node[*frame.Container], error) {
+		//
 		if node.Payload == nil {
 			//if c == nil {
 			return nil, nil
 		}
-		c := node.Payload.(*frame.Container)
+		c := node.Payload
 		style := c.DOMNode().ComputedStyles().GetPropertyValue // function shortcut
 		if IsPrincipal(c.RenderNode()) {
 			//if c.Type() == TypePrincipal {
@@ -204,8 +157,7 @@ func makeAttributesAction(root *frame.Container) tree.Action {
 			//
 		} else if IsText(c.RenderNode()) {
 			//} else if c.Type() == TypeText {
-			parent := parentNode.Payload.(*frame.Container)
-			setWhitespaceProperties(c, parent)
+			setWhitespaceProperties(c, parentNode.Payload)
 		}
 		return node, nil
 	}
@@ -228,25 +180,25 @@ func setSizingInformationForPrincipalBox(c *frame.Container, view *view, font st
 		bt := css.DimenOption(style("border-top-width"))
 		c.CSSBox().BorderWidth[frame.Top] = scale(bt, view, frame.Top, font)
 	} else {
-		c.CSSBox().BorderWidth[frame.Top] = css.SomeDimen(0)
+		c.CSSBox().BorderWidth[frame.Top] = css.JustDimen(0)
 	}
 	if style("border-right-style") != "" { // TODO should be "none"
 		br := css.DimenOption(style("border-right-width"))
 		c.CSSBox().BorderWidth[frame.Right] = scale(br, view, frame.Right, font)
 	} else {
-		c.CSSBox().BorderWidth[frame.Right] = css.SomeDimen(0)
+		c.CSSBox().BorderWidth[frame.Right] = css.JustDimen(0)
 	}
 	if style("border-bottom-style") != "" { // TODO should be "none"
 		bb := css.DimenOption(style("border-bottom-width"))
 		c.CSSBox().BorderWidth[frame.Bottom] = scale(bb, view, frame.Bottom, font)
 	} else {
-		c.CSSBox().BorderWidth[frame.Bottom] = css.SomeDimen(0)
+		c.CSSBox().BorderWidth[frame.Bottom] = css.JustDimen(0)
 	}
 	if style("border-left-style") != "" { // TODO should be "none"
 		bl := css.DimenOption(style("border-left-width"))
 		c.CSSBox().BorderWidth[frame.Left] = scale(bl, view, frame.Left, font)
 	} else {
-		c.CSSBox().BorderWidth[frame.Left] = css.SomeDimen(0)
+		c.CSSBox().BorderWidth[frame.Left] = css.JustDimen(0)
 	}
 	// Margins
 	mt := css.DimenOption(style("margin-top"))
@@ -263,7 +215,7 @@ func setSizingInformationForPrincipalBox(c *frame.Container, view *view, font st
 	w := css.DimenOption(style("width"))
 	w = scale(w, view, frame.Left, font)
 	h := css.DimenOption(style("height"))
-	h = scale(w, view, frame.Top, font)
+	h = scale(h, view, frame.Top, font)
 	c.CSSBox().W = w
 	c.CSSBox().H = h
 	// TODO min-/max-w + h
@@ -282,7 +234,11 @@ func setVisualStylesForPrincipalBox(c *frame.Container) {
 	if bcolor == nil && fgcolor != nil {
 		bcolor = fgcolor // border-color = currentcolor as defined by CSS spec
 	}
-	if bcolor != nil || fgcolor != nil || bgcolor != nil {
+	bgimage := backgroundImageURL(string(style("background-image")))
+	lstyle, _ := frame.LineStyleFromProperty(string(style("border-top-style")))
+	var radius dimen.DU
+	css.DimenOption(style("border-top-left-radius")).Match().Just(&radius)
+	if bcolor != nil || fgcolor != nil || bgcolor != nil || bgimage != "" || radius > 0 {
 		if pbox.Styles == nil {
 			// T().Debugf("bcolor = %v", bcolor)
 			// T().Debugf("fgcolor = %v", fgcolor)
@@ -297,7 +253,21 @@ func setVisualStylesForPrincipalBox(c *frame.Container) {
 		}
 		pbox.Styles.Border.LineColor = bcolor
 		pbox.Styles.Colors.Foreground = fgcolor
+		pbox.Styles.Colors.Background = bgcolor
+		pbox.Styles.Colors.BackgroundImage = bgimage
+		pbox.Styles.Border.LineStyle = int8(lstyle)
+		pbox.Styles.Border.CornerRadius = radius
+	}
+}
+
+// backgroundImageURL extracts the URL from a property value `url(…)`. Other
+// images, e.g. gradients, are not supported.
+func backgroundImageURL(p string) string {
+	p = strings.TrimSpace(p)
+	if !strings.HasPrefix(p, "url(") || !strings.HasSuffix(p, ")") {
+		return ""
 	}
+	return strings.Trim(strings.TrimSpace(p[4:len(p)-1]), `"'`)
 }
 
 /*
@@ -334,34 +304,24 @@ func setWhitespaceProperties(c, parent *frame.Container) {
 
 type view struct {
 	// TODO create this during DOM tree building
-	font string // TODO TypeFace
-	size dimen.Point
+	font  string         // TODO TypeFace
+	units css.Resolution // context for resolving relative units
 }
 
 func viewFromBoxRoot(root *frame.Container) *view {
 	return &view{
 		font: "view font",
-		size: dimen.DINA4,
+		units: css.Resolution{
+			FontSize: 10 * dimen.PT, // TODO size of font
+			Viewport: dimen.DINA4,
+		},
 	}
 }
 
+// scale resolves font- and viewport-relative dimensions. Percentages are left
+// for layout, as they depend on the containing block.
 func scale(d css.DimenT, view *view, dir int, font string) css.DimenT {
-	//T().Debugf("scaling dimen %+v", d)
-	if d.IsRelative() {
-		if d.UnitString() == "rem" {
-			d = d.ScaleFromFont(view.font)
-		} else {
-			d = d.ScaleFromFont(font)
-		}
-		d = d.ScaleFromViewport(view.size.X, view.size.Y)
-		// switch dir {
-		// case frame.Top:
-		// case frame.Right:
-		// case frame.Bottom:
-		// case frame.Left:
-		// }
-	}
-	return d
+	return view.units.Resolve(d)
 }
 
 func boxname(c *frame.Container) string {
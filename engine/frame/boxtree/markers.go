@@ -0,0 +1,161 @@
+package boxtree
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom"
+)
+
+// List items are numbered by CSS counter "list-item" (CSS Lists Level 3 §4.4).
+// A list resets the counter, either by property "counter-reset" or by HTML
+// attributes "start" and "reversed" of an ordered list. Every list item
+// increments the counter, by 1 (-1 for reversed lists) or by the amount set with
+// property "counter-increment", unless it sets the counter with HTML attribute
+// "value". The marker of a list item shows the value of the counter, formatted
+// according to property "list-style-type".
+//
+// Markers are positioned according to property "list-style-position": "outside"
+// markers hang to the left of the first line box of a list item, "inside" markers
+// start its first line (see package inline).
+
+// numberListItems sets the markers of the list items of a list.
+func numberListItems(list *PrincipalBox, items []*PrincipalBox) {
+	if len(items) == 0 {
+		return
+	}
+	value, step := 0, 1
+	if hasAttribute(list.DOMNode(), "reversed") {
+		value, step = len(items)+1, -1
+	}
+	if start, ok := intAttribute(list.DOMNode(), "start"); ok {
+		value = start - step
+	}
+	if reset, ok := counterValue(list.DOMNode(), "counter-reset", 0); ok {
+		value = reset
+	}
+	for _, item := range items {
+		if v, ok := intAttribute(item.DOMNode(), "value"); ok {
+			value = v
+		} else if inc, ok := counterValue(item.DOMNode(), "counter-increment", 1); ok {
+			value += inc
+		} else {
+			value += step
+		}
+		setListMarker(item, value)
+	}
+}
+
+// setListMarker sets the marker of a list item box, according to CSS properties
+// "list-style-type" and "list-style-position", and the value n of counter
+// "list-item" for the item.
+func setListMarker(pbox *PrincipalBox, n int) {
+	styles := pbox.DOMNode().ComputedStyles()
+	pbox.Marker = listMarker(string(styles.GetPropertyValue("list-style-type")), n)
+	pbox.MarkerInside = styles.GetPropertyValue("list-style-position") == "inside"
+	tracer().Debugf("list item #%d has marker %q", n, pbox.Marker)
+}
+
+// listMarker returns the marker text for list item number n.
+// Unknown list style types are treated as "disc".
+func listMarker(styletype string, n int) string {
+	switch styletype {
+	case "none":
+		return ""
+	case "circle":
+		return "◦"
+	case "square":
+		return "▪"
+	case "decimal":
+		return strconv.Itoa(n) + "."
+	case "decimal-leading-zero":
+		if n < 10 {
+			return "0" + strconv.Itoa(n) + "."
+		}
+		return strconv.Itoa(n) + "."
+	case "lower-alpha", "lower-latin":
+		return alphabetic(n) + "."
+	case "upper-alpha", "upper-latin":
+		return strings.ToUpper(alphabetic(n)) + "."
+	case "lower-roman":
+		return roman(n) + "."
+	case "upper-roman":
+		return strings.ToUpper(roman(n)) + "."
+	}
+	return "•"
+}
+
+// alphabetic returns a, b, …, z, aa, ab, … for n = 1, 2, … Numbers less than 1
+// are returned as decimals.
+func alphabetic(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	s := ""
+	for ; n > 0; n = (n - 1) / 26 {
+		s = string(rune('a'+(n-1)%26)) + s
+	}
+	return s
+}
+
+var romanDigits = []struct {
+	value  int
+	digits string
+}{
+	{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+	{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+}
+
+// roman returns n as a lowercase roman numeral. Roman numerals are defined for
+// 1…3999 only, other numbers are returned as decimals.
+func roman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	var b strings.Builder
+	for _, r := range romanDigits {
+		for ; n >= r.value; n -= r.value {
+			b.WriteString(r.digits)
+		}
+	}
+	return b.String()
+}
+
+// --- Counters --------------------------------------------------------------
+
+// counterValue returns the value for counter "list-item" from a counter property
+// of node n, i.e. "counter-reset" or "counter-increment". A counter given without
+// a value has value dflt. If the property does not name the counter, the second
+// return value is false.
+func counterValue(n *dom.W3CNode, property string, dflt int) (int, bool) {
+	fields := strings.Fields(string(n.ComputedStyles().GetPropertyValue(property)))
+	for i, f := range fields {
+		if f != "list-item" {
+			continue
+		}
+		if i+1 < len(fields) {
+			if v, err := strconv.Atoi(fields[i+1]); err == nil {
+				return v, true
+			}
+		}
+		return dflt, true
+	}
+	return 0, false
+}
+
+// hasAttribute is true if element n has an HTML attribute key.
+func hasAttribute(n *dom.W3CNode, key string) bool {
+	return n.Attributes().GetNamedItem(key) != nil
+}
+
+// intAttribute returns the value of an integer HTML attribute key of element n.
+// If n has no such attribute or its value is not an integer, the second return
+// value is false.
+func intAttribute(n *dom.W3CNode, key string) (int, bool) {
+	attr := n.Attributes().GetNamedItem(key)
+	if attr == nil {
+		return 0, false
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(attr.Value()))
+	return v, err == nil
+}
@@ -1,18 +1,18 @@
 package boxtree
 
 import (
-	"github.com/npillmayer/tyse/core/option"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 	"github.com/npillmayer/tyse/engine/frame"
 	"github.com/npillmayer/tyse/engine/tree"
 )
 
 // reorderBoxTree reorders box nodes of a box-tree to account for
-// "position" and "float" CSS properties.
+// the "position" CSS property.
 //
 // Currently this function moves boxes with positions 'fixed' or 'absolute' out of
 // the normal DOM hierarchy and re-attaches them to the document root or an ancestor
-// with non-static positioning, respectively.
+// with non-static positioning, respectively. Floats stay with their parent, as they
+// are placed by the formatting context they are contained in.
 //
 // In a future version, CSS regions should be supported as well.
 //
@@ -26,19 +26,14 @@ func reorderBoxTree(boxRoot *PrincipalBox) error {
 	return err
 }
 
-// Tree filter predicate: box has position "fixed", "absolute" or is a float.
-func reposition(node *tree.Node, unused *tree.Node) (match *tree.Node, err error) {
+// Tree filter predicate: box has position "fixed" or "absolute".
+func reposition(node *tree.Node[*frame.Container], unused *tree.Node[*frame.Container]) (
+	match *tree.Node[*frame.Container], err error) {
+	//
 	pbox := TreeNodeAsPrincipalBox(node)
 	if pbox != nil {
-		pos := css.PositionOption(pbox.domNode)
-		if nonflow, _ := pos.Match(option.Of{
-			option.None:            false,
-			css.PositionFixed:      true,
-			css.PositionAbsolute:   true,
-			css.PositionFloatLeft:  true,
-			css.PositionFloatRight: true,
-			option.Some:            false,
-		}); nonflow.(bool) {
+		pos := css.Position(pbox.domNode.ComputedStyles().GetPropertyValue("position"))
+		if pos.IsAbsolute() || pos.IsFixed() {
 			tracer().Debugf("box has to be re-ordered: %s (%v)", boxname(&pbox.Container), pos)
 			match = pbox.TreeNode()
 		}
@@ -47,51 +42,33 @@ func reposition(node *tree.Node, unused *tree.Node) (match *tree.Node, err error
 }
 
 // Tree filter predicate with side effect: attaches node to anchor, if suited.
-func anchor(anchorCandidate *tree.Node, node *tree.Node) (match *tree.Node, err error) {
+func anchor(anchorCandidate *tree.Node[*frame.Container], node *tree.Node[*frame.Container]) (
+	match *tree.Node[*frame.Container], err error) {
+	//
 	if node == nil || anchorCandidate == nil {
 		panic("one of node, anchor is nil")
 	}
-	positionedChild := node.Payload.(*frame.Container)
-	possibleAnchor := anchorCandidate.Payload.(*frame.Container)
-	//if positionedChild.Type() != TypePrincipal || possibleAnchor.Type() != TypePrincipal {
+	positionedChild := node.Payload
+	possibleAnchor := anchorCandidate.Payload
 	if !IsPrincipal(positionedChild.RenderNode()) || !IsPrincipal(possibleAnchor.RenderNode()) {
 		return
 	}
 	tracer().Debugf("trying to re-attach %s node", boxname(positionedChild))
 	tracer().Debugf("   candidate anchor is %s", boxname(possibleAnchor))
-	var anchor *PrincipalBox
-	anchorCandidateIsDocRoot := func(interface{}) (interface{}, error) {
-		return possibleAnchor.DOMNode().NodeName() == "#document", nil
+	position := css.Position(positionedChild.DOMNode().ComputedStyles().GetPropertyValue("position"))
+	var found bool
+	if position.IsFixed() { // test for document root
+		found = possibleAnchor.DOMNode().NodeName() == "#document"
+	} else { // test for out-of-flow position
+		ancpos := css.Position(possibleAnchor.DOMNode().ComputedStyles().GetPropertyValue("position"))
+		found = !ancpos.IsUnset() && !ancpos.IsStatic()
 	}
-	anchorCandidateIsPositioned := func(interface{}) (interface{}, error) {
-		ancpos := css.PositionOption(possibleAnchor.DOMNode())
-		ok, _ := ancpos.Match(option.Of{
-			option.None:          false,
-			css.PositionAbsolute: true,
-			css.PositionFixed:    true,
-			css.PositionRelative: true,
-			css.PositionSticky:   true,
-			option.Some:          false,
-		})
-		return ok.(bool), nil
-	}
-	anchorCandidateIsFlowRoot := func(interface{}) (interface{}, error) {
-		return possibleAnchor.Context.IsFlowRoot(), nil
-	}
-	position := css.PositionOption(positionedChild.DOMNode())
-	found, err := position.Match(option.Of{
-		css.PositionFixed:      anchorCandidateIsDocRoot,    // test for document root
-		css.PositionAbsolute:   anchorCandidateIsPositioned, // test for out-of-flow position
-		css.PositionFloatLeft:  anchorCandidateIsFlowRoot,   // test for flow-root
-		css.PositionFloatRight: anchorCandidateIsFlowRoot,   // test for flow-root
-	})
-	if err != nil || !(found.(bool)) {
+	if !found {
 		return
 	}
-	anchor = possibleAnchor.RenderNode().(*PrincipalBox)
-	if anchor != nil {
-		positionedChild.TreeNode().Isolate()
-		anchor.AppendChild(positionedChild.RenderNode().(*PrincipalBox)) // TODO will lose ordering !
+	if anchorCandidate != node.Parent() {
+		node.Isolate()
+		anchorCandidate.AddChild(node) // TODO will lose ordering !
 	}
-	return
+	return anchorCandidate, nil
 }
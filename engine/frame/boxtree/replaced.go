@@ -0,0 +1,31 @@
+package boxtree
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/core/locate/resources"
+	"github.com/npillmayer/tyse/engine/dom"
+)
+
+// --- Replaced elements -----------------------------------------------------
+
+// Replaced elements have content which is outside the scope of CSS formatting,
+// e.g. images. They are laid out as atomic boxes, sized from their intrinsic
+// dimensions and CSS properties width, height and max-width.
+//
+// Currently only <img> is supported. Loading of the image starts as soon as
+// its box is created and runs in the background until the box is laid out.
+
+// replacedContent starts loading the content of a replaced element. For
+// elements which are not replaced elements, nil is returned.
+func replacedContent(domnode *dom.W3CNode) resources.PicturePromise {
+	if domnode == nil || domnode.NodeName() != "img" {
+		return nil
+	}
+	attr := domnode.Attributes().GetNamedItem("src")
+	if attr == nil || strings.TrimSpace(attr.Value()) == "" {
+		tracer().Infof("<img> without src")
+		return nil
+	}
+	return resources.ResolvePicture(strings.TrimSpace(attr.Value()))
+}
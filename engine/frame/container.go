@@ -1,6 +1,8 @@
 package frame
 
 import (
+	"fmt"
+
 	"github.com/npillmayer/tyse/engine/dom"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 	"github.com/npillmayer/tyse/engine/tree"
@@ -79,6 +81,20 @@ func (b *Container) CSSBox() *Box {
 	return b.renderNode.CSSBox()
 }
 
+// String returns a short description of a container. Containers have to
+// implement it themselves, as the promoted method of the tree node would print
+// the payload, i.e., the container itself.
+func (b *Container) String() string {
+	if b == nil {
+		return "<nil container>"
+	}
+	name := "anon"
+	if n := b.DOMNode(); n != nil {
+		name = n.NodeName()
+	}
+	return fmt.Sprintf("(Container %s %s #ch=%d)", name, b.Display.Symbol(), b.ChildCount())
+}
+
 // DisplayMode returns the computed display mode of this box.
 // func (b *ContainerBase) DisplayMode() css.DisplayMode {
 // 	return b.Display
@@ -1,6 +1,9 @@
 package frame
 
 import (
+	"sync"
+
+	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 )
 
@@ -30,6 +33,16 @@ type ContextInterf interface {
 type FlowRoot struct {
 	PositionedFloats   *FloatList
 	UnpositionedFloats *FloatList
+	Placer             *FloatPlacer // places floats within the block formatting context
+}
+
+// NewFlowRoot creates a flow root for a block formatting context of width w.
+func NewFlowRoot(w dimen.DU) *FlowRoot {
+	return &FlowRoot{
+		PositionedFloats:   &FloatList{mutex: &sync.Mutex{}},
+		UnpositionedFloats: &FloatList{mutex: &sync.Mutex{}},
+		Placer:             NewFloatPlacer(w),
+	}
 }
 
 type ContextBase struct {
@@ -68,6 +81,12 @@ func (ctx ContextBase) FlowRoot() *FlowRoot {
 	return ctx.flowRoot
 }
 
+// SetFlowRoot sets the flow root of a context which establishes a new block
+// formatting context.
+func (ctx *ContextBase) SetFlowRoot(fr *FlowRoot) {
+	ctx.flowRoot = fr
+}
+
 func (ctx ContextBase) Contained() []*Container {
 	// c := make([]*Container, 0, ctx.TreeNode().ChildCount())
 	// for _, node := range ctx.TreeNode().Children(true) {
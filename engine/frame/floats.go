@@ -2,6 +2,9 @@ package frame
 
 import (
 	"sync"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
 )
 
 type FloatList struct {
@@ -45,3 +48,132 @@ func (l *FloatList) Floats() []*Container {
 	copy(floats, l.floats)
 	return floats
 }
+
+// --- Float placement -------------------------------------------------------
+
+// PlacedFloat is a floated container, placed within a block formatting context.
+// Pos is the top left corner of the float's margin box, relative to the content
+// box of the flow root.
+type PlacedFloat struct {
+	Container *Container
+	Side      css.FloatT
+	Pos       dimen.Point
+	W, H      dimen.DU // dimensions of the margin box
+}
+
+// Bottom returns the position of the lower edge of f.
+func (f PlacedFloat) Bottom() dimen.DU {
+	return f.Pos.Y + f.H
+}
+
+func (f PlacedFloat) overlaps(y, h dimen.DU) bool {
+	if h <= 0 {
+		return f.Pos.Y <= y && y < f.Bottom()
+	}
+	return f.Pos.Y < y+h && y < f.Bottom()
+}
+
+// FloatPlacer places floats within a block formatting context of a given width,
+// following the placement rules of CSS 2.1 §9.5.1.
+// Floats have to be placed in document order.
+type FloatPlacer struct {
+	Width  dimen.DU // width of the content box of the flow root
+	floats []PlacedFloat
+	top    dimen.DU // no float may be placed higher than this
+}
+
+// NewFloatPlacer creates a float placer for a block formatting context of width w.
+func NewFloatPlacer(w dimen.DU) *FloatPlacer {
+	return &FloatPlacer{Width: w}
+}
+
+// Place places a float c with a margin box of w × h, floating to side. y is the
+// top of the current line box; the float will not be placed above it.
+// If the float does not fit next to floats already placed, it is moved
+// down until it fits or until there are no more floats to avoid.
+func (fp *FloatPlacer) Place(c *Container, side css.FloatT, w, h, y dimen.DU) PlacedFloat {
+	if y < fp.top {
+		y = fp.top
+	}
+	for {
+		left, width := fp.Band(y, h)
+		if width >= w || !fp.occupied(y, h) {
+			f := PlacedFloat{Container: c, Side: side, W: w, H: h}
+			f.Pos.Y = y
+			f.Pos.X = left
+			if side == css.FloatRight {
+				f.Pos.X = left + width - w
+			}
+			fp.floats = append(fp.floats, f)
+			fp.top = y
+			tracer().Debugf("placed float at %v, size %s × %s", f.Pos, w, h)
+			return f
+		}
+		y = fp.nextBottom(y, h)
+	}
+}
+
+// Band returns the horizontal space available for content within a band of
+// height h, starting at y: the left offset and the width. Floats overlapping
+// the band shorten it. A height of 0 denotes the line at position y.
+func (fp *FloatPlacer) Band(y, h dimen.DU) (dimen.DU, dimen.DU) {
+	left, right := dimen.DU(0), fp.Width
+	for _, f := range fp.floats {
+		if !f.overlaps(y, h) {
+			continue
+		}
+		if f.Side == css.FloatLeft && f.Pos.X+f.W > left {
+			left = f.Pos.X + f.W
+		} else if f.Side == css.FloatRight && f.Pos.X < right {
+			right = f.Pos.X
+		}
+	}
+	if right < left {
+		return left, 0
+	}
+	return left, right - left
+}
+
+// Clearance returns the position a box with clear-property clear has to be moved
+// to, if it would otherwise start at y.
+func (fp *FloatPlacer) Clearance(clear css.ClearT, y dimen.DU) dimen.DU {
+	for _, f := range fp.floats {
+		if clear.Clears(f.Side) && f.Bottom() > y {
+			y = f.Bottom()
+		}
+	}
+	return y
+}
+
+// Floats returns the floats placed so far, in placement order.
+func (fp *FloatPlacer) Floats() []PlacedFloat {
+	floats := make([]PlacedFloat, len(fp.floats))
+	copy(floats, fp.floats)
+	return floats
+}
+
+// Bottom returns the lowest edge of all floats placed, or 0 if there are none.
+func (fp *FloatPlacer) Bottom() dimen.DU {
+	return fp.Clearance(css.ClearBoth, 0)
+}
+
+func (fp *FloatPlacer) occupied(y, h dimen.DU) bool {
+	for _, f := range fp.floats {
+		if f.overlaps(y, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextBottom returns the nearest bottom edge of a float overlapping the band
+// at y with height h. If there is none, y is returned.
+func (fp *FloatPlacer) nextBottom(y, h dimen.DU) dimen.DU {
+	next := y
+	for _, f := range fp.floats {
+		if f.overlaps(y, h) && f.Bottom() > y && (next == y || f.Bottom() < next) {
+			next = f.Bottom()
+		}
+	}
+	return next
+}
@@ -0,0 +1,58 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestFloatPlacement(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	fp := NewFloatPlacer(100 * dimen.PT)
+	f1 := fp.Place(nil, css.FloatLeft, 30*dimen.PT, 20*dimen.PT, 0)
+	f2 := fp.Place(nil, css.FloatRight, 40*dimen.PT, 10*dimen.PT, 0)
+	if f1.Pos.X != 0 || f2.Pos.X != 60*dimen.PT || f2.Pos.Y != 0 {
+		t.Errorf("expected floats at x=0 and x=60pt, have %v and %v", f1.Pos, f2.Pos)
+	}
+	// does not fit next to f1 and f2, has to move below f2
+	f3 := fp.Place(nil, css.FloatLeft, 50*dimen.PT, 10*dimen.PT, 0)
+	if f3.Pos.X != 30*dimen.PT || f3.Pos.Y != 10*dimen.PT {
+		t.Errorf("expected 3rd float at (30pt,10pt), is at %v", f3.Pos)
+	}
+	// may not be placed above f3
+	f4 := fp.Place(nil, css.FloatRight, 10*dimen.PT, 5*dimen.PT, 0)
+	if f4.Pos.Y != 10*dimen.PT || f4.Pos.X != 90*dimen.PT {
+		t.Errorf("expected 4th float at (90pt,10pt), is at %v", f4.Pos)
+	}
+	if left, w := fp.Band(0, 0); left != 30*dimen.PT || w != 30*dimen.PT {
+		t.Errorf("expected band at top to be [30pt…60pt], is %s + %s", left, w)
+	}
+	if left, w := fp.Band(20*dimen.PT, 5*dimen.PT); left != 0 || w != 100*dimen.PT {
+		t.Errorf("expected band below floats to be unrestricted, is %s + %s", left, w)
+	}
+}
+
+func TestFloatClearance(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	fp := NewFloatPlacer(100 * dimen.PT)
+	fp.Place(nil, css.FloatLeft, 30*dimen.PT, 20*dimen.PT, 0)
+	fp.Place(nil, css.FloatRight, 30*dimen.PT, 40*dimen.PT, 0)
+	if y := fp.Clearance(css.ClearLeft, 5*dimen.PT); y != 20*dimen.PT {
+		t.Errorf("expected clearance of left float to be 20pt, is %s", y)
+	}
+	if y := fp.Clearance(css.ClearNone, 5*dimen.PT); y != 5*dimen.PT {
+		t.Errorf("expected no clearance, have %s", y)
+	}
+	if y := fp.Bottom(); y != 40*dimen.PT {
+		t.Errorf("expected floats to end at 40pt, is %s", y)
+	}
+	if css.Clear("both") != css.ClearBoth || css.Float("Right") != css.FloatRight {
+		t.Errorf("expected CSS float and clear properties to be recognized")
+	}
+}
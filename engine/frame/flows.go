@@ -0,0 +1,67 @@
+package frame
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom/style"
+)
+
+// MainFlow is the name of the flow containing all content which has not been
+// assigned to a named flow.
+const MainFlow = "main"
+
+// FlowName returns the name of the named flow a property value of CSS property
+// "flow-into" refers to. Values `none` and unset refer to the main flow.
+func FlowName(p style.Property) string {
+	name := strings.TrimSpace(strings.ToLower(string(p)))
+	if name == "" || name == "none" {
+		return MainFlow
+	}
+	return name
+}
+
+// FlowRouter collects containers into named flows (CSS Regions). Each flow
+// keeps its containers in document order. Regions of page templates will then
+// consume content from the flows they are connected to.
+type FlowRouter struct {
+	flows  map[string][]*Container
+	names  []string              // flow names in order of first appearance
+	routed map[*Container]string // flow of every routed container
+}
+
+// NewFlowRouter creates an empty flow router.
+func NewFlowRouter() *FlowRouter {
+	return &FlowRouter{
+		flows:  make(map[string][]*Container),
+		routed: make(map[*Container]string),
+	}
+}
+
+// Route appends container c to the flow with the given name.
+func (fr *FlowRouter) Route(c *Container, name string) {
+	if _, ok := fr.flows[name]; !ok {
+		fr.names = append(fr.names, name)
+	}
+	fr.flows[name] = append(fr.flows[name], c)
+	fr.routed[c] = name
+	tracer().Debugf("routed container into flow '%s'", name)
+}
+
+// Flow returns the containers of the flow with the given name.
+func (fr *FlowRouter) Flow(name string) []*Container {
+	return fr.flows[name]
+}
+
+// IsRouted returns true if container c has been routed into a flow itself, i.e.
+// it does not simply travel along with an ancestor.
+func (fr *FlowRouter) IsRouted(c *Container) bool {
+	_, ok := fr.routed[c]
+	return ok
+}
+
+// Names returns the names of all flows with content, in order of first appearance.
+func (fr *FlowRouter) Names() []string {
+	names := make([]string, len(fr.names))
+	copy(names, fr.names)
+	return names
+}
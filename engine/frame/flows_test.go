@@ -0,0 +1,23 @@
+package frame
+
+import "testing"
+
+func TestFlowRouting(t *testing.T) {
+	if FlowName("none") != MainFlow || FlowName("") != MainFlow || FlowName(" Sidebar") != "sidebar" {
+		t.Errorf("expected flow names to be normalized")
+	}
+	fr := NewFlowRouter()
+	c1, c2, c3 := &Container{}, &Container{}, &Container{}
+	fr.Route(c1, MainFlow)
+	fr.Route(c2, "sidebar")
+	fr.Route(c3, MainFlow)
+	if names := fr.Names(); len(names) != 2 || names[0] != MainFlow || names[1] != "sidebar" {
+		t.Errorf("expected flows [main sidebar], have %v", names)
+	}
+	if main := fr.Flow(MainFlow); len(main) != 2 || main[1] != c3 {
+		t.Errorf("expected main flow to contain 2 containers in document order")
+	}
+	if len(fr.Flow("margin")) != 0 {
+		t.Errorf("expected flow 'margin' to be empty")
+	}
+}
@@ -86,7 +86,7 @@ func children(c *frame.Container) []*frame.Container {
 		} else if ch == nil {
 			tracing.Debugf("Child at #%d is nil", i)
 		} else {
-			kids = append(kids, ch.Payload.(*frame.Container))
+			kids = append(kids, ch.Payload)
 		}
 	}
 	return kids
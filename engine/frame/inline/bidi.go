@@ -0,0 +1,172 @@
+package inline
+
+import (
+	"unicode"
+
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/uax/bidi"
+)
+
+// --- Bidi reordering of lines ----------------------------------------------
+
+// ReorderLine reorders the items of a set line from logical to visual order,
+// following rules L1 to L4 of UAX#9. dirAt reports the resolved bidi direction at
+// a position of the paragraph's text, rtl is the direction of the paragraph.
+//
+// Embedding levels are derived from directions: text in the direction of the
+// paragraph is at the paragraph's level, other text one level above, i.e., nested
+// embeddings are not told apart. Glue, kerns and other knots between text of the
+// same level take that level, otherwise the paragraph's level, as do white space
+// and tabs at the end of a line or before a tab (rule L1).
+//
+// Items are re-positioned from left to right, starting at the position of the
+// leftmost item. Shapers deliver glyphs in visual order and mirror characters for
+// right-to-left text, so shaped text boxes are left as they are. For text boxes
+// which have not been shaped, text at odd levels is reversed and characters like
+// brackets are mirrored, such that renderers may map characters to glyphs from
+// left to right.
+func ReorderLine(line *SetLine, dirAt func(pos uint64) bidi.Direction, rtl bool) {
+	if line == nil || len(line.Items) == 0 || dirAt == nil {
+		return
+	}
+	levels := lineLevels(line.Items, dirAt, rtl)
+	maxLevel, minOdd := 0, 1<<30
+	for _, l := range levels {
+		if l > maxLevel {
+			maxLevel = l
+		}
+		if l%2 == 1 && l < minOdd {
+			minOdd = l
+		}
+	}
+	if maxLevel == 0 { // left-to-right text only
+		return
+	}
+	x := line.Items[0].X
+	for level := maxLevel; level >= minOdd; level-- { // rule L2
+		for i := 0; i < len(levels); {
+			if levels[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(levels) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				line.Items[a], line.Items[b] = line.Items[b], line.Items[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+	for i := range line.Items {
+		item := &line.Items[i]
+		if box, ok := item.Knot.(*khipu.TextBox); ok && levels[i]%2 == 1 && len(box.Glyphs().Glyphs) == 0 {
+			item.Knot = reversedBox(box)
+		}
+		item.X = x
+		x += item.W
+	}
+	tracer().Debugf("reordered line %d for levels up to %d", line.Number, maxLevel)
+}
+
+// lineLevels returns the embedding levels of the items of a line, derived from
+// the directions of its text (see ReorderLine).
+func lineLevels(items []PositionedKnot, dirAt func(pos uint64) bidi.Direction, rtl bool) []int {
+	base := 0
+	if rtl {
+		base = 1
+	}
+	levels := make([]int, len(items))
+	for i, item := range items {
+		levels[i] = -1 // neutral
+		if box, ok := item.Knot.(*khipu.TextBox); ok {
+			levels[i] = base
+			if (dirAt(box.Position) == bidi.RightToLeft) != rtl {
+				levels[i] = base + 1
+			}
+		}
+	}
+	for i := 0; i < len(levels); { // neutrals, as rules N1 and N2 would resolve them
+		if levels[i] >= 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(levels) && levels[j] < 0 {
+			j++
+		}
+		level := base // at the start and at the end of the line (rule L1), or between levels
+		if i > 0 && j < len(levels) && levels[i-1] == levels[j] {
+			level = levels[j]
+		}
+		for k := i; k < j; k++ {
+			levels[k] = level
+		}
+		i = j
+	}
+	for i, item := range items { // rule L1 for tabs and white space before them
+		if item.Knot.Type() != khipu.KTTab {
+			continue
+		}
+		levels[i] = base
+		for k := i - 1; k >= 0 && isWhiteSpace(items[k].Knot); k-- {
+			levels[k] = base
+		}
+	}
+	return levels
+}
+
+func isWhiteSpace(knot khipu.Knot) bool {
+	return knot.Type() == khipu.KTGlue || knot.Type() == khipu.KTKern
+}
+
+// reversedBox returns a copy of a text box with its text reversed and mirrored.
+func reversedBox(box *khipu.TextBox) *khipu.TextBox {
+	r := khipu.NewTextBox(reverseText(box.Text()), box.Position)
+	r.Width, r.Height, r.Depth = box.Width, box.Height, box.Depth
+	r.Shift, r.Deco = box.Shift, box.Deco
+	return r
+}
+
+// reverseText reverses the characters of s and mirrors them (rule L4). Combining
+// marks are kept after their base characters (rule L3).
+func reverseText(s string) string {
+	runes := []rune(s)
+	reversed := make([]rune, 0, len(runes))
+	for end := len(runes); end > 0; {
+		start := end - 1
+		for start > 0 && unicode.Is(unicode.Mn, runes[start]) {
+			start--
+		}
+		for _, r := range runes[start:end] {
+			if m, ok := mirrored[r]; ok {
+				r = m
+			}
+			reversed = append(reversed, r)
+		}
+		end = start
+	}
+	return string(reversed)
+}
+
+// mirrored maps characters with property Bidi_Mirrored to their mirror images,
+// for the brackets and relations frequent in text.
+var mirrored = func() map[rune]rune {
+	pairs := []rune("()[]{}<>«»‹›⁅⁆⁽⁾₍₎≤≥∈∋⊂⊃〈〉《》「」『』【】〔〕（）［］｛｝＜＞")
+	m := make(map[rune]rune, len(pairs))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		m[pairs[i]], m[pairs[i+1]] = pairs[i+1], pairs[i]
+	}
+	return m
+}()
+
+// bidiDirection returns the resolved bidi direction at a position of the text of
+// a paragraph.
+func (para *Paragraph) bidiDirection(pos uint64) bidi.Direction {
+	if para.Paragraph == nil || para.BidiLevels() == nil {
+		return bidi.LeftToRight
+	}
+	return para.BidiLevels().DirectionAt(pos)
+}
@@ -0,0 +1,91 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/uax/bidi"
+)
+
+// bidiLine sets knots from left to right, as SetLineOf would do for a line of
+// natural width.
+func bidiLine(knots ...khipu.Knot) *SetLine {
+	line := &SetLine{}
+	x := dimen.DU(0)
+	for _, knot := range knots {
+		line.Items = append(line.Items, PositionedKnot{Knot: knot, X: x, W: knot.W()})
+		x += knot.W()
+	}
+	return line
+}
+
+func bidiBox(s string, pos uint64) *khipu.TextBox {
+	box := khipu.NewTextBox(s, pos)
+	box.Width = dimen.DU(len([]rune(s))) * 10 * dimen.PT
+	return box
+}
+
+func visualText(line *SetLine) []string {
+	var texts []string
+	for _, item := range line.Items {
+		if box, ok := item.Knot.(*khipu.TextBox); ok {
+			texts = append(texts, box.Text())
+		}
+	}
+	return texts
+}
+
+func TestReorderLine(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	space := khipu.NewGlue(5*dimen.PT, 0, 0)
+	line := bidiLine(bidiBox("abc", 0), space, bidiBox("אבג", 4), space, bidiBox("דה", 11),
+		space, bidiBox("xyz", 16))
+	hebrew := func(pos uint64) bidi.Direction {
+		if pos >= 4 && pos < 16 {
+			return bidi.RightToLeft
+		}
+		return bidi.LeftToRight
+	}
+	ReorderLine(line, hebrew, false)
+	texts := visualText(line)
+	if len(texts) != 4 || texts[0] != "abc" || texts[1] != "הד" || texts[2] != "גבא" || texts[3] != "xyz" {
+		t.Errorf("expected Hebrew words to be reversed between Latin words, have %q", texts)
+	}
+	for i, x := range []dimen.DU{0, 30, 35, 55, 60, 90, 95} {
+		if line.Items[i].X != x*dimen.PT {
+			t.Errorf("expected item #%d at %s, is at %s", i, x*dimen.PT, line.Items[i].X)
+		}
+	}
+}
+
+func TestReorderRTLLine(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	space := khipu.NewGlue(5*dimen.PT, 0, 0)
+	line := bidiLine(bidiBox("שלום", 0), space, bidiBox("abc", 9), space, bidiBox("(אב", 13),
+		khipu.NewGlue(10*dimen.PT, 0, 0))
+	latin := func(pos uint64) bidi.Direction {
+		if pos >= 9 && pos < 12 {
+			return bidi.LeftToRight
+		}
+		return bidi.RightToLeft
+	}
+	ReorderLine(line, latin, true)
+	texts := visualText(line)
+	if len(texts) != 3 || texts[0] != "בא)" || texts[1] != "abc" || texts[2] != "םולש" {
+		t.Errorf("expected right-to-left line with Latin word in visual order, have %q", texts)
+	}
+	if line.Items[0].Knot.Type() != khipu.KTGlue || line.Items[1].X != 10*dimen.PT {
+		t.Errorf("expected trailing space to be set at the left end of the line, have %v", line.Items[0])
+	}
+	ltr := bidiLine(bidiBox("abc", 0), space)
+	ReorderLine(ltr, func(uint64) bidi.Direction { return bidi.LeftToRight }, false)
+	if texts = visualText(ltr); texts[0] != "abc" {
+		t.Errorf("expected left-to-right line to be left untouched, have %q", texts)
+	}
+}
@@ -0,0 +1,116 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+// --- Text decoration -------------------------------------------------------
+
+// DecorationRect is a rectangle to paint for a decoration line of a set line.
+// Decoration rectangles are render primitives, which are painted in the color
+// of the text.
+type DecorationRect struct {
+	Decoration frame.TextDecoration // exactly one of underline, overline or line-through
+	X, W       dimen.DU             // horizontal extent, relative to the start of the line
+	Y, H       dimen.DU             // top edge relative to the baseline (positive downwards), and thickness
+}
+
+// DecorationMetrics are the positions and thicknesses of decoration lines for a
+// font at a given size. Positions denote the top edge of a line, relative to the
+// baseline, with positive values upwards.
+type DecorationMetrics struct {
+	UnderlinePosition, UnderlineThickness dimen.DU
+	OverlinePosition, OverlineThickness   dimen.DU
+	StrikeoutPosition, StrikeoutThickness dimen.DU
+}
+
+// DecorationMetricsFromFont calculates the decoration metrics for font otf set at
+// size em. Underline position and thickness are taken from table post, strikeout
+// position and size from table OS/2. The overline is placed at the ascender, with
+// the thickness of the underline. Missing values are estimated from em.
+func DecorationMetricsFromFont(otf *ot.Font, em dimen.DU) DecorationMetrics {
+	dm := DecorationMetrics{
+		UnderlinePosition:  -em / 10,
+		UnderlineThickness: em / 20,
+		OverlinePosition:   em * 4 / 5,
+		StrikeoutPosition:  em * 3 / 10,
+	}
+	if otf != nil {
+		m := otquery.LineMetrics(otf, otquery.AutoMetrics)
+		if m.UnderlineThickness > 0 {
+			dm.UnderlinePosition = opentype.UnitsToDimen(m.UnderlinePosition, m.UnitsPerEm, em)
+			dm.UnderlineThickness = opentype.UnitsToDimen(m.UnderlineThickness, m.UnitsPerEm, em)
+		}
+		if m.Ascent > 0 {
+			dm.OverlinePosition = opentype.UnitsToDimen(m.Ascent, m.UnitsPerEm, em)
+		}
+		if m.StrikeoutSize > 0 {
+			dm.StrikeoutPosition = opentype.UnitsToDimen(m.StrikeoutPosition, m.UnitsPerEm, em)
+			dm.StrikeoutThickness = opentype.UnitsToDimen(m.StrikeoutSize, m.UnitsPerEm, em)
+		}
+	}
+	dm.OverlineThickness = dm.UnderlineThickness
+	if dm.StrikeoutThickness == 0 {
+		dm.StrikeoutThickness = dm.UnderlineThickness
+	}
+	return dm
+}
+
+// Decorations computes the decoration rectangles for a set line. Text boxes of the
+// line which are adjacent and share a decoration line are decorated by a single
+// rectangle, including the glue in between. Decorations do not follow the baseline
+// shift of text boxes, as with CSS, decoration lines of a run of text are painted
+// at a single position.
+func Decorations(line *SetLine, dm DecorationMetrics) []DecorationRect {
+	if line == nil {
+		return nil
+	}
+	var rects []DecorationRect
+	for _, deco := range []frame.TextDecoration{frame.Underline, frame.Overline, frame.LineThrough} {
+		open := false
+		var r DecorationRect
+		for _, item := range line.Items {
+			box, ok := item.Knot.(*khipu.TextBox)
+			if !ok {
+				continue // glue, kerns, etc. are decorated between decorated boxes only
+			}
+			if box.Deco&deco == 0 {
+				if open {
+					rects = append(rects, r)
+					open = false
+				}
+				continue
+			}
+			if !open {
+				r = dm.rect(deco)
+				r.X = item.X
+				open = true
+			}
+			r.W = item.X + item.W - r.X
+		}
+		if open {
+			rects = append(rects, r)
+		}
+	}
+	return rects
+}
+
+// rect returns a decoration rectangle for a single decoration line, without
+// horizontal extent.
+func (dm DecorationMetrics) rect(deco frame.TextDecoration) DecorationRect {
+	r := DecorationRect{Decoration: deco}
+	switch deco {
+	case frame.Underline:
+		r.Y, r.H = -dm.UnderlinePosition, dm.UnderlineThickness
+	case frame.Overline:
+		r.Y, r.H = -dm.OverlinePosition, dm.OverlineThickness
+	case frame.LineThrough:
+		r.Y, r.H = -dm.StrikeoutPosition, dm.StrikeoutThickness
+	}
+	return r
+}
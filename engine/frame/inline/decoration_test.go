@@ -0,0 +1,55 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestDecorations(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := func(s string, deco frame.TextDecoration) *khipu.TextBox {
+		b := khipu.NewTextBox(s, 0)
+		b.Deco = deco
+		return b
+	}
+	glue := khipu.NewGlue(4*dimen.PT, 0, 0)
+	line := &SetLine{Items: []PositionedKnot{
+		{Knot: box("one", frame.Underline), X: 0, W: 20 * dimen.PT},
+		{Knot: glue, X: 20 * dimen.PT, W: 4 * dimen.PT},
+		{Knot: box("two", frame.Underline|frame.LineThrough), X: 24 * dimen.PT, W: 20 * dimen.PT},
+		{Knot: glue, X: 44 * dimen.PT, W: 4 * dimen.PT},
+		{Knot: box("three", 0), X: 48 * dimen.PT, W: 30 * dimen.PT},
+	}}
+	dm := DecorationMetrics{UnderlinePosition: -dimen.PT, UnderlineThickness: dimen.PT / 2,
+		StrikeoutPosition: 3 * dimen.PT, StrikeoutThickness: dimen.PT / 2}
+	rects := Decorations(line, dm)
+	if len(rects) != 2 {
+		t.Fatalf("expected 2 decoration rectangles, have %d: %v", len(rects), rects)
+	}
+	if u := rects[0]; u.Decoration != frame.Underline || u.X != 0 || u.W != 44*dimen.PT || u.Y != dimen.PT {
+		t.Errorf("expected underline to span the first two words at 1pt below the baseline, is %+v", u)
+	}
+	if s := rects[1]; s.Decoration != frame.LineThrough || s.X != 24*dimen.PT || s.W != 20*dimen.PT {
+		t.Errorf("expected line-through for the second word only, is %+v", s)
+	}
+}
+
+func TestDecorationMetricsFromFont(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	otf, em := paragraphFont(nil)
+	dm := DecorationMetricsFromFont(otf, em)
+	if dm.UnderlinePosition >= 0 || dm.UnderlineThickness <= 0 {
+		t.Errorf("expected underline below the baseline, have %+v", dm)
+	}
+	if dm.StrikeoutPosition <= 0 || dm.OverlinePosition <= dm.StrikeoutPosition {
+		t.Errorf("expected strikeout between baseline and overline, have %+v", dm)
+	}
+}
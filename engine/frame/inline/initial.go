@@ -0,0 +1,172 @@
+package inline
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"github.com/npillmayer/tyse/engine/glyphing/monospace"
+)
+
+// --- Initial letters -------------------------------------------------------
+
+// https://www.w3.org/TR/css-inline-3/#initial-letter-styling
+
+// Paragraphs with CSS property initial-letter set start with an enlarged first
+// letter ("drop cap"). The letter is taken out of the paragraph's text, together
+// with punctuation enclosing it, and set at the start edge of the first line.
+// The lines next to the letter are indented by its width, plus a quarter em.
+
+// defaultCapHeight is the cap height of fonts without metrics, relative to their
+// font size.
+const defaultCapHeight = 0.7
+
+// InitialLetter is the enlarged first letter of a paragraph. The letter spans
+// Size lines, i.e. its cap height is Size-1 times the leading plus the cap height
+// of the paragraph's text. It sinks Sink lines into the paragraph, i.e. its
+// baseline is aligned with the baseline of line number Sink, counting from 1.
+// For paragraphs with fewer lines, the letter is aligned with the last line.
+type InitialLetter struct {
+	Box      *khipu.TextBox // the letter, measured at font size Em
+	Em       dimen.DU       // font size of the letter
+	Height   dimen.DU       // cap height of the letter
+	Size     float64        // number of lines the letter spans
+	Sink     int            // number of lines the letter sinks into the paragraph
+	X        dimen.DU       // offset of the letter from the left edge of the paragraph
+	Baseline dimen.DU       // position of the letter's baseline, from the top of the paragraph
+}
+
+// initialLetter takes the initial letter out of the text of a paragraph, if the
+// paragraph of c has CSS property initial-letter set. Otherwise, or if the
+// paragraph does not start with a letter or digit, it returns nil.
+func initialLetter(c *frame.Container, para *Paragraph) *InitialLetter {
+	if c == nil || c.DOMNode() == nil || para.Khipu == nil {
+		return nil
+	}
+	size, sink := css.InitialLetter(c.DOMNode().ComputedStyles().GetPropertyValue("initial-letter"))
+	if size == 0 {
+		return nil
+	}
+	letter := takeInitialLetter(para.Khipu)
+	if letter == "" {
+		return nil
+	}
+	ratio := para.capHeightRatio()
+	em := initialLetterSize(size, para.leading(), para.Em, ratio)
+	k, err := khipu.EncodeText(letter, khipu.WithRegisters(para.Regs),
+		khipu.WithShaper(monospace.Shaper(em, nil)))
+	if err != nil {
+		tracer().Errorf("cannot encode initial letter %q: %v", letter, err)
+		return nil
+	}
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		if box, ok := cursor.Knot().(*khipu.TextBox); ok {
+			tracer().Debugf("initial letter %q spans %.1f lines at %s", letter, size, em)
+			return &InitialLetter{
+				Box:    box,
+				Em:     em,
+				Height: dimen.FromFloat(ratio * float64(em)),
+				Size:   size,
+				Sink:   sink,
+			}
+		}
+	}
+	return nil
+}
+
+// initialLetterSize returns the font size for an initial letter spanning size
+// lines of distance leading, for a paragraph with font size em and fonts with a
+// cap height of ratio times the font size.
+func initialLetterSize(size float64, leading, em dimen.DU, ratio float64) dimen.DU {
+	capHeight := (size-1)*float64(leading) + ratio*float64(em)
+	return dimen.FromFloat(capHeight / ratio)
+}
+
+// takeInitialLetter removes the first letter or digit from the text of k, together
+// with punctuation enclosing it, and returns it. The letter has to start the
+// first text box of k, with only empty knots before it. If k does not start with
+// a letter, takeInitialLetter leaves k unchanged and returns the empty string.
+func takeInitialLetter(k *khipu.Khipu) string {
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if !ok {
+			if cursor.Knot().W() != 0 {
+				return ""
+			}
+			continue
+		}
+		at := initialLetterEnd(box.Text())
+		if at == 0 {
+			return ""
+		}
+		letter, rest := box.Split(at)
+		cursor.ReplaceKnot(rest) // may be empty, e.g. for words of a single letter
+		return letter.Text()
+	}
+	return ""
+}
+
+// initialLetterEnd returns the byte position after the first letter or digit of
+// s, including punctuation before and after it. If s does not start with a letter
+// or digit, possibly after punctuation, it returns 0.
+func initialLetterEnd(s string) int {
+	i := 0
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if !unicode.IsPunct(r) {
+			break
+		}
+		i += n
+	}
+	r, n := utf8.DecodeRuneInString(s[i:])
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return 0
+	}
+	i += n
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if !unicode.In(r, unicode.Pe, unicode.Pf, unicode.Po) {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// capHeightRatio returns the cap height of the paragraph's font, relative to its
+// font size. Paragraphs without font information use defaultCapHeight.
+func (para *Paragraph) capHeightRatio() float64 {
+	if para.Font == nil {
+		return defaultCapHeight
+	}
+	metrics := otquery.LineMetrics(para.Font, otquery.AutoMetrics)
+	if metrics.CapHeight <= 0 || metrics.UnitsPerEm <= 0 {
+		return defaultCapHeight
+	}
+	return float64(metrics.CapHeight) / float64(metrics.UnitsPerEm)
+}
+
+// makeRoom positions an initial letter at the start edge of the first line of
+// parshape and returns a parshape with the lines next to the letter indented by
+// the width of the letter plus gap.
+func (il *InitialLetter) makeRoom(parshape linebreak.ParShape, gap dimen.DU, rtl bool) linebreak.ParShape {
+	il.X = linebreak.LineIndent(parshape, 0)
+	if rtl {
+		il.X += parshape.LineLength(0) - il.Box.Width
+	}
+	return indentLines(parshape, il.Box.Width+gap, int32(il.Sink), rtl)
+}
+
+// ascent returns the height the letter rises above the baseline of the first
+// line, for lines of distance leading. Raised initial letters, sinking fewer lines
+// than they span, rise above the text of the first line.
+func (il *InitialLetter) ascent(leading dimen.DU) dimen.DU {
+	return il.Height - dimen.DU(il.Sink-1)*leading
+}
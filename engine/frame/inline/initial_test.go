@@ -0,0 +1,59 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+func TestTakeInitialLetter(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.Penalty(0)).AppendKnot(khipu.NewTextBox("“Once", 0))
+	if letter := takeInitialLetter(k); letter != "“O" {
+		t.Fatalf("expected initial letter to include opening quote, is %q", letter)
+	}
+	if text := k.Text(0, k.Length()); text != "nce" {
+		t.Errorf("expected initial letter to be removed from text, text is %q", text)
+	}
+	k = khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("—", 0))
+	if letter := takeInitialLetter(k); letter != "" {
+		t.Errorf("expected no initial letter for text starting with a dash, have %q", letter)
+	}
+}
+
+func TestInitialLetterPlacement(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	leading, em := 12*dimen.PT, 10*dimen.PT
+	size := initialLetterSize(3, leading, em, 0.5)
+	if size != 58*dimen.PT {
+		t.Errorf("expected letter spanning 3 lines to be set at 58pt, is %s", size)
+	}
+	il := &InitialLetter{Box: khipu.NewTextBox("A", 0), Height: 29 * dimen.PT, Sink: 3}
+	il.Box.Width = 20 * dimen.PT
+	parshape := il.makeRoom(linebreak.RectangularParShape(100*dimen.PT), 5*dimen.PT, false)
+	for l := int32(0); l < 3; l++ {
+		if parshape.LineLength(l) != 75*dimen.PT || linebreak.LineIndent(parshape, l) != 25*dimen.PT {
+			t.Errorf("expected line %d to be indented by 25pt", l)
+		}
+	}
+	if parshape.LineLength(3) != 100*dimen.PT {
+		t.Errorf("expected line 4 to span the paragraph")
+	}
+	if a := il.ascent(leading); a != 5*dimen.PT {
+		t.Errorf("expected dropped letter to rise 5pt above the first baseline, rises %s", a)
+	}
+	il.Sink = 1
+	il.makeRoom(linebreak.RectangularParShape(100*dimen.PT), 5*dimen.PT, true)
+	if il.X != 80*dimen.PT || il.ascent(leading) != 29*dimen.PT {
+		t.Errorf("expected raised rtl letter at 80pt, rising 29pt, is at %s, rising %s", il.X, il.ascent(leading))
+	}
+}
@@ -0,0 +1,312 @@
+package inline
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Multi-level justification ---------------------------------------------
+
+// JustificationLevel is a level of a multi-level justification.
+// Levels with lower priority values are applied first.
+type JustificationLevel struct {
+	Priority  int
+	Justifier linebreak.Justifier
+}
+
+// MultiLevelJustifier justifies lines in multiple levels, similar to the rules of
+// JIS X 4051 or ICU: each level is handed the part of the excess width which the
+// levels before it were not able to absorb, e.g., inter-word spaces are expanded
+// first, then inter-character spacing for CJK text is applied, then kashidas are
+// inserted into Arabic text.
+//
+// Lines containing infinitely stretchable glue (e.g., the last line of a paragraph)
+// are not justified.
+type MultiLevelJustifier struct {
+	Levels []JustificationLevel
+}
+
+var _ linebreak.Justifier = MultiLevelJustifier{}
+
+// NewMultiLevelJustifier creates a multi-level justification strategy with default
+// levels and limits, given the em size and the width of a kashida for a paragraph:
+//
+//  1. expand inter-word spaces up to their stretchability
+//  2. add up to 1/4 em between CJK characters
+//  3. insert up to 3 kashidas into each Arabic word
+func NewMultiLevelJustifier(em, kashida dimen.DU) MultiLevelJustifier {
+	return MultiLevelJustifier{Levels: []JustificationLevel{
+		{Priority: 1, Justifier: SpaceJustifier{}},
+		{Priority: 2, Justifier: InterCharacterJustifier{MaxPerGap: em / 4}},
+		{Priority: 3, Justifier: NewKashidaJustifier(kashida)},
+	}}
+}
+
+// Justify is part of interface linebreak.Justifier.
+func (mlj MultiLevelJustifier) Justify(k *khipu.Khipu, from, to int64, excess dimen.DU) (int64, dimen.DU) {
+	if hasInfiniteGlue(k, from, to) {
+		return to, excess
+	}
+	levels := make([]JustificationLevel, len(mlj.Levels))
+	copy(levels, mlj.Levels)
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Priority < levels[j].Priority
+	})
+	for _, level := range levels {
+		if excess == 0 {
+			break
+		}
+		to, excess = level.Justifier.Justify(k, from, to, excess)
+	}
+	return to, excess
+}
+
+// SpaceJustifier expands or shrinks the inter-word glue of a line. Leading and
+// trailing glue of a line is not changed.
+type SpaceJustifier struct {
+	MaxPerSpace dimen.DU // maximum expansion of a space, 0 for the glue's stretchability
+}
+
+var _ linebreak.Justifier = SpaceJustifier{}
+
+// Justify is part of interface linebreak.Justifier.
+func (sj SpaceJustifier) Justify(k *khipu.Khipu, from, to int64, excess dimen.DU) (int64, dimen.DU) {
+	var positions []int64
+	var glues []khipu.Glue
+	var capacities []dimen.DU
+	first, last := contentRange(k, from, to)
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().Type() != khipu.KTGlue {
+			continue
+		}
+		if pos := cursor.Position(); pos < first || pos > last { // leading or trailing glue
+			continue
+		}
+		g := cursor.AsGlue()
+		c := g.MaxW() - g.W()
+		if excess < 0 {
+			c = g.W() - g.MinW()
+		} else if sj.MaxPerSpace > 0 {
+			c = sj.MaxPerSpace
+		}
+		if c > 0 && c < dimen.Fil {
+			positions = append(positions, cursor.Position())
+			glues = append(glues, g)
+			capacities = append(capacities, c)
+		}
+	}
+	sign := dimen.DU(1)
+	if excess < 0 {
+		sign = -1
+	}
+	deltas, rest := distribute(sign*excess, capacities)
+	for i, pos := range positions {
+		if deltas[i] == 0 {
+			continue
+		}
+		g := glues[i]
+		d := sign * deltas[i]
+		k.ReplaceKnot(pos, khipu.NewGlue(g.W()+d, g.W()+d-g.MinW(), g.MaxW()-g.W()-d))
+	}
+	return to, sign * rest
+}
+
+// InterCharacterJustifier inserts space between adjacent CJK characters of a line,
+// i.e., between text boxes which are not separated by glue. UAX#14 finds break
+// opportunities between ideographs, so ideographs are usually set in text boxes of
+// their own, separated by penalties (see khipu.KinsokuShori). Excess width is
+// distributed evenly to the gaps between characters.
+//
+// Following the basic rules of JLREQ, no space is inserted after opening brackets,
+// before closing brackets, full stops and commas, or between inseparable
+// characters (see interCharacterGap). Space is inserted between CJK characters
+// and adjacent Western characters as well.
+type InterCharacterJustifier struct {
+	MaxPerGap dimen.DU // maximum space to insert between two characters
+}
+
+var _ linebreak.Justifier = InterCharacterJustifier{}
+
+// Justify is part of interface linebreak.Justifier.
+// It inserts kern knots into the line.
+func (icj InterCharacterJustifier) Justify(k *khipu.Khipu, from, to int64, excess dimen.DU) (int64, dimen.DU) {
+	if icj.MaxPerGap <= 0 || excess <= 0 {
+		return to, excess
+	}
+	var gaps []int64 // positions after text boxes, where space may be inserted
+	var capacities []dimen.DU
+	prev := int64(-1) // position of previous text box
+	var last rune     // last character of previous text box
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		switch cursor.Knot().Type() {
+		case khipu.KTTextBox:
+			text := cursor.AsTextBox().Text()
+			first, _ := utf8.DecodeRuneInString(text)
+			if prev >= 0 && interCharacterGap(last, first) {
+				gaps = append(gaps, prev+1)
+				capacities = append(capacities, icj.MaxPerGap)
+			}
+			prev = cursor.Position()
+			last, _ = utf8.DecodeLastRuneInString(text)
+		case khipu.KTGlue, khipu.KTKern:
+			prev = -1
+		}
+	}
+	deltas, rest := distribute(excess, capacities)
+	for i := len(gaps) - 1; i >= 0; i-- { // back to front, as insertions shift positions
+		if deltas[i] > 0 {
+			k.InsertKnot(gaps[i], khipu.Kern(deltas[i]))
+			to++
+		}
+	}
+	return to, rest
+}
+
+// Character classes of JLREQ (Requirements for Japanese Text Layout), as far as
+// they are relevant for inter-character spacing.
+const (
+	jlreqOpening     = "‘“（〔［｛〈《「『【｟〘〖〝«([{"         // cl-01
+	jlreqClosing     = "’”）〕］｝〉》」』】｠〙〗〟»)]}、，､,。．｡." // cl-02, cl-06, cl-07
+	jlreqInseparable = "—―…‥〳〴〵"                     // cl-08
+)
+
+// interCharacterGap is true if space may be inserted between characters a and b
+// for inter-character justification. At least one of the characters has to be a
+// CJK character.
+func interCharacterGap(a, b rune) bool {
+	if (!isCJK(a) && !isCJK(b)) || unicode.IsSpace(a) || unicode.IsSpace(b) ||
+		a == utf8.RuneError || b == utf8.RuneError {
+		return false
+	}
+	switch {
+	case strings.ContainsRune(jlreqOpening, a), strings.ContainsRune(jlreqClosing, b):
+		return false
+	case strings.ContainsRune(jlreqInseparable, a) && strings.ContainsRune(jlreqInseparable, b):
+		return false
+	}
+	return true
+}
+
+// isCJK is true for characters of scripts which are justified by inter-character
+// spacing, together with CJK punctuation and fullwidth forms.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// distribute distributes an amount as evenly as possible to slots of limited
+// capacity. It returns the amount for each slot and the amount which could not
+// be distributed.
+func distribute(amount dimen.DU, capacities []dimen.DU) ([]dimen.DU, dimen.DU) {
+	shares := make([]dimen.DU, len(capacities))
+	for amount > 0 {
+		open := 0
+		for i, c := range capacities {
+			if shares[i] < c {
+				open++
+			}
+		}
+		if open == 0 {
+			break
+		}
+		share := amount / dimen.DU(open)
+		if share == 0 {
+			share = 1
+		}
+		for i, c := range capacities {
+			if amount == 0 {
+				break
+			}
+			if shares[i] < c {
+				d := share
+				if d > c-shares[i] {
+					d = c - shares[i]
+				}
+				if d > amount {
+					d = amount
+				}
+				shares[i] += d
+				amount -= d
+			}
+		}
+	}
+	return shares, amount
+}
+
+// contentRange returns the positions of the first and the last non-discardable
+// knot within line [from…to-1]. Knots in between are interior to the line.
+// If the line has no non-discardable knots, the range returned is empty.
+func contentRange(k *khipu.Khipu, from, to int64) (int64, int64) {
+	first, last := to, from-1
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if !cursor.Knot().IsDiscardable() {
+			if first == to {
+				first = cursor.Position()
+			}
+			last = cursor.Position()
+		}
+	}
+	return first, last
+}
+
+// hasInfiniteGlue is true if line [from…to-1] contains infinitely stretchable glue.
+func hasInfiniteGlue(k *khipu.Khipu, from, to int64) bool {
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().Type() == khipu.KTGlue {
+			if g := cursor.AsGlue(); g.MaxW()-g.W() >= dimen.Fil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// justifierForContainer selects a justification strategy for a paragraph,
+// depending on the CSS properties "text-align" and "text-justify" of the paragraph's
+// container. em is the font size and kashida is the width of a kashida in the
+// paragraph's font.
+// Returns nil if no justification should be done, i.e., unless text-align is
+// "justify" or "justify-all". The flag returned is true if the last line of the
+// paragraph should be justified as well, as requested by "justify-all" or by
+// text-align-last.
+func justifierForContainer(c *frame.Container, em, kashida dimen.DU) (linebreak.Justifier, bool) {
+	if c == nil || c.DOMNode() == nil {
+		return nil, false
+	}
+	styles := c.DOMNode().ComputedStyles()
+	align := styles.GetPropertyValue("text-align")
+	if align != "justify" && align != "justify-all" {
+		return nil, false
+	}
+	last := align == "justify-all" || styles.GetPropertyValue("text-align-last") == "justify"
+	return justifierForTextJustify(styles.GetPropertyValue("text-justify"), em, kashida), last
+}
+
+// justifierForTextJustify selects a justification strategy for a value of CSS
+// property "text-justify".
+func justifierForTextJustify(textJustify style.Property, em, kashida dimen.DU) linebreak.Justifier {
+	switch textJustify {
+	case "none":
+		return nil
+	case "inter-word":
+		return SpaceJustifier{}
+	case "inter-character":
+		return InterCharacterJustifier{MaxPerGap: em / 4}
+	case "kashida":
+		tracer().Debugf("paragraph uses kashida justification")
+		return NewKashidaJustifier(kashida)
+	}
+	return NewMultiLevelJustifier(em, kashida)
+}
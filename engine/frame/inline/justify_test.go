@@ -0,0 +1,131 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestDistribute(t *testing.T) {
+	shares, rest := distribute(10, []dimen.DU{2, 10, 10})
+	if shares[0] != 2 || shares[1] != 4 || shares[2] != 4 || rest != 0 {
+		t.Errorf("expected shares [2 4 4] and no rest, have %v and %d", shares, rest)
+	}
+	shares, rest = distribute(10, []dimen.DU{2, 3})
+	if shares[0] != 2 || shares[1] != 3 || rest != 5 {
+		t.Errorf("expected shares [2 3] and a rest of 5, have %v and %d", shares, rest)
+	}
+}
+
+func TestSpaceJustify(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, dimen.PT)) // leading glue is not stretched
+	k.AppendKnot(khipu.NewTextBox("Hello", 0))
+	k.AppendKnot(khipu.NewGlue(dimen.PT, dimen.PT/2, 2*dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("World", 0))
+	_, rest := SpaceJustifier{}.Justify(k, 0, 4, 3*dimen.PT)
+	if rest != dimen.PT {
+		t.Errorf("expected 1pt of excess to be left, have %s", rest)
+	}
+	if w, _, _ := k.Measure(0, 4); w != 4*dimen.PT {
+		t.Errorf("expected line to be 4pt wide, is %s", w)
+	}
+	_, rest = SpaceJustifier{}.Justify(k, 0, 4, -dimen.PT)
+	if rest != 0 {
+		t.Errorf("expected line to shrink by 1pt, %s left", rest)
+	}
+}
+
+func TestMultiLevelJustify(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("日本", 0))
+	k.AppendKnot(khipu.Penalty(0))
+	k.AppendKnot(khipu.NewTextBox("語", 0))
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("كتب", 0))
+	mlj := NewMultiLevelJustifier(4*dimen.PT, dimen.PT)
+	end, rest := mlj.Justify(k, 0, 5, 4*dimen.PT)
+	if end != 6 {
+		t.Errorf("expected a kern to be inserted between CJK characters, line end is %d", end)
+	}
+	if rest != 0 {
+		t.Errorf("expected excess to be absorbed, %s left", rest)
+	}
+	if w, _, _ := k.Measure(0, end); w != 5*dimen.PT {
+		t.Errorf("expected line to be 5pt wide, is %s", w)
+	}
+	// the last line of a paragraph is not justified
+	k = khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("Hello", 0))
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, dimen.PT))
+	k.AppendKnot(khipu.NewFill(2))
+	if _, rest = mlj.Justify(k, 0, 3, 4*dimen.PT); rest != 4*dimen.PT {
+		t.Errorf("expected last line to stay unjustified, %s left", rest)
+	}
+}
+
+func TestInterCharacterJustify(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	for i, s := range []string{"日", "本", "「語", "」。", "Go"} {
+		if i > 0 {
+			k.AppendKnot(khipu.Penalty(0))
+		}
+		k.AppendKnot(khipu.NewTextBox(s, 0))
+	}
+	end, rest := InterCharacterJustifier{MaxPerGap: 2 * dimen.PT}.Justify(k, 0, 9, 3*dimen.PT)
+	if end != 12 || rest != 0 {
+		t.Fatalf("expected 3 kerns to absorb the excess, have line end %d and rest %s", end, rest)
+	}
+	for _, pos := range []int64{1, 4, 9} { // after 日, before 「, and before Latin text
+		cursor := khipu.NewCursorAt(k, pos)
+		if !cursor.Next() || cursor.Knot().Type() != khipu.KTKern || cursor.Knot().W() != dimen.PT {
+			t.Errorf("expected kern of 1pt at position %d, have %v", pos, cursor.Knot())
+		}
+	}
+	if !interCharacterGap('語', '「') || interCharacterGap('「', '語') || interCharacterGap('語', '。') {
+		t.Errorf("expected no space after opening brackets and before full stops")
+	}
+	if interCharacterGap('…', '…') || interCharacterGap('a', 'b') {
+		t.Errorf("expected no space between inseparable or Western characters")
+	}
+}
+
+func TestJustifyLineRange(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("Hello", 0)) // previous line
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("World", 6)) // line starts here
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, 2*dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("Wide", 12))
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, 2*dimen.PT)) // trailing glue
+	if first, last := contentRange(k, 2, 6); first != 2 || last != 4 {
+		t.Errorf("expected content of line to span knots 2…4, is %d…%d", first, last)
+	}
+	_, rest := SpaceJustifier{}.Justify(k, 2, 6, 3*dimen.PT)
+	if rest != dimen.PT {
+		t.Errorf("expected 1pt of excess to be left, have %s", rest)
+	}
+	if w, _, _ := k.Measure(0, 2); w != dimen.PT {
+		t.Errorf("expected previous line to stay unchanged, glue is %s", w)
+	}
+	// justified last line
+	k.AppendKnot(khipu.NewFill(2))
+	fixParfillskip(k, 2, 7)
+	if hasInfiniteGlue(k, 2, 7) {
+		t.Errorf("expected parfillskip to be replaced for a justified last line")
+	}
+}
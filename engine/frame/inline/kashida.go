@@ -0,0 +1,192 @@
+package inline
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Kashida justification -------------------------------------------------
+
+// tatweel is the Unicode character used for kashidas.
+const tatweel = 'ـ'
+
+// KashidaJustifier is a justification strategy for Arabic text. Instead of
+// stretching inter-word glue only, it lengthens words by inserting kashidas
+// (tatweel) between joining letters. Existing kashidas will be lengthened.
+//
+// Kashidas are inserted in whole units of width Kashida. The part of a line's
+// excess width which cannot be covered by kashidas is left for the glue. Lines
+// which have to shrink are not handled.
+type KashidaJustifier struct {
+	Kashida    dimen.DU // width of a single kashida
+	MaxPerWord int      // maximum number of kashidas to insert into a word, 0 for no limit
+}
+
+var _ linebreak.Justifier = KashidaJustifier{}
+
+// NewKashidaJustifier creates a kashida justification strategy for kashidas of
+// width w, inserting no more than 3 kashidas into a single word.
+func NewKashidaJustifier(w dimen.DU) KashidaJustifier {
+	return KashidaJustifier{Kashida: w, MaxPerWord: 3}
+}
+
+// Justify is part of interface linebreak.Justifier.
+// It does not insert knots, but lengthens the text boxes of a line.
+func (kj KashidaJustifier) Justify(k *khipu.Khipu, from, to int64, excess dimen.DU) (int64, dimen.DU) {
+	if kj.Kashida <= 0 || excess < kj.Kashida {
+		return to, excess
+	}
+	var boxes []*khipu.TextBox
+	var positions []int
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().Type() != khipu.KTTextBox {
+			continue
+		}
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if !ok {
+			continue
+		}
+		if at := kashidaPosition(box.Text()); at >= 0 {
+			boxes = append(boxes, box)
+			positions = append(positions, at)
+		}
+	}
+	if len(boxes) == 0 {
+		return to, excess
+	}
+	// distribute kashidas evenly among the words of the line
+	counts := make([]int, len(boxes))
+	n := int(excess / kj.Kashida)
+	for inserted := true; n > 0 && inserted; {
+		inserted = false
+		for i := 0; i < len(counts) && n > 0; i++ {
+			if kj.MaxPerWord > 0 && counts[i] >= kj.MaxPerWord {
+				continue
+			}
+			counts[i]++
+			n--
+			inserted = true
+		}
+	}
+	for i, box := range boxes {
+		if counts[i] > 0 {
+			box.Insert(positions[i], strings.Repeat(string(tatweel), counts[i]),
+				dimen.DU(counts[i])*kj.Kashida)
+			excess -= dimen.DU(counts[i]) * kj.Kashida
+		}
+	}
+	tracer().Debugf("kashida justification inserted kashidas into %d words", len(boxes))
+	return to, excess
+}
+
+// kashidaPosition finds a position within a word where a kashida may be inserted,
+// i.e., the byte position between two joining letters. If the word already contains
+// a kashida, the position after it is used. Otherwise we use the last opportunity, as
+// kashidas are preferably placed towards the end of a word.
+//
+// Returns -1 if there is no opportunity for a kashida.
+func kashidaPosition(word string) int {
+	at := -1
+	var prev rune // previous non-transparent letter
+	for i, r := range word {
+		jt := arabicJoiningType(r)
+		if jt == joinTransparent {
+			continue // marks stay with their letter, as we insert in front of the next one
+		}
+		if prev != 0 && joinsLeft(prev) && joinsRight(r) && !isLamAlef(prev, r) {
+			at = i
+			if prev == tatweel {
+				return at
+			}
+		}
+		prev = r
+	}
+	return at
+}
+
+type joiningType int8
+
+// Arabic joining types, see Unicode ArabicShaping.txt
+const (
+	joinNone        joiningType = iota // non-joining (U)
+	joinRight                          // joins to the preceding letter only (R)
+	joinDual                           // joins on both sides (D)
+	joinCausing                        // tatweel (C)
+	joinTransparent                    // marks (T)
+)
+
+// arabicJoiningType classifies letters of the Arabic block. This is a heuristic
+// and does not cover the Arabic supplement and extension blocks.
+func arabicJoiningType(r rune) joiningType {
+	switch {
+	case r == tatweel:
+		return joinCausing
+	case r >= 0x064b && r <= 0x065f, r == 0x0670, r >= 0x06d6 && r <= 0x06ed:
+		return joinTransparent
+	case r == 0x0621, r == 0x0674:
+		return joinNone
+	case r >= 0x0622 && r <= 0x0625, r == 0x0627, r == 0x0629,
+		r >= 0x062f && r <= 0x0632, r == 0x0648,
+		r >= 0x0671 && r <= 0x0677 && r != 0x0674,
+		r >= 0x0688 && r <= 0x0699, r == 0x06c0, r >= 0x06c3 && r <= 0x06cb,
+		r == 0x06cd, r == 0x06cf, r == 0x06d2, r == 0x06d3:
+		return joinRight
+	case r >= 0x0620 && r <= 0x064a, r == 0x066e, r == 0x066f,
+		r >= 0x0678 && r <= 0x06bf, r == 0x06c1, r == 0x06c2, r == 0x06cc,
+		r == 0x06ce, r == 0x06d0, r == 0x06d1:
+		return joinDual
+	}
+	return joinNone
+}
+
+func joinsLeft(r rune) bool {
+	jt := arabicJoiningType(r)
+	return jt == joinDual || jt == joinCausing
+}
+
+func joinsRight(r rune) bool {
+	jt := arabicJoiningType(r)
+	return jt == joinDual || jt == joinRight || jt == joinCausing
+}
+
+// isLamAlef is true for lam followed by an alef, which will be shaped as a ligature.
+func isLamAlef(r1, r2 rune) bool {
+	return r1 == 0x0644 && (r2 == 0x0627 || (r2 >= 0x0622 && r2 <= 0x0625 && r2 != 0x0624))
+}
+
+// KashidaWidthFromFont returns the width of a kashida for font otf at size em.
+// If the font contains a JSTF table listing extender glyphs for Arabic, the first
+// one is used. Otherwise the glyph for U+0640 (tatweel) is used. If the font
+// contains neither, false is returned.
+func KashidaWidthFromFont(otf *ot.Font, em dimen.DU) (dimen.DU, bool) {
+	if otf == nil {
+		return 0, false
+	}
+	gid := ot.GlyphIndex(0)
+	if table := otf.Table(ot.T("JSTF")); table != nil {
+		if script, ok := table.Self().AsJstf().Script(ot.T("arab")); ok {
+			if ext := script.ExtenderGlyphs(); len(ext) > 0 {
+				gid = ext[0]
+			}
+		}
+	}
+	if gid == 0 {
+		gid = otquery.GlyphIndex(otf, tatweel)
+	}
+	if gid == 0 {
+		return 0, false
+	}
+	upem := otquery.FontMetrics(otf).UnitsPerEm
+	if upem == 0 {
+		return 0, false
+	}
+	adv := otquery.GlyphMetrics(otf, gid, 0).Advance
+	return opentype.UnitsToDimen(adv, upem, em), true
+}
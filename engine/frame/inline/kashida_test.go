@@ -0,0 +1,67 @@
+package inline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestKashidaPosition(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	for i, test := range []struct {
+		word string
+		at   int
+	}{
+		{"كتب", 4},       // before final beh
+		{"دار", -1},      // dal and alef do not join to the left
+		{"لا", -1},       // lam-alef ligature
+		{"بـيت", 4},      // lengthen existing kashida
+		{"مُحَمَّد", 14}, // after the marks of meem
+		{"Hello", -1},
+	} {
+		if at := kashidaPosition(test.word); at != test.at {
+			t.Errorf("test #%d: expected kashida position for %q to be %d, is %d", i, test.word, test.at, at)
+		}
+	}
+}
+
+func TestKashidaJustify(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("كتب", 0))
+	k.AppendKnot(khipu.NewGlue(dimen.PT, 0, dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("سلام", 7))
+	end, rest := NewKashidaJustifier(dimen.PT).Justify(k, 0, 3, 3*dimen.PT+dimen.PT/2)
+	if end != 3 {
+		t.Errorf("expected kashida justification to not insert knots, line end is %d", end)
+	}
+	if rest != dimen.PT/2 {
+		t.Errorf("expected 0.5pt to be left for glue, have %s", rest)
+	}
+	if n := strings.Count(k.Text(0, 3), string(tatweel)); n != 3 {
+		t.Errorf("expected 3 kashidas to be inserted, have %d in %q", n, k.Text(0, 3))
+	}
+}
+
+func TestKashidaWidthFromFallbackFont(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	otf, em := paragraphFont(nil)
+	if otf == nil {
+		t.Fatalf("expected fallback font for paragraph without a container")
+	}
+	if em != defaultFontSize {
+		t.Errorf("expected default font size for paragraph, is %s", em)
+	}
+	if _, ok := KashidaWidthFromFont(otf, em); ok { // Go Sans has no Arabic glyphs
+		t.Errorf("expected fallback font to have no tatweel")
+	}
+}
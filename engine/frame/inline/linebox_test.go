@@ -35,7 +35,7 @@ func TestBox1(t *testing.T) {
 	}
 	t.Logf("inner text of DOM = '%s'", para.Raw().String())
 	regs := parameters.NewTypesettingRegisters()
-	k, err := khipu.EncodeParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
+	k, err := khipu.EncodeStyledParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -71,13 +71,13 @@ func TestBreakParagraph(t *testing.T) {
 	para, _ := InnerParagraphText(p)
 	t.Logf("inner text of DOM = '%s'", para.Raw().String())
 	regs := parameters.NewTypesettingRegisters()
-	_, _ = khipu.EncodeParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
+	_, _ = khipu.EncodeStyledParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
 	//t.Logf("khipu = %v", k)
 	pbox := boxtree.NewPrincipalBox(p, css.BlockMode)
 	pbox.Box.W = css.SomeDimen(60 * 10 * dimen.BP)
 	pbox.Box.H = css.SomeDimen(10 * dimen.CM)
 	//var err error
-	gtrace.EngineTracer.SetTraceLevel(tracing.LevelDebug)
+	tracer().SetTraceLevel(tracing.LevelDebug)
 	// pbox, err = BreakParagraph(k, pbox, regs)
 	// if err != nil {
 	// 	t.Fatal(err.Error())
@@ -100,12 +100,12 @@ func TestInlineBoxes(t *testing.T) {
 	para, _ := InnerParagraphText(p)
 	t.Logf("inner text of DOM = '%s'", para.Raw().String())
 	regs := parameters.NewTypesettingRegisters()
-	_, _ = khipu.EncodeParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
+	_, _ = khipu.EncodeStyledParagraph(para.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
 	//t.Logf("khipu = %v", k)
 	pbox.Box.W = css.SomeDimen(60 * 10 * dimen.BP)
 	pbox.Box.H = css.SomeDimen(10 * dimen.CM)
 	//var err error
-	gtrace.EngineTracer.SetTraceLevel(tracing.LevelDebug)
+	tracer().SetTraceLevel(tracing.LevelDebug)
 	// pbox, err = BreakParagraph(k, pbox, regs)
 	// if err != nil {
 	// 	t.Fatal(err.Error())
@@ -1,7 +1,13 @@
 package inline
 
 import (
+	"sync"
+
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/opentype"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
 	"github.com/npillmayer/tyse/core/parameters"
 	"github.com/npillmayer/tyse/engine/dom"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
@@ -10,6 +16,7 @@ import (
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/firstfit"
+	"github.com/npillmayer/tyse/engine/glyphing"
 	"github.com/npillmayer/tyse/engine/glyphing/monospace"
 	"github.com/npillmayer/tyse/engine/tree"
 )
@@ -31,10 +38,19 @@ type LineBox struct {
 	indent  dimen.DU            // horizontal offset of the text within the line box
 	pos     int64               // start position within the khipu
 	length  int64               // length of the segment for this line
+	ascent  dimen.DU            // height of the line above the baseline
+	descent dimen.DU            // depth of the line below the baseline
+	line    *SetLine            // line with glue set, if the line box results from line breaking
 	context frame.ContextInterf // formatting context
 	//ChildInx uint32      // this box represents a text node at #ChildInx of the principal box
 }
 
+// strut is the minimum height of a line box.
+const strut = 12 * dimen.PT
+
+// NewLineBox creates a line box for the khipu segment of length knots at start.
+// The height of the line box covers all text boxes of the segment, including
+// text boxes with a baseline shift (superscripts, subscripts, etc.).
 func NewLineBox(k *khipu.Khipu, start, length int64, indent dimen.DU) *LineBox {
 	lbox := &LineBox{
 		Box:    frame.InitEmptyBox(&frame.Box{}),
@@ -43,11 +59,34 @@ func NewLineBox(k *khipu.Khipu, start, length int64, indent dimen.DU) *LineBox {
 		length: length,
 		indent: indent,
 	}
-	lbox.Box.H = css.SomeDimen(12 * dimen.PT)
+	h, d := k.MaxHeightAndDepth(start, start+length)
+	if h+d < strut {
+		h = strut - d
+	}
+	lbox.ascent, lbox.descent = h, d
+	lbox.Box.H = css.SomeDimen(h + d)
 	lbox.Payload = lbox
 	return lbox
 }
 
+// Baseline returns the distance from the top of a line box to its baseline.
+func (lbox *LineBox) Baseline() dimen.DU {
+	return lbox.ascent
+}
+
+// Line returns the set line of a line box, i.e., its knots positioned with glue set.
+// Line boxes not resulting from BreakParagraph return nil.
+func (lbox *LineBox) Line() *SetLine {
+	return lbox.line
+}
+
+// Segment returns the khipu of a line box, together with the start position and
+// the length of the segment for this line. Text boxes of the segment keep their
+// baseline shift, which is relative to the baseline of the line box.
+func (lbox *LineBox) Segment() (*khipu.Khipu, int64, int64) {
+	return lbox.khipu, lbox.pos, lbox.length
+}
+
 // DOMNode returns the underlying DOM node for a render tree element.
 // For line boxes, it returns the DOM node corresponding to the parent container,
 // which should be of type PrincipalBox.
@@ -124,62 +163,217 @@ var _ frame.RenderTreeNode = &LineBox{}
 // If an error occurs during line-breaking, a pbox of nil is returned, together with the
 // error value.
 //
-func BreakParagraph(para *Paragraph, box *frame.Box) ([]*frame.Container, error) {
-	//
-	// TODO
-	// find all children with align=left or align=right and collect their boxes
-	// there should be an API for this in frame/layout.
-	//
-	//leftAlign, rightAlign := collectFloatBoxes(pbox)
-	//parshape := OutlineParshape(pbox, leftAlign, rightAlign)
-	parshape := OutlineParshape(box, nil, nil)
+// If floats is non-nil, lines will flow around the floats placed so far. box then
+// has to be positioned in the coordinate system of the flow root of floats.
+//
+func BreakParagraph(para *Paragraph, box *frame.Box, floats *frame.FloatPlacer) ([]*frame.Container, error) {
+	leading := para.leading()
+	var parshape linebreak.ParShape
+	if floats != nil && len(floats.Floats()) > 0 {
+		parshape = FloatParShape(box, floats, leading)
+	} else {
+		parshape = OutlineParshape(box, nil, nil)
+	}
 	if parshape == nil {
 		tracer().Errorf("could not create a parshape for principal box")
+	} else {
+		indent := para.Style.FirstLineIndent(parshape.LineLength(0))
+		if para.Initial != nil {
+			parshape = para.Initial.makeRoom(parshape, para.Em/4, para.Style.RTL)
+		}
+		parshape = indentFirstLine(parshape, indent, para.Style.RTL)
 	}
+	params := para.Style.Parameters()
 	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(para.Khipu), 10*dimen.BP, 0)
-	breakpoints, err := firstfit.BreakParagraph(cursor, parshape, nil)
+	breakpoints, err := firstfit.BreakParagraph(cursor, parshape, params)
 	if err != nil {
 		return nil, err
 	}
 	tracer().Debugf("text broken up with %d breaks: %v", len(breakpoints), breakpoints)
+	decoMetrics := DecorationMetricsFromFont(para.Font, para.Em)
 	//
-	// TODO
 	// assemble the broken line segments into anonymous line boxes
 	tracer().Debugf("     |---------+---------+---------+---------+---------50--------|")
 	j := int64(0)
+	shift := int64(0) // knots inserted by justification shift the following breakpoints
 	var lines []*frame.Container
+	var prev *SetLine
 	for i := 1; i < len(breakpoints); i++ {
-		pos := breakpoints[i].Position()
+		pos := breakpoints[i].Position() + shift
+		ResolveTabs(para.Khipu, j, pos, linebreak.LineIndent(parshape, int32(i-1)), para.Style.Tabs)
+		// lines before forced breaks, e.g. for preserved newlines, are set as last lines
+		last := i == len(breakpoints)-1 || hasFill(para.Khipu, j, pos)
+		if para.Justifier != nil && (!last || para.JustifyLast) {
+			if last { // justified last line: \parfillskip=0pt
+				fixParfillskip(para.Khipu, j, pos)
+			}
+			end := justifyLine(para, parshape, int32(i-1), j, pos)
+			shift += end - pos
+			pos = end
+		} else if last && para.Style.dropsParfillskip() { // centered or ragged-left last line
+			fixParfillskip(para.Khipu, j, pos)
+		}
 		tracer().Debugf("%3d: %s", i, para.Khipu.Text(j, pos))
 		l := pos - j
-		indent := dimen.DU(0) // TODO derive from parshape
-		linebox := NewLineBox(para.Khipu, breakpoints[i].Position(), l, indent)
+		indent := linebreak.LineIndent(parshape, int32(i-1))
+		linebox := NewLineBox(para.Khipu, j, l, indent)
 		linebox.Box.W = box.W
+		linebox.line = SetProtrudingLineOf(para.Khipu, j, pos, int32(i-1), parshape, params,
+			para.Protrusion)
+		ReorderLine(linebox.line, para.bidiDirection, para.Style.RTL)
+		linebox.line.Ruby = placeRubyText(linebox.line, para.ruby)
+		if i == 1 && para.Marker != nil {
+			hangMarker(linebox.line, para.Marker, para.Em/2, para.Style.RTL)
+		}
+		if i == 1 && para.Initial != nil {
+			linebox.line.Initial = para.Initial
+			if a := para.Initial.ascent(leading); a > linebox.line.Ascent { // raised initial letter
+				linebox.line.Ascent, linebox.ascent = a, a
+				linebox.Box.H = css.JustDimen(a + linebox.descent)
+			}
+		}
+		PlaceBaseline(linebox.line, prev, leading)
+		if para.Initial != nil && i <= para.Initial.Sink {
+			para.Initial.Baseline = linebox.line.Baseline
+		}
+		linebox.line.Deco = Decorations(linebox.line, decoMetrics)
 		lines = append(lines, &linebox.Container)
 		//linebox.AppendToPrincipalBox(pbox)
-		j = breakpoints[i].Position()
+		prev, j = linebox.line, pos
 	}
 	//
 	return lines, nil
 }
 
+// leading returns the distance between the baselines of a paragraph's lines. It is
+// set by CSS property line-height or, for line-height "normal", recommended by the
+// line metrics of the paragraph's font. Paragraphs without font information use
+// the strut.
+func (para *Paragraph) leading() dimen.DU {
+	if para.Style.LineHeight > 0 {
+		return para.Style.LineHeight
+	}
+	if para.Font == nil || para.Em == 0 {
+		return strut
+	}
+	metrics := otquery.LineMetrics(para.Font, otquery.AutoMetrics)
+	if l := opentype.UnitsToDimen(metrics.LineHeight(), metrics.UnitsPerEm, para.Em); l > 0 {
+		return l
+	}
+	return strut
+}
+
+// justifyLine consults the justification hook of a paragraph for the line spanning
+// knots [from…to-1], with lineno counting from 0. As the hook may insert knots, it
+// returns the new end position of the line. The remaining excess width will be
+// distributed to the glue of the line by SetLineOf.
+func justifyLine(para *Paragraph, parshape linebreak.ParShape, lineno int32, from, to int64) int64 {
+	w, _, _ := para.Khipu.Measure(from, to)
+	excess := parshape.LineLength(lineno) - w
+	end, rest := para.Justifier.Justify(para.Khipu, from, to, excess)
+	tracer().Debugf("justification of line %d leaves %.2fpt for glue", lineno, rest.Points())
+	return end
+}
+
+// fixParfillskip replaces the infinitely stretchable glue ending the last line
+// [from…to-1] of a paragraph with glue of zero width, as TeX users do with
+// \parfillskip=0pt. Otherwise the parfillskip would absorb all of the excess
+// width of the line.
+func fixParfillskip(k *khipu.Khipu, from, to int64) {
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().Type() == khipu.KTGlue {
+			if g := cursor.AsGlue(); g.MaxW()-g.W() >= dimen.Fil {
+				cursor.ReplaceKnot(khipu.NewGlue(0, 0, 0))
+			}
+		}
+	}
+}
+
+// hasFill is true if line [from…to-1] contains infinitely stretchable glue, as
+// lines ending a paragraph or ending with a forced break do.
+func hasFill(k *khipu.Khipu, from, to int64) bool {
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().Type() == khipu.KTGlue {
+			if g := cursor.AsGlue(); g.MaxW()-g.W() >= dimen.Fil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func EncodeTextOfParagraph(c *frame.Container) (*Paragraph, []*frame.Container, error) {
 	paraText, blocks, err := paragraphTextFromBox(c)
 	if err != nil {
 		tracer().Errorf(err.Error())
 		return nil, []*frame.Container{}, err
 	}
-	paraText.Regs = parameters.NewTypesettingRegisters()
-	paraText.Regs = adaptTypesettingRegisters(paraText.Regs, c)
-	paraText.Khipu, err = khipu.EncodeParagraph(paraText.Paragraph, 0,
-		monospace.Shaper(11*dimen.PT, nil), nil, paraText.Regs)
+	paraText.Font, paraText.Em = paragraphFont(c)
+	paraText.Style = textStyleForContainer(c, paraText.Em)
+	paraText.Regs = paraText.Style.registers(parameters.NewTypesettingRegisters())
+	paraText.Khipu, err = khipu.EncodeStyledParagraph(paraText.Paragraph, 0,
+		monospace.Shaper(11*dimen.PT, nil), nil, paraText.Regs,
+		khipu.WithSpacing(paraText.Style.Spacing), khipu.WithWrapping(paraText.Style.Wrapping()))
 	if err != nil || paraText.Khipu == nil {
 		tracer().Errorf("lines: khipu resulting from paragraph is nil")
 		return nil, []*frame.Container{}, err
 	}
+	sizeReplacedElements(paraText)
+	setRubyAnnotations(paraText)
+	kashida, ok := KashidaWidthFromFont(paraText.Font, paraText.Em)
+	if !ok { // font has no tatweel, fall back to 1 em
+		kashida = paraText.Em
+	}
+	paraText.Justifier, paraText.JustifyLast = justifierForContainer(c, paraText.Em, kashida)
+	paraText.Marker = outsideMarker(c, paraText.Regs)
+	paraText.Initial = initialLetter(c, paraText)
 	return paraText, blocks, err
 }
 
+// defaultFontSize is the font size of paragraphs without a font selected. It matches
+// the size of the monospace shaper measuring the text.
+const defaultFontSize = 11 * dimen.PT
+
+// paragraphFont returns the OpenType font and the font size for the paragraph of
+// container c. If no font is selected for c, the fallback font is returned.
+// The font is nil if it cannot be parsed. The font size is adjusted to CSS
+// property font-size-adjust, using the x-height of the font.
+func paragraphFont(c *frame.Container) (*ot.Font, dimen.DU) {
+	sf, em := font.FallbackFont(), defaultFontSize
+	adjust := 0.0
+	if c != nil && c.DOMNode() != nil {
+		set := frame.StyleSet{Props: c.DOMNode().ComputedStyles().Styles()}
+		if tc := set.Font(); tc != nil && tc.ScalableFontParent() != nil {
+			sf, em = tc.ScalableFontParent(), dimen.DU(float32(dimen.PT)*tc.PtSize())
+		}
+		adjust = set.FontSizeAdjust()
+	}
+	otf := openTypeFont(sf)
+	if adjust > 0 && otf != nil {
+		em = dimen.DU(glyphing.AdjustedSize(float32(em), adjust, glyphing.AspectValue(otf)))
+	}
+	return otf, em
+}
+
+// openTypeFonts caches parsed fonts: *font.ScalableFont ⇒ *ot.Font
+var openTypeFonts sync.Map
+
+// openTypeFont parses the binary of a scalable font, caching the result.
+func openTypeFont(sf *font.ScalableFont) *ot.Font {
+	if otf, ok := openTypeFonts.Load(sf); ok {
+		return otf.(*ot.Font)
+	}
+	otf, err := ot.Parse(sf.Binary)
+	if err != nil {
+		tracer().Errorf("cannot parse font %s: %v", sf.Fontname, err)
+		return nil
+	}
+	actual, _ := openTypeFonts.LoadOrStore(sf, otf)
+	return actual.(*ot.Font)
+}
+
 func XFindParaWidthAndText(pbox *ParagraphBox, rootctx frame.ContextInterf) (
 	[]*frame.Container, frame.ContextInterf, error) {
 	//
@@ -188,9 +382,9 @@ func XFindParaWidthAndText(pbox *ParagraphBox, rootctx frame.ContextInterf) (
 		tracer().Errorf(err.Error())
 		return []*frame.Container{}, rootctx, err
 	}
-	regs := parameters.NewTypesettingRegisters()
-	regs = adaptTypesettingRegisters(regs, &pbox.Container)
-	k, err := khipu.EncodeParagraph(paraText.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
+	_, em := paragraphFont(&pbox.Container)
+	regs := textStyleForContainer(&pbox.Container, em).registers(parameters.NewTypesettingRegisters())
+	k, err := khipu.EncodeStyledParagraph(paraText.Paragraph, 0, monospace.Shaper(11*dimen.PT, nil), nil, regs)
 	if err != nil || k == nil {
 		tracer().Errorf("lines: khipu resulting from paragraph is nil")
 		return []*frame.Container{}, rootctx, err
@@ -227,8 +421,29 @@ func XFindParaWidthAndText(pbox *ParagraphBox, rootctx frame.ContextInterf) (
 	*/
 }
 
-func adaptTypesettingRegisters(regs *parameters.TypesettingRegisters, c *frame.Container) *parameters.TypesettingRegisters {
-	return regs
+// spacingForContainer maps CSS properties letter-spacing and word-spacing of a
+// container to spacing adjustments for its khipu. Font-relative values are
+// resolved for a font size of em, which selects the optical tracking as well.
+func spacingForContainer(c *frame.Container, em dimen.DU) khipu.Spacing {
+	sp := khipu.Spacing{FontSize: em}
+	if c == nil || c.DOMNode() == nil {
+		return sp
+	}
+	styles := c.DOMNode().ComputedStyles()
+	spacing := func(key string) dimen.DU {
+		p := styles.GetPropertyValue(key)
+		if p == "normal" {
+			return 0
+		}
+		var d dimen.DU
+		if css.DimenOption(p).ScaleFromFont(em).Match().Just(&d) == nil {
+			tracer().Debugf("unsupported value for %s: %q", key, p)
+		}
+		return d
+	}
+	sp.LetterSpacing = spacing("letter-spacing")
+	sp.WordSpacing = spacing("word-spacing")
+	return sp
 }
 
 func addLineHeights(pbox *boxtree.PrincipalBox) dimen.DU {
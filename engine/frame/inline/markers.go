@@ -0,0 +1,87 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/glyphing/monospace"
+)
+
+// --- List markers ----------------------------------------------------------
+
+// The markers of list items are set by package boxtree. The first paragraph of a
+// list item carries its marker: "inside" markers are part of the paragraph's text,
+// followed by a space, whereas "outside" markers hang before the first line,
+// separated from it by half an em.
+
+// listItemOf returns the list item box, if c is the box of a list item or the
+// anonymous box wrapping the first run of inline content of a list item. For
+// other boxes, it returns nil.
+func listItemOf(c *frame.Container) *boxtree.PrincipalBox {
+	if c == nil {
+		return nil
+	}
+	if pbox, ok := c.RenderNode().(*boxtree.PrincipalBox); ok {
+		if pbox.Marker == "" {
+			return nil
+		}
+		return pbox
+	}
+	if !boxtree.IsAnonymous(c.RenderNode()) {
+		return nil
+	}
+	parent := c.TreeNode().Parent()
+	if first, ok := parent.Child(0); !ok || first != c.TreeNode() {
+		return nil
+	}
+	if pbox := boxtree.TreeNodeAsPrincipalBox(parent); pbox != nil && pbox.Marker != "" {
+		return pbox
+	}
+	return nil
+}
+
+// insideMarker returns the text to start the paragraph of c with, if c carries
+// an inside marker of a list item.
+func insideMarker(c *frame.Container) string {
+	if item := listItemOf(c); item != nil && item.MarkerInside {
+		return item.Marker + " "
+	}
+	return ""
+}
+
+// outsideMarker returns a measured text box for the outside marker of a list
+// item, if the paragraph of c carries one. Otherwise it returns nil.
+func outsideMarker(c *frame.Container, regs *parameters.TypesettingRegisters) *khipu.TextBox {
+	item := listItemOf(c)
+	if item == nil || item.MarkerInside {
+		return nil
+	}
+	k, err := khipu.EncodeText(item.Marker, khipu.WithRegisters(regs),
+		khipu.WithShaper(monospace.Shaper(defaultFontSize, nil)))
+	if err != nil {
+		tracer().Errorf("cannot encode list marker %q: %v", item.Marker, err)
+		return nil
+	}
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		if box, ok := cursor.Knot().(*khipu.TextBox); ok {
+			return box
+		}
+	}
+	return nil
+}
+
+// hangMarker prepends an outside marker to the items of the first line of a
+// paragraph. The marker ends gap before the start of the line, or, for
+// right-to-left paragraphs, starts gap after its end.
+func hangMarker(line *SetLine, marker *khipu.TextBox, gap dimen.DU, rtl bool) {
+	x := -marker.Width - gap
+	if rtl {
+		x = line.Length + gap
+	}
+	item := PositionedKnot{Knot: marker, X: x, W: marker.Width}
+	line.Items = append([]PositionedKnot{item}, line.Items...)
+	line.Marker = marker
+}
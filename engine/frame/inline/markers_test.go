@@ -0,0 +1,27 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestHangMarker(t *testing.T) {
+	marker := khipu.NewTextBox("1.", 0)
+	marker.Width = 10 * dimen.PT
+	text := khipu.NewTextBox("Item", 0)
+	line := &SetLine{Length: 100 * dimen.PT, Items: []PositionedKnot{{Knot: text, W: 20 * dimen.PT}}}
+	hangMarker(line, marker, 5*dimen.PT, false)
+	if len(line.Items) != 2 || line.Items[0].Knot != marker {
+		t.Fatalf("expected marker to be the first item of the line, have %d items", len(line.Items))
+	}
+	if x := line.Items[0].X; x != -15*dimen.PT {
+		t.Errorf("expected marker to hang at -15pt, is at %s", x)
+	}
+	line.Items = line.Items[1:]
+	hangMarker(line, marker, 5*dimen.PT, true)
+	if x := line.Items[0].X; x != 105*dimen.PT {
+		t.Errorf("expected marker of right-to-left line at 105pt, is at %s", x)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/npillmayer/cords"
 	"github.com/npillmayer/cords/styled"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
 	"github.com/npillmayer/tyse/core/parameters"
 	"github.com/npillmayer/tyse/engine/dom"
 	"github.com/npillmayer/tyse/engine/dom/style"
@@ -14,6 +16,7 @@ import (
 	"github.com/npillmayer/tyse/engine/frame"
 	"github.com/npillmayer/tyse/engine/frame/boxtree"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
 	"github.com/npillmayer/tyse/engine/tree"
 	"github.com/npillmayer/uax/bidi"
 	"golang.org/x/net/html"
@@ -93,6 +96,16 @@ type Paragraph struct {
 	irs               infoIRS      // info about Bidi Isolating Run Sequences
 	Khipu             *khipu.Khipu // knot-encoding of the paragraph's text
 	Regs              *parameters.TypesettingRegisters
+	Style             TextStyle           // CSS text properties of the paragraph
+	Justifier         linebreak.Justifier // optional hook for justifying lines, may be nil
+	JustifyLast       bool                // justify the last line as well (CSS text-align-last)
+	Font              *ot.Font            // OpenType font of the paragraph, may be nil
+	Em                dimen.DU            // font size of the paragraph
+	Marker            *khipu.TextBox      // outside marker of a list item, or nil
+	Initial           *InitialLetter      // enlarged first letter, or nil
+	Protrusion        *Protrusion         // optical margin alignment, or nil
+	replaced          []replacedElement   // replaced elements within the text, e.g. images
+	ruby              []*rubyAnnotation   // ruby annotations of base text
 }
 
 type infoIRS struct {
@@ -117,30 +130,39 @@ func paragraphTextFromBox(c *frame.Container) (*Paragraph, []*frame.Container, e
 		},
 	}
 	var innerText *styled.Text // TODO set boxText()
-	innerText, blocks, err := containedText(c, &para.irs)
+	innerText, blocks, err := containedText(c, &para.irs, &para.replaced, &para.ruby)
 	if err != nil {
 		return nil, nil, err
 	}
 	tracer().Debugf("para from container: inner text = '%s'", innerText.Raw())
-	eBidiDir, _ := findEmbeddingBidiDirection(c.DOMNode())
+	eBidiDir, explicit := findEmbeddingBidiDirection(c.DOMNode())
+	if !explicit && eBidiDir == bidi.LeftToRight { // use first strong character, see UAX#9 P2 and P3
+		eBidiDir, _ = khipu.ParagraphDirection(innerText.Raw().String(), para.bidiMarkup())
+	}
 	para.Paragraph, err = styled.ParagraphFromText(innerText, 0, innerText.Raw().Len(), eBidiDir,
 		para.bidiMarkup())
 	return para, blocks, err
 }
 
-func containedText(c *frame.Container, irs *infoIRS) (*styled.Text, []*frame.Container, error) {
+func containedText(c *frame.Container, irs *infoIRS, replaced *[]replacedElement,
+	ruby *[]*rubyAnnotation) (*styled.Text, []*frame.Container, error) {
+	//
 	if c == nil {
 		return styled.TextFromString(""), []*frame.Container{}, cords.ErrIllegalArguments
 	}
 	b := styled.NewTextBuilder()
 	var blocks []*frame.Container
 	tracer().Debugf("collecting contained text of [%s]", boxtree.ContainerName(c))
-	collectContainedText(c, c, b, irs, blocks)
+	if marker := insideMarker(c); marker != "" {
+		leaf := &pLeaf{element: c.DOMNode(), length: uint64(len(marker)), content: marker}
+		b.Append(leaf, frame.StyleSet{Props: c.DOMNode().ComputedStyles().Styles()})
+	}
+	collectContainedText(c, c, b, irs, replaced, ruby, blocks)
 	return b.Text(), blocks, nil
 }
 
 func collectContainedText(root, c *frame.Container, b *styled.TextBuilder, irs *infoIRS,
-	blocks []*frame.Container) {
+	replaced *[]replacedElement, ruby *[]*rubyAnnotation, blocks []*frame.Container) {
 	//
 	if c.DOMNode() != nil && c.DOMNode().NodeType() == html.TextNode {
 		leaf := createLeaf(c.DOMNode())
@@ -155,8 +177,22 @@ func collectContainedText(root, c *frame.Container, b *styled.TextBuilder, irs *
 		}
 		//text.Style(styleset, pos, pos+l.Weight())
 		b.Append(leaf, styleset)
+	} else if c != root && boxtree.IsReplaced(c.RenderNode()) {
+		*replaced = append(*replaced, replacedElement{pos: b.Len(), c: c})
+		leaf := &pLeaf{element: c.DOMNode(), length: uint64(len(objectReplacement)), content: objectReplacement}
+		b.Append(leaf, frame.StyleSet{Props: c.DOMNode().ComputedStyles().Styles()})
 	} else if c != root && c.Display.Outer() == css.BlockMode {
 		b.Append(&nonReplacableElementLeaf{c.DOMNode()}, frame.StyleSet{})
+	} else if c != root && isRuby(c) {
+		start := b.Len() // start of the base text of the next annotation
+		for _, childContainer := range c.Context.Contained() {
+			if isRubyText(childContainer) {
+				*ruby = append(*ruby, &rubyAnnotation{from: start, to: b.Len(), text: rubyTextOf(childContainer)})
+				start = b.Len()
+				continue
+			}
+			collectContainedText(root, childContainer, b, irs, replaced, ruby, blocks)
+		}
 	} else {
 		tracer().Debugf("styled paragraph: collect text of <%s>", c.DOMNode().NodeName())
 		if c.Context == nil {
@@ -164,7 +200,7 @@ func collectContainedText(root, c *frame.Container, b *styled.TextBuilder, irs *
 		}
 		children := c.Context.Contained()
 		for _, childContainer := range children {
-			collectContainedText(root, childContainer, b, irs, blocks)
+			collectContainedText(root, childContainer, b, irs, replaced, ruby, blocks)
 		}
 	}
 	// if c.TreeNode().ChildCount() > 0 {
@@ -182,10 +218,11 @@ func createLeaf(n *dom.W3CNode) *pLeaf {
 	for parent != nil && parent.NodeType() != html.ElementNode {
 		parent = parent.ParentNode()
 	}
-	value := n.NodeValue()
+	element := parent.(*dom.W3CNode)
+	value := transformText(n.NodeValue(), element)
 	//T().Debugf("parent of text node = %v, text = '%s'", parent, value)
 	leaf := &pLeaf{
-		element: parent.(*dom.W3CNode),
+		element: element,
 		length:  uint64(len(value)),
 		content: value,
 	}
@@ -353,27 +390,36 @@ func collectText(n *dom.W3CNode, b *styled.TextBuilder, irs *infoIRS) {
 // findEmbeddingBidiDirection finds out style settings which determine the
 // embedding text direction for this HTML node.
 // Bidi directions in HTML may either be set with an attribute `dir` (highest
-// priority) or with CSS property `direction`. We treat L2R as the default,
-// only switching it for explicit setting of R2L.
+// priority) or with CSS property `direction`. We treat L2R as the default.
+// Settings of either `ltr` or `rtl` on the node itself are explicit, an inherited
+// direction is not. Attribute `dir="auto"` does not count as an explicit setting,
+// as the direction has to be found from the text.
 func findEmbeddingBidiDirection(pnode *dom.W3CNode) (eBidiDir bidi.Direction, explicit bool) {
 	if pnode == nil {
 		return
 	}
 	attrset := false
 	if pnode.HasAttributes() {
-		dirattr := pnode.Attributes().GetNamedItem("dir")
-		attrset, explicit = true, true
-		if dirattr.Value() == "rtl" {
-			eBidiDir = bidi.RightToLeft
+		if dirattr := pnode.Attributes().GetNamedItem("dir"); dirattr != nil {
+			switch dirattr.Value() {
+			case "rtl":
+				eBidiDir = bidi.RightToLeft
+				attrset, explicit = true, true
+			case "ltr":
+				attrset, explicit = true, true
+			case "auto":
+				attrset = true
+			}
 		}
 	}
 	if !attrset {
 		var textDir style.Property
-		_ = pnode.ComputedStyles().Styles()
 		textDir = css.GetLocalProperty(pnode.ComputedStyles().Styles(), "direction")
-		if textDir == "rtl" {
-			eBidiDir = bidi.RightToLeft
-			explicit = true
+		switch textDir {
+		case "rtl":
+			return bidi.RightToLeft, true
+		case "ltr":
+			return bidi.LeftToRight, true
 		}
 		textDir = pnode.ComputedStyles().GetPropertyValue("direction")
 		if textDir == "rtl" {
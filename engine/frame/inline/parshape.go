@@ -19,9 +19,11 @@ func OutlineParshape(box *frame.Box, leftAlign, rightAlign []*frame.Box) linebre
 	if polygon == nil {
 		return nil
 	}
+	var w dimen.DU
+	box.ContentWidth().Match().Just(&w)
 	return polygonParshape{
 		lineskip: 12 * dimen.PT,
-		width:    box.ContentWidth().Unwrap(),
+		width:    w,
 		polygon:  polygon,
 	}
 }
@@ -46,6 +48,99 @@ func (pp polygonParshape) LineLength(l int32) dimen.DU {
 	return w
 }
 
+// FloatParShape returns a parshape for a paragraph flowing around the floats
+// placed by placer. box is the content box of the paragraph, with its position
+// given in the coordinates of the flow root of placer.
+func FloatParShape(box *frame.Box, placer *frame.FloatPlacer, lineskip dimen.DU) linebreak.ParShape {
+	var w dimen.DU
+	if box == nil || box.W.Match().Just(&w) == nil {
+		tracer().Errorf("float parshape cannot be calculated for unfixed box")
+		return nil
+	}
+	return floatParshape{
+		placer:   placer,
+		origin:   box.TopL,
+		width:    w,
+		lineskip: lineskip,
+	}
+}
+
+type floatParshape struct {
+	placer   *frame.FloatPlacer
+	origin   dimen.Point // top left corner of the paragraph within the flow root
+	width    dimen.DU
+	lineskip dimen.DU
+}
+
+// LineLength is part of interface ParShape. It returns the width left between
+// floats for line number l.
+// As with polygonParshape, lines are assumed to be lineskip apart.
+func (fp floatParshape) LineLength(l int32) dimen.DU {
+	start, end := fp.lineExtent(l)
+	return end - start
+}
+
+// LineIndent is part of interface IndentingParShape. It returns the offset of
+// line number l from the left edge of the paragraph.
+func (fp floatParshape) LineIndent(l int32) dimen.DU {
+	start, _ := fp.lineExtent(l)
+	return start - fp.origin.X
+}
+
+func (fp floatParshape) lineExtent(l int32) (dimen.DU, dimen.DU) {
+	start, end := fp.origin.X, fp.origin.X+fp.width
+	if fp.placer == nil {
+		return start, end
+	}
+	y := fp.origin.Y + dimen.DU(l)*fp.lineskip
+	left, w := fp.placer.Band(y, fp.lineskip)
+	start, end = max(start, left), min(end, left+w)
+	if end < start {
+		return start, start
+	}
+	return start, end
+}
+
+// indentFirstLine returns a parshape with the first line of parshape indented by
+// indent, as requested by CSS property text-indent. The indent is applied at the
+// start edge of the paragraph, i.e., at the right edge for right-to-left text.
+// Negative values result in a hanging first line.
+func indentFirstLine(parshape linebreak.ParShape, indent dimen.DU, rtl bool) linebreak.ParShape {
+	return indentLines(parshape, indent, 1, rtl)
+}
+
+// indentLines returns a parshape with the first n lines of parshape indented by
+// indent, at the start edge of the paragraph.
+func indentLines(parshape linebreak.ParShape, indent dimen.DU, n int32, rtl bool) linebreak.ParShape {
+	if parshape == nil || indent == 0 || n <= 0 {
+		return parshape
+	}
+	return indentedParshape{base: parshape, indent: indent, lines: n, rtl: rtl}
+}
+
+type indentedParshape struct {
+	base   linebreak.ParShape
+	indent dimen.DU
+	lines  int32 // number of lines to indent
+	rtl    bool  // indent at the right edge
+}
+
+// LineLength is part of interface ParShape.
+func (ip indentedParshape) LineLength(l int32) dimen.DU {
+	if l < ip.lines {
+		return ip.base.LineLength(l) - ip.indent
+	}
+	return ip.base.LineLength(l)
+}
+
+// LineIndent is part of interface IndentingParShape.
+func (ip indentedParshape) LineIndent(l int32) dimen.DU {
+	if l < ip.lines && !ip.rtl {
+		return linebreak.LineIndent(ip.base, l) + ip.indent
+	}
+	return linebreak.LineIndent(ip.base, l)
+}
+
 type isoPolygon struct {
 	stack []isoBox
 }
@@ -60,12 +155,15 @@ func (iso isoPolygon) String() string {
 }
 
 func paragraphPolygon(pbox *frame.Box, leftAlign, rightAlign []*frame.Box) *isoPolygon {
+	var w, h dimen.DU
+	pbox.W.Match().Just(&w)
+	pbox.H.Match().Just(&h)
 	parPolygon := &isoPolygon{
 		stack: []isoBox{{ // inner box of paragraph's principal box
 			TopL: pbox.TopL,
 			BotR: dimen.Point{
-				X: pbox.TopL.X + pbox.W.Unwrap(),
-				Y: pbox.TopL.Y + pbox.H.Unwrap(),
+				X: pbox.TopL.X + w,
+				Y: pbox.TopL.Y + h,
 			},
 		}},
 	}
@@ -143,14 +241,14 @@ func box2box(f *frame.Box) isoBox {
 	if !f.HasFixedBorderBoxWidth(true) {
 		return nullbox
 	}
-	w := f.TotalWidth()
-	if !w.IsAbsolute() {
+	var w dimen.DU
+	if f.TotalWidth().Match().Just(&w) == nil {
 		return nullbox
 	}
 	b := isoBox{}
 	outer := f.OuterBox()
 	b.TopL = outer.TopL
-	b.BotR.X = outer.TopL.X + w.Unwrap()
+	b.BotR.X = outer.TopL.X + w
 	b.TopL.Y = outer.TopL.Y + 3*dimen.CM // TODO
 	return b
 }
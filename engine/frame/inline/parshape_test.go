@@ -4,10 +4,12 @@ import (
 	"testing"
 
 	"github.com/npillmayer/schuko/testconfig"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
 	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/engine/dom/style/css"
 	"github.com/npillmayer/tyse/engine/frame"
 	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
 )
 
 func TestBoxIntersection(t *testing.T) {
@@ -34,6 +36,29 @@ func TestBoxIntersection(t *testing.T) {
 	}
 }
 
+func TestFloatParShape(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := &frame.Box{}
+	box.W = css.JustDimen(100 * dimen.PT)
+	box.TopL = dimen.Point{X: 10 * dimen.PT}
+	placer := frame.NewFloatPlacer(120 * dimen.PT)
+	placer.Place(nil, css.FloatLeft, 40*dimen.PT, 20*dimen.PT, 0)
+	placer.Place(nil, css.FloatRight, 20*dimen.PT, 10*dimen.PT, 0)
+	parshape := FloatParShape(box, placer, 10*dimen.PT)
+	// line 0 is next to both floats, line 1 next to the left float only
+	if l, ind := parshape.LineLength(0), linebreak.LineIndent(parshape, 0); l != 60*dimen.PT || ind != 30*dimen.PT {
+		t.Errorf("expected line 0 to be 60pt long with indent 30pt, is %s/%s", l, ind)
+	}
+	if l, ind := parshape.LineLength(1), linebreak.LineIndent(parshape, 1); l != 70*dimen.PT || ind != 30*dimen.PT {
+		t.Errorf("expected line 1 to be 70pt long with indent 30pt, is %s/%s", l, ind)
+	}
+	if l, ind := parshape.LineLength(2), linebreak.LineIndent(parshape, 2); l != 100*dimen.PT || ind != 0 {
+		t.Errorf("expected line 2 to span the paragraph, is %s/%s", l, ind)
+	}
+}
+
 /*
 func TestParaPolygon(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
@@ -58,23 +83,23 @@ func makeIsoBox(a, b, c, d dimen.DU) isoBox {
 func makePara() (*boxtree.PrincipalBox, []*frame.Box, []*frame.Box) {
 	para := boxtree.NewPrincipalBox(nil, css.BlockMode)
 	para.Box = &frame.StyledBox{}
-	para.Box.W = css.SomeDimen(500)
-	para.Box.H = css.SomeDimen(800)
+	para.Box.W = css.JustDimen(500)
+	para.Box.H = css.JustDimen(800)
 	//
 	lalgn1 := frame.Box{}
 	lalgn1.TopL = dimen.Point{X: 0, Y: 0}
-	lalgn1.W = css.SomeDimen(100)
-	lalgn1.H = css.SomeDimen(20)
+	lalgn1.W = css.JustDimen(100)
+	lalgn1.H = css.JustDimen(20)
 	//
 	lalgn2 := frame.Box{}
 	lalgn2.TopL = dimen.Point{X: 0, Y: 20}
-	lalgn1.W = css.SomeDimen(200)
-	lalgn1.H = css.SomeDimen(50)
+	lalgn1.W = css.JustDimen(200)
+	lalgn1.H = css.JustDimen(50)
 	//
 	ralgn := frame.Box{}
 	ralgn.TopL = dimen.Point{X: 300, Y: 500}
-	lalgn1.W = css.SomeDimen(500)
-	lalgn1.H = css.SomeDimen(800)
+	lalgn1.W = css.JustDimen(500)
+	lalgn1.H = css.JustDimen(800)
 	//
 	return para, []*frame.Box{&lalgn1, &lalgn2}, []*frame.Box{&ralgn}
 }
@@ -0,0 +1,93 @@
+package inline
+
+import (
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+// --- Optical margin alignment ----------------------------------------------
+
+// Protrusion is a table of protrusion factors for characters, used for optical
+// margin alignment. Characters at the start or at the end of a line protrude into
+// the margin by a fraction of their width. Punctuation and hyphens are optically
+// lighter than letters, and margins look straighter if they are set partly into
+// the margin ("hanging punctuation").
+//
+// Factors are given in thousandths of the width of a character, as with the
+// \lpcode and \rpcode tables of pdfTeX. Characters not contained in a table do
+// not protrude.
+type Protrusion struct {
+	Left  map[rune]int // protrusion into the start margin, for the first character of a line
+	Right map[rune]int // protrusion into the end margin, for the last character of a line
+}
+
+// DefaultProtrusion is a protrusion table for punctuation, hyphens and quotation
+// marks of Latin text, similar to the defaults of LaTeX package microtype.
+var DefaultProtrusion = &Protrusion{
+	Left: map[rune]int{
+		'"': 500, '\'': 700, '“': 500, '‘': 700, '„': 500, '‚': 700,
+		'«': 400, '‹': 400, '»': 400, '›': 400,
+		'(': 50, '[': 50, '-': 700, '‐': 700, '–': 300, '—': 200,
+	},
+	Right: map[rune]int{
+		'.': 700, ',': 700, ':': 500, ';': 500, '!': 200, '?': 200,
+		'"': 500, '\'': 700, '”': 500, '’': 700, '“': 500, '‘': 700,
+		'«': 400, '‹': 400, '»': 400, '›': 400,
+		')': 50, ']': 50, '-': 700, '‐': 700, '–': 300, '—': 200, '…': 100,
+	},
+}
+
+// Protrude returns the amounts by which a line protrudes into the left and right
+// margins. first and last are the text boxes at the edges of the line, and either
+// may be nil if the line starts or ends with a knot other than text. For a
+// hyphenated line, last is the box of the hyphen. A nil table does not protrude.
+func (p *Protrusion) Protrude(first, last *khipu.TextBox) (left, right dimen.DU) {
+	if p == nil {
+		return 0, 0
+	}
+	if first != nil {
+		r, w := edgeChar(first, true)
+		left = factor(p.Left[r], w)
+	}
+	if last != nil {
+		r, w := edgeChar(last, false)
+		right = factor(p.Right[r], w)
+	}
+	return
+}
+
+// edgeChar returns the first or last character of a text box, together with its
+// width. The width is taken from the glyphs of the box, if it has been shaped,
+// or is estimated as an equal share of the box's width otherwise.
+func edgeChar(b *khipu.TextBox, start bool) (rune, dimen.DU) {
+	text := b.Text()
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return utf8.RuneError, 0
+	}
+	var r rune
+	if start {
+		r, _ = utf8.DecodeRuneInString(text)
+	} else {
+		r, _ = utf8.DecodeLastRuneInString(text)
+	}
+	if glyphs := b.Glyphs().Glyphs; len(glyphs) > 0 {
+		if start {
+			return r, glyphs[0].XAdvance
+		}
+		return r, glyphs[len(glyphs)-1].XAdvance
+	}
+	return r, b.Width / dimen.DU(n)
+}
+
+func factor(f int, w dimen.DU) dimen.DU {
+	if f <= 0 {
+		return 0
+	}
+	if f > 1000 {
+		f = 1000
+	}
+	return w * dimen.DU(f) / 1000
+}
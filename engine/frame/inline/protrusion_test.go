@@ -0,0 +1,58 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+func TestProtrude(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	quoted := khipu.NewTextBox("“Hi,", 0)
+	quoted.Width = 40 * dimen.PT
+	left, right := DefaultProtrusion.Protrude(quoted, quoted)
+	if left != 5*dimen.PT || right != 7*dimen.PT {
+		t.Errorf("expected protrusion of 5pt/7pt, have %s/%s", left, right)
+	}
+	word := khipu.NewTextBox("word", 0)
+	word.Width = 40 * dimen.PT
+	if left, right = DefaultProtrusion.Protrude(word, nil); left != 0 || right != 0 {
+		t.Errorf("expected letters not to protrude, have %s/%s", left, right)
+	}
+	var prot *Protrusion
+	if left, right = prot.Protrude(quoted, quoted); left != 0 || right != 0 {
+		t.Errorf("expected nil table not to protrude, have %s/%s", left, right)
+	}
+}
+
+func TestSetProtrudingLine(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := func(s string, w dimen.DU) *khipu.TextBox {
+		b := khipu.NewTextBox(s, 0)
+		b.Width = w
+		return b
+	}
+	k := khipu.NewKhipu()
+	k.AppendKnot(box("“ab", 30*dimen.PT))
+	k.AppendKnot(khipu.NewGlue(4*dimen.PT, 0, 4*dimen.PT))
+	k.AppendKnot(box("cd.", 30*dimen.PT))
+	parshape := linebreak.RectangularParShape(80 * dimen.PT)
+	line := SetProtrudingLineOf(k, 0, k.Length(), 0, parshape, nil, DefaultProtrusion)
+	if first := line.Items[0]; first.X != -5*dimen.PT {
+		t.Errorf("expected opening quote to protrude by 5pt, starts at %s", first.X)
+	}
+	if end := line.Items[2]; end.X+end.W != 87*dimen.PT {
+		t.Errorf("expected period to protrude by 7pt, line ends at %s", end.X+end.W)
+	}
+	plain := SetAlignedLineOf(k, 0, k.Length(), 0, parshape, nil)
+	if plain.Items[0].X != 0 || plain.Ratio >= line.Ratio {
+		t.Errorf("expected protrusion to stretch glue, have ratio %.2f/%.2f", plain.Ratio, line.Ratio)
+	}
+}
@@ -0,0 +1,51 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+// --- Replaced elements -----------------------------------------------------
+
+// Replaced elements within a paragraph, e.g. inline images, are part of the
+// paragraph's text as an object replacement character. After the text has been
+// encoded, the text box holding the character is given the size of the element's
+// box, which has been determined by layout. Images sit on the baseline.
+
+// objectReplacement is the text for a replaced element (U+FFFC).
+const objectReplacement = "\uFFFC"
+
+// replacedElement is a replaced element at text position pos of a paragraph.
+type replacedElement struct {
+	pos uint64
+	c   *frame.Container
+}
+
+// sizeReplacedElements widens the text boxes of para's khipu which hold replaced
+// elements to the width of their elements, and raises them to their height.
+func sizeReplacedElements(para *Paragraph) {
+	if len(para.replaced) == 0 || para.Khipu == nil {
+		return
+	}
+	cursor := khipu.NewCursor(para.Khipu)
+	for cursor.Next() {
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if !ok || len(box.Text()) == 0 {
+			continue
+		}
+		n := uint64(len(box.Text()))
+		for _, r := range para.replaced {
+			if r.pos < box.Position || r.pos >= box.Position+n {
+				continue
+			}
+			var w, h dimen.DU
+			r.c.CSSBox().W.Match().Just(&w)
+			r.c.CSSBox().H.Match().Just(&h)
+			// the object replacement character takes its share of the measured width
+			box.Width += w - box.Width.Scale(int64(len(objectReplacement)), int64(n))
+			box.Height = dimen.Max(box.Height, h)
+			tracer().Debugf("replaced element <%s> at text position %d", r.c.DOMNode().NodeName(), r.pos)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package inline
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/glyphing/monospace"
+)
+
+// --- Ruby annotations ------------------------------------------------------
+
+// https://www.w3.org/TR/css-ruby-1/
+
+// Ruby annotations, e.g. furigana for Japanese text, are short runs of text set
+// above their base text. While collecting the text of a paragraph, the text of
+// ruby text elements (<rt>, display: ruby-text) is left out of the paragraph's
+// text and recorded as an annotation of the base text preceding it within the
+// enclosing ruby element. After the text has been encoded, annotations are
+// measured at half the font size of the paragraph, and
+//
+//   - lines may not be broken within base text,
+//   - base text narrower than its annotation is spaced apart, as far as the
+//     annotation may not overhang adjacent text,
+//   - the text boxes of the base are heightened to include the annotation,
+//     making lines with annotations taller if necessary.
+//
+// Annotations may overhang adjacent text by up to one character of the
+// annotation, unless the adjacent character is a Han ideograph (see JLREQ,
+// https://www.w3.org/TR/jlreq/#positioning_of_ruby). Ruby text containers
+// (<rtc>) and positions other than "over" are not supported.
+
+// rubyScale is the font size of ruby annotations, relative to their base text.
+const rubyScale = 0.5
+
+// rubyAnnotation is an annotation of the base text at text positions
+// [from…to-1] of a paragraph.
+type rubyAnnotation struct {
+	from, to uint64
+	text     string
+	box      *khipu.TextBox   // measured annotation text
+	em       dimen.DU         // font size of the annotation
+	raise    dimen.DU         // distance of the annotation's baseline above the baseline
+	base     []*khipu.TextBox // text boxes of the base, after encoding
+}
+
+// RubyText is a ruby annotation, positioned above its base text within a line.
+type RubyText struct {
+	Box   *khipu.TextBox // annotation text, measured at font size Em
+	Em    dimen.DU       // font size of the annotation
+	X     dimen.DU       // offset from the start of the line
+	Raise dimen.DU       // distance of the annotation's baseline above the baseline of the line
+}
+
+// isRuby returns true if c is a ruby container, i.e. has display: ruby.
+func isRuby(c *frame.Container) bool {
+	return displayOf(c) == "ruby"
+}
+
+// isRubyText returns true if c is a ruby annotation, i.e. has display: ruby-text.
+func isRubyText(c *frame.Container) bool {
+	return displayOf(c) == "ruby-text"
+}
+
+func displayOf(c *frame.Container) string {
+	if c == nil || c.DOMNode() == nil {
+		return ""
+	}
+	return string(c.DOMNode().ComputedStyles().GetPropertyValue("display"))
+}
+
+// rubyTextOf returns the text of a ruby annotation c, transformed by the CSS
+// text-transform of c.
+func rubyTextOf(c *frame.Container) string {
+	text, err := c.DOMNode().TextContent()
+	if err != nil {
+		tracer().Errorf("cannot get text of ruby annotation: %v", err)
+	}
+	return transformText(text, c.DOMNode())
+}
+
+// setRubyAnnotations measures the ruby annotations of para and adapts the base
+// text of each annotation within para's khipu.
+func setRubyAnnotations(para *Paragraph) {
+	if len(para.ruby) == 0 || para.Khipu == nil {
+		return
+	}
+	em := dimen.FromFloat(rubyScale * float64(para.Em))
+	for i := len(para.ruby) - 1; i >= 0; i-- { // back to front, as knots are inserted
+		r := para.ruby[i]
+		r.box, r.em = measureText(r.text, em, para.Regs), em
+		if r.box == nil {
+			continue
+		}
+		start, end := rubyBase(para.Khipu, r)
+		if start == end {
+			continue
+		}
+		var baseW, baseH dimen.DU
+		cursor := khipu.NewCursorAt(para.Khipu, start)
+		for cursor.Next() && cursor.Position() < end {
+			if cursor.Position() > start && cursor.Knot().Type() == khipu.KTPenalty {
+				cursor.ReplaceKnot(khipu.Penalty(dimen.Infinity))
+			}
+			baseW += cursor.Knot().W()
+		}
+		for _, box := range r.base {
+			baseH = dimen.Max(baseH, box.Height)
+		}
+		r.raise = baseH + r.box.Depth
+		for _, box := range r.base {
+			box.Height = dimen.Max(box.Height, r.raise+r.box.Height)
+		}
+		if excess := r.box.Width - baseW; excess > 0 {
+			half := excess / 2
+			if kern := half - rubyOverhang(para.Khipu, end, false, half, em); kern > 0 {
+				para.Khipu.InsertKnot(end, khipu.Kern(kern))
+			}
+			if kern := half - rubyOverhang(para.Khipu, start-1, true, half, em); kern > 0 {
+				para.Khipu.InsertKnot(start, khipu.Kern(kern))
+			}
+		}
+		tracer().Debugf("ruby annotation %q over %d text boxes", r.text, len(r.base))
+	}
+}
+
+// rubyBase collects the text boxes of the base text of annotation r, splitting
+// text boxes at the boundaries of the base, if necessary. It returns the range of
+// knots [start…end-1] spanning the base.
+func rubyBase(k *khipu.Khipu, r *rubyAnnotation) (int64, int64) {
+	splitTextAt(k, r.from)
+	splitTextAt(k, r.to)
+	start, end := int64(-1), int64(-1)
+	r.base = r.base[:0]
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if !ok || box.Text() == "" || box.Position < r.from || box.Position >= r.to {
+			continue
+		}
+		if start < 0 {
+			start = cursor.Position()
+		}
+		end = cursor.Position() + 1
+		r.base = append(r.base, box)
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// splitTextAt splits the text box of k containing text position pos, if pos is
+// inside of the box.
+func splitTextAt(k *khipu.Khipu, pos uint64) {
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if ok && box.Position < pos && pos < box.Position+uint64(len(box.Text())) {
+			first, rest := box.Split(int(pos - box.Position))
+			cursor.ReplaceKnot(first)
+			k.InsertKnot(cursor.Position()+1, rest)
+			return
+		}
+	}
+}
+
+// rubyOverhang returns how far an annotation may overhang the text adjacent to its
+// base, with at most limit and one annotation character of size em. The adjacent
+// text box is searched from knot position inx on, skipping penalties, forward or,
+// if before is set, backward. Annotations do not overhang anything but text, nor
+// Han ideographs.
+func rubyOverhang(k *khipu.Khipu, inx int64, before bool, limit, em dimen.DU) dimen.DU {
+	step := int64(1)
+	if before {
+		step = -1
+	}
+	for ; inx >= 0 && inx < k.Length(); inx += step {
+		cursor := khipu.NewCursorAt(k, inx)
+		cursor.Next()
+		if cursor.Knot().Type() == khipu.KTPenalty {
+			continue
+		}
+		box, ok := cursor.Knot().(*khipu.TextBox)
+		if !ok || box.Text() == "" {
+			return 0
+		}
+		var r rune
+		if before {
+			r, _ = utf8.DecodeLastRuneInString(box.Text())
+		} else {
+			r, _ = utf8.DecodeRuneInString(box.Text())
+		}
+		if unicode.Is(unicode.Han, r) {
+			return 0
+		}
+		return dimen.Min(limit, dimen.Min(em, box.Width))
+	}
+	return 0
+}
+
+// measureText returns a text box for text, measured at font size em, or nil if
+// text cannot be encoded.
+func measureText(text string, em dimen.DU, regs *parameters.TypesettingRegisters) *khipu.TextBox {
+	k, err := khipu.EncodeText(text, khipu.WithRegisters(regs),
+		khipu.WithShaper(monospace.Shaper(em, nil)))
+	if err != nil {
+		tracer().Errorf("cannot encode text %q: %v", text, err)
+		return nil
+	}
+	box := khipu.NewTextBox(text, 0)
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		if b, ok := cursor.Knot().(*khipu.TextBox); ok {
+			box.Width += b.Width
+			box.Height = dimen.Max(box.Height, b.Height)
+			box.Depth = dimen.Max(box.Depth, b.Depth)
+		}
+	}
+	return box
+}
+
+// placeRubyText positions the ruby annotations of a paragraph whose base text is
+// part of line. Annotations are centered above their base text.
+func placeRubyText(line *SetLine, annotations []*rubyAnnotation) []RubyText {
+	var set []RubyText
+	for _, r := range annotations {
+		if r.box == nil {
+			continue
+		}
+		var lo, hi dimen.DU
+		found := false
+		for _, item := range line.Items {
+			if !isBaseOf(item.Knot, r) {
+				continue
+			}
+			if !found || item.X < lo {
+				lo = item.X
+			}
+			if !found || item.X+item.W > hi {
+				hi = item.X + item.W
+			}
+			found = true
+		}
+		if found {
+			set = append(set, RubyText{
+				Box:   r.box,
+				Em:    r.em,
+				X:     (lo+hi)/2 - r.box.Width/2,
+				Raise: r.raise,
+			})
+		}
+	}
+	return set
+}
+
+func isBaseOf(knot khipu.Knot, r *rubyAnnotation) bool {
+	for _, box := range r.base {
+		if knot == box {
+			return true
+		}
+	}
+	return false
+}
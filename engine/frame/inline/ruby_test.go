@@ -0,0 +1,64 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestRubyAnnotation(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := rubyParagraph("は", "漢字を", "かんじ")
+	setRubyAnnotations(para)
+	r := para.ruby[0]
+	if len(r.base) != 1 || r.base[0].Text() != "漢字" || r.base[0].Width != 20*dimen.PT {
+		t.Fatalf("expected base text to be split off, have %v", r.base)
+	}
+	if r.box.Width <= 20*dimen.PT || para.Khipu.Length() != 4 {
+		t.Errorf("expected wide annotation to overhang the adjacent kana, khipu is %v", para.Khipu)
+	}
+	if r.base[0].Height < r.raise+r.box.Height {
+		t.Errorf("expected base text to include the annotation, height is %s", r.base[0].Height)
+	}
+	para = rubyParagraph("山", "漢字川", "かんじ")
+	setRubyAnnotations(para)
+	r = para.ruby[0]
+	half := (r.box.Width - 20*dimen.PT) / 2
+	if para.Khipu.Length() != 6 {
+		t.Fatalf("expected base to be spaced apart between ideographs, khipu is %v", para.Khipu)
+	}
+	for _, inx := range []int64{2, 4} {
+		cursor := khipu.NewCursorAt(para.Khipu, inx)
+		if !cursor.Next() || cursor.Knot().Type() != khipu.KTKern || cursor.Knot().W() != half {
+			t.Errorf("expected kern of %s at knot %d, have %v", half, inx, cursor.Knot())
+		}
+	}
+	line := &SetLine{Items: []PositionedKnot{{Knot: r.base[0], X: 10 * dimen.PT, W: 20 * dimen.PT}}}
+	ruby := placeRubyText(line, para.ruby)
+	if len(ruby) != 1 || ruby[0].X != 20*dimen.PT-r.box.Width/2 {
+		t.Errorf("expected annotation to be centered above its base, is %v", ruby)
+	}
+}
+
+// rubyParagraph creates a paragraph of two text boxes, separated by a break
+// opportunity, with the first two characters of the second box annotated.
+func rubyParagraph(first, second, annotation string) *Paragraph {
+	k := khipu.NewKhipu()
+	b1 := khipu.NewTextBox(first, 0)
+	b1.Width, b1.Height = 10*dimen.PT, 8*dimen.PT
+	b2 := khipu.NewTextBox(second, uint64(len(first)))
+	b2.Width, b2.Height = 30*dimen.PT, 8*dimen.PT
+	k.AppendKnot(b1).AppendKnot(khipu.Penalty(0)).AppendKnot(b2)
+	from := uint64(len(first))
+	return &Paragraph{
+		Khipu: k,
+		Em:    10 * dimen.PT,
+		Regs:  parameters.NewTypesettingRegisters(),
+		ruby:  []*rubyAnnotation{{from: from, to: from + uint64(len("漢字")), text: annotation}},
+	}
+}
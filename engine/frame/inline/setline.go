@@ -0,0 +1,265 @@
+package inline
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Setting lines ---------------------------------------------------------
+
+// SetLine is a line of a broken paragraph, ready to be placed into a line box.
+// The glue of the line has been set, i.e., every knot of the line has been
+// positioned horizontally, and the vertical metrics of the line are known.
+type SetLine struct {
+	Number     int32            // line number, counting from 0
+	From, To   int64            // content of the line is [From…To-1] within the khipu
+	Length     dimen.DU         // target length of the line, from the parshape
+	Indent     dimen.DU         // offset of the line from the start edge, from the parshape
+	Ratio      float64          // glue set ratio, positive for stretch, negative for shrink
+	Infinite   bool             // Ratio applies to infinitely stretchable glue only
+	Expansion  float64          // horizontal scaling of text, as a fraction of its width
+	Ascent     dimen.DU         // height of the line above the baseline
+	Descent    dimen.DU         // depth of the line below the baseline
+	Baseline   dimen.DU         // position of the baseline, from the top of the paragraph
+	Hyphenated bool             // line ends at a discretionary break
+	Items      []PositionedKnot // knots of the line, positioned
+	Deco       []DecorationRect // decoration lines, see Decorations
+	Marker     *khipu.TextBox   // outside list marker hanging before the line, or nil
+	Initial    *InitialLetter   // initial letter, for the first line of a paragraph, or nil
+	Ruby       []RubyText       // ruby annotations above the text of the line
+}
+
+// PositionedKnot is a knot of a set line, together with its horizontal offset
+// from the start of the line and the width it is set to. The start of the line
+// is offset from the paragraph's edge by the line's Indent.
+type PositionedKnot struct {
+	Knot khipu.Knot
+	X    dimen.DU // offset from the start of the line
+	W    dimen.DU // width as set, i.e., for glue including stretch or shrink
+}
+
+func (sl *SetLine) String() string {
+	return fmt.Sprintf("line #%d [%d…%d) r=%.2f h=%s d=%s", sl.Number, sl.From, sl.To,
+		sl.Ratio, sl.Ascent, sl.Descent)
+}
+
+// SetLines converts the breakpoints of a paragraph into set lines. breakpoints are
+// the marks returned from a line breaker, with the first mark denoting the start of
+// the paragraph, and each following mark the end of a line. Line lengths are taken
+// from parshape. Baselines are placed at a distance of leading, unless the lines
+// are too high and would overlap.
+//
+// Text boxes are expected to have been measured. At discretionary breaks, the
+// pre-break text of the discretionary (e.g., a hyphen) is appended to the line,
+// and its post-break text starts the following line. Discretionaries within a line
+// are set as their no-break text.
+func SetLines(k *khipu.Khipu, breakpoints []khipu.Mark, parshape linebreak.ParShape,
+	leading dimen.DU) ([]*SetLine, error) {
+	//
+	if k == nil || len(breakpoints) < 2 || parshape == nil {
+		return nil, fmt.Errorf("inline: no lines to set")
+	}
+	lines := make([]*SetLine, 0, len(breakpoints)-1)
+	from := breakpoints[0].Position()
+	if from < 0 {
+		from = 0
+	}
+	var prev *SetLine
+	for i := 1; i < len(breakpoints); i++ {
+		to := breakpoints[i].Position()
+		line := SetLineOf(k, from, to, int32(i-1), parshape)
+		PlaceBaseline(line, prev, leading)
+		lines = append(lines, line)
+		prev, from = line, to
+	}
+	return lines, nil
+}
+
+// SetLineOf sets line number lineno, spanning knots [from…to-1] of khipu k, with
+// the line break occuring at position to. Discardable knots at the start and at
+// the end of the line are dropped. The baseline of the line is not yet placed
+// (see PlaceBaseline).
+func SetLineOf(k *khipu.Khipu, from, to int64, lineno int32, parshape linebreak.ParShape) *SetLine {
+	return SetAlignedLineOf(k, from, to, lineno, parshape, nil)
+}
+
+// SetAlignedLineOf is like SetLineOf, but respects the left and right skips of
+// params (see TextStyle.Parameters). For lines which are not justified, the skips
+// take up the excess width of the line, glue within the line keeps its natural
+// width, and the knots of the line are offset by the width of the left skip. If
+// params is nil, the line is set without skips.
+//
+// If params enables font expansion, text boxes of justified lines are set wider
+// or narrower, by a factor recorded in the line's Expansion. Renderers are
+// expected to scale the glyphs of text boxes horizontally by 1+Expansion.
+//
+// Leaders, and tabs resolved with a leader (see ResolveTabs), are followed by
+// positioned copies of their leader boxes.
+func SetAlignedLineOf(k *khipu.Khipu, from, to int64, lineno int32, parshape linebreak.ParShape,
+	params *linebreak.Parameters) *SetLine {
+	//
+	return SetProtrudingLineOf(k, from, to, lineno, parshape, params, nil)
+}
+
+// SetProtrudingLineOf is like SetAlignedLineOf, but lets characters at the edges of
+// the line protrude into the margins, according to protrusion table prot. The line
+// is set to its length plus the amounts of protrusion, and its knots are offset by
+// the amount of protrusion into the start margin, which will result in a negative
+// offset of the first knot. If prot is nil, no characters protrude.
+func SetProtrudingLineOf(k *khipu.Khipu, from, to int64, lineno int32, parshape linebreak.ParShape,
+	params *linebreak.Parameters, prot *Protrusion) *SetLine {
+	//
+	line := &SetLine{Number: lineno, Length: parshape.LineLength(lineno)}
+	line.Indent = linebreak.LineIndent(parshape, lineno)
+	line.From, line.To = linebreak.TrimLine(k, from, to)
+	linelen := line.Length
+	first, last := lineEdges(k, line.From, line.To)
+	var pre, post *khipu.TextBox // pre- and post-break text of broken discretionaries
+	startDisc, endDisc := int64(-1), int64(-1)
+	if disc, pos, ok := linebreak.BrokenDiscretionary(k, from); ok && from > 0 && disc.Post != nil {
+		post, startDisc = postBreakBox(disc, first), pos
+		linelen -= post.Width
+		first = post
+	}
+	if disc, pos, ok := linebreak.BrokenDiscretionary(k, to); ok && pos >= line.From && pos != startDisc {
+		endDisc = pos
+		linelen -= linebreak.BreakWidth(disc).W // pre-break text replaces no-break text
+		if pre = disc.PreBreak(); pre != nil {
+			line.Hyphenated = true
+			last = pre
+		}
+	}
+	var left, right dimen.DU
+	if prot != nil {
+		left, right = prot.Protrude(first, last)
+		linelen += left + right
+	}
+	line.Ratio, line.Infinite, line.Expansion = linebreak.ExpandedGlueSetRatio(k, line.From, line.To,
+		linelen, params)
+	line.Ascent, line.Descent = k.MaxHeightAndDepth(line.From, line.To)
+	x := -left
+	if params != nil {
+		lskip, _ := params.Skips()
+		x += lskip.W() + linebreak.GlueDelta(lskip, line.Ratio, line.Infinite)
+	}
+	if post != nil {
+		line.Items = append(line.Items, PositionedKnot{Knot: post, X: x, W: post.Width})
+		x += post.Width
+	}
+	var prev *khipu.TextBox
+	cursor := khipu.NewCursorAt(k, line.From)
+	for cursor.Next() && cursor.Position() < line.To {
+		knot := cursor.Knot()
+		w := knot.W()
+		var leader *khipu.TextBox
+		switch knot.Type() {
+		case khipu.KTPenalty:
+			continue // not visible within a line
+		case khipu.KTDiscretionary:
+			disc := knot.(khipu.Discretionary)
+			if disc.NoBreak == nil || cursor.Position() == startDisc || cursor.Position() == endDisc {
+				continue
+			}
+			knot = noBreakBox(disc, prev)
+		case khipu.KTGlue:
+			w += params.GlueDelta(cursor.AsGlue(), line.Ratio, line.Infinite)
+		case khipu.KTLeaders:
+			leaders := knot.(khipu.Leaders)
+			w += params.GlueDelta(leaders.Glue, line.Ratio, line.Infinite)
+			leader = leaders.Box
+		case khipu.KTTab:
+			leader = knot.(khipu.Tab).Leader
+		case khipu.KTTextBox:
+			prev = cursor.AsTextBox()
+			w += dimen.DU(line.Expansion * float64(w))
+		}
+		line.Items = append(line.Items, PositionedKnot{Knot: knot, X: x, W: w})
+		if leader != nil {
+			line.Items = appendLeaders(line.Items, leader, line.Indent, x, w)
+		}
+		x += w
+	}
+	if pre != nil {
+		pre = preBreakBox(pre, prev)
+		line.Items = append(line.Items, PositionedKnot{Knot: pre, X: x, W: pre.Width})
+	}
+	tracer().Debugf("set %v", line)
+	return line
+}
+
+// lineEdges returns the text boxes at the start and at the end of the content
+// [from…to-1] of a line. If the line starts or ends with a visible knot other
+// than a text box, the respective edge is nil.
+func lineEdges(k *khipu.Khipu, from, to int64) (first, last *khipu.TextBox) {
+	var edge khipu.Knot
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		switch cursor.Knot().Type() {
+		case khipu.KTPenalty, khipu.KTDiscretionary:
+			continue
+		case khipu.KTTextBox:
+			if edge == nil {
+				first = cursor.AsTextBox()
+			}
+			last = cursor.AsTextBox()
+		default:
+			last = nil
+		}
+		edge = cursor.Knot()
+	}
+	return
+}
+
+// PlaceBaseline positions the baseline of a line below the baseline of its
+// predecessor prev, at a distance of leading. If the descent of prev and the
+// ascent of line would overlap, the baseline is moved down. For the first line of
+// a paragraph, prev is nil and the baseline is placed at the ascent of line.
+func PlaceBaseline(line, prev *SetLine, leading dimen.DU) {
+	if prev == nil {
+		line.Baseline = line.Ascent
+		return
+	}
+	skip := leading
+	if prev.Descent+line.Ascent > skip {
+		skip = prev.Descent + line.Ascent
+	}
+	line.Baseline = prev.Baseline + skip
+}
+
+// preBreakBox creates a text box for the pre-break text pre of a discretionary
+// (see khipu.Discretionary.PreBreak), following text box prev. The box inherits
+// the baseline shift and the decoration of prev, as it is set with the last
+// syllable of a line. A hyphen character inherits the vertical metrics of prev
+// as well.
+func preBreakBox(pre *khipu.TextBox, prev *khipu.TextBox) *khipu.TextBox {
+	box := *pre // pre-break text may be shared between lines
+	if prev != nil {
+		box.Position = prev.Position + uint64(len(prev.Text()))
+		if box.Height == 0 && box.Depth == 0 {
+			box.Height, box.Depth = prev.Height, prev.Depth
+		}
+		box.Shift, box.Deco = prev.Shift, prev.Deco
+	}
+	return &box
+}
+
+// postBreakBox creates a text box for the post-break text of discretionary d,
+// starting a line before text box next, from which it inherits the baseline shift
+// and the decoration.
+func postBreakBox(d khipu.Discretionary, next *khipu.TextBox) *khipu.TextBox {
+	box := *d.Post
+	if next != nil {
+		box.Position = next.Position
+		box.Shift, box.Deco = next.Shift, next.Deco
+	}
+	return &box
+}
+
+// noBreakBox creates a text box for the no-break text of discretionary d, set
+// within a line after text box prev.
+func noBreakBox(d khipu.Discretionary, prev *khipu.TextBox) *khipu.TextBox {
+	return preBreakBox(d.NoBreak, prev)
+}
@@ -0,0 +1,148 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+func TestSetLines(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := func(s string, w, shift dimen.DU) *khipu.TextBox {
+		b := khipu.NewTextBox(s, 0)
+		b.Width, b.Height, b.Depth, b.Shift = w, 7*dimen.PT, 2*dimen.PT, shift
+		return b
+	}
+	k := khipu.NewKhipu()
+	k.AppendKnot(box("Hy", 10*dimen.PT, 0))
+	k.AppendKnot(khipu.Discretionary{HyphenChar: '-', Width: 3 * dimen.PT})
+	k.AppendKnot(khipu.Penalty(50)) // break #1
+	k.AppendKnot(box("phen", 20*dimen.PT, 0))
+	k.AppendKnot(khipu.NewGlue(4*dimen.PT, 2*dimen.PT, 4*dimen.PT))
+	k.AppendKnot(box("word", 20*dimen.PT, 3*dimen.PT))
+	k.AppendKnot(khipu.NewFill(2))
+	k.AppendKnot(khipu.Penalty(-10000)) // break #2
+	var breakpoints []khipu.Mark
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		if p := cursor.Position(); p == 0 || p == 2 || p == 7 {
+			breakpoints = append(breakpoints, cursor.Mark())
+		}
+	}
+	parshape := linebreak.RectangularParShape(50 * dimen.PT)
+	lines, err := SetLines(k, breakpoints, parshape, 12*dimen.PT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, have %d", len(lines))
+	}
+	first, last := lines[0], lines[1]
+	if !first.Hyphenated || len(first.Items) != 2 {
+		t.Fatalf("expected first line to end with a hyphen, have %v", first.Items)
+	}
+	if hyphen := first.Items[1]; hyphen.X != 10*dimen.PT || hyphen.Knot.(*khipu.TextBox).Text() != "-" {
+		t.Errorf("expected hyphen at 10pt, have %q at %s", hyphen.Knot, hyphen.X)
+	}
+	if !last.Infinite || last.Items[2].X != 24*dimen.PT {
+		t.Errorf("expected glue of last line to be set at natural width, have %v", last)
+	}
+	if end := last.Items[3]; end.X+end.W != 50*dimen.PT {
+		t.Errorf("expected last line to be filled to 50pt, ends at %s", end.X+end.W)
+	}
+	if last.Ascent != 10*dimen.PT || last.Descent != 2*dimen.PT {
+		t.Errorf("expected superscript to raise ascent to 10pt, have %s", last.Ascent)
+	}
+	if first.Baseline != 7*dimen.PT || last.Baseline != 19*dimen.PT {
+		t.Errorf("expected baselines at 7pt and 19pt, have %s and %s", first.Baseline, last.Baseline)
+	}
+}
+
+func TestSetDiscretionary(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := func(s string) *khipu.TextBox {
+		b := khipu.NewTextBox(s, 0)
+		b.Width = dimen.DU(len(s)) * 5 * dimen.PT
+		return b
+	}
+	d := khipu.NewDiscretionary("k-", "k", "ck")
+	d.Pre, d.Post, d.NoBreak = box("k-"), box("k"), box("ck")
+	k := khipu.NewKhipu()
+	k.AppendKnot(box("ba")).AppendKnot(d).AppendKnot(khipu.Penalty(50)) // break #1
+	k.AppendKnot(box("en")).AppendKnot(khipu.NewFill(2)).AppendKnot(khipu.Penalty(-10000))
+	parshape := linebreak.RectangularParShape(30 * dimen.PT)
+	first := SetAlignedLineOf(k, 0, 2, 0, parshape, nil)
+	if !first.Hyphenated || len(first.Items) != 2 {
+		t.Fatalf("expected first line to end with pre-break text, have %v", first.Items)
+	}
+	if pre := first.Items[1]; pre.X != 10*dimen.PT || pre.Knot.(*khipu.TextBox).Text() != "k-" {
+		t.Errorf("expected pre-break text at 10pt, have %v at %s", pre.Knot, pre.X)
+	}
+	second := SetAlignedLineOf(k, 2, k.Length(), 1, parshape, nil)
+	if post := second.Items[0]; post.X != 0 || post.Knot.(*khipu.TextBox).Text() != "k" {
+		t.Errorf("expected second line to start with post-break text, have %v", post.Knot)
+	}
+	if en := second.Items[1]; en.X != 5*dimen.PT {
+		t.Errorf("expected text after post-break text at 5pt, is at %s", en.X)
+	}
+	whole := SetAlignedLineOf(k, 0, k.Length(), 0, parshape, nil)
+	if whole.Hyphenated || whole.Items[1].Knot.(*khipu.TextBox).Text() != "ck" {
+		t.Errorf("expected unbroken line to contain no-break text, have %v", whole.Items)
+	}
+	if en := whole.Items[2]; en.X != 20*dimen.PT {
+		t.Errorf("expected text after no-break text at 20pt, is at %s", en.X)
+	}
+}
+
+func TestSetExpandedLine(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	a, b := khipu.NewTextBox("a", 0), khipu.NewTextBox("b", 1)
+	a.Width, b.Width = 50*dimen.PT, 50*dimen.PT
+	k.AppendKnot(a).AppendKnot(khipu.NewGlue(2*dimen.PT, dimen.PT, 4*dimen.PT)).AppendKnot(b)
+	params := *linebreak.DefaultParameters
+	params.Expansion = linebreak.Expansion{Stretch: 20, Shrink: 20}
+	parshape := linebreak.RectangularParShape(105 * dimen.PT)
+	line := SetAlignedLineOf(k, 0, k.Length(), 0, parshape, &params)
+	if line.Expansion < 0.0099 || line.Expansion > 0.0101 {
+		t.Fatalf("expected text to be expanded by 1%%, is %.4f", line.Expansion)
+	}
+	if w := line.Items[0].W; w < 50*dimen.PT+dimen.PT/2-1 || w > 50*dimen.PT+dimen.PT/2+1 {
+		t.Errorf("expected text box to be set to 50.5pt, is %s", w)
+	}
+	if end := line.Items[2]; end.X+end.W < 105*dimen.PT-2 || end.X+end.W > 105*dimen.PT+2 {
+		t.Errorf("expected line to be justified to 105pt, ends at %s", end.X+end.W)
+	}
+}
+
+func TestPlaceBaseline(t *testing.T) {
+	prev := &SetLine{Baseline: 10 * dimen.PT, Descent: 4 * dimen.PT}
+	line := &SetLine{Ascent: 10 * dimen.PT}
+	PlaceBaseline(line, prev, 12*dimen.PT)
+	if line.Baseline != 24*dimen.PT {
+		t.Errorf("expected high line to push down baseline to 24pt, is %s", line.Baseline)
+	}
+}
+
+func TestParagraphLeading(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	if l := (&Paragraph{}).leading(); l != strut {
+		t.Errorf("expected paragraph without font to use the strut, leading is %s", l)
+	}
+	otf, em := paragraphFont(nil)
+	para := &Paragraph{Font: otf, Em: em}
+	if l := para.leading(); l <= em || l == strut {
+		t.Errorf("expected leading from line metrics of the font, is %s for em = %s", l, em)
+	}
+}
@@ -0,0 +1,115 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Tab stops -------------------------------------------------------------
+
+// TabAlign is an enum type for the alignment of text at a tab stop.
+type TabAlign uint8
+
+// Enum values for type TabAlign
+const (
+	TabLeft   TabAlign = iota // text following a tab starts at the tab stop
+	TabRight                  // text following a tab ends at the tab stop
+	TabCenter                 // text following a tab is centered at the tab stop
+)
+
+// A TabStop is a position within the lines of a paragraph, at which text following
+// a tab is aligned. Tab stops with a leader box fill the space of the tab with
+// copies of the box, e.g. with dots for a table of contents.
+type TabStop struct {
+	Position dimen.DU       // offset from the edge of the paragraph
+	Align    TabAlign       // alignment of the text following a tab
+	Leader   *khipu.TextBox // measured box to fill the tab with, or nil
+}
+
+// TabStops is a list of tab stops, ordered by position. Tabs beyond the last tab
+// stop advance to the next multiple of Interval, as set by CSS property tab-size.
+type TabStops struct {
+	Stops    []TabStop
+	Interval dimen.DU
+}
+
+// Next returns the first tab stop after position x. If there is none, the second
+// return value is false.
+func (ts TabStops) Next(x dimen.DU) (TabStop, bool) {
+	for _, stop := range ts.Stops {
+		if stop.Position > x {
+			return stop, true
+		}
+	}
+	if ts.Interval > 0 {
+		return TabStop{Position: (x/ts.Interval + 1) * ts.Interval}, true
+	}
+	return TabStop{}, false
+}
+
+// ResolveTabs sets the tabs of line [from…to-1] of khipu k to reach their tab
+// stops from ts. The line starts at offset indent from the edge of the paragraph.
+// Positions within the line are calculated from the natural widths of its knots,
+// i.e. lines containing tabs should not be justified. Tabs without a following
+// tab stop keep their width.
+//
+// Tabs are resolved after line breaking, which used the provisional width of tabs.
+// For right-aligned and centered tab stops, lines may therefore end up longer than
+// the length they have been broken for.
+func ResolveTabs(k *khipu.Khipu, from, to int64, indent dimen.DU, ts TabStops) {
+	from, to = linebreak.TrimLine(k, from, to)
+	var knots []khipu.Knot
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		knots = append(knots, cursor.Knot())
+	}
+	x := indent
+	for i, knot := range knots {
+		tab, ok := knot.(khipu.Tab)
+		if !ok {
+			x += knot.W()
+			continue
+		}
+		if stop, ok := ts.Next(x); ok {
+			w := stop.Position - x
+			switch stop.Align {
+			case TabRight:
+				w -= widthUpToTab(knots[i+1:])
+			case TabCenter:
+				w -= widthUpToTab(knots[i+1:]) / 2
+			}
+			tab.Width, tab.Leader = dimen.Max(0, w), stop.Leader
+			k.ReplaceKnot(from+int64(i), tab)
+		}
+		x += tab.Width
+	}
+}
+
+// widthUpToTab returns the natural width of knots up to the first tab.
+func widthUpToTab(knots []khipu.Knot) dimen.DU {
+	var w dimen.DU
+	for _, knot := range knots {
+		if knot.Type() == khipu.KTTab {
+			break
+		}
+		w += knot.W()
+	}
+	return w
+}
+
+// appendLeaders appends copies of leader box to the items of a line, filling the
+// space [x…x+w) of the line. Copies are placed at multiples of the width of box,
+// counting from the edge of the paragraph, which is at -indent, so that leaders of
+// consecutive lines line up.
+func appendLeaders(items []PositionedKnot, box *khipu.TextBox, indent, x, w dimen.DU) []PositionedKnot {
+	bw := box.Width
+	if bw <= 0 {
+		return items
+	}
+	start := (indent + x + bw - 1) / bw * bw
+	for bx := start; bx+bw <= indent+x+w; bx += bw {
+		items = append(items, PositionedKnot{Knot: box, X: bx - indent, W: bw})
+	}
+	return items
+}
@@ -0,0 +1,65 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+func TestTabStops(t *testing.T) {
+	ts := TabStops{Stops: []TabStop{{Position: 20 * dimen.PT}}, Interval: 40 * dimen.PT}
+	if stop, ok := ts.Next(10 * dimen.PT); !ok || stop.Position != 20*dimen.PT {
+		t.Errorf("expected explicit tab stop at 20pt, have %s", stop.Position)
+	}
+	if stop, ok := ts.Next(45 * dimen.PT); !ok || stop.Position != 80*dimen.PT {
+		t.Errorf("expected tab stop at interval 80pt, have %s", stop.Position)
+	}
+	if _, ok := (TabStops{}).Next(0); ok {
+		t.Errorf("expected no tab stop without stops and interval")
+	}
+}
+
+func TestResolveTabs(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	box := func(s string, w dimen.DU) *khipu.TextBox {
+		b := khipu.NewTextBox(s, 0)
+		b.Width = w
+		return b
+	}
+	dot := box(".", 5*dimen.PT)
+	k := khipu.NewKhipu()
+	k.AppendKnot(box("Title", 30*dimen.PT)).AppendKnot(khipu.NewTab(40*dimen.PT, 5))
+	k.AppendKnot(box("5", 10*dimen.PT))
+	ts := TabStops{Stops: []TabStop{{Position: 100 * dimen.PT, Align: TabRight, Leader: dot}}}
+	ResolveTabs(k, 0, k.Length(), 0, ts)
+	parshape := linebreak.RectangularParShape(100 * dimen.PT)
+	line := SetLineOf(k, 0, k.Length(), 0, parshape)
+	if n := len(line.Items); n != 15 {
+		t.Fatalf("expected 3 knots and 12 leader dots in line, have %d", n)
+	}
+	if tab := line.Items[1]; tab.X != 30*dimen.PT || tab.W != 60*dimen.PT {
+		t.Errorf("expected tab to reach from 30pt to right-aligned tab stop, is %s wide", tab.W)
+	}
+	if last := line.Items[13]; last.Knot != dot || last.X != 85*dimen.PT {
+		t.Errorf("expected last leader dot at 85pt, is at %s", last.X)
+	}
+	if page := line.Items[14]; page.X != 90*dimen.PT {
+		t.Errorf("expected page number to end at tab stop, starts at %s", page.X)
+	}
+	//
+	k = khipu.NewKhipu()
+	k.AppendKnot(box("A", 10*dimen.PT)).AppendKnot(khipu.NewLeaders(dot, khipu.NewFill(1)))
+	k.AppendKnot(box("9", 10*dimen.PT))
+	line = SetLineOf(k, 0, k.Length(), 0, linebreak.RectangularParShape(63*dimen.PT))
+	if leaders := line.Items[1]; leaders.W < 43*dimen.PT-1 || leaders.W > 43*dimen.PT+1 {
+		t.Errorf("expected leaders to fill 43pt, fill %s", leaders.W)
+	}
+	if n := len(line.Items); n != 3+8 {
+		t.Errorf("expected leaders to be filled with 8 aligned dots, have %d items", n)
+	}
+}
@@ -0,0 +1,150 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"github.com/npillmayer/uax/bidi"
+	"golang.org/x/text/language"
+)
+
+// --- CSS text properties ---------------------------------------------------
+
+// TextStyle holds the CSS properties of a paragraph which determine how its text
+// is broken into lines and how the lines are set:
+//
+//   - text-align selects the left and right skips of lines (see Parameters)
+//   - text-indent indents the first line of the paragraph
+//   - line-height sets the distance between baselines
+//   - white-space collapses or preserves spaces and newlines
+//   - white-space, word-break and hyphens add or suppress break opportunities
+//     (see Wrapping)
+//   - hyphenate-limit-chars overrides the hyphenation minima of the language of
+//     the paragraph, which is set by attribute lang; the language selects
+//     hyphenation patterns, the handling of compound words and user exceptions
+//     (see hyphenation.LanguageOptions)
+//   - letter-spacing and word-spacing adjust the spacing of the khipu
+//   - tab-size sets the interval of tab stops for preserved tabs; clients may add
+//     explicit tab stops (see ResolveTabs)
+//
+// Justified text is set by the Justifier of a Paragraph.
+type TextStyle struct {
+	Align        css.TextAlignT           // text-align, resolved for the direction of the paragraph
+	Indent       css.DimenT               // text-indent; percentages refer to the length of the first line
+	LineHeight   dimen.DU                 // distance between baselines, or 0 for the font's default
+	WhiteSpace   css.WhiteSpaceT          // white-space
+	WordBreak    css.WordBreakT           // word-break
+	Hyphens      css.HyphensT             // hyphens
+	Spacing      khipu.Spacing            // letter-spacing and word-spacing
+	Tabs         TabStops                 // tab stops, at intervals of tab-size
+	RTL          bool                     // paragraph direction is right-to-left
+	Lang         language.Tag             // language of the paragraph, or language.Und if not set
+	HyphenLimits css.HyphenateLimitCharsT // hyphenate-limit-chars
+}
+
+// minHyphenLength is the minimum length of words to be hyphenated for CSS
+// hyphens "auto".
+const minHyphenLength = 5
+
+// textStyleForContainer collects the CSS text properties of the paragraph of
+// container c, with font-relative values resolved for a font size of em.
+func textStyleForContainer(c *frame.Container, em dimen.DU) TextStyle {
+	space := khipu.SpaceWidth(nil)
+	ts := TextStyle{Align: css.TextAlignLeft, Indent: css.JustDimen(0)}
+	ts.Tabs.Interval = css.TabSize("", space, em)
+	if c == nil || c.DOMNode() == nil {
+		return ts
+	}
+	styles := c.DOMNode().ComputedStyles()
+	dir, _ := findEmbeddingBidiDirection(c.DOMNode())
+	ts.RTL = dir == bidi.RightToLeft
+	ts.Align = css.TextAlign(styles.GetPropertyValue("text-align")).Resolve(ts.RTL)
+	ts.Indent = css.DimenOption(styles.GetPropertyValue("text-indent")).ScaleFromFont(em)
+	ts.LineHeight = css.LineHeight(styles.GetPropertyValue("line-height"), em)
+	ts.WhiteSpace = css.WhiteSpace(styles.GetPropertyValue("white-space"))
+	ts.WordBreak = css.WordBreak(styles.GetPropertyValue("word-break"))
+	ts.Hyphens = css.Hyphens(styles.GetPropertyValue("hyphens"))
+	ts.HyphenLimits = css.HyphenateLimitChars(styles.GetPropertyValue("hyphenate-limit-chars"))
+	ts.Lang = languageOf(c.DOMNode())
+	ts.Spacing = spacingForContainer(c, em)
+	ts.Tabs.Interval = css.TabSize(styles.GetPropertyValue("tab-size"), space, em)
+	return ts
+}
+
+// Parameters returns the line-breaking parameters for a paragraph. Lines which are
+// not justified are set ragged-right, ragged-left or centered, depending on the
+// alignment (see linebreak.Alignment). The skips of these lines take up the excess
+// width of lines, leaving inter-word glue at its natural width.
+func (ts TextStyle) Parameters() *linebreak.Parameters {
+	params := *linebreak.DefaultParameters
+	switch ts.Align.Resolve(ts.RTL) {
+	case css.TextAlignLeft:
+		params.Alignment = linebreak.RaggedRight
+	case css.TextAlignRight:
+		params.Alignment = linebreak.RaggedLeft
+	case css.TextAlignCenter:
+		params.Alignment = linebreak.Centered
+	}
+	if ts.Hyphens == css.HyphensNone {
+		params.HyphenPenalty = linebreak.InfinityDemerits
+	}
+	return &params
+}
+
+// Wrapping returns the adjustments of line break opportunities requested by CSS
+// properties white-space, word-break and hyphens.
+func (ts TextStyle) Wrapping() khipu.Wrapping {
+	return khipu.Wrapping{
+		NoWrap:    !ts.WhiteSpace.Wraps(),
+		BreakAll:  ts.WordBreak == css.WordBreakBreakAll,
+		NoHyphens: ts.Hyphens == css.HyphensNone,
+	}
+}
+
+// FirstLineIndent returns the indent of the first line of a paragraph, for a
+// first line of length linelen.
+func (ts TextStyle) FirstLineIndent(linelen dimen.DU) dimen.DU {
+	indent, err := css.Resolution{}.Absolute(ts.Indent, linelen)
+	if err != nil {
+		return 0
+	}
+	return indent
+}
+
+// registers sets the typesetting registers for white space, for the language, for
+// hyphenation and for breaks between CJK characters.
+func (ts TextStyle) registers(regs *parameters.TypesettingRegisters) *parameters.TypesettingRegisters {
+	if ts.WhiteSpace != css.WhiteSpaceNormal {
+		regs.Push(parameters.P_WHITESPACE, ts.WhiteSpace.String())
+	}
+	if ts.Lang != language.Und {
+		regs.Push(parameters.P_LANGUAGE, ts.Lang.String())
+	}
+	if ts.Hyphens == css.HyphensAuto {
+		minlen := minHyphenLength
+		if ts.HyphenLimits.Word > 0 {
+			minlen = ts.HyphenLimits.Word
+		}
+		regs.Push(parameters.P_MINHYPHENLENGTH, minlen)
+		if ts.HyphenLimits.Before > 0 {
+			regs.Push(parameters.P_LEFTHYPHENMIN, ts.HyphenLimits.Before)
+		}
+		if ts.HyphenLimits.After > 0 {
+			regs.Push(parameters.P_RIGHTHYPHENMIN, ts.HyphenLimits.After)
+		}
+	}
+	if ts.WordBreak == css.WordBreakKeepAll {
+		regs.Push(parameters.P_CJKPENALTY, int(dimen.Infinity))
+	}
+	return regs
+}
+
+// dropsParfillskip is true if the last line of a paragraph has to be set without
+// a parfillskip, as the left skip of the line will position it.
+func (ts TextStyle) dropsParfillskip() bool {
+	a := ts.Align.Resolve(ts.RTL)
+	return a == css.TextAlignCenter || a == css.TextAlignRight
+}
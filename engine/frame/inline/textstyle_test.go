@@ -0,0 +1,101 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"golang.org/x/text/language"
+)
+
+func TestTextStyleParameters(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	ts := TextStyle{Align: css.TextAlignStart, RTL: true}
+	params := ts.Parameters()
+	if left, right := params.Skips(); params.Alignment != linebreak.RaggedLeft ||
+		left.MaxW() != params.Raggedness || right.MaxW() != 0 {
+		t.Errorf("expected rtl text to be set ragged-left, have skips %v and %v", left, right)
+	}
+	ts = TextStyle{Align: css.TextAlignJustify, Hyphens: css.HyphensNone}
+	params = ts.Parameters()
+	if left, right := params.Skips(); left.MaxW() != 0 || right.MaxW() != 0 {
+		t.Errorf("expected justified text to be set without skips")
+	}
+	if params.HyphenPenalty != linebreak.InfinityDemerits || !ts.Wrapping().NoHyphens {
+		t.Errorf("expected hyphens 'none' to suppress hyphenation")
+	}
+	if linebreak.DefaultParameters.HyphenPenalty == linebreak.InfinityDemerits {
+		t.Errorf("expected default parameters to be unchanged")
+	}
+	if w := (TextStyle{WhiteSpace: css.WhiteSpacePre}).Wrapping(); !w.NoWrap {
+		t.Errorf("expected white-space 'pre' to suppress wrapping")
+	}
+	regs := TextStyle{WhiteSpace: css.WhiteSpacePreLine}.registers(parameters.NewTypesettingRegisters())
+	if ws := regs.S(parameters.P_WHITESPACE); ws != "pre-line" {
+		t.Errorf("expected white-space to be passed to the khipukamayuq, is %q", ws)
+	}
+	ts = TextStyle{
+		Hyphens:      css.HyphensAuto,
+		Lang:         language.German,
+		HyphenLimits: css.HyphenateLimitChars("6 3"),
+	}
+	regs = ts.registers(parameters.NewTypesettingRegisters())
+	if regs.S(parameters.P_LANGUAGE) != "de" || regs.N(parameters.P_MINHYPHENLENGTH) != 6 ||
+		regs.N(parameters.P_LEFTHYPHENMIN) != 3 || regs.N(parameters.P_RIGHTHYPHENMIN) != 3 {
+		t.Errorf("expected language and hyphenate-limit-chars to be passed to the khipukamayuq")
+	}
+}
+
+func TestSetAlignedLine(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	k := khipu.NewKhipu()
+	for i, s := range []string{"ab", "cd"} {
+		if i > 0 {
+			k.AppendKnot(khipu.NewGlue(4*dimen.PT, 2*dimen.PT, 4*dimen.PT))
+		}
+		b := khipu.NewTextBox(s, uint64(3*i))
+		b.Width = 10 * dimen.PT
+		k.AppendKnot(b)
+	}
+	parshape := linebreak.RectangularParShape(50 * dimen.PT)
+	params := TextStyle{Align: css.TextAlignCenter}.Parameters()
+	line := SetAlignedLineOf(k, 0, k.Length(), 0, parshape, params)
+	if len(line.Items) != 3 {
+		t.Fatalf("expected 3 knots in line, have %d", len(line.Items))
+	}
+	if x := line.Items[0].X; x < 13*dimen.PT-1 || x > 13*dimen.PT+1 {
+		t.Errorf("expected centered line to start at 13pt, starts at %s", x)
+	}
+	if w := line.Items[1].W; w != 4*dimen.PT {
+		t.Errorf("expected inter-word glue at natural width, is %s", w)
+	}
+	if plain := SetLineOf(k, 0, k.Length(), 0, parshape); plain.Items[0].X != 0 {
+		t.Errorf("expected line without skips to start at 0, starts at %s", plain.Items[0].X)
+	}
+}
+
+func TestFirstLineIndent(t *testing.T) {
+	ts := TextStyle{Indent: css.DimenOption("10%")}
+	if indent := ts.FirstLineIndent(200 * dimen.PT); indent != 20*dimen.PT {
+		t.Errorf("expected indent of 10%% of 200pt, is %s", indent)
+	}
+	parshape := indentFirstLine(linebreak.RectangularParShape(100*dimen.PT), 20*dimen.PT, false)
+	if parshape.LineLength(0) != 80*dimen.PT || linebreak.LineIndent(parshape, 0) != 20*dimen.PT {
+		t.Errorf("expected first line to be indented by 20pt")
+	}
+	if parshape.LineLength(1) != 100*dimen.PT || linebreak.LineIndent(parshape, 1) != 0 {
+		t.Errorf("expected second line not to be indented")
+	}
+	rtl := indentFirstLine(linebreak.RectangularParShape(100*dimen.PT), 20*dimen.PT, true)
+	if rtl.LineLength(0) != 80*dimen.PT || linebreak.LineIndent(rtl, 0) != 0 {
+		t.Errorf("expected rtl first line to be indented at the right edge")
+	}
+}
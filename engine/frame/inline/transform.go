@@ -0,0 +1,89 @@
+package inline
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"golang.org/x/net/html"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// --- Text transformation ---------------------------------------------------
+
+// https://www.w3.org/TR/css-text-3/#text-transform-property
+
+// CSS property text-transform changes the case of text. Case mappings depend on
+// the language of the text, which is taken from the lang attribute of the text's
+// element or of its nearest ancestor with one. For example, Turkish and
+// Azerbaijani map i to İ and I to ı, and Dutch capitalizes the digraph ij as a
+// whole. Text is transformed while it is collected for a paragraph, i.e. before
+// it is encoded into a khipu; hyphenation and measuring see the transformed text.
+//
+// Words are capitalized within each DOM text node. A word spanning several
+// elements, e.g. "<b>T</b>ext", is capitalized at the start of each part.
+
+// CapitalSharpS selects the uppercase mapping of German ß (U+00DF). CSS maps it
+// to "SS", but German orthography admits the capital sharp s ẞ (U+1E9E) as well.
+// If CapitalSharpS is set, uppercasing text in German maps ß to ẞ.
+var CapitalSharpS = false
+
+// transformText applies the CSS text-transform of element n to text s.
+func transformText(s string, n *dom.W3CNode) string {
+	if n == nil || s == "" {
+		return s
+	}
+	tt := css.TextTransform(n.ComputedStyles().GetPropertyValue("text-transform"))
+	if tt == css.TextTransformNone {
+		return s
+	}
+	return TransformText(s, tt, languageOf(n), CapitalSharpS)
+}
+
+// TransformText changes the case of text s as requested by tt, using the case
+// mappings of language lang. If capitalSharpS is set, text in German maps ß to ẞ
+// for uppercase, instead of "SS".
+func TransformText(s string, tt css.TextTransformT, lang language.Tag, capitalSharpS bool) string {
+	switch tt {
+	case css.TextTransformUppercase:
+		if capitalSharpS && isGerman(lang) {
+			s = strings.ReplaceAll(s, "ß", "ẞ")
+		}
+		return cases.Upper(lang).String(s)
+	case css.TextTransformLowercase:
+		return cases.Lower(lang).String(s)
+	case css.TextTransformCapitalize:
+		return cases.Title(lang, cases.NoLower).String(s)
+	}
+	return s
+}
+
+// languageOf returns the language of the content of element n, as set by the
+// lang attribute of n or of its nearest ancestor with one. If no language is set,
+// or it is not a valid BCP 47 tag, languageOf returns language.Und.
+func languageOf(n *dom.W3CNode) language.Tag {
+	for n != nil {
+		if n.NodeType() == html.ElementNode && n.HasAttributes() {
+			if attr := n.Attributes().GetNamedItem("lang"); attr != nil {
+				lang, err := language.Parse(strings.TrimSpace(attr.Value()))
+				if err != nil {
+					tracer().Debugf("illegal language tag %q: %v", attr.Value(), err)
+					return language.Und
+				}
+				return lang
+			}
+		}
+		parent := n.ParentNode()
+		if parent == nil {
+			break
+		}
+		n, _ = parent.(*dom.W3CNode)
+	}
+	return language.Und
+}
+
+func isGerman(lang language.Tag) bool {
+	base, _ := lang.Base()
+	return base.String() == "de"
+}
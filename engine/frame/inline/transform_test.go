@@ -0,0 +1,36 @@
+package inline
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"golang.org/x/text/language"
+)
+
+func TestTransformText(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	for _, x := range []struct {
+		text     string
+		tt       css.TextTransformT
+		lang     language.Tag
+		sharpS   bool
+		expected string
+	}{
+		{"istanbul", css.TextTransformUppercase, language.English, false, "ISTANBUL"},
+		{"istanbul", css.TextTransformUppercase, language.Turkish, false, "İSTANBUL"},
+		{"ISPARTA", css.TextTransformLowercase, language.Turkish, false, "ısparta"},
+		{"straße", css.TextTransformUppercase, language.German, false, "STRASSE"},
+		{"straße", css.TextTransformUppercase, language.German, true, "STRAẞE"},
+		{"straße", css.TextTransformUppercase, language.English, true, "STRASSE"},
+		{"the iPhone", css.TextTransformCapitalize, language.English, false, "The IPhone"},
+		{"ijsselmeer", css.TextTransformCapitalize, language.Dutch, false, "IJsselmeer"},
+		{"Text", css.TextTransformNone, language.English, false, "Text"},
+	} {
+		if s := TransformText(x.text, x.tt, x.lang, x.sharpS); s != x.expected {
+			t.Errorf("expected %q to be transformed to %q for %s, have %q", x.text, x.expected, x.lang, s)
+		}
+	}
+}
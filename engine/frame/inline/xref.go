@@ -0,0 +1,87 @@
+package inline
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"golang.org/x/net/html"
+)
+
+// --- Locating elements within lines ----------------------------------------
+
+// TextRange is a range [From…To) of text positions within the text of a
+// paragraph.
+type TextRange struct {
+	From, To uint64
+}
+
+// ElementRanges returns the text ranges of the elements within the paragraph of
+// container c, i.e. for every element the range of text positions spanned by its
+// text. Text positions are counted as for the paragraph text (see
+// EncodeTextOfParagraph), and refer to the text boxes of the paragraph's lines.
+//
+// Together with LineBox.Extent, this locates inline elements, e.g. the anchors
+// and links of cross references, within the lines of a paragraph.
+func ElementRanges(c *frame.Container) map[*dom.W3CNode]TextRange {
+	ranges := make(map[*dom.W3CNode]TextRange)
+	pos := uint64(len(insideMarker(c)))
+	// extend the ranges of element e and its ancestors within c by l
+	extend := func(e *dom.W3CNode, l uint64) {
+		for p, ok := e, e != nil; ok && p != nil; p, ok = p.ParentNode().(*dom.W3CNode) {
+			r, seen := ranges[p]
+			if !seen {
+				r.From = pos
+			}
+			r.To = pos + l
+			ranges[p] = r
+			if p == c.DOMNode() {
+				break
+			}
+		}
+		pos += l
+	}
+	var collect func(sub *frame.Container)
+	collect = func(sub *frame.Container) {
+		n := sub.DOMNode()
+		switch {
+		case n != nil && n.NodeType() == html.TextNode:
+			parent, _ := n.ParentNode().(*dom.W3CNode)
+			extend(parent, uint64(len(n.NodeValue())))
+		case sub != c && boxtree.IsReplaced(sub.RenderNode()):
+			extend(n, uint64(len(objectReplacement)))
+		case sub != c && sub.Display.Outer() == css.BlockMode:
+			pos++ // see nonReplacableElementLeaf
+		case sub.Context != nil:
+			for _, ch := range sub.Context.Contained() {
+				collect(ch)
+			}
+		}
+	}
+	collect(c)
+	return ranges
+}
+
+// Extent returns the horizontal extent [x0…x1) of the text at text positions r
+// within a line box, relative to the edge of the paragraph. If the line box does
+// not contain text of r, ok is false.
+func (lbox *LineBox) Extent(r TextRange) (x0, x1 dimen.DU, ok bool) {
+	if lbox.line == nil {
+		return 0, 0, false
+	}
+	for _, item := range lbox.line.Items {
+		box, isbox := item.Knot.(*khipu.TextBox)
+		if !isbox || box == lbox.line.Marker || box.Position < r.From || box.Position >= r.To {
+			continue
+		}
+		x := lbox.line.Indent + item.X
+		if !ok {
+			x0, x1, ok = x, x+item.W, true
+			continue
+		}
+		x0, x1 = dimen.Min(x0, x), dimen.Max(x1, x+item.W)
+	}
+	return x0, x1, ok
+}
@@ -0,0 +1,57 @@
+package khipu
+
+import (
+	"github.com/npillmayer/uax/bidi"
+	xbidi "golang.org/x/text/unicode/bidi"
+)
+
+// --- Paragraph direction ---------------------------------------------------
+
+// ParagraphDirection finds the direction of a paragraph of text, following rules
+// P2 and P3 of UAX#9: the first character of bidi class L, R or AL determines the
+// direction, where characters between an isolate initiator and its matching PDI
+// are skipped. Isolates may either be part of the text or be given as out-of-line
+// markup, which may be nil.
+//
+// If text does not contain a strong character, ParagraphDirection returns
+// LeftToRight and false.
+func ParagraphDirection(text string, markup bidi.OutOfLineBidiMarkup) (bidi.Direction, bool) {
+	isolates := 0
+	for pos, r := range text {
+		if markup != nil {
+			m := markup(uint64(pos))
+			if m&0xff == bidi.MarkupPDI && isolates > 0 {
+				isolates--
+			}
+			if m>>8 != 0 {
+				isolates++
+			}
+		}
+		props, _ := xbidi.LookupRune(r)
+		switch props.Class() {
+		case xbidi.LRI, xbidi.RLI, xbidi.FSI:
+			isolates++
+		case xbidi.PDI:
+			if isolates > 0 {
+				isolates--
+			}
+		case xbidi.L:
+			if isolates == 0 {
+				return bidi.LeftToRight, true
+			}
+		case xbidi.R, xbidi.AL:
+			if isolates == 0 {
+				return bidi.RightToLeft, true
+			}
+		}
+	}
+	return bidi.LeftToRight, false
+}
+
+// WithDirection sets the direction of a paragraph for EncodeText. If not set,
+// the direction is determined from the text (see ParagraphDirection).
+func WithDirection(dir bidi.Direction) Option {
+	return func(kk *khipukamayuq) {
+		kk.dir, kk.hasDir = dir, true
+	}
+}
@@ -0,0 +1,66 @@
+package khipu
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	params "github.com/npillmayer/tyse/core/parameters"
+)
+
+// Break-control characters are not set as glyphs, but are encoded as knots
+// which control line breaking.
+const (
+	SoftHyphen            = '\u00ad' // break opportunity, with a hyphen if broken
+	ZeroWidthSpace        = '\u200b' // break opportunity without a hyphen; clients map HTML <wbr> to it
+	WordJoiner            = '\u2060' // prohibits a break
+	ZeroWidthNoBreakSpace = '\ufeff' // prohibits a break (deprecated in favour of WordJoiner)
+)
+
+// isBreakControl is true for the break-control characters.
+func isBreakControl(r rune) bool {
+	switch r {
+	case SoftHyphen, ZeroWidthSpace, WordJoiner, ZeroWidthNoBreakSpace:
+		return true
+	}
+	return false
+}
+
+// encodeFragment appends a fragment of text starting at text position textpos to
+// k. Break-control characters within the fragment split it into text boxes:
+//
+//   - a soft hyphen is encoded as a discretionary with the hyphen character of
+//     register P_HYPHENCHAR, followed by a penalty of P_HYPHENPENALTY
+//   - a zero-width space is encoded as a penalty of 0
+//   - word joiners are encoded as an infinite penalty
+//
+// encodeFragment returns true if the fragment ends with a break opportunity from
+// a break-control character, i.e. if a penalty has already been appended for it.
+func encodeFragment(fragment string, textpos uint64, k *Khipu, regs *params.TypesettingRegisters) bool {
+	start := 0
+	breaks := false
+	for i, r := range fragment {
+		if !isBreakControl(r) {
+			continue
+		}
+		if i > start {
+			k.AppendKnot(NewTextBox(fragment[start:i], textpos+uint64(start)))
+		}
+		switch r {
+		case SoftHyphen:
+			hyphen := NewKnot(KTDiscretionary).(Discretionary)
+			hyphen.HyphenChar = rune(regs.N(params.P_HYPHENCHAR))
+			k.AppendKnot(hyphen).AppendKnot(Penalty(regs.N(params.P_HYPHENPENALTY)))
+			breaks = true
+		case ZeroWidthSpace:
+			k.AppendKnot(Penalty(0))
+			breaks = true
+		default:
+			k.AppendKnot(Penalty(dimen.Infinity))
+			breaks = false
+		}
+		start = i + len(string(r))
+	}
+	if start < len(fragment) {
+		k.AppendKnot(NewTextBox(fragment[start:], textpos+uint64(start)))
+		breaks = false
+	}
+	return breaks
+}
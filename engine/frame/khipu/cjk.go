@@ -0,0 +1,141 @@
+package khipu
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	params "github.com/npillmayer/tyse/core/parameters"
+)
+
+// --- Line breaking for CJK text --------------------------------------------
+
+// Scripts like Chinese and Japanese do not separate words by spaces. UAX#14 finds
+// break opportunities between ideographs, and our khipukamayuq will insert
+// penalties for them. However, these penalties need adjustment:
+//
+//   - breaks between CJK characters get penalty P_CJKPENALTY
+//   - kinsoku shori: some characters must not start a line (e.g., closing
+//     brackets and full stops), others must not end a line (opening brackets)
+//
+// Kinsoku rules differ between languages and between levels of strictness (see
+// CSS property line-break), set with register P_LINEBREAKSTRICTNESS.
+
+// Characters which must never start a line.
+const kinsokuNoStart = ")]}〕〉》」』】〙〗〟’”｠»）］｝、。，．｡､・：；！？‼⁇⁈⁉"
+
+// Characters which must never end a line.
+const kinsokuNoEnd = "([{〔〈《「『【〘〖〝‘“｟«（［｛"
+
+// Japanese characters which must not start a line, unless line breaking is
+// "normal" or "loose".
+const kinsokuSmallKana = "ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶㇰㇱㇲㇳㇴㇵㇶㇷㇸㇹㇺㇻㇼㇽㇾㇿｧｨｩｪｫｯｬｭｮー"
+
+// Japanese characters which must not start a line, unless line breaking is "loose".
+const kinsokuLooseOnly = "々〻ゝゞヽヾ‐゠–〜～"
+
+// kinsoku holds the line breaking rules for a language.
+type kinsoku struct {
+	noStart string // characters which must not start a line
+	noEnd   string // characters which must not end a line
+	breakOK string // characters before which UAX#14 is relaxed
+}
+
+// kinsokuRules returns the line breaking rules for a language, depending on the
+// strictness of line breaking.
+func kinsokuRules(lang string, strictness string) kinsoku {
+	rules := kinsoku{noStart: kinsokuNoStart, noEnd: kinsokuNoEnd}
+	if lang != "ja" {
+		return rules
+	}
+	switch strictness {
+	case "strict":
+		rules.noStart += kinsokuSmallKana + kinsokuLooseOnly
+	case "loose":
+		rules.breakOK = kinsokuSmallKana + kinsokuLooseOnly
+	default:
+		rules.noStart += kinsokuLooseOnly
+		rules.breakOK = kinsokuSmallKana
+	}
+	return rules
+}
+
+// baseLanguage extracts the language part of a language setting like "ja_JP".
+func baseLanguage(l string) string {
+	if i := strings.IndexAny(l, "_-"); i >= 0 {
+		l = l[:i]
+	}
+	return strings.ToLower(l)
+}
+
+// KinsokuShori adjusts the line break opportunities of a khipu for CJK text,
+// according to the language and the line breaking strictness set in regs. It
+// returns a new khipu, as text boxes may be split to allow additional breaks.
+//
+// Penalties between text boxes are set to P_CJKPENALTY, if one of the boxes
+// borders on a CJK character, and to infinity if a break is prohibited.
+func KinsokuShori(k *Khipu, regs *params.TypesettingRegisters) *Khipu {
+	if k == nil {
+		return k
+	}
+	if regs == nil {
+		regs = params.NewTypesettingRegisters()
+	}
+	rules := kinsokuRules(baseLanguage(regs.S(params.P_LANGUAGE)), regs.S(params.P_LINEBREAKSTRICTNESS))
+	cjkpenalty := Penalty(regs.N(params.P_CJKPENALTY))
+	adjusted := NewKhipu()
+	adjusted.typ = k.typ
+	for _, knot := range k.knots {
+		if box, ok := knot.(*TextBox); ok && rules.breakOK != "" {
+			splitForKinsoku(box, rules, cjkpenalty, adjusted)
+			continue
+		}
+		adjusted.AppendKnot(knot)
+	}
+	for i := 1; i+1 < len(adjusted.knots); i++ {
+		if adjusted.knots[i].Type() != KTPenalty {
+			continue
+		}
+		before, ok1 := adjusted.knots[i-1].(*TextBox)
+		after, ok2 := adjusted.knots[i+1].(*TextBox)
+		if !ok1 || !ok2 {
+			continue
+		}
+		a, _ := utf8.DecodeLastRuneInString(before.text)
+		b, _ := utf8.DecodeRuneInString(after.text)
+		if strings.ContainsRune(rules.noEnd, a) || strings.ContainsRune(rules.noStart, b) {
+			adjusted.knots[i] = Penalty(dimen.Infinity)
+		} else if isCJK(a) || isCJK(b) {
+			adjusted.knots[i] = cjkpenalty
+		}
+	}
+	return adjusted
+}
+
+// splitForKinsoku appends box to k. If line breaking rules allow breaks before
+// characters which UAX#14 keeps with their predecessor, box is split there.
+func splitForKinsoku(box *TextBox, rules kinsoku, p Penalty, k *Khipu) {
+	start := 0
+	var prev rune
+	for i, r := range box.text {
+		if i > start && isCJK(prev) && strings.ContainsRune(rules.breakOK, r) {
+			k.AppendKnot(box.fragment(box.text[start:i], box.Position+uint64(start)))
+			k.AppendKnot(p)
+			start = i
+		}
+		prev = r
+	}
+	if start == 0 {
+		k.AppendKnot(box)
+		return
+	}
+	k.AppendKnot(box.fragment(box.text[start:], box.Position+uint64(start)))
+}
+
+// isCJK is true for characters of scripts without spaces between words,
+// together with CJK punctuation and fullwidth forms.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
@@ -40,6 +40,16 @@ func NewCursor(kh *Khipu) *Cursor {
 	return &Cursor{kh, -1}
 }
 
+// NewCursorAt creates a cursor for a given khipu, positioned in front of knot #pos,
+// i.e., the first call to Next will move the cursor to pos.
+// Usage is unsafe if the referenced khipu changes during lifetime of the cursor.
+func NewCursorAt(kh *Khipu, pos int64) *Cursor {
+	if pos < 0 {
+		pos = 0
+	}
+	return &Cursor{kh, pos - 1}
+}
+
 func (c Cursor) String() string {
 	return fmt.Sprintf("[%d]%v", c.inx, c.Knot())
 }
@@ -28,7 +28,7 @@
 //
 // Create Khipus from Text
 //
-// (1) Normalize Unicode text
+// (1) Normalize Unicode text (NFC or NFD, see Normalize)
 //
 // 	https://godoc.org/golang.org/x/text/unicode/norm
 //
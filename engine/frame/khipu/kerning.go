@@ -0,0 +1,94 @@
+package khipu
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/glyphing"
+)
+
+// --- Kerning at hyphenation points -----------------------------------------
+
+// Shapers apply kerning (GPOS pair adjustments) to the advances of glyphs within
+// the text they shape. Syllables of hyphenated words are shaped separately, which
+// loses the kerning between the last glyph of a syllable and the first glyph of the
+// next one, e.g. for "Ta-fel".
+//
+// As TeX does, we put kerns between syllables, after the hyphenation point. If a
+// line is broken at the hyphenation point, the kern starts the next line and is
+// discarded.
+
+// kernHyphenated shapes the hyphenated words of k as a whole and inserts kerns
+// between syllables, where the advances of the word as a whole differ from the
+// advances of its syllables. Text boxes of k have to be shaped already.
+//
+// paramsAt returns the shaping parameters for a text position. Hyphenated
+// words of right-to-left text are left untouched.
+func kernHyphenated(ctx context.Context, k *Khipu, shaper glyphing.Shaper, paramsAt func(uint64) glyphing.Params) error {
+	if k == nil || shaper == nil {
+		return nil
+	}
+	knots := make([]Knot, 0, len(k.knots))
+	for i := 0; i < len(k.knots); {
+		n := hyphenatedWord(k.knots[i:])
+		if n == 0 {
+			knots = append(knots, k.knots[i])
+			i++
+			continue
+		}
+		p := paramsAt(k.knots[i].(*TextBox).Position)
+		if p.Direction == glyphing.RightToLeft {
+			knots = append(knots, k.knots[i:i+n]...)
+			i += n
+			continue
+		}
+		word, err := kernWord(ctx, k.knots[i:i+n], shaper, p)
+		if err != nil {
+			return err
+		}
+		knots = append(knots, word...)
+		i += n
+	}
+	k.knots = knots
+	return nil
+}
+
+// kernWord shapes the text of a hyphenated word and returns its knots, with kerns
+// inserted in front of syllables (but the first).
+func kernWord(ctx context.Context, knots []Knot, shaper glyphing.Shaper, p glyphing.Params) ([]Knot, error) {
+	var text strings.Builder
+	for _, knot := range knots {
+		if box, ok := knot.(*TextBox); ok {
+			text.WriteString(box.text)
+		}
+	}
+	seq, err := shape(ctx, shaper, text.String(), p)
+	if err != nil {
+		return nil, core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape %q", text.String())
+	}
+	kerned := make([]Knot, 0, len(knots)+2)
+	var kern dimen.DU // kern between the previous syllable and the next one
+	g, from := 0, 0   // next glyph of seq, start of syllable as rune position
+	for _, knot := range knots {
+		box, ok := knot.(*TextBox)
+		if !ok {
+			kerned = append(kerned, knot)
+			continue
+		}
+		if kern != 0 {
+			kerned = append(kerned, Kern(kern))
+		}
+		kerned = append(kerned, box)
+		to := from + utf8.RuneCountInString(box.text)
+		var w dimen.DU // advance of the syllable within the word as a whole
+		for ; g < len(seq.Glyphs) && seq.Glyphs[g].ClusterID < to; g++ {
+			w += seq.Glyphs[g].XAdvance
+		}
+		kern = w - box.glyphs.Advance(0, len(box.glyphs.Glyphs))
+		from = to
+	}
+	return kerned, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
 	"github.com/npillmayer/tyse/engine/glyphing"
 )
 
@@ -66,6 +67,9 @@ const (
 	KTTextBox
 	KTPenalty
 	KTDiscretionary
+	KTLeaders
+	KTTab
+	KTVBox
 	KTUserDefined // clients should use custom knot types above this
 )
 
@@ -202,10 +206,40 @@ func NewFill(f int) Glue {
 
 // --- Discretionary ---------------------------------------------------------
 
-// A Discretionary is a hyphenation opportunity
+// A Discretionary is a break opportunity within a word, in the manner of TeX's
+// \discretionary{pre}{post}{nobreak}. If a line is broken at a discretionary, its
+// pre-break text ends the line and its post-break text starts the next line.
+// Otherwise its no-break text is set in place of the discretionary.
+//
+// Hyphenation opportunities are discretionaries with a hyphen character as their
+// pre-break text, and without post-break or no-break text. Other discretionaries
+// change the spelling of a word at a break, e.g., for German "backen", broken
+// as "bak-ken" in traditional orthography,
+//
+//	ba ⟨Pre: "k-", Post: "k", NoBreak: "ck"⟩ en
+//
+// or break up a ligature, e.g., "ff" in "affix" as "f-" and "f".
+//
+// As with hyphens, line breakers break at penalties following a discretionary.
 type Discretionary struct {
-	HyphenChar rune
-	Width      dimen.DU
+	HyphenChar rune     // hyphen character, the pre-break text of simple discretionaries
+	Width      dimen.DU // width of the hyphen character
+	Pre        *TextBox // pre-break text, in place of the hyphen character if non-nil
+	Post       *TextBox // post-break text, or nil
+	NoBreak    *TextBox // no-break text, or nil
+}
+
+// NewDiscretionary creates a discretionary from pre-break, post-break and no-break
+// text. Empty strings result in nil text boxes. Text boxes have to be measured
+// before line breaking, as other text boxes do.
+func NewDiscretionary(pre, post, nobreak string) Discretionary {
+	box := func(s string) *TextBox {
+		if s == "" {
+			return nil
+		}
+		return NewTextBox(s, 0)
+	}
+	return Discretionary{Pre: box(pre), Post: box(post), NoBreak: box(nobreak)}
 }
 
 // Type is part of interface Knot.
@@ -213,19 +247,53 @@ func (d Discretionary) Type() KnotType {
 	return KTDiscretionary
 }
 
-// W is part of interface Knot. Returns the width of the un-hyphenated text.
+// W is part of interface Knot. Returns the width of the no-break text.
 func (d Discretionary) W() dimen.DU {
-	return 0
+	if d.NoBreak == nil {
+		return 0
+	}
+	return d.NoBreak.Width
 }
 
-// MinW is part of interface Knot. Returns the width of the pre-hyphen text.
+// MinW is part of interface Knot. Returns the width of the no-break text.
 func (d Discretionary) MinW() dimen.DU {
-	return 0
+	return d.W()
 }
 
-// MaxW is part of interface Knot. Returns the width of the post-hyphen text.
+// MaxW is part of interface Knot. Returns the width of the no-break text.
 func (d Discretionary) MaxW() dimen.DU {
-	return d.Width
+	return d.W()
+}
+
+// PreBreak returns a text box for the pre-break text of d, or nil if d has no
+// pre-break text. For simple discretionaries, a box for the hyphen character is
+// returned.
+func (d Discretionary) PreBreak() *TextBox {
+	if d.Pre != nil {
+		return d.Pre
+	}
+	if d.HyphenChar == 0 {
+		return nil
+	}
+	hyphen := NewTextBox(string(d.HyphenChar), 0)
+	hyphen.Width = d.Width
+	return hyphen
+}
+
+// PreWidth returns the width of the pre-break text of d.
+func (d Discretionary) PreWidth() dimen.DU {
+	if pre := d.PreBreak(); pre != nil {
+		return pre.Width
+	}
+	return 0
+}
+
+// PostWidth returns the width of the post-break text of d.
+func (d Discretionary) PostWidth() dimen.DU {
+	if d.Post == nil {
+		return 0
+	}
+	return d.Post.Width
 }
 
 // IsDiscardable is part of interface Knot. Discretionaries are not discardable.
@@ -241,8 +309,11 @@ type TextBox struct {
 	Height   dimen.DU               // height
 	Depth    dimen.DU               // depth
 	Position uint64                 // start position in text
+	Shift    dimen.DU               // baseline shift, positive values raise the box
+	Deco     frame.TextDecoration   // decoration lines of the text
 	text     string                 // text, if available
 	glyphs   glyphing.GlyphSequence // result of shaping
+	shaping  *glyphing.Params       // parameters for shaping, if recorded during encoding
 }
 
 // NewTextBox creates a text box.
@@ -251,11 +322,41 @@ func NewTextBox(s string, textpos uint64) *TextBox {
 	return box
 }
 
+// fragment creates a text box for a part of the text of b, starting at textpos.
+// The fragment keeps the baseline shift, the decoration and the shaping parameters
+// of b.
+func (b *TextBox) fragment(s string, textpos uint64) *TextBox {
+	return &TextBox{Position: textpos, Shift: b.Shift, Deco: b.Deco, text: s, shaping: b.shaping}
+}
+
+// measuredFragment is like fragment, but for a box b which has been measured, the
+// fragment is given a share of the width of b proportional to the length of s.
+func (b *TextBox) measuredFragment(s string, textpos uint64) *TextBox {
+	f := b.fragment(s, textpos)
+	if b.Width != 0 && len(b.text) > 0 {
+		f.Width = b.Width.Scale(int64(len(s)), int64(len(b.text)))
+		f.Height, f.Depth = b.Height, b.Depth
+	}
+	return f
+}
+
+// Extent returns the height and depth of a text box relative to the baseline of
+// the line it is set in, i.e., with the baseline shift of the box applied.
+func (b TextBox) Extent() (dimen.DU, dimen.DU) {
+	return b.Height + b.Shift, b.Depth - b.Shift
+}
+
 // Text returns the enclosed text as a string.
 func (b TextBox) Text() string {
 	return b.text
 }
 
+// Glyphs returns the result of shaping the text of b. For boxes which have not
+// been shaped, the glyph sequence is empty.
+func (b TextBox) Glyphs() glyphing.GlyphSequence {
+	return b.glyphs
+}
+
 // Type is part of interface Knot.
 func (b TextBox) Type() KnotType {
 	return KTTextBox
@@ -286,6 +387,31 @@ func (b TextBox) IsDiscardable() bool {
 	return false
 }
 
+// Insert inserts a string s at byte position at into the text of a text box and
+// widens the box by w. This is intended for justification, e.g., to insert kashidas
+// into Arabic words. Shaping results for the box are dropped, as they are no longer
+// valid; clients have to re-shape the box text if they need glyph information.
+// If at is not a valid position within the box text, nothing is done.
+func (b *TextBox) Insert(at int, s string, w dimen.DU) {
+	if at < 0 || at > len(b.text) {
+		return
+	}
+	b.text = b.text[:at] + s + b.text[at:]
+	b.Width += w
+	b.glyphs = glyphing.GlyphSequence{}
+}
+
+// Split splits a text box after byte position at of its text. For a measured box,
+// both parts are given a share of the width of b proportional to the length of
+// their text. If at is not a valid position within the box text, Split returns
+// b and nil.
+func (b *TextBox) Split(at int) (*TextBox, *TextBox) {
+	if at < 0 || at > len(b.text) {
+		return b, nil
+	}
+	return b.measuredFragment(b.text[:at], b.Position), b.measuredFragment(b.text[at:], b.Position+uint64(at))
+}
+
 var _ Knot = &TextBox{}
 
 // --- Penalty ---------------------------------------------------------------
@@ -382,12 +508,25 @@ func (kh *Khipu) ReplaceKnot(inx int64, knot Knot) Knot {
 	return nil
 }
 
+// InsertKnot inserts a knot at position inx, shifting the knots from inx
+// onwards to the right. If inx is not a valid index for the khipu or the position
+// right after its end, nothing is done.
+func (kh *Khipu) InsertKnot(inx int64, knot Knot) *Khipu {
+	if inx < 0 || inx > int64(len(kh.knots)) {
+		return kh
+	}
+	kh.knots = append(kh.knots, nil)
+	copy(kh.knots[inx+1:], kh.knots[inx:])
+	kh.knots[inx] = knot
+	return kh
+}
+
 // Measure returns the widths of a subset of this knot list. The subset runs from
 // index [from ... to-1]. The method returns natural, maximum and minimum
 // width.
 func (kh *Khipu) Measure(from, to int64) (dimen.DU, dimen.DU, dimen.DU) {
 	var w, max, min dimen.DU
-	to = iMax(to, int64(len(kh.knots)))
+	to = iMin(to, int64(len(kh.knots)))
 	for i := from; i < to; i++ {
 		knot := kh.knots[i]
 		w += knot.W()
@@ -422,7 +561,7 @@ func (kh *Khipu) Reach(start int, distance dimen.DU) (int, int) {
 
 // MaxWidth finds the maximum width of the knots in the range [from ... to-1].
 func (kh *Khipu) MaxWidth(from, to int64) dimen.DU {
-	to = iMax(to, int64(len(kh.knots)))
+	to = iMin(to, int64(len(kh.knots)))
 	var w dimen.DU
 	for i := from; i < to; i++ {
 		knot := kh.knots[i]
@@ -435,17 +574,18 @@ func (kh *Khipu) MaxWidth(from, to int64) dimen.DU {
 
 // MaxHeightAndDepth finds the maximum height and depth of the knots in the range
 // [from ... to-1].
-// Only knots of type TextBox are considered.
+// Only knots of type TextBox are considered, with their baseline shift applied.
 func (kh *Khipu) MaxHeightAndDepth(from, to int64) (dimen.DU, dimen.DU) {
 	to = iMax(from, iMin(to, int64(len(kh.knots))))
 	var h, d dimen.DU
 	for i := from; i < to; i++ {
 		if knot, ok := kh.knots[i].(*TextBox); ok {
-			if knot.Height > h {
-				h = knot.Height
+			height, depth := knot.Extent()
+			if height > h {
+				h = height
 			}
-			if knot.Depth > d {
-				d = knot.Depth
+			if depth > d {
+				d = depth
 			}
 		}
 	}
@@ -462,7 +602,13 @@ func (kh *Khipu) Text(from, to int64) string {
 		if knot.Type() == KTTextBox {
 			b.WriteString(knot.(*TextBox).text)
 			spacecnt = 0
-		} else if knot.Type() == KTGlue {
+		} else if knot.Type() == KTDiscretionary {
+			if d, ok := knot.(Discretionary); ok && d.NoBreak != nil {
+				b.WriteString(d.NoBreak.text)
+			}
+		} else if knot.Type() == KTTab {
+			b.WriteString("\t")
+		} else if knot.Type() == KTGlue || knot.Type() == KTLeaders {
 			if spacecnt == 0 {
 				b.WriteString(" ")
 			}
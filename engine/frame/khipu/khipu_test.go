@@ -1,12 +1,18 @@
 package khipu
 
 import (
+	"context"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/hyphenation"
 	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	"github.com/npillmayer/tyse/engine/glyphing/monospace"
+	"github.com/npillmayer/uax/bidi"
 )
 
 func TestDimen(t *testing.T) {
@@ -31,6 +37,87 @@ func TestKhipu(t *testing.T) {
 	}
 }
 
+func TestMeasure(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	kh := NewKhipu()
+	kh.AppendKnot(NewGlue(10*dimen.PT, 2*dimen.PT, 3*dimen.PT))
+	kh.AppendKnot(NewGlue(20*dimen.PT, 0, 0))
+	kh.AppendKnot(NewGlue(30*dimen.PT, 0, 0))
+	if w, max, min := kh.Measure(0, 1); w != 10*dimen.PT || max != 13*dimen.PT || min != 8*dimen.PT {
+		t.Errorf("expected first knot to measure 10pt/13pt/8pt, is %s/%s/%s", w, max, min)
+	}
+	if w, _, _ := kh.Measure(1, 10); w != 50*dimen.PT {
+		t.Errorf("expected range beyond end of khipu to measure 50pt, is %s", w)
+	}
+	if w := kh.MaxWidth(0, 2); w != 20*dimen.PT {
+		t.Errorf("expected max width of first 2 knots to be 20pt, is %s", w)
+	}
+}
+
+func TestInsertKnot(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	kh := NewKhipu()
+	kh.AppendKnot(NewTextBox("Hello", 0)).AppendKnot(NewTextBox("World", 5))
+	kh.InsertKnot(1, NewGlue(dimen.PT, 0, 0)).InsertKnot(3, Penalty(0)).InsertKnot(7, Kern(0))
+	if kh.Length() != 4 || kh.knots[1].Type() != KTGlue || kh.knots[3].Type() != KTPenalty {
+		t.Errorf("unexpected khipu after inserting knots: %s", kh)
+	}
+}
+
+func TestTextBoxInsert(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	box := NewTextBox("Hllo", 0)
+	box.Width = 4 * dimen.PT
+	box.Insert(1, "e", dimen.PT)
+	box.Insert(17, "!", dimen.PT) // invalid position
+	if box.Text() != "Hello" || box.W() != 5*dimen.PT {
+		t.Errorf("expected box to contain 'Hello' at 5pt, is %q at %s", box.Text(), box.W())
+	}
+}
+
+func TestTextBoxSplit(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	box := NewTextBox("Hello", 10)
+	box.Width = 5 * dimen.PT
+	first, rest := box.Split(1)
+	if first.Text() != "H" || first.W() != dimen.PT || rest.Text() != "ello" || rest.Position != 11 {
+		t.Errorf("expected box to be split into 'H' at 1pt and 'ello' at 11, is %v/%v", first, rest)
+	}
+}
+
+func TestBaselineShift(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	k := NewKhipu()
+	k.AppendKnot(&TextBox{Width: 20 * dimen.PT, Height: 8 * dimen.PT, Depth: 2 * dimen.PT, text: "x"})
+	k.AppendKnot(&TextBox{Width: 10 * dimen.PT, Height: 8 * dimen.PT, Depth: 2 * dimen.PT,
+		Shift: 4 * dimen.PT, text: "2"})
+	k.AppendKnot(&TextBox{Width: 10 * dimen.PT, Height: 8 * dimen.PT, Depth: 2 * dimen.PT,
+		Shift: -3 * dimen.PT, text: "i"})
+	if h, d := k.MaxHeightAndDepth(0, 2); h != 12*dimen.PT || d != 2*dimen.PT {
+		t.Errorf("expected superscript to raise line height to 12pt, have %s/%s", h, d)
+	}
+	if h, d := k.MaxHeightAndDepth(0, 3); h != 12*dimen.PT || d != 5*dimen.PT {
+		t.Errorf("expected subscript to lower line depth to 5pt, have %s/%s", h, d)
+	}
+	adjusted := AdjustSpacing(k, Spacing{LetterSpacing: dimen.PT})
+	adjusted = KinsokuShori(adjusted, nil)
+	for _, knot := range adjusted.knots {
+		if box, ok := knot.(*TextBox); ok && box.Text() == "2" && box.Shift != 4*dimen.PT {
+			t.Errorf("expected baseline shift to survive transformations, is %s", box.Shift)
+		}
+	}
+}
+
 func TestBreaking1(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
 	defer teardown()
@@ -86,3 +173,647 @@ func TestExHyphen(t *testing.T) {
 		t.Errorf("output text != input text")
 	}
 }
+
+func TestHyphenationOptions(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_LANGUAGE, "de_DE")
+	regs.Push(parameters.P_RIGHTHYPHENMIN, 4)
+	x := hyphenation.NewExceptions("Kha-ki")
+	regs.Push(parameters.P_HYPHENEXCEPTIONS, x)
+	opts := hyphenationOptions(regs.S(parameters.P_LANGUAGE), regs)
+	if !opts.Compounds || opts.LeftMin != 2 || opts.RightMin != 4 || opts.Exceptions != x {
+		t.Errorf("expected German options with right minimum 4 and user exceptions, have %+v", opts)
+	}
+	if syllables, ok := hyphenateWord("khaki", nil, opts); !ok || syllables[0] != "kha" {
+		t.Errorf("expected user exception to hyphenate khaki as kha-ki, have %v", syllables)
+	}
+}
+
+func TestDiscretionary(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	d := NewDiscretionary("k-", "k", "ck")
+	d.Pre.Width, d.Post.Width, d.NoBreak.Width = 12*dimen.PT, 6*dimen.PT, 11*dimen.PT
+	if d.W() != 11*dimen.PT || d.MinW() != d.W() || d.MaxW() != d.W() {
+		t.Errorf("expected discretionary to be as wide as its no-break text, is %s", d.W())
+	}
+	if d.PreBreak() != d.Pre || d.PreWidth() != 12*dimen.PT || d.PostWidth() != 6*dimen.PT {
+		t.Errorf("unexpected pre-break/post-break text of discretionary")
+	}
+	kh := NewKhipu()
+	kh.AppendKnot(NewTextBox("ba", 0)).AppendKnot(d).AppendKnot(NewTextBox("en", 4))
+	if out := kh.Text(0, kh.Length()); out != "backen" {
+		t.Errorf("expected text of khipu to contain no-break text, is %q", out)
+	}
+	hyphen := Discretionary{HyphenChar: '-', Width: 3 * dimen.PT}
+	if pre := hyphen.PreBreak(); pre == nil || pre.Text() != "-" || pre.Width != 3*dimen.PT {
+		t.Errorf("expected hyphen as pre-break text, have %v", pre)
+	}
+	if hyphen.W() != 0 || hyphen.PostWidth() != 0 {
+		t.Errorf("expected hyphenation opportunity to be empty if not broken")
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	text := "The quick brown-fox "
+	kh, err := EncodeText(text, WithStartPosition(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	if out := kh.Text(0, kh.Length()); out != "The quick brown-fox " {
+		t.Errorf("unexpected text from khipu: %q", out)
+	}
+	if kh.knots[0].(*TextBox).Position != 10 {
+		t.Errorf("expected first text box at position 10, is at %d", kh.knots[0].(*TextBox).Position)
+	}
+	last := kh.knots[kh.Length()-1]
+	if p, ok := last.(Penalty); !ok || p != -10000 {
+		t.Errorf("expected paragraph to end with a forced break, ends with %v", last)
+	}
+	if kh.knots[kh.Length()-4].Type() != KTTextBox {
+		t.Errorf("expected trailing space to be removed from paragraph")
+	}
+}
+
+func TestEncodeTextMeasure(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	kh, err := EncodeText("Hello World", WithShaper(monospace.Shaper(10*dimen.PT, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, _, _ := kh.Measure(0, 1); w != 50*dimen.PT {
+		t.Errorf("expected 'Hello' to be 50pt wide, is %s", w)
+	}
+}
+
+func TestStreamParagraphs(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	doc := "\nThe quick brown fox\njumps over\n\n  \nthe lazy dog.\r\n\r\nThe end."
+	var texts []string
+	var positions []uint64
+	for segment := range StreamParagraphs(context.Background(), strings.NewReader(doc)) {
+		if segment.Err != nil {
+			t.Fatal(segment.Err)
+		}
+		if segment.Para != len(texts) {
+			t.Errorf("expected paragraph #%d, have #%d", len(texts), segment.Para)
+		}
+		texts = append(texts, segment.Khipu.Text(0, segment.Khipu.Length()))
+		positions = append(positions, segment.Khipu.knots[0].(*TextBox).Position)
+	}
+	if len(texts) != 3 {
+		t.Fatalf("expected document to have 3 paragraphs, have %d: %q", len(texts), texts)
+	}
+	if !strings.HasPrefix(texts[1], "the lazy") || !strings.HasPrefix(texts[2], "The end") {
+		t.Errorf("unexpected paragraphs: %q", texts)
+	}
+	if positions[0] != 1 || positions[1] != 36 {
+		t.Errorf("expected paragraphs to start at text positions 1 and 36, are %v", positions)
+	}
+}
+
+func TestStreamCursor(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	fragments := make(chan string)
+	go func() {
+		for _, f := range []string{"Hello Wo", "rld!\n", "\nSecond", " paragraph\n\n"} {
+			fragments <- f
+		}
+		close(fragments)
+	}()
+	cursor := NewStreamCursor(StreamFragments(context.Background(), fragments))
+	if cursor.Next() {
+		t.Errorf("expected cursor to be positioned before first paragraph")
+	}
+	var texts []string
+	for cursor.NextSegment() {
+		var b strings.Builder
+		for cursor.Next() {
+			if cursor.Knot().Type() == KTTextBox {
+				b.WriteString(cursor.AsTextBox().Text())
+			}
+		}
+		texts = append(texts, b.String())
+	}
+	if cursor.Err() != nil {
+		t.Error(cursor.Err())
+	}
+	if len(texts) != 2 || texts[0] != "HelloWorld!" || texts[1] != "Secondparagraph" {
+		t.Errorf("unexpected streamed paragraphs: %q", texts)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	fragments := make(chan string) // never closed
+	go func() {
+		fragments <- "First paragraph\n\nSecond paragraph\n\nThird"
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	segments := StreamFragments(ctx, fragments)
+	if segment := <-segments; segment.Err != nil || segment.Para != 0 {
+		t.Fatalf("expected first paragraph, have %v", segment)
+	}
+	cancel() // stop consuming early
+	for range segments {
+	}
+	// reaching this point means the producer closed the channel
+}
+
+func TestAdjustSpacing(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	k := NewKhipu()
+	k.AppendKnot(&TextBox{Width: 30 * dimen.PT, text: "Hé́y"})
+	k.AppendKnot(NewGlue(5*dimen.PT, 2*dimen.PT, 3*dimen.PT))
+	k.AppendKnot(&TextBox{Width: 10 * dimen.PT, text: "كتب"})
+	k.AppendKnot(NewFill(2))
+	w, _, _ := k.Measure(0, k.Length())
+	adjusted := AdjustSpacing(k, Spacing{LetterSpacing: dimen.PT, WordSpacing: 2 * dimen.PT})
+	if adjusted.Length() != 8 {
+		t.Fatalf("expected latin word to be split into 3 clusters, have %s", adjusted)
+	}
+	if adjusted.knots[1].Type() != KTKern || adjusted.knots[4].Type() != KTTextBox {
+		t.Errorf("expected clusters to be separated by kerns, have %s", adjusted)
+	}
+	if text := adjusted.knots[2].(*TextBox).Text(); text != "é́" {
+		t.Errorf("expected combining mark to stay with its letter, have %q", text)
+	}
+	// 2 kerns between letters, letter- and word-spacing for the space
+	if aw, _, _ := adjusted.Measure(0, adjusted.Length()); aw != w+5*dimen.PT {
+		t.Errorf("expected spacing to add 5pt, line width changed from %s to %s", w, aw)
+	}
+	if g := adjusted.knots[7].(Glue); g.MaxW() != dimen.Fill {
+		t.Errorf("expected infinite glue to be unchanged, is %v", g)
+	}
+}
+
+func TestAdjustWrapping(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	k := NewKhipu()
+	k.AppendKnot(&TextBox{Width: 30 * dimen.PT, text: "Hy"})
+	k.AppendKnot(Discretionary{HyphenChar: '-'})
+	k.AppendKnot(&TextBox{Width: 30 * dimen.PT, text: "phen"})
+	k.AppendKnot(NewGlue(5*dimen.PT, 2*dimen.PT, 3*dimen.PT))
+	k.AppendKnot(Penalty(0))
+	k.AppendKnot(&TextBox{Width: 10 * dimen.PT, text: "x"})
+	k.AppendKnot(Penalty(-10000))
+	nowrap := AdjustWrapping(k, Wrapping{NoWrap: true})
+	if nowrap.Length() != 6 {
+		t.Fatalf("expected discretionary to be dropped, have %s", nowrap)
+	}
+	if p := nowrap.knots[3].(Penalty); p < 10000 {
+		t.Errorf("expected break at space to be suppressed, penalty is %d", p)
+	}
+	if p := nowrap.knots[5].(Penalty); p != -10000 {
+		t.Errorf("expected forced break to be kept, penalty is %d", p)
+	}
+	breakall := AdjustWrapping(k, Wrapping{BreakAll: true, NoHyphens: true})
+	if breakall.Length() != 14 {
+		t.Fatalf("expected words to be split into letters, have %s", breakall)
+	}
+	if breakall.knots[1].Type() != KTPenalty || breakall.knots[2].(*TextBox).Width != 15*dimen.PT {
+		t.Errorf("expected letters to be separated by penalties, have %s", breakall)
+	}
+	if w, _, _ := breakall.Measure(0, breakall.Length()); w != 75*dimen.PT {
+		t.Errorf("expected width to be unchanged, is %s", w)
+	}
+	if AdjustWrapping(k, Wrapping{}) != k {
+		t.Errorf("expected khipu to be unchanged without adjustments")
+	}
+}
+
+func TestOpticalTracking(t *testing.T) {
+	if tr := OpticalTracking(12 * dimen.PT); tr != 0 {
+		t.Errorf("expected no tracking for 12pt, have %s", tr)
+	}
+	if tr := OpticalTracking(48 * dimen.PT); tr >= 0 {
+		t.Errorf("expected display sizes to be tracked tighter, have %s", tr)
+	}
+	if tr := OpticalTracking(7 * dimen.PT); tr <= 0 {
+		t.Errorf("expected small sizes to be tracked looser, have %s", tr)
+	}
+}
+
+func TestEncodeTextWithSpacing(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	kh, err := EncodeText("ab cd", WithShaper(monospace.Shaper(10*dimen.PT, nil)),
+		WithSpacing(Spacing{LetterSpacing: dimen.PT}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kh.knots[0].W() != 10*dimen.PT || kh.knots[1].Type() != KTKern {
+		t.Errorf("expected letters to be measured separately and spaced, have %s", kh)
+	}
+}
+
+func TestEncodeWhiteSpace(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	k := NewKhipu().AppendKnot(NewTextBox("a", 0))
+	whiteSpaceRules("normal").encode(" \n ", 1, -10000, k, regs)
+	whiteSpaceRules("normal").encode("  ", 4, 100, k, regs)
+	if k.Length() != 3 || k.knots[2] != Penalty(0) {
+		t.Errorf("expected spaces and newlines to collapse into a single space, have %s", k)
+	}
+	k = NewKhipu().AppendKnot(NewTextBox("a", 0))
+	whiteSpaceRules("pre-line").encode(" \n  ", 1, -10000, k, regs)
+	if k.Length() != 4 || k.knots[3] != Penalty(-10000) {
+		t.Errorf("expected newline to force a break and spaces to vanish, have %s", k)
+	}
+	k = NewKhipu().AppendKnot(NewTextBox("a", 0))
+	whiteSpaceRules("pre").encode("  \n    ", 1, 0, k, regs)
+	if k.Length() != 7 {
+		t.Fatalf("expected 7 knots for preserved white space, have %s", k)
+	}
+	if box, ok := k.knots[4].(*TextBox); !ok || box.Text() != "" || box.Position != 4 {
+		t.Errorf("expected indentation to be kept from being discarded, have %s", k)
+	}
+	if g := k.knots[5]; g.W() != 20*dimen.PT || g.MaxW() != g.W() || k.knots[6] != Penalty(dimen.Infinity) {
+		t.Errorf("expected indentation to be set as unbreakable fixed glue, have %s", k)
+	}
+	//
+	regs.Push(parameters.P_WHITESPACE, "pre")
+	kh, err := EncodeText("if x {\n\treturn\n}", WithRegisters(regs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaks, tab := 0, false
+	for _, knot := range kh.knots {
+		if p, ok := knot.(Penalty); ok && p <= -10000 {
+			breaks++
+		} else if knot.Type() == KTTab && knot.W() == tabSize*5*dimen.PT {
+			tab = true
+		}
+	}
+	if breaks != 3 || !tab {
+		t.Errorf("expected preformatted text to keep lines and tab, have %s", kh)
+	}
+}
+
+func TestEncodeUnicodeSpaces(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	for _, x := range []struct {
+		space   string
+		w       dimen.DU
+		stretch bool
+	}{
+		{"\u2009", 3 * dimen.PT, false},  // thin space
+		{"\u2003", 15 * dimen.PT, false}, // em space
+		{"\u00a0", 5 * dimen.PT, true},   // no-break space
+		{" \u00a0 ", 10 * dimen.PT, true},
+		{" \u2009", 8 * dimen.PT, true},
+	} {
+		k := NewKhipu().AppendKnot(NewTextBox("a", 0))
+		whiteSpaceRules("normal").encode(x.space, 1, 0, k, regs)
+		if k.Length() != 3 {
+			t.Fatalf("expected %+q to be encoded as a single glue, have %s", x.space, k)
+		}
+		if g := k.knots[1]; g.W() != x.w || (g.MaxW() > g.W()) != x.stretch {
+			t.Errorf("expected %+q to be glue of %s, stretchable=%v, have %s", x.space, x.w, x.stretch, k)
+		}
+	}
+	k := NewKhipu().AppendKnot(NewTextBox("a", 0))
+	whiteSpaceRules("normal").encode(" ", 1, 0, k, regs)
+	whiteSpaceRules("normal").encode("\u2009", 2, 0, k, regs)
+	if k.Length() != 5 {
+		t.Errorf("expected thin space not to collapse with preceding space, have %s", k)
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	if s := Normalize("e\u0301", regs); s != "\u00e9" {
+		t.Errorf("expected text to be normalized to NFC by default, have %+q", s)
+	}
+	regs.Push(parameters.P_NORMALIZATION, "NFD")
+	k, err := EncodeText("caf\u00e9", WithRegisters(regs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box, ok := k.knots[0].(*TextBox); !ok || box.Text() != "cafe\u0301" {
+		t.Errorf("expected text to be normalized to NFD, have %s", k)
+	}
+}
+
+func TestKinsokuShori(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_LANGUAGE, "ja_JP")
+	regs.Push(parameters.P_CJKPENALTY, 10)
+	text := "「こんにちは」と言った。"
+	kh, err := EncodeText(text, WithRegisters(regs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	for i := int64(1); i+1 < kh.Length(); i++ {
+		before, ok1 := kh.knots[i-1].(*TextBox)
+		after, ok2 := kh.knots[i+1].(*TextBox)
+		if !ok1 || !ok2 {
+			continue
+		}
+		p := kh.knots[i].(Penalty)
+		switch {
+		case strings.HasPrefix(after.Text(), "」") || strings.HasPrefix(after.Text(), "。"):
+			if p < 10000 {
+				t.Errorf("line must not start with %q", after.Text())
+			}
+		case p != 10:
+			t.Errorf("expected CJK penalty between %q and %q, have %d", before.Text(), after.Text(), p)
+		}
+	}
+	if !strings.Contains(kh.String(), "«っ") {
+		t.Errorf("expected break opportunity before small kana for normal line breaking")
+	}
+	regs.Push(parameters.P_LINEBREAKSTRICTNESS, "strict")
+	kh, _ = EncodeText(text, WithRegisters(regs))
+	if strings.Contains(kh.String(), "«っ") {
+		t.Errorf("expected no break opportunity before small kana for strict line breaking")
+	}
+	if strings.TrimSpace(kh.Text(0, kh.Length())) != text {
+		t.Errorf("expected text to be unchanged, is %q", kh.Text(0, kh.Length()))
+	}
+}
+
+func TestParagraphDirection(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	for i, x := range []struct {
+		text  string
+		dir   bidi.Direction
+		found bool
+	}{
+		{"Hello World", bidi.LeftToRight, true},
+		{"123 שלום world", bidi.RightToLeft, true},
+		{"“مرحبا” hello", bidi.RightToLeft, true},
+		{"⁧abc⁩ שלום", bidi.RightToLeft, true}, // RLI…PDI is skipped
+		{"12 + 3 = 15", bidi.LeftToRight, false},
+	} {
+		dir, found := ParagraphDirection(x.text, nil)
+		if dir != x.dir || found != x.found {
+			t.Errorf("#%d: expected %v/%v for %q, have %v/%v", i, x.dir, x.found, x.text, dir, found)
+		}
+	}
+	markup := func(pos uint64) int { // isolate for "abc"
+		switch pos {
+		case 0:
+			return bidi.MarkupLRI
+		case 3:
+			return bidi.MarkupPDI
+		}
+		return 0
+	}
+	if dir, _ := ParagraphDirection("abc שלום", markup); dir != bidi.RightToLeft {
+		t.Errorf("expected out-of-line isolate to be skipped, have %v", dir)
+	}
+}
+
+// directionShaper sets every rune 5pt wide and records the direction every text
+// has been shaped in.
+type directionShaper map[string]glyphing.Direction
+
+func (s directionShaper) Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, ctx [][]rune,
+	p glyphing.Params) (glyphing.GlyphSequence, error) {
+	//
+	var runes []rune
+	for r, _, err := text.ReadRune(); err == nil; r, _, err = text.ReadRune() {
+		runes = append(runes, r)
+	}
+	s[string(runes)] = p.Direction
+	seq := glyphing.GlyphSequence{Glyphs: buf[:0]}
+	for i, r := range runes {
+		seq.Glyphs = append(seq.Glyphs, glyphing.ShapedGlyph{ClusterID: i, CodePoint: r, XAdvance: 5 * dimen.PT})
+		seq.W += 5 * dimen.PT
+	}
+	seq.H = 7 * dimen.PT
+	return seq, nil
+}
+
+func TestBidiRunDirection(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	shaper := directionShaper{}
+	kh, err := EncodeText("hello שלום world", WithShaper(shaper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	for text, dir := range map[string]glyphing.Direction{
+		"hello": glyphing.LeftToRight,
+		"שלום":  glyphing.RightToLeft,
+		"world": glyphing.LeftToRight,
+	} {
+		if d, ok := shaper[text]; !ok || d != dir {
+			t.Errorf("expected %q to be shaped in direction %v, have %v", text, dir, d)
+		}
+	}
+}
+
+func TestGraphemeClusters(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	family := "\U0001F468\u200d\U0001F469\u200d\U0001F467\u200d\U0001F466"
+	kh, err := EncodeText("Family: "+family+"\U0001F44D\U0001F3FD!", WithShaper(monospace.Shaper(10*dimen.PT, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	for _, knot := range kh.knots {
+		if box, ok := knot.(*TextBox); ok {
+			if strings.HasPrefix(box.text, "\u200d") || strings.HasSuffix(box.text, "\u200d") ||
+				strings.HasPrefix(box.text, "\U0001F3FD") {
+				t.Errorf("expected grapheme clusters to be kept together, have text box %q", box.text)
+			}
+		}
+	}
+	syllables := keepGraphemes("cafe\u0301s", []string{"ca", "fe", "\u0301s"})
+	if len(syllables) != 2 || syllables[1] != "fe\u0301s" {
+		t.Errorf("expected combining mark to stay with its base, have %q", syllables)
+	}
+}
+
+func TestBreakControls(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_HYPHENPENALTY, 50)
+	kh, err := EncodeText("co\u00adop\u200bera\u2060tion", WithRegisters(regs),
+		WithShaper(monospace.Shaper(10*dimen.PT, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	expected := []KnotType{KTTextBox, KTDiscretionary, KTPenalty, KTTextBox, KTPenalty, KTTextBox, KTPenalty, KTTextBox}
+	if kh.Length() < int64(len(expected)) {
+		t.Fatalf("expected at least %d knots, have %d", len(expected), kh.Length())
+	}
+	for i, typ := range expected {
+		if kh.knots[i].Type() != typ {
+			t.Errorf("expected knot #%d to be of type %v, is %v", i, typ, kh.knots[i])
+		}
+	}
+	if out := kh.Text(0, kh.Length()); out != "cooperation" {
+		t.Errorf("expected break controls to be removed from text, have %q", out)
+	}
+	if d, ok := kh.knots[1].(Discretionary); !ok || d.HyphenChar != '-' || d.Width != 10*dimen.PT {
+		t.Errorf("expected soft hyphen to be a measured discretionary, is %v", kh.knots[1])
+	}
+	if kh.knots[2] != Penalty(50) || kh.knots[4] != Penalty(0) || kh.knots[6] != Penalty(dimen.Infinity) {
+		t.Errorf("unexpected penalties for soft hyphen, zero-width space or word joiner: %v, %v, %v",
+			kh.knots[2], kh.knots[4], kh.knots[6])
+	}
+}
+
+// ligatureShaper sets every rune 5pt wide, except for f-ligatures, which are set
+// as a single glyph of 8pt.
+type ligatureShaper struct{}
+
+func (ligatureShaper) Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, ctx [][]rune,
+	p glyphing.Params) (glyphing.GlyphSequence, error) {
+	//
+	var runes []rune
+	for r, _, err := text.ReadRune(); err == nil; r, _, err = text.ReadRune() {
+		runes = append(runes, r)
+	}
+	seq := glyphing.GlyphSequence{Glyphs: buf[:0]}
+	for i := 0; i < len(runes); {
+		g := glyphing.ShapedGlyph{ClusterID: i, CodePoint: runes[i], XAdvance: 5 * dimen.PT}
+		n := 1
+		for _, lig := range []string{"ffi", "ffl", "ff", "fi", "fl"} {
+			if strings.HasPrefix(string(runes[i:]), lig) {
+				n, g.XAdvance = len(lig), 8*dimen.PT
+				break
+			}
+		}
+		seq.Glyphs = append(seq.Glyphs, g)
+		seq.W += g.XAdvance
+		i += n
+	}
+	seq.H = 7 * dimen.PT
+	return seq, nil
+}
+
+func TestLigatureHyphenation(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_HYPHENPENALTY, 50)
+	kh, err := EncodeText("dif\u00adfi\u00adcult", WithRegisters(regs), WithShaper(ligatureShaper{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	expected := []KnotType{KTTextBox, KTDiscretionary, KTPenalty, KTDiscretionary, KTPenalty, KTTextBox}
+	if kh.Length() < int64(len(expected)) {
+		t.Fatalf("expected at least %d knots, have %d", len(expected), kh.Length())
+	}
+	for i, typ := range expected {
+		if kh.knots[i].Type() != typ {
+			t.Fatalf("expected knot #%d to be of type %v, is %v", i, typ, kh.knots[i])
+		}
+	}
+	if out := kh.Text(0, kh.Length()); out != "difficult" {
+		t.Errorf("expected text of khipu to be unchanged, is %q", out)
+	}
+	d := kh.knots[1].(Discretionary)
+	if d.Pre.Text() != "f-" || d.Post.Text() != "fi" || d.NoBreak.Text() != "ffi" {
+		t.Errorf("expected hyphenation point within ffi-ligature to be re-encoded, is %v", d)
+	}
+	if d.W() != 8*dimen.PT || d.PreWidth() != 10*dimen.PT || d.PostWidth() != 8*dimen.PT {
+		t.Errorf("expected ligatures to be shaped for discretionary texts, have %s/%s/%s",
+			d.PreWidth(), d.PostWidth(), d.W())
+	}
+	if simple := kh.knots[3].(Discretionary); simple.NoBreak != nil || simple.Width != 5*dimen.PT {
+		t.Errorf("expected hyphenation point after ligature to be left untouched, is %v", simple)
+	}
+}
+
+// kerningShaper sets every rune 5pt wide, except for an 'a' followed by a 'V',
+// which is kerned by -2pt.
+type kerningShaper struct{}
+
+func (kerningShaper) Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, ctx [][]rune,
+	p glyphing.Params) (glyphing.GlyphSequence, error) {
+	//
+	var runes []rune
+	for r, _, err := text.ReadRune(); err == nil; r, _, err = text.ReadRune() {
+		runes = append(runes, r)
+	}
+	seq := glyphing.GlyphSequence{Glyphs: buf[:0]}
+	for i, r := range runes {
+		g := glyphing.ShapedGlyph{ClusterID: i, CodePoint: r, XAdvance: 5 * dimen.PT}
+		if r == 'a' && i+1 < len(runes) && runes[i+1] == 'V' {
+			g.XAdvance -= 2 * dimen.PT
+		}
+		seq.Glyphs = append(seq.Glyphs, g)
+		seq.W += g.XAdvance
+	}
+	seq.H = 7 * dimen.PT
+	return seq, nil
+}
+
+func TestKerningHyphenation(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.khipu")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_HYPHENPENALTY, 50)
+	kh, err := EncodeText("Ka\u00adVa\u00adlo", WithRegisters(regs), WithShaper(kerningShaper{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("khipu = %s", kh)
+	expected := []KnotType{KTTextBox, KTDiscretionary, KTPenalty, KTKern, KTTextBox, KTDiscretionary,
+		KTPenalty, KTTextBox}
+	if kh.Length() < int64(len(expected)) {
+		t.Fatalf("expected at least %d knots, have %d", len(expected), kh.Length())
+	}
+	for i, typ := range expected {
+		if kh.knots[i].Type() != typ {
+			t.Fatalf("expected knot #%d to be of type %v, is %v", i, typ, kh.knots[i])
+		}
+	}
+	if kern := kh.knots[3].W(); kern != -2*dimen.PT {
+		t.Errorf("expected kern of -2pt between 'a' and 'V', is %s", kern)
+	}
+	if w := kh.knots[0].W(); w != 10*dimen.PT {
+		t.Errorf("expected syllable 'Ka' to be set without kerning, is %s wide", w)
+	}
+}
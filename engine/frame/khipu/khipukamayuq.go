@@ -12,25 +12,30 @@ Copyright © 2017–2021 Norbert Pillmayer <norbert@pillmayer.com>
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"math"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/npillmayer/cords/styled"
+	"github.com/npillmayer/tyse/core"
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/hyphenation"
 	"github.com/npillmayer/tyse/core/locate"
 	params "github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/core/spans"
 	"github.com/npillmayer/tyse/engine/frame"
 	"github.com/npillmayer/tyse/engine/glyphing"
+	"github.com/npillmayer/tyse/engine/text"
 	"github.com/npillmayer/uax"
 	"github.com/npillmayer/uax/bidi"
 	"github.com/npillmayer/uax/segment"
 	"github.com/npillmayer/uax/uax14"
 	"github.com/npillmayer/uax/uax29"
 	"golang.org/x/text/language"
-	"golang.org/x/text/unicode/norm"
 )
 
 // A TypesettingPipeline consists of steps to produce a khipu from text.
@@ -43,7 +48,6 @@ type TypesettingPipeline struct {
 }
 
 type typEnv struct { // typesetting environment
-	shaper   glyphing.Shaper
 	pipeline *TypesettingPipeline
 	regs     *params.TypesettingRegisters
 	levels   *bidi.ResolvedLevels
@@ -56,25 +60,63 @@ type styledItem struct {
 	prevStyles, styles styled.Style
 }
 
+// EncodeStyledParagraph transforms a styled paragraph into a khipu. Text boxes
+// are measured with shaper. If pipeline is nil, a default pipeline is used (see
+// PrepareTypesettingPipeline).
+//
+// As EncodeText does, EncodeStyledParagraph adjusts line break opportunities for
+// CJK text, hyphenates words if register P_MINHYPHENLENGTH is set to a finite
+// value, and applies options WithSpacing and WithWrapping, before text boxes are
+// shaped and measured. Other options are ignored.
+//
+// For encoding plain text, see EncodeText.
+func EncodeStyledParagraph(para *styled.Paragraph, startpos uint64, shaper glyphing.Shaper,
+	pipeline *TypesettingPipeline, regs *params.TypesettingRegisters, opts ...Option) (*Khipu, error) {
+	//
+	return EncodeStyledParagraphContext(context.Background(), para, startpos, shaper, pipeline, regs, opts...)
+}
+
+// EncodeParagraph transforms a styled paragraph into a khipu.
+//
+// Deprecated: EncodeParagraph has been renamed to EncodeStyledParagraph. For
+// encoding plain text, use EncodeText.
 func EncodeParagraph(para *styled.Paragraph, startpos uint64, shaper glyphing.Shaper,
 	pipeline *TypesettingPipeline, regs *params.TypesettingRegisters) (*Khipu, error) {
 	//
+	return EncodeStyledParagraph(para, startpos, shaper, pipeline, regs)
+}
+
+// EncodeStyledParagraphContext is like EncodeStyledParagraph, but records a trace span
+// as a child of a span contained in ctx (see package core/spans). Encoding stops
+// with the error of ctx if ctx is cancelled or its deadline is exceeded.
+func EncodeStyledParagraphContext(ctx context.Context, para *styled.Paragraph, startpos uint64,
+	shaper glyphing.Shaper, pipeline *TypesettingPipeline, regs *params.TypesettingRegisters,
+	opts ...Option) (*Khipu, error) {
+	//
+	kk := &khipukamayuq{}
+	for _, opt := range opts {
+		opt(kk)
+	}
+	ctx, span := spans.Start(ctx, spans.Stage, "khipu.EncodeStyledParagraph")
+	defer span.End()
 	if regs == nil {
 		regs = params.NewTypesettingRegisters()
 	}
 	env := typEnv{
-		shaper:   shaper,
 		pipeline: pipeline,
 		regs:     regs,
 		levels:   para.BidiLevels(),
 	}
 	text := para.Raw().Reader()
 	//paraLen := para.Raw().Len()
-	env.pipeline = PrepareTypesettingPipeline(text, env.pipeline)
+	env.pipeline = prepareTypesettingPipeline(text, env.pipeline, regs)
 	var result *Khipu = NewKhipu()
 	tracer().Debugf("------------ start of para -----------")
 	//T().Debugf("para text = '%s'", para.Raw().String())
-	para.EachStyleRun(func(content string, sty styled.Style, pos, length uint64) error {
+	err := para.EachStyleRun(func(content string, sty styled.Style, pos, length uint64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		item := styledItem{
 			offset: para.Offset,
 			end:    para.Offset + para.Raw().Len(),
@@ -91,6 +133,22 @@ func EncodeParagraph(para *styled.Paragraph, startpos uint64, shaper glyphing.Sh
 		return nil
 	})
 	tracer().Debugf("------------- end of para ------------")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	result = KinsokuShori(result, regs)
+	if regs.N(params.P_MINHYPHENLENGTH) < dimen.Infinity {
+		HyphenateTextBoxes(result, env.pipeline, regs)
+	}
+	result = AdjustSpacing(result, kk.spacing)
+	result = AdjustWrapping(result, kk.wrapping)
+	if shaper != nil {
+		if err = shapeTextBoxes(ctx, result, shaper); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
@@ -101,12 +159,18 @@ func encodeRun(text io.Reader, item styledItem, env typEnv) (k *Khipu, err error
 		stopper = math.MaxInt // set stopper to unreachable value
 	}
 	seg := env.pipeline.segmenter
+	ws := whiteSpaceRules(env.regs.S(params.P_WHITESPACE))
 	for seg.BoundedNext(int(stopper)) {
 		segment := seg.Text()
 		p := penlty(seg.Penalties())
 		tracer().Debugf("next segment = '%s'\twith penalties %d|%d", segment, p.p1, p.p2)
 		item.from = item.to
 		item.to += uint64(len(segment))
+		if p.breaksAtSpace() && isspace(segment) { // encode into k, to collapse with preceding spaces
+			tracer().Debugf("khipukamayuq: encode space with penalites %v", p)
+			ws.encode(segment, item.from, p.p2, k, env.regs)
+			continue
+		}
 		kfrag, err := encodeSegment(segment, p, item, env)
 		// if regs.N(params.P_MINHYPHENLENGTH) < dimen.Infty {
 		// 	HyphenateTextBoxes(k, pipeline, regs)
@@ -122,14 +186,14 @@ func encodeRun(text io.Reader, item styledItem, env typEnv) (k *Khipu, err error
 
 func encodeSegment(segm string, p penalties, item styledItem, env typEnv) (*Khipu, error) {
 	//
-	if p.breaksAtSpace() && isspace(segm) {
-		return encodeSpace(segm, p, item.styles, env.regs), nil
-	}
 	if p.canWrapLine() && p.breaksAtSpace() {
 		// line wrap at space
 		// b := NewTextBox(seg.Text(), textpos)
 		// khipu.AppendKnot(b).AppendKnot(Penalty(dimen.Infty))
-		b := encodeText(segm, item, env)
+		b, err := encodeText(segm, item, env)
+		if err != nil {
+			return nil, err
+		}
 		b.AppendKnot(Penalty(p.p1))
 		return b, nil
 	}
@@ -145,21 +209,14 @@ func encodeSegment(segm string, p penalties, item styledItem, env typEnv) (*Khip
 	// pen := Penalty(dimen.Infty)
 	// khipu.AppendKnot(b).AppendKnot(pen)
 	tracer().Debugf("no line wrap possible, encode unbreakable text")
-	b := encodeText(segm, item, env)
+	b, err := encodeText(segm, item, env)
+	if err != nil {
+		return nil, err
+	}
 	b.AppendKnot(Penalty(dimen.Infinity))
 	return b, nil
 }
 
-func encodeSpace(fragm string, p penalties, styles styled.Style,
-	regs *params.TypesettingRegisters) *Khipu {
-	//
-	tracer().Debugf("khipukamayuq: encode space with penalites %v", p)
-	k := NewKhipu()
-	g := spaceglue(regs)
-	k.AppendKnot(g).AppendKnot(Penalty(p.p2))
-	return k
-}
-
 // Currently we do a re-scan of every segment to extract word break opportunities.
 // That is obviously not the most efficient way to go about it, as we already scanned
 // every input code-point to get here in the first place.
@@ -182,7 +239,8 @@ func encodeSpace(fragm string, p penalties, styles styled.Style,
 // For now, our proposition is that paragraphs are the finest level down to which we can
 // parallelize things. From paragraphs on we switch to sequential mode.
 //
-func encodeText(fragm string, item styledItem, env typEnv) *Khipu {
+// Shaping errors are reported within error domain core.ErrShaping.
+func encodeText(fragm string, item styledItem, env typEnv) (*Khipu, error) {
 	//
 	wordsKhipu := NewKhipu()
 	// 1. break fragment into words by UAX#29
@@ -207,29 +265,76 @@ func encodeText(fragm string, item styledItem, env typEnv) *Khipu {
 		// 2. configure shaper
 		styleset := item.styles.(frame.StyleSet)
 		shapingParams := glyphing.Params{
-			Font:      styleset.Font(),
-			Script:    scriptForText(item.styles, env.regs),
-			Direction: directionForText(item.styles, bidiDir, env.regs),
-			Language:  matchLang(item.styles, env.regs.S(params.P_LANGUAGE)),
+			Font:       styleset.Font(),
+			Direction:  directionForText(item.styles, bidiDir, env.regs),
+			Language:   matchLang(item.styles, env.regs.S(params.P_LANGUAGE)),
+			Features:   glyphing.RunFeatures(styleset.FontFeatures()),
+			SizeAdjust: styleset.FontSizeAdjust(),
 		}
-		// env.shaper.SetDirection(directionForText(item.styles, bidiDir, env.regs))
-		// env.shaper.SetScript(scriptForText(item.styles, env.regs))
-		// env.shaper.SetLanguage(env.regs.S(params.P_LANGUAGE))
 		//
 		// 3. do NOT hyphenate => leave this to line breaker
-		// 4. attach glyph sequences to text boxes
-		box := NewTextBox(word, pos)
-		//
-		wordrd := strings.NewReader(word)
-		box.glyphs, _ = env.shaper.Shape(wordrd, nil, nil, shapingParams)
-		//
-		// 5. measure text of glyph sequence
-		box.Width, box.Height, box.Depth = box.glyphs.BoundingBox()
+		// 4. create text boxes, one for every run of script, to be shaped after
+		//    line break opportunities and spacing have been adjusted
+		for _, run := range text.ItemizeText(word, bidiDir) {
+			shapingParams.Script = scriptForRun(run, item.styles, env.regs)
+			box := NewTextBox(run.Text, pos+run.From)
+			p := shapingParams
+			box.shaping = &p
+			box.Shift = styleset.BaselineShift(dimen.DU(styleset.Font().PtSize()) * dimen.PT)
+			box.Deco = styleset.TextDecoration()
+			wordsKhipu.AppendKnot(box)
+		}
 		pos = end
-		wordsKhipu.AppendKnot(box)
 	}
 	tracer().Debugf("###############################################")
-	return wordsKhipu
+	return wordsKhipu, nil
+}
+
+// shapeTextBoxes shapes the text boxes of k with the shaping parameters recorded
+// during encoding and sets their dimensions. The widths of discretionaries are set
+// to the widths of their hyphen characters. Shaping errors are reported within
+// error domain core.ErrShaping.
+func shapeTextBoxes(ctx context.Context, k *Khipu, shaper glyphing.Shaper) error {
+	shapeBox := func(box *TextBox) error {
+		if box == nil || box.text == "" || box.shaping == nil {
+			return nil
+		}
+		glyphs, err := shape(ctx, shaper, box.text, *box.shaping)
+		if err != nil {
+			return core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape %q", box.text)
+		}
+		box.glyphs = glyphs
+		box.Width, box.Height, box.Depth = glyphs.BoundingBox()
+		return nil
+	}
+	var p *glyphing.Params // shaping parameters of the latest text box, for hyphens
+	for i, knot := range k.knots {
+		switch knot := knot.(type) {
+		case *TextBox:
+			if err := shapeBox(knot); err != nil {
+				return err
+			}
+			if knot.shaping != nil {
+				p = knot.shaping
+			}
+		case Discretionary:
+			for _, box := range []*TextBox{knot.Pre, knot.Post, knot.NoBreak} {
+				if err := shapeBox(box); err != nil {
+					return err
+				}
+			}
+			if knot.HyphenChar == 0 || p == nil {
+				continue
+			}
+			glyphs, err := shape(ctx, shaper, string(knot.HyphenChar), *p)
+			if err != nil {
+				return core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape hyphen %q", knot.HyphenChar)
+			}
+			knot.Width = glyphs.W
+			k.knots[i] = knot
+		}
+	}
+	return nil
 }
 
 func directionForText(styles styled.Style, dir bidi.Direction,
@@ -256,6 +361,16 @@ func scriptForText(styles styled.Style, regs *params.TypesettingRegisters) langu
 	return language.MustParseScript("Latn")
 }
 
+// scriptForRun returns the script of a run of text, as found by itemization (see
+// package engine/text). Runs without a script of their own, e.g. numbers, are
+// shaped in the script of the typesetting registers.
+func scriptForRun(run text.Run, styles styled.Style, regs *params.TypesettingRegisters) language.Script {
+	if run.Script == text.Common || run.Script == text.Unknown {
+		return scriptForText(styles, regs)
+	}
+	return run.Script
+}
+
 func matchLang(styles styled.Style, l string) language.Tag {
 	// TODO use langauage Matcher derived for styles
 	// matcher := language.NewMatcher([]language.Tag{
@@ -265,6 +380,264 @@ func matchLang(styles styled.Style, l string) language.Tag {
 	return language.English
 }
 
+// --- Khipukamayuq for plain text -------------------------------------------
+
+// Option is a type for configuring EncodeText.
+type Option func(*khipukamayuq)
+
+type khipukamayuq struct { // the knot-maker
+	ctx      context.Context // context for trace spans
+	regs     *params.TypesettingRegisters
+	shaper   glyphing.Shaper
+	startpos uint64
+	spacing  Spacing
+	wrapping Wrapping
+	dir      bidi.Direction       // paragraph direction
+	hasDir   bool                 // paragraph direction set by client
+	levels   *bidi.ResolvedLevels // resolved bidi levels of the text, if measured
+}
+
+// WithRegisters sets the typesetting registers to use. If not set, default
+// registers are used.
+func WithRegisters(regs *params.TypesettingRegisters) Option {
+	return func(kk *khipukamayuq) {
+		kk.regs = regs
+	}
+}
+
+// WithContext sets a context for EncodeText. Trace spans will be recorded as
+// children of a span contained in ctx (see package core/spans). If ctx is
+// cancelled, EncodeText stops shaping text and returns the error of ctx.
+func WithContext(ctx context.Context) Option {
+	return func(kk *khipukamayuq) {
+		kk.ctx = ctx
+	}
+}
+
+// WithShaper sets a shaper to measure text boxes. If not set, text boxes will
+// have a width of zero, and clients will have to measure them by other means,
+// e.g. with a linebreak.FixedWidthCursor.
+func WithShaper(shaper glyphing.Shaper) Option {
+	return func(kk *khipukamayuq) {
+		kk.shaper = shaper
+	}
+}
+
+// WithStartPosition sets the text position of the first character of the text.
+// Defaults to 0.
+func WithStartPosition(pos uint64) Option {
+	return func(kk *khipukamayuq) {
+		kk.startpos = pos
+	}
+}
+
+// EncodeText transforms a paragraph of plain text into a khipu which is ready
+// to be broken into lines. It will
+//
+//   - normalize the text as set with register P_NORMALIZATION (see Normalize)
+//   - find the paragraph direction, unless option WithDirection is given
+//   - find line break opportunities according to UAX#14
+//   - keep grapheme clusters (UAX#29) together, e.g. emoji ZWJ sequences
+//   - hyphenate words, if register P_MINHYPHENLENGTH is set to a finite value
+//   - encode spaces as glue and line break opportunities as penalties, collapsing
+//     or preserving white space as set with register P_WHITESPACE
+//   - encode soft hyphens as discretionaries, and zero-width spaces and word
+//     joiners as penalties
+//   - adjust line break opportunities for CJK text (see KinsokuShori)
+//   - apply letter-spacing and word-spacing, if option WithSpacing is given
+//   - adjust line break opportunities, if option WithWrapping is given or white
+//     space is not to be wrapped
+//   - end the paragraph as TeX does: \unskip\penalty10000\hskip\parfillskip\penalty-10000
+//
+// Text positions of text boxes refer to the normalized text.
+func EncodeText(text string, opts ...Option) (*Khipu, error) {
+	kk := &khipukamayuq{}
+	for _, opt := range opts {
+		opt(kk)
+	}
+	ctx, span := spans.Start(kk.ctx, spans.Stage, "khipu.EncodeText")
+	defer span.End()
+	if kk.regs == nil {
+		kk.regs = params.NewTypesettingRegisters()
+	}
+	text = Normalize(text, kk.regs)
+	if !kk.hasDir {
+		kk.dir, _ = ParagraphDirection(text, nil)
+	}
+	if kk.shaper != nil { // runs of text are shaped in their own direction
+		kk.levels = bidi.ResolveParagraph(strings.NewReader(text), nil, bidi.DefaultDirection(kk.dir))
+	}
+	pipeline := prepareLineWrapPipeline(strings.NewReader(text))
+	ws := whiteSpaceRules(kk.regs.S(params.P_WHITESPACE))
+	k := NewKhipu()
+	textpos := kk.startpos
+	graphemes := graphemeBoundaries(text)
+	seg := pipeline.segmenter
+	var pending string // fragment ending inside a grapheme cluster
+	for seg.Next() {
+		fragment := pending + seg.Text()
+		if end := int(textpos-kk.startpos) + len(fragment); end < len(graphemes) && !graphemes[end] {
+			pending = fragment // never break or end a text box within a grapheme cluster
+			continue
+		}
+		pending = ""
+		p1, _ := seg.Penalties()
+		if isspace(fragment) {
+			ws.encode(fragment, textpos, p1, k, kk.regs)
+		} else {
+			brk := encodeFragment(fragment, textpos, k, kk.regs)
+			if p1 < uax.InfinitePenalty && !brk { // break opportunity without space, e.g. after a hyphen
+				k.AppendKnot(Penalty(kk.regs.N(params.P_HYPHENPENALTY)))
+			}
+		}
+		textpos += uint64(len(fragment))
+	}
+	if err := seg.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	k = KinsokuShori(k, kk.regs)
+	if kk.regs.N(params.P_MINHYPHENLENGTH) < dimen.Infinity {
+		HyphenateTextBoxes(k, pipeline, kk.regs)
+	}
+	k = AdjustSpacing(k, kk.spacing)
+	wrapping := kk.wrapping
+	wrapping.NoWrap = wrapping.NoWrap || !ws.wrap
+	k = AdjustWrapping(k, wrapping)
+	if kk.shaper != nil {
+		if err := kk.measure(ctx, k); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+	endParagraph(k)
+	span.SetAttribute("knots", k.Length())
+	return k, nil
+}
+
+// measure shapes all the text boxes of k and sets their dimensions. Every text box
+// is shaped in the direction of its bidi run, as resolved by UAX#9. The widths of
+// discretionaries are set to the widths of their hyphen characters. Hyphenation
+// points within ligatures are re-encoded first (see reshapeHyphenated), kerns
+// between syllables are inserted last (see kernHyphenated). Shaping errors are
+// reported within error domain core.ErrShaping.
+func (kk *khipukamayuq) measure(ctx context.Context, k *Khipu) error {
+	shapingParams := glyphing.Params{
+		Script:   scriptForText(nil, kk.regs),
+		Language: matchLang(nil, kk.regs.S(params.P_LANGUAGE)),
+	}
+	paramsAt := func(pos uint64) glyphing.Params {
+		p := shapingParams
+		p.Direction = directionForText(nil, kk.directionAt(pos), kk.regs)
+		return p
+	}
+	if err := reshapeHyphenated(ctx, k, kk.shaper, paramsAt); err != nil {
+		return err
+	}
+	p := paramsAt(kk.startpos) // parameters of the latest text box, for hyphens
+	for i, knot := range k.knots {
+		switch knot := knot.(type) {
+		case *TextBox:
+			if knot.text == "" { // empty boxes keep preserved white space from being discarded
+				continue
+			}
+			p = paramsAt(knot.Position)
+			glyphs, err := shape(ctx, kk.shaper, knot.text, p)
+			if err != nil {
+				return core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape %q", knot.text)
+			}
+			knot.glyphs = glyphs
+			knot.Width, knot.Height, knot.Depth = knot.glyphs.BoundingBox()
+		case Discretionary:
+			for _, box := range []*TextBox{knot.Pre, knot.Post, knot.NoBreak} {
+				if box == nil || box.text == "" {
+					continue
+				}
+				glyphs, err := shape(ctx, kk.shaper, box.text, paramsAt(box.Position))
+				if err != nil {
+					return core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape discretionary text %q", box.text)
+				}
+				box.glyphs = glyphs
+				box.Width, box.Height, box.Depth = glyphs.BoundingBox()
+			}
+			if knot.HyphenChar == 0 {
+				continue
+			}
+			glyphs, err := shape(ctx, kk.shaper, string(knot.HyphenChar), p)
+			if err != nil {
+				return core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape hyphen %q", knot.HyphenChar)
+			}
+			knot.Width = glyphs.W
+			k.knots[i] = knot
+		}
+	}
+	return kernHyphenated(ctx, k, kk.shaper, paramsAt)
+}
+
+// directionAt returns the resolved bidi direction at text position pos. Without
+// resolved levels, this is the paragraph direction.
+func (kk *khipukamayuq) directionAt(pos uint64) bidi.Direction {
+	if kk.levels == nil || pos < kk.startpos {
+		return kk.dir
+	}
+	return kk.levels.DirectionAt(pos - kk.startpos)
+}
+
+// shape shapes a run of text, recording a trace span as a child of a span
+// contained in ctx. If ctx is cancelled, shape returns the error of ctx.
+func shape(ctx context.Context, shaper glyphing.Shaper, text string, p glyphing.Params) (glyphing.GlyphSequence, error) {
+	if err := ctx.Err(); err != nil {
+		return glyphing.GlyphSequence{}, err
+	}
+	_, span := spans.Start(ctx, spans.Run, "glyphing.Shape")
+	defer span.End()
+	if spans.Enabled() {
+		span.SetAttribute("bytes", len(text))
+		span.SetAttribute("script", p.Script.String())
+	}
+	seq, err := shaper.Shape(strings.NewReader(text), nil, nil, p)
+	span.RecordError(err)
+	return seq, err
+}
+
+// endParagraph removes trailing glue and penalties from k and appends
+// a \parfillskip, together with a forced line break.
+func endParagraph(k *Khipu) {
+	n := len(k.knots)
+	for n > 0 && (k.knots[n-1].Type() == KTGlue || k.knots[n-1].Type() == KTPenalty) {
+		n--
+	}
+	k.knots = k.knots[:n]
+	k.AppendKnot(Penalty(dimen.Infinity))
+	k.AppendKnot(NewFill(2)) // same as knuthplass' default for \parfillskip
+	k.AppendKnot(Penalty(-10000))
+}
+
+// capPenalty maps a UAX#14 penalty to a TeX-like penalty, i.e., to [-10000…10000].
+// Mandatory breaks (e.g., after a newline) will result in a forced break.
+func capPenalty(p int) Penalty {
+	if p >= uax.InfinitePenalty {
+		return Penalty(dimen.Infinity)
+	} else if p <= -uax.InfinitePenalty {
+		return Penalty(-10000)
+	}
+	return Penalty(p)
+}
+
+// prepareLineWrapPipeline creates a typesetting pipeline using a uax14.LineWrap as
+// the primary breaker and a segment.SimpleWordBreaker to extract spans of whitespace.
+func prepareLineWrapPipeline(text io.Reader) *TypesettingPipeline {
+	pipeline := &TypesettingPipeline{}
+	pipeline.input = bufio.NewReader(text)
+	pipeline.linewrap = uax14.NewLineWrap()
+	pipeline.segmenter = segment.NewSegmenter(pipeline.linewrap, segment.NewSimpleWordBreaker())
+	pipeline.segmenter.Init(pipeline.input)
+	pipeline.wordbreaker = uax29.NewWordBreaker(1)
+	pipeline.words = segment.NewSegmenter(pipeline.wordbreaker)
+	pipeline.words.BreakOnZero(true, false)
+	return pipeline
+}
+
 // KnotEncode transforms an input text into a khipu.
 func KnotEncode(text io.Reader, startpos uint64, pipeline *TypesettingPipeline,
 	regs *params.TypesettingRegisters) *Khipu {
@@ -272,7 +645,7 @@ func KnotEncode(text io.Reader, startpos uint64, pipeline *TypesettingPipeline,
 	if regs == nil {
 		regs = params.NewTypesettingRegisters()
 	}
-	pipeline = PrepareTypesettingPipeline(text, pipeline)
+	pipeline = prepareTypesettingPipeline(text, pipeline, regs)
 	textpos := startpos
 	khipu := NewKhipu()
 	seg := pipeline.segmenter
@@ -340,7 +713,8 @@ func createPartialKhipuFromSegment(seg *segment.Segmenter, textpos uint64, pipel
 }
 
 // HyphenateTextBoxes hypenates all the words in a khipu.
-// Words are contained inside TextBox knots.
+// Words are contained inside TextBox knots. If text boxes have already been
+// measured, their widths are apportioned to the syllables.
 //
 // Hyphenation is governed by the typesetting registers.
 // If regs is nil, no hyphenation is done.
@@ -350,6 +724,11 @@ func HyphenateTextBoxes(khipu *Khipu, pipeline *TypesettingPipeline,
 	if regs == nil || khipu == nil {
 		return
 	}
+	lang := regs.S(params.P_LANGUAGE)
+	opts := hyphenationOptions(lang, regs)
+	dictionary := sync.OnceValue(func() *hyphenation.Dictionary { // load only if needed
+		return locate.Dictionary(lang)
+	})
 	k := make([]Knot, 0, khipu.Length())
 	iterator := NewCursor(khipu)
 	for iterator.Next() {
@@ -361,6 +740,10 @@ func HyphenateTextBoxes(khipu *Khipu, pipeline *TypesettingPipeline,
 		textbox := iterator.AsTextBox()
 		textpos := textbox.Position
 		text := textbox.text
+		if !opts.Compounds && hyphenation.HasExplicitHyphen(text) {
+			k = append(k, textbox) // as TeX does, do not hyphenate compounds like "lime-tree"
+			continue
+		}
 		pipeline.words.Init(strings.NewReader(text))
 		for pipeline.words.Next() {
 			word := pipeline.words.Text()
@@ -368,22 +751,26 @@ func HyphenateTextBoxes(khipu *Khipu, pipeline *TypesettingPipeline,
 			var syllables []string
 			isHyphenated := false
 			if len(word) >= regs.N(params.P_MINHYPHENLENGTH) {
-				if syllables, isHyphenated = HyphenateWord(word, regs); isHyphenated {
+				if syllables, isHyphenated = hyphenateWord(word, dictionary(), opts); isHyphenated {
+					syllables = keepGraphemes(word, syllables)
+					isHyphenated = len(syllables) > 1
+				}
+				if isHyphenated {
 					hyphen := NewKnot(KTDiscretionary)
 					pos := textpos
 					for _, sy := range syllables[:len(syllables)-1] {
-						k = append(k, NewTextBox(sy, pos))
+						k = append(k, textbox.measuredFragment(sy, pos))
 						k = append(k, hyphen)
 						pos += uint64(len(sy))
 					}
-					k = append(k, NewTextBox(syllables[len(syllables)-1], pos))
+					k = append(k, textbox.measuredFragment(syllables[len(syllables)-1], pos))
 				}
 			}
 			if !isHyphenated {
 				if word == text {
 					k = append(k, iterator.Knot())
 				} else {
-					k = append(k, NewTextBox(word, textpos))
+					k = append(k, textbox.measuredFragment(word, textpos))
 				}
 			}
 			textpos += uint64(len(word))
@@ -399,12 +786,22 @@ func HyphenateTextBoxes(khipu *Khipu, pipeline *TypesettingPipeline,
 // use a segment.SimpleWordBreaker to extract spans of whitespace.
 // For the inner loop we use a uax29.WordBreaker.
 // This is a default configuration adequate for western languages.
+//
+// Input text is normalized to NFC.
 func PrepareTypesettingPipeline(text io.Reader, pipeline *TypesettingPipeline) *TypesettingPipeline {
+	return prepareTypesettingPipeline(text, pipeline, nil)
+}
+
+// prepareTypesettingPipeline is like PrepareTypesettingPipeline, but normalizes
+// the input as set with register P_NORMALIZATION of regs.
+func prepareTypesettingPipeline(text io.Reader, pipeline *TypesettingPipeline,
+	regs *params.TypesettingRegisters) *TypesettingPipeline {
+	//
 	// wrap a normalization-reader around the input
 	if pipeline == nil {
 		pipeline = &TypesettingPipeline{}
 	}
-	pipeline.input = bufio.NewReader(norm.NFC.Reader(text))
+	pipeline.input = bufio.NewReader(normalizingReader(text, regs))
 	if pipeline.segmenter == nil {
 		// pipeline.linewrap = uax14.NewLineWrap()
 		// pipeline.segmenter = segment.NewSegmenter(pipeline.linewrap, segment.NewSimpleWordBreaker())
@@ -417,20 +814,36 @@ func PrepareTypesettingPipeline(text io.Reader, pipeline *TypesettingPipeline) *
 	return pipeline
 }
 
-// HyphenateWord hyphenates a single word.
+// HyphenateWord hyphenates a single word, following the hyphenation options of the
+// language in register P_LANGUAGE (see hyphenationOptions).
 func HyphenateWord(word string, regs *params.TypesettingRegisters) ([]string, bool) {
-	dict := locate.Dictionary(regs.S(params.P_LANGUAGE))
-	ok := false
-	if dict == nil {
-		panic("TODO not yet implemented: find dictionnary for language")
-	}
+	lang := regs.S(params.P_LANGUAGE)
+	return hyphenateWord(word, locate.Dictionary(lang), hyphenationOptions(lang, regs))
+}
+
+func hyphenateWord(word string, dict *hyphenation.Dictionary, opts hyphenation.Options) ([]string, bool) {
 	tracer().Debugf("   will try to hyphenate word")
-	splitWord := dict.Hyphenate(word)
-	if len(splitWord) > 1 {
-		ok = true
-	}
+	splitWord := dict.HyphenateWith(word, opts) // dict may be nil
 	tracer().Debugf("   %v", splitWord)
-	return splitWord, ok
+	return splitWord, len(splitWord) > 1
+}
+
+// hyphenationOptions returns the hyphenation options for language lang, with
+// hyphenation minima overridden by registers P_LEFTHYPHENMIN and P_RIGHTHYPHENMIN.
+// User exceptions in register P_HYPHENEXCEPTIONS replace the user exceptions of
+// the language.
+func hyphenationOptions(lang string, regs *params.TypesettingRegisters) hyphenation.Options {
+	opts := hyphenation.LanguageOptions(lang)
+	if n := regs.N(params.P_LEFTHYPHENMIN); n > 0 {
+		opts.LeftMin = n
+	}
+	if n := regs.N(params.P_RIGHTHYPHENMIN); n > 0 {
+		opts.RightMin = n
+	}
+	if x, ok := regs.Get(params.P_HYPHENEXCEPTIONS).(*hyphenation.Exceptions); ok && x != nil {
+		opts.Exceptions = x
+	}
+	return opts
 }
 
 // ---------------------------------------------------------------------------
@@ -0,0 +1,203 @@
+package khipu
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core"
+	"github.com/npillmayer/tyse/engine/glyphing"
+)
+
+// --- Ligatures at hyphenation points ---------------------------------------
+
+// Syllables of hyphenated words are shaped separately, which keeps ligatures and
+// kerning from spanning hyphenation points. E.g., "dif-fi-cult" would be set with
+// an "fi" ligature, but not with the "ffi" ligature of the word as a whole. On the
+// other hand, shaping words as a whole and splitting their glyphs at a break would
+// leave a stale ligature at the end of a line.
+//
+// As TeX does, we encode hyphenation points within a ligature as discretionaries
+// with the characters of the ligature as no-break text. The pre-break text and
+// the post-break text are shaped as fragments of their own:
+//
+//	«di» ⟨Pre: "f-", Post: "fi", NoBreak: "ffi"⟩ «cult»
+//
+// Line breakers and line setting will then select the correct text for either
+// case.
+
+// reshapeHyphenated shapes the hyphenated words of k as a whole and re-encodes
+// hyphenation points which are not safe to break (see glyphing.GlyphSequence.SafeToBreak)
+// as discretionaries with pre-break, post-break and no-break text. Text boxes of
+// the words are split accordingly and have to be shaped afterwards.
+//
+// paramsAt returns the shaping parameters for a text position. Hyphenation
+// points within right-to-left text are left untouched.
+func reshapeHyphenated(ctx context.Context, k *Khipu, shaper glyphing.Shaper, paramsAt func(uint64) glyphing.Params) error {
+	if k == nil || shaper == nil {
+		return nil
+	}
+	knots := make([]Knot, 0, len(k.knots))
+	for i := 0; i < len(k.knots); {
+		n := hyphenatedWord(k.knots[i:])
+		if n == 0 {
+			knots = append(knots, k.knots[i])
+			i++
+			continue
+		}
+		p := paramsAt(k.knots[i].(*TextBox).Position)
+		if p.Direction == glyphing.RightToLeft {
+			knots = append(knots, k.knots[i:i+n]...)
+			i += n
+			continue
+		}
+		word, err := reshapeWord(ctx, k.knots[i:i+n], shaper, p)
+		if err != nil {
+			return err
+		}
+		knots = append(knots, word...)
+		i += n
+	}
+	k.knots = knots
+	return nil
+}
+
+// hyphenatedWord returns the number of knots of a hyphenated word at the start of
+// knots, i.e., of text boxes separated by hyphenation points (discretionaries
+// without pre-break, post-break or no-break text, possibly followed by penalties).
+// If knots does not start with a hyphenated word, 0 is returned.
+func hyphenatedWord(knots []Knot) int {
+	if box, ok := knots[0].(*TextBox); !ok || box.text == "" {
+		return 0
+	}
+	n := 1
+	for i := 1; i < len(knots); {
+		if d, ok := knots[i].(Discretionary); !ok || d.Pre != nil || d.Post != nil || d.NoBreak != nil {
+			break
+		}
+		i++
+		for i < len(knots) && knots[i].Type() == KTPenalty {
+			i++
+		}
+		if i == len(knots) {
+			break
+		}
+		if box, ok := knots[i].(*TextBox); !ok || box.text == "" {
+			break
+		}
+		i++
+		n = i
+	}
+	if n == 1 {
+		return 0
+	}
+	return n
+}
+
+// reshapeWord shapes the text of a hyphenated word and re-encodes its knots, if
+// any of its hyphenation points is not safe to break. A hyphenation point within
+// a ligature which covers other hyphenation points as well is dropped, together
+// with its penalties.
+func reshapeWord(ctx context.Context, knots []Knot, shaper glyphing.Shaper, p glyphing.Params) ([]Knot, error) {
+	var first *TextBox
+	var text strings.Builder
+	var bounds []int // hyphenation points, as rune positions within the word
+	runes := 0
+	for _, knot := range knots {
+		switch knot := knot.(type) {
+		case *TextBox:
+			if first == nil {
+				first = knot
+			}
+			text.WriteString(knot.text)
+			runes += utf8.RuneCountInString(knot.text)
+		case Discretionary:
+			bounds = append(bounds, runes)
+		}
+	}
+	seq, err := shape(ctx, shaper, text.String(), p)
+	if err != nil {
+		return nil, core.WrapErrorIn(err, core.ErrShaping, core.EINVALID, "cannot shape %q", text.String())
+	}
+	word := []rune(text.String())
+	spans := make([][2]int, len(bounds))
+	safe := true
+	for i, b := range bounds {
+		spans[i][0], spans[i][1] = unsafeSpan(seq, b, len(word))
+		safe = safe && spans[i][0] == spans[i][1]
+	}
+	if safe {
+		return knots, nil
+	}
+	fragment := func(from, to int, suffix string) *TextBox {
+		if from == to && suffix == "" {
+			return nil
+		}
+		pos := first.Position + uint64(len(string(word[:from])))
+		return first.fragment(string(word[from:to])+suffix, pos)
+	}
+	reshaped := make([]Knot, 0, len(knots)+2)
+	at, i := 0, 0 // start of text not yet encoded, index of next hyphenation point
+	dropped := false
+	for _, knot := range knots {
+		switch knot := knot.(type) {
+		case *TextBox:
+			continue // text is re-encoded between hyphenation points
+		case Discretionary:
+			b, from, to := bounds[i], spans[i][0], spans[i][1]
+			next := len(word)
+			if i++; i < len(bounds) {
+				next = bounds[i]
+			}
+			if dropped = from < at || to > next; dropped {
+				tracer().Debugf("dropping hyphenation point within ligature of %q", string(word))
+				continue
+			}
+			if from > at {
+				reshaped = append(reshaped, fragment(at, from, ""))
+			}
+			if from < to {
+				hyphen := ""
+				if knot.HyphenChar != 0 {
+					hyphen = string(knot.HyphenChar)
+				}
+				knot.Pre = fragment(from, b, hyphen)
+				knot.Post = fragment(b, to, "")
+				knot.NoBreak = fragment(from, to, "")
+			}
+			reshaped = append(reshaped, knot)
+			at = to
+		default: // penalties following a hyphenation point
+			if !dropped {
+				reshaped = append(reshaped, knot)
+			}
+		}
+	}
+	if at < len(word) {
+		reshaped = append(reshaped, fragment(at, len(word), ""))
+	}
+	return reshaped, nil
+}
+
+// unsafeSpan returns the range [from…to) of runes around position pos of the
+// (left-to-right) input of seq, which would have to be re-shaped if the input
+// were broken at pos. This is the range of the glyph clusters at pos, extended
+// to neighbouring clusters which the shaper flagged as unsafe to break. If seq
+// is safe to break at pos, an empty range is returned.
+func unsafeSpan(seq glyphing.GlyphSequence, pos, n int) (from, to int) {
+	g0, g1 := seq.GlyphsForRune(pos)
+	if g0 == g1 || (seq.Glyphs[g0].ClusterID == pos && seq.SafeToBreak(g0)) {
+		return pos, pos
+	}
+	for g0 > 0 && !seq.SafeToBreak(g0) {
+		g0, _ = seq.Cluster(g0 - 1)
+	}
+	for g1 < len(seq.Glyphs) && !seq.SafeToBreak(g1) {
+		_, g1 = seq.Cluster(g1)
+	}
+	from, to = seq.Glyphs[g0].ClusterID, n
+	if g1 < len(seq.Glyphs) {
+		to = seq.Glyphs[g1].ClusterID
+	}
+	return from, to
+}
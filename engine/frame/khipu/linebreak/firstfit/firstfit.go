@@ -74,18 +74,19 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.  */
 package firstfit
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse/core/spans"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
 )
 
-// T traces to the core tracer.
+// T traces with key 'tyse.frame'.
 func T() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.frame")
 }
 
 // We use a small object to manage line-breaking information for a client call.
@@ -132,11 +133,26 @@ func newLinebreaker(cursor linebreak.Cursor, parshape linebreak.ParShape,
 func BreakParagraph(cursor linebreak.Cursor, parshape linebreak.ParShape,
 	params *linebreak.Parameters) ([]khipu.Mark, error) {
 	//
+	return BreakParagraphContext(context.Background(), cursor, parshape, params)
+}
+
+// BreakParagraphContext is like BreakParagraph, but records a trace span as a child
+// of a span contained in ctx (see package core/spans). Breaking stops with the
+// error of ctx if ctx is cancelled or its deadline is exceeded.
+func BreakParagraphContext(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) ([]khipu.Mark, error) {
+	//
+	_, span := spans.Start(ctx, spans.Paragraph, "firstfit.BreakParagraph")
+	defer span.End()
 	lb, err := newLinebreaker(cursor, parshape, params)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
-	return lb.FindBreakpoints()
+	breakpoints, err := lb.findBreakpoints(ctx)
+	span.RecordError(err)
+	span.SetAttribute("lines", len(breakpoints))
+	return breakpoints, err
 }
 
 // FindBreakpoints is the main work horse. It iterates over the knots in the input
@@ -147,6 +163,12 @@ func BreakParagraph(cursor linebreak.Cursor, parshape linebreak.ParShape,
 // checkpoints at feasible breakpoints and jump back, as soon as we have to create
 // a line-break.
 func (lb *linebreaker) FindBreakpoints() ([]khipu.Mark, error) {
+	return lb.findBreakpoints(context.Background())
+}
+
+// findBreakpoints is FindBreakpoints, stopping with the error of ctx if ctx is
+// cancelled.
+func (lb *linebreaker) findBreakpoints(ctx context.Context) ([]khipu.Mark, error) {
 	breakpoints := make([]khipu.Mark, 1, 10)
 	breakpoints[0] = provisionalMark(-1) // first break is before first knot item
 	lineno := int32(0)
@@ -155,12 +177,15 @@ func (lb *linebreaker) FindBreakpoints() ([]khipu.Mark, error) {
 	knot := lb.next() // we will iterate of every knot item in the khipu
 	last := lb.mark() // and remember the last one
 	for knot != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		linelen := lb.parshape.LineLength(lineno)
-		gtrace.CoreTracer.Debugf("_______________ %v ___________________", knot)
-		if knot.Type() == khipu.KTPenalty { // TODO discretionaries
+		T().Debugf("_______________ %v ___________________", knot)
+		if knot.Type() == khipu.KTPenalty {
 			last = lb.mark()
 			penalty := lb.penalty()
-			spaceUsed.append(knot)
+			spaceUsed.append(knot, lb.params)
 			segm := spaceUsed.width(lb.params)
 			if linebreak.Merits(penalty.Demerits()) < linebreak.InfinityDemerits {
 				T().Debugf("penalty %v is acceptable", penalty.Demerits())
@@ -183,11 +208,11 @@ func (lb *linebreaker) FindBreakpoints() ([]khipu.Mark, error) {
 					if !lb.checkpoint() {
 						panic("CANNOT SET CHECKPOINT") // TODO remove after debugging
 					}
-					gtrace.CoreTracer.Infof("setting checkpoint with demerits=%v", penalty.Demerits())
+					T().Infof("setting checkpoint with demerits=%v", penalty.Demerits())
 				}
 			} // otherwise no feasible break, just move over penalty
 		} else { // knot is not a penalty => append at end of segment
-			spaceUsed.append(knot)
+			spaceUsed.append(knot, lb.params)
 			if firstInLine && !knot.IsDiscardable() { // do not add space to front of line
 				spaceUsed.trackcarry()
 				firstInLine = false
@@ -246,29 +271,41 @@ type segment struct {
 	length       linebreak.WSS // length of material of current line
 	breakDiscard linebreak.WSS // sum of discardable space while looking for next breakpoint
 	carry        linebreak.WSS // material to carry over for line break
+	discBreak    linebreak.WSS // change of width when breaking at a discretionary
+	post         linebreak.WSS // post-break text of a discretionary, starting the next line
 }
 
 // append the width information of a knot at the end of a segment.
 // if the knot is a discardable item, s.breakDiscard is updated as well.
-func (s *segment) append(knot khipu.Knot) {
-	s.length = s.length.Add(linebreak.WSS{}.SetFromKnot(knot))
-	s.carry = s.carry.Add(linebreak.WSS{}.SetFromKnot(knot))
+func (s *segment) append(knot khipu.Knot, params *linebreak.Parameters) {
+	wss := params.Measure(knot)
+	s.length = s.length.Add(wss)
+	s.carry = s.carry.Add(wss)
 	if knot.IsDiscardable() {
-		s.breakDiscard = s.breakDiscard.Add(linebreak.WSS{}.SetFromKnot(knot))
+		s.breakDiscard = s.breakDiscard.Add(wss)
 	} else {
 		s.breakDiscard = linebreak.WSS{}
 	}
+	switch knot := knot.(type) {
+	case khipu.Discretionary: // pre-break text replaces no-break text at a break
+		s.discBreak = linebreak.BreakWidth(knot)
+		w := knot.PostWidth()
+		s.post = linebreak.WSS{W: w, Min: w, Max: w}
+	case khipu.Penalty:
+	default:
+		s.discBreak, s.post = linebreak.WSS{}, linebreak.WSS{}
+	}
 }
 
 // width returns the widths of the current partial line, subtracting
-// space at the end of the segment (as this will be dropped).
-// width is respecting params.LeftSkip & RightSkip.
+// space at the end of the segment (as this will be dropped). At a discretionary,
+// its pre-break text is counted instead of its no-break text.
+// width is respecting the skips of lines (see linebreak.Parameters.Skips).
 func (s *segment) width(params *linebreak.Parameters) linebreak.WSS {
-	segw := s.length.Subtract(s.breakDiscard)
-	w := linebreak.WSS{}.SetFromKnot(params.LeftSkip)
-	segw = segw.Add(w)
-	w = linebreak.WSS{}.SetFromKnot(params.RightSkip)
-	segw = segw.Add(w)
+	segw := s.length.Subtract(s.breakDiscard).Add(s.discBreak)
+	left, right := params.Skips()
+	segw = segw.Add(linebreak.WSS{}.SetFromKnot(left))
+	segw = segw.Add(linebreak.WSS{}.SetFromKnot(right))
 	return segw
 }
 
@@ -280,13 +317,15 @@ func (s *segment) reset() {
 	s.length = s.carry
 	s.carry = linebreak.WSS{}
 	s.breakDiscard = linebreak.WSS{}
+	s.discBreak, s.post = linebreak.WSS{}, linebreak.WSS{}
 }
 
-// trackcarry signals that we will start tracking carry items. It simply truncates
-// carry.
+// trackcarry signals that we will start tracking carry items. It truncates carry,
+// which will start with the post-break text of a discretionary, if a line break
+// at the current position breaks one.
 func (s *segment) trackcarry() {
 	T().Debugf("truncating track")
-	s.carry = linebreak.WSS{}
+	s.carry = s.post
 }
 
 func (s *segment) String() string {
@@ -296,7 +335,7 @@ func (s *segment) String() string {
 // linebreak creates a breakpoint and appends it to a given list.
 func (lb *linebreaker) linebreak(breakpoints []khipu.Mark) []khipu.Mark {
 	lb.linecount++
-	gtrace.CoreTracer.Debugf("new line #%d", lb.linecount)
+	T().Debugf("new line #%d", lb.linecount)
 	breakpoints = append(breakpoints, lb.mark())
 	lb.buffer = lb.buffer[:0]
 	lb.pos = -1
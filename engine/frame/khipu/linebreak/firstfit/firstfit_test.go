@@ -2,6 +2,7 @@ package firstfit
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/npillmayer/tyse/core/parameters"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/linetest"
 )
 
 var graphviz = false // global switch for GraphViz DOT output
@@ -77,6 +79,16 @@ func TestLinebreak(t *testing.T) {
 	}
 }
 
+func TestLines(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := linetest.Words("aaa bb cc ddddd").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(12), nil, "aaa bb cc", "ddddd")
+	para = linetest.Words("wir").Space().Text("ba").Discretionary("k-", "k", "ck").Text("en").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(8), nil, "wir bak-", "ken")
+}
+
 var princess = `In olden times when wishing still helped one, there lived a king whose daughters were all beautiful; and the youngest was so beautiful that the sun itself, which has seen so much, was astonished whenever it shone in her face. Close by the king's castle lay a great dark forest, and under an old lime-tree in the forest was a well, and when the day was very warm, the king's child went out into the forest and sat down by the side of the cool fountain; and when she was bored she took a golden ball, and threw it up on high and caught it; and this ball was her favorite plaything.`
 
 func TestPrincess(t *testing.T) {
@@ -194,3 +206,31 @@ func justify(text string, l int, even bool) string {
 	}
 	return b.String()
 }
+
+func TestStreamedParagraphs(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	doc := princess + "\n\n" + princess + "\n\n" + "the quick brown fox jumps over the lazy dog."
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_MINHYPHENLENGTH, 100) // inhibit hyphenation
+	stream := khipu.NewStreamCursor(khipu.StreamParagraphs(context.Background(), strings.NewReader(doc),
+		khipu.WithRegisters(regs)))
+	cursor := linebreak.NewFixedWidthCursor(stream, 10*dimen.BP, 0)
+	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
+	var lines []int
+	for stream.NextSegment() {
+		breakpoints, err := BreakParagraph(cursor, parshape, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, len(breakpoints)-1)
+	}
+	if stream.Err() != nil {
+		t.Fatal(stream.Err())
+	}
+	t.Logf("lines of streamed paragraphs: %v", lines)
+	if len(lines) != 3 || lines[0] != lines[1] || lines[2] != 1 {
+		t.Errorf("expected 3 paragraphs, the first two of equal length, have %v", lines)
+	}
+}
@@ -0,0 +1,204 @@
+package linebreak
+
+import (
+	"math"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+// --- Setting glue of broken lines ------------------------------------------
+
+// TrimLine strips discardable knots from the start and the end of line
+// [from…to-1], as they vanish at a line break. It returns the range of the
+// remaining content. Infinitely stretchable glue is not stripped, as it is
+// used to fill lines (e.g., parfillskip), not to separate words.
+func TrimLine(k *khipu.Khipu, from, to int64) (int64, int64) {
+	start, end := to, from
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Knot().IsDiscardable() && !isFill(cursor.Knot()) {
+			continue
+		}
+		if start == to {
+			start = cursor.Position()
+		}
+		end = cursor.Position() + 1
+	}
+	if start > end {
+		return from, from
+	}
+	return start, end
+}
+
+// BrokenDiscretionary returns the discretionary broken by a line break at position
+// brk, i.e., a discretionary at brk or preceding the penalties at brk, together
+// with its position. The pre-break text of the discretionary ends the line before
+// the break, its post-break text starts the line after it.
+func BrokenDiscretionary(k *khipu.Khipu, brk int64) (khipu.Discretionary, int64, bool) {
+	for pos := brk; pos >= 0; pos-- {
+		cursor := khipu.NewCursorAt(k, pos)
+		if !cursor.Next() || cursor.Position() != pos {
+			break
+		}
+		switch knot := cursor.Knot().(type) {
+		case khipu.Discretionary:
+			return knot, pos, true
+		case khipu.Penalty:
+			continue
+		}
+		break
+	}
+	return khipu.Discretionary{}, -1, false
+}
+
+// BreakWidth returns the change of width of a line ending at discretionary d, if
+// the line is broken at d: the pre-break text replaces the no-break text.
+func BreakWidth(d khipu.Discretionary) WSS {
+	w := d.PreWidth() - d.W()
+	return WSS{W: w, Min: w, Max: w}
+}
+
+// GlueSetRatio calculates the ratio of stretch (positive) or shrink (negative)
+// which has to be applied to the glue of line [from…to-1] to reach linelen. If the
+// line contains infinitely stretchable glue, the ratio applies to infinite stretch
+// only, and the second return value is true.
+func GlueSetRatio(k *khipu.Khipu, from, to int64, linelen dimen.DU) (float64, bool) {
+	return glueSetRatio(k, from, to, linelen, nil)
+}
+
+// GlueSetRatioWithSkips is like GlueSetRatio, but respects the skips surrounding
+// the line (see Parameters.Skips). For ragged or centered lines, the skips will
+// take up the excess width of the line, while glue within the line keeps its
+// natural width. If params is nil, no skips are added.
+func GlueSetRatioWithSkips(k *khipu.Khipu, from, to int64, linelen dimen.DU,
+	params *Parameters) (float64, bool) {
+	//
+	if params == nil {
+		return glueSetRatio(k, from, to, linelen, nil)
+	}
+	left, right := params.Skips()
+	return glueSetRatio(k, from, to, linelen, params, left, right)
+}
+
+// ExpandedGlueSetRatio is like GlueSetRatioWithSkips, but lets the text of a
+// justified line expand or shrink within the limits of params.Expansion. Text and
+// glue share the excess width of the line in proportion to their stretch or
+// shrink, as the line breakers assume (see Parameters.Measure). If the text
+// reaches its limit, the glue takes the rest.
+//
+// The expansion of text is returned as a fraction of its natural width, e.g.,
+// 0.01 for text set 1% wider, rounded towards 0 to a multiple of
+// params.Expansion.Step. Lines with infinitely stretchable glue are not expanded.
+func ExpandedGlueSetRatio(k *khipu.Khipu, from, to int64, linelen dimen.DU,
+	params *Parameters) (ratio float64, infinite bool, expansion float64) {
+	//
+	if !params.expands() {
+		ratio, infinite = GlueSetRatioWithSkips(k, from, to, linelen, params)
+		return ratio, infinite, 0
+	}
+	left, right := params.Skips()
+	spread := measureLine(k, from, to, params, left, right)
+	excess := linelen - spread.w
+	limit, flex := params.Expansion.Stretch, spread.stretch
+	if excess < 0 {
+		limit, flex = params.Expansion.Shrink, spread.shrink
+	}
+	textflex := spread.text * dimen.DU(limit) / 1000
+	if excess == 0 || (excess > 0 && spread.fil > 0) || textflex <= 0 {
+		ratio, infinite = spread.ratio(linelen)
+		return ratio, infinite, 0
+	}
+	r := float64(excess) / float64(flex+textflex)
+	r = math.Max(-1, math.Min(1, r)) // text will not be scaled beyond its limits
+	permille := r * float64(limit)
+	if step := float64(params.Expansion.Step); step > 0 {
+		permille = math.Trunc(permille/step) * step
+	}
+	expansion = permille / 1000
+	if flex > 0 {
+		rest := excess - dimen.DU(expansion*float64(spread.text))
+		ratio = float64(rest) / float64(flex)
+	}
+	return ratio, false, expansion
+}
+
+func glueSetRatio(k *khipu.Khipu, from, to int64, linelen dimen.DU, params *Parameters,
+	skips ...khipu.Glue) (float64, bool) {
+	//
+	return measureLine(k, from, to, params, skips...).ratio(linelen)
+}
+
+// lineSpread is the natural width of a line, together with the stretch and shrink
+// of its glue and the width of its text.
+type lineSpread struct {
+	w, stretch, shrink dimen.DU
+	fil                float64 // infinite stretch would overflow dimensions
+	text               dimen.DU
+}
+
+func measureLine(k *khipu.Khipu, from, to int64, params *Parameters, skips ...khipu.Glue) lineSpread {
+	var spread lineSpread
+	add := func(wss WSS, glue bool) {
+		spread.w += wss.W
+		if glue {
+			if s := wss.Max - wss.W; s >= dimen.Fil {
+				spread.fil += float64(s)
+			} else {
+				spread.stretch += s
+			}
+			spread.shrink += wss.W - wss.Min
+		}
+	}
+	for _, skip := range skips {
+		add(WSS{}.SetFromKnot(skip), true)
+	}
+	cursor := khipu.NewCursorAt(k, from)
+	for cursor.Next() && cursor.Position() < to {
+		add(params.Measure(cursor.Knot()), isGlue(cursor.Knot()))
+		if cursor.Knot().Type() == khipu.KTTextBox {
+			spread.text += cursor.Knot().W()
+		}
+	}
+	return spread
+}
+
+// ratio returns the glue set ratio for a line of length linelen.
+func (spread lineSpread) ratio(linelen dimen.DU) (float64, bool) {
+	excess := linelen - spread.w
+	switch {
+	case excess > 0 && spread.fil > 0:
+		return float64(excess) / spread.fil, true
+	case excess > 0 && spread.stretch > 0:
+		return float64(excess) / float64(spread.stretch), false
+	case excess < 0 && spread.shrink > 0:
+		return float64(excess) / float64(spread.shrink), false
+	}
+	return 0, false
+}
+
+// GlueDelta returns the change of width applied to glue g at a given glue set
+// ratio (see GlueSetRatio). Glue will not shrink beyond its minimum width.
+func GlueDelta(g khipu.Glue, ratio float64, infinite bool) dimen.DU {
+	stretch := g.MaxW() - g.W()
+	switch {
+	case infinite && stretch >= dimen.Fil:
+		return dimen.DU(ratio * float64(stretch))
+	case infinite:
+		return 0
+	case ratio > 0:
+		return dimen.DU(ratio * float64(stretch))
+	case ratio < -1:
+		return g.MinW() - g.W()
+	}
+	return dimen.DU(ratio * float64(g.W()-g.MinW()))
+}
+
+func isFill(knot khipu.Knot) bool {
+	return isGlue(knot) && knot.MaxW()-knot.W() >= dimen.Fil
+}
+
+// isGlue is true for glue and for leaders, which stretch and shrink like glue.
+func isGlue(knot khipu.Knot) bool {
+	return knot.Type() == khipu.KTGlue || knot.Type() == khipu.KTLeaders
+}
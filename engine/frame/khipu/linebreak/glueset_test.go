@@ -0,0 +1,133 @@
+package linebreak
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+func TestGlueSetRatio(t *testing.T) {
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewTextBox("a", 0))
+	k.AppendKnot(khipu.NewGlue(2*dimen.PT, dimen.PT, 4*dimen.PT))
+	k.AppendKnot(khipu.NewTextBox("b", 1))
+	if r, inf := GlueSetRatio(k, 0, 3, 4*dimen.PT); r != 0.5 || inf {
+		t.Errorf("expected ratio of 0.5, have %.2f", r)
+	}
+	if r, _ := GlueSetRatio(k, 0, 3, 3*dimen.PT/2); r > -0.49 || r < -0.51 {
+		t.Errorf("expected ratio of -0.5, have %.2f", r)
+	}
+	k.AppendKnot(khipu.NewFill(2))
+	r, inf := GlueSetRatio(k, 0, 4, 10*dimen.PT)
+	if !inf {
+		t.Errorf("expected infinite stretch to be detected")
+	}
+	k.AppendKnot(khipu.NewFill(2))
+	if r2, _ := GlueSetRatio(k, 0, 5, 10*dimen.PT); r2 > r/2+0.001 || r2 < r/2-0.001 {
+		t.Errorf("expected infinite stretch of two fills to add up, ratio is %.4f", r2)
+	}
+}
+
+func TestGlueSetRatioWithSkips(t *testing.T) {
+	a, b := khipu.NewTextBox("a", 0), khipu.NewTextBox("b", 1)
+	a.Width, b.Width = 10*dimen.PT, 10*dimen.PT
+	k := khipu.NewKhipu()
+	glue := khipu.NewGlue(2*dimen.PT, dimen.PT, 4*dimen.PT)
+	k.AppendKnot(a).AppendKnot(glue).AppendKnot(b)
+	params := *DefaultParameters
+	params.LeftSkip, params.RightSkip = khipu.NewFill(1), khipu.NewFill(1)
+	r, inf := GlueSetRatioWithSkips(k, 0, 3, 32*dimen.PT, &params)
+	if !inf {
+		t.Fatalf("expected skips to take up the excess width")
+	}
+	if d := GlueDelta(params.LeftSkip, r, inf); d < 5*dimen.PT-1 || d > 5*dimen.PT+1 {
+		t.Errorf("expected left skip to be set to 5pt, is %s", d)
+	}
+	if d := GlueDelta(glue, r, inf); d != 0 {
+		t.Errorf("expected inter-word glue to keep its natural width, is stretched by %s", d)
+	}
+	if r2, inf2 := GlueSetRatioWithSkips(k, 0, 3, 26*dimen.PT, nil); r2 != 1.0 || inf2 {
+		t.Errorf("expected ratio of 1 without skips, have %.2f", r2)
+	}
+}
+
+func TestRaggedLines(t *testing.T) {
+	params := *DefaultParameters
+	params.Alignment = Centered
+	params.Raggedness = 4 * dimen.PT
+	if left, right := params.Skips(); left.MaxW() != 2*dimen.PT || right.MaxW() != 2*dimen.PT {
+		t.Errorf("expected raggedness to be split between skips, have %v and %v", left, right)
+	}
+	params.Alignment, params.Raggedness = RaggedRight, 0
+	if left, right := params.Skips(); left.MaxW() != 0 || right.MaxW() < dimen.Fil {
+		t.Errorf("expected infinite right skip without raggedness, have %v and %v", left, right)
+	}
+	a, b := khipu.NewTextBox("a", 0), khipu.NewTextBox("b", 1)
+	a.Width, b.Width = 10*dimen.PT, 10*dimen.PT
+	k := khipu.NewKhipu()
+	glue := khipu.NewGlue(2*dimen.PT, dimen.PT, 4*dimen.PT)
+	k.AppendKnot(a).AppendKnot(glue).AppendKnot(b)
+	params.Raggedness = 4 * dimen.PT
+	if wss := params.Measure(glue); wss.Min != wss.W || wss.Max != wss.W {
+		t.Errorf("expected inter-word glue of ragged lines to be rigid, is %v", wss)
+	}
+	r, inf := GlueSetRatioWithSkips(k, 0, 3, 24*dimen.PT, &params)
+	if r != 0.5 || inf {
+		t.Errorf("expected right skip to be stretched with ratio 0.5, have %.2f", r)
+	}
+	if d := params.GlueDelta(glue, r, inf); d != 0 {
+		t.Errorf("expected inter-word glue to keep its natural width, is stretched by %s", d)
+	}
+}
+
+func TestExpandedGlueSetRatio(t *testing.T) {
+	a, b := khipu.NewTextBox("a", 0), khipu.NewTextBox("b", 1)
+	a.Width, b.Width = 50*dimen.PT, 50*dimen.PT
+	k := khipu.NewKhipu()
+	glue := khipu.NewGlue(2*dimen.PT, dimen.PT, 4*dimen.PT)
+	k.AppendKnot(a).AppendKnot(glue).AppendKnot(b)
+	params := *DefaultParameters
+	params.Expansion = Expansion{Stretch: 20, Shrink: 20}
+	if wss := params.Measure(a); wss.Max != 51*dimen.PT || wss.Min != 49*dimen.PT {
+		t.Errorf("expected text to stretch and shrink by 1pt, is %v", wss)
+	}
+	near := func(x, y float64) bool { return x > y-0.001 && x < y+0.001 }
+	// text stretches by 2pt, glue by 4pt
+	r, _, e := ExpandedGlueSetRatio(k, 0, 3, 105*dimen.PT, &params)
+	if !near(r, 0.5) || !near(e, 0.01) {
+		t.Errorf("expected ratio 0.5 and expansion 0.01, have %.3f and %.3f", r, e)
+	}
+	r, _, e = ExpandedGlueSetRatio(k, 0, 3, 110*dimen.PT, &params)
+	if !near(r, 1.5) || !near(e, 0.02) {
+		t.Errorf("expected glue to take the rest beyond expansion 0.02, have %.3f and %.3f", r, e)
+	}
+	r, _, e = ExpandedGlueSetRatio(k, 0, 3, 101*dimen.PT, &params)
+	if !near(r, -1.0/3) || !near(e, -0.02/3) {
+		t.Errorf("expected text and glue to shrink, have %.3f and %.3f", r, e)
+	}
+	params.Expansion.Step = 15
+	r, _, e = ExpandedGlueSetRatio(k, 0, 3, 105*dimen.PT, &params)
+	if !near(r, 0.75) || e != 0 {
+		t.Errorf("expected expansion to be rounded to 0, have %.3f and %.3f", r, e)
+	}
+	params.Alignment = RaggedRight
+	if _, _, e = ExpandedGlueSetRatio(k, 0, 3, 105*dimen.PT, &params); e != 0 {
+		t.Errorf("expected ragged lines not to be expanded, have %.3f", e)
+	}
+}
+
+func TestTrimLine(t *testing.T) {
+	k := khipu.NewKhipu()
+	k.AppendKnot(khipu.NewGlue(2*dimen.PT, 0, 0))
+	k.AppendKnot(khipu.NewTextBox("a", 0))
+	k.AppendKnot(khipu.Penalty(0))
+	if from, to := TrimLine(k, 0, 3); from != 1 || to != 2 {
+		t.Errorf("expected line content to be [1…2), is [%d…%d)", from, to)
+	}
+	k.AppendKnot(khipu.NewFill(2))
+	k.AppendKnot(khipu.Penalty(-10000))
+	if _, to := TrimLine(k, 0, 5); to != 4 {
+		t.Errorf("expected fill glue to be kept at end of line, content ends at %d", to)
+	}
+}
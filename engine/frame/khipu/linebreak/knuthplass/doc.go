@@ -47,11 +47,10 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.  */
 package knuthplass
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// T traces to the core tracer.
+// T traces with key 'tyse.frame'.
 func T() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.frame")
 }
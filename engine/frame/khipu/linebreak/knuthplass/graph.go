@@ -91,6 +91,7 @@ func newFBGraph() *fbGraph {
 type wEdge struct {
 	from, to  int64 // this is an edge between two text-positions
 	cost      linebreak.Merits
+	badness   linebreak.Merits
 	total     linebreak.Merits
 	linecount int32
 }
@@ -106,7 +107,7 @@ func (e wEdge) isNull() bool {
 // newWEdge returns a new weighted edge from one breakpoint to another,
 // given two breakpoints and a label-key.
 // It is not yet inserted into a graph.
-func newWEdge(from, to *feasibleBreakpoint, cost, total linebreak.Merits, linecnt int32) wEdge {
+func newWEdge(from, to *feasibleBreakpoint, c cost, total linebreak.Merits, linecnt int32) wEdge {
 	if from.books[linecnt-1] == nil {
 		panic(fmt.Errorf("startpoint of new line %d seems to have incorrent books: %v", linecnt, from))
 	}
@@ -116,7 +117,8 @@ func newWEdge(from, to *feasibleBreakpoint, cost, total linebreak.Merits, linecn
 	return wEdge{
 		from:      from.mark.Position(),
 		to:        to.mark.Position(),
-		cost:      cost,
+		cost:      c.demerits,
+		badness:   c.badness,
 		total:     total,
 		linecount: linecnt,
 	}
@@ -180,6 +182,19 @@ func (g *fbGraph) Edges(includePruned bool) []wEdge {
 	return edges
 }
 
+// PrunedEdges returns the edges which have been deleted from a graph.
+func (g *fbGraph) PrunedEdges() []wEdge {
+	var edges []wEdge
+	for _, from := range g.prunedEdges {
+		for _, edgesDict := range from {
+			for _, e := range edgesDict {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
 // Breakpoint returns the feasible breakpoint at the given position if it exists in the graph,
 // and nil otherwise.
 func (g *fbGraph) Breakpoint(position int64) *feasibleBreakpoint {
@@ -225,7 +240,7 @@ func (g *fbGraph) RemoveEdge(from, to *feasibleBreakpoint, linecnt int32) {
 // AddEdge adds a weighted edge from one node to another. Endpoints which are
 // not yet contained in the graph are added.
 // Does nothing if from=to.
-func (g *fbGraph) AddEdge(from, to *feasibleBreakpoint, cost, total linebreak.Merits, linecnt int32) {
+func (g *fbGraph) AddEdge(from, to *feasibleBreakpoint, c cost, total linebreak.Merits, linecnt int32) {
 	if from.mark.Position() == to.mark.Position() {
 		return
 	}
@@ -233,7 +248,7 @@ func (g *fbGraph) AddEdge(from, to *feasibleBreakpoint, cost, total linebreak.Me
 		g.Add(to)
 	}
 	if g.Edge(from, to, linecnt).isNull() {
-		edge := newWEdge(from, to, cost, total, linecnt)
+		edge := newWEdge(from, to, c, total, linecnt)
 		if t, ok := g.edgesTo[to.mark.Position()]; ok {
 			edges := t[from.mark.Position()]
 			if edges == nil {
@@ -15,7 +15,7 @@ type graphParamsType struct {
 }
 
 func allBreakpointBoxes(kp *linebreaker, kh *khipu.Khipu, optimal map[int32][]khipu.Mark,
-	boxT *template.Template, w io.Writer) map[int64]*n {
+	boxT *template.Template, w io.Writer) (map[int64]*n, error) {
 	//
 	breakBoxes := make(map[int64]*n)
 	for _, fb := range kp.nodes {
@@ -24,33 +24,57 @@ func allBreakpointBoxes(kp *linebreaker, kh *khipu.Khipu, optimal map[int32][]kh
 			box.Color = "darkolivegreen1"
 		}
 		if err := boxT.Execute(w, box); err != nil {
-			panic(err)
+			return nil, err
 		}
 		breakBoxes[fb.mark.Position()] = box
 	}
-	return breakBoxes
+	return breakBoxes, nil
 }
 
-func allEdges(kp *linebreaker, kh *khipu.Khipu, boxes map[int64]*n, edgeT *template.Template,
-	w io.Writer) {
+// allEdges outputs the candidate lines between breakpoints. Lines of an optimal
+// solution are highlighted, lines which have been pruned in favour of cheaper ones
+// are dashed.
+func allEdges(kp *linebreaker, kh *khipu.Khipu, boxes map[int64]*n, optimal map[int32][]khipu.Mark,
+	edgeT *template.Template, w io.Writer) error {
 	//
-	for _, edge := range kp.Edges(true) {
+	edges := kp.Edges(false)
+	active := len(edges)
+	edges = append(edges, kp.PrunedEdges()...)
+	for i, edge := range edges {
 		T().Debugf("output of edge %v", edge)
-		e := &e{}
+		e := &e{Color: "black", Style: "solid"}
 		e.N1 = boxes[edge.from]
 		e.N2 = boxes[edge.to]
 		e.Cost = edge.cost
+		e.Badness = edge.badness
 		e.Total = edge.total
 		e.Line = edge.linecount
+		if i >= active {
+			e.Color, e.Style = "grey60", "dashed"
+		} else if isOptimalLine(edge, optimal) {
+			e.Color = "darkgreen"
+		}
 		start := int64(0)
 		if edge.from >= 0 {
 			start = edge.from
 		}
 		e.Text = kh.Text(start, edge.to)
 		if err := edgeT.Execute(w, e); err != nil {
-			panic(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// isOptimalLine is true if edge is line of one of the solutions in results.
+func isOptimalLine(edge wEdge, results map[int32][]khipu.Mark) bool {
+	for _, breaks := range results {
+		l := int(edge.linecount)
+		if l < len(breaks) && breaks[l-1].Position() == edge.from && breaks[l].Position() == edge.to {
+			return true
 		}
 	}
+	return false
 }
 
 func isOptimal(mark khipu.Mark, results map[int32][]khipu.Mark) (bool, int32) {
@@ -88,18 +112,28 @@ func makeBox(fb *feasibleBreakpoint, kh *khipu.Khipu) *n {
 	return box
 }
 
+// toGraphViz outputs the graph of feasible breakpoints in GraphViz DOT format.
+// Nodes are feasible breakpoints, edges are candidate lines, labelled with their
+// costs. Breakpoints and lines of the solutions in results are highlighted.
 func (kp *linebreaker) toGraphViz(cursor *khipu.Cursor, results map[int32][]khipu.Mark,
-	w io.Writer) {
+	w io.Writer) error {
 	//
-	tmpl, _ := template.New("graph").Parse(graphHeader)
+	tmpl := template.Must(template.New("graph").Parse(graphHeader))
 	gparams := graphParamsType{Fontname: "Helvetica"}
-	_ = tmpl.Execute(w, gparams)
+	if err := tmpl.Execute(w, gparams); err != nil {
+		return err
+	}
 	boxT := template.Must(template.New("box").Parse(boxTmpl))
 	edgeT := template.Must(template.New("edge").Parse(edgeTmpl))
-	// cursor.Next()
-	boxes := allBreakpointBoxes(kp, cursor.Khipu(), results, boxT, w)
-	allEdges(kp, cursor.Khipu(), boxes, edgeT, w)
-	w.Write([]byte("}\n"))
+	boxes, err := allBreakpointBoxes(kp, cursor.Khipu(), results, boxT, w)
+	if err != nil {
+		return err
+	}
+	if err = allEdges(kp, cursor.Khipu(), boxes, results, edgeT, w); err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("}\n"))
+	return err
 }
 
 type n struct {
@@ -113,9 +147,11 @@ type n struct {
 type e struct {
 	N1, N2      *n
 	Cost, Total linebreak.Merits
+	Badness     linebreak.Merits
 	Line        int32
 	Text        string
 	Color       string
+	Style       string
 }
 
 const graphHeader = `digraph g {                                                                                                             
@@ -129,7 +165,7 @@ const boxTmpl = `{{.Name}}	[ label="{{.Text}}" shape=box style=filled fillcolor=
 `
 
 //const edgeTmpl = `{{ .N1.Name }} -> {{ .N2.Name }} [weight=1] ;
-const edgeTmpl = `{{.N1.Name}} -> {{.N2.Name}} [weight=1 label="{{.Cost}} of\n{{.Total}}\nline={{.Line}}" tooltip="“{{ .Text }}”" ] ;
+const edgeTmpl = `{{.N1.Name}} -> {{.N2.Name}} [weight=1 color={{.Color}} style={{.Style}} label="b={{.Badness}}\n{{.Cost}} of\n{{.Total}}\nline={{.Line}}" tooltip="“{{ .Text }}”" ] ;
 `
 
 // ----------------------------------------------------------------------
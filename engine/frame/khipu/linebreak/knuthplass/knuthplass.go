@@ -36,12 +36,14 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.  */
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 
 	"github.com/emirpasic/gods/sets/hashset"
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
 )
@@ -54,6 +56,41 @@ type linebreaker struct {
 	parshape linebreak.ParShape         // target shape of the paragraph
 	root     *feasibleBreakpoint        // "break" at start of paragraph
 	end      *feasibleBreakpoint        // "break" at end of paragraph
+	pass     pass                       // current pass over the paragraph
+	overfull bool                       // an overfull line had to be set in this pass
+	post     linebreak.WSS              // post-break text of a discretionary before the current knot
+}
+
+// pass holds the settings for one pass of the line breaker over a paragraph.
+// As with TeX, a paragraph is broken in up to three passes:
+//
+//   - a first pass without hyphenation, accepting lines with a badness below
+//     PreTolerance (skipped if PreTolerance < 0)
+//   - a second pass with hyphenation, accepting lines with a badness below Tolerance
+//   - a final pass, which adds EmergencyStretch to the stretchability of every line
+//     (skipped if EmergencyStretch ≤ 0)
+//
+// A pass fails if it has to set an overfull line. The result of the first pass
+// which does not fail is taken, or the result of the final pass.
+type pass struct {
+	no        int              // 1, 2 or 3
+	tolerance linebreak.Merits // acceptable badness of lines
+	hyphenate bool             // may lines be broken at discretionaries?
+	emergency dimen.DU         // additional stretchability of lines
+}
+
+// breakingPasses returns the passes to break a paragraph with parameters params.
+func breakingPasses(params *linebreak.Parameters) []pass {
+	var passes []pass
+	if params.PreTolerance >= 0 {
+		passes = append(passes, pass{no: 1, tolerance: params.PreTolerance})
+	}
+	passes = append(passes, pass{no: 2, tolerance: params.Tolerance, hyphenate: true})
+	if params.EmergencyStretch > 0 {
+		passes = append(passes, pass{no: 3, tolerance: params.Tolerance, hyphenate: true,
+			emergency: params.EmergencyStretch})
+	}
+	return passes
 }
 
 func newLinebreaker(parshape linebreak.ParShape, params *linebreak.Parameters) *linebreaker {
@@ -65,6 +102,7 @@ func newLinebreaker(parshape linebreak.ParShape, params *linebreak.Parameters) *
 		params = NewKPDefaultParameters()
 	}
 	kp.params = params
+	kp.pass = pass{no: 2, tolerance: params.Tolerance, hyphenate: true}
 	return kp
 }
 
@@ -83,6 +121,7 @@ func NewKPDefaultParameters() *linebreak.Parameters {
 		LeftSkip:             khipu.NewGlue(0, 0, 0),
 		RightSkip:            khipu.NewGlue(0, 0, 0),
 		ParFillSkip:          khipu.NewFill(2),
+		Raggedness:           dimen.DU(dimen.PT * 20),
 	}
 }
 
@@ -152,6 +191,7 @@ type bookkeeping struct {
 	totalcost    linebreak.Merits // sum of costs for segment up to this breakpoint
 	startDiscard linebreak.WSS    // sum of discardable space at start of segment / line
 	breakDiscard linebreak.WSS    // sum of discardable space while lookinf for next breakpoint
+	discBreak    linebreak.WSS    // change of width when breaking at a discretionary
 	hasContent   bool             // does this segment contain non-discardable item?
 }
 
@@ -196,8 +236,8 @@ func (fb *feasibleBreakpoint) UpdateSegment(linecnt int32, diff linebreak.WSS) {
 	}
 }
 
-func (fb *feasibleBreakpoint) UpdateSegmentBookkeeping(mark khipu.Mark) {
-	wss := linebreak.WSS{}.SetFromKnot(mark.Knot()) // get dimensions of knot
+func (fb *feasibleBreakpoint) UpdateSegmentBookkeeping(mark khipu.Mark, params *linebreak.Parameters) {
+	wss := params.Measure(mark.Knot()) // get dimensions of knot
 	for _, book := range fb.books {
 		book.segment = book.segment.Add(wss)
 		if book.hasContent {
@@ -213,6 +253,13 @@ func (fb *feasibleBreakpoint) UpdateSegmentBookkeeping(mark khipu.Mark) {
 				book.hasContent = true
 			}
 		}
+		switch knot := mark.Knot().(type) {
+		case khipu.Discretionary: // pre-break text replaces no-break text at a break
+			book.discBreak = linebreak.BreakWidth(knot)
+		case khipu.Penalty:
+		default:
+			book.discBreak = linebreak.WSS{}
+		}
 		T().Debugf("extending segment to %v", book.segment)
 	}
 }
@@ -270,20 +317,22 @@ func (kp *linebreaker) findPredecessorsWithLinecount(fb *feasibleBreakpoint, lin
 // than the exising one, the new segment replaces the old one
 // (just one segment between the two breakpoints can exist with pruning).
 func (kp *linebreaker) newFeasibleLine(fb *feasibleBreakpoint, mark khipu.Mark,
-	cost linebreak.Merits, linecnt int32) *feasibleBreakpoint {
+	c cost, linecnt int32) *feasibleBreakpoint {
 	//
 	newfb := kp.findBreakpointAtMark(mark)
 	if newfb == nil { // breakpoint not yet existent => create one
 		newfb = kp.newBreakpointAtMark(mark)
 	}
-	targettotal := fb.books[linecnt-1].totalcost + cost // total cost of new line
+	targettotal := fb.books[linecnt-1].totalcost + c.demerits // total cost of new line
 	//T().Debugf("targettotal=%d, cost=%d", targettotal, cost)
 	if kp.isCheapestSurvivor(newfb, targettotal, linecnt) {
-		newfb.books[linecnt] = &bookkeeping{totalcost: targettotal}
-		kp.AddEdge(fb, newfb, cost, targettotal, linecnt)
-		T().Debugf("new line %v ---%d---> %v", fb, cost, newfb)
+		// a line after a discretionary break starts with the post-break text
+		newfb.books[linecnt] = &bookkeeping{totalcost: targettotal, segment: kp.post,
+			hasContent: kp.post.W != 0}
+		kp.AddEdge(fb, newfb, c, targettotal, linecnt)
+		T().Debugf("new line %v ---%d---> %v", fb, c.demerits, newfb)
 	} else {
-		T().Debugf("not creating line %v ---%d---> %v", fb, cost, newfb)
+		T().Debugf("not creating line %v ---%d---> %v", fb, c.demerits, newfb)
 	}
 	return newfb
 }
@@ -333,8 +382,11 @@ func (kp *linebreaker) isCheapestSurvivor(fb *feasibleBreakpoint, totalcost line
 // Calculate the cost of a breakpoint. A breakpoint may result either in being
 // infeasible (demerits >= infinity) or having a positive (demerits) or negative
 // (merits) cost/benefit.
+//
+// emergency is additional stretchability for every line, as used by the final
+// pass of the line breaker.
 func (fb *feasibleBreakpoint) calculateCostsTo(penalty khipu.Penalty, parshape linebreak.ParShape,
-	params *linebreak.Parameters) (map[int32]cost, bool) {
+	params *linebreak.Parameters, emergency dimen.DU) (map[int32]cost, bool) {
 	//
 	T().Debugf("### calculateCostsTo(%v)", penalty)
 	var costs = make(map[int32]cost) // linecount => cost, i.e. costs for different line targets
@@ -343,6 +395,7 @@ func (fb *feasibleBreakpoint) calculateCostsTo(penalty khipu.Penalty, parshape l
 		T().Debugf(" ## checking cost at linecnt=%d", linecnt)
 		linelen := parshape.LineLength(linecnt + 1) // length of line to fit into
 		segwss := fb.segmentWidth(linecnt, params)
+		segwss.Max += emergency
 		d := linebreak.InfinityDemerits  // pre-set result variable
 		b := linebreak.InfinityDemerits  // badness of line
 		stsh := absD(linelen - segwss.W) // stretch or shrink of glue in line
@@ -384,16 +437,17 @@ func (fb *feasibleBreakpoint) calculateCostsTo(penalty khipu.Penalty, parshape l
 
 // segmentWidth returns the widths of a segment at fb, subtracting discardable
 // items at the start of the segment and at the end (= possible breakpoint).
-//
-// TODO This is the location to use params.LeftSkip & RightSkip
+// For a breakpoint at a discretionary, its pre-break text is counted instead of
+// its no-break text.
+// The skips at the edges of the line are added (see linebreak.Parameters.Skips).
 func (fb *feasibleBreakpoint) segmentWidth(linecnt int32, params *linebreak.Parameters) linebreak.WSS {
 	segw := fb.books[linecnt].segment
 	segw = segw.Subtract(fb.books[linecnt].startDiscard)
 	segw = segw.Subtract(fb.books[linecnt].breakDiscard)
-	w := linebreak.WSS{}.SetFromKnot(params.LeftSkip)
-	segw = segw.Add(w)
-	w = linebreak.WSS{}.SetFromKnot(params.RightSkip)
-	segw = segw.Add(w)
+	segw = segw.Add(fb.books[linecnt].discBreak)
+	left, right := params.Skips()
+	segw = segw.Add(linebreak.WSS{}.SetFromKnot(left))
+	segw = segw.Add(linebreak.WSS{}.SetFromKnot(right))
 	return segw
 }
 
@@ -484,7 +538,16 @@ func penaltyAt(cursor linebreak.Cursor) (khipu.Penalty, khipu.Mark) {
 func BreakParagraph(cursor linebreak.Cursor, parshape linebreak.ParShape,
 	params *linebreak.Parameters) ([]khipu.Mark, error) {
 	//
-	variants, breakpoints, err := FindBreakpoints(cursor, parshape, params, nil)
+	return BreakParagraphContext(context.Background(), cursor, parshape, params)
+}
+
+// BreakParagraphContext is like BreakParagraph, but records a trace span as a child
+// of a span contained in ctx (see package core/spans). Breaking stops with the
+// error of ctx if ctx is cancelled or its deadline is exceeded.
+func BreakParagraphContext(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) ([]khipu.Mark, error) {
+	//
+	variants, breakpoints, err := FindBreakpointsContext(ctx, cursor, parshape, params, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -513,23 +576,68 @@ func BreakParagraph(cursor linebreak.Cursor, parshape linebreak.ParShape,
 func FindBreakpoints(cursor linebreak.Cursor, parshape linebreak.ParShape, params *linebreak.Parameters,
 	dotfile io.Writer) ([]int32, map[int32][]khipu.Mark, error) {
 	//
-	kp, err := setupLinebreaker(cursor, parshape, params)
-	if err != nil {
-		return nil, nil, err
-	}
-	err = kp.constructBreakpointGraph(cursor, parshape, params)
+	return FindBreakpointsContext(context.Background(), cursor, parshape, params, dotfile)
+}
+
+// FindBreakpointsContext is like FindBreakpoints, but records a trace span as a child
+// of a span contained in ctx (see package core/spans). Breaking stops with the
+// error of ctx if ctx is cancelled or its deadline is exceeded.
+func FindBreakpointsContext(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters, dotfile io.Writer) ([]int32, map[int32][]khipu.Mark, error) {
+	//
+	_, span := spans.Start(ctx, spans.Paragraph, "knuthplass.FindBreakpoints")
+	defer span.End()
+	kp, err := breakInPasses(ctx, cursor, parshape, params)
 	if err != nil {
-		T().Errorf(err.Error())
+		span.RecordError(err)
 		return nil, nil, err
 	}
 	variants, breaks := kp.collectFeasibleBreakpoints(kp.end)
+	span.SetAttribute("variants", len(variants))
+	span.SetAttribute("pass", kp.pass.no)
 	if dotfile != nil {
 		dotcursor := khipu.NewCursor(cursor.Khipu())
-		kp.toGraphViz(dotcursor, breaks, dotfile)
+		if err = kp.toGraphViz(dotcursor, breaks, dotfile); err != nil {
+			T().Errorf("cannot write breakpoint graph: %v", err)
+		}
 	}
 	return variants, breaks, nil
 }
 
+// breakInPasses breaks a paragraph in up to three passes (see type pass) and
+// returns the line breaker of the pass whose result is to be taken. The first
+// pass reads the paragraph with cursor, subsequent passes re-read the khipu of
+// cursor from the start.
+func breakInPasses(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) (*linebreaker, error) {
+	//
+	if params == nil {
+		params = NewKPDefaultParameters()
+	}
+	var kp *linebreaker
+	var err error
+	for i, pass := range breakingPasses(params) {
+		if i > 0 {
+			T().Infof("K&P pass %d failed, starting pass %d", kp.pass.no, pass.no)
+			cursor = khipu.NewCursor(cursor.Khipu())
+		}
+		if kp, err = setupLinebreaker(cursor, parshape, params); err != nil {
+			return nil, err
+		}
+		kp.pass = pass
+		if err = kp.constructBreakpointGraph(ctx, cursor, parshape, params); err != nil {
+			if ctx.Err() == nil { // do not report cancellation as an error
+				T().Errorf(err.Error())
+			}
+			return nil, err
+		}
+		if !kp.overfull {
+			break
+		}
+	}
+	return kp, nil
+}
+
 // constructBreakpointGraph is the central algorithm, akin to the paragraph breaking
 // algorithm described by Knuth & Plass for the TeX typesetting system.
 //
@@ -548,13 +656,31 @@ func FindBreakpoints(cursor linebreak.Cursor, parshape linebreak.ParShape, param
 //
 // The above operations contruct a DAG, starting from a single node representing the
 // start of the paragraph, to a single node representing the end.
-func (kp *linebreaker) constructBreakpointGraph(cursor linebreak.Cursor, parshape linebreak.ParShape,
+//
+// Construction stops with the error of ctx if ctx is cancelled.
+func (kp *linebreaker) constructBreakpointGraph(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
 	params *linebreak.Parameters) error {
 	//
+	kp.post = linebreak.WSS{}
 	var last khipu.Mark        // will hold last position within input khipu
 	var fb *feasibleBreakpoint // will hold feasible breakpoint from horizon
+	var prev khipu.KnotType    // type of the knot preceding the current one
 	for cursor.Next() {        // outer loop over input knots
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		last = cursor.Mark() // we will need the last knot at the end of the loop
+		// penalties after a discretionary break a word, which is not allowed in pass 1
+		hyphenBreak := prev == khipu.KTDiscretionary
+		prev = last.Knot().Type()
+		switch knot := last.Knot().(type) {
+		case khipu.Discretionary:
+			w := knot.PostWidth()
+			kp.post = linebreak.WSS{W: w, Min: w, Max: w}
+		case khipu.Penalty:
+		default:
+			kp.post = linebreak.WSS{}
+		}
 		T().Debugf("_______________ %d/%v ___________________", last.Position(), last.Knot())
 		if fb = kp.horizon.first(); fb == nil {
 			panic("no more active breakpoints, but input available") // TODO remove after debugging
@@ -562,32 +688,34 @@ func (kp *linebreaker) constructBreakpointGraph(cursor linebreak.Cursor, parshap
 		// --- main loop over active breakpoints in horizon ------------
 		for fb != nil { // loop over active feasible breakpoints of horizon
 			T().Debugf("                %d/%v  (in horizon)", fb.mark.Position(), fb.mark.Knot())
-			fb.UpdateSegmentBookkeeping(cursor.Mark())
+			fb.UpdateSegmentBookkeeping(cursor.Mark(), kp.params)
 			// Breakpoints are allowed at penalties only
-			if cursor.Mark().Knot().Type() == khipu.KTPenalty { // TODO discretionaries
+			if cursor.Mark().Knot().Type() == khipu.KTPenalty && (kp.pass.hyphenate || !hyphenBreak) {
 				var penalty khipu.Penalty
 				penalty, last = penaltyAt(cursor) // find correct p, if more than one
-				costs, stillreachable := fb.calculateCostsTo(penalty, parshape, kp.params)
+				costs, stillreachable := fb.calculateCostsTo(penalty, parshape, kp.params, kp.pass.emergency)
 				if stillreachable { // yes, position may have been reached in this iteration
 					for linecnt, cost := range costs { // check for every linecount alternative
 						if linebreak.Merits(penalty.Demerits()) <= linebreak.InfinityMerits { // forced break
-							if cost.badness > kp.params.Tolerance {
+							if cost.badness > kp.pass.tolerance {
 								T().Infof("Underfull box at line %d, b=%d, d=%d", linecnt+1, cost.badness, cost.demerits)
 							}
-							newfb := kp.newFeasibleLine(fb, cursor.Mark(), cost.demerits, linecnt+1)
+							newfb := kp.newFeasibleLine(fb, cursor.Mark(), cost, linecnt+1)
 							kp.horizon.Add(newfb) // make forced break member of horizon n+1
-						} else if cost.badness < kp.params.Tolerance &&
+						} else if cost.badness < kp.pass.tolerance &&
 							cost.demerits < linebreak.InfinityDemerits { // happy case: new breakpoint is feasible
 							//
-							newfb := kp.newFeasibleLine(fb, cursor.Mark(), cost.demerits, linecnt+1)
+							newfb := kp.newFeasibleLine(fb, cursor.Mark(), cost, linecnt+1)
 							kp.horizon.Add(newfb) // make new breakpoint member of horizon n+1
 						}
 					}
 				} else { // no longer reachable => check against draining of horizon
 					if kp.horizon.Size() <= 1 { // oops, low on options
+						overfull := cost{badness: linebreak.InfinityDemerits, demerits: linebreak.InfinityDemerits}
 						for linecnt := range costs {
 							T().Infof("Overfull box at line %d, cost=10000", linecnt+1)
-							newfb := kp.newFeasibleLine(fb, cursor.Mark(), linebreak.InfinityDemerits, linecnt+1)
+							kp.overfull = true
+							newfb := kp.newFeasibleLine(fb, cursor.Mark(), overfull, linecnt+1)
 							kp.horizon.Add(newfb) // make new fb member of horizon n+1
 							if newfb.mark.Position() == fb.mark.Position() {
 								panic("THIS SHOULD NOT HAPPEN ?!?")
@@ -2,18 +2,20 @@ package knuthplass
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/schuko/tracing/gotestingadapter"
 	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/core/parameters"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/linetest"
 )
 
 var graphviz = false // globally switches GraphViz output on/off
@@ -22,7 +24,7 @@ func TestGraph1(t *testing.T) {
 	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
 	defer teardown()
 	//
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
 	g := newLinebreaker(parshape, nil)
 	g.newBreakpointAtMark(provisionalMark(1))
@@ -43,7 +45,7 @@ func setupKPTest(t *testing.T, paragraph string, hyphens bool) (*khipu.Khipu, li
 		t.Errorf("no Khipu to test; input is %s", paragraph)
 	}
 	kh.AppendKnot(khipu.Penalty(linebreak.InfinityMerits))
-	gtrace.CoreTracer.Infof("input khipu=%s", kh.String())
+	T().Infof("input khipu=%s", kh.String())
 	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 0)
 	var dotfile io.Writer
 	var err error
@@ -61,7 +63,7 @@ func TestKPUnderfull(t *testing.T) {
 	defer teardown()
 	//
 	kh, cursor, dotfile := setupKPTest(t, " ", false)
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
 	v, breaks, err := FindBreakpoints(cursor, parshape, nil, dotfile)
 	t.Logf("%d linebreaking-variants for empty line found, error = %v", len(v), err)
@@ -83,7 +85,7 @@ func TestKPExactFit(t *testing.T) {
 	defer teardown()
 	//
 	kh, cursor, dotfile := setupKPTest(t, "The quick.", false)
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
 	v, breaks, err := FindBreakpoints(cursor, parshape, nil, dotfile)
 	t.Logf("%d linebreaking-variants found, error = %v", len(v), err)
@@ -108,7 +110,7 @@ func TestKPOverfull(t *testing.T) {
 	params := NewKPDefaultParameters()
 	params.EmergencyStretch = dimen.DU(0)
 	params.Tolerance = 400
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
 	v, breaks, err := FindBreakpoints(cursor, parshape, params, dotfile)
 	t.Logf("%d linebreaking-variants found, error = %v", len(v), err)
@@ -136,7 +138,7 @@ func TestKPParaKing(t *testing.T) {
 	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 3)
 	params := NewKPDefaultParameters()
 	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
-	gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	T().SetTraceLevel(tracing.LevelDebug)
 	v, breaks, err := FindBreakpoints(cursor, parshape, params, dotfile)
 	t.Logf("%d linebreaking-variants found, error = %v", len(v), err)
 	for linecnt, breakpoints := range breaks {
@@ -161,7 +163,7 @@ func TestKPParaPrincess(t *testing.T) {
 	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 2)
 	params := NewKPDefaultParameters()
 	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
-	//gtrace.CoreTracer.SetTraceLevel(tracing.LevelDebug)
+	//T().SetTraceLevel(tracing.LevelDebug)
 	breakpoints, err := BreakParagraph(cursor, parshape, params)
 	//v, breaks, err := FindBreakpoints(cursor, parshape, params, dotfile)
 	//t.Logf("%d linebreaking-variants found, error = %v", len(v), err)
@@ -230,3 +232,263 @@ func justify(text string, l int, even bool) string {
 	}
 	return b.String()
 }
+
+func TestKPPasses(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	params := NewKPDefaultParameters()
+	params.PreTolerance = -1
+	params.EmergencyStretch = 0
+	if passes := breakingPasses(params); len(passes) != 1 || passes[0].no != 2 {
+		t.Errorf("expected second pass only, have %v", passes)
+	}
+	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
+	_, cursor, _ := setupKPTest(t, "The quick.", false)
+	kp, err := breakInPasses(context.Background(), cursor, parshape, NewKPDefaultParameters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kp.pass.no != 1 {
+		t.Errorf("expected exact fit to be found in first pass, have pass %d", kp.pass.no)
+	}
+	// without stretchable glue, the lines fit with emergency stretch only
+	params = NewKPDefaultParameters()
+	params.EmergencyStretch = 200 * dimen.BP
+	_, cursor, _ = setupKPTest(t, "The quick brown fox.", false)
+	kp, err = breakInPasses(context.Background(), cursor, parshape, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kp.pass.no != 3 || kp.overfull {
+		t.Errorf("expected emergency pass to succeed, have pass %d, overfull=%v", kp.pass.no, kp.overfull)
+	}
+}
+
+func TestKPCancel(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	_, cursor, _ := setupKPTest(t, "The quick brown fox.", false)
+	parshape := linebreak.RectangularParShape(10 * 10 * dimen.BP)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BreakParagraphContext(ctx, cursor, parshape, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected breaking to be cancelled, error is %v", err)
+	}
+}
+
+func TestKPBreakSequence(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	kh, _, _ := setupKPTest(t, king, false)
+	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 3)
+	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
+	params := NewKPDefaultParameters()
+	params.DebugGraph = true
+	seq, err := BreakLines(cursor, parshape, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("break sequence:\n%s", seq)
+	if len(seq.Lines) != 2 || len(seq.Breaks()) != 3 {
+		t.Fatalf("expected paragraph to be broken into 2 lines, have %d", len(seq.Lines))
+	}
+	var total linebreak.Merits
+	for i, line := range seq.Lines {
+		if line.Break.Position() != seq.Breaks()[i+1].Position() {
+			t.Errorf("line %d: break at %d does not match breakpoint", i+1, line.Break.Position())
+		}
+		if line.Hyphenated {
+			t.Errorf("line %d: did not expect hyphenation", i+1)
+		}
+		total += line.Demerits
+	}
+	if total != seq.Demerits {
+		t.Errorf("expected total demerits to be %d, have %d", total, seq.Demerits)
+	}
+	if seq.Lines[0].Ratio < -1 || seq.Lines[0].Badness >= 10000 {
+		t.Errorf("expected first line to be set without overfull box, r=%.2f", seq.Lines[0].Ratio)
+	}
+}
+
+func TestKPLines(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	// first-fit would break after "cc", see firstfit.TestLines
+	para := linetest.Words("aaa bb cc ddddd").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(12), nil, "aaa bb", "cc ddddd")
+	para = linetest.Text("hyphen").Hyphen().Text("ation").Space().Text("is").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(8), nil, "hyphen-", "ation is")
+	para = linetest.Words("the quick brown fox jumps over the lazy dog").End()
+	linetest.ExpectBreaks(t, para, BreakParagraphContext, linetest.Width(15), nil, 8, 17, 27)
+}
+
+func TestKPDiscretionary(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := linetest.Words("wir").Space().Text("ba").Discretionary("k-", "k", "ck").Text("en").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(10), nil, "wir backen")
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(8), nil, "wir bak-", "ken")
+}
+
+func TestKPRaggedRight(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	params := NewKPDefaultParameters()
+	para := linetest.Words("aaa bb cc ddddd").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(11), params, "aaa bb", "cc ddddd")
+	// with rigid inter-word glue, a line "aaa bb" would be too loose
+	params.Alignment = linebreak.RaggedRight
+	params.Raggedness = 3 * dimen.BP
+	para = linetest.Words("aaa bb cc ddddd").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(11), params, "aaa bb cc", "ddddd")
+}
+
+func TestKPExpansion(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	params := NewKPDefaultParameters()
+	para := linetest.Words("the quick brown fox jumps over the lazy dog").End()
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(14), params,
+		"the quick", "brown fox", "jumps over", "the lazy dog")
+	// inter-word glue cannot shrink, but text may be compressed by up to 10%
+	params.Expansion = linebreak.Expansion{Stretch: 100, Shrink: 100}
+	linetest.ExpectLines(t, para, BreakParagraphContext, linetest.Width(14), params,
+		"the quick brown", "fox jumps over", "the lazy dog")
+}
+
+func TestKPGraphExport(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
+	kh, _, _ := setupKPTest(t, king, false)
+	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 3)
+	seq, err := BreakLines(cursor, parshape, NewKPDefaultParameters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dot bytes.Buffer
+	if err = seq.GraphViz(&dot); err != ErrNoGraph {
+		t.Errorf("expected graph not to be retained without DebugGraph, have err=%v", err)
+	}
+	params := NewKPDefaultParameters()
+	params.DebugGraph = true
+	kh, _, _ = setupKPTest(t, king, false)
+	cursor = linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 3)
+	if seq, err = BreakLines(cursor, parshape, params); err != nil {
+		t.Fatal(err)
+	}
+	if err = seq.GraphViz(&dot); err != nil {
+		t.Fatal(err)
+	}
+	out := dot.String()
+	if !strings.HasPrefix(out, "digraph") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("expected GraphViz output, have %q", out)
+	}
+	if !strings.Contains(out, "color=darkgreen") {
+		t.Errorf("expected lines of the solution to be highlighted")
+	}
+}
+
+func setupParagraphs(t testing.TB, n int) []linebreak.Paragraph {
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_MINHYPHENLENGTH, 100) // inhibit hyphenation
+	parshape := linebreak.RectangularParShape(45 * 10 * dimen.BP)
+	paras := make([]linebreak.Paragraph, n)
+	for i := range paras {
+		text := princess
+		if i%2 == 1 {
+			text = king
+		}
+		kh := khipu.KnotEncode(strings.NewReader(text), 0, nil, regs)
+		if kh == nil {
+			t.Fatalf("no Khipu to test; input is %s", text)
+		}
+		kh.AppendKnot(khipu.Penalty(linebreak.InfinityMerits))
+		cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(kh), 10*dimen.BP, 2)
+		paras[i] = linebreak.Paragraph{Cursor: cursor, ParShape: parshape}
+	}
+	return paras
+}
+
+func TestKPParallel(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	paras := setupParagraphs(t, 8)
+	results := linebreak.BreakParagraphs(context.Background(), paras, BreakParagraphContext, 4)
+	if len(results) != len(paras) {
+		t.Fatalf("expected %d results, have %d", len(paras), len(results))
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("paragraph %d: %v", i, r.Err)
+		}
+		if r.Breakpoints[0].Position() != -1 {
+			t.Errorf("paragraph %d: expected breakpoints to start at start of paragraph", i)
+		}
+		if len(r.Breakpoints) != len(results[i%2].Breakpoints) {
+			t.Errorf("paragraph %d: expected %d breakpoints, have %d", i,
+				len(results[i%2].Breakpoints), len(r.Breakpoints))
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = linebreak.BreakParagraphs(ctx, setupParagraphs(t, 2), BreakParagraphContext, 1)
+	if results[0].Err == nil || results[1].Err == nil {
+		t.Errorf("expected cancelled context to prevent breaking paragraphs")
+	}
+}
+
+func TestKPBreakPages(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	// 12 lines of 10pt on pages of 100pt, where a break after line 10 is penalized
+	vl := khipu.NewVList()
+	for i := 0; i < 12; i++ {
+		vl.AppendKnot(khipu.NewVBox(10*dimen.PT, i))
+		if i == 9 {
+			vl.AppendKnot(khipu.Penalty(5000))
+		} else if i < 11 {
+			vl.AppendKnot(khipu.Penalty(0))
+		}
+	}
+	EndVList(vl, nil)
+	pages := linebreak.RectangularParShape(100 * dimen.PT)
+	breaks, err := BreakPages(khipu.NewCursor(vl), pages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breaks) != 2 {
+		t.Fatalf("expected list to be broken into 2 pages, have %d breaks", len(breaks))
+	}
+	if breaks[0].Position() != 17 { // penalty after line 9
+		t.Errorf("expected first page to end after line 9, instead of a penalized break, is %d", breaks[0].Position())
+	}
+	if breaks[1].Position() != vl.Length()-1 {
+		t.Errorf("expected last break at end of list, is %d", breaks[1].Position())
+	}
+}
+
+func benchmarkParallel(b *testing.B, workers int) {
+	T().SetTraceLevel(tracing.LevelError)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		paras := setupParagraphs(b, 64)
+		b.StartTimer()
+		linebreak.BreakParagraphs(context.Background(), paras, BreakParagraphContext, workers)
+	}
+}
+
+func BenchmarkKPSequential(b *testing.B)  { benchmarkParallel(b, 1) }
+func BenchmarkKPParallel2(b *testing.B)   { benchmarkParallel(b, 2) }
+func BenchmarkKPParallel4(b *testing.B)   { benchmarkParallel(b, 4) }
+func BenchmarkKPParallelMax(b *testing.B) { benchmarkParallel(b, 0) }
@@ -0,0 +1,151 @@
+package knuthplass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/spans"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Break sequences -------------------------------------------------------
+
+// BreakSequence is the result of breaking a paragraph: the optimal sequence of
+// breakpoints, together with diagnostics for each of the broken lines.
+//
+// If parameter DebugGraph is set for line breaking, a BreakSequence holds on to the
+// graph of feasible breakpoints the line breaker has constructed, which may then be
+// exported with GraphViz for debugging.
+type BreakSequence struct {
+	Lines    []BrokenLine     // broken lines of the paragraph
+	Demerits linebreak.Merits // total demerits of the paragraph
+	Pass     int              // pass of the line breaker which found the breakpoints
+	breaks   []khipu.Mark     // breakpoints, starting with the start of the paragraph
+	kp       *linebreaker     // line breaker holding the feasible-breakpoint graph, if retained
+	khipu    *khipu.Khipu     // the paragraph
+}
+
+// BrokenLine describes a line of a broken paragraph. The line spans knots
+// [From…Break-1] of the paragraph's khipu.
+type BrokenLine struct {
+	From       int64            // position of the first knot of the line
+	Break      khipu.Mark       // breakpoint at the end of the line
+	Ratio      float64          // glue set ratio (see linebreak.GlueSetRatio)
+	Infinite   bool             // Ratio applies to infinitely stretchable glue only
+	Badness    linebreak.Merits // badness of the line, 0…10000
+	Demerits   linebreak.Merits // demerits of the line, including its penalty
+	Hyphenated bool             // the line ends with the pre-break text of a discretionary
+}
+
+// BreakLines determines optimal linebreaks for a paragraph, as BreakParagraph
+// does, but returns them as a BreakSequence.
+func BreakLines(cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) (*BreakSequence, error) {
+	//
+	return BreakLinesContext(context.Background(), cursor, parshape, params)
+}
+
+// BreakLinesContext is like BreakLines, but records a trace span as a child
+// of a span contained in ctx (see package core/spans). Breaking stops with the
+// error of ctx if ctx is cancelled or its deadline is exceeded.
+func BreakLinesContext(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) (*BreakSequence, error) {
+	//
+	_, span := spans.Start(ctx, spans.Paragraph, "knuthplass.BreakLines")
+	defer span.End()
+	kp, err := breakInPasses(ctx, cursor, parshape, params)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	variants, breakpoints := kp.collectFeasibleBreakpoints(kp.end)
+	if len(variants) == 0 {
+		err = fmt.Errorf("No breakpoints could be found for paragraph")
+		span.RecordError(err)
+		return nil, err
+	}
+	best := variants[0] // slice is sorted by increasing totalcost, first one is best
+	seq := kp.breakSequence(cursor.Khipu(), breakpoints[best], parshape)
+	seq.Demerits = kp.end.books[best].totalcost
+	if !kp.params.DebugGraph {
+		seq.kp = nil // do not retain the graph
+	}
+	span.SetAttribute("pass", seq.Pass)
+	span.SetAttribute("lines", len(seq.Lines))
+	return seq, nil
+}
+
+// breakSequence collects the diagnostics for the lines between breaks.
+func (kp *linebreaker) breakSequence(k *khipu.Khipu, breaks []khipu.Mark,
+	parshape linebreak.ParShape) *BreakSequence {
+	//
+	seq := &BreakSequence{Pass: kp.pass.no, breaks: breaks, kp: kp, khipu: k}
+	for i := 1; i < len(breaks); i++ {
+		lineno := int32(i - 1)
+		from, to := breaks[i-1].Position(), breaks[i].Position()
+		if from < 0 {
+			from = 0
+		}
+		line := BrokenLine{From: from, Break: breaks[i]}
+		edge := kp.Edge(kp.Breakpoint(breaks[i-1].Position()), kp.Breakpoint(to), int32(i))
+		line.Badness, line.Demerits = edge.badness, edge.cost
+		start, end := linebreak.TrimLine(k, from, to)
+		linelen := parshape.LineLength(lineno)
+		if disc, _, ok := linebreak.BrokenDiscretionary(k, to); ok {
+			line.Hyphenated = disc.PreBreak() != nil
+			linelen -= linebreak.BreakWidth(disc).W
+		}
+		if disc, _, ok := linebreak.BrokenDiscretionary(k, from); ok && i > 1 {
+			linelen -= disc.PostWidth()
+		}
+		line.Ratio, line.Infinite = linebreak.GlueSetRatio(k, start, end, linelen)
+		seq.Lines = append(seq.Lines, line)
+	}
+	return seq
+}
+
+// Breaks returns the breakpoints of the paragraph, starting with the start of the
+// paragraph, as returned by BreakParagraph.
+func (seq *BreakSequence) Breaks() []khipu.Mark {
+	return seq.breaks
+}
+
+// String lists the broken lines of a break sequence with their diagnostics.
+func (seq *BreakSequence) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d lines, pass %d, demerits %s\n", len(seq.Lines), seq.Pass,
+		demeritsString(seq.Demerits))
+	for i, line := range seq.Lines {
+		hyphen := ""
+		if line.Hyphenated {
+			hyphen = " -"
+		}
+		fmt.Fprintf(&b, "%3d: [%d…%d] r=%.3f b=%d d=%s%s\n", i+1, line.From,
+			line.Break.Position(), line.Ratio, line.Badness, demeritsString(line.Demerits), hyphen)
+	}
+	return b.String()
+}
+
+// GraphViz outputs the graph of feasible breakpoints the line breaker has
+// considered for the paragraph, in GraphViz DOT format. Nodes are feasible
+// breakpoints, edges are candidate lines, labelled with badness, demerits and
+// total demerits. Breakpoints and lines of the sequence are highlighted, candidate
+// lines which have been discarded for cheaper ones are dashed.
+//
+// The graph is available only if the paragraph has been broken with parameter
+// DebugGraph set. Otherwise GraphViz returns ErrNoGraph.
+func (seq *BreakSequence) GraphViz(w io.Writer) error {
+	if seq.kp == nil || seq.khipu == nil {
+		return ErrNoGraph
+	}
+	optimal := map[int32][]khipu.Mark{int32(len(seq.Lines)): seq.breaks}
+	return seq.kp.toGraphViz(khipu.NewCursor(seq.khipu), optimal, w)
+}
+
+// ErrNoGraph is returned when exporting the breakpoint graph of a paragraph which
+// has been broken without parameter DebugGraph set.
+var ErrNoGraph = errors.New("breakpoint graph not retained; set parameter DebugGraph")
@@ -0,0 +1,85 @@
+package knuthplass
+
+import (
+	"context"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// --- Page breaking ---------------------------------------------------------
+
+// NewKPPageParameters creates parameters for breaking vertical lists into pages
+// (see BreakPages). Pages have a ragged bottom: every page may run short by
+// up to about raggedness, with increasing badness. Glue at the end of the list
+// lets the last page run short without penalty.
+//
+// Contrary to TeX, which breaks pages one at a time, page breaks are chosen to
+// be optimal for the complete list.
+func NewKPPageParameters(raggedness dimen.DU) *linebreak.Parameters {
+	return &linebreak.Parameters{
+		Tolerance:    linebreak.InfinityDemerits,
+		PreTolerance: -1, // vertical lists have no discretionaries
+		LinePenalty:  10,
+		LeftSkip:     khipu.NewGlue(0, 0, 0),
+		RightSkip:    khipu.NewGlue(0, 0, raggedness), // acts as glue at the bottom of pages
+		ParFillSkip:  khipu.NewFill(2),
+	}
+}
+
+// EndVList appends the end of a vertical list to vl, to be broken by BreakPages:
+// glue params.ParFillSkip, held in place by an empty box, and a forced break.
+// Without the box, the glue would be discarded at the break and the last page
+// would count as underfull.
+func EndVList(vl *khipu.Khipu, params *linebreak.Parameters) *khipu.Khipu {
+	fill := khipu.NewFill(2)
+	if params != nil {
+		fill = params.ParFillSkip
+	}
+	return vl.AppendKnot(fill).AppendKnot(khipu.NewVBox(0, -1)).
+		AppendKnot(khipu.Penalty(linebreak.InfinityMerits))
+}
+
+// BreakPages determines optimal page breaks for a vertical list (see
+// khipu.NewVList), consisting of vertical boxes for lines and blocks, glue
+// between them and penalties for page breaks. Pages may be broken at penalties
+// only; the list has to be terminated with EndVList. The height of page n is
+// given by pages.LineLength(n).
+//
+// Pages take the role of lines in the Knuth-Plass algorithm, i.e. the vertical
+// list is broken like a paragraph, sharing the graph of feasible breakpoints.
+// If params is nil, NewKPPageParameters is used with a raggedness of half the
+// height of the first page.
+//
+// BreakPages returns the marks of the penalties to break pages at, the last one
+// being the end of the list.
+func BreakPages(cursor linebreak.Cursor, pages linebreak.ParShape,
+	params *linebreak.Parameters) ([]khipu.Mark, error) {
+	//
+	return BreakPagesContext(context.Background(), cursor, pages, params)
+}
+
+// BreakPagesContext is like BreakPages, but records a trace span as a child
+// of a span contained in ctx (see package core/spans). Breaking stops with the
+// error of ctx if ctx is cancelled or its deadline is exceeded.
+func BreakPagesContext(ctx context.Context, cursor linebreak.Cursor, pages linebreak.ParShape,
+	params *linebreak.Parameters) ([]khipu.Mark, error) {
+	//
+	if params == nil && pages != nil {
+		params = NewKPPageParameters(pages.LineLength(1) / 2)
+	}
+	ctx, span := spans.Start(ctx, spans.Stage, "knuthplass.BreakPages")
+	defer span.End()
+	breaks, err := BreakParagraphContext(ctx, cursor, pages, params)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(breaks) > 0 && breaks[0].Position() < 0 {
+		breaks = breaks[1:] // drop the start of the list
+	}
+	span.SetAttribute("pages", len(breaks))
+	return breaks, nil
+}
@@ -40,15 +40,14 @@ package linebreak
 import (
 	"fmt"
 
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/engine/frame/khipu"
 )
 
-// T traces to a global core tracer.
+// T traces with key 'tyse.frame'.
 func T() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.frame")
 }
 
 type Merits int32
@@ -69,6 +68,10 @@ type Parameters struct {
 	LeftSkip             khipu.Glue // glue at left edge of paragraphs
 	RightSkip            khipu.Glue // glue at right edge of paragraphs
 	ParFillSkip          khipu.Glue // glue at the end of a paragraph
+	Alignment            Alignment  // setting of lines, see Skips
+	Raggedness           dimen.DU   // stretch of the skips of lines which are not justified
+	Expansion            Expansion  // limits of font expansion for justified lines
+	DebugGraph           bool       // retain graph of feasible breakpoints for inspection
 }
 
 // DefaultParameters are the standard line-breaking parameters.
@@ -86,6 +89,110 @@ var DefaultParameters = &Parameters{
 	LeftSkip:             khipu.NewGlue(0, 0, 0),
 	RightSkip:            khipu.NewGlue(0, 0, 0),
 	ParFillSkip:          khipu.NewGlue(0, 0, 0),
+	Raggedness:           dimen.DU(dimen.PT * 20),
+}
+
+// Alignment is the setting of the lines of a paragraph.
+//
+// Lines which are not justified are set in the manner of TeX's \raggedright:
+// their left or right skips get additional stretch (see Parameters.Skips), and
+// finite glue between words keeps its natural width. As the stretch of the skips
+// is finite, line breakers will still avoid lines which are very short, contrary
+// to simply filling up lines with infinitely stretchable glue.
+type Alignment uint8
+
+// Alignments of lines
+const (
+	Justified   Alignment = iota // lines are stretched or shrunk to the line length
+	RaggedRight                  // lines are set flush left
+	RaggedLeft                   // lines are set flush right
+	Centered                     // lines are centered
+)
+
+func (a Alignment) String() string {
+	switch a {
+	case RaggedRight:
+		return "ragged-right"
+	case RaggedLeft:
+		return "ragged-left"
+	case Centered:
+		return "centered"
+	}
+	return "justified"
+}
+
+// Skips returns the glue at the left and right edges of lines. For lines which
+// are not justified, params.Raggedness is added to the stretch of the right skip
+// (RaggedRight), of the left skip (RaggedLeft) or split between both skips
+// (Centered). If params.Raggedness is 0, the skips will stretch infinitely.
+func (params *Parameters) Skips() (left, right khipu.Glue) {
+	left, right = params.LeftSkip, params.RightSkip
+	stretch, half := params.Raggedness, params.Raggedness/2
+	if stretch == 0 {
+		stretch, half = dimen.Fil, dimen.Fil
+	}
+	switch params.Alignment {
+	case RaggedRight:
+		right[2] += stretch
+	case RaggedLeft:
+		left[2] += stretch
+	case Centered:
+		left[2] += half
+		right[2] += half
+	}
+	return left, right
+}
+
+// Expansion configures font expansion in the manner of pdfTeX's \pdfadjustspacing:
+// the glyphs of a justified line may be scaled horizontally, making the text of
+// the line wider or narrower, which lets line breakers find lines with glue set
+// closer to its natural width. Limits are given in thousandths of the width of
+// text; pdfTeX users typically choose a Stretch and Shrink of 20 with a Step of 5.
+// The zero value disables font expansion.
+type Expansion struct {
+	Stretch int // maximum expansion of text, in thousandths of its width
+	Shrink  int // maximum compression of text, in thousandths of its width
+	Step    int // granularity of expansion in thousandths, or 0 for continuous scaling
+}
+
+// Measure returns the width of a knot within a line, together with its minimum
+// and maximum width. For lines which are not justified, finite glue is rigid, as
+// are leaders. For justified lines with font expansion (see Expansion), text boxes
+// stretch and shrink within the limits of params.Expansion. A nil params measures
+// knots of justified lines.
+func (params *Parameters) Measure(knot khipu.Knot) WSS {
+	wss := WSS{}.SetFromKnot(knot)
+	if params.isRigid(knot) {
+		wss.Min, wss.Max = wss.W, wss.W
+	} else if params.expands() && knot.Type() == khipu.KTTextBox {
+		wss.Max += wss.W * dimen.DU(params.Expansion.Stretch) / 1000
+		wss.Min -= wss.W * dimen.DU(params.Expansion.Shrink) / 1000
+	}
+	return wss
+}
+
+// expands is true if text of lines may be expanded or compressed.
+func (params *Parameters) expands() bool {
+	return params != nil && params.Alignment == Justified &&
+		(params.Expansion.Stretch > 0 || params.Expansion.Shrink > 0)
+}
+
+// GlueDelta is like function GlueDelta, but respects the alignment of lines: for
+// lines which are not justified, finite glue keeps its natural width.
+// A nil params sets glue of justified lines.
+func (params *Parameters) GlueDelta(g khipu.Glue, ratio float64, infinite bool) dimen.DU {
+	if params.isRigid(g) {
+		return 0
+	}
+	return GlueDelta(g, ratio, infinite)
+}
+
+// isRigid is true if knot is glue which has to be set at its natural width.
+func (params *Parameters) isRigid(knot khipu.Knot) bool {
+	if params == nil || params.Alignment == Justified || knot == nil {
+		return false
+	}
+	return isGlue(knot) && !isFill(knot)
 }
 
 // ----------------------------------------------------------------------
@@ -159,6 +266,9 @@ func CapDemerits(d Merits) Merits {
 // --- Interfaces -------------------------------------------------------
 
 // Cursor is a type to iterate over a khipu.
+//
+// For streamed documents, a khipu.StreamCursor iterates over the khipu of one
+// paragraph at a time. Line breakers will break the current paragraph only.
 type Cursor interface {
 	Next() bool
 	Knot() khipu.Knot
@@ -167,6 +277,9 @@ type Cursor interface {
 	Khipu() *khipu.Khipu
 }
 
+var _ Cursor = &khipu.Cursor{}
+var _ Cursor = &khipu.StreamCursor{}
+
 // ParShape is a type to return the line length for a given line number.
 type ParShape interface {
 	LineLength(int32) dimen.DU
@@ -182,3 +295,34 @@ func (r rectParShape) LineLength(int32) dimen.DU {
 func RectangularParShape(linelen dimen.DU) ParShape {
 	return rectParShape(linelen)
 }
+
+// IndentingParShape is a ParShape which additionally offsets lines from the
+// start edge of the paragraph, e.g., to flow text around a float.
+type IndentingParShape interface {
+	ParShape
+	LineIndent(int32) dimen.DU
+}
+
+// LineIndent returns the indent of line l for parshape, if parshape is an
+// IndentingParShape, and 0 otherwise.
+func LineIndent(parshape ParShape, l int32) dimen.DU {
+	if ips, ok := parshape.(IndentingParShape); ok {
+		return ips.LineIndent(l)
+	}
+	return 0
+}
+
+// Justifier is a hook for distributing the extra space of a justified line.
+//
+// After a paragraph has been broken into lines, the difference between a line's
+// natural width and its target length will be distributed to the glue of the line.
+// A Justifier may absorb part of this difference beforehand, e.g., by inserting or
+// lengthening kashidas in Arabic text, or by applying the JSTF lookups of a font.
+type Justifier interface {
+	// Justify is called for a line spanning knots [from…to-1] of khipu k. excess is
+	// the difference between the line's target length and its natural width; it is
+	// negative for lines which have to shrink. Justify may insert or replace knots
+	// within the line. It returns the (possibly changed) end position of the line and
+	// the part of excess which is left for distribution to glue.
+	Justify(k *khipu.Khipu, from, to int64, excess dimen.DU) (int64, dimen.DU)
+}
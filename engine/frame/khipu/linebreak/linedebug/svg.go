@@ -0,0 +1,235 @@
+/*
+Package linedebug renders paragraphs which have been broken into lines, for
+debugging purposes. Output is SVG, with optional overlays to make the
+typographic quality of line breaking reviewable at a glance:
+
+  - glue, color-coded by the stretch or shrink actually applied to it
+  - penalties considered as breakpoints vs. breakpoints chosen
+  - hyphenation points
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2021 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package linedebug
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/schuko/tracing"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// tracer traces with key 'tyse.frame'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.frame")
+}
+
+// Overlay is a set of flags to select overlays for the SVG output.
+type Overlay uint8
+
+// Overlays to draw on top of the lines of a paragraph.
+const (
+	GlueOverlay    Overlay = 1 << iota // stretch/shrink applied to glue, color-coded
+	PenaltyOverlay                     // penalties considered vs. breakpoints chosen
+	HyphenOverlay                      // hyphenation points (discretionaries)
+	NoOverlays     Overlay = 0
+	AllOverlays            = GlueOverlay | PenaltyOverlay | HyphenOverlay
+)
+
+// Colors for glue, depending on the adjustment ratio applied.
+const (
+	colorNeutral  = "#cccccc" // glue set at (almost) natural width
+	colorShrink   = "#4a90d9" // glue shrunk within its limits
+	colorOverfull = "#8e44ad" // glue shrunk beyond its limits
+	colorLoose    = "#7ac36a" // glue stretched up to half of its stretchability
+	colorVLoose   = "#f0c419" // glue stretched up to its stretchability
+	colorUnderful = "#e74c3c" // glue stretched beyond its stretchability
+)
+
+const (
+	lineHeight = 18.0 // line distance in pt
+	boxHeight  = 12.0 // height of text boxes in pt
+	margin     = 10.0 // page margin in pt
+	labelWidth = 60.0 // width of the column for line statistics in pt
+)
+
+// ParagraphToSVG draws the lines of a paragraph as SVG. breakpoints are the marks
+// returned from a line breaker, with the first mark denoting the start of the
+// paragraph, and each following mark the end of a line. Line lengths are taken from
+// parshape. Text boxes are expected to have been measured.
+//
+// Glue is drawn at the width it is set to in a justified line, i.e., including the
+// stretch or shrink distributed to it.
+func ParagraphToSVG(k *khipu.Khipu, breakpoints []khipu.Mark, parshape linebreak.ParShape,
+	overlays Overlay, w io.Writer) error {
+	//
+	if k == nil || len(breakpoints) < 2 || parshape == nil {
+		return fmt.Errorf("linedebug: nothing to draw")
+	}
+	chosen := make(map[int64]bool, len(breakpoints))
+	for _, b := range breakpoints {
+		chosen[b.Position()] = true
+	}
+	var width dimen.DU
+	for l := int32(0); l < int32(len(breakpoints)-1); l++ {
+		if parshape.LineLength(l) > width {
+			width = parshape.LineLength(l)
+		}
+	}
+	ew := &errWriter{w: w}
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%.2fpt" height="%.2fpt" viewBox="0 0 %.2f %.2f">`+"\n",
+		width.Points()+2*margin+labelWidth, float64(len(breakpoints)-1)*lineHeight+2*margin,
+		width.Points()+2*margin+labelWidth, float64(len(breakpoints)-1)*lineHeight+2*margin)
+	ew.printf(`<g font-family="monospace" font-size="8">` + "\n")
+	from := int64(0)
+	for i := 1; i < len(breakpoints); i++ {
+		to := breakpoints[i].Position()
+		if from < 0 {
+			from = 0
+		}
+		lineno := int32(i - 1)
+		y := margin + float64(lineno)*lineHeight
+		drawLine(k, from, to, parshape.LineLength(lineno), y, chosen, overlays, ew)
+		from = to
+	}
+	ew.printf("</g>\n</svg>\n")
+	return ew.err
+}
+
+// drawLine draws knots [from…to-1] of k as a line of length linelen at vertical
+// position y.
+func drawLine(k *khipu.Khipu, from, to int64, linelen dimen.DU, y float64,
+	chosen map[int64]bool, overlays Overlay, ew *errWriter) {
+	//
+	end := to
+	from, to = linebreak.TrimLine(k, from, to)
+	ratio, infinite := linebreak.GlueSetRatio(k, from, to, linelen)
+	tracer().Debugf("line [%d…%d] has adjustment ratio %.2f", from, to, ratio)
+	ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="#eeeeee"/>`+"\n",
+		margin, y, linelen.Points(), boxHeight)
+	x := margin
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() && cursor.Position() < to {
+		if cursor.Position() < from {
+			continue
+		}
+		knot := cursor.Knot()
+		switch knot.Type() {
+		case khipu.KTTextBox:
+			box := cursor.AsTextBox()
+			by := y - box.Shift.Points() // raised or lowered by baseline shift
+			ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#f8f8f8" stroke="#999999" stroke-width="0.3"/>`+"\n",
+				x, by, box.W().Points(), boxHeight)
+			ew.printf(`<text x="%.2f" y="%.2f">%s</text>`+"\n", x, by+boxHeight-3, escape(box.Text()))
+			x += box.W().Points()
+		case khipu.KTGlue:
+			g := cursor.AsGlue()
+			delta := linebreak.GlueDelta(g, ratio, infinite)
+			gw := (g.W() + delta).Points()
+			if overlays&GlueOverlay != 0 {
+				ew.printf(`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"><title>%s</title></rect>`+"\n",
+					x, y+boxHeight/2, gw, boxHeight/2, GlueColor(ratio, infinite),
+					fmt.Sprintf("%.2fpt %+.2fpt (ratio %.2f)", g.W().Points(), delta.Points(), ratio))
+			}
+			x += gw
+		case khipu.KTKern:
+			x += knot.W().Points()
+		case khipu.KTPenalty:
+			if overlays&PenaltyOverlay != 0 {
+				drawPenalty(cursor.AsPenalty(), false, x, y, ew)
+			}
+		case khipu.KTDiscretionary:
+			if overlays&HyphenOverlay != 0 {
+				ew.printf(`<path d="M %.2f %.2f l -2 -3 h 4 z" fill="#e67e22"><title>hyphenation point</title></path>`+"\n",
+					x, y)
+			}
+			x += knot.W().Points() // no-break text, if any
+		}
+	}
+	if overlays&PenaltyOverlay != 0 && chosen[end] {
+		if p, ok := penaltyAt(k, end); ok {
+			drawPenalty(p, true, x, y, ew)
+		}
+	}
+	label := fmt.Sprintf("r=%.2f", ratio)
+	if infinite {
+		label = "r=fil"
+	}
+	ew.printf(`<text x="%.2f" y="%.2f" fill="#666666">%s</text>`+"\n",
+		margin+linelen.Points()+5, y+boxHeight-3, label)
+}
+
+// drawPenalty draws a marker for a penalty. Penalties which are considered as
+// breakpoints are marked with a tick below the line, chosen breakpoints with a
+// bar spanning the line.
+func drawPenalty(p khipu.Penalty, chosen bool, x, y float64, ew *errWriter) {
+	if chosen {
+		ew.printf(`<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="#27ae60" stroke-width="1.2"><title>break, penalty %d</title></line>`+"\n",
+			x, y-2, x, y+boxHeight+2, p)
+		return
+	}
+	if linebreak.Merits(p) >= linebreak.InfinityDemerits {
+		return // prohibited breakpoint
+	}
+	ew.printf(`<line x1="%.2f" y1="%.2f" x2="%.2f" y2="%.2f" stroke="#999999" stroke-width="0.5"><title>penalty %d</title></line>`+"\n",
+		x, y+boxHeight, x, y+boxHeight+3, p)
+}
+
+// GlueColor selects a color for glue, depending on the adjustment ratio of its
+// line. Colors are given as SVG color strings.
+func GlueColor(ratio float64, infinite bool) string {
+	switch {
+	case infinite:
+		return colorNeutral
+	case ratio < -1:
+		return colorOverfull
+	case ratio < -0.05:
+		return colorShrink
+	case ratio <= 0.05:
+		return colorNeutral
+	case ratio <= 0.5:
+		return colorLoose
+	case ratio <= 1:
+		return colorVLoose
+	}
+	return colorUnderful
+}
+
+func penaltyAt(k *khipu.Khipu, pos int64) (khipu.Penalty, bool) {
+	cursor := khipu.NewCursor(k)
+	for cursor.Next() {
+		if cursor.Position() == pos {
+			p, ok := cursor.Knot().(khipu.Penalty)
+			return p, ok
+		}
+	}
+	return 0, false
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// errWriter remembers the first error occuring during a sequence of writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
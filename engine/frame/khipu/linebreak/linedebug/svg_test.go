@@ -0,0 +1,58 @@
+package linedebug
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/parameters"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/firstfit"
+)
+
+var svgfile = false // global switch for writing SVG output to a file
+
+func TestParagraphToSVG(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	regs := parameters.NewTypesettingRegisters()
+	regs.Push(parameters.P_MINHYPHENLENGTH, 100) // inhibit hyphenation
+	k, err := khipu.EncodeText("The quick brown fox jumps over the lazy dog.",
+		khipu.WithRegisters(regs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := linebreak.NewFixedWidthCursor(khipu.NewCursor(k), 10*dimen.BP, 1)
+	parshape := linebreak.RectangularParShape(20 * 10 * dimen.BP)
+	breakpoints, err := firstfit.BreakParagraph(cursor, parshape, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svg bytes.Buffer
+	if err = ParagraphToSVG(k, breakpoints, parshape, AllOverlays, &svg); err != nil {
+		t.Fatal(err)
+	}
+	out := svg.String()
+	if !strings.HasPrefix(out, "<svg") || !strings.HasSuffix(out, "</svg>\n") {
+		t.Errorf("expected output to be an SVG document")
+	}
+	if n := strings.Count(out, "<title>break"); n != len(breakpoints)-1 {
+		t.Errorf("expected %d chosen breakpoints to be marked, have %d", len(breakpoints)-1, n)
+	}
+	if !strings.Contains(out, "<title>penalty") {
+		t.Errorf("expected penalties considered to be marked")
+	}
+	if !strings.Contains(out, `fill="`+colorLoose+`"`) && !strings.Contains(out, `fill="`+colorVLoose+`"`) {
+		t.Errorf("expected stretched glue to be color-coded")
+	}
+	if svgfile {
+		if err := os.WriteFile("paragraph.svg", svg.Bytes(), 0644); err != nil {
+			t.Error(err)
+		}
+	}
+}
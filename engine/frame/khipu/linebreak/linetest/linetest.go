@@ -0,0 +1,271 @@
+/*
+Package linetest helps writing tests for line breakers.
+
+Tests for line breakers need paragraphs with well-known dimensions. Encoding
+text with package khipu will result in knots depending on fonts, hyphenation
+dictionaries and line-breaking rules of Unicode, which makes it hard to tell
+where a paragraph should be broken. Package linetest provides a builder for
+khipus instead, where every character of a text box is one unit wide and glue,
+kerns and penalties are given explicitly:
+
+	para := linetest.Text("hello").Glue(1, 0, 1).Penalty(0).Text("world").End()
+
+As with khipus encoded from text, line breakers will break lines at penalties
+only.
+
+Dimensions are in units of 1 bp. Paragraphs are broken by a line breaker, e.g.,
+knuthplass.BreakParagraphContext, and the resulting lines or breakpoints are
+compared to expected ones:
+
+	linetest.ExpectLines(t, para, knuthplass.BreakParagraphContext, linetest.Width(11), nil,
+	    "hello", "world")
+
+Package linetest is intended to be used in tests only.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package linetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// Unit is the width of a character and the unit of dimensions given to a Para.
+const Unit = dimen.BP
+
+// Para is a builder for the khipu of a paragraph. Methods of Para append knots
+// and return the paragraph, so calls may be chained.
+type Para struct {
+	kh      *khipu.Khipu
+	textpos uint64 // position of the next text box in the (virtual) text
+}
+
+// New creates an empty paragraph.
+func New() *Para {
+	return &Para{kh: khipu.NewKhipu()}
+}
+
+// Text creates a paragraph starting with a text box for s.
+func Text(s string) *Para {
+	return New().Text(s)
+}
+
+// Words creates a paragraph from words separated by spaces. Words are separated
+// by inter-word glue, see method Space.
+func Words(s string) *Para {
+	return New().Words(s)
+}
+
+// Text appends a text box for s, one unit wide per character.
+func (p *Para) Text(s string) *Para {
+	box := khipu.NewTextBox(s, p.textpos)
+	p.textpos += uint64(measure(box))
+	p.kh.AppendKnot(box)
+	return p
+}
+
+// Words appends text boxes for the words of s, separated by inter-word glue and
+// break opportunities (see method Space).
+func (p *Para) Words(s string) *Para {
+	for i, word := range strings.Fields(s) {
+		if i > 0 {
+			p.Space()
+		}
+		p.Text(word)
+	}
+	return p
+}
+
+// Glue appends glue of width w, which may shrink by shrink and stretch by stretch.
+func (p *Para) Glue(w, shrink, stretch float64) *Para {
+	p.kh.AppendKnot(khipu.NewGlue(units(w), units(shrink), units(stretch)))
+	p.textpos++
+	return p
+}
+
+// Space appends inter-word glue of one unit, with a stretch of one unit and no
+// shrink, followed by a penalty of 0 as a break opportunity.
+func (p *Para) Space() *Para {
+	return p.Glue(1, 0, 1).Penalty(0)
+}
+
+// Kern appends a kern of width w.
+func (p *Para) Kern(w float64) *Para {
+	p.kh.AppendKnot(khipu.Kern(units(w)))
+	return p
+}
+
+// Penalty appends a penalty. Penalties of 10000 or more inhibit a break, penalties
+// of -10000 or less force a break.
+func (p *Para) Penalty(penalty int) *Para {
+	p.kh.AppendKnot(khipu.Penalty(penalty))
+	return p
+}
+
+// HyphenPenalty is the penalty for breaking at a hyphenation opportunity, see
+// method Hyphen. It is the default of TeX's \hyphenpenalty.
+const HyphenPenalty = 50
+
+// Hyphen appends a hyphenation opportunity with a hyphen one unit wide, followed
+// by a penalty of HyphenPenalty.
+func (p *Para) Hyphen() *Para {
+	p.kh.AppendKnot(khipu.Discretionary{HyphenChar: '-', Width: Unit})
+	return p.Penalty(HyphenPenalty)
+}
+
+// Discretionary appends a discretionary with pre-break, post-break and no-break
+// text (see khipu.Discretionary), followed by a penalty of HyphenPenalty. E.g.,
+// "backen" with a break changing "ck" to "k-k" is built as
+//
+//	linetest.Text("ba").Discretionary("k-", "k", "ck").Text("en")
+func (p *Para) Discretionary(pre, post, nobreak string) *Para {
+	d := khipu.NewDiscretionary(pre, post, nobreak)
+	for _, box := range []*khipu.TextBox{d.Pre, d.Post, d.NoBreak} {
+		if box != nil {
+			measure(box)
+		}
+	}
+	if d.NoBreak != nil {
+		d.NoBreak.Position = p.textpos
+		p.textpos += uint64(utf8.RuneCountInString(nobreak))
+	}
+	p.kh.AppendKnot(d)
+	return p.Penalty(HyphenPenalty)
+}
+
+// Break appends a forced break.
+func (p *Para) Break() *Para {
+	return p.Penalty(-10000)
+}
+
+// End ends the paragraph as TeX does, with a \parfillskip and a forced break.
+// Paragraphs should not end with glue when End is called.
+func (p *Para) End() *Para {
+	p.kh.AppendKnot(khipu.Penalty(dimen.Infinity))
+	p.kh.AppendKnot(khipu.NewFill(2))
+	return p.Break()
+}
+
+// Khipu returns the khipu built so far.
+func (p *Para) Khipu() *khipu.Khipu {
+	return p.kh
+}
+
+// Width returns a paragraph shape for lines w units long.
+func Width(w float64) linebreak.ParShape {
+	return linebreak.RectangularParShape(units(w))
+}
+
+// Breaks breaks a paragraph into lines and returns the positions of the breakpoints
+// within the khipu, excluding the start of the paragraph. A nil params selects the
+// breaker's default parameters.
+func Breaks(p *Para, breaker linebreak.BreakFunc, parshape linebreak.ParShape,
+	params *linebreak.Parameters) ([]int64, error) {
+	//
+	marks, err := breaker(context.Background(), khipu.NewCursor(p.kh), parshape, params)
+	if err != nil {
+		return nil, err
+	}
+	var breaks []int64
+	for _, mark := range marks {
+		if mark.Position() >= 0 {
+			breaks = append(breaks, mark.Position())
+		}
+	}
+	return breaks, nil
+}
+
+// Lines breaks a paragraph into lines and returns the text of every line, with
+// spaces for glue. Lines broken at a discretionary end with its pre-break text
+// (e.g., a hyphen), and the following lines start with its post-break text.
+func Lines(p *Para, breaker linebreak.BreakFunc, parshape linebreak.ParShape,
+	params *linebreak.Parameters) ([]string, error) {
+	//
+	breaks, err := Breaks(p, breaker, parshape, params)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(breaks))
+	from := int64(0)
+	post := ""
+	for i, pos := range breaks {
+		end, pre := pos, ""
+		d, dpos, broken := linebreak.BrokenDiscretionary(p.kh, pos)
+		if broken = broken && dpos >= from; broken {
+			end = dpos
+			if box := d.PreBreak(); box != nil {
+				pre = box.Text()
+			}
+		}
+		lines[i] = post + strings.TrimSpace(p.kh.Text(from, end)) + pre
+		post = ""
+		if broken && d.Post != nil {
+			post = d.Post.Text()
+		}
+		from = pos + 1
+	}
+	return lines, nil
+}
+
+// ExpectBreaks breaks a paragraph and reports an error to t, if the breakpoints
+// found differ from the expected positions.
+func ExpectBreaks(t testing.TB, p *Para, breaker linebreak.BreakFunc, parshape linebreak.ParShape,
+	params *linebreak.Parameters, expected ...int64) {
+	//
+	t.Helper()
+	breaks, err := Breaks(p, breaker, parshape, params)
+	if err != nil {
+		t.Fatalf("cannot break paragraph: %v", err)
+	}
+	if len(breaks) != len(expected) {
+		t.Errorf("expected breakpoints %v, have %v", expected, breaks)
+		return
+	}
+	for i := range breaks {
+		if breaks[i] != expected[i] {
+			t.Errorf("expected breakpoints %v, have %v", expected, breaks)
+			return
+		}
+	}
+}
+
+// ExpectLines breaks a paragraph and reports an error to t, if the text of the
+// lines differs from the expected lines (see Lines).
+func ExpectLines(t testing.TB, p *Para, breaker linebreak.BreakFunc, parshape linebreak.ParShape,
+	params *linebreak.Parameters, expected ...string) {
+	//
+	t.Helper()
+	lines, err := Lines(p, breaker, parshape, params)
+	if err != nil {
+		t.Fatalf("cannot break paragraph: %v", err)
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") || len(lines) != len(expected) {
+		t.Errorf("expected lines\n  %s\nhave\n  %s", strings.Join(expected, "\n  "),
+			strings.Join(lines, "\n  "))
+	}
+}
+
+// measure sets the dimensions of a text box, one unit wide per character, and
+// returns the number of characters.
+func measure(box *khipu.TextBox) int {
+	n := utf8.RuneCountInString(box.Text())
+	box.Width = dimen.DU(n) * Unit
+	box.Height = Unit
+	return n
+}
+
+func units(x float64) dimen.DU {
+	return dimen.DU(x * float64(Unit))
+}
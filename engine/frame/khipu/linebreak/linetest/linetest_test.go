@@ -0,0 +1,48 @@
+package linetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// breakAtPenalties is a line breaker breaking at every penalty below 10000.
+func breakAtPenalties(ctx context.Context, cursor linebreak.Cursor, parshape linebreak.ParShape,
+	params *linebreak.Parameters) ([]khipu.Mark, error) {
+	//
+	var marks []khipu.Mark
+	for cursor.Next() {
+		if p, ok := cursor.Knot().(khipu.Penalty); ok && p < 10000 {
+			marks = append(marks, cursor.Mark())
+		}
+	}
+	return marks, nil
+}
+
+func TestBuilder(t *testing.T) {
+	para := Words("the quick").Kern(0.5).Text("fox").End()
+	if para.Khipu().Length() != 9 {
+		t.Errorf("expected khipu of 9 knots, have %s", para.Khipu())
+	}
+	w, max, _ := para.Khipu().Measure(0, 3)
+	if w != 4*dimen.BP || max != 5*dimen.BP {
+		t.Errorf("expected 'the' followed by a space to be 4bp wide, stretching to 5bp, is %s…%s", w, max)
+	}
+	if text := para.Khipu().Text(0, para.Khipu().Length()); text != "the quick fox " {
+		t.Errorf("unexpected text of khipu: %q", text)
+	}
+}
+
+func TestLines(t *testing.T) {
+	para := Words("a b").Space().Text("hyph").Hyphen().Text("en").End()
+	ExpectBreaks(t, para, breakAtPenalties, Width(10), nil, 2, 5, 8, 12)
+	ExpectLines(t, para, breakAtPenalties, Width(10), nil, "a", "b", "hyph-", "en")
+	para = Text("ba").Discretionary("k-", "k", "ck").Text("en").End()
+	if text := para.Khipu().Text(0, para.Khipu().Length()); text != "backen " {
+		t.Errorf("expected no-break text in text of khipu, have %q", text)
+	}
+	ExpectLines(t, para, breakAtPenalties, Width(10), nil, "bak-", "ken")
+}
@@ -72,6 +72,13 @@ func (fwc FixedWidthCursor) setTextDimens(knot khipu.Knot) (khipu.Knot, bool) {
 		d := knot.(khipu.Discretionary)
 		isChanged = (d.Width != fwc.glyphWidth)
 		d.Width = fwc.glyphWidth
+		for _, b := range []*khipu.TextBox{d.Pre, d.Post, d.NoBreak} {
+			if b != nil {
+				b.Width = dimen.DU(len(b.Text())) * fwc.glyphWidth
+				b.Height = fwc.glyphWidth
+			}
+		}
+		return d, isChanged
 	case khipu.KTTextBox:
 		b := knot.(*khipu.TextBox)
 		newW := dimen.DU(len(b.Text())) * fwc.glyphWidth
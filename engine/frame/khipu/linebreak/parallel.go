@@ -0,0 +1,74 @@
+package linebreak
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+)
+
+// --- Breaking paragraphs in parallel ---------------------------------------
+
+// BreakFunc is the signature of line breakers, e.g. of
+// knuthplass.BreakParagraphContext or firstfit.BreakParagraphContext.
+type BreakFunc func(context.Context, Cursor, ParShape, *Parameters) ([]khipu.Mark, error)
+
+// Paragraph is a paragraph to break with BreakParagraphs. Paragraphs broken
+// concurrently must not share a khipu or a cursor. Parameters may be shared,
+// as line breakers do not modify them.
+type Paragraph struct {
+	Cursor   Cursor      // cursor to read the paragraph's khipu with
+	ParShape ParShape    // shape of the paragraph
+	Params   *Parameters // line-breaking parameters, nil for the breaker's defaults
+}
+
+// BreakResult is the result of breaking a paragraph with BreakParagraphs.
+type BreakResult struct {
+	Breakpoints []khipu.Mark // breakpoints found for the paragraph
+	Err         error        // error from the line breaker or the context
+}
+
+// BreakParagraphs breaks paragraphs independently of each other, with up to
+// workers paragraphs being broken in parallel. If workers is not positive,
+// GOMAXPROCS paragraphs are broken in parallel.
+//
+// Results are in the order of paragraphs, independent of the order in which
+// the paragraphs have been broken. If ctx is cancelled, paragraphs not yet
+// started are not broken, and their results carry the context's error.
+func BreakParagraphs(ctx context.Context, paragraphs []Paragraph, breaker BreakFunc,
+	workers int) []BreakResult {
+	//
+	results := make([]BreakResult, len(paragraphs))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(paragraphs))
+	T().Debugf("breaking %d paragraphs with %d workers", len(paragraphs), workers)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := paragraphs[i]
+				results[i].Breakpoints, results[i].Err = breaker(ctx, p.Cursor, p.ParShape, p.Params)
+			}
+		}()
+	}
+	for i := range paragraphs {
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
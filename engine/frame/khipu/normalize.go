@@ -0,0 +1,49 @@
+package khipu
+
+import (
+	"io"
+	"strings"
+
+	params "github.com/npillmayer/tyse/core/parameters"
+	"golang.org/x/text/unicode/norm"
+)
+
+// --- Unicode normalization -------------------------------------------------
+
+// https://unicode.org/reports/tr15/
+//
+// Text is normalized before it is encoded into knots, which is step (1) of the
+// pipeline described in the package documentation. The normalization form is set
+// with register P_NORMALIZATION:
+//
+//   - "NFC" composes characters, e.g. "e" followed by a combining acute accent
+//     results in "é" (default)
+//   - "NFD" decomposes characters, which may be preferable for fonts with
+//     mark positioning, but without glyphs for precomposed characters
+//
+// Illegal values result in NFC. Text positions of text boxes refer to the
+// normalized text.
+
+// Normalization returns the Unicode normalization form set with register
+// P_NORMALIZATION. If regs is nil, NFC is returned.
+func Normalization(regs *params.TypesettingRegisters) norm.Form {
+	if regs == nil {
+		return norm.NFC
+	}
+	if strings.ToUpper(regs.S(params.P_NORMALIZATION)) == "NFD" {
+		return norm.NFD
+	}
+	return norm.NFC
+}
+
+// Normalize returns text in the normalization form set with register
+// P_NORMALIZATION.
+func Normalize(text string, regs *params.TypesettingRegisters) string {
+	return Normalization(regs).String(text)
+}
+
+// normalizingReader wraps text into a reader returning text in the normalization
+// form set with register P_NORMALIZATION.
+func normalizingReader(text io.Reader, regs *params.TypesettingRegisters) io.Reader {
+	return Normalization(regs).Reader(text)
+}
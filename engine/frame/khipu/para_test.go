@@ -41,7 +41,7 @@ func TestParaBreak(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 	t.Logf("inner text of DOM = '%s'", para.Raw().String())
-	k, err := EncodeParagraph(para, 0, monospace.Shaper(11*dimen.PT, nil), nil, nil)
+	k, err := EncodeStyledParagraph(para, 0, monospace.Shaper(11*dimen.PT, nil), nil, nil)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
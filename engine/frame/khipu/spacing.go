@@ -0,0 +1,218 @@
+package khipu
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/uax/grapheme"
+	"github.com/npillmayer/uax/segment"
+)
+
+// --- Letter-spacing and word-spacing ---------------------------------------
+
+// Spacing holds adjustments of inter-character and inter-word spacing, as set by
+// CSS properties letter-spacing and word-spacing.
+type Spacing struct {
+	LetterSpacing dimen.DU // space added between characters, may be negative
+	WordSpacing   dimen.DU // space added to inter-word glue, may be negative
+	FontSize      dimen.DU // if set, optical tracking for this size is added to LetterSpacing
+}
+
+// IsZero is true if spacing s will not change a khipu.
+func (s Spacing) IsZero() bool {
+	return s.tracking() == 0 && s.WordSpacing == 0
+}
+
+func (s Spacing) tracking() dimen.DU {
+	if s.FontSize > 0 {
+		return s.LetterSpacing + OpticalTracking(s.FontSize)
+	}
+	return s.LetterSpacing
+}
+
+// WithSpacing sets letter-spacing and word-spacing for EncodeText and
+// EncodeStyledParagraph. Spacing is applied before text boxes are measured.
+func WithSpacing(s Spacing) Option {
+	return func(kk *khipukamayuq) {
+		kk.spacing = s
+	}
+}
+
+// trackingTable lists tracking values in 1/1000 em for font sizes in points,
+// loosely following common practice for optical sizes: small text is set looser,
+// display text tighter.
+var trackingTable = []struct {
+	size     int
+	tracking int
+}{
+	{6, 40}, {9, 15}, {12, 0}, {18, -10}, {24, -15}, {36, -20}, {72, -25},
+}
+
+// OpticalTracking returns the tracking for text set at a given font size.
+// Values are interpolated linearly between entries of a table of common sizes.
+func OpticalTracking(size dimen.DU) dimen.DU {
+	pt := float64(size) / float64(dimen.PT)
+	first, last := trackingTable[0], trackingTable[len(trackingTable)-1]
+	var t float64
+	switch {
+	case pt <= float64(first.size):
+		t = float64(first.tracking)
+	case pt >= float64(last.size):
+		t = float64(last.tracking)
+	default:
+		for i := 1; i < len(trackingTable); i++ {
+			lo, hi := trackingTable[i-1], trackingTable[i]
+			if pt <= float64(hi.size) {
+				f := (pt - float64(lo.size)) / float64(hi.size-lo.size)
+				t = float64(lo.tracking) + f*float64(hi.tracking-lo.tracking)
+				break
+			}
+		}
+	}
+	return dimen.DU(float64(size) * t / 1000)
+}
+
+// AdjustSpacing transforms a khipu to implement letter-spacing and word-spacing.
+// It returns a new khipu, as knots will be inserted.
+//
+// Letter-spacing splits text boxes into grapheme clusters, separated by kerns.
+// Widths of measured boxes are apportioned to the clusters from the glyphs of the
+// box, if there is one glyph per cluster, or evenly otherwise. Clients needing
+// exact widths or glyph information have to re-shape the split boxes.
+// Letter-spacing is not applied to cursive scripts like Arabic, as it would break
+// the joining of letters.
+//
+// Word-spacing and letter-spacing are added to the natural width of glue,
+// leaving stretchability unchanged. Glue will not be set narrower than zero.
+// Infinite glue (e.g., parfillskip) is not changed.
+func AdjustSpacing(k *Khipu, s Spacing) *Khipu {
+	if k == nil || s.IsZero() {
+		return k
+	}
+	ls := s.tracking()
+	adjusted := NewKhipu()
+	adjusted.typ = k.typ
+	for i, knot := range k.knots {
+		switch knot.Type() {
+		case KTTextBox:
+			box, ok := knot.(*TextBox)
+			if !ok || ls == 0 || isCursive(box.text) {
+				adjusted.AppendKnot(knot)
+				break
+			}
+			splitTextBox(box, Kern(ls), adjusted)
+			if i+1 < len(k.knots) && k.knots[i+1].Type() == KTDiscretionary {
+				adjusted.AppendKnot(Kern(ls)) // letter-spacing between syllables
+			}
+		case KTGlue:
+			g := knot.(Glue)
+			if g.MaxW()-g.W() < dimen.Fil {
+				w := g[0] + ls + s.WordSpacing
+				if w < 0 {
+					w = 0
+				}
+				shrink := g[1]
+				if shrink > w {
+					shrink = w
+				}
+				g = NewGlue(w, shrink, g[2])
+			}
+			adjusted.AppendKnot(g)
+		default:
+			adjusted.AppendKnot(knot)
+		}
+	}
+	tracer().Debugf("spacing adjusted khipu from %d to %d knots", k.Length(), adjusted.Length())
+	return adjusted
+}
+
+// splitTextBox appends the grapheme clusters of box to k as separate text boxes,
+// separated by knot sep.
+func splitTextBox(box *TextBox, sep Knot, k *Khipu) {
+	clusters := graphemeClusters(box.text)
+	if len(clusters) < 2 {
+		k.AppendKnot(box)
+		return
+	}
+	widths := make([]dimen.DU, len(clusters))
+	if len(box.glyphs.Glyphs) == len(clusters) {
+		for i, g := range box.glyphs.Glyphs {
+			widths[i] = g.XAdvance
+		}
+	} else {
+		for i := range widths {
+			widths[i] = box.Width / dimen.DU(len(clusters))
+		}
+		widths[len(widths)-1] += box.Width % dimen.DU(len(clusters))
+	}
+	pos := box.Position
+	for i, cluster := range clusters {
+		if i > 0 {
+			k.AppendKnot(sep)
+		}
+		f := box.fragment(cluster, pos)
+		f.Width, f.Height, f.Depth = widths[i], box.Height, box.Depth
+		k.AppendKnot(f)
+		pos += uint64(len(cluster))
+	}
+}
+
+// graphemeClusters splits a string into user-perceived characters.
+func graphemeClusters(s string) []string {
+	grapheme.SetupGraphemeClasses()
+	splitter := segment.NewSegmenter(grapheme.NewBreaker(1))
+	splitter.Init(strings.NewReader(s))
+	var clusters []string
+	for splitter.Next() {
+		clusters = append(clusters, splitter.Text())
+	}
+	return clusters
+}
+
+// graphemeBoundaries finds the positions in s at which grapheme clusters start.
+// b[i] is true if a cluster starts at byte position i of s, or if i == len(s).
+func graphemeBoundaries(s string) []bool {
+	b := make([]bool, len(s)+1)
+	pos := 0
+	for _, cluster := range graphemeClusters(s) {
+		b[pos] = true
+		pos += len(cluster)
+	}
+	b[len(s)] = true
+	return b
+}
+
+// keepGraphemes merges syllables of word which would otherwise split a grapheme
+// cluster, e.g. a base character from its combining marks.
+func keepGraphemes(word string, syllables []string) []string {
+	if len(syllables) < 2 {
+		return syllables
+	}
+	b := graphemeBoundaries(word)
+	merged := []string{syllables[0]}
+	pos := len(syllables[0])
+	for _, sy := range syllables[1:] {
+		if pos < len(b) && b[pos] {
+			merged = append(merged, sy)
+		} else {
+			merged[len(merged)-1] += sy
+		}
+		pos += len(sy)
+	}
+	return merged
+}
+
+// isCursive is true if a text starts with a letter of a cursive script.
+func isCursive(s string) bool {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if unicode.IsLetter(r) {
+			return unicode.In(r, unicode.Arabic, unicode.Syriac, unicode.Mongolian,
+				unicode.Nko, unicode.Mandaic)
+		}
+		s = s[size:]
+	}
+	return false
+}
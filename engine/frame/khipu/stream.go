@@ -0,0 +1,215 @@
+package khipu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+)
+
+// --- Streaming khipukamayuq ------------------------------------------------
+
+// Segment is a part of a streamed document, holding the khipu for a single
+// paragraph.
+type Segment struct {
+	Khipu *Khipu // khipu of the paragraph, nil in case of an error
+	Para  int    // number of the paragraph within the document, counting from 0
+	Err   error  // error which occured while reading or encoding the paragraph
+}
+
+// maxParagraphLength is the maximum length of a paragraph in bytes, when streamed.
+const maxParagraphLength = 1 << 24
+
+// StreamParagraphs reads a document from r and encodes it paragraph by paragraph,
+// using EncodeText with the given options. Paragraphs are separated by blank
+// lines. Segments are sent to the returned channel, in order, as soon as they are
+// encoded. The channel is closed after the last paragraph or after the first error.
+//
+// Only a single paragraph is held in memory by the producer. Clients therefore may
+// stream very large documents, as long as they do not hold on to the segments
+// themselves. Clients have to either drain the channel or cancel ctx, otherwise the
+// producer will leak. After ctx is cancelled, no more segments are sent. Paragraphs
+// are encoded with ctx as the context for trace spans (see WithContext).
+//
+// If option WithStartPosition is given, it denotes the text position of the first
+// byte of r. Text positions of each paragraph will be offset by the byte position
+// of the paragraph within r.
+func StreamParagraphs(ctx context.Context, r io.Reader, opts ...Option) <-chan Segment {
+	kk := &khipukamayuq{}
+	for _, opt := range opts {
+		opt(kk)
+	}
+	segments := make(chan Segment)
+	send := func(segment Segment) bool {
+		select {
+		case segments <- segment:
+			return true
+		case <-ctx.Done():
+			tracer().Debugf("streaming of paragraphs cancelled: %v", ctx.Err())
+			return false
+		}
+	}
+	go func(startpos uint64) {
+		defer close(segments)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 4096), maxParagraphLength)
+		var offset, tokenStart uint64 // byte positions within r
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, start, token, err := scanParagraph(data, atEOF)
+			if token != nil {
+				tokenStart = offset + uint64(start)
+			}
+			offset += uint64(advance)
+			return advance, token, err
+		})
+		para := 0
+		for scanner.Scan() {
+			if ctx.Err() != nil {
+				return
+			}
+			popts := append(opts[:len(opts):len(opts)], WithContext(ctx),
+				WithStartPosition(startpos+tokenStart))
+			k, err := EncodeText(scanner.Text(), popts...)
+			if err != nil {
+				send(Segment{Para: para, Err: err})
+				return
+			}
+			tracer().Debugf("streamed paragraph #%d with %d knots", para, k.Length())
+			if !send(Segment{Khipu: k, Para: para}) {
+				return
+			}
+			para++
+		}
+		if err := scanner.Err(); err != nil {
+			send(Segment{Para: para, Err: err})
+		}
+	}(kk.startpos)
+	return segments
+}
+
+// StreamFragments is a variant of StreamParagraphs which consumes text fragments
+// from a channel. Fragments are concatenated, i.e., a paragraph may span several
+// fragments, and a fragment may contain several paragraphs. The returned channel
+// will be closed after in has been closed and the last paragraph has been encoded,
+// or after ctx has been cancelled.
+func StreamFragments(ctx context.Context, in <-chan string, opts ...Option) <-chan Segment {
+	pr, pw := io.Pipe()
+	stop := context.AfterFunc(ctx, func() { // unblock reader and writer of the pipe
+		pr.CloseWithError(ctx.Err())
+	})
+	go func() {
+		defer stop()
+		for {
+			select {
+			case fragment, ok := <-in:
+				if !ok {
+					pw.Close()
+					return
+				}
+				if _, err := io.WriteString(pw, fragment); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			}
+		}
+	}()
+	return StreamParagraphs(ctx, pr, opts...)
+}
+
+// scanParagraph splits text at blank lines, similar to a bufio.SplitFunc. In addition
+// to the values returned by a bufio.SplitFunc, it returns the start position of the
+// token within data.
+// Leading white space is skipped, and paragraphs consisting of white space only
+// are dropped.
+func scanParagraph(data []byte, atEOF bool) (advance int, start int, token []byte, err error) {
+	for start < len(data) && isLineSpace(data[start]) {
+		start++
+	}
+	for i := start; i < len(data); i++ {
+		if data[i] != '\n' {
+			continue
+		}
+		j := i + 1 // check if the next line is blank
+		for j < len(data) && isLineSpace(data[j]) && data[j] != '\n' {
+			j++
+		}
+		if j < len(data) && data[j] == '\n' {
+			if token := trimLineEnd(data[start:i]); len(bytes.TrimSpace(token)) > 0 {
+				return j + 1, start, token, nil
+			}
+			return j + 1, start, nil, nil
+		} else if j == len(data) && !atEOF {
+			break // need more data to decide
+		}
+	}
+	if atEOF && start < len(data) {
+		if token := trimLineEnd(data[start:]); len(bytes.TrimSpace(token)) > 0 {
+			return len(data), start, token, nil
+		}
+		return len(data), start, nil, nil
+	}
+	return start, start, nil, nil // request more data
+}
+
+func isLineSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+func trimLineEnd(line []byte) []byte {
+	return bytes.TrimRight(line, "\r\n")
+}
+
+// StreamCursor is a cursor over the segments of a streamed document. It behaves
+// like a Cursor for the khipu of the current paragraph and thus may be handed to
+// a line breaker. After the line breaker is done with a paragraph, NextSegment
+// moves on to the next one. Khipus of previous paragraphs are not referenced by a
+// StreamCursor.
+//
+// A new StreamCursor is positioned before the first paragraph, with an empty khipu.
+type StreamCursor struct {
+	*Cursor
+	segments <-chan Segment
+	segment  Segment
+}
+
+// NewStreamCursor creates a cursor for the segments of a streamed document, as
+// returned by StreamParagraphs or StreamFragments.
+func NewStreamCursor(segments <-chan Segment) *StreamCursor {
+	return &StreamCursor{
+		Cursor:   NewCursor(NewKhipu()),
+		segments: segments,
+		segment:  Segment{Para: -1},
+	}
+}
+
+// NextSegment moves the cursor to the start of the next paragraph. It returns
+// false if no paragraph is left or if an error occured (see Err).
+func (sc *StreamCursor) NextSegment() bool {
+	if sc.segment.Err != nil {
+		return false
+	}
+	segment, ok := <-sc.segments
+	if !ok {
+		sc.Cursor = NewCursor(NewKhipu())
+		return false
+	}
+	sc.segment = segment
+	if segment.Err != nil {
+		sc.Cursor = NewCursor(NewKhipu())
+		return false
+	}
+	sc.Cursor = NewCursor(segment.Khipu)
+	return true
+}
+
+// Segment returns the current segment.
+func (sc *StreamCursor) Segment() Segment {
+	return sc.segment
+}
+
+// Err returns the error which stopped the stream, if any.
+func (sc *StreamCursor) Err() error {
+	return sc.segment.Err
+}
@@ -0,0 +1,115 @@
+package khipu
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	params "github.com/npillmayer/tyse/core/parameters"
+)
+
+// --- Leaders ---------------------------------------------------------------
+
+// Leaders is glue which is filled with copies of a box, as with TeX's
+// \leaders\hbox{.}\hskip 0pt plus 1fil. Leaders are typically used for the dots
+// between entries of a table of contents and their page numbers.
+//
+// Leaders shrink and stretch like glue. When a line is set, the box is repeated
+// on a grid of multiples of its width, measured from the edge of the paragraph,
+// so that leaders of consecutive lines line up ("aligned leaders" in TeX).
+type Leaders struct {
+	Glue Glue     // space to be filled
+	Box  *TextBox // box to repeat, has to be measured
+}
+
+// NewLeaders creates leaders of glue g, filled with copies of box.
+func NewLeaders(box *TextBox, g Glue) Leaders {
+	return Leaders{Glue: g, Box: box}
+}
+
+// Type is part of interface Knot.
+func (l Leaders) Type() KnotType {
+	return KTLeaders
+}
+
+func (l Leaders) String() string {
+	return fmt.Sprintf("…%s", l.Glue)
+}
+
+// W is part of interface Knot. Natural width of the glue.
+func (l Leaders) W() dimen.DU {
+	return l.Glue.W()
+}
+
+// MinW is part of interface Knot. Minimum width of the glue.
+func (l Leaders) MinW() dimen.DU {
+	return l.Glue.MinW()
+}
+
+// MaxW is part of interface Knot. Maximum width of the glue.
+func (l Leaders) MaxW() dimen.DU {
+	return l.Glue.MaxW()
+}
+
+// IsDiscardable is part of interface Knot. Leaders are discardable, as glue is.
+func (l Leaders) IsDiscardable() bool {
+	return true
+}
+
+// --- Tabs ------------------------------------------------------------------
+
+// A Tab is a knot for a tab character of preserved white space (see P_WHITESPACE).
+// Tabs have to be resolved against a list of tab stops, when their horizontal
+// position within a line is known, i.e. after line breaking (see package inline).
+// Until then, a tab has the width of tabSize spaces, which the line breaker will
+// use.
+//
+// Resolving a tab sets its width and, optionally, a box for leaders to fill the
+// tab with. Contrary to glue and kerns, tabs are not discardable, as indentation
+// by tabs has to survive line breaks.
+type Tab struct {
+	Width    dimen.DU // width, until resolved the width of tabSize spaces
+	Leader   *TextBox // box to fill the tab with, as with Leaders, or nil
+	Position uint64   // position of the tab character in the text
+}
+
+// NewTab creates a tab for a tab character at text position textpos, with a
+// provisional width of w.
+func NewTab(w dimen.DU, textpos uint64) Tab {
+	return Tab{Width: w, Position: textpos}
+}
+
+// Type is part of interface Knot.
+func (t Tab) Type() KnotType {
+	return KTTab
+}
+
+func (t Tab) String() string {
+	return fmt.Sprintf("⇥%.2f", t.Width.Points())
+}
+
+// W is part of interface Knot. Width of the tab.
+func (t Tab) W() dimen.DU {
+	return t.Width
+}
+
+// MinW is part of interface Knot. Tabs do not shrink.
+func (t Tab) MinW() dimen.DU {
+	return t.Width
+}
+
+// MaxW is part of interface Knot. Tabs do not stretch.
+func (t Tab) MaxW() dimen.DU {
+	return t.Width
+}
+
+// IsDiscardable is part of interface Knot. Tabs are not discardable.
+func (t Tab) IsDiscardable() bool {
+	return false
+}
+
+// SpaceWidth returns the natural width of a space, as encoded by the
+// khipukamayuq. Tab stops at multiples of a number of spaces (CSS tab-size) may
+// be calculated from it.
+func SpaceWidth(regs *params.TypesettingRegisters) dimen.DU {
+	return spaceglue(regs).W()
+}
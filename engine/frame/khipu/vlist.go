@@ -0,0 +1,59 @@
+package khipu
+
+import (
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+)
+
+// --- Vertical lists --------------------------------------------------------
+
+// A VBox is a box of a vertical list, e.g. a line of a paragraph or a block
+// which is not to be broken. Vertical lists are measured along the block axis,
+// i.e. the width of a vertical box is its height.
+type VBox struct {
+	Height dimen.DU // height of the line or block
+	Ref    int      // reference to the line or block, for clients
+}
+
+// NewVBox creates a vertical box of height h, referring to client item ref.
+func NewVBox(h dimen.DU, ref int) VBox {
+	return VBox{Height: h, Ref: ref}
+}
+
+// Type is part of interface Knot.
+func (b VBox) Type() KnotType {
+	return KTVBox
+}
+
+func (b VBox) String() string {
+	return fmt.Sprintf("▯%.2f", b.Height.Points())
+}
+
+// W is part of interface Knot. The width of a vertical box is its height.
+func (b VBox) W() dimen.DU {
+	return b.Height
+}
+
+// MinW is part of interface Knot. Vertical boxes do not shrink.
+func (b VBox) MinW() dimen.DU {
+	return b.Height
+}
+
+// MaxW is part of interface Knot. Vertical boxes do not stretch.
+func (b VBox) MaxW() dimen.DU {
+	return b.Height
+}
+
+// IsDiscardable is part of interface Knot. Vertical boxes are not discardable.
+func (b VBox) IsDiscardable() bool {
+	return false
+}
+
+// NewVList creates a new vertical list, e.g. to break into pages. Vertical lists
+// consist of vertical boxes, glue between them and penalties for page breaks.
+func NewVList() *Khipu {
+	kh := NewKhipu()
+	kh.typ = VList
+	return kh
+}
@@ -0,0 +1,237 @@
+package khipu
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	params "github.com/npillmayer/tyse/core/parameters"
+)
+
+// --- White space -----------------------------------------------------------
+
+// White space is handled according to CSS property white-space, set with register
+// P_WHITESPACE:
+//
+//   - "normal" collapses sequences of spaces and newlines into a single space
+//   - "nowrap" collapses white space like "normal", but suppresses line breaks
+//   - "pre" preserves spaces and newlines, and suppresses line breaks
+//   - "pre-wrap" preserves spaces and newlines, and allows breaks after spaces
+//   - "pre-line" collapses sequences of spaces, but preserves newlines
+//
+// Collapsible spaces are encoded as inter-word glue. Preserved spaces are encoded
+// as glue of fixed width, which will not stretch or shrink when lines are set.
+// Preserved tabs are encoded as tabs (see Tab), to be aligned at tab stops.
+// Preserved newlines are encoded as forced breaks, as TeX's \\ does:
+// \unskip\penalty10000\hfil\penalty-10000.
+//
+// Unicode has a number of space characters besides U+0020, which are classified
+// as follows:
+//
+//   - no-break spaces (U+00A0) are encoded as inter-word glue, but never collapse
+//     with adjacent spaces; UAX#14 prohibits line breaks after them
+//   - spaces of a given width, e.g. thin spaces (U+2009) or em spaces (U+2003),
+//     are encoded as glue of fixed width; narrow no-break spaces (U+202F) and
+//     figure spaces (U+2007) prohibit line breaks as well
+//
+// Khipus do not know the font size of their text. Widths of spaces relative to
+// an em are taken from the inter-word space, which is a third of an em (as it is
+// for TeX's Computer Modern fonts).
+
+// whiteSpace holds the rules for encoding white space.
+type whiteSpace struct {
+	collapse bool // collapse sequences of spaces into a single space
+	newlines bool // newlines force a line break
+	wrap     bool // lines may be broken after spaces
+}
+
+// tabSize is the number of spaces of the provisional width of a tab. This is the
+// default of CSS property tab-size.
+const tabSize = 8
+
+// whiteSpaceRules returns the rules for encoding white space for a value of CSS
+// property white-space. Illegal values result in the rules for "normal".
+func whiteSpaceRules(mode string) whiteSpace {
+	switch strings.ToLower(mode) {
+	case "nowrap":
+		return whiteSpace{collapse: true}
+	case "pre":
+		return whiteSpace{newlines: true}
+	case "pre-wrap", "break-spaces":
+		return whiteSpace{newlines: true, wrap: true}
+	case "pre-line":
+		return whiteSpace{collapse: true, newlines: true, wrap: true}
+	}
+	return whiteSpace{collapse: true, wrap: true}
+}
+
+// encode appends a fragment of white space, starting at text position textpos, to
+// k. p1 is the UAX#14 penalty for a break after the fragment.
+//
+// Non-collapsible spaces, e.g. no-break spaces, keep a fragment from collapsing
+// with preceding spaces. Preserved spaces at the start of a line are preceded by
+// an empty text box, as otherwise they would be discarded by the line breaker.
+func (ws whiteSpace) encode(fragment string, textpos uint64, p1 int, k *Khipu,
+	regs *params.TypesettingRegisters) {
+	//
+	penalty := capPenalty(p1)
+	if !ws.wrap {
+		penalty = Penalty(dimen.Infinity)
+	} else if penalty <= -10000 { // mandatory break after a newline, which is collapsed
+		penalty = 0
+	}
+	var run spaceRun // pending spaces
+	start := textpos // text position of the first pending space
+	tab := false     // a tab has been appended to k
+	for i, r := range fragment {
+		if isNewline(r) && ws.newlines {
+			if r == '\n' && i > 0 && fragment[i-1] == '\r' { // CR LF is a single newline
+				continue
+			}
+			run, tab = spaceRun{}, false // spaces before a newline would hang at the end of the line
+			forceBreak(k, textpos+uint64(i))
+			continue
+		}
+		if r == '\t' && !ws.collapse { // tabs will be resolved against tab stops
+			ws.space(k, run, start, regs)
+			k.AppendKnot(NewTab(tabSize*spaceglue(regs).W(), textpos+uint64(i)))
+			run, tab = spaceRun{}, true
+			continue
+		}
+		if run.empty() {
+			start = textpos + uint64(i)
+		}
+		run.add(r)
+	}
+	if run.empty() && !tab {
+		return
+	}
+	if ws.collapse && !run.nonCollapsible() { // collapse with a preceding space or forced break
+		if l := len(k.knots); l >= 2 && k.knots[l-2].Type() == KTGlue && k.knots[l-1].Type() == KTPenalty {
+			if penalty < k.knots[l-1].(Penalty) {
+				k.knots[l-1] = penalty
+			}
+			return
+		}
+	}
+	ws.space(k, run, start, regs)
+	k.AppendKnot(penalty)
+}
+
+// space appends glue for a run of spaces, starting at text position start, to k.
+// Collapsible spaces are encoded as a single inter-word glue, preserved spaces as
+// glue of fixed width. Spaces of a given width add to the width of the glue.
+func (ws whiteSpace) space(k *Khipu, run spaceRun, start uint64, regs *params.TypesettingRegisters) {
+	if run.empty() {
+		return
+	}
+	word := spaceglue(regs)
+	n := run.collapsible + run.variable // number of inter-word spaces
+	if ws.collapse && run.collapsible > 0 {
+		n = run.variable + 1
+	}
+	w := dimen.DU(n)*word.W() + dimen.FromFloat(run.width*spacesPerEm*float64(word.W()))
+	if !ws.collapse {
+		if atLineStart(k) {
+			k.AppendKnot(NewTextBox("", start))
+		}
+		k.AppendKnot(NewGlue(w, 0, 0))
+		return
+	}
+	k.AppendKnot(NewGlue(w, dimen.DU(n)*(word.W()-word.MinW()), dimen.DU(n)*(word.MaxW()-word.W())))
+}
+
+// spacesPerEm is the number of inter-word spaces making up an em.
+const spacesPerEm = 3
+
+// spaceRun is a run of white space characters, to be encoded as a single glue.
+type spaceRun struct {
+	collapsible int     // number of collapsible spaces, e.g. U+0020
+	variable    int     // number of non-collapsible inter-word spaces, e.g. no-break spaces
+	width       float64 // total width of spaces of a given width, in ems
+}
+
+// add classifies space character r and adds it to the run.
+func (run *spaceRun) add(r rune) {
+	if w, ok := spaceWidth(r); ok {
+		run.width += w
+	} else if r == ' ' || r == '\t' || isNewline(r) {
+		run.collapsible++
+	} else {
+		run.variable++
+	}
+}
+
+func (run spaceRun) empty() bool {
+	return run == spaceRun{}
+}
+
+// nonCollapsible is true if the run contains spaces other than collapsible ones.
+func (run spaceRun) nonCollapsible() bool {
+	return run.variable > 0 || run.width > 0
+}
+
+// spaceWidth returns the width of space character r in ems, for spaces of a
+// given width. For other spaces, e.g. U+0020 or no-break spaces, the second
+// return value is false. Widths follow the Unicode standard, section 6.2.
+func spaceWidth(r rune) (float64, bool) {
+	switch r {
+	case '\u2000', '\u2002': // en quad, en space
+		return 1.0 / 2, true
+	case '\u2001', '\u2003', '\u3000': // em quad, em space, ideographic space
+		return 1, true
+	case '\u2004': // three-per-em space
+		return 1.0 / 3, true
+	case '\u2005': // four-per-em space
+		return 1.0 / 4, true
+	case '\u2006': // six-per-em space
+		return 1.0 / 6, true
+	case '\u2007': // figure space, i.e. the width of a digit
+		return 1.0 / 2, true
+	case '\u2008': // punctuation space, i.e. the width of a period
+		return 1.0 / 4, true
+	case '\u2009', '\u202F': // thin space, narrow no-break space
+		return 1.0 / 5, true
+	case '\u200A': // hair space
+		return 1.0 / 10, true
+	case '\u205F': // medium mathematical space
+		return 4.0 / 18, true
+	}
+	return 0, false
+}
+
+// forceBreak appends a forced line break at text position textpos to k. Trailing
+// glue and penalties are removed, unless they end a line themselves. An empty line
+// gets an empty text box, as otherwise the line would vanish.
+func forceBreak(k *Khipu, textpos uint64) {
+	n := len(k.knots)
+	for n > 0 && (k.knots[n-1].Type() == KTGlue || k.knots[n-1].Type() == KTPenalty) {
+		if p, ok := k.knots[n-1].(Penalty); ok && p <= -10000 {
+			break
+		}
+		n--
+	}
+	k.knots = k.knots[:n]
+	if atLineStart(k) {
+		k.AppendKnot(NewTextBox("", textpos))
+	}
+	k.AppendKnot(Penalty(dimen.Infinity)).AppendKnot(NewFill(1)).AppendKnot(Penalty(-10000))
+}
+
+// atLineStart is true if k is empty or ends with a forced break.
+func atLineStart(k *Khipu) bool {
+	n := len(k.knots)
+	if n == 0 {
+		return true
+	}
+	p, ok := k.knots[n-1].(Penalty)
+	return ok && p <= -10000
+}
+
+// isNewline is true for characters which end a line of text.
+func isNewline(r rune) bool {
+	switch r {
+	case '\n', '\r', '\f', '\v', '\u0085', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
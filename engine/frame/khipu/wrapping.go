@@ -0,0 +1,74 @@
+package khipu
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+)
+
+// --- Line break opportunities ----------------------------------------------
+
+// Wrapping holds adjustments of the line break opportunities of a khipu, as set by
+// CSS properties white-space, word-break and hyphens.
+type Wrapping struct {
+	NoWrap    bool // suppress optional breaks, for white-space "nowrap" and "pre"
+	BreakAll  bool // allow breaks between the letters of words, for word-break "break-all"
+	NoHyphens bool // drop hyphenation opportunities, for hyphens "none"
+}
+
+// IsZero is true if wrapping w will not change a khipu.
+func (w Wrapping) IsZero() bool {
+	return !w.NoWrap && !w.BreakAll && !w.NoHyphens
+}
+
+// WithWrapping sets adjustments of line break opportunities for EncodeText and
+// EncodeStyledParagraph.
+func WithWrapping(w Wrapping) Option {
+	return func(kk *khipukamayuq) {
+		kk.wrapping = w
+	}
+}
+
+// AdjustWrapping transforms a khipu to add or suppress line break opportunities.
+// It returns a new khipu, as knots may be inserted or dropped.
+//
+// With NoWrap set, every penalty allowing a break is set to infinity, and
+// discretionaries are dropped. Forced breaks (penalties of -10000 and below) are
+// kept. BreakAll splits text boxes into grapheme clusters, separated by penalties
+// of zero. Widths of measured boxes are apportioned as for letter-spacing (see
+// AdjustSpacing). As with letter-spacing, cursive scripts are not split.
+// NoHyphens drops discretionaries, including soft hyphens.
+//
+// Wrapping has to be adjusted after spacing: for letter-spaced text, BreakAll
+// allows breaks after the kerns between clusters.
+func AdjustWrapping(k *Khipu, w Wrapping) *Khipu {
+	if k == nil || w.IsZero() {
+		return k
+	}
+	adjusted := NewKhipu()
+	adjusted.typ = k.typ
+	for i, knot := range k.knots {
+		switch knot.Type() {
+		case KTPenalty:
+			if p := knot.(Penalty); w.NoWrap && p > -10000 {
+				knot = Penalty(dimen.Infinity)
+			}
+		case KTDiscretionary:
+			if w.NoWrap || w.NoHyphens {
+				continue
+			}
+		case KTTextBox:
+			if box, ok := knot.(*TextBox); ok && w.BreakAll && !w.NoWrap && !isCursive(box.text) {
+				splitTextBox(box, Penalty(0), adjusted)
+				continue
+			}
+		case KTKern:
+			if w.BreakAll && !w.NoWrap && i > 0 && i+1 < len(k.knots) &&
+				k.knots[i-1].Type() == KTTextBox && k.knots[i+1].Type() == KTTextBox {
+				adjusted.AppendKnot(knot).AppendKnot(Penalty(0))
+				continue
+			}
+		}
+		adjusted.AppendKnot(knot)
+	}
+	tracer().Debugf("wrapping adjusted khipu from %d to %d knots", k.Length(), adjusted.Length())
+	return adjusted
+}
@@ -10,8 +10,10 @@ import (
 )
 
 const (
-	TypeBlockFormattingContext  frame.FormattingContextType = 100
-	TypeInlineFormattingContext frame.FormattingContextType = 101
+	TypeBlockFormattingContext    frame.FormattingContextType = 100
+	TypeInlineFormattingContext   frame.FormattingContextType = 101
+	TypeFlexFormattingContext     frame.FormattingContextType = 102
+	TypeMulticolFormattingContext frame.FormattingContextType = 103
 )
 
 // --- Block Formatting Context ----------------------------------------------
@@ -64,10 +66,6 @@ func (ctx *BlockContext) AddContained(c *frame.Container) {
 		tracer().Debugf("block context added [%v] wrapped in anon box", c.DOMNode().NodeName())
 		return
 	}
-	// if c.DOMNode().ComputedStyles().GetPropertyValue("float") != style.NullStyle {
-	// 	T().P("context", "block").Errorf("float box cannot be added")
-	// 	panic("illegal argument for BlockContext.AddBox(c)")
-	// }
 	// if c.DOMNode().ComputedStyles().GetPropertyValue("position") == "absolute" ||
 	// 	c.DOMNode().ComputedStyles().GetPropertyValue("position") == "fixed" {
 	// 	//
@@ -82,18 +80,39 @@ func (ctx *BlockContext) AddContained(c *frame.Container) {
 	ctx.AddChild(c.TreeNode())
 }
 
+// Layout positions the contained boxes of ctx one below the other. Floated
+// boxes are taken out of the flow and placed at the side; boxes with a
+// clear-property are moved below the floats they clear.
+// If ctx is a flow root, its height is extended to contain all its floats.
+//
+// TODO Float positions are relative to the flow root, whereas the positions of
+// in-flow boxes are relative to their context. For contexts nested within a flow
+// root this is not yet reconciled.
 func (ctx *BlockContext) Layout(flowRoot *frame.FlowRoot) error {
+	flowRoot = flowRootFor(ctx, flowRoot)
 	H := dimen.Zero
 	for _, c := range ctx.Contained() {
 		tracer().Debugf("[%s] positions box [%s]", boxtree.ContainerName(ctx.Container()),
 			boxtree.ContainerName(c))
+		float, clear := floatAndClear(c)
+		if float.IsFloating() {
+			if err := placeFloat(c, float, clear, flowRoot, H); err != nil {
+				return err
+			}
+			continue
+		}
+		H = flowRoot.Placer.Clearance(clear, H)
 		c.CSSBox().TopL.Y = H
-		if !c.CSSBox().H.IsAbsolute() {
+		var h dimen.DU
+		if c.CSSBox().H.Match().Just(&h) == nil {
 			return ErrHeightNotFixed
 		}
-		H += c.CSSBox().H.Unwrap()
+		H += h
+	}
+	if ctx.IsFlowRoot() {
+		H = dimen.Max(H, flowRoot.Placer.Bottom())
 	}
-	ctx.Container().CSSBox().H = css.SomeDimen(H)
+	ctx.Container().CSSBox().H = css.JustDimen(H)
 	return nil
 }
 
@@ -103,25 +122,30 @@ func (ctx *BlockContext) Measure() (frame.Size, css.DimenT, css.DimenT) {
 	for i, ch := range children {
 		chw, chh := ch.CSSBox().TotalWidth(), ch.CSSBox().TotalHeight()
 		if !chw.IsAbsolute() || !chh.IsAbsolute() {
-			return frame.Size{}, css.Dimen(), css.Dimen()
+			return frame.Size{}, css.DimenT{}, css.DimenT{}
 		}
-		if w := ch.CSSBox().W.Unwrap(); w > wmax {
+		var w, chH, mTop dimen.DU
+		ch.CSSBox().W.Match().Just(&w)
+		ch.CSSBox().H.Match().Just(&chH)
+		ch.CSSBox().Margins[frame.Top].Match().Just(&mTop)
+		if w > wmax {
 			wmax = w
 		}
-		h += ch.CSSBox().H.Unwrap()
+		h += chH
 		if i == 0 && !ctx.IsFlowRoot() {
-			h -= ch.CSSBox().Margins[frame.Top].Unwrap()
+			h -= mTop
 		} else if i > 0 {
-			minMargin := dimen.Min(margin, ch.CSSBox().Margins[frame.Top].Unwrap())
+			minMargin := dimen.Min(margin, mTop)
 			h -= minMargin
 		}
-		margin = ch.CSSBox().Margins[frame.Bottom].Unwrap()
+		margin = dimen.Zero
+		ch.CSSBox().Margins[frame.Bottom].Match().Just(&margin)
 		if i == len(children) && !ctx.IsFlowRoot() {
 			h -= margin
 		}
 	}
-	//return css.SomeDimen(wmax), css.SomeDimen(h)
-	return ctx.Container().CSSBox().Size, css.SomeDimen(0), css.SomeDimen(0)
+	//return css.JustDimen(wmax), css.JustDimen(h)
+	return ctx.Container().CSSBox().Size, css.JustDimen(0), css.JustDimen(0)
 }
 
 var _ frame.ContextInterf = &BlockContext{}
@@ -152,6 +176,11 @@ func (ctx *InlineContext) Type() frame.FormattingContextType {
 	return TypeInlineFormattingContext
 }
 
+// Lines returns the line boxes of the paragraph, once it has been laid out.
+func (ctx *InlineContext) Lines() []*frame.Container {
+	return ctx.lines
+}
+
 func (ctx *InlineContext) AddContained(c *frame.Container) {
 	if c.Display.Outer() == css.BlockMode {
 		ctx.AddChild(c.TreeNode())
@@ -180,27 +209,28 @@ func (ctx *InlineContext) addLines(lines ...*frame.Container) {
 	//size, _, mBot := ctx.Measure()
 	size := ctx.Container().CSSBox().Size
 	h, margin := dimen.Zero, dimen.Zero
-	if !size.H.IsNone() {
-		h = size.H.Unwrap()
+	if size.H.Match().Just(&h) != nil {
 		if lastbox := lastbox(ctx); lastbox != nil {
-			margin = lastbox.Margins[frame.Bottom].Unwrap()
+			lastbox.Margins[frame.Bottom].Match().Just(&margin)
 		}
 	}
 	for i, line := range lines {
-		lh := line.CSSBox().TotalHeight()
-		if !lh.IsAbsolute() {
+		var lh, mTop dimen.DU
+		if line.CSSBox().TotalHeight().Match().Just(&lh) == nil {
 			panic("line box must have fixed height")
 		}
-		h += lh.Unwrap()
+		h += lh
+		line.CSSBox().Margins[frame.Top].Match().Just(&mTop)
 		if boxcnt == 0 && !ctx.IsFlowRoot() {
-			h -= line.CSSBox().Margins[frame.Top].Unwrap()
+			h -= mTop
 		} else if boxcnt > 0 {
-			minMargin := dimen.Min(margin, line.CSSBox().Margins[frame.Top].Unwrap())
+			minMargin := dimen.Min(margin, mTop)
 			h -= minMargin
 		}
 		line.CSSBox().TopL.Y = h
 		ctx.lines = append(ctx.lines, line)
-		margin = line.CSSBox().Margins[frame.Bottom].Unwrap()
+		margin = dimen.Zero
+		line.CSSBox().Margins[frame.Bottom].Match().Just(&margin)
 		if i == len(ctx.lines) && !ctx.IsFlowRoot() {
 			h -= margin
 		}
@@ -215,15 +245,25 @@ func (ctx *InlineContext) Layout(flowRoot *frame.FlowRoot) error {
 	} else if len(blocks) > 0 {
 		tracer().Debugf("layout of inline container: %d enclosed blocks", len(blocks))
 	}
+	flowRoot = flowRootFor(ctx, flowRoot)
 	box := ctx.Container().CSSBox()
-	lines, err := inline.BreakParagraph(para, box)
+	for _, b := range blocks { // floats are placed at the top of the paragraph
+		if float, clear := floatAndClear(b); float.IsFloating() {
+			if err = placeFloat(b, float, clear, flowRoot, box.TopL.Y); err != nil {
+				return err
+			}
+		}
+	}
+	lines, err := inline.BreakParagraph(para, box, flowRoot.Placer)
 	if err != nil {
 		return err
 	}
 	tracer().Debugf("paragraph broken into %d lines", len(lines))
 	if len(lines) > 0 {
 		last := lines[len(lines)-1]
-		ctx.Container().CSSBox().H = css.SomeDimen(last.CSSBox().TopL.Y + last.CSSBox().H.Unwrap())
+		var lastH dimen.DU
+		last.CSSBox().H.Match().Just(&lastH)
+		ctx.Container().CSSBox().H = css.JustDimen(last.CSSBox().TopL.Y + lastH)
 		ctx.addLines(lines...)
 	}
 	return nil
@@ -234,7 +274,7 @@ func (ctx *InlineContext) Measure() (frame.Size, css.DimenT, css.DimenT) {
 	// for i, line := range ctx.lines {
 	// 	lh := line.CSSBox().TotalHeight()
 	// 	if !lh.IsAbsolute() {
-	// 		return frame.Size{}, css.Dimen(), css.Dimen()
+	// 		return frame.Size{}, css.DimenT{}, css.DimenT{}
 	// 	}
 	// 	//h += line.CSSBox().H.Unwrap()
 	// 	h += lh.Unwrap()
@@ -250,7 +290,252 @@ func (ctx *InlineContext) Measure() (frame.Size, css.DimenT, css.DimenT) {
 	// 	}
 	// }
 	//
-	return ctx.Container().CSSBox().OuterBox().Size, css.SomeDimen(0), css.SomeDimen(0)
+	return ctx.Container().CSSBox().OuterBox().Size, css.JustDimen(0), css.JustDimen(0)
+}
+
+// --- Flex Context ----------------------------------------------------------
+
+// FlexContext establishes a CSS flex formatting context.
+//
+// “A flex container establishes a new flex formatting context for its contents.
+// This is the same as establishing a block formatting context, except that flex
+// layout is used instead of block layout.”
+//
+// Every in-flow child of a flex container becomes a flex item.
+type FlexContext struct {
+	frame.ContextBase
+}
+
+func NewFlexContext(c *frame.Container, isRoot bool) *FlexContext {
+	ctx := &FlexContext{}
+	ctx.IsRootCtx = isRoot
+	ctx.C = c
+	ctx.Payload = ctx
+	return ctx
+}
+
+func Flex(ctx frame.ContextInterf) *FlexContext {
+	if flex, ok := ctx.(*FlexContext); ok {
+		return flex
+	}
+	panic("context is not a flex context")
+}
+
+func (ctx *FlexContext) Type() frame.FormattingContextType {
+	return TypeFlexFormattingContext
+}
+
+// AddContained adds a flex item. Inline-level children are wrapped into
+// anonymous block boxes, as text runs of a flex container are.
+func (ctx *FlexContext) AddContained(c *frame.Container) {
+	if c.Display.Outer() == css.InlineMode {
+		anon := boxtree.NewAnonymousBox(css.BlockMode | css.InnerInlineMode)
+		c.TreeNode().Isolate()
+		anon.AddChild(c.TreeNode())
+		ctx.AddChild(anon.TreeNode())
+		tracer().Debugf("flex context added [%v] wrapped in anon box", c.DOMNode().NodeName())
+		return
+	}
+	c.TreeNode().Isolate()
+	if ctx.C.TreeNode().IndexOfChild(c.TreeNode()) >= 0 {
+		panic("container is child container; cannot have 2 parents")
+	}
+	tracer().Debugf("flex context added [%v]", c.DOMNode().NodeName())
+	ctx.AddChild(c.TreeNode())
+}
+
+// Layout resolves the sizes of the flex items and positions them. Flex items
+// have already been laid out with the width of the container; items of a row
+// are laid out again with their resolved widths.
+func (ctx *FlexContext) Layout(flowRoot *frame.FlowRoot) error {
+	flowRoot = flowRootFor(ctx, flowRoot)
+	box := ctx.Container().CSSBox()
+	p := flexParamsOf(ctx.Container())
+	items := make([]*flexItem, 0, len(ctx.Contained()))
+	var mainsize, sum dimen.DU
+	for _, c := range ctx.Contained() {
+		item := flexItemFor(c, p.direction)
+		if !p.direction.IsColumn() {
+			c := c
+			item.layout = func(main dimen.DU) (dimen.DU, error) {
+				return relayoutFlexItem(c, main, flowRoot)
+			}
+		}
+		items = append(items, item)
+		sum += item.basis
+	}
+	if p.direction.IsColumn() {
+		if box.H.Match().Just(&mainsize) == nil {
+			mainsize = sum // height is auto: no free space to distribute
+		}
+	} else if box.W.Match().Just(&mainsize) == nil {
+		return ErrEnclosingWidthNotFixed
+	}
+	crosssize, err := layoutFlexItems(items, p, mainsize)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		b := item.c.CSSBox()
+		if p.direction.IsColumn() {
+			b.TopL = dimen.Point{X: item.pos.Y, Y: item.pos.X}
+			b.W, b.H = css.JustDimen(item.cross), css.JustDimen(item.main)
+		} else {
+			b.TopL = item.pos
+			b.W, b.H = css.JustDimen(item.main), css.JustDimen(item.cross)
+		}
+	}
+	if p.direction.IsColumn() {
+		box.H = css.JustDimen(mainsize)
+	} else {
+		box.H = css.JustDimen(crosssize)
+	}
+	tracer().Debugf("flex container [%s] laid out %d items", boxtree.ContainerName(ctx.Container()), len(items))
+	return nil
+}
+
+func (ctx *FlexContext) Measure() (frame.Size, css.DimenT, css.DimenT) {
+	return ctx.Container().CSSBox().Size, css.JustDimen(0), css.JustDimen(0)
+}
+
+var _ frame.ContextInterf = &FlexContext{}
+
+// flexParamsOf reads the flex container properties of c.
+func flexParamsOf(c *frame.Container) flexParams {
+	p := flexParams{align: css.AlignStretch}
+	if c.DOMNode() == nil {
+		return p
+	}
+	styles := c.DOMNode().ComputedStyles()
+	p.direction = css.FlexDirection(styles.GetPropertyValue("flex-direction"))
+	p.wrap = css.FlexWrapping(styles.GetPropertyValue("flex-wrap"))
+	p.justify = css.JustifyContent(styles.GetPropertyValue("justify-content"))
+	if a := css.AlignItems(styles.GetPropertyValue("align-items")); a != css.AlignAuto {
+		p.align = a
+	}
+	return p
+}
+
+// flexItemFor creates a flex item for container c, which has already been laid
+// out. If flex-basis is `auto`, the size of c along the main axis is used, which
+// for a row item without a width is its max-content width.
+func flexItemFor(c *frame.Container, dir css.FlexDirectionT) *flexItem {
+	item := &flexItem{c: c, shrink: 1, stretch: true}
+	box := c.CSSBox()
+	mainsize, crosssize, crosskey := box.W, box.H, "height"
+	if dir.IsColumn() {
+		mainsize, crosssize, crosskey = box.H, box.W, "width"
+	}
+	if !dir.IsColumn() && hasAutoWidth(c) {
+		item.basis = maxContentWidth(c)
+	} else {
+		mainsize.Match().Just(&item.basis)
+	}
+	crosssize.Match().Just(&item.cross)
+	if c.DOMNode() == nil {
+		return item
+	}
+	styles := c.DOMNode().ComputedStyles()
+	item.grow = css.FlexFactor(styles.GetPropertyValue("flex-grow"), 0)
+	item.shrink = css.FlexFactor(styles.GetPropertyValue("flex-shrink"), 1)
+	item.align = css.AlignItems(styles.GetPropertyValue("align-self"))
+	css.DimenOption(styles.GetPropertyValue("flex-basis")).Match().Just(&item.basis)
+	cross := styles.GetPropertyValue(crosskey)
+	item.stretch = cross == style.NullStyle || cross == "auto"
+	return item
+}
+
+// relayoutFlexItem lays out flex item c again with a width of w and returns its
+// resulting height. Descendants of c without a width of their own are laid out
+// again as well.
+func relayoutFlexItem(c *frame.Container, w dimen.DU, flowRoot *frame.FlowRoot) (dimen.DU, error) {
+	box := c.CSSBox()
+	var h, current dimen.DU
+	if box.W.Match().Just(&current) != nil && current == w {
+		box.H.Match().Just(&h)
+		return h, nil // no need to lay out c again
+	}
+	box.W = css.JustDimen(w)
+	if err := layoutContents(c, flowRoot); err != nil {
+		return 0, err
+	}
+	box.H.Match().Just(&h)
+	return h, nil
+}
+
+// layoutContents lays out the contents of container c again, for the width c
+// currently has. Descendants of c without a width of their own are laid out
+// again as well.
+func layoutContents(c *frame.Container, flowRoot *frame.FlowRoot) error {
+	if c.Context == nil {
+		return nil
+	}
+	inherited := inheritedParams{flowRoot: flowRoot, W: enclosingWidthFor(c)}
+	inherited.W.Match().Just(&inherited.MaxW)
+	resetWidths(c)
+	for _, sub := range c.Context.Contained() {
+		if boxtree.IsText(sub.RenderNode()) {
+			continue
+		}
+		if s := CalcBlockWidths(sub, inherited); s.lastErr != nil {
+			return s.lastErr
+		}
+	}
+	return c.Context.Layout(flowRoot)
+}
+
+// resetWidths prepares the descendants of c for being laid out again: widths not
+// set by CSS are reset to `auto` and lines of paragraphs are dropped.
+func resetWidths(c *frame.Container) {
+	if c.Context == nil {
+		return
+	}
+	if inl, ok := c.Context.(*InlineContext); ok {
+		inl.lines = nil
+	}
+	for _, sub := range c.Context.Contained() {
+		if boxtree.IsText(sub.RenderNode()) {
+			continue
+		}
+		if hasAutoWidth(sub) {
+			sub.CSSBox().W = css.Auto()
+		}
+		resetWidths(sub)
+	}
+}
+
+// maxContentWidth returns the max-content width of container c, i.e. the width
+// c would take if none of its paragraphs were broken into lines.
+func maxContentWidth(c *frame.Container) dimen.DU {
+	var w dimen.DU
+	if !hasAutoWidth(c) && c.CSSBox().W.Match().Just(&w) != nil {
+		return w
+	}
+	switch ctx := c.Context.(type) {
+	case nil:
+		c.CSSBox().W.Match().Just(&w)
+	case *InlineContext:
+		para, _, err := inline.EncodeTextOfParagraph(ctx.Container())
+		if err == nil && para.Khipu != nil {
+			w, _, _ = para.Khipu.Measure(0, para.Khipu.Length())
+		}
+	default:
+		for _, sub := range ctx.Contained() {
+			var deco dimen.DU
+			sub.CSSBox().DecorationWidth(true).Match().Just(&deco)
+			w = dimen.Max(w, maxContentWidth(sub)+deco)
+		}
+	}
+	return w
+}
+
+// hasAutoWidth returns true if the width of c is not set by CSS.
+func hasAutoWidth(c *frame.Container) bool {
+	if c.DOMNode() == nil {
+		return true
+	}
+	w := c.DOMNode().ComputedStyles().GetPropertyValue("width")
+	return w == style.NullStyle || w == "auto"
 }
 
 // ---------------------------------------------------------------------------
@@ -276,7 +561,7 @@ A new root (block) formatting context is created by:
 */
 func needsRootContext(c *frame.Container) bool {
 	root := false
-	if c.Display.Inner().Contains(css.FlowRootMode) {
+	if c.Display.Inner().Contains(css.FlowRootMode) || c.Display.Inner().Contains(css.FlexMode) {
 		root = true
 	} else if c.Display.Contains(css.InlineMode | css.InnerBlockMode) { // "inline-root"
 		root = true
@@ -292,6 +577,8 @@ func needsRootContext(c *frame.Container) bool {
 			root = true
 		} else if overflow != "visible" && overflow != "clip" {
 			root = true
+		} else if isMulticol(c) {
+			root = true
 		} // TODO and other rules
 	}
 	return root
@@ -307,10 +594,18 @@ func NewContextFor(c *frame.Container) frame.ContextInterf {
 	}
 	inner := c.Display.Inner()
 	isroot := needsRootContext(c)
+	if inner.Contains(css.FlexMode) {
+		tracer().Debugf("providing flex context for [%v]", boxtree.ContainerName(c))
+		return NewFlexContext(c, true)
+	}
 	if inner.Contains(css.InnerInlineMode) {
 		tracer().Debugf("providing inline context (root=%v) for [%v]", isroot, boxtree.ContainerName(c))
 		return NewInlineContext(c, isroot)
 	}
+	if inner.Contains(css.InnerBlockMode) && isMulticol(c) {
+		tracer().Debugf("providing multi-column context for [%v]", boxtree.ContainerName(c))
+		return NewMulticolContext(c)
+	}
 	if inner.Contains(css.InnerBlockMode) {
 		if c.TreeNode().ChildCount() > 0 {
 			tracer().Debugf("context: checking %d children", c.TreeNode().ChildCount())
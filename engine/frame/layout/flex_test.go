@@ -0,0 +1,81 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestFlexGrowShrink(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	items := []*flexItem{
+		{basis: 20 * dimen.PT, grow: 1, shrink: 1, cross: 10 * dimen.PT, stretch: true},
+		{basis: 20 * dimen.PT, grow: 3, shrink: 1, cross: 30 * dimen.PT},
+	}
+	p := flexParams{align: css.AlignStretch}
+	h, _ := layoutFlexItems(items, p, 80*dimen.PT)
+	if items[0].main != 30*dimen.PT || items[1].main != 50*dimen.PT || items[1].pos.X != 30*dimen.PT {
+		t.Errorf("expected free space to be distributed 1:3, have %s and %s", items[0].main, items[1].main)
+	}
+	if h != 30*dimen.PT || items[0].cross != 30*dimen.PT {
+		t.Errorf("expected first item to be stretched to 30pt, is %s", items[0].cross)
+	}
+	items[0].basis, items[1].basis = 60*dimen.PT, 20*dimen.PT
+	layoutFlexItems(items, p, 40*dimen.PT)
+	if items[0].main != 30*dimen.PT || items[1].main != 10*dimen.PT {
+		t.Errorf("expected items to shrink proportional to their basis, have %s and %s",
+			items[0].main, items[1].main)
+	}
+}
+
+func TestFlexWrapAndJustify(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	items := make([]*flexItem, 3)
+	for i := range items {
+		items[i] = &flexItem{basis: 30 * dimen.PT, cross: 10 * dimen.PT}
+	}
+	p := flexParams{wrap: css.FlexWrap, justify: css.JustifySpaceBetween, align: css.AlignStart}
+	h, _ := layoutFlexItems(items, p, 70*dimen.PT)
+	if h != 20*dimen.PT || items[2].pos.Y != 10*dimen.PT {
+		t.Errorf("expected 3rd item to wrap to a second line, is at %v", items[2].pos)
+	}
+	if items[1].pos.X != 40*dimen.PT || items[2].pos.X != 0 {
+		t.Errorf("expected space between items of 1st line, items at %v and %v", items[1].pos, items[2].pos)
+	}
+	p = flexParams{direction: css.FlexRowReverse, justify: css.JustifyCenter}
+	layoutFlexItems(items[:2], p, 100*dimen.PT)
+	if items[0].pos.X != 50*dimen.PT || items[1].pos.X != 20*dimen.PT {
+		t.Errorf("expected reversed items to be centered, are at %v and %v", items[0].pos, items[1].pos)
+	}
+}
+
+func TestFlexRelayout(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	var widths []dimen.DU
+	text := &flexItem{basis: 60 * dimen.PT, grow: 1, shrink: 1, cross: 10 * dimen.PT, stretch: true}
+	text.layout = func(main dimen.DU) (dimen.DU, error) { // a paragraph of 60pt text
+		widths = append(widths, main)
+		lines := (60*dimen.PT + main - 1) / main
+		return lines * 10 * dimen.PT, nil
+	}
+	fixed := &flexItem{basis: 20 * dimen.PT, cross: 10 * dimen.PT}
+	p := flexParams{align: css.AlignStart}
+	h, err := layoutFlexItems([]*flexItem{text, fixed}, p, 50*dimen.PT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(widths) != 1 || widths[0] != 30*dimen.PT {
+		t.Fatalf("expected text to be laid out again at 30pt, have %v", widths)
+	}
+	if text.cross != 20*dimen.PT || h != 20*dimen.PT {
+		t.Errorf("expected text to break into 2 lines of 10pt, have height %s", text.cross)
+	}
+}
@@ -0,0 +1,200 @@
+package layout
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak"
+)
+
+// flexUnit scales flex grow factors to glue stretch.
+const flexUnit = dimen.PT
+
+// flexItem is an item of a flex container. Sizes are given along the main axis
+// and the cross axis of the container, i.e. main is the width for rows and the
+// height for columns.
+type flexItem struct {
+	c       *frame.Container
+	basis   dimen.DU        // flex base size
+	grow    float64         // flex-grow
+	shrink  float64         // flex-shrink
+	cross   dimen.DU        // hypothetical cross size
+	stretch bool            // cross size is auto and may be stretched
+	align   css.AlignItemsT // align-self
+	main    dimen.DU        // resolved main size
+	pos     dimen.Point     // resolved position, as (main, cross)
+	// layout lays out the item again at its resolved main size and returns its
+	// resulting cross size. May be nil, if the cross size does not depend on the
+	// main size.
+	layout func(main dimen.DU) (dimen.DU, error)
+}
+
+// flexParams are the properties of a flex container.
+type flexParams struct {
+	direction css.FlexDirectionT
+	wrap      css.FlexWrapT
+	justify   css.JustifyContentT
+	align     css.AlignItemsT
+}
+
+// flexLine is a line of flex items.
+type flexLine struct {
+	items []*flexItem
+	cross dimen.DU // cross size of the line
+}
+
+// layoutFlexItems resolves the sizes and positions of flex items within a flex
+// container with a given main size, following the CSS flexbox layout
+// algorithm (https://www.w3.org/TR/css-flexbox-1/#layout-algorithm). It returns
+// the cross size of the container.
+//
+// Once their main sizes are resolved, items are laid out again, as their cross
+// sizes may depend on their main sizes, e.g. for paragraphs broken into lines.
+//
+// Resolving flexible lengths maps onto glue: every item of a line is glue with
+// the flex base size as natural width, stretchable proportional to flex-grow and
+// shrinkable proportional to flex-shrink scaled by the flex base size. Setting
+// the glue of the line to the container's main size resolves the item sizes.
+func layoutFlexItems(items []*flexItem, p flexParams, mainsize dimen.DU) (dimen.DU, error) {
+	lines := collectFlexLines(items, p.wrap, mainsize)
+	var crossPos dimen.DU
+	for _, line := range lines {
+		resolveFlexibleLengths(line, mainsize)
+		if err := layoutFlexLine(line); err != nil {
+			return crossPos, err
+		}
+		justifyFlexLine(line, p, mainsize)
+		alignFlexLine(line, p.align, crossPos)
+		crossPos += line.cross
+	}
+	if p.wrap == css.FlexWrapReverse { // lines stack from the cross end
+		for _, line := range lines {
+			for _, item := range line.items {
+				item.pos.Y = crossPos - item.pos.Y - item.cross
+			}
+		}
+	}
+	return crossPos, nil
+}
+
+// collectFlexLines distributes items into lines. Unless wrapping is enabled,
+// all items are collected into a single line.
+func collectFlexLines(items []*flexItem, wrap css.FlexWrapT, mainsize dimen.DU) []*flexLine {
+	lines := []*flexLine{{}}
+	var w dimen.DU
+	for _, item := range items {
+		line := lines[len(lines)-1]
+		if wrap != css.FlexNoWrap && len(line.items) > 0 && w+item.basis > mainsize {
+			line = &flexLine{}
+			lines = append(lines, line)
+			w = 0
+		}
+		line.items = append(line.items, item)
+		w += item.basis
+	}
+	return lines
+}
+
+// resolveFlexibleLengths grows or shrinks the items of a line to fill mainsize.
+// Items will not shrink below a size of 0.
+func resolveFlexibleLengths(line *flexLine, mainsize dimen.DU) {
+	k := khipu.NewKhipu()
+	for _, item := range line.items {
+		stretch := dimen.DU(item.grow * float64(flexUnit))
+		shrink := dimen.DU(item.shrink * float64(item.basis))
+		k.AppendKnot(khipu.NewGlue(item.basis, shrink, stretch))
+	}
+	n := int64(len(line.items))
+	ratio, infinite := linebreak.GlueSetRatio(k, 0, n, mainsize)
+	cursor := khipu.NewCursor(k)
+	for i := 0; cursor.Next() && i < len(line.items); i++ {
+		item := line.items[i]
+		item.main = item.basis + linebreak.GlueDelta(cursor.AsGlue(), ratio, infinite)
+		if item.main < 0 {
+			item.main = 0
+		}
+	}
+}
+
+// layoutFlexLine lays out the items of a line at their resolved main sizes.
+// Items with an auto cross size take the resulting cross size.
+func layoutFlexLine(line *flexLine) error {
+	for _, item := range line.items {
+		if item.layout == nil {
+			continue
+		}
+		cross, err := item.layout(item.main)
+		if err != nil {
+			return err
+		}
+		if item.stretch {
+			item.cross = cross
+		}
+	}
+	return nil
+}
+
+// justifyFlexLine positions the items of a line along the main axis, distributing
+// free space according to justify-content.
+func justifyFlexLine(line *flexLine, p flexParams, mainsize dimen.DU) {
+	var used dimen.DU
+	for _, item := range line.items {
+		used += item.main
+	}
+	free, n := mainsize-used, dimen.DU(len(line.items))
+	var offset, gap dimen.DU
+	if free > 0 {
+		switch p.justify {
+		case css.JustifyEnd:
+			offset = free
+		case css.JustifyCenter:
+			offset = free / 2
+		case css.JustifySpaceBetween:
+			if n > 1 {
+				gap = free / (n - 1)
+			}
+		case css.JustifySpaceAround:
+			gap = free / n
+			offset = gap / 2
+		case css.JustifySpaceEvenly:
+			gap = free / (n + 1)
+			offset = gap
+		}
+	}
+	x := offset
+	for _, item := range line.items {
+		item.pos.X = x
+		if p.direction.IsReverse() {
+			item.pos.X = mainsize - x - item.main
+		}
+		x += item.main + gap
+	}
+}
+
+// alignFlexLine positions the items of a line along the cross axis, with the
+// line starting at crossPos. Baseline alignment is treated as start alignment.
+func alignFlexLine(line *flexLine, align css.AlignItemsT, crossPos dimen.DU) {
+	for _, item := range line.items {
+		if item.cross > line.cross {
+			line.cross = item.cross
+		}
+	}
+	for _, item := range line.items {
+		a := item.align
+		if a == css.AlignAuto {
+			a = align
+		}
+		item.pos.Y = crossPos
+		switch a {
+		case css.AlignStretch, css.AlignAuto:
+			if item.stretch {
+				item.cross = line.cross
+			}
+		case css.AlignEnd:
+			item.pos.Y += line.cross - item.cross
+		case css.AlignCenter:
+			item.pos.Y += (line.cross - item.cross) / 2
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package layout
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+// floatAndClear returns the computed CSS properties "float" and "clear" of
+// container c.
+func floatAndClear(c *frame.Container) (css.FloatT, css.ClearT) {
+	if c.DOMNode() == nil {
+		return css.FloatNone, css.ClearNone
+	}
+	styles := c.DOMNode().ComputedStyles()
+	return css.Float(styles.GetPropertyValue("float")), css.Clear(styles.GetPropertyValue("clear"))
+}
+
+// placeFloat places a floated container c within flow root fr, with the current
+// line starting at y. Clearance of c is respected. The position of c's box is set
+// relative to the flow root.
+//
+// The outer box of c must have its dimensions fixed.
+func placeFloat(c *frame.Container, side css.FloatT, clear css.ClearT, fr *frame.FlowRoot,
+	y dimen.DU) error {
+	//
+	var w, h dimen.DU
+	outer := c.CSSBox().OuterBox()
+	if outer.W.Match().Just(&w) == nil {
+		return ErrEnclosingWidthNotFixed
+	} else if outer.H.Match().Just(&h) == nil {
+		return ErrHeightNotFixed
+	}
+	y = fr.Placer.Clearance(clear, y)
+	f := fr.Placer.Place(c, side, w, h, y)
+	c.CSSBox().TopL = f.Pos
+	fr.PositionedFloats.AppendFloat(c)
+	tracer().Debugf("float [%s] placed at %v", boxtree.ContainerName(c), f.Pos)
+	return nil
+}
+
+// flowRootFor returns the flow root to place floats of context ctx into. If ctx
+// establishes a new block formatting context or if there is no enclosing flow
+// root, a new flow root is created for ctx.
+func flowRootFor(ctx frame.ContextInterf, flowRoot *frame.FlowRoot) *frame.FlowRoot {
+	if ctx.FlowRoot() != nil {
+		return ctx.FlowRoot()
+	}
+	if flowRoot != nil && !ctx.IsFlowRoot() {
+		return flowRoot
+	}
+	w := dimen.Zero
+	ctx.Container().CSSBox().W.Match().Just(&w)
+	return frame.NewFlowRoot(w)
+}
@@ -0,0 +1,153 @@
+package layout
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+// fragment is a render tree node for the continuation of a paragraph which has
+// been broken across regions. It shares the DOM node with the container it has
+// been split from.
+type fragment struct {
+	domNode *dom.W3CNode
+	box     *frame.Box
+}
+
+func (f fragment) DOMNode() *dom.W3CNode { return f.domNode }
+func (f fragment) CSSBox() *frame.Box    { return f.box }
+func (f fragment) PresetContained() bool { return false }
+
+// breaksOf returns the values of the properties break-before, break-after and
+// break-inside for c.
+func breaksOf(c *frame.Container) (before, after, inside css.BreakT) {
+	if c.DOMNode() == nil {
+		return
+	}
+	styles := c.DOMNode().ComputedStyles()
+	before = css.Break(styles.GetPropertyValue("break-before"))
+	after = css.Break(styles.GetPropertyValue("break-after"))
+	inside = css.Break(styles.GetPropertyValue("break-inside"))
+	return
+}
+
+// widowsAndOrphans returns the minimum number of lines of a paragraph which have
+// to be left at the top and at the bottom of a region, respectively.
+func widowsAndOrphans(c *frame.Container) (widows, orphans int) {
+	widows, orphans = 2, 2
+	if c.DOMNode() == nil {
+		return
+	}
+	styles := c.DOMNode().ComputedStyles()
+	widows = css.LineCount(styles.GetPropertyValue("widows"), 2)
+	orphans = css.LineCount(styles.GetPropertyValue("orphans"), 2)
+	return
+}
+
+// linesOf returns the line boxes of a paragraph container. For containers not
+// establishing an inline formatting context, nil is returned.
+func linesOf(c *frame.Container) []*frame.Container {
+	if c.Context == nil || c.Context.Type() != TypeInlineFormattingContext {
+		return nil
+	}
+	return Inline(c.Context).lines
+}
+
+// lineHeight returns the total height of a line box.
+func lineHeight(line *frame.Container) dimen.DU {
+	var h dimen.DU
+	line.CSSBox().H.Match().Just(&h)
+	return h
+}
+
+// Penalties for page breaks. Breaks between the lines of a paragraph are
+// penalized in the manner of TeX's \clubpenalty and \widowpenalty: a break
+// leaving fewer than `orphans` lines at the bottom of a region, or fewer than
+// `widows` lines at the top of the next one, is penalized for every line missing.
+//
+// Breaks between blocks are penalized if they are to be avoided, following
+// break-before and break-after. Headings keep with their next block, as if
+// styled `break-after: avoid`.
+const (
+	interlinePenalty = 0    // penalty for a break between lines
+	orphanPenalty    = 1000 // penalty for every orphan line missing
+	widowPenalty     = 1000 // penalty for every widow line missing
+	avoidPenalty     = 5000 // penalty for a break avoided by a block before or after it
+)
+
+// breakPenalties returns the penalties for page breaks between the lines of
+// paragraph c, where penalties[k-1] is the penalty for a break after line k.
+func breakPenalties(c *frame.Container) []int {
+	lines := linesOf(c)
+	if len(lines) < 2 {
+		return nil
+	}
+	widows, orphans := widowsAndOrphans(c)
+	penalties := make([]int, len(lines)-1)
+	for k := 1; k < len(lines); k++ {
+		penalties[k-1] = interlinePenalty
+		if k < orphans {
+			penalties[k-1] += (orphans - k) * orphanPenalty
+		}
+		if rest := len(lines) - k; rest < widows {
+			penalties[k-1] += (widows - rest) * widowPenalty
+		}
+	}
+	return penalties
+}
+
+// linesFitting returns how many lines of paragraph c should be placed into a
+// space of height avail. Of all breaks fitting, the one with the least penalty
+// is chosen, preferring later breaks for equal penalties. Breaks creating widows
+// or orphans, or breaking a paragraph with break-inside: avoid, are chosen only
+// if the region is empty, i.e. if c cannot be moved to the next region as a
+// whole instead. If the paragraph may not be broken at all, 0 is returned.
+func linesFitting(c *frame.Container, avail dimen.DU, empty bool) int {
+	if _, _, inside := breaksOf(c); inside.Avoids() && !empty {
+		return 0
+	}
+	penalties := breakPenalties(c)
+	best, y := 0, dimen.Zero
+	for k, line := range linesOf(c)[:len(penalties)] {
+		if y += lineHeight(line); y > avail {
+			break
+		}
+		if best == 0 || penalties[k] <= penalties[best-1] {
+			best = k + 1
+		}
+	}
+	if best == 0 || (penalties[best-1] > interlinePenalty && !empty) {
+		return 0
+	}
+	return best
+}
+
+// splitAfterLine breaks paragraph c after line k. The lines following line k
+// are moved to a new container, which is returned. Heights of both containers
+// are adjusted.
+func splitAfterLine(c *frame.Container, k int) *frame.Container {
+	lines := linesOf(c)
+	offset := dimen.Zero
+	for _, line := range lines[:k] {
+		offset += lineHeight(line)
+	}
+	var h dimen.DU
+	c.CSSBox().H.Match().Just(&h)
+	box := &frame.Box{}
+	box.W = c.CSSBox().W
+	box.H = css.JustDimen(h - offset)
+	cont := frame.MakeContainer(fragment{domNode: c.DOMNode(), box: box})
+	cont.Payload = &cont // always points to itself
+	cont.Display = c.Display
+	ctx := NewInlineContext(&cont, false)
+	for _, line := range lines[k:] {
+		line.CSSBox().TopL.Y -= offset
+		ctx.lines = append(ctx.lines, line)
+	}
+	cont.Context = ctx
+	Inline(c.Context).lines = lines[:k:k]
+	c.CSSBox().H = css.JustDimen(offset)
+	tracer().Debugf("paginator: paragraph split after line %d", k)
+	return &cont
+}
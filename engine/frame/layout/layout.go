@@ -1,6 +1,7 @@
 package layout
 
 import (
+	"context"
 	"errors"
 
 	"github.com/npillmayer/tyse/core/dimen"
@@ -43,6 +44,7 @@ var ErrNotAPercentageDimension error = errors.New("input dimension not a percent
      we need a clear understanding of input vs output.
 */
 type inheritedParams struct {
+	ctx        context.Context // for cancellation of layout; may be nil
 	flowRoot   *frame.FlowRoot
 	view       *View
 	W          css.DimenT // enclosing width
@@ -56,10 +58,15 @@ type synthesizedParams struct {
 }
 
 type View struct {
-	Width dimen.DU
+	Width  dimen.DU
+	Height dimen.DU // height of the viewport, used for fixed positioning
 }
 
 func BoxTreeToLayoutTree(boxRoot *boxtree.PrincipalBox, view *View) (syn synthesizedParams) {
+	return boxTreeToLayoutTree(context.Background(), boxRoot, view)
+}
+
+func boxTreeToLayoutTree(ctx context.Context, boxRoot *boxtree.PrincipalBox, view *View) (syn synthesizedParams) {
 	tracer().Debugf("================= ###### Layout ###### =====================")
 	if view == nil {
 		if boxRoot != nil {
@@ -68,7 +75,8 @@ func BoxTreeToLayoutTree(boxRoot *boxtree.PrincipalBox, view *View) (syn synthes
 		return
 	}
 	params := inheritedParams{
-		W:    css.SomeDimen(view.Width),
+		ctx:  ctx,
+		W:    css.JustDimen(view.Width),
 		MinW: 0,
 		MaxW: view.Width,
 		view: view,
@@ -84,62 +92,77 @@ func BoxTreeToLayoutTree(boxRoot *boxtree.PrincipalBox, view *View) (syn synthes
 		params.flowRoot = boxRoot.Context.FlowRoot()
 		syn = CalcBlockWidths(&boxRoot.Container, params)
 	}
-	if syn.lastErr != nil {
+	if syn.lastErr == nil {
+		syn.lastErr = ctx.Err()
+	}
+	if syn.lastErr == nil {
+		// out-of-flow boxes are laid out in a second pass
+		syn.lastErr = layoutOutOfFlow(&boxRoot.Container, view)
+	}
+	if syn.lastErr != nil && ctx.Err() == nil {
 		tracer().Errorf("layout tree error: %v", syn.lastErr)
 	}
 	tracer().Debugf("=================== ############### ======================")
-	// TODO
-	// now recursivly call boxRoot.Context().Layout(params/flowRoot)
 	return syn
 }
 
+// Layout lays out a box tree for a view and returns the first error
+// encountered, if any.
+func Layout(boxRoot *boxtree.PrincipalBox, view *View) error {
+	return LayoutContext(context.Background(), boxRoot, view)
+}
+
+// LayoutContext is like Layout, but stops with the error of ctx if ctx is
+// cancelled or its deadline is exceeded. Cancellation is checked before the
+// layout of every block container.
+func LayoutContext(ctx context.Context, boxRoot *boxtree.PrincipalBox, view *View) error {
+	return boxTreeToLayoutTree(ctx, boxRoot, view).lastErr
+}
+
 // Potentially recursive call to nested containers
 func CalcBlockWidths(c *frame.Container, inherited inheritedParams) (syn synthesizedParams) {
+	if inherited.ctx != nil && inherited.ctx.Err() != nil {
+		return withError(syn, inherited.ctx.Err())
+	}
 	if c.Context == nil {
 		c.Context = NewContextFor(c)
 	}
 	tracer().Debugf("calculating block width of [%s]", boxtree.ContainerName(c))
+	if boxtree.IsReplaced(c.RenderNode()) {
+		return sizeReplaced(c, inherited)
+	}
 	// case c.Box.W is Font or View dependent: should have been done already => error
 	// case c.Box.W is Content dependent: call calc on nested block
 	// case c.Box.W is absolute: we're done
-	/*if w, err := c.CSSBox().TotalWidth().Match(option.Of{
-		option.None:       nil, // defaults to `auto`
-		css.FontScaled:    option.Fail(ErrUnfixedscaledUnit),
-		css.ViewScaled:    option.Fail(ErrUnfixedscaledUnit),
-		css.FixedValue:    c.CSSBox().TotalWidth().Unwrap(),
-		css.ContentScaled: nil,
-		option.Some:       nil,
-	}); w != nil {
-		syn.W = w.(dimen.Dimen)
-		return
-	} else if err != nil {
-		return withError(syn, err)
-	}*/
-	if w := c.CSSBox().TotalWidth(); w.IsAbsolute() {
-		syn.W = w.Unwrap()
+	if c.CSSBox().TotalWidth().Match().Just(&syn.W) != nil {
 		return
 	}
-	// if c.ctx.isFlowRoot:
-	//      tell flow root to layout floats
-	//      wait for shelf-line condition
-	//      subtract floats' width from enclosing width
-	//
 	// Now we're ready to:
 	//syn = solveWidthTopDown(c, inherited)
 	var ok bool
-	if inherited.W.IsAbsolute() {
-		ok, syn.lastErr = frame.FixDimensionsFromEnclosingWidth(c.CSSBox(), inherited.W.Unwrap())
+	var enclosing dimen.DU
+	if inherited.W.Match().Just(&enclosing) != nil {
+		ok, syn.lastErr = frame.FixDimensionsFromEnclosingWidth(c.CSSBox(), enclosing)
 	} else {
 		syn.lastErr = frame.ErrContentScaling
 	}
+	// if c is a flow root, floats of its sub-containers will be placed into it
+	if ok && c.Context.IsFlowRoot() && c.Context.FlowRoot() == nil {
+		if ctx, isbase := c.Context.(interface{ SetFlowRoot(*frame.FlowRoot) }); isbase {
+			ctx.SetFlowRoot(flowRootFor(c.Context, nil))
+		}
+	}
+	if c.Context.FlowRoot() != nil {
+		inherited.flowRoot = c.Context.FlowRoot()
+	}
 	// recursion step
 	if ok && syn.lastErr == nil {
 		// recurse down
 		if hasContained := c.RenderNode().PresetContained(); hasContained {
 			tracer().Debugf("calculating width for %d children of [%s]", len(c.Context.Contained()),
 				boxtree.ContainerName(c))
-			inherited.W = c.CSSBox().W
-			inherited.MaxW = c.CSSBox().W.Unwrap()
+			inherited.W = enclosingWidthFor(c) // width of a column for multi-column containers
+			inherited.W.Match().Just(&inherited.MaxW)
 			for _, sub := range c.Context.Contained() {
 				//if sub.Type() == boxtree.TypeText {
 				if boxtree.IsText(sub.RenderNode()) {
@@ -166,9 +189,7 @@ func CalcBlockWidths(c *frame.Container, inherited inheritedParams) (syn synthes
 	if syn.lastErr != nil {
 		tracer().Debugf("calc block width: last error = %v", syn.lastErr)
 	}
-	if size, _, _ := c.Context.Measure(); !size.H.IsNone() {
-		syn.H = size.H.Unwrap()
-	} else {
+	if size, _, _ := c.Context.Measure(); size.H.Match().Just(&syn.H) == nil {
 		return withError(syn, ErrHeightNotFixed)
 	}
 	return
@@ -222,44 +243,6 @@ func LayoutInlineFormattingContext(ctx frame.ContextInterf, flowRoot *frame.Flow
 	return nil
 }
 
-// enclosing is width of containing block.
-// will stay inside c, no traversing of children containers.
-// Instead will flag an appropriate error, which the caller will use to traverse
-// nested containers before calling Solve… again.
-//
-// Will distribute space according to the equation (ref. CSS spec):
-//
-//     margin-left + width + margin-right = width of containing block
-//
-/*
-func solveWidthTopDown(c frame.Container, inherited inheritedParams) (syn synthesizedParams) {
-	// TODO fix relative width,margins => resolve against enclosing
-	// => should already been done
-	width := c.CSSBox().ContentWidth()
-	calc, err := width.Match(option.Of{
-		option.None:       calcWidthAsRest, // default is `auto`
-		css.Auto:          calcWidthAsRest,
-		css.ContentScaled: option.Fail(ErrContentScaling),
-		option.Some:       takeWidth,
-	})
-	if err != nil {
-		return withError(syn, err)
-	}
-	T().Debugf("solving width of [%v] top down, width now = %v", boxtree.ContainerName(c), width)
-	solve := asCalcFn(calc)
-	w, _ := solve(c, width, inherited.W)
-	box := distributeMarginSpace(c, w.Unwrap(), inherited.W.Unwrap())
-	// TODO how to proceed with box?
-	// -> assign to CSS box of c
-	T().Debugf("solved width of [%v] to %s", boxtree.ContainerName(c), box.TotalWidth())
-	if !box.W.IsAbsolute() {
-		return withError(syn, "box width not determined after margin space distribution")
-	}
-	syn.W = box.TotalWidth().Unwrap()
-	return
-}
-*/
-
 func solveWidthForContent(c *frame.Container, inherited inheritedParams) (syn synthesizedParams) {
 	panic("TODO")
 }
@@ -268,114 +251,6 @@ func SolveWidthBottomUp(c *frame.Container, enclosing dimen.DU) (*frame.Box, err
 	panic("TODO")
 }
 
-// --- Various dimen constraint solving strategies ---------------------------
-/*
-type calcFn func(c frame.Container, w, enclosing css.DimenT) (css.DimenT, error)
-
-func asCalcFn(f interface{}) calcFn {
-	return f.(func(c frame.Container, w, enclosing css.DimenT) (css.DimenT, error))
-}
-
-func takeWidth(c frame.Container, w, enclosing css.DimenT) (css.DimenT, error) {
-	T().Debugf("calculating width: simply take is as is = %v", w)
-	return c.CSSBox().ContentWidth(), nil
-}
-
-// Spec: If 'width' is set to 'auto', any other 'auto' values become '0'
-// and 'width' follows from the resulting equality.
-func calcWidthAsRest(c frame.Container, w, enclosing css.DimenT) (css.DimenT, error) {
-	left := fixDimensionMust(c.CSSBox().Margins[frame.Left], c, enclosing)
-	c.CSSBox().Margins[frame.Left] = css.SomeDimen(left) // do not lose fixed value
-	right := fixDimensionMust(c.CSSBox().Margins[frame.Right], c, enclosing)
-	c.CSSBox().Margins[frame.Right] = css.SomeDimen(right) // do not lose fixed value
-	width := enclosing.Unwrap() - left - right
-	r := css.SomeDimen(width)
-	T().Debugf("calculate width as rest (without decoration) to %s", r)
-	return r, nil
-	//return css.SomeDimen(width), nil
-}
-*/
-// ---------------------------------------------------------------------------
-
-// This must not be called for dimensions in unfixed relative units, except '%'.
-/*
-func fixDimensionMust(d css.DimenT, c frame.Container, enclosing css.DimenT) (fixedDimen dimen.Dimen) {
-	var err error
-	var fixed interface{}
-	if d.IsRelative() {
-		fixedDimen, err = fixRelativeDimension(d, c, enclosing)
-	} else {
-		fixed, err = d.Match(option.Of{ // TODO function MatchToDimen ? frequent case ?
-			option.None: dimen.Zero,
-			css.Initial: dimen.Zero,
-			css.Auto:    dimen.Zero,
-			option.Some: d.Unwrap(), // TODO safety: this will give nonsense for unfixed relative units
-		})
-		if fixed != nil {
-			fixedDimen = fixed.(dimen.Dimen)
-		}
-	}
-	if err != nil {
-		T().Errorf("layout fix relative dimen: %s", err.Error())
-		return dimen.Zero
-	}
-	return fixedDimen
-}
-
-// Forbidden to have side effects !
-func fixRelativeDimension(d css.DimenT, c frame.Container, w css.DimenT) (dimen.Dimen, error) {
-	if d.IsAbsolute() {
-		return d.Unwrap(), nil
-	}
-	enclosing := w.Unwrap()
-	width, err := d.Match(option.Of{
-		option.None: dimen.Zero,
-		"%":         d.Unwrap() * enclosing / 100,
-		option.Some: option.Fail(ErrNotAPercentageDimension),
-		// These 2 should both be done during boxtree buildup:
-		// TODO css.FontScaled: d.ScaleFromFont(c.DOMNode().Font()),
-		// TODO css.ViewScaled: d.ScaleFromView(...)
-	})
-	if err != nil {
-		T().Errorf("layout fix %%-dimen: %s", err.Error())
-		return dimen.Zero, err
-	}
-	return width.(dimen.Dimen), nil
-}
-
-// w and enclosing should be fixed
-func distributeMarginSpace(c frame.Container, w, enclosing dimen.Dimen) *frame.Box {
-	//box := &frame.Box{}
-	box := c.CSSBox() // make a copy of c's CSS box
-	remaining := enclosing - w
-	if remaining == 0 { // TODO fit into general case
-		box.FixBorderBoxWidth(w)
-	} else {
-		left := c.CSSBox().Margins[frame.Left]
-		right := c.CSSBox().Margins[frame.Right]
-		l, _ := left.Match(option.Of{
-			css.Auto: option.Safe(right.Match(option.Of{
-				css.Auto:    remaining / 2,
-				option.Some: remaining - right.Unwrap(),
-			})),
-		})
-		r := remaining - l.(dimen.Dimen)
-		box.Margins[frame.Left] = css.SomeDimen(l.(dimen.Dimen))
-		box.Margins[frame.Right] = css.SomeDimen(r)
-		box.FixBorderBoxWidth(w)
-	}
-	return box
-}
-
-func setWidthFromParent(c frame.Container, enclosing dimen.Dimen) bool {
-	dw := c.CSSBox().DecorationWidth(true)
-	if !dw.IsAbsolute() {
-		return false
-	}
-	return c.CSSBox().FixContentWidth(dw.Unwrap())
-}
-*/
-
 func withError(syn synthesizedParams, arg interface{}) synthesizedParams {
 	switch a := arg.(type) {
 	case string:
@@ -0,0 +1,294 @@
+package layout
+
+import (
+	"image/color"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+// --- Multi-column Context --------------------------------------------------
+
+// https://www.w3.org/TR/css-multicol-1/
+
+// MulticolContext establishes a CSS multi-column formatting context, for
+// containers with column-count or column-width set.
+//
+// Contained boxes are collected like in a block formatting context, but are laid
+// out with the width of a column. The resulting flow is then distributed onto
+// columns of balanced heights. Paragraphs may be broken between lines, following
+// the same rules as for page breaks (widows, orphans and break-inside), and
+// breaks between blocks follow break-before and break-after.
+//
+// Multi-column containers are not broken across pages. Forced column breaks
+// are not supported, and floats within the container are positioned like blocks.
+type MulticolContext struct {
+	BlockContext
+	Count       int      // used number of columns
+	ColumnWidth dimen.DU // used width of columns
+	Gap         dimen.DU // gap between columns
+	used        int      // number of columns with content
+	shifts      map[*frame.Container]dimen.Point
+}
+
+func NewMulticolContext(c *frame.Container) *MulticolContext {
+	ctx := &MulticolContext{Count: 1}
+	ctx.IsRootCtx = true // multi-column containers are flow roots
+	ctx.C = c
+	return ctx
+}
+
+func Multicol(ctx frame.ContextInterf) *MulticolContext {
+	if multicol, ok := ctx.(*MulticolContext); ok {
+		return multicol
+	}
+	panic("context is not a multi-column context")
+}
+
+func (ctx *MulticolContext) Type() frame.FormattingContextType {
+	return TypeMulticolFormattingContext
+}
+
+// setColumns determines the used number and width of columns for a container
+// of width w, from the container's column properties, and returns the width of
+// a column.
+func (ctx *MulticolContext) setColumns(w dimen.DU) dimen.DU {
+	count, width, gap := 0, dimen.Zero, css.DefaultFontSize
+	if ctx.Container().DOMNode() != nil {
+		styles := ctx.Container().DOMNode().ComputedStyles()
+		count = css.ColumnCount(styles.GetPropertyValue("column-count"))
+		width = css.ColumnWidth(styles.GetPropertyValue("column-width"), css.DefaultFontSize)
+		gap = css.ColumnGap(styles.GetPropertyValue("column-gap"), css.DefaultFontSize, w)
+	}
+	ctx.Gap = gap
+	ctx.Count, ctx.ColumnWidth = css.Columns(count, width, gap, w)
+	tracer().Debugf("multi-column container [%s]: %d columns of %s", boxtree.ContainerName(ctx.Container()),
+		ctx.Count, ctx.ColumnWidth)
+	return ctx.ColumnWidth
+}
+
+// Layout distributes the contained boxes, which have already been laid out with
+// the width of a column, onto columns of balanced heights. The height of the
+// container is the height of its highest column.
+func (ctx *MulticolContext) Layout(flowRoot *frame.FlowRoot) error {
+	for line, d := range ctx.shifts { // lines have been moved by a previous layout
+		line.CSSBox().TopL.X -= d.X
+		line.CSSBox().TopL.Y -= d.Y
+	}
+	children := ctx.Contained()
+	for _, c := range children {
+		if !c.CSSBox().H.IsAbsolute() {
+			return ErrHeightNotFixed
+		}
+	}
+	var h dimen.DU
+	h, ctx.used, ctx.shifts = layoutColumns(children, ctx.Count, ctx.ColumnWidth, ctx.Gap)
+	ctx.Container().CSSBox().H = css.JustDimen(h)
+	return nil
+}
+
+func (ctx *MulticolContext) Measure() (frame.Size, css.DimenT, css.DimenT) {
+	return ctx.Container().CSSBox().Size, css.JustDimen(0), css.JustDimen(0)
+}
+
+var _ frame.ContextInterf = &MulticolContext{}
+
+// isMulticol returns true if c is a multi-column container.
+func isMulticol(c *frame.Container) bool {
+	if c.DOMNode() == nil {
+		return false
+	}
+	styles := c.DOMNode().ComputedStyles()
+	return css.IsMulticol(styles.GetPropertyValue("column-count"), styles.GetPropertyValue("column-width"))
+}
+
+// enclosingWidthFor returns the width available to the children of container c,
+// which is the width of a column for multi-column containers.
+func enclosingWidthFor(c *frame.Container) css.DimenT {
+	if multicol, ok := c.Context.(*MulticolContext); ok {
+		var w dimen.DU
+		if c.CSSBox().W.Match().Just(&w) != nil {
+			return css.JustDimen(multicol.setColumns(w))
+		}
+	}
+	return c.CSSBox().W
+}
+
+// --- Column balancing ------------------------------------------------------
+
+// columnUnit is a box to be placed into a column: a block or a line of a
+// paragraph.
+type columnUnit struct {
+	c    *frame.Container
+	para *frame.Container // paragraph of a line, nil for blocks
+	h    dimen.DU
+}
+
+// columnChunk is a sequence of units which may not be broken between columns.
+type columnChunk struct {
+	units []columnUnit
+	h     dimen.DU
+}
+
+// columnChunks splits a flow of blocks into chunks, which may be broken between.
+// Paragraphs are broken between lines wherever no penalty applies, i.e. widows
+// and orphans are kept together with their neighbouring lines.
+func columnChunks(flow []*frame.Container) []columnChunk {
+	var chunks []columnChunk
+	add := func(u columnUnit, breakable bool) {
+		if breakable || len(chunks) == 0 {
+			chunks = append(chunks, columnChunk{})
+		}
+		chunk := &chunks[len(chunks)-1]
+		chunk.units = append(chunk.units, u)
+		chunk.h += u.h
+	}
+	for i, c := range flow {
+		var h dimen.DU
+		c.CSSBox().H.Match().Just(&h)
+		breakable := i > 0 && blockBreakPenalty(flow[i-1], c) == 0
+		lines := linesOf(c)
+		if _, _, inside := breaksOf(c); len(lines) < 2 || inside.Avoids() {
+			add(columnUnit{c: c, h: h}, breakable)
+			continue
+		}
+		penalties := breakPenalties(c)
+		for k, line := range lines {
+			lh := lineHeight(line)
+			h -= lh
+			if k > 0 {
+				breakable = penalties[k-1] <= interlinePenalty
+			}
+			if k == len(lines)-1 {
+				lh += h // space of the paragraph below its lines
+			}
+			add(columnUnit{c: line, para: c, h: lh}, breakable)
+		}
+	}
+	return chunks
+}
+
+// balanceColumns returns the least column height for which chunks fit into n
+// columns, filling columns one after the other. If a chunk is higher than the
+// columns would otherwise be, columns get the height of the chunk.
+func balanceColumns(chunks []columnChunk, n int) dimen.DU {
+	var total, h dimen.DU
+	for _, chunk := range chunks {
+		total += chunk.h
+		h = dimen.Max(h, chunk.h)
+	}
+	h = dimen.Max(h, (total+dimen.DU(n)-1)/dimen.DU(n))
+	for {
+		cols, y, grow := 1, dimen.Zero, dimen.Zero
+		for _, chunk := range chunks {
+			if y > 0 && y+chunk.h > h {
+				if d := y + chunk.h - h; grow == 0 || d < grow {
+					grow = d // least increase of h moving a column break
+				}
+				cols, y = cols+1, 0
+			}
+			y += chunk.h
+		}
+		if cols <= n {
+			return h
+		}
+		h += grow
+	}
+}
+
+// layoutColumns distributes a flow of blocks onto n columns of width w, with
+// gaps between them, balancing the heights of columns. Blocks are positioned
+// within their column. Lines of paragraphs broken between columns are moved into
+// the following columns, relative to their paragraph.
+//
+// layoutColumns returns the height of the highest column, the number of columns
+// having content and the offsets lines have been moved by.
+func layoutColumns(flow []*frame.Container, n int, w, gap dimen.DU) (dimen.DU, int,
+	map[*frame.Container]dimen.Point) {
+	//
+	chunks := columnChunks(flow)
+	if len(chunks) == 0 {
+		return 0, 0, nil
+	}
+	colh := balanceColumns(chunks, n)
+	shifts := make(map[*frame.Container]dimen.Point)
+	type paraPos struct {
+		origin dimen.Point // position of the paragraph
+		offset dimen.DU    // offset of the next line within the paragraph
+	}
+	paras := make(map[*frame.Container]*paraPos)
+	col, y, h := 0, dimen.Zero, dimen.Zero
+	for _, chunk := range chunks {
+		if y > 0 && y+chunk.h > colh {
+			col, y = col+1, 0
+		}
+		x := dimen.DU(col) * (w + gap)
+		for _, u := range chunk.units {
+			if u.para == nil {
+				u.c.CSSBox().TopL = dimen.Point{X: x, Y: y}
+				y += u.h
+				continue
+			}
+			p := paras[u.para]
+			if p == nil {
+				p = &paraPos{origin: dimen.Point{X: x, Y: y}}
+				u.para.CSSBox().TopL = p.origin
+				paras[u.para] = p
+			}
+			if d := (dimen.Point{X: x - p.origin.X, Y: y - p.origin.Y - p.offset}); d != dimen.Origin {
+				u.c.CSSBox().TopL.X += d.X
+				u.c.CSSBox().TopL.Y += d.Y
+				shifts[u.c] = d
+			}
+			p.offset += u.h
+			y += u.h
+		}
+		h = dimen.Max(h, y)
+	}
+	tracer().Debugf("multi-column layout: %d columns of height %s", col+1, h)
+	return h, col + 1, shifts
+}
+
+// --- Column rules ----------------------------------------------------------
+
+// columnRules returns decorations for the rules between the columns of a
+// multi-column container c, which is positioned at pos. Rules are drawn between
+// columns having content, centered in the gaps, and are drawn solid.
+func columnRules(c *frame.Container, pos dimen.Point) []*Decoration {
+	multicol, ok := c.Context.(*MulticolContext)
+	if !ok || multicol.used < 2 || c.DOMNode() == nil {
+		return nil
+	}
+	styles := c.DOMNode().ComputedStyles()
+	linestyle, visible := frame.LineStyleFromProperty(string(styles.GetPropertyValue("column-rule-style")))
+	width, err := css.Resolution{}.Absolute(css.DimenOption(styles.GetPropertyValue("column-rule-width")), 0)
+	if !visible || err != nil || width <= 0 {
+		return nil
+	}
+	rulecolor := styles.GetPropertyValue("column-rule-color").Color()
+	if rulecolor == nil {
+		rulecolor = styles.GetPropertyValue("color").Color()
+	}
+	if rulecolor == nil {
+		rulecolor = color.Black
+	}
+	var h dimen.DU
+	c.CSSBox().H.Match().Just(&h)
+	var rules []*Decoration
+	for k := 1; k < multicol.used; k++ {
+		x := pos.X + dimen.DU(k)*(multicol.ColumnWidth+multicol.Gap) - (multicol.Gap+width)/2
+		rect := dimen.Rect{
+			TopL: dimen.Point{X: x, Y: pos.Y},
+			BotR: dimen.Point{X: x + width, Y: pos.Y + h},
+		}
+		rules = append(rules, &Decoration{
+			PaintArea:  frame.PaintArea{Border: rect, Padding: rect},
+			Background: rulecolor,
+			LineStyle:  linestyle,
+			Node:       c.DOMNode(),
+		})
+	}
+	return rules
+}
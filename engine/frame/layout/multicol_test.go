@@ -0,0 +1,42 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+func TestMulticolBalance(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := paragraphOfLines(6, 10*dimen.PT)
+	block := blockOfHeight(20 * dimen.PT)
+	h, used, _ := layoutColumns([]*frame.Container{para, block}, 2, 100*dimen.PT, 10*dimen.PT)
+	if h != 40*dimen.PT || used != 2 {
+		t.Fatalf("expected 2 columns of 40pt, have %d of %v", used, h)
+	}
+	line := linesOf(para)[4]
+	if line.CSSBox().TopL != (dimen.Point{X: 110 * dimen.PT, Y: 0}) {
+		t.Errorf("expected 5th line at top of 2nd column, is at %v", line.CSSBox().TopL)
+	}
+	if block.CSSBox().TopL != (dimen.Point{X: 110 * dimen.PT, Y: 20 * dimen.PT}) {
+		t.Errorf("expected block below lines in 2nd column, is at %v", block.CSSBox().TopL)
+	}
+}
+
+func TestMulticolWidows(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := paragraphOfLines(5, 10*dimen.PT)
+	h, used, _ := layoutColumns([]*frame.Container{para}, 2, 100*dimen.PT, 10*dimen.PT)
+	if h != 30*dimen.PT || used != 2 {
+		t.Fatalf("expected 2 columns of 30pt, have %d of %v", used, h)
+	}
+	if x := linesOf(para)[3].CSSBox().TopL.X; x != 110*dimen.PT {
+		t.Errorf("expected last 2 lines in 2nd column, 4th line is at x=%v", x)
+	}
+}
@@ -2,23 +2,402 @@ package layout
 
 import (
 	"container/heap"
+	"fmt"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/percent"
+	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/frame"
 )
 
 type Page struct {
-	dimen.Rect             // page size
-	queue      *EventQ     // every page manages an event queue (e.g., reflow events)
-	template   interface{} // TODO
+	dimen.Rect                 // page size
+	Name        string         // name of the page, selecting @page rules for named pages
+	Number      int            // page number, counting from 1
+	Side        PageSide       // left or right page of a spread
+	Content     dimen.Rect     // content area of the page, i.e. the page without its margins
+	Template    *PageTemplate  // template the page has been created from, if any
+	Regions     []*RegionBox   // regions of the page, filled with content from named flows
+	Links       []*Link        // links placed on the page, see ResolveCrossReferences
+	Images      []*PlacedImage // images placed on the page, see PlaceImages
+	Decorations []*Decoration  // backgrounds and borders of boxes on the page, see PaintDecorations
+	queue       *EventQ        // every page manages an event queue (e.g., reflow events)
 }
 
 func NewPage(papersize dimen.Point) *Page {
 	page := &Page{}
 	page.Rect.BotR = papersize
+	page.Content = page.Rect
 	return page
 }
 
+// NewPageFor creates page number n (counting from 1) for a page model.
+func NewPageFor(pm *PageModel, n int) *Page {
+	return NewNamedPageFor(pm, "", n)
+}
+
+// NewNamedPageFor creates page number n (counting from 1) for a page model,
+// applying @page rules for pages named name in addition to the general ones.
+func NewNamedPageFor(pm *PageModel, name string, n int) *Page {
+	page := NewPage(pm.SizeOf(name))
+	page.Name = name
+	page.Number = n
+	page.Side = pm.Side(n)
+	page.Content = pm.ContentAreaOf(name, n)
+	return page
+}
+
+// --- Page Model ------------------------------------------------------------
+
+// PageSide denotes the side of a page within a spread of two facing pages.
+type PageSide uint8
+
+// A right page is called recto, a left page verso.
+const (
+	RightPage PageSide = iota
+	LeftPage
+)
+
+func (side PageSide) String() string {
+	if side == LeftPage {
+		return "left"
+	}
+	return "right"
+}
+
+// BindingEdge is the edge of a page where a two-sided document will be bound.
+type BindingEdge uint8
+
+// Documents with left-to-right page progression are bound at their left edge and
+// start with a right page, documents with right-to-left progression vice versa.
+const (
+	BindLeft BindingEdge = iota
+	BindRight
+)
+
+// Page selectors of @page rules which are considered for page margins.
+const (
+	pageAll = iota
+	pageLeft
+	pageRight
+	pageFirst
+)
+
+// PageModel is the page geometry of a document, following CSS Paged Media.
+// Margins are collected from @page rules, where rules for `:left` and `:right`
+// pages override rules for all pages, and a rule for `:first` overrides both.
+// This enables mirrored margins for two-sided layouts.
+//
+// Rules for named pages, e.g. `@page chapter :first`, override rules for unnamed
+// pages. Named pages may have a size of their own.
+//
+// Gutter is extra space for the binding of two-sided documents. It is added to
+// the inner margin of every page, i.e. the margin at the binding edge.
+type PageModel struct {
+	Size    dimen.Point // paper size
+	Gutter  dimen.DU    // binding gutter, added to the inner margins
+	Binding BindingEdge // edge of the binding
+	margins [4][4]css.DimenT
+	named   map[string]*namedPage
+}
+
+// namedPage holds the properties of @page rules for a named page.
+type namedPage struct {
+	size    dimen.Point // page size, zero if not set
+	margins [4][4]css.DimenT
+}
+
+// NewPageModel creates a page model for pages of a given paper size, without
+// any margins.
+func NewPageModel(papersize dimen.Point) *PageModel {
+	return &PageModel{Size: papersize}
+}
+
+// SetMargins sets the margins (top, right, bottom, left) for pages matching an
+// @page selector. A selector consists of an optional page name and an optional
+// pseudo-class, which is one of `:left`, `:right` or `:first`.
+// Margins which are unset do not override margins from less specific rules.
+func (pm *PageModel) SetMargins(selector string, margins [4]css.DimenT) error {
+	name, sel, err := pageSelector(selector)
+	if err != nil {
+		return err
+	}
+	target := &pm.margins
+	if name != "" {
+		target = &pm.namedPage(name).margins
+	}
+	for i, m := range margins {
+		if !m.IsNone() {
+			target[sel][i] = m
+		}
+	}
+	return nil
+}
+
+// SetSize sets the page size for pages matching an @page selector without a
+// pseudo-class. For an empty selector, this changes the paper size of the model.
+func (pm *PageModel) SetSize(selector string, size dimen.Point) error {
+	name, sel, err := pageSelector(selector)
+	if err != nil {
+		return err
+	}
+	if sel != pageAll {
+		return fmt.Errorf("page size cannot be set for page selector %q", selector)
+	}
+	if name == "" {
+		pm.Size = size
+	} else {
+		pm.namedPage(name).size = size
+	}
+	return nil
+}
+
+// SizeOf returns the size of pages named name. Pages without a size of their
+// own have the paper size of the model.
+func (pm *PageModel) SizeOf(name string) dimen.Point {
+	if np, ok := pm.named[name]; ok && np.size != (dimen.Point{}) {
+		return np.size
+	}
+	return pm.Size
+}
+
+func (pm *PageModel) namedPage(name string) *namedPage {
+	if pm.named == nil {
+		pm.named = make(map[string]*namedPage)
+	}
+	np, ok := pm.named[name]
+	if !ok {
+		np = &namedPage{}
+		pm.named[name] = np
+	}
+	return np
+}
+
+// AddPageRule adds the size and margin properties of an @page rule to the page
+// model. Rules for other at-keywords than @page are ignored. Margin shorthands are
+// split up into their components.
+//
+// Property `size` is either `auto`, one or two absolute lengths, or a page size
+// keyword (A3, A4, A5, B4, B5, letter, legal, ledger), optionally combined with
+// an orientation of `portrait` or `landscape`. Sizes for pages with a
+// pseudo-class are ignored, as mandated by CSS Paged Media.
+func (pm *PageModel) AddPageRule(rule cssom.Rule) error {
+	selector := strings.TrimSpace(rule.Selector())
+	if selector != "@page" && !strings.HasPrefix(selector, "@page ") {
+		return nil
+	}
+	selector = strings.TrimSpace(strings.TrimPrefix(selector, "@page"))
+	var margins [4]css.DimenT
+	for _, key := range rule.Properties() {
+		if key == "size" {
+			name, sel, err := pageSelector(selector)
+			if err != nil {
+				return err
+			}
+			if sel != pageAll {
+				tracer().Infof("@page %s: ignoring size for page with pseudo-class", selector)
+				continue
+			}
+			size, err := pageSize(rule.Value(key), pm.SizeOf(name))
+			if err != nil {
+				return err
+			}
+			if err = pm.SetSize(selector, size); err != nil {
+				return err
+			}
+		} else if key == "margin" {
+			kv, err := style.SplitCompoundProperty("margin", rule.Value(key))
+			if err != nil {
+				return err
+			}
+			for _, m := range kv {
+				margins[marginIndex(m.Key)] = css.DimenOption(m.Value)
+			}
+		} else if i := marginIndex(key); i >= 0 {
+			margins[i] = css.DimenOption(rule.Value(key))
+		}
+	}
+	return pm.SetMargins(selector, margins)
+}
+
+// Side returns the side of page number n within a spread. For left-bound
+// documents page 1 is a right page.
+func (pm *PageModel) Side(n int) PageSide {
+	if (n%2 == 1) == (pm.Binding == BindLeft) {
+		return RightPage
+	}
+	return LeftPage
+}
+
+// Margins returns the margins (top, right, bottom, left) of page number n,
+// including the binding gutter. Percentages refer to the width of the page.
+func (pm *PageModel) Margins(n int) [4]dimen.DU {
+	return pm.MarginsOf("", n)
+}
+
+// MarginsOf returns the margins (top, right, bottom, left) of page number n,
+// being a page named name. Rules for the named page override the rules for
+// unnamed pages.
+func (pm *PageModel) MarginsOf(name string, n int) [4]dimen.DU {
+	cascade := []int{pageAll, pageRight}
+	if pm.Side(n) == LeftPage {
+		cascade[1] = pageLeft
+	}
+	if n == 1 {
+		cascade = append(cascade, pageFirst)
+	}
+	rules := []*[4][4]css.DimenT{&pm.margins}
+	if np, ok := pm.named[name]; ok && name != "" {
+		rules = append(rules, &np.margins)
+	}
+	width := pm.SizeOf(name).X
+	var margins [4]dimen.DU
+	for _, r := range rules {
+		for _, sel := range cascade {
+			for i, m := range r[sel] {
+				var d dimen.DU
+				var p percent.Percent
+				if m.Match().Just(&d) != nil {
+					margins[i] = d
+				} else if m.Match().Percentage(&p) != nil {
+					margins[i] = width.Scale(int64(p), 100)
+				}
+			}
+		}
+	}
+	margins[pm.innerEdge(n)] += pm.Gutter
+	return margins
+}
+
+// ContentArea returns the area of page number n within its margins.
+func (pm *PageModel) ContentArea(n int) dimen.Rect {
+	return pm.ContentAreaOf("", n)
+}
+
+// ContentAreaOf returns the area of page number n, being a page named name,
+// within its margins.
+func (pm *PageModel) ContentAreaOf(name string, n int) dimen.Rect {
+	m := pm.MarginsOf(name, n)
+	size := pm.SizeOf(name)
+	return dimen.Rect{
+		TopL: dimen.Point{X: m[frame.Left], Y: m[frame.Top]},
+		BotR: dimen.Point{X: size.X - m[frame.Right], Y: size.Y - m[frame.Bottom]},
+	}
+}
+
+// OuterEdge returns the edge of page number n facing away from the binding,
+// either frame.Left or frame.Right. Running heads and folios will usually be set
+// flush to the outer edge, thus alternating between left and right pages.
+func (pm *PageModel) OuterEdge(n int) int {
+	if pm.innerEdge(n) == frame.Left {
+		return frame.Right
+	}
+	return frame.Left
+}
+
+// innerEdge returns the edge of page n at the spine of its spread. Within a
+// spread, the spine is always between the left and the right page.
+func (pm *PageModel) innerEdge(n int) int {
+	if pm.Side(n) == RightPage {
+		return frame.Left
+	}
+	return frame.Right
+}
+
+var pageName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// pageSelector splits an @page selector into a page name and a pseudo-class,
+// e.g. "chapter :first".
+func pageSelector(selector string) (string, int, error) {
+	name, pseudo := strings.TrimSpace(selector), ""
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name, pseudo = strings.TrimSpace(name[:i]), name[i:]
+	}
+	if name != "" && !pageName.MatchString(name) {
+		return "", pageAll, fmt.Errorf("illegal page name in page selector: %q", selector)
+	}
+	switch pseudo {
+	case "":
+		return name, pageAll, nil
+	case ":left":
+		return name, pageLeft, nil
+	case ":right":
+		return name, pageRight, nil
+	case ":first":
+		return name, pageFirst, nil
+	}
+	return name, pageAll, fmt.Errorf("unsupported page selector: %q", selector)
+}
+
+// Page sizes for property `size` of @page rules, in portrait orientation.
+var pageSizes = map[string]dimen.Point{
+	"a3":     {X: 297 * dimen.MM, Y: 420 * dimen.MM},
+	"a4":     dimen.DINA4,
+	"a5":     dimen.DINA5,
+	"b4":     {X: 250 * dimen.MM, Y: 353 * dimen.MM},
+	"b5":     {X: 176 * dimen.MM, Y: 250 * dimen.MM},
+	"letter": dimen.USLetter,
+	"legal":  dimen.USLegal,
+	"ledger": {X: 279 * dimen.MM, Y: 432 * dimen.MM},
+}
+
+// pageSize interprets the value of property `size` of an @page rule. Value
+// `auto` and a lone orientation refer to the current size of the page.
+func pageSize(p style.Property, current dimen.Point) (dimen.Point, error) {
+	size, orientation := current, ""
+	var lengths []dimen.DU
+	for _, v := range strings.Fields(strings.ToLower(string(p))) {
+		if s, ok := pageSizes[v]; ok {
+			size = s
+			continue
+		}
+		switch v {
+		case "auto":
+			continue
+		case "portrait", "landscape":
+			orientation = v
+			continue
+		}
+		var d dimen.DU
+		if css.DimenOption(style.Property(v)).Match().Just(&d) == nil || d <= 0 {
+			return current, fmt.Errorf("illegal page size: %q", p)
+		}
+		lengths = append(lengths, d)
+	}
+	switch len(lengths) {
+	case 0:
+	case 1:
+		size = dimen.Point{X: lengths[0], Y: lengths[0]}
+	case 2:
+		size = dimen.Point{X: lengths[0], Y: lengths[1]}
+	default:
+		return current, fmt.Errorf("illegal page size: %q", p)
+	}
+	if (orientation == "landscape") != (size.X > size.Y) && orientation != "" {
+		size.X, size.Y = size.Y, size.X
+	}
+	return size, nil
+}
+
+func marginIndex(key string) int {
+	switch key {
+	case "margin-top":
+		return frame.Top
+	case "margin-right":
+		return frame.Right
+	case "margin-bottom":
+		return frame.Bottom
+	case "margin-left":
+		return frame.Left
+	}
+	return -1
+}
+
 // --- Event Queue -----------------------------------------------------------
 
 type Event struct {
@@ -0,0 +1,103 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+func TestPageSpread(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	sheet, err := parser.Parse(`
+	@page { margin: 20mm; }
+	@page :left { margin-left: 30mm; }
+	@page :right { margin-right: 30mm; }
+	@page :first { margin-top: 50mm; }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPageModel(dimen.DINA4)
+	pm.Gutter = 5 * dimen.MM
+	for _, rule := range douceuradapter.Wrap(sheet).Rules() {
+		if err = pm.AddPageRule(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pm.Side(1) != RightPage || pm.Side(2) != LeftPage {
+		t.Errorf("expected page 1 to be a right page, page 2 to be a left page")
+	}
+	m := pm.Margins(1)
+	if m[frame.Top] != 50*dimen.MM || m[frame.Left] != 25*dimen.MM || m[frame.Right] != 30*dimen.MM {
+		t.Errorf("expected margins of page 1 to be (50, 30, 20, 25)mm, are %v", m)
+	}
+	m = pm.Margins(2)
+	if m[frame.Top] != 20*dimen.MM || m[frame.Left] != 30*dimen.MM || m[frame.Right] != 25*dimen.MM {
+		t.Errorf("expected margins of page 2 to be (20, 25, 20, 30)mm, are %v", m)
+	}
+	if pm.OuterEdge(1) != frame.Right || pm.OuterEdge(2) != frame.Left {
+		t.Errorf("expected outer edges to alternate")
+	}
+	page := NewPageFor(pm, 3)
+	if page.Side != RightPage || page.Content.TopL.X != 25*dimen.MM {
+		t.Errorf("expected page 3 to be a right page with inner margin 25mm, is %v", page.Content)
+	}
+	pm.Binding = BindRight
+	if pm.Side(1) != LeftPage || pm.Margins(1)[frame.Right] != 25*dimen.MM {
+		t.Errorf("expected page 1 to be a left page bound at the right edge")
+	}
+}
+
+func TestNamedPages(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	sheet, err := parser.Parse(`
+	@page { size: A5; margin: 10mm; }
+	@page :first { margin-top: 40mm; }
+	@page chapter { size: A4 landscape; margin-left: 20mm; }
+	@page chapter :first { margin-top: 60mm; }
+	@page :left { size: 100mm 100mm; }
+	@media screen { @page { margin: 0; } }
+	@media print and (min-width: 100mm) { @page chapter { margin-right: 5%; } }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPageModel(dimen.DINA4)
+	media := cssom.PrintMedia(pm.Size)
+	for _, rule := range cssom.EffectiveRules(douceuradapter.Wrap(sheet), media) {
+		if err = pm.AddPageRule(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pm.Size != dimen.DINA5 {
+		t.Errorf("expected paper size to be A5, is %v", pm.Size)
+	}
+	landscape := dimen.Point{X: 297 * dimen.MM, Y: 210 * dimen.MM}
+	if pm.SizeOf("chapter") != landscape || pm.SizeOf("index") != dimen.DINA5 {
+		t.Errorf("expected chapter pages to be A4 landscape, are %v", pm.SizeOf("chapter"))
+	}
+	m := pm.MarginsOf("chapter", 1)
+	if m[frame.Top] != 60*dimen.MM || m[frame.Left] != 20*dimen.MM || m[frame.Right] != landscape.X/20 {
+		t.Errorf("expected margins of first chapter page to be (60, 5%%, 10, 20)mm, are %v", m)
+	}
+	if m = pm.Margins(1); m[frame.Top] != 40*dimen.MM || m[frame.Left] != 10*dimen.MM {
+		t.Errorf("expected margins of first page to be (40, 10, 10, 10)mm, are %v", m)
+	}
+	page := NewNamedPageFor(pm, "chapter", 3)
+	if page.Name != "chapter" || page.Content.BotR.Y != 200*dimen.MM {
+		t.Errorf("expected page 3 to be a chapter page with content bottom at 200mm, is %v", page.Content)
+	}
+	if err = pm.SetMargins("chapter:last", [4]css.DimenT{}); err == nil {
+		t.Errorf("expected unsupported page selector to be rejected")
+	}
+}
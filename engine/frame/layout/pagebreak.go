@@ -0,0 +1,169 @@
+package layout
+
+import (
+	"context"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/khipu/linebreak/knuthplass"
+)
+
+// --- Optimal page breaking -------------------------------------------------
+
+// pagePlan holds the page breaks chosen for a flow. Breaks are denoted by the
+// container to break after, either a block or a line of a paragraph.
+type pagePlan map[*frame.Container]bool
+
+// planPageBreaks determines optimal page breaks for flow (see Paginator.Optimal).
+// Blocks too high for a region are replaced by their children, if possible.
+// planPageBreaks returns the flow to place, together with the page breaks.
+//
+// The flow is translated into a vertical list, with boxes for blocks and for
+// the lines of paragraphs. Penalties between boxes are taken from the
+// paginator's cost model (see breakPenalties and blockBreakPenalty), forced
+// breaks become penalties of -10000. The vertical list is then broken into
+// pages with the Knuth-Plass algorithm.
+func (pg *Paginator) planPageBreaks(ctx context.Context, flowname string, flow []*frame.Container,
+	router *frame.FlowRouter) ([]*frame.Container, pagePlan, error) {
+	//
+	heights := regionHeights{pg: pg, flow: flowname}
+	flow = expandFlow(flow, router, heights.LineLength(1))
+	vl := khipu.NewVList()
+	var breakAfter []*frame.Container // container to break after, per knot position
+	appendKnot := func(knot khipu.Knot, after *frame.Container) {
+		vl.AppendKnot(knot)
+		breakAfter = append(breakAfter, after)
+	}
+	for i, c := range flow {
+		var h dimen.DU
+		if c.CSSBox().H.Match().Just(&h) == nil {
+			return flow, nil, ErrHeightNotFixed
+		}
+		if i > 0 {
+			prev := flow[i-1]
+			_, after, _ := breaksOf(prev)
+			before, _, _ := breaksOf(c)
+			if before.IsForced() || after.IsForced() {
+				appendKnot(khipu.Penalty(-10000), prev)
+			} else {
+				appendKnot(khipu.Penalty(blockBreakPenalty(prev, c)), prev)
+			}
+		}
+		lines := linesOf(c)
+		if len(lines) == 0 {
+			appendKnot(khipu.NewVBox(h, i), nil)
+			continue
+		}
+		penalties := breakPenalties(c)
+		_, _, inside := breaksOf(c)
+		for k, line := range lines {
+			if k > 0 {
+				p := penalties[k-1]
+				if inside.Avoids() {
+					p += avoidPenalty
+				}
+				appendKnot(khipu.Penalty(p), lines[k-1])
+			}
+			appendKnot(khipu.NewVBox(lineHeight(line), i), nil)
+			h -= lineHeight(line)
+		}
+		if h > 0 { // space of the paragraph below its lines
+			appendKnot(khipu.NewVBox(h, i), nil)
+		}
+	}
+	knuthplass.EndVList(vl, nil)
+	params := knuthplass.NewKPPageParameters(heights.LineLength(1) / 2)
+	marks, err := knuthplass.BreakPagesContext(ctx, khipu.NewCursor(vl), heights, params)
+	if err != nil {
+		return flow, nil, err
+	}
+	plan := make(pagePlan)
+	for _, mark := range marks {
+		if pos := mark.Position(); pos < int64(len(breakAfter)) && breakAfter[pos] != nil {
+			plan[breakAfter[pos]] = true
+		}
+	}
+	tracer().Debugf("paginator: planned %d page breaks for flow '%s'", len(plan), flowname)
+	return flow, plan, nil
+}
+
+// expandFlow replaces blocks of flow which are higher than h by their children,
+// recursively, as far as they may be broken between them.
+func expandFlow(flow []*frame.Container, router *frame.FlowRouter, h dimen.DU) []*frame.Container {
+	var expanded []*frame.Container
+	for _, c := range flow {
+		var ch dimen.DU
+		c.CSSBox().H.Match().Just(&ch)
+		if ch > h {
+			if children := blockChildren(c, router, true); len(children) > 0 {
+				expanded = append(expanded, expandFlow(children, router, h)...)
+				continue
+			}
+		}
+		expanded = append(expanded, c)
+	}
+	return expanded
+}
+
+// fillRegionPlanned places containers from flow into rbox, one below the other,
+// up to the next planned page break. A paragraph is broken after the line the
+// plan breaks at. Containers are placed even if they overflow the region.
+// fillRegionPlanned returns the remaining flow and the forced
+// break which ended the region, if any.
+func fillRegionPlanned(rbox *RegionBox, flow []*frame.Container, plan pagePlan) ([]*frame.Container, css.BreakT) {
+	y := rbox.Frame.TopL.Y
+	for i, c := range flow {
+		if lines := linesOf(c); len(lines) > 1 {
+			for k, line := range lines[:len(lines)-1] {
+				if plan[line] {
+					cont := splitAfterLine(c, k+1)
+					plan[cont] = plan[c] // a break after the paragraph moves to its continuation
+					rest := append([]*frame.Container{cont}, flow[i+1:]...)
+					placeInRegion(rbox, c, y)
+					return rest, css.BreakAuto
+				}
+			}
+		}
+		var h dimen.DU
+		c.CSSBox().H.Match().Just(&h)
+		placeInRegion(rbox, c, y)
+		y += h
+		if i+1 < len(flow) {
+			_, after, _ := breaksOf(c)
+			before, _, _ := breaksOf(flow[i+1])
+			if before.IsForced() {
+				return flow[i+1:], before
+			} else if after.IsForced() {
+				return flow[i+1:], after
+			} else if plan[c] {
+				return flow[i+1:], css.BreakAuto
+			}
+		}
+	}
+	return nil, css.BreakAuto
+}
+
+// regionHeights is a linebreak.ParShape for breaking a flow into pages: the
+// height of page n is the height of the first region of page n consuming the
+// flow. Blank pages inserted for forced breaks to left or right pages are not
+// accounted for.
+type regionHeights struct {
+	pg   *Paginator
+	flow string
+}
+
+func (rh regionHeights) LineLength(n int32) dimen.DU {
+	template := rh.pg.Templates(int(n))
+	if template == nil {
+		return 0
+	}
+	content := rh.pg.Model.ContentAreaOf(template.Name, int(n))
+	for _, region := range template.Regions {
+		if region.Flow == rh.flow {
+			return regionFrame(content, region.Area).Height()
+		}
+	}
+	return content.Height()
+}
@@ -0,0 +1,298 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/spans"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+// ErrNoRegionForFlow is flagged if content of a named flow remains, but no page
+// template provides a region to consume it.
+var ErrNoRegionForFlow error = errors.New("no region consumes content of flow")
+
+// Region is a box on a page template which consumes content from a named flow,
+// e.g. "main", "sidebar" or "margin". Area is relative to the content area of
+// the page; an empty area denotes the complete content area.
+type Region struct {
+	Name string
+	Flow string
+	Area dimen.Rect
+}
+
+// PageTemplate is a template for pages, defining regions for named flows.
+// The name of a template is the page name for @page rules, i.e. pages created
+// from a template named "chapter" are subject to rules `@page chapter { … }`.
+type PageTemplate struct {
+	Name    string
+	Regions []Region
+}
+
+// RegionBox is a region of a concrete page, together with the containers from
+// its flow which have been placed into it.
+type RegionBox struct {
+	Region
+	Frame   dimen.Rect         // area of the region on the page
+	Content []*frame.Container // containers placed into the region
+}
+
+// TemplateSelector returns the page template for page number n.
+type TemplateSelector func(n int) *PageTemplate
+
+// SpreadTemplates returns a template selector which alternates between a
+// template for left pages and one for right pages.
+func SpreadTemplates(pm *PageModel, left, right *PageTemplate) TemplateSelector {
+	return func(n int) *PageTemplate {
+		if pm.Side(n) == LeftPage {
+			return left
+		}
+		return right
+	}
+}
+
+// Paginator distributes the content of named flows onto pages. Pages are created
+// from page templates, and every region of a page consumes containers from the
+// flow it is connected to, as long as they fit. Pages are created until all flows
+// are exhausted.
+//
+// By default, pages are filled first-fit, i.e. every region takes as much content
+// as fits. If Optimal is set, page breaks for the main flow are chosen to be
+// optimal for the flow as a whole, balancing the fill of all pages against
+// penalties for breaks.
+type Paginator struct {
+	Model     *PageModel
+	Templates TemplateSelector
+	Optimal   bool // break the main flow optimally, instead of first-fit
+}
+
+// NewPaginator creates a paginator for a page model, using page templates
+// selected by templates.
+func NewPaginator(pm *PageModel, templates TemplateSelector) *Paginator {
+	return &Paginator{Model: pm, Templates: templates}
+}
+
+// Paginate distributes the flows of router onto pages. Containers have to have
+// their heights fixed, i.e. they must have been laid out already.
+//
+// Page breaks honor the properties break-before, break-after and break-inside,
+// and keep headings with the block following them. Avoided breaks are
+// translated into penalties: when a region is full, it ends at the least
+// penalized break between the blocks placed into it. A block avoiding breaks
+// inside is broken only if it is too high for an empty region. Paragraphs are
+// broken between lines, leaving at least `orphans` lines at the
+// bottom of a region and `widows` lines at the top of the next one. These are
+// translated into penalties for breaks between lines, such that a paragraph too
+// high for an empty region is broken at the least penalized line. Other blocks
+// are broken between their block-level children. A container too high for an
+// empty region which cannot be broken is placed nevertheless and will overflow.
+//
+// If pg.Optimal is set, the page breaks of the main flow are planned in advance,
+// using the same penalties (see planPageBreaks). Planning assumes that every page
+// has a single region for the main flow; blank pages left for forced breaks to
+// left or right pages are not accounted for.
+//
+// If a page has been created where no region consumes any content, but content
+// remains, ErrNoRegionForFlow is returned together with the pages created so far.
+func (pg *Paginator) Paginate(router *frame.FlowRouter) ([]*Page, error) {
+	return pg.PaginateContext(context.Background(), router)
+}
+
+// PaginateContext is like Paginate, but records a trace span for every page as a
+// child of a span contained in ctx (see package core/spans). If ctx is cancelled or
+// its deadline is exceeded, pagination stops and the error of ctx is returned,
+// together with the pages created so far.
+func (pg *Paginator) PaginateContext(ctx context.Context, router *frame.FlowRouter) ([]*Page, error) {
+	queues := make(map[string][]*frame.Container)
+	for _, name := range router.Names() {
+		queues[name] = append([]*frame.Container(nil), router.Flow(name)...)
+	}
+	var plan pagePlan
+	if flow := queues[frame.MainFlow]; pg.Optimal && len(flow) > 0 {
+		var err error
+		if queues[frame.MainFlow], plan, err = pg.planPageBreaks(ctx, frame.MainFlow, flow, router); err != nil {
+			return nil, err
+		}
+	}
+	pending := make(map[string]css.BreakT) // forced breaks to left or right pages
+	var pages []*Page
+	for n := 1; !exhausted(queues); n++ {
+		if err := ctx.Err(); err != nil {
+			return pages, err
+		}
+		template := pg.Templates(n)
+		if template == nil {
+			return pages, fmt.Errorf("no page template for page %d", n)
+		}
+		_, span := spans.Start(ctx, spans.Page, "layout.Page")
+		span.SetAttribute("page", n)
+		page := NewNamedPageFor(pg.Model, template.Name, n)
+		page.Template = template
+		progress := false
+		for _, region := range template.Regions {
+			rbox := &RegionBox{Region: region, Frame: regionFrame(page.Content, region.Area)}
+			page.Regions = append(page.Regions, rbox)
+			if wrongSide(pending[region.Flow], page.Side) {
+				progress = true // leave a blank page
+				continue
+			}
+			var rest []*frame.Container
+			var forced css.BreakT
+			var err error
+			if plan != nil && region.Flow == frame.MainFlow {
+				rest, forced = fillRegionPlanned(rbox, queues[region.Flow], plan)
+			} else if rest, forced, err = fillRegion(rbox, queues[region.Flow], router); err != nil {
+				span.RecordError(err)
+				span.End()
+				return pages, err
+			}
+			progress = progress || len(rest) < len(queues[region.Flow]) || len(rbox.Content) > 0
+			queues[region.Flow], pending[region.Flow] = rest, forced
+		}
+		pages = append(pages, page)
+		span.End()
+		tracer().Debugf("paginator: page %d (%s) from template '%s'", n, page.Side, template.Name)
+		if !progress {
+			return pages, fmt.Errorf("%w: page %d", ErrNoRegionForFlow, n)
+		}
+	}
+	return pages, nil
+}
+
+// fillRegion places containers from flow into rbox, one below the other, as long
+// as they fit or until a forced break occurs. A paragraph not fitting completely
+// is broken between lines, if possible. Any other block not fitting completely
+// is replaced by its children, which are then placed one by one.
+// fillRegion returns the remaining flow and the forced break which ended the
+// region, if any.
+func fillRegion(rbox *RegionBox, flow []*frame.Container, router *frame.FlowRouter) ([]*frame.Container, css.BreakT, error) {
+	y := rbox.Frame.TopL.Y
+	for i := 0; i < len(flow); i++ {
+		c := flow[i]
+		before, after, _ := breaksOf(c)
+		if i > 0 && before.IsForced() {
+			return flow[i:], before, nil
+		}
+		var h dimen.DU
+		if c.CSSBox().H.Match().Just(&h) == nil {
+			return flow[i:], css.BreakAuto, ErrHeightNotFixed
+		}
+		if y+h > rbox.Frame.BotR.Y {
+			if k := linesFitting(c, rbox.Frame.BotR.Y-y, len(rbox.Content) == 0); k > 0 {
+				rest := append([]*frame.Container{splitAfterLine(c, k)}, flow[i+1:]...)
+				placeInRegion(rbox, c, y)
+				return rest, css.BreakAuto, nil
+			}
+			if children := blockChildren(c, router, len(rbox.Content) == 0); len(children) > 0 {
+				flow = spliceChildren(flow, i, children)
+				i--
+				continue
+			}
+			if i > 0 { // region is full
+				j := leastPenalizedBreak(flow, i)
+				rbox.Content = rbox.Content[:j]
+				return flow[j:], css.BreakAuto, nil
+			}
+		}
+		placeInRegion(rbox, c, y)
+		y += h
+		if after.IsForced() {
+			return flow[i+1:], after, nil
+		}
+	}
+	return nil, css.BreakAuto, nil
+}
+
+// blockChildren returns the children of a block container c, if c may be broken
+// between them. Multi-column containers are not broken. A block avoiding breaks
+// inside is broken only if the region is
+// empty. Children routed into a flow of their own are left out, as they will be
+// consumed from their flow.
+func blockChildren(c *frame.Container, router *frame.FlowRouter, empty bool) []*frame.Container {
+	if _, multicol := c.Context.(*MulticolContext); multicol || linesOf(c) != nil {
+		return nil
+	}
+	if _, _, inside := breaksOf(c); inside.Avoids() && !empty {
+		return nil
+	}
+	var children []*frame.Container
+	for _, ch := range c.TreeNode().Children(true) {
+		if ch.Payload == nil || router.IsRouted(ch.Payload) {
+			continue
+		}
+		children = append(children, ch.Payload)
+	}
+	return children
+}
+
+// spliceChildren replaces flow[i] by children, leaving flow untouched.
+func spliceChildren(flow []*frame.Container, i int, children []*frame.Container) []*frame.Container {
+	spliced := make([]*frame.Container, 0, len(flow)-1+len(children))
+	spliced = append(spliced, flow[:i]...)
+	spliced = append(spliced, children...)
+	return append(spliced, flow[i+1:]...)
+}
+
+func placeInRegion(rbox *RegionBox, c *frame.Container, y dimen.DU) {
+	c.CSSBox().TopL = dimen.Point{X: rbox.Frame.TopL.X, Y: y}
+	rbox.Content = append(rbox.Content, c)
+}
+
+// leastPenalizedBreak finds the break between flow[j-1] and flow[j], 0 < j ≤ i,
+// with the least penalty (see blockBreakPenalty), for a region which is full
+// before flow[i]. Of breaks with equal penalties, the latest one is chosen.
+func leastPenalizedBreak(flow []*frame.Container, i int) int {
+	best, penalty := i, blockBreakPenalty(flow[i-1], flow[i])
+	for j := i - 1; j > 0 && penalty > 0; j-- {
+		if p := blockBreakPenalty(flow[j-1], flow[j]); p < penalty {
+			best, penalty = j, p
+		}
+	}
+	return best
+}
+
+// blockBreakPenalty returns the penalty for a page break between blocks prev and
+// next. Either block may ask to avoid the break, adding avoidPenalty each.
+func blockBreakPenalty(prev, next *frame.Container) int {
+	penalty := 0
+	if _, after, _ := breaksOf(prev); after.Avoids() || isHeading(prev) {
+		penalty += avoidPenalty
+	}
+	if before, _, _ := breaksOf(next); before.Avoids() {
+		penalty += avoidPenalty
+	}
+	return penalty
+}
+
+// isHeading returns true if c has been created for a heading, h1…h6.
+func isHeading(c *frame.Container) bool {
+	return c.DOMNode() != nil && headingLevel(c.DOMNode()) > 0
+}
+
+// wrongSide returns true if a forced break requires a page of the opposite side.
+func wrongSide(b css.BreakT, side PageSide) bool {
+	return (b == css.BreakLeft && side != LeftPage) || (b == css.BreakRight && side != RightPage)
+}
+
+// regionFrame positions a region's area within the content area of a page.
+func regionFrame(content, area dimen.Rect) dimen.Rect {
+	if area.Width() == 0 || area.Height() == 0 {
+		return content
+	}
+	return dimen.Rect{
+		TopL: dimen.Point{X: content.TopL.X + area.TopL.X, Y: content.TopL.Y + area.TopL.Y},
+		BotR: dimen.Point{X: content.TopL.X + area.BotR.X, Y: content.TopL.Y + area.BotR.Y},
+	}
+}
+
+func exhausted(queues map[string][]*frame.Container) bool {
+	for _, flow := range queues {
+		if len(flow) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,333 @@
+package layout
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"golang.org/x/net/html"
+)
+
+func TestPaginateFlows(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	pm := NewPageModel(dimen.Point{X: 100 * dimen.PT, Y: 100 * dimen.PT})
+	pm.SetMargins("", [4]css.DimenT{
+		css.JustDimen(10 * dimen.PT), css.JustDimen(10 * dimen.PT),
+		css.JustDimen(10 * dimen.PT), css.JustDimen(10 * dimen.PT),
+	})
+	withSidebar := &PageTemplate{Name: "sidebar", Regions: []Region{
+		{Name: "body", Flow: frame.MainFlow, Area: dimen.Rect{BotR: dimen.Point{X: 50 * dimen.PT, Y: 80 * dimen.PT}}},
+		{Name: "aside", Flow: "sidebar", Area: dimen.Rect{
+			TopL: dimen.Point{X: 60 * dimen.PT},
+			BotR: dimen.Point{X: 80 * dimen.PT, Y: 80 * dimen.PT},
+		}},
+	}}
+	plain := &PageTemplate{Name: "plain", Regions: []Region{{Name: "body", Flow: frame.MainFlow}}}
+	router := frame.NewFlowRouter()
+	for i := 0; i < 5; i++ {
+		router.Route(blockOfHeight(30*dimen.PT), frame.MainFlow)
+	}
+	aside := blockOfHeight(20 * dimen.PT)
+	router.Route(aside, "sidebar")
+	pg := NewPaginator(pm, SpreadTemplates(pm, plain, withSidebar))
+	pages, err := pg.Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 5 blocks to be distributed onto 3 pages, have %d pages", len(pages))
+	}
+	if len(pages[0].Regions) != 2 || len(pages[0].Regions[0].Content) != 2 || len(pages[1].Regions[0].Content) != 2 {
+		t.Errorf("expected 2 blocks per page")
+	}
+	if aside.CSSBox().TopL.X != 70*dimen.PT || pages[0].Regions[1].Content[0] != aside {
+		t.Errorf("expected sidebar content on page 1 at x=70pt, is at %v", aside.CSSBox().TopL)
+	}
+	router.Route(blockOfHeight(10*dimen.PT), "margin")
+	if _, err = pg.Paginate(router); !errors.Is(err, ErrNoRegionForFlow) {
+		t.Errorf("expected flow without region to be flagged, have %v", err)
+	}
+}
+
+func TestPaginateForcedAndAvoidedBreaks(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body>
+	<p>1</p>
+	<p style="break-before: page">2</p>
+	<p style="break-after: avoid">3</p>
+	<p>4</p>
+	<p style="break-before: right">5</p>
+	</body></html>`, 30*dimen.PT)
+	router := frame.NewFlowRouter()
+	for _, b := range blocks {
+		router.Route(b, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 5 {
+		t.Fatalf("expected 5 pages, have %d", len(pages))
+	}
+	expected := [][]*frame.Container{{blocks[0]}, {blocks[1]}, {blocks[2], blocks[3]}, nil, {blocks[4]}}
+	for i, page := range pages {
+		content := page.Regions[0].Content
+		if len(content) != len(expected[i]) {
+			t.Errorf("expected %d blocks on page %d, have %d", len(expected[i]), page.Number, len(content))
+			continue
+		}
+		for j, c := range content {
+			if c != expected[i][j] {
+				t.Errorf("unexpected block #%d on page %d", j, page.Number)
+			}
+		}
+	}
+}
+
+func TestPaginateWidowsAndOrphans(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	for _, tc := range []struct {
+		above        dimen.DU
+		lines, first int
+	}{{50 * dimen.PT, 6, 3}, {50 * dimen.PT, 4, 2}, {55 * dimen.PT, 3, 0}} {
+		router := frame.NewFlowRouter()
+		router.Route(blockOfHeight(tc.above), frame.MainFlow)
+		para := paragraphOfLines(tc.lines, 10*dimen.PT)
+		router.Route(para, frame.MainFlow)
+		pages, err := paginatorForTest().Paginate(router)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := 0
+		if content := pages[0].Regions[0].Content; len(content) == 2 {
+			first = len(linesOf(content[1]))
+		}
+		if first != tc.first || len(pages) != 2 {
+			t.Errorf("expected paragraph of %d lines to leave %d lines on page 1, have %d on %d pages",
+				tc.lines, tc.first, first, len(pages))
+			continue
+		}
+		cont := pages[1].Regions[0].Content[0]
+		var h dimen.DU
+		cont.CSSBox().H.Match().Just(&h)
+		if tc.first > 0 && (len(linesOf(cont)) != tc.lines-tc.first || h != dimen.DU(tc.lines-tc.first)*10*dimen.PT) {
+			t.Errorf("expected continuation of paragraph on page 2 with %d lines", tc.lines-tc.first)
+		}
+	}
+}
+
+func TestPaginateWidowInEmptyRegion(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	para := paragraphOfLines(3, 30*dimen.PT)
+	if p := breakPenalties(para); len(p) != 2 || p[0] != orphanPenalty || p[1] != widowPenalty {
+		t.Errorf("expected both breaks to be penalized, have %v", p)
+	}
+	router := frame.NewFlowRouter()
+	router.Route(para, frame.MainFlow)
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected paragraph too high for a page to be broken onto 2 pages, have %d", len(pages))
+	}
+	if n := len(linesOf(pages[0].Regions[0].Content[0])); n != 2 {
+		t.Errorf("expected 2 lines on page 1, leaving a widow, have %d", n)
+	}
+}
+
+func TestPaginateKeepTogether(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body>
+	<p>1</p>
+	<h2>2</h2>
+	<p>3</p>
+	<div style="break-inside: avoid"></div>
+	</body></html>`, 30*dimen.PT)
+	box := blocks[3]
+	box.CSSBox().H = css.JustDimen(120 * dimen.PT)
+	for i := 0; i < 3; i++ {
+		ch := blockOfHeight(40 * dimen.PT)
+		ch.Payload = ch
+		box.TreeNode().AddChild(ch.TreeNode())
+	}
+	router := frame.NewFlowRouter()
+	for _, b := range blocks {
+		router.Route(b, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 pages, have %d", len(pages))
+	}
+	if content := pages[0].Regions[0].Content; len(content) != 1 {
+		t.Errorf("expected heading to move to page 2 with its paragraph, page 1 has %d blocks", len(content))
+	}
+	if content := pages[1].Regions[0].Content; len(content) != 2 || content[0] != blocks[1] {
+		t.Errorf("expected heading and paragraph on page 2")
+	}
+	if content := pages[2].Regions[0].Content; len(content) != 2 {
+		t.Errorf("expected block too high for a page to be broken despite break-inside: avoid")
+	}
+}
+
+func TestPaginateOptimal(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	heights := []dimen.DU{20, 20, 20, 20, 45, 45, 10}
+	var blocks []*frame.Container
+	router := frame.NewFlowRouter()
+	for _, h := range heights {
+		b := blockOfHeight(h * dimen.PT)
+		blocks = append(blocks, b)
+		router.Route(b, frame.MainFlow)
+	}
+	pg := paginatorForTest()
+	pg.Optimal = true
+	pages, err := pg.Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, have %d", len(pages))
+	}
+	// first-fit would fill page 1 completely, leaving page 2 short by 35pt
+	if content := pages[0].Regions[0].Content; len(content) != 3 {
+		t.Errorf("expected 3 blocks on page 1, have %d", len(content))
+	}
+	if content := pages[1].Regions[0].Content; len(content) != 2 || content[0] != blocks[3] {
+		t.Errorf("expected blocks 4 and 5 on page 2")
+	}
+	if y := blocks[4].CSSBox().TopL.Y; y != 30*dimen.PT {
+		t.Errorf("expected block 5 at y=30pt, is at %v", y)
+	}
+}
+
+func TestPaginateBlockChildren(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	body := blockOfHeight(130 * dimen.PT)
+	var children []*frame.Container
+	for i := 0; i < 4; i++ {
+		ch := blockOfHeight(30 * dimen.PT)
+		ch.Payload = ch
+		body.TreeNode().AddChild(ch.TreeNode())
+		children = append(children, ch)
+	}
+	aside := blockOfHeight(10 * dimen.PT)
+	aside.Payload = aside
+	body.TreeNode().AddChild(aside.TreeNode())
+	router := frame.NewFlowRouter()
+	router.Route(body, frame.MainFlow)
+	router.Route(aside, "sidebar")
+	pages, err := paginatorForTest().Paginate(router)
+	if !errors.Is(err, ErrNoRegionForFlow) {
+		t.Fatalf("expected sidebar to remain unconsumed, have %v", err)
+	}
+	if len(pages) < 2 {
+		t.Fatalf("expected body to be broken onto 2 pages, have %d", len(pages))
+	}
+	first, second := pages[0].Regions[0].Content, pages[1].Regions[0].Content
+	if len(first) != 2 || first[0] != children[0] || first[1] != children[1] {
+		t.Errorf("expected first 2 children of body on page 1, have %d containers", len(first))
+	}
+	if len(second) != 2 || second[0] != children[2] || second[1] != children[3] {
+		t.Errorf("expected last 2 children of body on page 2, have %d containers", len(second))
+	}
+	if children[2].CSSBox().TopL.Y != 10*dimen.PT {
+		t.Errorf("expected child #3 at top of page 2, is at %v", children[2].CSSBox().TopL)
+	}
+}
+
+// --- Helpers ----------------------------------------------------------
+
+type testBlock struct {
+	box  *frame.Box
+	node *dom.W3CNode
+}
+
+func (b testBlock) DOMNode() *dom.W3CNode { return b.node }
+func (b testBlock) CSSBox() *frame.Box    { return b.box }
+func (b testBlock) PresetContained() bool { return false }
+
+func blockOfHeight(h dimen.DU) *frame.Container {
+	return blockFor(nil, h)
+}
+
+func blockFor(node *dom.W3CNode, h dimen.DU) *frame.Container {
+	box := &frame.Box{}
+	box.H = css.JustDimen(h)
+	c := frame.MakeContainer(testBlock{box: box, node: node})
+	return &c
+}
+
+// paginatorForTest paginates onto pages of 100×100pt with margins of 10pt.
+func paginatorForTest() *Paginator {
+	pm := NewPageModel(dimen.Point{X: 100 * dimen.PT, Y: 100 * dimen.PT})
+	m := css.JustDimen(10 * dimen.PT)
+	pm.SetMargins("", [4]css.DimenT{m, m, m, m})
+	plain := &PageTemplate{Name: "plain", Regions: []Region{{Name: "body", Flow: frame.MainFlow}}}
+	return NewPaginator(pm, SpreadTemplates(pm, plain, plain))
+}
+
+// styledBlocks creates a block of height h for every element child of <body>.
+func styledBlocks(t *testing.T, hh string, h dimen.DU) []*frame.Container {
+	doc, err := html.Parse(strings.NewReader(hh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body *dom.W3CNode
+	for n := dom.FromHTMLParseTree(doc, nil).FirstChild(); n != nil; {
+		node := n.(*dom.W3CNode)
+		if node.NodeName() == "body" {
+			body = node
+			break
+		}
+		if n = node.FirstChild(); n == nil {
+			n = node.NextSibling()
+		}
+	}
+	if body == nil {
+		t.Fatal("test document has no body")
+	}
+	var blocks []*frame.Container
+	children := body.Children()
+	for i := 0; i < children.Length(); i++ {
+		if node := children.Item(i).(*dom.W3CNode); node != nil {
+			blocks = append(blocks, blockFor(node, h))
+		}
+	}
+	return blocks
+}
+
+// paragraphOfLines creates a paragraph container with n lines of height h.
+func paragraphOfLines(n int, h dimen.DU) *frame.Container {
+	para := blockOfHeight(dimen.DU(n) * h)
+	ctx := NewInlineContext(para, false)
+	for i := 0; i < n; i++ {
+		line := blockOfHeight(h)
+		line.CSSBox().TopL.Y = dimen.DU(i) * h
+		ctx.lines = append(ctx.lines, line)
+	}
+	para.Context = ctx
+	return para
+}
@@ -0,0 +1,76 @@
+package layout
+
+import (
+	"image/color"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/locate/resources"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+// --- Box decorations -------------------------------------------------------
+
+// Decoration is the background and border of a box placed on a page, as a
+// primitive for backends. Backends paint decorations in order, before the
+// content of a page.
+type Decoration struct {
+	frame.PaintArea                    // border box, padding box and border widths
+	Background      color.Color        // background color, nil for transparent
+	Image           *resources.Picture // background image, drawn at the top left of the padding box
+	BorderColor     color.Color        // color of all sides of the border
+	LineStyle       frame.LineStyle    // line style of all sides of the border
+	Radius          dimen.DU           // radius of rounded corners of the border box
+	Node            *dom.W3CNode       // element of the box
+}
+
+// PaintDecorations finds the backgrounds and borders of boxes placed on paginated
+// pages and records them with their pages (see Page.Decorations), in painting
+// order, i.e. enclosing boxes precede their children. Boxes without a background
+// or a visible border are skipped, as are continuation fragments of paragraphs
+// broken across pages.
+//
+// Background images are loaded once per URL. Images which cannot be loaded are
+// left out, keeping the background color.
+func PaintDecorations(pages []*Page) {
+	pictures := make(map[string]*resources.Picture)
+	for _, page := range pages {
+		page.Decorations = nil
+	}
+	walkPages(pages, func(page *Page, c *frame.Container, pos dimen.Point) bool {
+		pbox, ok := c.RenderNode().(*boxtree.PrincipalBox)
+		if !ok || pbox.Box == nil || pbox.Box.Styles == nil {
+			return true
+		}
+		// pos is the position of TopL on the page, PaintArea wants its origin
+		origin := dimen.Point{X: pos.X - pbox.Box.TopL.X, Y: pos.Y - pbox.Box.TopL.Y}
+		deco := &Decoration{
+			PaintArea:  pbox.Box.PaintArea(origin),
+			Background: pbox.Box.Styles.Colors.Background,
+			Radius:     pbox.Box.Styles.Border.CornerRadius,
+			LineStyle:  frame.LineStyle(pbox.Box.Styles.Border.LineStyle),
+			Node:       c.DOMNode(),
+		}
+		if deco.HasBorder() {
+			if deco.BorderColor = pbox.Box.Styles.Border.LineColor; deco.BorderColor == nil {
+				deco.BorderColor = color.Black
+			}
+		}
+		if url := pbox.Box.Styles.Colors.BackgroundImage; url != "" {
+			if _, ok := pictures[url]; !ok {
+				pic, err := resources.ResolvePicture(url).Picture()
+				if err != nil {
+					tracer().Errorf("background image %s: %v", url, err)
+				}
+				pictures[url] = pic
+			}
+			deco.Image = pictures[url]
+		}
+		if deco.Background != nil || deco.Image != nil || deco.BorderColor != nil {
+			page.Decorations = append(page.Decorations, deco)
+		}
+		page.Decorations = append(page.Decorations, columnRules(c, pos)...)
+		return true
+	})
+}
@@ -0,0 +1,58 @@
+package layout
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+func TestPaintDecorations(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body><div></div><p></p></body></html>`, 0)
+	router := frame.NewFlowRouter()
+	for i, b := range blocks {
+		pbox := boxtree.NewPrincipalBox(b.DOMNode(), css.BlockMode)
+		box := pbox.CSSBox()
+		box.W, box.H = css.JustDimen(60*dimen.PT), css.JustDimen(20*dimen.PT)
+		if i == 0 { // second block has no styles and should not be decorated
+			for dir := frame.Top; dir <= frame.Left; dir++ {
+				box.BorderWidth[dir] = css.JustDimen(2 * dimen.PT)
+			}
+			box.Margins[frame.Left] = css.JustDimen(5 * dimen.PT)
+			pbox.Box.Styles = &frame.Styling{
+				Colors: frame.ColorStyle{Background: color.White},
+				Border: frame.BorderStyle{LineStyle: int8(frame.LSDashed), CornerRadius: 3 * dimen.PT},
+			}
+		}
+		router.Route(&pbox.Container, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PaintDecorations(pages)
+	if len(pages) == 0 || len(pages[0].Decorations) != 1 {
+		t.Fatalf("expected first page to have 1 decoration")
+	}
+	deco := pages[0].Decorations[0]
+	border := dimen.Rect{
+		TopL: dimen.Point{X: 15 * dimen.PT, Y: 10 * dimen.PT},
+		BotR: dimen.Point{X: 79 * dimen.PT, Y: 34 * dimen.PT},
+	}
+	if deco.Border != border {
+		t.Errorf("expected border box %v, is %v", border, deco.Border)
+	}
+	if deco.Padding.TopL != (dimen.Point{X: 17 * dimen.PT, Y: 12 * dimen.PT}) {
+		t.Errorf("expected padding box to start within border, is at %v", deco.Padding.TopL)
+	}
+	if deco.BorderColor != color.Black || deco.LineStyle != frame.LSDashed || deco.Radius != 3*dimen.PT {
+		t.Errorf("expected dashed black border with rounded corners, have %+v", deco)
+	}
+}
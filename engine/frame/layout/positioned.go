@@ -0,0 +1,107 @@
+package layout
+
+import (
+	"errors"
+
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+// ErrContainingBlockNotFixed is flagged if an out-of-flow box is to be positioned
+// against a containing block with unknown dimensions.
+var ErrContainingBlockNotFixed error = errors.New("containing block not fixed")
+
+// positionOf returns the computed CSS position of container c, including its
+// offsets.
+func positionOf(c *frame.Container) css.PositionT {
+	if c == nil || c.DOMNode() == nil {
+		return css.PositionT{}
+	}
+	styles := c.DOMNode().ComputedStyles()
+	pos := css.Position(styles.GetPropertyValue("position"))
+	offsets := css.PositionOffsets(styles.GetPropertyValue("top"), styles.GetPropertyValue("right"),
+		styles.GetPropertyValue("bottom"), styles.GetPropertyValue("left"))
+	switch {
+	case pos.IsAbsolute():
+		return css.Absolute(offsets)
+	case pos.IsFixed():
+		return css.Fixed(offsets)
+	case pos.IsRelative():
+		return css.Relative(offsets)
+	}
+	return pos
+}
+
+func isOutOfFlow(pos css.PositionT) bool {
+	return pos.IsAbsolute() || pos.IsFixed()
+}
+
+// layoutOutOfFlow is the second layout pass. After the boxes of the normal flow
+// have been laid out, absolutely positioned and fixed boxes in the tree below c
+// are sized and positioned against their containing blocks. Out-of-flow boxes
+// have not taken part in the layout of their siblings.
+//
+// Positions of out-of-flow boxes are relative to the padding box of the
+// ancestor establishing their containing block, or to the viewport.
+func layoutOutOfFlow(c *frame.Container, view *View) error {
+	for _, ch := range c.TreeNode().Children(true) {
+		sub := ch.Payload
+		if pos := positionOf(sub); isOutOfFlow(pos) {
+			if err := layoutPositioned(sub, pos, view); err != nil {
+				return err
+			}
+		}
+		if err := layoutOutOfFlow(sub, view); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layoutPositioned sizes an out-of-flow container c and positions it against its
+// containing block.
+func layoutPositioned(c *frame.Container, pos css.PositionT, view *View) error {
+	cb, err := containingBlock(c, pos, view)
+	if err != nil {
+		return err
+	}
+	offsets := cb.Resolve(pos.Offsets())
+	w, _ := cb.AvailableWidth(offsets)
+	params := inheritedParams{
+		W:    css.JustDimen(w),
+		MaxW: w,
+		view: view,
+	}
+	syn := CalcBlockWidths(c, params)
+	if syn.lastErr != nil {
+		return syn.lastErr
+	}
+	static := c.CSSBox().TopL // position within the normal flow, if any
+	c.CSSBox().TopL = cb.Place(offsets, syn.W, syn.H, static)
+	tracer().Debugf("out-of-flow box [%s] placed at %v", boxtree.ContainerName(c), c.CSSBox().TopL)
+	return nil
+}
+
+// containingBlock finds the containing block for an out-of-flow container c
+// (CSS 2.1 §10.1): for position `fixed` this is the viewport, for position
+// `absolute` it is the padding box of the nearest positioned ancestor. If there
+// is none, the initial containing block, i.e. the viewport, is used.
+func containingBlock(c *frame.Container, pos css.PositionT, view *View) (frame.ContainingBlock, error) {
+	viewport := frame.ContainingBlock{W: view.Width, H: view.Height}
+	if pos.IsFixed() {
+		return viewport, nil
+	}
+	for n := c.TreeNode().Parent(); n != nil; n = n.Parent() {
+		anc := n.Payload
+		if p := positionOf(anc); p.IsUnset() || p.IsStatic() {
+			continue
+		}
+		cb, ok := frame.PaddingBox(anc.CSSBox())
+		if !ok {
+			return cb, ErrContainingBlockNotFixed
+		}
+		return cb, nil
+	}
+	return viewport, nil
+}
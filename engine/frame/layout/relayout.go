@@ -0,0 +1,260 @@
+package layout
+
+import (
+	"errors"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+// --- Incremental re-layout -------------------------------------------------
+
+// Damage reports the containers of a box tree which have been laid out again by
+// Relayout.
+type Damage struct {
+	Relaid    []*frame.Container // containers laid out again because of a change
+	Restacked []*frame.Container // ancestors whose children have been positioned again
+	Resized   bool               // the height of the layout root has changed
+}
+
+// IsEmpty is true if no container has been laid out again.
+func (d *Damage) IsEmpty() bool {
+	return d == nil || len(d.Relaid) == 0
+}
+
+// Contains is true if container c has been affected by a re-layout, i.e. if c, an
+// ancestor or a descendant of c has been laid out again. Fragments of a container
+// created by pagination are affected if the container is.
+func (d *Damage) Contains(c *frame.Container) bool {
+	if d == nil || c == nil {
+		return false
+	}
+	for _, r := range d.Relaid {
+		if r == c || isAncestor(r, c) || isAncestor(c, r) ||
+			(r.DOMNode() != nil && r.DOMNode() == c.DOMNode()) {
+			return true
+		}
+	}
+	return false
+}
+
+// relaid is true if c or an ancestor of c has been laid out again.
+func (d *Damage) relaid(c *frame.Container) bool {
+	for _, r := range d.Relaid {
+		if r == c || isAncestor(r, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Relayout lays out the parts of box tree boxRoot again which are affected by
+// changes of DOM nodes changed, e.g. after a user has edited a document. The
+// box tree must have been laid out with Layout before, and boxes must already
+// reflect the changes (i.e., styles and text of boxes must be up to date).
+//
+// For every changed node, the nearest container with a formatting context is
+// laid out again, with paragraphs broken into lines anew. If its height changes,
+// its ancestors position their children again, up to the first ancestor keeping
+// its height. Containers outside these paths are left untouched. If a container
+// shares its flow root with floats, the complete flow root is laid out again, as
+// floats may have moved.
+//
+// Relayout returns the damage done to the layout, which clients may use to find
+// the pages to paginate again (see FirstDamagedPage).
+func Relayout(boxRoot *boxtree.PrincipalBox, view *View, changed ...*dom.W3CNode) (*Damage, error) {
+	if boxRoot == nil || view == nil {
+		return nil, errors.New("illegal arguments: box root or view is void")
+	}
+	root := &boxRoot.Container
+	damage := &Damage{}
+	if root.Context == nil { // not laid out yet
+		damage.Relaid, damage.Resized = []*frame.Container{root}, true
+		return damage, Layout(boxRoot, view)
+	}
+	for _, c := range dirtyContainers(root, changed) {
+		if damage.relaid(c) {
+			continue // already laid out again as part of a flow root
+		}
+		if err := relayoutContainer(c, root, view, damage); err != nil {
+			return damage, err
+		}
+	}
+	tracer().Debugf("re-layout of %d changed nodes affected %d containers", len(changed),
+		len(damage.Relaid)+len(damage.Restacked))
+	return damage, nil
+}
+
+// relayoutContainer lays out container c again and propagates a change of its
+// height to its ancestors.
+func relayoutContainer(c, root *frame.Container, view *View, damage *Damage) error {
+	if fr, owner := enclosingFlowRoot(c); fr != nil && owner != c && len(fr.Placer.Floats()) > 0 {
+		c = owner // floats may move, lay out the complete flow root again
+	}
+	if c == root {
+		damage.Relaid = append(damage.Relaid, root)
+		resetWidths(root)
+		syn := BoxTreeToLayoutTree(root.RenderNode().(*boxtree.PrincipalBox), view)
+		damage.Resized = true
+		return syn.lastErr
+	}
+	tracer().Debugf("re-layout of [%s]", boxtree.ContainerName(c))
+	old := heightOf(c)
+	parent := parentContainer(c)
+	if hasAutoWidth(c) {
+		var w dimen.DU
+		if parent.CSSBox().W.Match().Just(&w) == nil {
+			return ErrEnclosingWidthNotFixed
+		}
+		c.CSSBox().W = css.Auto()
+		if _, err := frame.FixDimensionsFromEnclosingWidth(c.CSSBox(), w); err != nil {
+			return err
+		}
+	}
+	resetFlowRoot(c)
+	fr, _ := enclosingFlowRoot(c)
+	if err := layoutContents(c, fr); err != nil {
+		return err
+	}
+	damage.Relaid = append(damage.Relaid, c)
+	for h := heightOf(c); h != old && parent != nil; parent = parentContainer(parent) {
+		if parent.Context == nil {
+			continue
+		}
+		old = heightOf(parent)
+		fr, _ := enclosingFlowRoot(parent)
+		if err := parent.Context.Layout(fr); err != nil {
+			return err
+		}
+		damage.Restacked = append(damage.Restacked, parent)
+		h = heightOf(parent)
+		if parent == root && h != old {
+			damage.Resized = true
+		}
+	}
+	return nil
+}
+
+// dirtyContainers returns the containers to lay out again for changed nodes:
+// for every container generated for a changed node, the nearest container
+// (itself or an ancestor) with a formatting context. Containers contained in
+// others to lay out again are omitted.
+func dirtyContainers(root *frame.Container, changed []*dom.W3CNode) []*frame.Container {
+	nodes := make(map[*dom.W3CNode]bool, len(changed))
+	for _, n := range changed {
+		nodes[n] = true
+	}
+	var dirty []*frame.Container
+	var walk func(c *frame.Container)
+	walk = func(c *frame.Container) {
+		if c.DOMNode() != nil && nodes[c.DOMNode()] {
+			for c != root && (c.Context == nil || boxtree.IsText(c.RenderNode())) {
+				c = parentContainer(c)
+			}
+			dirty = append(dirty, c)
+			return
+		}
+		for _, ch := range c.TreeNode().Children(true) {
+			if ch.Payload != nil {
+				walk(ch.Payload)
+			}
+		}
+	}
+	walk(root)
+	var topmost []*frame.Container
+	for _, c := range dirty {
+		if containsContainer(topmost, c) || hasAncestorIn(dirty, c) {
+			continue
+		}
+		topmost = append(topmost, c)
+	}
+	return topmost
+}
+
+// FirstDamagedPage returns the index of the first page in pages containing a
+// container affected by a re-layout, or -1 if no page is affected. As positions
+// of subsequent content may change, pages from this index on have to be
+// paginated again; pages before it may be kept.
+func FirstDamagedPage(pages []*Page, damage *Damage) int {
+	if damage.IsEmpty() {
+		return -1
+	}
+	for i, page := range pages {
+		for _, rbox := range page.Regions {
+			for _, c := range rbox.Content {
+				if damage.Contains(c) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// --- Helpers ---------------------------------------------------------------
+
+// enclosingFlowRoot returns the flow root floats of c are placed into, together
+// with the container establishing it.
+func enclosingFlowRoot(c *frame.Container) (*frame.FlowRoot, *frame.Container) {
+	for ; c != nil; c = parentContainer(c) {
+		if c.Context != nil && c.Context.FlowRoot() != nil {
+			return c.Context.FlowRoot(), c
+		}
+	}
+	return nil, nil
+}
+
+// resetFlowRoot discards the floats placed into the flow root c establishes, if
+// any, as they will be placed again.
+func resetFlowRoot(c *frame.Container) {
+	if c.Context == nil || c.Context.FlowRoot() == nil {
+		return
+	}
+	if ctx, isbase := c.Context.(interface{ SetFlowRoot(*frame.FlowRoot) }); isbase {
+		ctx.SetFlowRoot(flowRootFor(c.Context, nil))
+	}
+}
+
+func parentContainer(c *frame.Container) *frame.Container {
+	if p := c.TreeNode().Parent(); p != nil {
+		return p.Payload
+	}
+	return nil
+}
+
+// isAncestor is true if a is a proper ancestor of c.
+func isAncestor(a, c *frame.Container) bool {
+	for p := parentContainer(c); p != nil; p = parentContainer(p) {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAncestorIn(cs []*frame.Container, c *frame.Container) bool {
+	for _, a := range cs {
+		if isAncestor(a, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsContainer(cs []*frame.Container, c *frame.Container) bool {
+	for _, x := range cs {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
+func heightOf(c *frame.Container) dimen.DU {
+	var h dimen.DU
+	c.CSSBox().H.Match().Just(&h)
+	return h
+}
@@ -0,0 +1,57 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+func TestRelayoutDamage(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body><p>one</p><p>two</p><p>three</p></body></html>`, 30*dimen.PT)
+	body := blockOfHeight(90 * dimen.PT)
+	body.Payload = body
+	body.Context = NewBlockContext(body, true)
+	for _, b := range blocks {
+		b.Payload = b
+		b.Context = NewBlockContext(b, false)
+		body.TreeNode().AddChild(b.TreeNode())
+	}
+	two := blocks[1].DOMNode()
+	dirty := dirtyContainers(body, []*dom.W3CNode{two, two})
+	if len(dirty) != 1 || dirty[0] != blocks[1] {
+		t.Fatalf("expected second paragraph to be laid out again, have %v", dirty)
+	}
+	if dirty = dirtyContainers(body, []*dom.W3CNode{nil}); len(dirty) != 0 {
+		t.Errorf("expected anonymous boxes not to be matched, have %v", dirty)
+	}
+	damage := &Damage{Relaid: dirty}
+	if !damage.Contains(blocks[1]) || !damage.Contains(body) || damage.Contains(blocks[0]) {
+		t.Errorf("expected damage to contain paragraph 2 and its ancestors only")
+	}
+	router := frame.NewFlowRouter()
+	for _, b := range blocks {
+		router.Route(b, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected 3 paragraphs on 2 pages, have %d pages", len(pages))
+	}
+	if n := FirstDamagedPage(pages, damage); n != 0 {
+		t.Errorf("expected page 1 to be damaged, have %d", n)
+	}
+	if n := FirstDamagedPage(pages, &Damage{Relaid: blocks[2:]}); n != 1 {
+		t.Errorf("expected page 2 to be damaged, have %d", n)
+	}
+	if n := FirstDamagedPage(pages, &Damage{}); n != -1 {
+		t.Errorf("expected no damaged page, have %d", n)
+	}
+}
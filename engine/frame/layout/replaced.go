@@ -0,0 +1,173 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/locate/resources"
+	"github.com/npillmayer/tyse/core/percent"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+)
+
+// --- Replaced elements -----------------------------------------------------
+
+// sizeReplaced determines the size of a replaced element, e.g. an image, from
+// its intrinsic size and CSS properties width, height and max-width (CSS 2.1
+// §10.3.2, §10.4 and §10.6.2). A dimension not given by CSS is calculated from
+// the intrinsic aspect ratio, if the other one is given. HTML attributes width
+// and height are honoured as presentational hints, in pixels.
+//
+// Images which cannot be loaded have no intrinsic size, i.e. they take up space
+// only if sized by CSS.
+func sizeReplaced(c *frame.Container, inherited inheritedParams) (syn synthesizedParams) {
+	pbox := c.RenderNode().(*boxtree.PrincipalBox)
+	var intrinsic dimen.Point
+	var ratio float64
+	if pic, err := pbox.Picture.Picture(); err != nil {
+		tracer().Errorf("replaced element <%s>: %v", c.DOMNode().NodeName(), err)
+	} else {
+		intrinsic, ratio = pic.Size, pic.AspectRatio()
+	}
+	var enclosing dimen.DU
+	inherited.W.Match().Just(&enclosing)
+	box := c.CSSBox()
+	w, hasW := replacedLength(box.W, c.DOMNode(), "width", enclosing)
+	h, hasH := replacedLength(box.H, c.DOMNode(), "height", -1)
+	switch {
+	case hasW && hasH:
+	case hasW && ratio > 0:
+		h = dimen.DU(float64(w) / ratio)
+	case hasW:
+		h = intrinsic.Y
+	case hasH && ratio > 0:
+		w = dimen.DU(float64(h) * ratio)
+	case hasH:
+		w = intrinsic.X
+	default:
+		w, h = intrinsic.X, intrinsic.Y
+	}
+	maxw := css.DimenOption(c.DOMNode().ComputedStyles().GetPropertyValue("max-width"))
+	if max, ok := replacedLength(maxw, nil, "", enclosing); ok && w > max {
+		if !hasH { // keep the aspect ratio, unless the height is given
+			h = h.Scale(int64(max), int64(w))
+		}
+		w = max
+	}
+	box.W, box.H = css.JustDimen(w), css.JustDimen(h)
+	tracer().Debugf("replaced element <%s> has size %v×%v", c.DOMNode().NodeName(), w, h)
+	syn.W, syn.H = w, h
+	return
+}
+
+// replacedLength resolves a CSS dimension d of a replaced element. If d is not
+// set, attribute key of n is used instead. Percentages are resolved against
+// enclosing, unless enclosing is negative. Returns false if the dimension is not
+// given.
+func replacedLength(d css.DimenT, n *dom.W3CNode, key string, enclosing dimen.DU) (dimen.DU, bool) {
+	var x dimen.DU
+	var p percent.Percent
+	if d.Match().Just(&x) != nil {
+		return x, true
+	} else if d.Match().Percentage(&p) != nil {
+		if enclosing < 0 {
+			return 0, false
+		}
+		return enclosing.Scale(int64(p), 100), true
+	}
+	if n == nil || key == "" {
+		return 0, false
+	}
+	if attr := n.Attributes().GetNamedItem(key); attr != nil {
+		px, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(attr.Value()), "px"), 64)
+		if err == nil && px >= 0 {
+			return dimen.DU(px * float64(dimen.PX)), true
+		}
+	}
+	return 0, false
+}
+
+// --- Placing images on pages -----------------------------------------------
+
+// PlacedImage is an image placed on a page, as a primitive for backends.
+type PlacedImage struct {
+	Rect    dimen.Rect         // area of the image on its page
+	Picture *resources.Picture // image to draw into Rect
+	Node    *dom.W3CNode       // element of the image
+}
+
+// PlaceImages finds the images placed on paginated pages and records them with
+// their pages (see Page.Images). Images within paragraphs are located within
+// the paragraph's lines, sitting on the baseline. Images which could not be
+// loaded are skipped.
+func PlaceImages(pages []*Page) {
+	for _, page := range pages {
+		page.Images = nil
+	}
+	walkPages(pages, func(page *Page, c *frame.Container, pos dimen.Point) bool {
+		if boxtree.IsReplaced(c.RenderNode()) {
+			var w, h dimen.DU
+			c.CSSBox().W.Match().Just(&w)
+			c.CSSBox().H.Match().Just(&h)
+			placeImage(page, c, dimen.Rect{TopL: pos, BotR: dimen.Point{X: pos.X + w, Y: pos.Y + h}})
+			return false
+		}
+		lines := linesOf(c)
+		if lines == nil {
+			return true
+		}
+		images := replacedWithin(c)
+		if len(images) == 0 {
+			return false
+		}
+		ranges := inline.ElementRanges(c)
+		for _, line := range lines {
+			lbox, ok := line.RenderNode().(*inline.LineBox)
+			if !ok {
+				continue
+			}
+			baseline := pos.Y + line.CSSBox().TopL.Y + lbox.Baseline()
+			for _, img := range images {
+				if x0, _, ok := lbox.Extent(ranges[img.DOMNode()]); ok {
+					var w, h dimen.DU
+					img.CSSBox().W.Match().Just(&w)
+					img.CSSBox().H.Match().Just(&h)
+					placeImage(page, img, dimen.Rect{
+						TopL: dimen.Point{X: pos.X + x0, Y: baseline - h},
+						BotR: dimen.Point{X: pos.X + x0 + w, Y: baseline},
+					})
+				}
+			}
+		}
+		return false
+	})
+}
+
+func placeImage(page *Page, c *frame.Container, rect dimen.Rect) {
+	pic, err := c.RenderNode().(*boxtree.PrincipalBox).Picture.Picture()
+	if err != nil || pic == nil {
+		return
+	}
+	page.Images = append(page.Images, &PlacedImage{Rect: rect, Picture: pic, Node: c.DOMNode()})
+}
+
+// replacedWithin returns the boxes of replaced elements within the paragraph of c.
+func replacedWithin(c *frame.Container) []*frame.Container {
+	var boxes []*frame.Container
+	var collect func(sub *frame.Container)
+	collect = func(sub *frame.Container) {
+		if sub != c && boxtree.IsReplaced(sub.RenderNode()) {
+			boxes = append(boxes, sub)
+		} else if sub.Context != nil {
+			for _, ch := range sub.Context.Contained() {
+				collect(ch)
+			}
+		}
+	}
+	collect(c)
+	return boxes
+}
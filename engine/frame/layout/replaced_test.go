@@ -0,0 +1,56 @@
+package layout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/locate/resources"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+)
+
+func TestSizeReplaced(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body>
+	<img src="a.png"><img src="b.png" width="40"><img src="c.png">
+	</body></html>`, 0)
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 images, have %d", len(blocks))
+	}
+	expected := []dimen.Point{
+		{X: 100 * dimen.PX, Y: 50 * dimen.PX},  // width from CSS
+		{X: 40 * dimen.PX, Y: 20 * dimen.PX},   // width from attribute
+		{X: 200 * dimen.PX, Y: 100 * dimen.PX}, // intrinsic size
+	}
+	for i, b := range blocks {
+		pbox := boxtree.NewPrincipalBox(b.DOMNode(), css.InlineMode)
+		pbox.Picture = testPicture{Size: dimen.Point{X: 200 * dimen.PX, Y: 100 * dimen.PX}}
+		if i == 0 {
+			pbox.CSSBox().W = css.JustDimen(100 * dimen.PX)
+		}
+		inherited := inheritedParams{W: css.JustDimen(300 * dimen.PX)}
+		syn := sizeReplaced(&pbox.Container, inherited)
+		if (dimen.Point{X: syn.W, Y: syn.H}) != expected[i] {
+			t.Errorf("expected image #%d to have size %v, has %v×%v", i, expected[i], syn.W, syn.H)
+		}
+	}
+}
+
+type testPicture resources.Picture
+
+func (pic testPicture) Picture() (*resources.Picture, error) {
+	p := resources.Picture(pic)
+	return &p, nil
+}
+
+func (pic testPicture) PictureContext(context.Context) (*resources.Picture, error) {
+	return pic.Picture()
+}
+
+func (pic testPicture) Await(context.Context) error {
+	return nil
+}
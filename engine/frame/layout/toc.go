@@ -0,0 +1,63 @@
+package layout
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+// --- Table of contents -----------------------------------------------------
+
+// TOCEntry is an entry of a table of contents, created for a heading element.
+type TOCEntry struct {
+	Level int          // 1 for <h1> through 6 for <h6>
+	Title string       // text of the heading, with white space collapsed
+	ID    string       // id of the heading, if any
+	Page  int          // number of the page the heading is placed on
+	Pos   dimen.Point  // position of the heading on its page
+	Node  *dom.W3CNode // heading element
+}
+
+// TableOfContents collects the headings <h1>…<h6> placed on paginated pages, in
+// the order of pages. Headings within <nav> elements, e.g. the title of a table
+// of contents, are not included.
+func TableOfContents(pages []*Page) []*TOCEntry {
+	var toc []*TOCEntry
+	seen := make(map[*dom.W3CNode]bool)
+	walkPages(pages, func(page *Page, c *frame.Container, pos dimen.Point) bool {
+		n := c.DOMNode()
+		level := headingLevel(n)
+		if level == 0 {
+			return n == nil || n.NodeName() != "nav"
+		}
+		if !seen[n] { // fragments of headings broken across pages share the DOM node
+			seen[n] = true
+			title, _ := n.TextContent()
+			toc = append(toc, &TOCEntry{
+				Level: level,
+				Title: strings.Join(strings.Fields(title), " "),
+				ID:    attribute(n, "id"),
+				Page:  page.Number,
+				Pos:   pos,
+				Node:  n,
+			})
+		}
+		return false
+	})
+	tracer().Debugf("table of contents has %d entries", len(toc))
+	return toc
+}
+
+// headingLevel returns the level of a heading element, or 0 if n is not a heading.
+func headingLevel(n *dom.W3CNode) int {
+	if n == nil {
+		return 0
+	}
+	name := n.NodeName()
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+func TestTableOfContents(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body>
+	<nav><h1>Contents</h1></nav>
+	<h1 id="intro">Introduction</h1>
+	<p>text</p>
+	<h2>The   quick
+	brown fox</h2>
+	</body></html>`, 50*dimen.PT)
+	router := frame.NewFlowRouter()
+	for _, b := range blocks {
+		router.Route(b, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toc := TableOfContents(pages)
+	if len(toc) != 2 {
+		t.Fatalf("expected 2 entries, have %d", len(toc))
+	}
+	if toc[0].Level != 1 || toc[0].ID != "intro" || toc[0].Title != "Introduction" || toc[0].Page != 2 {
+		t.Errorf("unexpected first entry %+v", toc[0])
+	}
+	if toc[1].Level != 2 || toc[1].Title != "The quick brown fox" || toc[1].Page != 4 {
+		t.Errorf("unexpected second entry %+v", toc[1])
+	}
+}
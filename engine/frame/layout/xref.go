@@ -0,0 +1,170 @@
+package layout
+
+import (
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"golang.org/x/net/html"
+)
+
+// --- Cross references ------------------------------------------------------
+
+// Anchor is the target of cross references, i.e. an element with an id.
+type Anchor struct {
+	ID   string
+	Page int         // number of the page the element starts on
+	Pos  dimen.Point // position of the start of the element on its page
+}
+
+// Link is a reference to an anchor, created for elements `<a href="#id">`.
+// Links to other documents keep their href and are not resolved.
+type Link struct {
+	Href   string       // value of attribute href
+	Page   int          // number of the page the link is placed on
+	Rect   dimen.Rect   // area of the link on its page
+	Anchor *Anchor      // target of an internal link, nil if unresolved or external
+	Node   *dom.W3CNode // element of the link
+}
+
+// Target returns the id an internal link refers to. For external links, it
+// returns the empty string.
+func (l *Link) Target() string {
+	if !strings.HasPrefix(l.Href, "#") {
+		return ""
+	}
+	return l.Href[1:]
+}
+
+// CrossRefs holds the anchors and links of a paginated document.
+type CrossRefs struct {
+	Anchors map[string]*Anchor
+	Links   []*Link
+}
+
+// PageOf returns the number of the page element with id id starts on, e.g. for
+// references "see page N". If there is no anchor with this id, false is returned.
+func (xr *CrossRefs) PageOf(id string) (int, bool) {
+	if a, ok := xr.Anchors[id]; ok {
+		return a.Page, true
+	}
+	return 0, false
+}
+
+// ResolveCrossReferences is a document-level pass over paginated pages. It finds
+// the anchors and links placed on the pages and resolves internal links to their
+// anchors. The links of a page are recorded with the page as well (see
+// Page.Links), for backends to create hyperlinks from.
+//
+// Anchors and links within paragraphs are located within the paragraph's lines.
+// A link broken across lines gets a link for each of its lines. Elements split
+// across pages are anchored on the page they start on.
+func ResolveCrossReferences(pages []*Page) *CrossRefs {
+	xr := &CrossRefs{Anchors: make(map[string]*Anchor)}
+	ranges := make(map[*dom.W3CNode]map[*dom.W3CNode]inline.TextRange)
+	for _, page := range pages {
+		page.Links = nil
+	}
+	walkPages(pages, func(page *Page, c *frame.Container, pos dimen.Point) bool {
+		var w, h dimen.DU
+		c.CSSBox().W.Match().Just(&w)
+		c.CSSBox().H.Match().Just(&h)
+		xr.addElement(page, c.DOMNode(), dimen.Rect{TopL: pos, BotR: dimen.Point{X: pos.X + w, Y: pos.Y + h}})
+		lines := linesOf(c)
+		if lines == nil {
+			return true
+		}
+		// ranges of elements are cached, as paragraphs broken across pages share them
+		para := c.DOMNode()
+		if _, ok := ranges[para]; !ok {
+			ranges[para] = inline.ElementRanges(c)
+		}
+		xr.collectInLines(page, lines, pos, ranges[para])
+		return false
+	})
+	for _, l := range xr.Links {
+		if id := l.Target(); id != "" {
+			if l.Anchor = xr.Anchors[id]; l.Anchor == nil {
+				tracer().Infof("cross reference to unknown id '%s' on page %d", id, l.Page)
+			}
+		}
+	}
+	tracer().Debugf("found %d anchors and %d links", len(xr.Anchors), len(xr.Links))
+	return xr
+}
+
+// walkPages calls visit for every container placed on pages, in page order and
+// in document order within regions. visit receives the position of a container
+// on its page, and returns false to skip the children of the container.
+// Positions of nested boxes are relative to their parents, as set by layout.
+func walkPages(pages []*Page, visit func(*Page, *frame.Container, dimen.Point) bool) {
+	var walk func(*Page, *frame.Container, dimen.Point)
+	walk = func(page *Page, c *frame.Container, origin dimen.Point) {
+		pos := origin
+		pos.Shift(c.CSSBox().TopL)
+		if !visit(page, c, pos) {
+			return
+		}
+		for _, ch := range c.TreeNode().Children(true) {
+			if ch.Payload != nil {
+				walk(page, ch.Payload, pos)
+			}
+		}
+	}
+	for _, page := range pages {
+		for _, rbox := range page.Regions {
+			for _, c := range rbox.Content {
+				walk(page, c, dimen.Origin)
+			}
+		}
+	}
+}
+
+// collectInLines finds the anchors and links within the lines of a paragraph
+// positioned at origin.
+func (xr *CrossRefs) collectInLines(page *Page, lines []*frame.Container, origin dimen.Point,
+	ranges map[*dom.W3CNode]inline.TextRange) {
+	//
+	for _, line := range lines {
+		lbox, ok := line.RenderNode().(*inline.LineBox)
+		if !ok {
+			continue
+		}
+		top := origin.Y + line.CSSBox().TopL.Y
+		for n, r := range ranges {
+			if x0, x1, ok := lbox.Extent(r); ok {
+				xr.addElement(page, n, dimen.Rect{
+					TopL: dimen.Point{X: origin.X + x0, Y: top},
+					BotR: dimen.Point{X: origin.X + x1, Y: top + lineHeight(line)},
+				})
+			}
+		}
+	}
+}
+
+// addElement records an anchor for element n, if n has an id, and a link, if n
+// is an HTML link. rect is the area of n on page.
+func (xr *CrossRefs) addElement(page *Page, n *dom.W3CNode, rect dimen.Rect) {
+	if n == nil || n.NodeType() != html.ElementNode {
+		return
+	}
+	if id := attribute(n, "id"); id != "" {
+		if _, ok := xr.Anchors[id]; !ok {
+			xr.Anchors[id] = &Anchor{ID: id, Page: page.Number, Pos: rect.TopL}
+		}
+	}
+	if href := attribute(n, "href"); href != "" && n.NodeName() == "a" {
+		l := &Link{Href: href, Page: page.Number, Rect: rect, Node: n}
+		xr.Links = append(xr.Links, l)
+		page.Links = append(page.Links, l)
+	}
+}
+
+func attribute(n *dom.W3CNode, key string) string {
+	if attr := n.Attributes().GetNamedItem(key); attr != nil {
+		return strings.TrimSpace(attr.Value())
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+)
+
+func TestResolveCrossReferences(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	blocks := styledBlocks(t, `<html><body>
+	<p id="intro">1</p>
+	<p id="details">2</p>
+	<a href="#intro">see page 1</a>
+	<a href="#nowhere">dangling</a>
+	<a href="https://example.com">external</a>
+	</body></html>`, 50*dimen.PT)
+	router := frame.NewFlowRouter()
+	for _, b := range blocks {
+		router.Route(b, frame.MainFlow)
+	}
+	pages, err := paginatorForTest().Paginate(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xr := ResolveCrossReferences(pages)
+	if n, ok := xr.PageOf("details"); !ok || n != 2 {
+		t.Errorf("expected #details to be anchored on page 2, is %d", n)
+	}
+	if len(xr.Links) != 3 {
+		t.Fatalf("expected 3 links, have %d", len(xr.Links))
+	}
+	link := xr.Links[0]
+	if link.Page != 3 || link.Anchor == nil || link.Anchor.Page != 1 {
+		t.Errorf("expected link on page 3 to refer to page 1, have %+v", link)
+	}
+	if link.Rect.TopL != (dimen.Point{X: 10 * dimen.PT, Y: 10 * dimen.PT}) {
+		t.Errorf("expected link at top of page content, is at %v", link.Rect.TopL)
+	}
+	if len(pages[2].Links) != 1 || pages[2].Links[0] != link {
+		t.Errorf("expected link to be recorded with page 3")
+	}
+	if xr.Links[1].Anchor != nil || xr.Links[2].Anchor != nil || xr.Links[2].Target() != "" {
+		t.Errorf("expected dangling and external links to remain unresolved")
+	}
+}
@@ -0,0 +1,66 @@
+package frame
+
+import "github.com/npillmayer/tyse/core/dimen"
+
+// --- Paint areas -----------------------------------------------------------
+
+// PaintArea is the geometry of a box for painting its decorations, i.e. its
+// background and its border (CSS Backgrounds and Borders Module Level 3 §3).
+// Backgrounds are painted into the border box and clipped to the curves of
+// rounded corners; borders are painted into the border box without the
+// padding box.
+type PaintArea struct {
+	Border  dimen.Rect  // border box
+	Padding dimen.Rect  // padding box, i.e. the border box without the border
+	Widths  [4]dimen.DU // border widths, starting at the top and travelling clockwise
+}
+
+// PaintArea returns the paint area of a box. As for OuterBox, the top left corner
+// of the margin box of box is at TopL. origin is the position TopL is relative to.
+// Dimensions which are not fixed count as zero.
+func (box *Box) PaintArea(origin dimen.Point) PaintArea {
+	var margins, padding [4]dimen.DU
+	var area PaintArea
+	for dir := Top; dir <= Left; dir++ {
+		box.Margins[dir].Match().Just(&margins[dir])
+		box.Padding[dir].Match().Just(&padding[dir])
+		box.BorderWidth[dir].Match().Just(&area.Widths[dir])
+	}
+	var w, h dimen.DU
+	box.W.Match().Just(&w)
+	box.H.Match().Just(&h)
+	if !box.BorderBoxSizing { // W and H are dimensions of the content box
+		w += padding[Left] + padding[Right] + area.Widths[Left] + area.Widths[Right]
+		h += padding[Top] + padding[Bottom] + area.Widths[Top] + area.Widths[Bottom]
+	}
+	topL := dimen.Point{
+		X: origin.X + box.TopL.X + margins[Left],
+		Y: origin.Y + box.TopL.Y + margins[Top],
+	}
+	area.Border = dimen.Rect{TopL: topL, BotR: dimen.Point{X: topL.X + w, Y: topL.Y + h}}
+	area.Padding = dimen.Rect{
+		TopL: dimen.Point{X: topL.X + area.Widths[Left], Y: topL.Y + area.Widths[Top]},
+		BotR: dimen.Point{X: topL.X + w - area.Widths[Right], Y: topL.Y + h - area.Widths[Bottom]},
+	}
+	return area
+}
+
+// HasBorder returns true if at least one side of the paint area has a border.
+func (area PaintArea) HasBorder() bool {
+	return area.Widths != [4]dimen.DU{}
+}
+
+// LineStyleFromProperty returns the line style for a CSS border style. Styles
+// other than `dashed` and `dotted` are drawn as solid lines. For `none` and
+// `hidden`, false is returned.
+func LineStyleFromProperty(s string) (LineStyle, bool) {
+	switch s {
+	case "", "none", "hidden", "default":
+		return LSSolid, false
+	case "dashed":
+		return LSDashed, true
+	case "dotted":
+		return LSDotted, true
+	}
+	return LSSolid, true
+}
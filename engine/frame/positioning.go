@@ -0,0 +1,99 @@
+package frame
+
+import (
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/percent"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+// ContainingBlock is the rectangle an out-of-flow box is positioned against
+// (CSS 2.1 §10.1). For absolutely positioned boxes this is the padding box of
+// the nearest positioned ancestor, for fixed boxes it is the viewport.
+type ContainingBlock struct {
+	TopL dimen.Point
+	W, H dimen.DU
+}
+
+// PaddingBox returns the padding box of box as a containing block, with its
+// origin at (0,0). If the width or the horizontal padding of box are not yet
+// fixed, false is returned. An unfixed height or vertical padding are taken as 0.
+func PaddingBox(box *Box) (ContainingBlock, bool) {
+	cb := ContainingBlock{}
+	var w, h, pl, pr, pt, pb dimen.DU
+	if box.W.Match().Just(&w) == nil || box.Padding[Left].Match().Just(&pl) == nil ||
+		box.Padding[Right].Match().Just(&pr) == nil {
+		return cb, false
+	}
+	box.H.Match().Just(&h)
+	box.Padding[Top].Match().Just(&pt)
+	box.Padding[Bottom].Match().Just(&pb)
+	cb.W, cb.H = w+pl+pr, h+pt+pb
+	return cb, true
+}
+
+// Offsets are the offsets of an out-of-flow box, resolved against a containing
+// block. Offsets are ordered top, right, bottom, left. Offsets which are `auto`
+// are flagged as unset.
+type Offsets struct {
+	D   [4]dimen.DU
+	Set [4]bool
+}
+
+// Resolve resolves position offsets against cb. Percentages refer to the width of
+// cb for horizontal offsets and to the height of cb for vertical offsets.
+func (cb ContainingBlock) Resolve(offsets []css.PositionOffset) Offsets {
+	var o Offsets
+	for _, offset := range offsets {
+		if offset.Dir < css.Top || offset.Dir > css.Left {
+			continue
+		}
+		ref := cb.H
+		if offset.Dir == css.Left || offset.Dir == css.Right {
+			ref = cb.W
+		}
+		var d dimen.DU
+		var p percent.Percent
+		if offset.Dim.Match().Just(&d) != nil {
+			o.D[offset.Dir], o.Set[offset.Dir] = d, true
+		} else if offset.Dim.Match().Percentage(&p) != nil {
+			o.D[offset.Dir], o.Set[offset.Dir] = ref.Scale(int64(p), 100), true
+		}
+	}
+	return o
+}
+
+// AvailableWidth returns the width available for an out-of-flow box with offsets
+// o. If both left and right offsets are set, this is the width the box has to
+// fill if its width is `auto`; otherwise the box may shrink to fit its content.
+func (cb ContainingBlock) AvailableWidth(o Offsets) (dimen.DU, bool) {
+	w := cb.W
+	if o.Set[Left] {
+		w -= o.D[Left]
+	}
+	if o.Set[Right] {
+		w -= o.D[Right]
+	}
+	if w < 0 {
+		w = 0
+	}
+	return w, o.Set[Left] && o.Set[Right]
+}
+
+// Place returns the position of an out-of-flow box of size w × h within cb.
+// Left takes precedence over right and top over bottom. If neither offset is set
+// for a dimension, the box stays at its static position, i.e., the position it
+// would have had in the normal flow.
+func (cb ContainingBlock) Place(o Offsets, w, h dimen.DU, static dimen.Point) dimen.Point {
+	pos := static
+	if o.Set[Left] {
+		pos.X = cb.TopL.X + o.D[Left]
+	} else if o.Set[Right] {
+		pos.X = cb.TopL.X + cb.W - o.D[Right] - w
+	}
+	if o.Set[Top] {
+		pos.Y = cb.TopL.Y + o.D[Top]
+	} else if o.Set[Bottom] {
+		pos.Y = cb.TopL.Y + cb.H - o.D[Bottom] - h
+	}
+	return pos
+}
@@ -0,0 +1,44 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+)
+
+func TestPlaceOutOfFlow(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.frame")
+	defer teardown()
+	//
+	cb := ContainingBlock{W: 200 * dimen.PT, H: 100 * dimen.PT}
+	o := cb.Resolve(css.PositionOffsets("10pt", "25%", "", ""))
+	pos := cb.Place(o, 50*dimen.PT, 20*dimen.PT, dimen.Point{X: 5 * dimen.PT, Y: 5 * dimen.PT})
+	if pos.X != 100*dimen.PT || pos.Y != 10*dimen.PT {
+		t.Errorf("expected box to be placed at (100pt,10pt), is at %v", pos)
+	}
+	o = cb.Resolve(css.PositionOffsets("", "", "10pt", ""))
+	pos = cb.Place(o, 50*dimen.PT, 20*dimen.PT, dimen.Point{X: 5 * dimen.PT, Y: 5 * dimen.PT})
+	if pos.X != 5*dimen.PT || pos.Y != 70*dimen.PT {
+		t.Errorf("expected box to be placed at (5pt,70pt), is at %v", pos)
+	}
+	o = cb.Resolve(css.PositionOffsets("", "20pt", "", "30pt"))
+	if w, fill := cb.AvailableWidth(o); w != 150*dimen.PT || !fill {
+		t.Errorf("expected box to fill 150pt, have %s (fill=%v)", w, fill)
+	}
+}
+
+func TestPaddingBox(t *testing.T) {
+	box := &Box{}
+	box.W = css.JustDimen(100 * dimen.PT)
+	if _, ok := PaddingBox(box); ok {
+		t.Errorf("expected padding box with unfixed padding to fail")
+	}
+	for i := range box.Padding {
+		box.Padding[i] = css.JustDimen(5 * dimen.PT)
+	}
+	if cb, ok := PaddingBox(box); !ok || cb.W != 110*dimen.PT || cb.H != 10*dimen.PT {
+		t.Errorf("expected padding box of 110pt × 10pt, have %v", cb)
+	}
+}
@@ -34,11 +34,14 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 import (
 	"image/color"
+	"strings"
 
 	"github.com/npillmayer/cords/styled"
 	"github.com/npillmayer/tyse/core/dimen"
 	"github.com/npillmayer/tyse/core/font"
 	"github.com/npillmayer/tyse/engine/dom/style"
+	"github.com/npillmayer/tyse/engine/dom/style/css"
+	"github.com/npillmayer/tyse/engine/glyphing"
 	"github.com/npillmayer/uax/bidi"
 )
 
@@ -76,8 +79,9 @@ var _ styled.Style = StyleSet{}
 
 // ColorStyle is a type for styling with color.
 type ColorStyle struct {
-	Foreground color.Color
-	Background color.Color // may be (semi-)transparent
+	Foreground      color.Color
+	Background      color.Color // may be (semi-)transparent
+	BackgroundImage string      // URL of a background image, painted over the background color
 }
 
 // TextStyle is a type for styling text.
@@ -192,3 +196,123 @@ func (set StyleSet) BidiDir() bidi.Direction {
 func (set StyleSet) Font() *font.TypeCase {
 	return font.NullTypeCase()
 }
+
+// BaselineShift returns the shift of the baseline for a run of text, as set by CSS
+// property vertical-align, with positive values raising the text. Keywords "super"
+// and "sub" are relative to fontsize.
+//
+// TODO support percentages and alignment relative to the line box (top, middle, …)
+func (set StyleSet) BaselineShift(fontsize dimen.DU) dimen.DU {
+	if set.Props == nil {
+		return 0
+	}
+	p, ok := set.Props.Property("vertical-align")
+	if !ok {
+		return 0
+	}
+	switch p {
+	case "baseline", style.NullStyle:
+		return 0
+	case "super":
+		return fontsize / 3
+	case "sub":
+		return -fontsize / 5
+	}
+	var d dimen.DU
+	if css.DimenOption(p).Match().Just(&d) == nil {
+		tracer().Debugf("unsupported value for vertical-align: %q", p)
+	}
+	return d
+}
+
+// TextDecoration is a set of decoration lines for text, as set by CSS property
+// text-decoration.
+type TextDecoration uint8
+
+// Decoration lines, in the order they are painted.
+const (
+	Underline   TextDecoration = 1 << iota // line below the baseline
+	Overline                               // line above the text
+	LineThrough                            // line through the middle of the text
+)
+
+// TextDecoration returns the decoration lines for a run of text, as set by CSS
+// property text-decoration-line, or by shorthand text-decoration. Decoration
+// styles and colors are not yet supported.
+func (set StyleSet) TextDecoration() TextDecoration {
+	if set.Props == nil {
+		return 0
+	}
+	p, ok := set.Props.Property("text-decoration-line")
+	if !ok {
+		if p, ok = set.Props.Property("text-decoration"); !ok {
+			return 0
+		}
+	}
+	var deco TextDecoration
+	for _, keyword := range strings.Fields(string(p)) {
+		switch keyword {
+		case "underline":
+			deco |= Underline
+		case "overline":
+			deco |= Overline
+		case "line-through":
+			deco |= LineThrough
+		}
+	}
+	return deco
+}
+
+// FontFeatures returns the OpenType features for a run of text, as selected by
+// CSS properties font-variant-ligatures, font-variant-numeric, font-variant-caps
+// and font-feature-settings, in this order. Small caps may be selected by
+// CSS 2 property font-variant as well. Illegal values are ignored.
+func (set StyleSet) FontFeatures() []glyphing.FeatureSetting {
+	if set.Props == nil {
+		return nil
+	}
+	property := func(key string) string {
+		p, _ := set.Props.Property(key)
+		return string(p)
+	}
+	caps := property("font-variant-caps")
+	if caps == "" || caps == "normal" {
+		caps = property("font-variant") // CSS 2: normal | small-caps
+	}
+	var features []glyphing.FeatureSetting
+	for _, variant := range []struct {
+		key, value string
+		parse      func(string) ([]glyphing.FeatureSetting, error)
+	}{
+		{"font-variant-ligatures", property("font-variant-ligatures"), glyphing.ParseFontVariantLigatures},
+		{"font-variant-numeric", property("font-variant-numeric"), glyphing.ParseFontVariantNumeric},
+		{"font-variant-caps", caps, glyphing.ParseFontVariantCaps},
+		{"font-feature-settings", property("font-feature-settings"), glyphing.ParseFontFeatureSettings},
+	} {
+		f, err := variant.parse(variant.value)
+		if err != nil {
+			tracer().Debugf("unsupported value for %s: %v", variant.key, err)
+			continue
+		}
+		features = append(features, f...)
+	}
+	return features
+}
+
+// FontSizeAdjust returns the aspect value fonts are to be scaled to for a run of
+// text, as set by CSS property font-size-adjust, or 0 for "none" (see
+// glyphing.ParseFontSizeAdjust). Illegal values are ignored.
+func (set StyleSet) FontSizeAdjust() float64 {
+	if set.Props == nil {
+		return 0
+	}
+	p, ok := set.Props.Property("font-size-adjust")
+	if !ok {
+		return 0
+	}
+	adjust, err := glyphing.ParseFontSizeAdjust(string(p))
+	if err != nil {
+		tracer().Debugf("unsupported value for font-size-adjust: %v", err)
+	}
+	return adjust
+}
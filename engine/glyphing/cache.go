@@ -0,0 +1,195 @@
+package glyphing
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/npillmayer/tyse/core/font"
+	"golang.org/x/text/language"
+)
+
+// --- Shaping cache ---------------------------------------------------------
+
+// ShaperFunc adapts a function to the Shaper interface, e.g. harfbuzz.Shape.
+type ShaperFunc func(io.RuneReader, []ShapedGlyph, [][]rune, Params) (GlyphSequence, error)
+
+// Shape calls f.
+func (f ShaperFunc) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, params Params) (GlyphSequence, error) {
+	return f(text, buf, ctx, params)
+}
+
+// ShapingCache is a shaper which remembers the glyph sequences of recently
+// shaped runs of text. Runs are identified by font, point size, direction,
+// script, language, features, synthesis, text and context. Whenever a run
+// is shaped again, the glyph sequence is taken from the cache instead of
+// calling the wrapped shaper.
+//
+// The cache holds a limited number of runs, evicting the least recently used
+// one when full. A ShapingCache is safe for concurrent use, provided the wrapped
+// shaper is.
+type ShapingCache struct {
+	shaper  Shaper
+	size    int
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List // front is most recently used
+	stats   CacheStats
+}
+
+// CacheStats reports the effectiveness of a shaping cache.
+type CacheStats struct {
+	Hits, Misses uint64 // calls to Shape answered from the cache or by shaping
+	Evictions    uint64 // runs evicted from the cache
+	Entries      int    // runs currently held
+}
+
+// HitRate returns the fraction of calls to Shape answered from the cache.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%d hits, %d misses (%.1f%%), %d evictions, %d entries",
+		s.Hits, s.Misses, 100*s.HitRate(), s.Evictions, s.Entries)
+}
+
+// DefaultCacheSize is the number of runs a shaping cache holds if no size is
+// given.
+const DefaultCacheSize = 4096
+
+// CachingShaper wraps a shaper with a cache for up to size runs of text. If size
+// is not positive, DefaultCacheSize is used.
+func CachingShaper(shaper Shaper, size int) *ShapingCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &ShapingCache{
+		shaper:  shaper,
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+type cacheKey struct {
+	font      *font.ScalableFont
+	size      float32
+	direction Direction
+	script    language.Script
+	lang      language.Tag
+	synthesis Synthesis
+	adjust    float64 // font-size-adjust
+	text      string  // the run of text
+	extra     string  // context and features
+}
+
+type cacheEntry struct {
+	key cacheKey
+	seq GlyphSequence
+}
+
+// Shape returns the glyph sequence for a run of text from the cache, or shapes
+// the run with the wrapped shaper and caches the result. Glyphs are copied into
+// buf, so clients may modify them freely.
+func (sc *ShapingCache) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, params Params) (GlyphSequence, error) {
+	if text == nil {
+		return sc.shaper.Shape(text, buf, ctx, params)
+	}
+	var runes strings.Builder
+	for {
+		r, sz, err := text.ReadRune()
+		if sz == 0 || err != nil {
+			break
+		}
+		runes.WriteRune(r)
+	}
+	key := makeCacheKey(runes.String(), ctx, params)
+	sc.mu.Lock()
+	if elem, ok := sc.entries[key]; ok {
+		sc.lru.MoveToFront(elem)
+		sc.stats.Hits++
+		seq := elem.Value.(*cacheEntry).seq
+		sc.mu.Unlock()
+		seq.Glyphs = append(buf[:0], seq.Glyphs...)
+		return seq, nil
+	}
+	sc.stats.Misses++
+	sc.mu.Unlock()
+	seq, err := sc.shaper.Shape(strings.NewReader(runes.String()), buf, ctx, params)
+	if err != nil {
+		return seq, err
+	}
+	cached := seq
+	cached.Glyphs = append([]ShapedGlyph(nil), seq.Glyphs...)
+	sc.add(key, cached)
+	return seq, nil
+}
+
+// add puts a glyph sequence into the cache, evicting the least recently used
+// entries if the cache is full.
+func (sc *ShapingCache) add(key cacheKey, seq GlyphSequence) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if elem, ok := sc.entries[key]; ok { // shaped concurrently
+		sc.lru.MoveToFront(elem)
+		return
+	}
+	sc.entries[key] = sc.lru.PushFront(&cacheEntry{key: key, seq: seq})
+	for sc.lru.Len() > sc.size {
+		oldest := sc.lru.Back()
+		sc.lru.Remove(oldest)
+		delete(sc.entries, oldest.Value.(*cacheEntry).key)
+		sc.stats.Evictions++
+	}
+}
+
+// Stats returns the current statistics of the cache.
+func (sc *ShapingCache) Stats() CacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	stats := sc.stats
+	stats.Entries = sc.lru.Len()
+	return stats
+}
+
+// Clear removes all runs from the cache, e.g. after fonts have been replaced.
+// Statistics are kept.
+func (sc *ShapingCache) Clear() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.entries = make(map[cacheKey]*list.Element)
+	sc.lru.Init()
+}
+
+func makeCacheKey(text string, ctx [][]rune, params Params) cacheKey {
+	key := cacheKey{
+		direction: params.Direction,
+		script:    params.Script,
+		lang:      params.Language,
+		synthesis: params.Synthesis,
+		adjust:    params.SizeAdjust,
+		text:      text,
+	}
+	if params.Font != nil {
+		key.font = params.Font.ScalableFontParent()
+		key.size = params.Font.PtSize()
+	}
+	if len(ctx) == 0 && len(params.Features) == 0 {
+		return key
+	}
+	var b strings.Builder
+	for _, c := range ctx { // length-prefixed, to keep keys unambiguous
+		fmt.Fprintf(&b, "%d:%s", len(c), string(c))
+	}
+	for _, f := range params.Features {
+		fmt.Fprintf(&b, "|%v=%d,%v[%d:%d]", f.Feature, f.Arg, f.On, f.Start, f.End)
+	}
+	key.extra = b.String()
+	return key
+}
@@ -0,0 +1,78 @@
+package glyphing
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// countingShaper counts calls to a shaper.
+type countingShaper struct {
+	shaper Shaper
+	mu     sync.Mutex
+	calls  int
+}
+
+func (cs *countingShaper) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, p Params) (GlyphSequence, error) {
+	cs.mu.Lock()
+	cs.calls++
+	cs.mu.Unlock()
+	return cs.shaper.Shape(text, buf, ctx, p)
+}
+
+func TestShapingCache(t *testing.T) {
+	counter := &countingShaper{shaper: runeShaper(1000)}
+	cache := CachingShaper(counter, 2)
+	shape := func(text string, params Params) GlyphSequence {
+		seq, err := cache.Shape(strings.NewReader(text), nil, nil, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seq
+	}
+	seq := shape("Tyse", Params{})
+	seq.Glyphs[0].GID = 0 // clients must not be able to modify cached glyphs
+	if seq = shape("Tyse", Params{}); counter.calls != 1 || seq.Glyphs[0].GID != 'T' || seq.W != 4000 {
+		t.Errorf("expected second run to be taken unchanged from cache, have %d calls, %v", counter.calls, seq.Glyphs)
+	}
+	shape("Tyse", Params{Script: language.MustParseScript("Latn")})
+	if counter.calls != 2 {
+		t.Errorf("expected run with different script to be shaped again")
+	}
+	shape("other", Params{}) // evicts first run
+	shape("Tyse", Params{})
+	stats := cache.Stats()
+	if counter.calls != 4 || stats.Evictions != 2 || stats.Entries != 2 {
+		t.Errorf("expected least recently used run to be evicted, have %d calls, %v", counter.calls, stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 4 || stats.HitRate() != 0.2 {
+		t.Errorf("expected 1 hit and 4 misses, have %v", stats)
+	}
+}
+
+func TestShapingCacheConcurrent(t *testing.T) {
+	counter := &countingShaper{shaper: runeShaper(1000)}
+	cache := CachingShaper(counter, 0)
+	words := []string{"the", "quick", "brown", "fox"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w := words[j%len(words)]
+				seq, err := cache.Shape(strings.NewReader(w), nil, nil, Params{})
+				if err != nil || len(seq.Glyphs) != len(w) {
+					t.Errorf("expected %d glyphs for %q, have %d", len(w), w, len(seq.Glyphs))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stats := cache.Stats(); stats.Entries != len(words) || stats.Hits+stats.Misses != 800 {
+		t.Errorf("expected %d cached runs, have %v", len(words), stats)
+	}
+}
@@ -0,0 +1,85 @@
+package glyphing
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/uax/grapheme"
+	"github.com/npillmayer/uax/segment"
+)
+
+// --- Cluster mapping -------------------------------------------------------
+
+// Cluster returns the range [from…to) of glyphs which belong to the same cluster
+// as glyph #i. For an index out of range, an empty range is returned.
+func (seq GlyphSequence) Cluster(i int) (from, to int) {
+	if i < 0 || i >= len(seq.Glyphs) {
+		return i, i
+	}
+	id := seq.Glyphs[i].ClusterID
+	from, to = i, i+1
+	for from > 0 && seq.Glyphs[from-1].ClusterID == id {
+		from--
+	}
+	for to < len(seq.Glyphs) && seq.Glyphs[to].ClusterID == id {
+		to++
+	}
+	return from, to
+}
+
+// GlyphsForRune returns the range [from…to) of glyphs representing the cluster
+// which contains the input rune at position pos. If pos precedes the input of
+// all glyphs, an empty range is returned.
+func (seq GlyphSequence) GlyphsForRune(pos int) (from, to int) {
+	found := -1
+	for i, g := range seq.Glyphs {
+		if g.ClusterID <= pos && (found < 0 || g.ClusterID > seq.Glyphs[found].ClusterID) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, 0
+	}
+	return seq.Cluster(found)
+}
+
+// SafeToBreak returns true if the sequence may be broken in front of glyph #i
+// without re-shaping. This is the case at cluster boundaries, unless the shaper
+// flagged glyph #i as UnsafeToBreak. Breaking at the start or at the end of the
+// sequence is always safe.
+func (seq GlyphSequence) SafeToBreak(i int) bool {
+	if i <= 0 || i >= len(seq.Glyphs) {
+		return true
+	}
+	g := seq.Glyphs[i]
+	return g.ClusterID != seq.Glyphs[i-1].ClusterID && g.Flags&UnsafeToBreak == 0
+}
+
+// Advance returns the sum of the horizontal advances of glyphs [from…to).
+func (seq GlyphSequence) Advance(from, to int) dimen.DU {
+	var w dimen.DU
+	for i := max(from, 0); i < to && i < len(seq.Glyphs); i++ {
+		w += seq.Glyphs[i].XAdvance
+	}
+	return w
+}
+
+// --- Grapheme clusters -----------------------------------------------------
+
+// graphemeStarts segments runes into grapheme clusters (UAX#29) and marks the
+// runes which start a cluster. Shapers wrapping other shapers split their input
+// at cluster starts only, as splitting a cluster, e.g. an emoji ZWJ sequence or an
+// Indic syllable, would prevent it from being shaped as a unit.
+func graphemeStarts(runes []rune) []bool {
+	starts := make([]bool, len(runes))
+	grapheme.SetupGraphemeClasses()
+	splitter := segment.NewSegmenter(grapheme.NewBreaker(1))
+	splitter.Init(strings.NewReader(string(runes)))
+	i := 0
+	for splitter.Next() && i < len(runes) {
+		starts[i] = true
+		i += utf8.RuneCount(splitter.Bytes())
+	}
+	return starts
+}
@@ -0,0 +1,61 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otshaper"
+)
+
+func TestGlyphSequenceClusters(t *testing.T) {
+	// "ffi" shaped to a ligature, with kerning between the ligature and "x"
+	seq := GlyphSequence{Glyphs: []ShapedGlyph{
+		{ClusterID: 0, XAdvance: 10},
+		{ClusterID: 1, XAdvance: 20},
+		{ClusterID: 1, XAdvance: 0},
+		{ClusterID: 4, XAdvance: 10, Flags: UnsafeToBreak},
+		{ClusterID: 5, XAdvance: 10},
+	}}
+	if from, to := seq.Cluster(2); from != 1 || to != 3 {
+		t.Errorf("expected cluster of glyph #2 to be [1…3), is [%d…%d)", from, to)
+	}
+	if from, to := seq.GlyphsForRune(3); from != 1 || to != 3 {
+		t.Errorf("expected rune #3 to be represented by glyphs [1…3), is [%d…%d)", from, to)
+	}
+	for i, safe := range []bool{true, true, false, false, true, true} {
+		if seq.SafeToBreak(i) != safe {
+			t.Errorf("expected break in front of glyph #%d to be safe=%v", i, safe)
+		}
+	}
+	if w := seq.Advance(1, 4); w != 30 {
+		t.Errorf("expected advance of glyphs [1…4) to be 30, is %d", w)
+	}
+}
+
+func TestSequenceFromBuffer(t *testing.T) {
+	otf, err := ot.Parse(font.FallbackFont().Binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := otshaper.Buffer{
+		{Index: 10, Advance: 500, Cluster: 0},
+		{Index: 11, Advance: 600, Cluster: 1},
+		{Index: 12, Advance: 0, Cluster: 1},
+		{Index: 13, Advance: 500, Cluster: 3},
+	}
+	seq := SequenceFromBuffer(buf, "aéb", otf, 10*dimen.PT)
+	clusters := []int{0, 1, 1, 2}
+	for i, g := range seq.Glyphs {
+		if g.ClusterID != clusters[i] {
+			t.Errorf("expected glyph #%d to map to rune #%d, maps to #%d", i, clusters[i], g.ClusterID)
+		}
+	}
+	if seq.Glyphs[3].CodePoint != 'b' {
+		t.Errorf("expected last glyph to be produced by 'b', is %q", seq.Glyphs[3].CodePoint)
+	}
+	if seq.W <= 0 || seq.H <= 0 {
+		t.Errorf("expected glyph sequence to be scaled to the font size, is %s × %s", seq.W, seq.H)
+	}
+}
@@ -11,3 +11,10 @@ Copyright © 2017–2021 Norbert Pillmayer <norbert@pillmayer.com>
 
 */
 package glyphing
+
+import "github.com/npillmayer/schuko/tracing"
+
+// tracer writes to trace with key 'tyse.glyphs'
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.glyphs")
+}
@@ -0,0 +1,199 @@
+package glyphing
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/fontregistry"
+)
+
+// --- Font fallback ---------------------------------------------------------
+
+// FallbackShaper wraps a shaper to set characters the font of a run has no glyph
+// for with fallback fonts. Fallback fonts are looked up in registry fr, in the
+// order configured for the script of the run (see fontregistry.SetFallbacks).
+//
+// The run is split into portions of characters covered by the same font, which
+// are shaped separately. Glyphs from a fallback font carry it as ShapedGlyph.Font.
+// Portions never split grapheme clusters: all characters of a cluster, e.g. a base
+// character and its combining marks or an emoji ZWJ sequence, are set with the
+// same font. Characters no font covers are left to the font of the run, and end
+// up as .notdef glyphs. If Params.SizeAdjust is set, fallback fonts are scaled to
+// the x-height requested (see AdjustedSize).
+func FallbackShaper(shaper Shaper, fr *fontregistry.Registry) Shaper {
+	return fallbackShaper{shaper: shaper, registry: fr}
+}
+
+type fallbackShaper struct {
+	shaper   Shaper
+	registry *fontregistry.Registry
+}
+
+func (fs fallbackShaper) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, params Params) (GlyphSequence, error) {
+	if text == nil || params.Font == nil || fs.registry == nil {
+		return fs.shaper.Shape(text, buf, ctx, params)
+	}
+	var runes []rune
+	for {
+		r, sz, err := text.ReadRune()
+		if sz == 0 || err != nil {
+			break
+		}
+		runes = append(runes, r)
+	}
+	fonts := newFallbackFonts(fs.registry, params)
+	defer fonts.release()
+	cases := fonts.assign(runes)
+	if cases == nil { // the font of the run covers all characters
+		return fs.shaper.Shape(strings.NewReader(string(runes)), buf, ctx, params)
+	}
+	tracer().Debugf("shaping run with fallback fonts")
+	var portions []GlyphSequence
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && cases[end] == cases[start] {
+			end++
+		}
+		p := params
+		if cases[start] != nil {
+			p.Font = cases[start]
+		}
+		part, err := fs.shaper.Shape(strings.NewReader(string(runes[start:end])), nil, ctx, p)
+		if err != nil {
+			return part, err
+		}
+		for i := range part.Glyphs {
+			part.Glyphs[i].ClusterID += start
+			if cases[start] != nil {
+				part.Glyphs[i].Font = cases[start]
+			}
+		}
+		portions = append(portions, part)
+		start = end
+	}
+	if params.Direction == RightToLeft { // glyphs are in visual order
+		for i, j := 0, len(portions)-1; i < j; i, j = i+1, j-1 {
+			portions[i], portions[j] = portions[j], portions[i]
+		}
+	}
+	seq := GlyphSequence{Glyphs: buf[:0]}
+	for _, part := range portions {
+		seq.Glyphs = append(seq.Glyphs, part.Glyphs...)
+		seq.W += part.W
+		seq.H, seq.D = max(seq.H, part.H), max(seq.D, part.D)
+	}
+	return seq, nil
+}
+
+// fallbackFonts holds the fallback typecases for a run of text. Typecases are
+// derived from the registry on first use, and have to be released after shaping.
+type fallbackFonts struct {
+	registry *fontregistry.Registry
+	primary  *font.TypeCase
+	adjust   float64 // aspect value to scale fallback fonts to, 0 for none
+	names    []string
+	cases    []*font.TypeCase // typecases for names, nil if not yet looked up
+	acquired []string         // fonts to release
+}
+
+func newFallbackFonts(fr *fontregistry.Registry, params Params) *fallbackFonts {
+	names := fr.Fallbacks(params.Script)
+	adjust := params.SizeAdjust
+	if adjust == SizeAdjustFromFont {
+		adjust = aspectOf(params.Font)
+	}
+	return &fallbackFonts{
+		registry: fr,
+		primary:  params.Font,
+		adjust:   adjust,
+		names:    names,
+		cases:    make([]*font.TypeCase, len(names)),
+	}
+}
+
+// assign selects a font for every grapheme cluster of runes: nil for the font of
+// the run, or the first fallback font covering the whole cluster. If no font
+// covers a cluster, its base character decides. The runes of a cluster share its
+// font. If no fallback font is needed at all, assign returns nil.
+func (ff *fallbackFonts) assign(runes []rune) []*font.TypeCase {
+	var cases []*font.TypeCase
+	clusters := graphemeStarts(runes)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && !clusters[end] {
+			end++
+		}
+		var tc *font.TypeCase
+		if !coversAll(ff.primary, runes[start:end]) {
+			if tc = ff.lookup(runes[start:end]); tc == nil && !covers(ff.primary, runes[start]) {
+				tc = ff.lookup(runes[start : start+1])
+			}
+		}
+		if tc != nil && cases == nil {
+			cases = make([]*font.TypeCase, len(runes))
+		}
+		for i := start; i < end && cases != nil; i++ {
+			cases[i] = tc
+		}
+		start = end
+	}
+	return cases
+}
+
+// lookup returns the first fallback font covering all of cluster, or nil.
+func (ff *fallbackFonts) lookup(cluster []rune) *font.TypeCase {
+	for i, name := range ff.names {
+		if name == "" {
+			continue
+		}
+		if ff.cases[i] == nil {
+			tc, err := ff.registry.TypeCase(name, ff.primary.PtSize())
+			if err != nil {
+				tracer().Infof("fallback font %s not available: %v", name, err)
+				ff.names[i] = "" // do not try again
+				continue
+			}
+			ff.acquired = append(ff.acquired, name)
+			if size := AdjustedSize(tc.PtSize(), ff.adjust, aspectOf(tc)); size != tc.PtSize() {
+				if adjusted, err := ff.registry.TypeCase(name, size); err == nil {
+					ff.acquired = append(ff.acquired, name)
+					tc = adjusted
+				}
+			}
+			ff.cases[i] = tc
+		}
+		if coversAll(ff.cases[i], cluster) {
+			return ff.cases[i]
+		}
+	}
+	return nil
+}
+
+func (ff *fallbackFonts) release() {
+	for _, name := range ff.acquired {
+		ff.registry.Release(name)
+	}
+}
+
+// coversAll is true if typecase tc has glyphs for all runes of cluster.
+func coversAll(tc *font.TypeCase, cluster []rune) bool {
+	for _, r := range cluster {
+		if !covers(tc, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// covers is true if typecase tc has a glyph for r. Whitespace, control characters,
+// joiners and variation selectors are always covered, as shapers do not need
+// glyphs for them.
+func covers(tc *font.TypeCase, r rune) bool {
+	if unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.Variation_Selector, r) ||
+		r == '\u200c' || r == '\u200d' {
+		return true
+	}
+	return tc != nil && tc.ScalableFontParent().HasGlyph(r)
+}
@@ -0,0 +1,61 @@
+package glyphing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/fontregistry"
+	"golang.org/x/text/language"
+)
+
+func TestFallbackShaper(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.glyphs")
+	defer teardown()
+	//
+	fonts := "../../core/locate/resources/packaged/fonts/"
+	logo, err := font.LoadOpenTypeFont(fonts + "TySELogo-Regular.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gentium, err := font.LoadOpenTypeFont(fonts + "GentiumPlus-R.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := logo.PrepareCase(12.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := fontregistry.NewRegistry(fontregistry.WithFallbacks(language.Script{}, "missing", "gentium"))
+	fr.StoreFont("gentium", gentium)
+	shaper := FallbackShaper(runeShaper(1000), fr)
+	// the logo font lacks 'λ' and '!', no font has a glyph for the snowman
+	seq, err := shaper.Shape(strings.NewReader("Tyse λ!☃"), nil, nil, Params{Font: tc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq.Glyphs) != 8 || seq.W != 8000 {
+		t.Fatalf("expected 8 glyphs with a total width of 8000, have %d with %d", len(seq.Glyphs), seq.W)
+	}
+	for i, g := range seq.Glyphs {
+		if g.ClusterID != i {
+			t.Errorf("expected glyph #%d to map to cluster %d, is %d", i, i, g.ClusterID)
+		}
+		fallback := i == 5 || i == 6
+		if (g.Font != nil) != fallback {
+			t.Errorf("glyph #%d (%c): expected fallback font to be used = %v", i, g.CodePoint, fallback)
+		}
+	}
+	if seq.Glyphs[5].Font != nil && seq.Glyphs[5].Font.ScalableFontParent() != gentium {
+		t.Errorf("expected Gentium as fallback font for 'λ'")
+	}
+	// the logo font lacks the combining acute, so the whole cluster has to be taken from Gentium
+	seq, err = shaper.Shape(strings.NewReader("Te\u0301"), nil, nil, Params{Font: tc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq.Glyphs) != 3 || seq.Glyphs[0].Font != nil || seq.Glyphs[1].Font == nil || seq.Glyphs[2].Font == nil {
+		t.Errorf("expected base character and combining mark to be set with the fallback font")
+	}
+}
@@ -24,6 +24,12 @@ func Instance(dir glyphing.Direction, script language.Script, lang language.Tag)
 	}
 }
 
+// CachedInstance returns a shaper as Instance does, caching up to size runs of
+// shaped text (see glyphing.ShapingCache).
+func CachedInstance(dir glyphing.Direction, script language.Script, lang language.Tag, size int) *glyphing.ShapingCache {
+	return glyphing.CachingShaper(Instance(dir, script, lang), size)
+}
+
 func (g *glypher) Shape(io.RuneReader, []glyphing.ShapedGlyph, [][]rune, glyphing.Params) (glyphing.GlyphSequence, error) {
 	panic("Glyphing Shape: TODO")
 }
@@ -22,6 +22,7 @@ import (
 	hblang "github.com/benoitkugler/textlayout/language"
 	"github.com/npillmayer/schuko/tracing"
 	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype"
 	"github.com/npillmayer/tyse/core/font/opentype/ot"
 	"github.com/npillmayer/tyse/engine/glyphing"
 	"golang.org/x/image/font"
@@ -128,7 +129,7 @@ func Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, context [][]rune, par
 	// Prepare shaping parameters
 	var hb_seqProps hb.SegmentProperties
 	convertParams(&hb_seqProps, params)
-	var features []hb.Feature = make([]hb.Feature, len(params.Features))
+	var features []hb.Feature = make([]hb.Feature, 0, len(params.Features))
 	for _, feat := range params.Features {
 		features = append(features, FeatureRange4HB(feat))
 	}
@@ -145,22 +146,33 @@ func Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, context [][]rune, par
 		buf = make([]glyphing.ShapedGlyph, len(hb_buf.Info))
 	}
 	seq := glyphing.GlyphSequence{
-		Glyphs: buf,
+		Glyphs: buf[:len(hb_buf.Info)],
 	}
 	// move HarfBuzz output to glyph sequence output
 	sfont := params.Font.ScalableFontParent().SFNT
 	var sfntBuf sfnt.Buffer
+	// HarfBuzz positions glyphs in font units, including GPOS adjustments
+	upem := sfont.UnitsPerEm()
+	size := dimen.DU(params.Font.PtSize() * float32(dimen.PT))
+	scale := func(p int32) dimen.DU {
+		return opentype.UnitsToDimen(sfnt.Units(p), upem, size)
+	}
 	for i, ginfo := range hb_buf.Info {
 		gpos := &hb_buf.Pos[i]
 		tracer().Debugf("[%3d] %q", i, ginfo.String())
 		g := &buf[i]
-		g.ClusterID = ginfo.Cluster
+		g.ClusterID = ginfo.Cluster - offset // clusters are relative to the input, not the context
 		g.GID = ot.GlyphIndex(ginfo.Glyph)
-		g.XAdvance = dimen.DU(gpos.XAdvance) // TODO convert / caluculate
-		g.YAdvance = dimen.DU(gpos.YAdvance)
-		g.XOffset = dimen.DU(gpos.XOffset)
-		g.YOffset = dimen.DU(gpos.YOffset)
-		g.CodePoint = runes[g.ClusterID]
+		g.Flags = 0
+		if ginfo.Mask&hb.GlyphUnsafeToBreak != 0 {
+			g.Flags |= glyphing.UnsafeToBreak
+		}
+		g.XAdvance = scale(int32(gpos.XAdvance))
+		g.YAdvance = scale(int32(gpos.YAdvance))
+		g.XOffset = scale(int32(gpos.XOffset))
+		g.YOffset = scale(int32(gpos.YOffset))
+		seq.W += g.XAdvance
+		g.CodePoint = runes[ginfo.Cluster]
 		bounds, adv, err := sfont.GlyphBounds(&sfntBuf, sfnt.GlyphIndex(g.GID), fixed.Int26_6(sfont.UnitsPerEm()), font.HintingNone)
 		if err != nil {
 			g.RawMetrics.Advance = sfnt.Units(adv)
@@ -175,6 +187,12 @@ func Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, context [][]rune, par
 	return seq, nil
 }
 
+// CachingShaper returns a shaper which shapes with HarfBuzz, caching up to size
+// runs of shaped text (see glyphing.ShapingCache).
+func CachingShaper(size int) *glyphing.ShapingCache {
+	return glyphing.CachingShaper(glyphing.ShaperFunc(Shape), size)
+}
+
 // convertParams is a helper function to convert glyphing parameters to
 // HarfBuzz's format.
 func convertParams(hb_seqProps *hb.SegmentProperties, params glyphing.Params) {
@@ -197,9 +215,10 @@ func bufferText(text io.RuneReader, context [][]rune) (buf bytes.Buffer, off int
 	var bytesBuf bytes.Buffer
 	var r rune
 	if len(context) > 0 && len(context[0]) > 0 {
-		for off, r = range context[0] {
+		for _, r = range context[0] {
 			bytesBuf.WriteRune(r)
 		}
+		off = len(context[0])
 	}
 	var sz int
 	var err error
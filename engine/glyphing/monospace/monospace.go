@@ -2,6 +2,7 @@ package monospace
 
 import (
 	"io"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/npillmayer/tyse/core/dimen"
@@ -15,24 +16,52 @@ import (
 type msshape struct {
 	em               dimen.DU
 	dir              glyphing.Direction
+	tabWidth         int // tab stops every tabWidth cells
 	graphemeSplitter *segment.Segmenter
 	context          *uax11.Context
 }
 
+// DefaultTabWidth is the distance of tab stops in cells, if not configured
+// otherwise by WithTabWidth.
+const DefaultTabWidth = 8
+
+// Option is a type for configuring a monospace shaper.
+type Option func(*msshape)
+
+// WithTabWidth sets the distance of tab stops to n cells. Values below 1 are
+// ignored.
+func WithTabWidth(n int) Option {
+	return func(ms *msshape) {
+		if n > 0 {
+			ms.tabWidth = n
+		}
+	}
+}
+
 // Shaper creates a shaper for monospace typesetting.
 // An em-dimension may be given which will then be used for shaping text.
 // If is is zero, it will be set to 10pt.
-func Shaper(em dimen.DU, context *uax11.Context) glyphing.Shaper {
+//
+// Every grapheme occupies one cell of width em, except East Asian wide and
+// fullwidth characters, which occupy two cells (following UAX#11 within context).
+// Combining marks without a base character and control characters have zero
+// width. A tab advances to the next tab stop.
+func Shaper(em dimen.DU, context *uax11.Context, opts ...Option) glyphing.Shaper {
 	if em == 0 {
 		em = 10 * dimen.PT
 	}
 	sh := &msshape{
-		em:  em,
-		dir: glyphing.LeftToRight,
+		em:       em,
+		dir:      glyphing.LeftToRight,
+		tabWidth: DefaultTabWidth,
+		context:  context,
 	}
 	if context == nil {
 		sh.context = uax11.LatinContext
 	}
+	for _, opt := range opts {
+		opt(sh)
+	}
 	onGraphemes := grapheme.NewBreaker(1)
 	sh.graphemeSplitter = segment.NewSegmenter(onGraphemes)
 	grapheme.SetupGraphemeClasses()
@@ -49,11 +78,17 @@ func (ms msshape) Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, ctx [][]
 		seq.Glyphs = make([]glyphing.ShapedGlyph, 0, 256)
 	}
 	ms.graphemeSplitter.Init(text)
-	i := 0
+	i := 0   // rune position of the current grapheme, which is a cluster of its own
+	col := 0 // cell position of the current grapheme, for tab stops
 	for ms.graphemeSplitter.Next() {
 		grphm := ms.graphemeSplitter.Bytes()
-		w := uax11.Width(grphm, ms.context)
 		codepoint, _ := utf8.DecodeRune(grphm)
+		w := ms.cells(codepoint, grphm, col)
+		if codepoint == '\n' || codepoint == '\r' {
+			col = 0
+		} else {
+			col += w
+		}
 		g := glyphing.ShapedGlyph{
 			XAdvance:  dimen.DU(w) * ms.em,
 			ClusterID: i,
@@ -61,13 +96,27 @@ func (ms msshape) Shape(text io.RuneReader, buf []glyphing.ShapedGlyph, ctx [][]
 		}
 		seq.Glyphs = append(seq.Glyphs, g)
 		seq.W += g.XAdvance
-		i++
+		i += utf8.RuneCount(grphm)
 	}
-	seq.H = 3 / 5 * ms.em
-	seq.D = 2 / 5 * ms.em
+	seq.H = ms.em * 3 / 5
+	seq.D = ms.em * 2 / 5
 	return seq, nil
 }
 
+// cells returns the number of cells grapheme grphm, starting with codepoint,
+// occupies at cell position col.
+func (ms msshape) cells(codepoint rune, grphm []byte, col int) int {
+	switch {
+	case codepoint == '\t':
+		return ms.tabWidth - col%ms.tabWidth
+	case unicode.IsControl(codepoint):
+		return 0
+	case unicode.In(codepoint, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0 // combining mark without base character, or format character
+	}
+	return uax11.Width(grphm, ms.context)
+}
+
 // SetScript does not do anything for monospace shapers.
 func (ms msshape) SetScript(scr language.Script) {
 	//
@@ -0,0 +1,46 @@
+package monospace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/glyphing"
+)
+
+func TestMonospaceCells(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.glyphs")
+	defer teardown()
+	//
+	em := 10 * dimen.PT
+	shaper := Shaper(em, nil, WithTabWidth(4))
+	for _, test := range []struct {
+		text  string
+		cells []int
+	}{
+		{"ab", []int{1, 1}},
+		{"a\tb", []int{1, 3, 1}},
+		{"abcd\tx", []int{1, 1, 1, 1, 4, 1}},
+		{"漢字", []int{2, 2}},
+		{"ＡＢ", []int{2, 2}},
+		{"e\u0301x", []int{1, 1}}, // é in NFD is a single grapheme
+		{"\u0301x", []int{0, 1}},  // combining mark without base character
+		{"a\n\tb", []int{1, 0, 4, 1}},
+	} {
+		seq, err := shaper.Shape(strings.NewReader(test.text), nil, nil, glyphing.Params{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(seq.Glyphs) != len(test.cells) {
+			t.Errorf("%q: expected %d glyphs, have %d", test.text, len(test.cells), len(seq.Glyphs))
+			continue
+		}
+		for i, g := range seq.Glyphs {
+			if g.XAdvance != dimen.DU(test.cells[i])*em {
+				t.Errorf("%q: expected glyph #%d to occupy %d cells, advance is %s",
+					test.text, i, test.cells[i], g.XAdvance)
+			}
+		}
+	}
+}
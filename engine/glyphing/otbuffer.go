@@ -0,0 +1,50 @@
+package glyphing
+
+import (
+	"unicode/utf8"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+	"github.com/npillmayer/tyse/core/font/opentype/otshaper"
+)
+
+// SequenceFromBuffer converts the output of the OpenType shaper (package otshaper)
+// to a glyph sequence. buf is the result of shaping input with font otf, which is
+// set at a given font size. Advances are scaled from font units to dimensions, and
+// clusters are converted from byte positions to rune positions within input.
+// Adjustments by GPOS lookups are added to the advances of glyphs and set as their
+// offsets, i.e., positioning counts for the width of the sequence.
+func SequenceFromBuffer(buf otshaper.Buffer, input string, otf *ot.Font, size dimen.DU) GlyphSequence {
+	seq := GlyphSequence{Glyphs: make([]ShapedGlyph, len(buf))}
+	if otf == nil {
+		return seq
+	}
+	metrics := otquery.FontMetrics(otf)
+	upem := metrics.UnitsPerEm
+	bytePos, runePos := 0, 0
+	for i, sg := range buf {
+		if sg.Cluster < bytePos { // clusters are ascending, but do not rely on it
+			bytePos, runePos = 0, 0
+		}
+		if sg.Cluster <= len(input) {
+			runePos += utf8.RuneCountInString(input[bytePos:sg.Cluster])
+			bytePos = sg.Cluster
+		}
+		g := &seq.Glyphs[i]
+		g.GID = sg.Index
+		g.ClusterID = runePos
+		g.CodePoint, _ = utf8.DecodeRuneInString(input[bytePos:])
+		g.RawMetrics.Advance = sg.Advance
+		g.RawMetrics.UnitsPerEm = upem
+		g.XAdvance = opentype.UnitsToDimen(sg.Advance+sg.Pos.XAdvance, upem, size)
+		g.YAdvance = opentype.UnitsToDimen(sg.Pos.YAdvance, upem, size)
+		g.XOffset = opentype.UnitsToDimen(sg.Pos.XPlacement, upem, size)
+		g.YOffset = opentype.UnitsToDimen(sg.Pos.YPlacement, upem, size)
+		seq.W += g.XAdvance
+	}
+	seq.H = opentype.UnitsToDimen(metrics.Ascent, upem, size)
+	seq.D = -opentype.UnitsToDimen(metrics.Descent, upem, size)
+	return seq
+}
@@ -0,0 +1,183 @@
+package glyphing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"golang.org/x/text/language"
+)
+
+// FeatureGlobalEnd may be used as the end position of a FeatureRange to apply a
+// feature up to the end of a run.
+const FeatureGlobalEnd = int(^uint(0) >> 1)
+
+// --- Shaping configuration -------------------------------------------------
+
+// ShapingConfig collects shaping overrides for a document. Instead of switching
+// features and languages programmatically for every run of text, clients set up
+// a configuration once and wrap their shaper with ConfiguredShaper.
+type ShapingConfig struct {
+	Overrides   []ShapingOverride
+	NoSynthesis Synthesis // styles which must not be synthesized
+}
+
+// ShapingOverride changes shaping parameters for runs of text in a given context
+// and script. Examples are:
+//
+//   - disable ligatures for code set in a monospace font:
+//     {Context: "code", Features: -liga -calt}
+//   - force lining numerals in tables:
+//     {Context: "table", Features: +lnum +tnum}
+//   - set Turkish language for all Latin runs:
+//     {Script: Latn, Language: tr}
+type ShapingOverride struct {
+	Context  string           // context defined by the document, e.g. "code"; empty matches all
+	Script   language.Script  // script of a run; zero value matches all scripts
+	Language language.Tag     // language to set for a run, unless language.Und
+	Features []FeatureSetting // features to turn on or off for whole runs
+}
+
+// FeatureSetting turns an OpenType feature on or off.
+type FeatureSetting struct {
+	Feature ot.Tag // 4-letter feature tag
+	Arg     int    // optional argument for this feature
+	On      bool   // turn it on or off?
+}
+
+// Matches is true if an override applies to a run of text in a given context
+// and script.
+func (o ShapingOverride) Matches(context string, script language.Script) bool {
+	var none language.Script
+	return (o.Context == "" || o.Context == context) && (o.Script == none || o.Script == script)
+}
+
+// Apply returns shaping parameters for a run of text in a given context, with
+// all matching overrides applied in order. Features of overrides are appended to
+// the features of params, spanning the whole run. As later features take
+// precedence, overrides win over settings for the run. Styles the configuration
+// forbids to synthesize are removed from the parameters.
+func (cfg *ShapingConfig) Apply(params Params, context string) Params {
+	if cfg == nil {
+		return params
+	}
+	params.Synthesis &^= cfg.NoSynthesis
+	var features []FeatureRange
+	for _, o := range cfg.Overrides {
+		if !o.Matches(context, params.Script) {
+			continue
+		}
+		if o.Language != language.Und {
+			params.Language = o.Language
+		}
+		features = append(features, RunFeatures(o.Features)...)
+	}
+	if len(features) > 0 {
+		all := make([]FeatureRange, 0, len(params.Features)+len(features))
+		params.Features = append(append(all, params.Features...), features...)
+	}
+	return params
+}
+
+// ParseFeatureSetting parses a feature setting in the notation used by HarfBuzz
+// and CSS: "liga" or "+liga" turns a feature on, "-liga" turns it off, and
+// "salt=2" selects an alternate.
+func ParseFeatureSetting(s string) (FeatureSetting, error) {
+	s = strings.TrimSpace(s)
+	f := FeatureSetting{On: true}
+	if strings.HasPrefix(s, "-") {
+		f.On = false
+		s = s[1:]
+	} else {
+		s = strings.TrimPrefix(s, "+")
+	}
+	if tag, arg, found := strings.Cut(s, "="); found {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return FeatureSetting{}, fmt.Errorf("illegal argument for feature %q: %q", tag, arg)
+		}
+		f.Arg, f.On, s = n, n > 0, tag
+	}
+	if len(s) != 4 {
+		return FeatureSetting{}, fmt.Errorf("illegal feature tag %q", s)
+	}
+	f.Feature = ot.T(s)
+	return f, nil
+}
+
+// ReadShapingConfig reads a shaping configuration in JSON format, e.g.:
+//
+//	{ "overrides": [
+//	    { "context": "code", "features": [ "-liga", "-calt" ] },
+//	    { "context": "table", "features": [ "lnum", "tnum" ] },
+//	    { "script": "Latn", "language": "tr" }
+//	],
+//	  "synthesis": "weight" }
+//
+// Key "synthesis" lists the styles which may be synthesized, in the notation of
+// CSS property font-synthesis. If it is missing, all styles may be synthesized.
+func ReadShapingConfig(r io.Reader) (*ShapingConfig, error) {
+	var doc struct {
+		Overrides []struct {
+			Context  string   `json:"context"`
+			Script   string   `json:"script"`
+			Language string   `json:"language"`
+			Features []string `json:"features"`
+		} `json:"overrides"`
+		Synthesis *string `json:"synthesis"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	cfg := &ShapingConfig{}
+	if doc.Synthesis != nil {
+		allowed, err := ParseFontSynthesis(*doc.Synthesis)
+		if err != nil {
+			return nil, fmt.Errorf("shaping config: %w", err)
+		}
+		cfg.NoSynthesis = AllSynthesis &^ allowed
+	}
+	for i, o := range doc.Overrides {
+		override := ShapingOverride{Context: o.Context}
+		var err error
+		if o.Script != "" {
+			if override.Script, err = language.ParseScript(o.Script); err != nil {
+				return nil, fmt.Errorf("shaping override #%d: %w", i, err)
+			}
+		}
+		if o.Language != "" {
+			if override.Language, err = language.Parse(o.Language); err != nil {
+				return nil, fmt.Errorf("shaping override #%d: %w", i, err)
+			}
+		}
+		for _, s := range o.Features {
+			f, err := ParseFeatureSetting(s)
+			if err != nil {
+				return nil, fmt.Errorf("shaping override #%d: %w", i, err)
+			}
+			override.Features = append(override.Features, f)
+		}
+		cfg.Overrides = append(cfg.Overrides, override)
+	}
+	return cfg, nil
+}
+
+// ConfiguredShaper wraps a shaper to apply a shaping configuration to the
+// parameters of every call to Shape. context is the document context in which the
+// shaper will be used, e.g. "code".
+func ConfiguredShaper(shaper Shaper, cfg *ShapingConfig, context string) Shaper {
+	return configuredShaper{shaper: shaper, cfg: cfg, context: context}
+}
+
+type configuredShaper struct {
+	shaper  Shaper
+	cfg     *ShapingConfig
+	context string
+}
+
+func (cs configuredShaper) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, params Params) (GlyphSequence, error) {
+	return cs.shaper.Shape(text, buf, ctx, cs.cfg.Apply(params, cs.context))
+}
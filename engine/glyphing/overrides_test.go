@@ -0,0 +1,72 @@
+package glyphing
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"golang.org/x/text/language"
+)
+
+func TestParseFeatureSetting(t *testing.T) {
+	for _, test := range []struct {
+		s   string
+		f   FeatureSetting
+		err bool
+	}{
+		{"liga", FeatureSetting{Feature: ot.T("liga"), On: true}, false},
+		{"-liga", FeatureSetting{Feature: ot.T("liga")}, false},
+		{"+lnum", FeatureSetting{Feature: ot.T("lnum"), On: true}, false},
+		{"salt=2", FeatureSetting{Feature: ot.T("salt"), Arg: 2, On: true}, false},
+		{"salt=0", FeatureSetting{Feature: ot.T("salt")}, false},
+		{"ligature", FeatureSetting{}, true},
+		{"salt=x", FeatureSetting{}, true},
+	} {
+		f, err := ParseFeatureSetting(test.s)
+		if (err != nil) != test.err || f != test.f {
+			t.Errorf("%q: expected %v (error=%v), have %v (%v)", test.s, test.f, test.err, f, err)
+		}
+	}
+}
+
+func TestShapingConfig(t *testing.T) {
+	cfg, err := ReadShapingConfig(strings.NewReader(`{ "overrides": [
+		{ "context": "code", "features": [ "-liga", "-calt" ] },
+		{ "context": "table", "features": [ "lnum", "tnum" ] },
+		{ "script": "Latn", "language": "tr" }
+	] }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latin := language.MustParseScript("Latn")
+	greek := language.MustParseScript("Grek")
+	var captured Params
+	shaper := ConfiguredShaper(shaperFunc(func(p Params) { captured = p }), cfg, "code")
+	run := []FeatureRange{{Feature: ot.T("liga"), On: true, Start: 0, End: 3}}
+	shaper.Shape(strings.NewReader("fi"), nil, nil, Params{Script: latin, Features: run})
+	if captured.Language.String() != "tr" {
+		t.Errorf("expected Latin run to be shaped with Turkish language, is %v", captured.Language)
+	}
+	if len(captured.Features) != 3 || captured.Features[1].Feature != ot.T("liga") ||
+		captured.Features[1].On || captured.Features[1].End != FeatureGlobalEnd {
+		t.Errorf("expected ligatures to be switched off for code, have %v", captured.Features)
+	}
+	if len(run) != 1 {
+		t.Errorf("expected features of run to be unchanged")
+	}
+	p := cfg.Apply(Params{Script: greek}, "table")
+	if p.Language != language.Und || len(p.Features) != 2 || !p.Features[0].On {
+		t.Errorf("expected Greek run in a table to have lining numerals only, have %v", p)
+	}
+	if _, err := ReadShapingConfig(strings.NewReader(`{"overrides":[{"features":["x"]}]}`)); err == nil {
+		t.Errorf("expected illegal feature tag to be rejected")
+	}
+}
+
+type shaperFunc func(Params)
+
+func (f shaperFunc) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, p Params) (GlyphSequence, error) {
+	f(p)
+	return GlyphSequence{}, nil
+}
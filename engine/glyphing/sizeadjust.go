@@ -0,0 +1,98 @@
+package glyphing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+)
+
+// --- Font size adjustment --------------------------------------------------
+
+// https://www.w3.org/TR/css-fonts-5/#font-size-adjust-prop
+//
+// The apparent size of text depends on the x-height of its font rather than on
+// the font size. CSS property font-size-adjust keeps the x-height of text when
+// fonts are substituted, e.g. by fallback fonts: every font is scaled such that
+// its x-height is the font size times a given aspect value. The aspect value of
+// a font is its x-height divided by its font size.
+
+// SizeAdjustFromFont is the value of Params.SizeAdjust for "font-size-adjust:
+// from-font": the aspect value is taken from the font of the run.
+const SizeAdjustFromFont = -1.0
+
+// ParseFontSizeAdjust parses a value of CSS property font-size-adjust: "none"
+// results in 0, "from-font" in SizeAdjustFromFont, and a number in the aspect
+// value. Font metric keyword "ex-height" may precede the value; other metrics
+// are not supported.
+func ParseFontSizeAdjust(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 2 && fields[0] == "ex-height" {
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("illegal value for font-size-adjust: %q", s)
+	}
+	switch fields[0] {
+	case "none":
+		return 0, nil
+	case "from-font":
+		return SizeAdjustFromFont, nil
+	}
+	adjust, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || adjust < 0 {
+		return 0, fmt.Errorf("illegal value for font-size-adjust: %q", s)
+	}
+	return adjust, nil
+}
+
+// AspectValue returns the aspect value of font otf, i.e. its x-height divided by
+// its font size. For fonts without information about their x-height, AspectValue
+// returns 0.
+func AspectValue(otf *ot.Font) float64 {
+	if otf == nil {
+		return 0
+	}
+	metrics := otquery.LineMetrics(otf, otquery.AutoMetrics)
+	if metrics.XHeight <= 0 || metrics.UnitsPerEm <= 0 {
+		return 0
+	}
+	return float64(metrics.XHeight) / float64(metrics.UnitsPerEm)
+}
+
+// AdjustedSize returns the size to set a font with aspect value aspect at, for
+// text of font size size with font-size-adjust set to adjust. If either value is
+// unknown or zero, size is returned unchanged.
+func AdjustedSize(size float32, adjust, aspect float64) float32 {
+	if adjust <= 0 || aspect <= 0 {
+		return size
+	}
+	return float32(float64(size) * adjust / aspect)
+}
+
+// aspects caches the aspect values of fonts: *font.ScalableFont ⇒ float64
+var aspects sync.Map
+
+// aspectOf returns the aspect value of the font of typecase tc, or 0 if it is
+// unknown.
+func aspectOf(tc *font.TypeCase) float64 {
+	if tc == nil || tc.ScalableFontParent() == nil {
+		return 0
+	}
+	sf := tc.ScalableFontParent()
+	if aspect, ok := aspects.Load(sf); ok {
+		return aspect.(float64)
+	}
+	var aspect float64
+	if otf, err := ot.Parse(sf.Binary); err != nil {
+		tracer().Errorf("cannot parse font %s: %v", sf.Fontname, err)
+	} else {
+		aspect = AspectValue(otf)
+	}
+	aspects.Store(sf, aspect)
+	return aspect
+}
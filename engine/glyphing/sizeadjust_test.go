@@ -0,0 +1,74 @@
+package glyphing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/core/font/fontregistry"
+	"golang.org/x/text/language"
+)
+
+func TestParseFontSizeAdjust(t *testing.T) {
+	for _, test := range []struct {
+		s      string
+		adjust float64
+		err    bool
+	}{
+		{"none", 0, false},
+		{"0.5", 0.5, false},
+		{"ex-height 0.45", 0.45, false},
+		{"from-font", SizeAdjustFromFont, false},
+		{"cap-height 0.7", 0, true},
+		{"-1", 0, true},
+	} {
+		adjust, err := ParseFontSizeAdjust(test.s)
+		if (err != nil) != test.err || adjust != test.adjust {
+			t.Errorf("%q: expected %v (error=%v), have %v (%v)", test.s, test.adjust, test.err, adjust, err)
+		}
+	}
+	if size := AdjustedSize(12, 0.5, 0.4); size != 15 {
+		t.Errorf("expected font with small x-height to be enlarged to 15pt, is %.2fpt", size)
+	}
+	if size := AdjustedSize(12, 0.5, 0); size != 12 {
+		t.Errorf("expected font without x-height to keep its size, is %.2fpt", size)
+	}
+}
+
+func TestFallbackSizeAdjust(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.glyphs")
+	defer teardown()
+	//
+	fonts := "../../core/locate/resources/packaged/fonts/"
+	logo, err := font.LoadOpenTypeFont(fonts + "TySELogo-Regular.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gentium, err := font.LoadOpenTypeFont(fonts + "GentiumPlus-R.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := logo.PrepareCase(12.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := fontregistry.NewRegistry(fontregistry.WithFallbacks(language.Script{}, "gentium"))
+	fr.StoreFont("gentium", gentium)
+	shaper := FallbackShaper(runeShaper(1000), fr)
+	seq, err := shaper.Shape(strings.NewReader("Tλ"), nil, nil, Params{Font: tc, SizeAdjust: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback := seq.Glyphs[1].Font
+	if fallback == nil {
+		t.Fatalf("expected 'λ' to be set with the fallback font")
+	}
+	aspect := aspectOf(fallback)
+	if aspect <= 0 {
+		t.Fatalf("expected Gentium to have an x-height")
+	}
+	if size := fallback.PtSize(); size != AdjustedSize(12, 0.5, aspect) {
+		t.Errorf("expected fallback font to be scaled to an x-height of 6pt, is set at %.2fpt", size)
+	}
+}
@@ -0,0 +1,185 @@
+package glyphing
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+	"github.com/npillmayer/tyse/core/font/opentype/otquery"
+)
+
+// --- Synthetic styles ------------------------------------------------------
+
+// Synthesis is a set of styles to synthesize for a run of text, as the font in
+// use lacks them. Small caps are synthesized by the shaper (see SynthesizingShaper),
+// while oblique and bold styles are synthesized by renderers: glyphs are slanted
+// by ObliqueShear, and glyph strokes are widened by BoldStroke.
+type Synthesis uint8
+
+// Styles which may be synthesized.
+const (
+	SyntheticOblique   Synthesis = 1 << iota // slant upright glyphs
+	SyntheticBold                            // widen glyph strokes
+	SyntheticSmallCaps                       // set lowercase letters as scaled-down capitals
+	NoSynthesis        Synthesis = 0
+	AllSynthesis                 = SyntheticOblique | SyntheticBold | SyntheticSmallCaps
+)
+
+const (
+	// ObliqueShear is the horizontal shear of synthetic oblique glyphs, i.e. the
+	// tangent of 14°, the slant the CSS spec recommends.
+	ObliqueShear = 0.25
+	// SmallCapsScale is the scale of synthetic small capitals, relative to the
+	// capitals of the font.
+	SmallCapsScale = 0.7
+)
+
+// SmallCap flags a glyph as a synthetic small capital, which renderers have to
+// scale by SmallCapsScale. Advance and offsets of the glyph are already scaled.
+const SmallCap GlyphFlags = 0x02
+
+// BoldStroke returns the amount to widen glyph strokes by for synthetic bold
+// glyphs of a font set at size em. Glyph advances grow by the same amount.
+func BoldStroke(em dimen.DU) dimen.DU {
+	return em / 32
+}
+
+// SynthesisFor determines which of the requested styles have to be synthesized
+// for font otf: oblique, if the font is not an italic face; bold, if it is not
+// a bold face; and small caps, if it lacks feature 'smcp'.
+func SynthesisFor(otf *ot.Font, requested Synthesis) Synthesis {
+	if otf == nil || requested == NoSynthesis {
+		return NoSynthesis
+	}
+	italic, bold := otquery.FontStyle(otf)
+	syn := requested
+	if italic {
+		syn &^= SyntheticOblique
+	}
+	if bold {
+		syn &^= SyntheticBold
+	}
+	if syn&SyntheticSmallCaps != 0 && otquery.HasFeature(otf, ot.T("smcp")) {
+		syn &^= SyntheticSmallCaps
+	}
+	return syn
+}
+
+// ParseFontSynthesis parses a value of CSS property font-synthesis, which lists
+// the styles which may be synthesized: "none", or any of "weight", "style" and
+// "small-caps".
+func ParseFontSynthesis(s string) (Synthesis, error) {
+	s = strings.TrimSpace(s)
+	if s == "none" {
+		return NoSynthesis, nil
+	}
+	var syn Synthesis
+	for _, keyword := range strings.Fields(s) {
+		switch keyword {
+		case "weight":
+			syn |= SyntheticBold
+		case "style":
+			syn |= SyntheticOblique
+		case "small-caps":
+			syn |= SyntheticSmallCaps
+		default:
+			return NoSynthesis, fmt.Errorf("illegal value for font-synthesis: %q", keyword)
+		}
+	}
+	return syn, nil
+}
+
+// SynthesizingShaper wraps a shaper to synthesize the styles requested by
+// Params.Synthesis. Lowercase letters are set as capitals scaled by SmallCapsScale
+// for synthetic small caps; glyph advances are widened for synthetic bold. The
+// resulting glyph sequence carries the synthesis flags for renderers.
+func SynthesizingShaper(shaper Shaper) Shaper {
+	return synthesizingShaper{shaper: shaper}
+}
+
+type synthesizingShaper struct {
+	shaper Shaper
+}
+
+func (ss synthesizingShaper) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, params Params) (GlyphSequence, error) {
+	if params.Synthesis == NoSynthesis || text == nil {
+		return ss.shaper.Shape(text, buf, ctx, params)
+	}
+	var seq GlyphSequence
+	var err error
+	if params.Synthesis&SyntheticSmallCaps != 0 {
+		seq, err = ss.shapeSmallCaps(text, buf, ctx, params)
+	} else {
+		seq, err = ss.shaper.Shape(text, buf, ctx, params)
+	}
+	if err != nil {
+		return seq, err
+	}
+	if params.Synthesis&SyntheticBold != 0 && params.Font != nil {
+		stroke := BoldStroke(dimen.DU(params.Font.PtSize() * float32(dimen.PT)))
+		for i := range seq.Glyphs {
+			seq.Glyphs[i].XAdvance += stroke
+			seq.W += stroke
+		}
+	}
+	seq.Synthesis = params.Synthesis
+	return seq, nil
+}
+
+// shapeSmallCaps splits text into runs of grapheme clusters starting with a
+// lowercase letter and runs of other clusters. Lowercase runs are shaped in upper
+// case and scaled down to small capitals.
+func (ss synthesizingShaper) shapeSmallCaps(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune,
+	params Params) (GlyphSequence, error) {
+	//
+	var runes []rune
+	for {
+		r, sz, err := text.ReadRune()
+		if sz == 0 || err != nil {
+			break
+		}
+		runes = append(runes, r)
+	}
+	seq := GlyphSequence{Glyphs: buf[:0]}
+	clusters := graphemeStarts(runes)
+	for start := 0; start < len(runes); {
+		lower := unicode.IsLower(runes[start])
+		end := start + 1
+		for end < len(runes) && (!clusters[end] || unicode.IsLower(runes[end]) == lower) {
+			end++
+		}
+		run := runes[start:end]
+		if lower {
+			run = []rune(strings.ToUpper(string(run)))
+			if len(run) != end-start { // e.g., 'ß' ⇒ "SS": keep as is
+				run = runes[start:end]
+			}
+		}
+		part, err := ss.shaper.Shape(strings.NewReader(string(run)), nil, ctx, params)
+		if err != nil {
+			return seq, err
+		}
+		for _, g := range part.Glyphs {
+			g.ClusterID += start
+			if lower {
+				g.XAdvance = scaleSmallCap(g.XAdvance)
+				g.YAdvance = scaleSmallCap(g.YAdvance)
+				g.XOffset = scaleSmallCap(g.XOffset)
+				g.YOffset = scaleSmallCap(g.YOffset)
+				g.Flags |= SmallCap
+			}
+			seq.Glyphs = append(seq.Glyphs, g)
+			seq.W += g.XAdvance
+		}
+		seq.H, seq.D = max(seq.H, part.H), max(seq.D, part.D)
+		start = end
+	}
+	return seq, nil
+}
+
+func scaleSmallCap(d dimen.DU) dimen.DU {
+	return dimen.DU(float64(d) * SmallCapsScale)
+}
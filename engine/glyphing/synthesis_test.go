@@ -0,0 +1,88 @@
+package glyphing
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+func TestParseFontSynthesis(t *testing.T) {
+	for _, test := range []struct {
+		s   string
+		syn Synthesis
+		err bool
+	}{
+		{"none", NoSynthesis, false},
+		{"weight", SyntheticBold, false},
+		{"style small-caps", SyntheticOblique | SyntheticSmallCaps, false},
+		{"weight style small-caps", AllSynthesis, false},
+		{"bold", NoSynthesis, true},
+	} {
+		syn, err := ParseFontSynthesis(test.s)
+		if (err != nil) != test.err || syn != test.syn {
+			t.Errorf("%q: expected %v (error=%v), have %v (%v)", test.s, test.syn, test.err, syn, err)
+		}
+	}
+}
+
+func TestSyntheticSmallCaps(t *testing.T) {
+	shaper := SynthesizingShaper(runeShaper(1000))
+	seq, err := shaper.Shape(strings.NewReader("Tyse 1"), nil, nil, Params{Synthesis: SyntheticSmallCaps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq.Glyphs) != 6 {
+		t.Fatalf("expected 6 glyphs, have %d", len(seq.Glyphs))
+	}
+	if seq.Glyphs[1].GID != 'Y' || seq.Glyphs[1].Flags&SmallCap == 0 || seq.Glyphs[1].XAdvance != 700 {
+		t.Errorf("expected 'y' to be set as a small capital, have %v", seq.Glyphs[1])
+	}
+	if seq.Glyphs[0].Flags&SmallCap != 0 || seq.Glyphs[4].Flags&SmallCap != 0 {
+		t.Errorf("expected capitals and spaces to remain unscaled")
+	}
+	for i, g := range seq.Glyphs {
+		if g.ClusterID != i {
+			t.Errorf("expected glyph #%d to map to cluster %d, is %d", i, i, g.ClusterID)
+		}
+	}
+	if seq.W != 3*1000+3*700 || seq.Synthesis != SyntheticSmallCaps {
+		t.Errorf("expected width of 5100 and small caps synthesis, have %d and %v", seq.W, seq.Synthesis)
+	}
+	seq, _ = shaper.Shape(strings.NewReader("e\u0301"), nil, nil, Params{Synthesis: SyntheticSmallCaps})
+	if len(seq.Glyphs) != 2 || seq.Glyphs[1].Flags&SmallCap == 0 {
+		t.Errorf("expected combining mark to be scaled together with its base, have %v", seq.Glyphs)
+	}
+}
+
+func TestSynthesisConfig(t *testing.T) {
+	cfg, err := ReadShapingConfig(strings.NewReader(`{ "synthesis": "weight" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cfg.Apply(Params{Synthesis: AllSynthesis}, "")
+	if p.Synthesis != SyntheticBold {
+		t.Errorf("expected synthesis to be restricted to bold, is %v", p.Synthesis)
+	}
+	if SynthesisFor(nil, AllSynthesis) != NoSynthesis {
+		t.Errorf("expected no synthesis without a font")
+	}
+}
+
+// runeShaper sets every rune as a glyph with GID equal to the rune.
+type runeShaper dimen.DU
+
+func (rs runeShaper) Shape(text io.RuneReader, buf []ShapedGlyph, ctx [][]rune, p Params) (GlyphSequence, error) {
+	seq := GlyphSequence{Glyphs: buf[:0], H: dimen.DU(rs)}
+	for i := 0; ; i++ {
+		r, _, err := text.ReadRune()
+		if err != nil {
+			break
+		}
+		seq.Glyphs = append(seq.Glyphs, ShapedGlyph{ClusterID: i, GID: ot.GlyphIndex(r), CodePoint: r, XAdvance: dimen.DU(rs)})
+		seq.W += dimen.DU(rs)
+	}
+	return seq, nil
+}
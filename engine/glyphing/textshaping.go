@@ -26,8 +26,12 @@ const (
 
 // A ShapedGlyph lives in design space (result from the shaper, which lives in design space
 // as well, at least its interface).
+//
+// ClusterID maps a glyph back to the input of the shaper: it is the index of the
+// first rune of the cluster of input runes the glyph has been produced for. All
+// glyphs of a cluster share the same ClusterID.
 type ShapedGlyph struct {
-	ClusterID  int                       // position of code-point(s) for this glyph in original string
+	ClusterID  int                       // rune position of code-point(s) for this glyph in original string
 	XAdvance   dimen.DU                  // advance after glyph has been set, in design units
 	YAdvance   dimen.DU                  //
 	XOffset    dimen.DU                  // position of anchor dot for glyph, in design units
@@ -35,8 +39,18 @@ type ShapedGlyph struct {
 	RawMetrics opentype.GlyphMetricsInfo // metrics in font units
 	GID        ot.GlyphIndex             // glyph index within font
 	CodePoint  rune                      // code-point of first rune to produce this glyph
+	Flags      GlyphFlags                // flags set by the shaper
+	Font       *font.TypeCase            // fallback font of the glyph, nil for the font of the run
 }
 
+// GlyphFlags are flags a shaper sets for a glyph.
+type GlyphFlags uint8
+
+// UnsafeToBreak flags a glyph in front of which text must not be broken
+// without re-shaping both parts, as shaping crossed the cluster boundary
+// (e.g., for contextual alternates or kerning).
+const UnsafeToBreak GlyphFlags = 0x01
+
 func (g ShapedGlyph) String() string {
 	return fmt.Sprintf("(GID=%d, advance=%s)", g.GID, g.XOffset)
 }
@@ -53,11 +67,13 @@ type Shaper interface {
 
 // Params collects shaping parameters.
 type Params struct {
-	Font      *font.TypeCase  // use a font at a given point-size
-	Direction Direction       // writing direction
-	Script    language.Script // 4-letter ISO 15924 script identifier
-	Language  language.Tag    // BCP 47 language tag
-	Features  []FeatureRange  // OpenType features to apply
+	Font       *font.TypeCase  // use a font at a given point-size
+	Direction  Direction       // writing direction
+	Script     language.Script // 4-letter ISO 15924 script identifier
+	Language   language.Tag    // BCP 47 language tag
+	Features   []FeatureRange  // OpenType features to apply
+	Synthesis  Synthesis       // styles to synthesize, if the font lacks them (see SynthesisFor)
+	SizeAdjust float64         // aspect value to scale fonts to (CSS font-size-adjust), 0 for none
 }
 
 // FeatureRange tells a shaper to turn a certain OpenType feature on or off for a
@@ -69,10 +85,16 @@ type FeatureRange struct {
 	Start, End int    // position of code-points to apply feature for
 }
 
-// GlyphSequence contains a sequence of shaped glyphs.
+// GlyphSequence contains a sequence of shaped glyphs. Every shaper produces a
+// glyph sequence, so clients of shapers do not have to care about which shaper
+// is in use.
+//
+// Glyphs are in visual order. For right-to-left text, cluster IDs therefore
+// decrease along the sequence.
 type GlyphSequence struct {
-	Glyphs  []ShapedGlyph // resulting sequence of glyphs
-	W, H, D dimen.DU      // width, height, depth of bounding box
+	Glyphs    []ShapedGlyph // resulting sequence of glyphs
+	W, H, D   dimen.DU      // width, height, depth of bounding box
+	Synthesis Synthesis     // synthetic styles renderers have to apply
 }
 
 func (seq GlyphSequence) BoundingBox() (w dimen.DU, h dimen.DU, d dimen.DU) {
@@ -0,0 +1,145 @@
+package glyphing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+// --- CSS font variants -----------------------------------------------------
+
+// https://www.w3.org/TR/css-fonts-4/#font-variant-ligatures-prop
+//
+// CSS controls OpenType features by the font-variant-* properties, which select
+// features by keywords, and by font-feature-settings, which switches features
+// by their tags. Settings of font-feature-settings take precedence, therefore
+// clients should append them after the features of the font-variant-* properties
+// (see RunFeatures).
+
+var ligatureKeywords = map[string][]FeatureSetting{
+	"none":                       {off("liga"), off("clig"), off("dlig"), off("hlig"), off("calt")},
+	"common-ligatures":           {on("liga"), on("clig")},
+	"no-common-ligatures":        {off("liga"), off("clig")},
+	"discretionary-ligatures":    {on("dlig")},
+	"no-discretionary-ligatures": {off("dlig")},
+	"historical-ligatures":       {on("hlig")},
+	"no-historical-ligatures":    {off("hlig")},
+	"contextual":                 {on("calt")},
+	"no-contextual":              {off("calt")},
+}
+
+var numericKeywords = map[string][]FeatureSetting{
+	"lining-nums":        {on("lnum")},
+	"oldstyle-nums":      {on("onum")},
+	"proportional-nums":  {on("pnum")},
+	"tabular-nums":       {on("tnum")},
+	"diagonal-fractions": {on("frac")},
+	"stacked-fractions":  {on("afrc")},
+	"ordinal":            {on("ordn")},
+	"slashed-zero":       {on("zero")},
+}
+
+var capsKeywords = map[string][]FeatureSetting{
+	"small-caps":      {on("smcp")},
+	"all-small-caps":  {on("c2sc"), on("smcp")},
+	"petite-caps":     {on("pcap")},
+	"all-petite-caps": {on("c2pc"), on("pcap")},
+	"unicase":         {on("unic")},
+	"titling-caps":    {on("titl")},
+}
+
+func on(tag string) FeatureSetting {
+	return FeatureSetting{Feature: ot.T(tag), On: true}
+}
+
+func off(tag string) FeatureSetting {
+	return FeatureSetting{Feature: ot.T(tag)}
+}
+
+// ParseFontVariantLigatures returns the features selected by a value of CSS
+// property font-variant-ligatures, e.g. "no-common-ligatures contextual".
+// "normal" leaves the features at their defaults and results in no settings.
+func ParseFontVariantLigatures(s string) ([]FeatureSetting, error) {
+	return parseVariantKeywords(s, "font-variant-ligatures", ligatureKeywords)
+}
+
+// ParseFontVariantNumeric returns the features selected by a value of CSS
+// property font-variant-numeric, e.g. "oldstyle-nums tabular-nums".
+// "normal" results in no settings.
+func ParseFontVariantNumeric(s string) ([]FeatureSetting, error) {
+	return parseVariantKeywords(s, "font-variant-numeric", numericKeywords)
+}
+
+// ParseFontVariantCaps returns the features selected by a value of CSS property
+// font-variant-caps, e.g. "small-caps". "normal" results in no settings. For
+// fonts lacking small capitals, clients may request SyntheticSmallCaps.
+func ParseFontVariantCaps(s string) ([]FeatureSetting, error) {
+	return parseVariantKeywords(s, "font-variant-caps", capsKeywords)
+}
+
+func parseVariantKeywords(s, property string, keywords map[string][]FeatureSetting) ([]FeatureSetting, error) {
+	var features []FeatureSetting
+	for _, keyword := range strings.Fields(s) {
+		if keyword == "normal" {
+			continue
+		}
+		f, ok := keywords[keyword]
+		if !ok {
+			return nil, fmt.Errorf("illegal value for %s: %q", property, keyword)
+		}
+		features = append(features, f...)
+	}
+	return features, nil
+}
+
+// ParseFontFeatureSettings parses a value of CSS property font-feature-settings,
+// a comma-separated list of quoted feature tags, each optionally followed by a
+// value: `"liga" 0, "tnum", "ss01" on, "salt" 2`. "normal" results in no settings.
+func ParseFontFeatureSettings(s string) ([]FeatureSetting, error) {
+	s = strings.TrimSpace(s)
+	if s == "normal" || s == "" {
+		return nil, nil
+	}
+	var features []FeatureSetting
+	for _, item := range strings.Split(s, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("illegal feature setting %q", strings.TrimSpace(item))
+		}
+		tag := strings.Trim(fields[0], `"'`)
+		if len(tag) != 4 || len(fields[0]) != 6 {
+			return nil, fmt.Errorf("illegal feature tag %s", fields[0])
+		}
+		f := on(tag)
+		if len(fields) == 2 {
+			switch fields[1] {
+			case "on":
+			case "off":
+				f.On = false
+			default:
+				n, err := strconv.Atoi(fields[1])
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("illegal argument for feature %q: %q", tag, fields[1])
+				}
+				f.Arg, f.On = n, n > 0
+			}
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// RunFeatures returns feature ranges for settings, spanning whole runs of text.
+// Later settings take precedence over earlier ones.
+func RunFeatures(settings []FeatureSetting) []FeatureRange {
+	if len(settings) == 0 {
+		return nil
+	}
+	features := make([]FeatureRange, len(settings))
+	for i, f := range settings {
+		features[i] = FeatureRange{Feature: f.Feature, Arg: f.Arg, On: f.On, Start: 0, End: FeatureGlobalEnd}
+	}
+	return features
+}
@@ -0,0 +1,45 @@
+package glyphing
+
+import (
+	"testing"
+
+	"github.com/npillmayer/tyse/core/font/opentype/ot"
+)
+
+func TestFontVariants(t *testing.T) {
+	f, err := ParseFontVariantLigatures("no-common-ligatures contextual")
+	if err != nil || len(f) != 3 || f[0] != off("liga") || f[2] != on("calt") {
+		t.Errorf("expected common ligatures off and contextual alternates on, have %v (%v)", f, err)
+	}
+	if f, err = ParseFontVariantNumeric("oldstyle-nums tabular-nums"); err != nil || len(f) != 2 ||
+		f[1].Feature != ot.T("tnum") {
+		t.Errorf("expected old-style tabular numerals, have %v (%v)", f, err)
+	}
+	if f, err = ParseFontVariantCaps("normal"); err != nil || len(f) != 0 {
+		t.Errorf("expected no features for normal caps, have %v (%v)", f, err)
+	}
+	if _, err = ParseFontVariantCaps("tiny-caps"); err == nil {
+		t.Errorf("expected error for illegal value of font-variant-caps")
+	}
+}
+
+func TestParseFontFeatureSettings(t *testing.T) {
+	f, err := ParseFontFeatureSettings(`"liga" 0, "tnum", "ss01" on, 'salt' 2`)
+	if err != nil || len(f) != 4 {
+		t.Fatalf("expected 4 feature settings, have %v (%v)", f, err)
+	}
+	expected := []FeatureSetting{off("liga"), on("tnum"), on("ss01"), {Feature: ot.T("salt"), Arg: 2, On: true}}
+	for i := range expected {
+		if f[i] != expected[i] {
+			t.Errorf("expected setting #%d to be %v, is %v", i, expected[i], f[i])
+		}
+	}
+	for _, s := range []string{`liga`, `"liga" x`, `"lig" 1`} {
+		if _, err := ParseFontFeatureSettings(s); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+	if r := RunFeatures(f); len(r) != 4 || r[3].Arg != 2 || r[3].End != FeatureGlobalEnd {
+		t.Errorf("expected settings to span whole runs, have %v", r)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Package golden is a harness for regression tests of the engine's layout output.
+
+Golden tests typeset fixture documents and compare a canonical text dump of the
+resulting pages against golden files. The dump lists pages, regions, boxes and
+line boxes, and for every line its knots, positioned as set, together with the
+glyphs of text boxes. Positions and dimensions are given in big points with two
+decimals, so dumps are stable across runs and platforms and differences are
+easy to review.
+
+Fixtures are HTML files in a directory, usually "testdata", with an optional
+stylesheet of the same name:
+
+	testdata/hyphenation.html
+	testdata/hyphenation.css      (optional)
+	testdata/hyphenation.golden   (written with flag -update)
+
+A test function runs all fixtures of a directory:
+
+	func TestLayoutGolden(t *testing.T) {
+	    golden.Run(t, "testdata", dimen.DINA5)
+	}
+
+After an intended change of layout output, golden files are re-written with
+
+	go test ./... -run Golden -update
+
+and the changes of the golden files are reviewed like source changes. Test
+packages using this package must not define a flag "update" of their own.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package golden
+
+import (
+	"github.com/npillmayer/schuko/tracing"
+)
+
+// tracer traces with key 'tyse.engine'.
+func tracer() tracing.Trace {
+	return tracing.Select("tyse.engine")
+}
@@ -0,0 +1,171 @@
+package golden
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+	"github.com/npillmayer/tyse/engine/glyphing"
+)
+
+// Dump writes a canonical text dump of pages to w. Every item is written on a
+// line of its own, indented by its level in the tree of pages, regions, boxes,
+// lines and knots:
+//
+//	page 1 right 419.53x595.28 content [56.69,56.69 306.15x481.89]
+//	  region main flow=main [56.69,56.69 306.15x481.89]
+//	    box body ▩ [0.00,0.00 306.15x24.00]
+//	      box p ▩ [0.00,0.00 306.15x24.00]
+//	        line 0 [0.00,0.00 306.15x12.00] baseline=9.00 ratio=0.250
+//	          text "Hello" x=0.00 w=22.00 glyphs=[43 72 79 79 82]
+//	          glue x=22.00 w=4.25
+//
+// Positions of boxes are relative to their parent box, positions of knots
+// relative to the start of their line. Lines of paragraphs are dumped in place
+// of the paragraph's children.
+func Dump(w io.Writer, pages []*layout.Page) error {
+	d := &dumper{w: bufio.NewWriter(w)}
+	for _, page := range pages {
+		d.page(page)
+	}
+	return d.w.Flush()
+}
+
+type dumper struct {
+	w     *bufio.Writer
+	level int
+}
+
+func (d *dumper) printf(format string, args ...interface{}) {
+	d.w.WriteString(strings.Repeat("  ", d.level))
+	fmt.Fprintf(d.w, format, args...)
+	d.w.WriteByte('\n')
+}
+
+func (d *dumper) page(page *layout.Page) {
+	d.printf("page %d %s %sx%s content %s", page.Number, page.Side,
+		bp(page.Rect.Width()), bp(page.Rect.Height()), rect(page.Content))
+	d.level++
+	for _, rbox := range page.Regions {
+		d.printf("region %s flow=%s %s", rbox.Name, rbox.Flow, rect(rbox.Frame))
+		d.level++
+		for _, c := range rbox.Content {
+			d.container(c)
+		}
+		d.level--
+	}
+	d.level--
+}
+
+func (d *dumper) container(c *frame.Container) {
+	name := "anon"
+	if n := c.DOMNode(); n != nil {
+		name = n.NodeName()
+	}
+	d.printf("box %s %s %s", name, c.Display.Symbol(), rect(frameOf(c)))
+	d.level++
+	defer func() { d.level-- }()
+	if ctx, ok := c.Context.(*layout.InlineContext); ok {
+		for _, line := range ctx.Lines() {
+			d.line(line)
+		}
+		return
+	}
+	for _, ch := range c.TreeNode().Children(true) {
+		if ch.Payload != nil {
+			d.container(ch.Payload)
+		}
+	}
+}
+
+func (d *dumper) line(line *frame.Container) {
+	lbox, ok := line.RenderNode().(*inline.LineBox)
+	if !ok || lbox.Line() == nil {
+		d.printf("line ? %s", rect(frameOf(line)))
+		return
+	}
+	l := lbox.Line()
+	expansion := ""
+	if l.Expansion != 0 {
+		expansion = fmt.Sprintf(" expansion=%.3f", l.Expansion)
+	}
+	d.printf("line %d %s baseline=%s ratio=%.3f%s", l.Number, rect(frameOf(line)),
+		bp(lbox.Baseline()), l.Ratio, expansion)
+	d.level++
+	d.setLine(l)
+	d.level--
+}
+
+// setLine dumps the knots of a set line.
+func (d *dumper) setLine(l *inline.SetLine) {
+	if l.Marker != nil {
+		d.printf("marker %q w=%s", l.Marker.Text(), bp(l.Marker.Width))
+	}
+	for _, item := range l.Items {
+		pos := fmt.Sprintf("x=%s w=%s", bp(item.X), bp(item.W))
+		switch k := item.Knot.(type) {
+		case khipu.TextBox:
+			d.printf("text %q %s glyphs=%s", k.Text(), pos, glyphs(k.Glyphs()))
+		case *khipu.TextBox:
+			d.printf("text %q %s glyphs=%s", k.Text(), pos, glyphs(k.Glyphs()))
+		case khipu.Glue:
+			d.printf("glue %s", pos)
+		case khipu.Kern:
+			d.printf("kern %s", pos)
+		case khipu.Penalty:
+			d.printf("penalty %d %s", k.Demerits(), pos)
+		case khipu.Discretionary:
+			d.printf("discretionary %q %s", k.HyphenChar, pos)
+		case khipu.Leaders:
+			d.printf("leaders %s", pos)
+		case khipu.Tab:
+			d.printf("tab %s", pos)
+		case khipu.VBox:
+			d.printf("vbox %s", pos)
+		default:
+			d.printf("knot type=%d %s", item.Knot.Type(), pos)
+		}
+	}
+}
+
+// frameOf returns the frame of a container, relative to its parent.
+func frameOf(c *frame.Container) dimen.Rect {
+	box := c.CSSBox()
+	if box == nil {
+		return dimen.Rect{}
+	}
+	var w, h dimen.DU
+	box.W.Match().Just(&w)
+	box.H.Match().Just(&h)
+	botR := box.TopL
+	botR.Shift(dimen.Point{X: w, Y: h})
+	return dimen.Rect{TopL: box.TopL, BotR: botR}
+}
+
+// bp formats a dimension in big points, with two decimals.
+func bp(x dimen.DU) string {
+	return fmt.Sprintf("%.2f", x.Points())
+}
+
+func rect(r dimen.Rect) string {
+	return fmt.Sprintf("[%s,%s %sx%s]", bp(r.TopL.X), bp(r.TopL.Y), bp(r.Width()), bp(r.Height()))
+}
+
+func glyphs(seq glyphing.GlyphSequence) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, g := range seq.Glyphs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%d", g.GID)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
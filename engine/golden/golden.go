@@ -0,0 +1,157 @@
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	douceur "github.com/aymerick/douceur/css"
+	"github.com/aymerick/douceur/parser"
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/dom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom"
+	"github.com/npillmayer/tyse/engine/dom/style/cssom/douceuradapter"
+	"github.com/npillmayer/tyse/engine/frame"
+	"github.com/npillmayer/tyse/engine/frame/boxtree"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+	"golang.org/x/net/html"
+)
+
+// Update selects writing golden files instead of comparing against them. It is
+// set with flag -update of the test binary.
+var Update = flag.Bool("update", false, "update golden files")
+
+// Typeset typesets an HTML document into pages of size papersize, applying
+// stylesheet css in addition to the stylesheets of the document. Pages have a
+// single region for the main flow.
+//
+// Typeset performs a single pass of layout and pagination, without resolving
+// cross references or filling in a table of contents (see package api).
+func Typeset(document, css string, papersize dimen.Point) ([]*layout.Page, error) {
+	h, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+	var sheet cssom.StyleSheet = douceuradapter.Wrap(&douceur.Stylesheet{})
+	if css != "" {
+		parsed, err := parser.Parse(css)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse stylesheet: %w", err)
+		}
+		sheet.AppendRules(douceuradapter.Wrap(parsed))
+	}
+	for _, s := range douceuradapter.ExtractStyleElements(h) {
+		sheet.AppendRules(s)
+	}
+	doc := dom.FromHTMLParseTree(h, sheet)
+	if doc == nil {
+		return nil, errors.New("no document to typeset")
+	}
+	boxes, err := boxtree.BuildBoxTree(doc)
+	if err != nil {
+		return nil, err
+	}
+	router := boxtree.RouteFlows(boxes)
+	pm := layout.NewPageModel(papersize)
+	for _, rule := range cssom.EffectiveRules(sheet, cssom.PrintMedia(papersize)) {
+		if err := pm.AddPageRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	content := pm.ContentArea(1)
+	view := &layout.View{Width: content.Width(), Height: content.Height()}
+	if err = layout.Layout(boxes.RenderNode().(*boxtree.PrincipalBox), view); err != nil {
+		return nil, err
+	}
+	template := &layout.PageTemplate{
+		Name:    "default",
+		Regions: []layout.Region{{Name: "main", Flow: frame.MainFlow}},
+	}
+	return layout.NewPaginator(pm, layout.SpreadTemplates(pm, template, template)).Paginate(router)
+}
+
+// Run typesets the fixtures in directory dir into pages of size papersize and
+// compares their dumps against golden files. Fixtures are files with extension
+// ".html"; a file with extension ".css" and the same name is applied as an
+// additional stylesheet. Every fixture is run as a sub-test, named after the
+// fixture.
+func Run(t *testing.T, dir string, papersize dimen.Point) {
+	t.Helper()
+	fixtures, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures found in %s", dir)
+	}
+	for _, fixture := range fixtures {
+		base := strings.TrimSuffix(fixture, filepath.Ext(fixture))
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			document, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			css, err := os.ReadFile(base + ".css")
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				t.Fatal(err)
+			}
+			pages, err := Typeset(string(document), string(css), papersize)
+			if err != nil {
+				t.Fatalf("cannot typeset %s: %v", fixture, err)
+			}
+			var dump bytes.Buffer
+			if err = Dump(&dump, pages); err != nil {
+				t.Fatal(err)
+			}
+			Check(t, base+".golden", dump.Bytes())
+		})
+	}
+}
+
+// Check compares dump against the contents of golden file filename and reports
+// the first differing line as an error of t. If flag -update is set, the golden
+// file is written instead.
+func Check(t testing.TB, filename string, dump []byte) {
+	t.Helper()
+	if *Update {
+		if err := os.WriteFile(filename, dump, 0644); err != nil {
+			t.Fatal(err)
+		}
+		tracer().Infof("updated golden file %s", filename)
+		return
+	}
+	golden, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist; run test with flag -update to create it", filename)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if n, want, have, differ := firstDifference(golden, dump); differ {
+		t.Errorf("output differs from golden file %s in line %d:\nwant: %s\nhave: %s", filename, n, want, have)
+	}
+}
+
+// firstDifference returns the first line in which a and b differ, counting from 1.
+// A missing line is reported as "<EOF>".
+func firstDifference(a, b []byte) (n int, lineA, lineB string, differ bool) {
+	la := strings.Split(string(a), "\n")
+	lb := strings.Split(string(b), "\n")
+	for n = 0; n < len(la) || n < len(lb); n++ {
+		lineA, lineB = "<EOF>", "<EOF>"
+		if n < len(la) {
+			lineA = la[n]
+		}
+		if n < len(lb) {
+			lineB = lb[n]
+		}
+		if lineA != lineB {
+			return n + 1, lineA, lineB, true
+		}
+	}
+	return 0, "", "", false
+}
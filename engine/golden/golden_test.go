@@ -0,0 +1,73 @@
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/npillmayer/tyse/core/dimen"
+	"github.com/npillmayer/tyse/engine/frame/inline"
+	"github.com/npillmayer/tyse/engine/frame/khipu"
+	"github.com/npillmayer/tyse/engine/frame/layout"
+)
+
+func TestDumpLine(t *testing.T) {
+	hello := khipu.NewTextBox("Hello", 0)
+	hello.Width = 22 * dimen.BP
+	line := &inline.SetLine{
+		Length: 60 * dimen.BP,
+		Items: []inline.PositionedKnot{
+			{Knot: hello, X: 0, W: 22 * dimen.BP},
+			{Knot: khipu.NewGlue(4*dimen.BP, 1*dimen.BP, 2*dimen.BP), X: 22 * dimen.BP, W: 5 * dimen.BP},
+			{Knot: khipu.Penalty(50), X: 27 * dimen.BP},
+		},
+	}
+	var buf bytes.Buffer
+	d := &dumper{w: bufio.NewWriter(&buf), level: 1}
+	d.setLine(line)
+	d.w.Flush()
+	expected := `  text "Hello" x=0.00 w=22.00 glyphs=[]
+  glue x=22.00 w=5.00
+  penalty 50 x=27.00 w=0.00
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected dump of line:\n%s", buf.String())
+	}
+}
+
+func TestDumpPage(t *testing.T) {
+	page := layout.NewPage(dimen.Point{X: 100 * dimen.BP, Y: 200 * dimen.BP})
+	page.Number = 3
+	page.Side = layout.LeftPage
+	page.Regions = []*layout.RegionBox{{
+		Region: layout.Region{Name: "main", Flow: "main"},
+		Frame:  dimen.Rect{BotR: dimen.Point{X: 100 * dimen.BP, Y: 150 * dimen.BP}},
+	}}
+	var buf bytes.Buffer
+	if err := Dump(&buf, []*layout.Page{page}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `page 3 left 100.00x200.00 content [0.00,0.00 100.00x200.00]
+  region main flow=main [0.00,0.00 100.00x150.00]
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected dump of page:\n%s", buf.String())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.golden")
+	defer func(u bool) { *Update = u }(*Update)
+	*Update = true
+	Check(t, filename, []byte("a\nb\n"))
+	*Update = false
+	Check(t, filename, []byte("a\nb\n"))
+	n, want, have, differ := firstDifference([]byte("a\nb\n"), []byte("a\nc\nd\n"))
+	if !differ || n != 2 || want != "b" || have != "c" {
+		t.Errorf("expected difference in line 2, have %d: %q/%q", n, want, have)
+	}
+	if n, _, have, _ = firstDifference([]byte("a\n"), []byte("a\n\nx")); n != 3 || have != "x" {
+		t.Errorf("expected difference at end of golden file, have line %d", n)
+	}
+}
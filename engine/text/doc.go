@@ -0,0 +1,26 @@
+/*
+Package text splits the text of paragraphs into runs for shaping.
+
+Shapers (see package glyphing) consume runs of text which are set in a single
+font and style, and which are of a single script, bidi direction and language.
+Styled paragraphs (see package cords/styled) come in runs of constant style, but
+a run of style may mix scripts and directions, e.g., for English text quoting a
+Hebrew word:
+
+	The Hebrew word for peace is שלום.
+
+Itemizing the paragraph yields separate runs for the English text and for the
+Hebrew word, which is shaped right-to-left. Characters without a script of their
+own, e.g. spaces, punctuation or digits, are attached to the runs surrounding
+them (see ScriptOf and Itemize).
+
+Encoding paragraphs into khipus (see package khipu) shapes text run by run.
+
+# License
+
+Governed by a 3-Clause BSD license. License file may be found in the root
+folder of this module.
+
+Copyright © 2017–2022 Norbert Pillmayer <norbert@pillmayer.com>
+*/
+package text
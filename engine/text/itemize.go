@@ -0,0 +1,135 @@
+package text
+
+import (
+	"github.com/npillmayer/cords/styled"
+	"github.com/npillmayer/tyse/core/font"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	"github.com/npillmayer/uax/bidi"
+	"golang.org/x/text/language"
+)
+
+// --- Runs ------------------------------------------------------------------
+
+// A Run is a run of paragraph text with constant style, script, bidi direction
+// and language. Runs are the units of text a shaper consumes.
+type Run struct {
+	From, To  uint64          // byte positions of the run within the text of its paragraph
+	Text      string          // text of the run
+	Style     styled.Style    // style of the run, including its font; nil for plain text
+	Script    language.Script // ISO 15924 script of the run, see ScriptOf
+	Direction bidi.Direction  // resolved bidi direction of the run
+	Language  language.Tag    // language of the run
+}
+
+// FontStyle is implemented by styles which select a font, e.g., frame.StyleSet.
+type FontStyle interface {
+	Font() *font.TypeCase
+}
+
+// ShapingParams returns parameters for shaping a run. For runs with a style
+// implementing FontStyle, the font of the style is selected.
+func (r Run) ShapingParams() glyphing.Params {
+	p := glyphing.Params{
+		Script:    r.Script,
+		Direction: glyphing.LeftToRight,
+		Language:  r.Language,
+	}
+	if r.Direction == bidi.RightToLeft {
+		p.Direction = glyphing.RightToLeft
+	}
+	if fs, ok := r.Style.(FontStyle); ok {
+		p.Font = fs.Font()
+	}
+	return p
+}
+
+// --- Itemization -----------------------------------------------------------
+
+// Option is a type for configuring Itemize and ItemizeText.
+type Option func(*itemizer)
+
+type itemizer struct {
+	lang func(styled.Style) language.Tag
+}
+
+// WithLanguage sets a function to find the language of a style. If not set, runs
+// are of language language.Und.
+func WithLanguage(lang func(styled.Style) language.Tag) Option {
+	return func(it *itemizer) {
+		if lang != nil {
+			it.lang = lang
+		}
+	}
+}
+
+func newItemizer(opts []Option) *itemizer {
+	it := &itemizer{lang: func(styled.Style) language.Tag { return language.Und }}
+	for _, opt := range opts {
+		opt(it)
+	}
+	return it
+}
+
+// Itemize splits the text of a styled paragraph into runs of constant style,
+// script, bidi direction and language. Runs are split
+//
+//   - at every change of style, as reported by para.EachStyleRun
+//   - at every change of script (see ScriptOf), where characters without a
+//     script of their own take the script of their neighbours
+//   - at every change of the bidi direction resolved for the paragraph
+//
+// Runs are returned in logical order.
+func Itemize(para *styled.Paragraph, opts ...Option) ([]Run, error) {
+	it := newItemizer(opts)
+	levels := para.BidiLevels()
+	dirAt := func(pos uint64) bidi.Direction {
+		if levels == nil {
+			return bidi.LeftToRight
+		}
+		return levels.DirectionAt(pos)
+	}
+	var runs []Run
+	err := para.EachStyleRun(func(content string, sty styled.Style, pos, length uint64) error {
+		runs = it.itemize(runs, content, pos, sty, dirAt)
+		return nil
+	})
+	return runs, err
+}
+
+// ItemizeText splits plain text of a single direction into runs of constant
+// script. Runs have no style.
+func ItemizeText(text string, dir bidi.Direction, opts ...Option) []Run {
+	it := newItemizer(opts)
+	return it.itemize(nil, text, 0, nil, func(uint64) bidi.Direction { return dir })
+}
+
+// itemize appends the runs of a run of style to runs. The text of the run of
+// style starts at position offset of the paragraph.
+func (it *itemizer) itemize(runs []Run, text string, offset uint64, sty styled.Style,
+	dirAt func(uint64) bidi.Direction) []Run {
+	//
+	lang := it.lang(sty)
+	run := func(from, to int, script language.Script, dir bidi.Direction) Run {
+		return Run{
+			From:      offset + uint64(from),
+			To:        offset + uint64(to),
+			Text:      text[from:to],
+			Style:     sty,
+			Script:    script,
+			Direction: dir,
+			Language:  lang,
+		}
+	}
+	for _, sr := range scriptRuns(text) {
+		from, dir := sr.from, dirAt(offset+uint64(sr.from))
+		for i := range text[sr.from:sr.to] {
+			pos := sr.from + i
+			if d := dirAt(offset + uint64(pos)); d != dir {
+				runs = append(runs, run(from, pos, sr.script, dir))
+				from, dir = pos, d
+			}
+		}
+		runs = append(runs, run(from, sr.to, sr.script, dir))
+	}
+	return runs
+}
@@ -0,0 +1,79 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/npillmayer/cords/styled"
+	"github.com/npillmayer/schuko/tracing/gotestingadapter"
+	"github.com/npillmayer/tyse/engine/glyphing"
+	"github.com/npillmayer/uax/bidi"
+	"golang.org/x/text/language"
+)
+
+func TestScriptOf(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	for _, x := range []struct {
+		r      rune
+		script string
+	}{
+		{'a', "Latn"}, {'é', "Latn"}, {'7', "Zyyy"}, {' ', "Zyyy"}, {'\u0301', "Zinh"},
+		{'ש', "Hebr"}, {'ب', "Arab"}, {'東', "Hani"}, {'か', "Hira"}, {'Ж', "Cyrl"},
+	} {
+		if script := ScriptOf(x.r); script.String() != x.script {
+			t.Errorf("expected %q to be of script %s, is %s", x.r, x.script, script)
+		}
+	}
+}
+
+func TestScriptRuns(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	for _, x := range []struct {
+		text string
+		runs []string
+	}{
+		{"hello world", []string{"hello world"}},
+		{"", nil},
+		{"1, 2, 3", []string{"1, 2, 3"}},
+		{"42 Tokyo東京", []string{"42 Tokyo", "東京"}},
+		{"peace is שלום (shalom).", []string{"peace is ", "שלום (", "shalom", ")."}},
+		{"word (שלום) end", []string{"word (", "שלום", ") end"}},
+		{"«Привет» said he", []string{"«Привет» ", "said he"}},
+		{"café Ωμέγα", []string{"café ", "Ωμέγα"}},
+	} {
+		runs := scriptRuns(x.text)
+		if len(runs) != len(x.runs) {
+			t.Errorf("expected %q to be split into %q, have %v", x.text, x.runs, runs)
+			continue
+		}
+		for i, run := range runs {
+			if x.text[run.from:run.to] != x.runs[i] {
+				t.Errorf("expected run #%d of %q to be %q, is %q", i, x.text, x.runs[i], x.text[run.from:run.to])
+			}
+		}
+	}
+}
+
+func TestItemizeText(t *testing.T) {
+	teardown := gotestingadapter.QuickConfig(t, "tyse.engine")
+	defer teardown()
+	//
+	german := func(styled.Style) language.Tag { return language.German }
+	runs := ItemizeText("Straße ΑΒΓ", bidi.LeftToRight, WithLanguage(german))
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, have %v", runs)
+	}
+	if runs[1].From != 8 || runs[1].To != 14 || runs[1].Text != "ΑΒΓ" {
+		t.Errorf("expected second run to be ΑΒΓ at [8…14), is %q at [%d…%d)", runs[1].Text, runs[1].From, runs[1].To)
+	}
+	p := runs[1].ShapingParams()
+	if p.Script.String() != "Grek" || p.Language != language.German || p.Direction != glyphing.LeftToRight {
+		t.Errorf("unexpected shaping parameters for Greek run: %v", p)
+	}
+	if runs = ItemizeText("שלום", bidi.RightToLeft); runs[0].ShapingParams().Direction != glyphing.RightToLeft {
+		t.Errorf("expected Hebrew run to be shaped right-to-left")
+	}
+}
@@ -0,0 +1,149 @@
+package text
+
+import (
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// --- Scripts ---------------------------------------------------------------
+
+// Pseudo-scripts of ISO 15924 for characters which are not of a script of their
+// own.
+var (
+	Common    = language.MustParseScript("Zyyy") // e.g., spaces, punctuation and digits
+	Inherited = language.MustParseScript("Zinh") // e.g., combining marks
+	Unknown   = language.MustParseScript("Zzzz") // unassigned code-points and unsupported scripts
+)
+
+// scripts maps Unicode scripts to ISO 15924 codes, more frequent scripts first.
+var scripts = []struct {
+	table  *unicode.RangeTable
+	script language.Script
+}{
+	{unicode.Latin, language.MustParseScript("Latn")},
+	{unicode.Han, language.MustParseScript("Hani")},
+	{unicode.Cyrillic, language.MustParseScript("Cyrl")},
+	{unicode.Arabic, language.MustParseScript("Arab")},
+	{unicode.Greek, language.MustParseScript("Grek")},
+	{unicode.Hebrew, language.MustParseScript("Hebr")},
+	{unicode.Hiragana, language.MustParseScript("Hira")},
+	{unicode.Katakana, language.MustParseScript("Kana")},
+	{unicode.Hangul, language.MustParseScript("Hang")},
+	{unicode.Devanagari, language.MustParseScript("Deva")},
+	{unicode.Bengali, language.MustParseScript("Beng")},
+	{unicode.Thai, language.MustParseScript("Thai")},
+	{unicode.Armenian, language.MustParseScript("Armn")},
+	{unicode.Georgian, language.MustParseScript("Geor")},
+	{unicode.Ethiopic, language.MustParseScript("Ethi")},
+	{unicode.Gujarati, language.MustParseScript("Gujr")},
+	{unicode.Gurmukhi, language.MustParseScript("Guru")},
+	{unicode.Kannada, language.MustParseScript("Knda")},
+	{unicode.Malayalam, language.MustParseScript("Mlym")},
+	{unicode.Oriya, language.MustParseScript("Orya")},
+	{unicode.Tamil, language.MustParseScript("Taml")},
+	{unicode.Telugu, language.MustParseScript("Telu")},
+	{unicode.Sinhala, language.MustParseScript("Sinh")},
+	{unicode.Khmer, language.MustParseScript("Khmr")},
+	{unicode.Lao, language.MustParseScript("Laoo")},
+	{unicode.Myanmar, language.MustParseScript("Mymr")},
+	{unicode.Tibetan, language.MustParseScript("Tibt")},
+	{unicode.Mongolian, language.MustParseScript("Mong")},
+	{unicode.Syriac, language.MustParseScript("Syrc")},
+	{unicode.Thaana, language.MustParseScript("Thaa")},
+	{unicode.Bopomofo, language.MustParseScript("Bopo")},
+	{unicode.Yi, language.MustParseScript("Yiii")},
+	{unicode.Cherokee, language.MustParseScript("Cher")},
+	{unicode.Canadian_Aboriginal, language.MustParseScript("Cans")},
+	{unicode.Inherited, Inherited},
+	{unicode.Common, Common},
+}
+
+// ScriptOf returns the ISO 15924 script of a rune, according to the Unicode
+// property Script. Runes of scripts not known to this package are of script
+// Unknown.
+func ScriptOf(r rune) language.Script {
+	if r < 0x80 { // ASCII
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return scripts[0].script
+		}
+		return Common
+	}
+	for _, s := range scripts {
+		if unicode.Is(s.table, r) {
+			return s.script
+		}
+	}
+	return Unknown
+}
+
+// brackets maps opening brackets to closing ones. Paired brackets are set in the
+// script of the text preceding the opening bracket.
+var brackets = map[rune]rune{
+	'(': ')', '[': ']', '{': '}', '«': '»', '‹': '›', '“': '”', '‘': '’',
+	'（': '）', '［': '］', '｛': '｝', '「': '」', '『': '』', '【': '】',
+	'〈': '〉', '《': '》', '〔': '〕',
+}
+
+// scriptRun is a run of text of a single script, given as byte positions.
+type scriptRun struct {
+	from, to int
+	script   language.Script
+}
+
+// scriptRuns splits text into runs of a single script, following the heuristics
+// of UAX#24 and of common shaping engines:
+//
+//   - characters of script Inherited take the script of the preceding character
+//   - characters of script Common take the script of the preceding character,
+//     or of the following characters at the start of text
+//   - paired brackets take the script of the text preceding the opening bracket
+//
+// Text without characters of a script of its own results in a single run of
+// script Common.
+func scriptRuns(text string) []scriptRun {
+	type bracket struct {
+		closing rune
+		script  language.Script
+	}
+	var runs []scriptRun
+	var open []bracket
+	current, start := Common, 0
+	for pos, r := range text {
+		script := ScriptOf(r)
+		switch script {
+		case Inherited:
+			script = current
+		case Common:
+			script = current
+			if closing, ok := brackets[r]; ok {
+				open = append(open, bracket{closing, current})
+				break
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].closing == r {
+					script, open = open[i].script, open[:i]
+					break
+				}
+			}
+		}
+		if script == current {
+			continue
+		}
+		if current == Common { // text up to here takes the first script found
+			for i := range open {
+				if open[i].script == Common {
+					open[i].script = script
+				}
+			}
+			current = script
+			continue
+		}
+		runs = append(runs, scriptRun{from: start, to: pos, script: current})
+		current, start = script, pos
+	}
+	if start < len(text) {
+		runs = append(runs, scriptRun{from: start, to: len(text), script: current})
+	}
+	return runs
+}
@@ -4,13 +4,12 @@ package html
 // github.com/PuerkitoBio/goquery
 
 import (
-	"github.com/npillmayer/schuko/gtrace"
 	"github.com/npillmayer/schuko/tracing"
 )
 
-// CT traces to the core-tracer.
+// CT traces with key 'tyse.input'.
 func CT() tracing.Trace {
-	return gtrace.CoreTracer
+	return tracing.Select("tyse.input")
 }
 
 /*
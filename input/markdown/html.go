@@ -0,0 +1,226 @@
+package markdown
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// --- Conversion to HTML ----------------------------------------------------
+
+// Until there is a Markdown parser building a DOM directly, Markdown input is
+// converted to HTML, which is then typeset like any other HTML document.
+// ToHTML supports the basic syntax (https://www.markdownguide.org/basic-syntax):
+//
+//   - ATX headings (# Title) and Setext headings (Title underlined by === or ---)
+//   - paragraphs, separated by blank lines
+//   - block quotes (> …), which may contain any other block
+//   - unordered lists (-, * or +) and ordered lists (1. or 1)), without nesting
+//   - fenced code blocks (```), with an optional language
+//   - thematic breaks (---, *** or ___)
+//   - inline code, strong and emphasized text, and links
+//
+// HTML contained in the input is escaped, not passed through.
+
+// ToHTML converts Markdown input to an HTML fragment.
+func ToHTML(r io.Reader) (string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	c := &converter{}
+	c.convert(lines)
+	return c.out.String(), nil
+}
+
+var (
+	atxHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?$`)
+	setextH1     = regexp.MustCompile(`^ {0,3}=+$`)
+	setextH2     = regexp.MustCompile(`^ {0,3}-+$`)
+	thematic     = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	listItem     = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])[ \t]+(.*)$`)
+	quoteLine    = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	fence        = regexp.MustCompile("^ {0,3}(```+)[ \t]*([^ \t`]*)")
+	inlineLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	inlineStrong = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	inlineEmph   = regexp.MustCompile(`\*([^*]+)\*|(^|[^\p{L}\p{N}_])_([^_]+)_([^\p{L}\p{N}_]|$)`)
+)
+
+// converter converts blocks of Markdown lines, collecting HTML in out.
+type converter struct {
+	out   strings.Builder
+	para  []string // lines of the current paragraph
+	list  string   // "ul" or "ol" while a list is open
+	item  []string // lines of the current list item
+	quote []string // lines of the current block quote
+}
+
+func (c *converter) convert(lines []string) {
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := quoteLine.FindStringSubmatch(line); m != nil {
+			c.closeParagraph()
+			c.closeList()
+			c.quote = append(c.quote, m[1])
+			continue
+		}
+		c.closeQuote()
+		if strings.TrimSpace(line) == "" {
+			c.closeParagraph()
+			c.closeList()
+			continue
+		}
+		if m := fence.FindStringSubmatch(line); m != nil {
+			c.closeAll()
+			i = c.codeBlock(lines, i+1, m[1], m[2])
+			continue
+		}
+		if len(c.para) > 0 && setextH1.MatchString(line) {
+			c.heading(1, strings.Join(c.para, " "))
+			c.para = c.para[:0]
+			continue
+		}
+		if len(c.para) > 0 && setextH2.MatchString(line) {
+			c.heading(2, strings.Join(c.para, " "))
+			c.para = c.para[:0]
+			continue
+		}
+		if thematic.MatchString(line) {
+			c.closeAll()
+			c.out.WriteString("<hr>\n")
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			c.closeAll()
+			c.heading(len(m[1]), m[2])
+			continue
+		}
+		if m := listItem.FindStringSubmatch(line); m != nil {
+			c.closeParagraph()
+			c.listItem(m[1], m[2])
+			continue
+		}
+		if c.list != "" && (line[0] == ' ' || line[0] == '\t') { // continuation of item
+			c.item = append(c.item, strings.TrimSpace(line))
+			continue
+		}
+		c.closeList()
+		c.para = append(c.para, strings.TrimSpace(line))
+	}
+	c.closeAll()
+}
+
+// codeBlock outputs the lines of a fenced code block, starting at line i, and
+// returns the index of the closing fence.
+func (c *converter) codeBlock(lines []string, i int, delim, lang string) int {
+	if lang != "" {
+		c.out.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+	} else {
+		c.out.WriteString("<pre><code>")
+	}
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimLeft(lines[i], " "), delim) {
+			break
+		}
+		c.out.WriteString(html.EscapeString(lines[i]))
+		c.out.WriteByte('\n')
+	}
+	c.out.WriteString("</code></pre>\n")
+	return i
+}
+
+func (c *converter) heading(level int, text string) {
+	tag := "h" + string(rune('0'+level))
+	c.out.WriteString("<" + tag + ">" + inline(text) + "</" + tag + ">\n")
+}
+
+func (c *converter) listItem(marker, text string) {
+	kind := "ol"
+	if strings.ContainsAny(marker, "-*+") {
+		kind = "ul"
+	}
+	if c.list != kind {
+		c.closeList()
+		c.list = kind
+		c.out.WriteString("<" + kind + ">\n")
+	}
+	c.closeItem()
+	c.item = append(c.item, text)
+}
+
+func (c *converter) closeItem() {
+	if len(c.item) > 0 {
+		c.out.WriteString("<li>" + inline(strings.Join(c.item, " ")) + "</li>\n")
+		c.item = c.item[:0]
+	}
+}
+
+func (c *converter) closeList() {
+	if c.list == "" {
+		return
+	}
+	c.closeItem()
+	c.out.WriteString("</" + c.list + ">\n")
+	c.list = ""
+}
+
+func (c *converter) closeParagraph() {
+	if len(c.para) > 0 {
+		c.out.WriteString("<p>" + inline(strings.Join(c.para, " ")) + "</p>\n")
+		c.para = c.para[:0]
+	}
+}
+
+// closeQuote outputs the current block quote, converting its content
+// recursively.
+func (c *converter) closeQuote() {
+	if len(c.quote) == 0 {
+		return
+	}
+	inner := &converter{}
+	inner.convert(c.quote)
+	c.out.WriteString("<blockquote>\n" + inner.out.String() + "</blockquote>\n")
+	c.quote = c.quote[:0]
+}
+
+func (c *converter) closeAll() {
+	c.closeParagraph()
+	c.closeList()
+	c.closeQuote()
+}
+
+// inline converts inline markup of text. Code spans are taken literally.
+func inline(text string) string {
+	var b strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1: // code span
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1: // unmatched backtick
+			b.WriteString("`" + emphasis(part))
+		default:
+			b.WriteString(emphasis(part))
+		}
+	}
+	return b.String()
+}
+
+func emphasis(text string) string {
+	text = html.EscapeString(text)
+	text = inlineLink.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = inlineStrong.ReplaceAllString(text, `<strong>$1$2</strong>`)
+	return inlineEmph.ReplaceAllStringFunc(text, func(s string) string {
+		m := inlineEmph.FindStringSubmatch(s)
+		if m[1] != "" {
+			return "<em>" + m[1] + "</em>"
+		}
+		return m[2] + "<em>" + m[3] + "</em>" + m[4]
+	})
+}
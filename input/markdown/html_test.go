@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTMLBlocks(t *testing.T) {
+	for i, test := range []struct {
+		md, html string
+	}{
+		{"# Title", "<h1>Title</h1>\n"},
+		{"### Section ###", "<h3>Section</h3>\n"},
+		{"Title\n=====", "<h1>Title</h1>\n"},
+		{"Sub\ntitle\n---", "<h2>Sub title</h2>\n"},
+		{"one\ntwo\n\nthree", "<p>one two</p>\n<p>three</p>\n"},
+		{"- a\n- b\n  c", "<ul>\n<li>a</li>\n<li>b c</li>\n</ul>\n"},
+		{"1. a\n2) b\n\ntext", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n<p>text</p>\n"},
+		{"> # Q\n> quoted", "<blockquote>\n<h1>Q</h1>\n<p>quoted</p>\n</blockquote>\n"},
+		{"```go\nif a < b {\n```", "<pre><code class=\"language-go\">if a &lt; b {\n</code></pre>\n"},
+		{"a\n\n***\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+	} {
+		html, err := ToHTML(strings.NewReader(test.md))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if html != test.html {
+			t.Errorf("test %d: expected %q, have %q", i, test.html, html)
+		}
+	}
+}
+
+func TestToHTMLInline(t *testing.T) {
+	for i, test := range []struct {
+		text, html string
+	}{
+		{"plain <b>", "plain &lt;b&gt;"},
+		{"**strong** and __strong__", "<strong>strong</strong> and <strong>strong</strong>"},
+		{"*em* and _em_, snake_case_name", "<em>em</em> and <em>em</em>, snake_case_name"},
+		{"a `*literal*` span", "a <code>*literal*</code> span"},
+		{"see [tyse](https://github.com/npillmayer/tyse)", `see <a href="https://github.com/npillmayer/tyse">tyse</a>`},
+	} {
+		if html := inline(test.text); html != test.html {
+			t.Errorf("test %d: expected %q, have %q", i, test.html, html)
+		}
+	}
+}